@@ -0,0 +1,288 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// commandNames is the names of volt commands, which aliases must not have.
+var commandNames map[string]bool
+
+// SetCommandNames sets the names of volt commands. Aliases which have the
+// same names as them, or which invoke unknown commands are reported as
+// invalid values of config.toml.
+// If this is not called, aliases are not checked with command names.
+func SetCommandNames(names []string) {
+	commandNames = make(map[string]bool, len(names))
+	for _, name := range names {
+		commandNames[name] = true
+	}
+}
+
+// checkAlias returns the message of the problem of alias name whose value is
+// args, or "" if it is valid. aliases is the all aliases of config.toml.
+func checkAlias(aliases map[string][]string, name string, args []string) string {
+	switch {
+	case name == "" || strings.HasPrefix(name, "-") || strings.IndexFunc(name, unicode.IsSpace) >= 0:
+		return fmt.Sprintf("alias.%q: alias name must not be empty, start with '-', or contain white spaces", name)
+	case commandNames[name]:
+		return fmt.Sprintf("alias.%q: alias cannot override volt command %q", name, name)
+	case len(args) == 0:
+		return fmt.Sprintf("alias.%q: must have a command", name)
+	}
+	if commandNames[args[0]] {
+		return ""
+	}
+	if _, isAlias := aliases[args[0]]; isAlias {
+		return fmt.Sprintf("alias.%q: cannot invoke alias %q (aliases are not expanded recursively)", name, args[0])
+	}
+	// External commands ("volt-{name}" in $PATH) are not known
+	if _, err := exec.LookPath("volt-" + args[0]); err == nil {
+		return ""
+	}
+	if commandNames != nil && !commandNames[args[0]] {
+		return fmt.Sprintf("alias.%q: unknown command %q", name, args[0])
+	}
+	return ""
+}
+
+// ValidateAlias returns an error if alias name cannot be set to args in
+// cfg: name is invalid or a volt command, args do not start with a volt
+// command, or other aliases invoke name.
+func ValidateAlias(cfg *Config, name string, args []string) error {
+	if msg := checkAlias(cfg.Alias, name, args); msg != "" {
+		return errors.New(msg)
+	}
+	for other, otherArgs := range cfg.Alias {
+		if other != name && len(otherArgs) > 0 && otherArgs[0] == name {
+			return errors.Errorf("alias.%q invokes %q: aliases are not expanded recursively", other, name)
+		}
+	}
+	return nil
+}
+
+// SetAlias sets alias name to args in config.toml. The other lines of
+// config.toml (including comments) are kept.
+func SetAlias(name string, args []string) error {
+	return editConfigAlias(name, args)
+}
+
+// RemoveAlias removes alias name from config.toml. The other lines of
+// config.toml (including comments) are kept.
+func RemoveAlias(name string) error {
+	return editConfigAlias(name, nil)
+}
+
+func editConfigAlias(name string, args []string) error {
+	configFile := pathutil.ConfigTOML()
+	var content []byte
+	perm := os.FileMode(0644)
+	if fi, err := os.Stat(configFile); err == nil {
+		perm = fi.Mode().Perm()
+		if content, err = ioutil.ReadFile(configFile); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	newContent, err := editAlias(content, name, args)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(configFile, newContent, perm)
+}
+
+// aliasHeaderRx matches "[alias]" table header line.
+var aliasHeaderRx = regexp.MustCompile(`^\s*\[\s*(?:alias|"alias")\s*\]\s*(?:#.*)?$`)
+
+// editAlias returns content whose alias name is set to args, or removed if
+// args is nil. An error is returned if alias name could not be edited
+// without changing the other values (e.g. aliases are written in dotted
+// keys or inline tables).
+func editAlias(content []byte, name string, args []string) ([]byte, error) {
+	var expected map[string]interface{}
+	if _, err := toml.Decode(string(content), &expected); err != nil {
+		return nil, err
+	}
+	if expected == nil {
+		expected = make(map[string]interface{}, 1)
+	}
+	aliases, _ := expected["alias"].(map[string]interface{})
+	if _, exists := aliases[name]; !exists && args == nil {
+		return nil, errors.Errorf("alias '%s' does not exist", name)
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	entry := tomlKey(name) + " = " + tomlStringList(args) + "\n"
+
+	// Find [alias] table, and the line of alias name in it
+	header, end := -1, len(lines)
+	for i := range lines {
+		trimmed := strings.TrimSpace(lines[i])
+		if header < 0 && aliasHeaderRx.MatchString(lines[i]) {
+			header = i
+		} else if header >= 0 && strings.HasPrefix(trimmed, "[") {
+			end = i
+			break
+		}
+	}
+	var newLines []string
+	switch {
+	case header < 0:
+		newLines = lines
+		if len(newLines) > 0 && !strings.HasSuffix(newLines[len(newLines)-1], "\n") {
+			newLines[len(newLines)-1] += "\n"
+		}
+		if len(newLines) > 0 {
+			newLines = append(newLines, "\n")
+		}
+		newLines = append(newLines, "[alias]\n", entry)
+	default:
+		first, last := -1, -1
+		for i := header + 1; i < end; i++ {
+			trimmed := strings.TrimSpace(lines[i])
+			eq := strings.Index(trimmed, "=")
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") || eq < 0 {
+				continue
+			}
+			valueEnd := tomlValueEnd(lines, i, strings.Index(lines[i], "=")+1)
+			if joinKeys(trimmed[:eq]) == name {
+				first, last = i, valueEnd
+				break
+			}
+			i = valueEnd
+		}
+		newLines = append(newLines, lines[:header+1]...)
+		switch {
+		case first >= 0 && args != nil:
+			newLines = append(newLines, lines[header+1:first]...)
+			newLines = append(newLines, entry)
+			newLines = append(newLines, lines[last+1:]...)
+		case first >= 0:
+			newLines = append(newLines, lines[header+1:first]...)
+			newLines = append(newLines, lines[last+1:]...)
+		default:
+			// Add the entry after the last non-empty line of [alias] table
+			pos := end
+			for pos > header+1 && strings.TrimSpace(lines[pos-1]) == "" {
+				pos--
+			}
+			newLines = append(newLines, lines[header+1:pos]...)
+			if !strings.HasSuffix(newLines[len(newLines)-1], "\n") {
+				newLines[len(newLines)-1] += "\n"
+			}
+			newLines = append(newLines, entry)
+			newLines = append(newLines, lines[pos:]...)
+		}
+	}
+	newContent := []byte(strings.Join(newLines, ""))
+
+	// Check only alias name was changed
+	var got map[string]interface{}
+	if _, err := toml.Decode(string(newContent), &got); err != nil {
+		return nil, errors.Wrap(err, "could not edit [alias] table")
+	}
+	if aliases == nil {
+		aliases = make(map[string]interface{}, 1)
+		expected["alias"] = aliases
+	}
+	if args != nil {
+		list := make([]interface{}, 0, len(args))
+		for _, arg := range args {
+			list = append(list, arg)
+		}
+		aliases[name] = list
+	} else {
+		delete(aliases, name)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		return nil, errors.New("could not edit [alias] table: please edit " + pathutil.ConfigTOML() + " manually")
+	}
+	return newContent, nil
+}
+
+// tomlValueEnd returns the index of the last line of the value which starts
+// at lines[lnum][col:]. Arrays may span multiple lines.
+func tomlValueEnd(lines []string, lnum, col int) int {
+	depth := 0
+	for i := lnum; i < len(lines); i++ {
+		line := lines[i]
+		if i == lnum {
+			line = line[col:]
+		}
+		var quote rune
+		escaped := false
+	scan:
+		for _, c := range line {
+			switch {
+			case escaped:
+				escaped = false
+			case quote != 0:
+				if c == '\\' && quote == '"' {
+					escaped = true
+				} else if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '\'':
+				quote = c
+			case c == '#':
+				break scan
+			case c == '[':
+				depth++
+			case c == ']':
+				depth--
+			}
+		}
+		if depth <= 0 {
+			return i
+		}
+	}
+	return len(lines) - 1
+}
+
+var bareKeyRx = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+	if bareKeyRx.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+func tomlStringList(list []string) string {
+	quoted := make([]string, 0, len(list))
+	for _, s := range list {
+		quoted = append(quoted, tomlString(s))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// tomlString returns a basic string of TOML. The escape sequences of JSON
+// strings are also valid in TOML.
+func tomlString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"testing"
+)
+
+const aliasTestTOML = `# volt config
+[alias]
+# upgrade all plugins
+up = ["get", "-l", "-u"]
+long = [
+  "list",  # comment with ]
+  "-f", "{{ range .Repos }}{{ println .Path }}{{ end }}",
+]
+
+[build]
+strategy = "copy"
+`
+
+func TestEditAlias(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		alias    string
+		args     []string
+		expected string
+	}{
+		{
+			name:    "replace",
+			content: aliasTestTOML,
+			alias:   "up",
+			args:    []string{"get", "-u"},
+			expected: `# volt config
+[alias]
+# upgrade all plugins
+up = ["get", "-u"]
+long = [
+  "list",  # comment with ]
+  "-f", "{{ range .Repos }}{{ println .Path }}{{ end }}",
+]
+
+[build]
+strategy = "copy"
+`,
+		},
+		{
+			name:    "remove multi-line value",
+			content: aliasTestTOML,
+			alias:   "long",
+			expected: `# volt config
+[alias]
+# upgrade all plugins
+up = ["get", "-l", "-u"]
+
+[build]
+strategy = "copy"
+`,
+		},
+		{
+			name:    "add to table",
+			content: aliasTestTOML,
+			alias:   "rm-all",
+			args:    []string{"rm", "-p", `"quoted"`},
+			expected: `# volt config
+[alias]
+# upgrade all plugins
+up = ["get", "-l", "-u"]
+long = [
+  "list",  # comment with ]
+  "-f", "{{ range .Repos }}{{ println .Path }}{{ end }}",
+]
+rm-all = ["rm", "-p", "\"quoted\""]
+
+[build]
+strategy = "copy"
+`,
+		},
+		{
+			name:    "add table",
+			content: "[build]\nstrategy = \"copy\"",
+			alias:   "a b",
+			args:    []string{"list"},
+			expected: `[build]
+strategy = "copy"
+
+[alias]
+"a b" = ["list"]
+`,
+		},
+		{
+			name:     "empty file",
+			content:  "",
+			alias:    "up",
+			args:     []string{"get", "-l", "-u"},
+			expected: "[alias]\nup = [\"get\", \"-l\", \"-u\"]\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := editAlias([]byte(tt.content), tt.alias, tt.args)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("%s: expected:\n%s\nbut got:\n%s", tt.name, tt.expected, string(got))
+		}
+	}
+
+	// Aliases in an inline table cannot be edited
+	if _, err := editAlias([]byte("alias = {up = [\"get\"]}\n"), "up", []string{"get", "-u"}); err == nil {
+		t.Error("expected error for an inline table")
+	}
+	if _, err := editAlias([]byte(aliasTestTOML), "nonexistent", nil); err == nil {
+		t.Error("expected error for removing a nonexistent alias")
+	}
+}
+
+func TestCheckAlias(t *testing.T) {
+	defer func() { commandNames = nil }()
+	SetCommandNames([]string{"get", "list"})
+
+	aliases := map[string][]string{
+		"up":      {"get", "-u"},
+		"get":     {"get", "-l"},
+		"upup":    {"up"},
+		"unknown": {"foo"},
+		"-x":      {"list"},
+		"empty":   {},
+	}
+	for name, invalid := range map[string]bool{
+		"up":      false,
+		"get":     true,
+		"upup":    true,
+		"unknown": true,
+		"-x":      true,
+		"empty":   true,
+	} {
+		if msg := checkAlias(aliases, name, aliases[name]); (msg != "") != invalid {
+			t.Errorf("%s: expected invalid=%v but got %q", name, invalid, msg)
+		}
+	}
+
+	cfg := &Config{Alias: map[string][]string{"up": {"get", "-u"}, "ls": {"list"}}}
+	if err := ValidateAlias(cfg, "ls", []string{"list", "-json"}); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateAlias(cfg, "get", []string{"list"}); err == nil {
+		t.Error("expected error for an alias overriding a command")
+	}
+	if err := ValidateAlias(cfg, "lsup", []string{"up"}); err == nil {
+		t.Error("expected error for an alias invoking an alias")
+	}
+	cfg.Alias["x"] = []string{"y"}
+	if err := ValidateAlias(cfg, "y", []string{"list"}); err == nil {
+		t.Error("expected error for an alias invoked by another alias")
+	}
+}
@@ -0,0 +1,313 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Problem is a problem of config.toml found by Check().
+type Problem struct {
+	// Key is a dotted key like "get.timeout"
+	Key string
+	// Line is a line number of Key in config.toml (1-origin).
+	// 0 means the line was not found.
+	Line int
+	Msg  string
+}
+
+func (p *Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Msg)
+	}
+	return p.Msg
+}
+
+// valueType is a type of value in config.toml.
+type valueType string
+
+const (
+	stringType      valueType = "string"
+	boolType        valueType = "boolean"
+	intType         valueType = "integer"
+	stringListType  valueType = "array of strings"
+	stringTableType valueType = "table of strings"
+	stringListTable valueType = "table of arrays of strings"
+	credentialTable valueType = "table of credentials"
+)
+
+// knownKeys are all keys of config.toml.
+var knownKeys = map[string]valueType{
+	"alias":                        stringListTable,
+	"build.strategy":               stringType,
+	"build.target":                 stringType,
+	"build.runtime_profile":        boolType,
+	"build.concat_plugin_scripts":  boolType,
+	"build.vim_executable":         stringType,
+	"build.profile_vim_executable": stringTableType,
+	"get.create_skeleton_plugconf": boolType,
+	"get.fallback_git_cmd":         boolType,
+	"get.timeout":                  stringType,
+	"get.clone_url":                stringTableType,
+	"get.repos_clone_url":          stringTableType,
+	"get.max_connections_per_host": intType,
+	"get.max_parallel":             intType,
+	"get.fetch_depth":              intType,
+	"get.fetch_shallow_since":      stringType,
+	"get.retry":                    intType,
+	"get.retry_delay":              stringType,
+	"get.upgrade_groups":           stringListTable,
+	"edit.editor":                  stringType,
+	"repos.alias":                  stringTableType,
+	"notify.enable":                boolType,
+	"notify.interval":              stringType,
+	"network.proxy":                stringType,
+	"self_upgrade.channel":         stringType,
+	"credentials":                  credentialTable,
+}
+
+// Check reads config.toml, and returns the merged configuration and all
+// problems (unknown keys, type mismatches, and invalid values).
+// If config.toml does not exist, the initial configuration is returned.
+// Non-nil error (and nil *Config) is returned if config.toml could not be
+// read or parsed, or there are type mismatches.
+func Check() (*Config, []Problem, error) {
+	configFile := pathutil.ConfigTOML()
+	initCfg := initialConfigTOML()
+	if !pathutil.Exists(configFile) {
+		return initCfg, nil, nil
+	}
+	content, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check keys and types
+	var raw map[string]interface{}
+	if _, err := toml.Decode(string(content), &raw); err != nil {
+		return nil, nil, err
+	}
+	lines := keyLines(content)
+	problems := make([]Problem, 0, 8)
+	if typeErrs := checkTypes(raw, "", lines, &problems); typeErrs > 0 {
+		sortProblems(problems)
+		return nil, problems, fmt.Errorf("%d type mismatch(es) found", typeErrs)
+	}
+
+	// Check values
+	var cfg Config
+	if _, err := toml.Decode(string(content), &cfg); err != nil {
+		return nil, nil, err
+	}
+	merge(&cfg, initCfg)
+	for _, p := range checkValues(&cfg) {
+		p.Line = lines[p.Key]
+		problems = append(problems, p)
+	}
+	sortProblems(problems)
+	return &cfg, problems, nil
+}
+
+// checkTypes appends unknown keys and type mismatches in table to problems,
+// and returns the number of type mismatches.
+func checkTypes(table map[string]interface{}, prefix string, lines map[string]int, problems *[]Problem) int {
+	typeErrs := 0
+	for key, value := range table {
+		fullKey := prefix + key
+		typ, known := knownKeys[fullKey]
+		if !known {
+			if sub, ok := value.(map[string]interface{}); ok && hasKnownKeyPrefix(fullKey+".") {
+				typeErrs += checkTypes(sub, fullKey+".", lines, problems)
+				continue
+			}
+			*problems = append(*problems, Problem{
+				Key:  fullKey,
+				Line: lines[fullKey],
+				Msg:  fmt.Sprintf("unknown key %q", fullKey),
+			})
+			continue
+		}
+		if !isType(value, typ) {
+			*problems = append(*problems, Problem{
+				Key:  fullKey,
+				Line: lines[fullKey],
+				Msg:  fmt.Sprintf("%s must be %s, but got %s", fullKey, typ, typeName(value)),
+			})
+			typeErrs++
+		}
+	}
+	return typeErrs
+}
+
+func hasKnownKeyPrefix(prefix string) bool {
+	for key := range knownKeys {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isType(value interface{}, typ valueType) bool {
+	switch typ {
+	case stringType:
+		_, ok := value.(string)
+		return ok
+	case boolType:
+		_, ok := value.(bool)
+		return ok
+	case intType:
+		_, ok := value.(int64)
+		return ok
+	case stringListType:
+		list, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for i := range list {
+			if _, ok := list[i].(string); !ok {
+				return false
+			}
+		}
+		return true
+	case stringTableType, stringListTable:
+		table, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		elemType := stringType
+		if typ == stringListTable {
+			elemType = stringListType
+		}
+		for _, v := range table {
+			if !isType(v, elemType) {
+				return false
+			}
+		}
+		return true
+	case credentialTable:
+		table, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for _, v := range table {
+			cred, ok := v.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			for key, field := range cred {
+				if !credentialKeys[key] || !isType(field, stringType) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// credentialKeys are the keys of each table of [credentials].
+var credentialKeys = map[string]bool{
+	"username": true,
+	"password": true,
+	"token":    true,
+	"helper":   true,
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case []interface{}, []map[string]interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "table"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// keyLines returns a map from dotted keys to line numbers of content.
+// This supports only key/value pairs and table headers (not inline tables).
+func keyLines(content []byte) map[string]int {
+	lines := make(map[string]int, 16)
+	table := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lnum := 1; scanner.Scan(); lnum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "["):
+			end := strings.Index(line, "]")
+			if end < 0 {
+				continue
+			}
+			table = joinKeys(strings.Trim(line[:end], "[]"))
+			if _, exists := lines[table]; !exists {
+				lines[table] = lnum
+			}
+		default:
+			eq := strings.Index(line, "=")
+			if eq < 0 {
+				continue
+			}
+			key := joinKeys(line[:eq])
+			if table != "" {
+				key = table + "." + key
+			}
+			if _, exists := lines[key]; !exists {
+				lines[key] = lnum
+			}
+		}
+	}
+	return lines
+}
+
+// joinKeys normalizes dotted keys (e.g. ` get . "clone_url" `) to
+// "get.clone_url". Dots in quoted keys are not treated as separators.
+func joinKeys(keys string) string {
+	parts := make([]string, 0, 4)
+	var part []rune
+	var quote rune
+	for _, c := range keys {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			part = append(part, c)
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '.':
+			parts = append(parts, strings.TrimSpace(string(part)))
+			part = part[:0]
+		default:
+			part = append(part, c)
+		}
+	}
+	parts = append(parts, strings.TrimSpace(string(part)))
+	return strings.Join(parts, ".")
+}
+
+func sortProblems(problems []Problem) {
+	// Problems whose line is unknown are sorted last
+	sort.SliceStable(problems, func(i, j int) bool {
+		li, lj := problems[i].Line, problems[j].Line
+		if li != lj {
+			return lj == 0 || (li != 0 && li < lj)
+		}
+		return problems[i].Key < problems[j].Key
+	})
+}
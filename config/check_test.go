@@ -0,0 +1,174 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+const checkTestTOML = `
+[build]
+strategy = "symlink"
+
+[get]
+timeout = 10
+unknown_key = true
+
+[get.clone_url]
+"git.company.com:8443" = "https://git.company.com:8443/scm"
+`
+
+func TestKeyLines(t *testing.T) {
+	lines := keyLines([]byte(checkTestTOML))
+	for key, line := range map[string]int{
+		"build.strategy":                     3,
+		"get.timeout":                        6,
+		"get.unknown_key":                    7,
+		"get.clone_url.git.company.com:8443": 10,
+	} {
+		if lines[key] != line {
+			t.Errorf("%s: expected line %d but got %d", key, line, lines[key])
+		}
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	var raw map[string]interface{}
+	if _, err := toml.Decode(checkTestTOML, &raw); err != nil {
+		t.Fatal(err)
+	}
+	var problems []Problem
+	typeErrs := checkTypes(raw, "", keyLines([]byte(checkTestTOML)), &problems)
+	if typeErrs != 1 {
+		t.Errorf("expected 1 type mismatch but got %d", typeErrs)
+	}
+	sortProblems(problems)
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems but got %v", problems)
+	}
+	if problems[0].Key != "get.timeout" || problems[0].Line != 6 {
+		t.Errorf("unexpected problem: %+v", problems[0])
+	}
+	if problems[1].Key != "get.unknown_key" || problems[1].Line != 7 {
+		t.Errorf("unexpected problem: %+v", problems[1])
+	}
+}
+
+func TestCheckValuesReposAlias(t *testing.T) {
+	cfg := initialConfigTOML()
+	cfg.Repos.Alias = map[string]string{
+		"fzf":      "junegunn/fzf.vim",
+		"foo/bar":  "user/name",
+		"invalid":  "fzf.vim",
+		"with:col": "user/name",
+	}
+	got := make(map[string]bool)
+	for _, p := range checkValues(cfg) {
+		got[p.Key] = true
+	}
+	for key, want := range map[string]bool{
+		"repos.alias.fzf":      false,
+		"repos.alias.foo/bar":  true,
+		"repos.alias.invalid":  true,
+		"repos.alias.with:col": true,
+	} {
+		if got[key] != want {
+			t.Errorf("%s: expected problem %v but got %v", key, want, got[key])
+		}
+	}
+}
+
+func TestCheckValuesConcatPluginScripts(t *testing.T) {
+	trueValue := true
+	cfg := initialConfigTOML()
+	cfg.Build.ConcatPluginScripts = &trueValue
+	problems := checkValues(cfg)
+	if len(problems) != 1 || problems[0].Key != "build.concat_plugin_scripts" {
+		t.Errorf("expected a problem of build.concat_plugin_scripts but got %v", problems)
+	}
+	cfg.Build.Strategy = CopyBuilder
+	if problems := checkValues(cfg); len(problems) != 0 {
+		t.Errorf("expected no problems but got %v", problems)
+	}
+}
+
+func TestCheckValuesSelfUpgradeChannel(t *testing.T) {
+	cfg := initialConfigTOML()
+	for _, channel := range []string{StableChannel, PreChannel} {
+		cfg.SelfUpgrade.Channel = channel
+		if problems := checkValues(cfg); len(problems) != 0 {
+			t.Errorf("expected no problems of %q but got %v", channel, problems)
+		}
+	}
+	cfg.SelfUpgrade.Channel = "nightly"
+	problems := checkValues(cfg)
+	if len(problems) != 1 || problems[0].Key != "self_upgrade.channel" {
+		t.Errorf("expected a problem of self_upgrade.channel but got %v", problems)
+	}
+}
+
+func TestCheckValuesReposCloneURL(t *testing.T) {
+	cfg := initialConfigTOML()
+	cfg.Get.ReposCloneURL = map[string]string{
+		"tyru/caw.vim":          "git@github.com:me/caw.vim.git",
+		"tyru/open-browser.vim": "https://example.com/me/open-browser.vim",
+		"invalid":               "https://example.com/me/invalid",
+		"tyru/no-scheme.vim":    "example.com/me/no-scheme.vim",
+	}
+	got := make(map[string]bool)
+	for _, p := range checkValues(cfg) {
+		got[p.Key] = true
+	}
+	for key, want := range map[string]bool{
+		"get.repos_clone_url.tyru/caw.vim":          false,
+		"get.repos_clone_url.tyru/open-browser.vim": false,
+		"get.repos_clone_url.invalid":               true,
+		"get.repos_clone_url.tyru/no-scheme.vim":    true,
+	} {
+		if got[key] != want {
+			t.Errorf("%s: expected problem %v but got %v", key, want, got[key])
+		}
+	}
+}
+
+func TestCheckValuesUpgradeGroups(t *testing.T) {
+	cfg := initialConfigTOML()
+	cfg.Get.UpgradeGroups = map[string][]string{
+		"lsp":     {"prabirshrestha/vim-lsp", "mattn/vim-lsp-settings"},
+		"invalid": {"invalid"},
+		"dup":     {"github.com/prabirshrestha/vim-lsp"},
+	}
+	got := make(map[string]bool)
+	for _, p := range checkValues(cfg) {
+		got[p.Key] = true
+	}
+	for key, want := range map[string]bool{
+		"get.upgrade_groups.invalid": true,
+		// Groups are checked in order of names, so the repository of "lsp"
+		// which is already in "dup" is reported
+		"get.upgrade_groups.dup": false,
+		"get.upgrade_groups.lsp": true,
+	} {
+		if got[key] != want {
+			t.Errorf("%s: expected problem %v but got %v", key, want, got[key])
+		}
+	}
+}
+
+func TestIsTypeCredentialTable(t *testing.T) {
+	for content, expected := range map[string]bool{
+		"[credentials.\"github.com\"]\ntoken = \"xxx\"\n":                       true,
+		"[credentials.\"github.com\"]\nusername = \"me\"\nhelper = \"store\"\n": true,
+		"[credentials.\"github.com\"]\ntoken = 1\n":                             false,
+		"[credentials.\"github.com\"]\nkey = \"xxx\"\n":                         false,
+		"[credentials]\n\"github.com\" = \"xxx\"\n":                             false,
+	} {
+		var raw map[string]interface{}
+		if _, err := toml.Decode(content, &raw); err != nil {
+			t.Fatal(err)
+		}
+		if got := isType(raw["credentials"], credentialTable); got != expected {
+			t.Errorf("%q: expected %v but got %v", content, expected, got)
+		}
+	}
+}
@@ -1,6 +1,14 @@
 package config
 
 import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/BurntSushi/toml"
 	"github.com/pkg/errors"
 
@@ -9,21 +17,219 @@ import (
 
 // Config is marshallable content of config.toml
 type Config struct {
-	Alias map[string][]string `toml:"alias"`
-	Build configBuild         `toml:"build"`
-	Get   configGet           `toml:"get"`
-	Edit  configEdit          `toml:"edit"`
+	Alias   map[string][]string `toml:"alias"`
+	Build   configBuild         `toml:"build"`
+	Get     configGet           `toml:"get"`
+	Edit    configEdit          `toml:"edit"`
+	Repos   configRepos         `toml:"repos"`
+	Notify  configNotify        `toml:"notify"`
+	Network configNetwork       `toml:"network"`
+	// SelfUpgrade is a config for 'volt self-upgrade'
+	SelfUpgrade configSelfUpgrade `toml:"self_upgrade"`
+	// Credentials is a map from a host (e.g. "github.com") to the credential
+	// to clone or fetch its private repositories
+	Credentials map[string]configCredential `toml:"credentials"`
+}
+
+// configCredential is a credential of a host (e.g. [credentials."github.com"]).
+type configCredential struct {
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	// Token is an access token, which is sent as the password of
+	// Username (default "x-access-token")
+	Token string `toml:"token"`
+	// Helper is a git credential helper (e.g. "store", "osxkeychain",
+	// "!pass show github") which returns the username and the password
+	Helper string `toml:"helper"`
+}
+
+// CredentialOf returns the credential of host (which may have a port).
+// nil is returned if no credential is configured for host.
+func (cfg *Config) CredentialOf(host string) *configCredential {
+	for h, cred := range cfg.Credentials {
+		if strings.EqualFold(h, host) {
+			return &cred
+		}
+	}
+	return nil
+}
+
+// redactedSecret replaces passwords and tokens shown by Redacted().
+const redactedSecret = "********"
+
+// Redacted returns a copy of cfg whose passwords and tokens of
+// [credentials] are replaced, to show the config without secrets.
+func (cfg *Config) Redacted() *Config {
+	redacted := *cfg
+	if cfg.Credentials == nil {
+		return &redacted
+	}
+	redacted.Credentials = make(map[string]configCredential, len(cfg.Credentials))
+	for host, cred := range cfg.Credentials {
+		if cred.Password != "" {
+			cred.Password = redactedSecret
+		}
+		if cred.Token != "" {
+			cred.Token = redactedSecret
+		}
+		redacted.Credentials[host] = cred
+	}
+	return &redacted
 }
 
 // configBuild is a config for 'volt build'.
 type configBuild struct {
-	Strategy string `toml:"strategy"`
+	Strategy            string `toml:"strategy"`
+	Target              string `toml:"target"`
+	RuntimeProfile      *bool  `toml:"runtime_profile"`
+	ConcatPluginScripts *bool  `toml:"concat_plugin_scripts"`
+	VimExecutable       string `toml:"vim_executable"`
+	// ProfileVimExecutable is a map from a profile name to vim executable
+	ProfileVimExecutable map[string]string `toml:"profile_vim_executable"`
+}
+
+// VimExecutableOf returns the vim executable configured for profileName.
+// build.profile_vim_executable takes precedence over build.vim_executable.
+// An empty string means it is not configured.
+func (cfg *configBuild) VimExecutableOf(profileName string) string {
+	if vim := cfg.ProfileVimExecutable[profileName]; vim != "" {
+		return vim
+	}
+	return cfg.VimExecutable
+}
+
+// IsNeovimTarget returns true if "volt build" installs plugins for Neovim.
+// If build.target is "auto", it is true if the vim executable of profileName
+// is Neovim.
+func (cfg *configBuild) IsNeovimTarget(profileName string) bool {
+	switch cfg.Target {
+	case NeovimTarget:
+		return true
+	case AutoTarget:
+		vim, err := pathutil.VimExecutable(cfg.VimExecutableOf(profileName))
+		return err == nil && pathutil.IsNeovim(vim)
+	}
+	return false
 }
 
 // configGet is a config for 'volt get'.
 type configGet struct {
-	CreateSkeletonPlugconf *bool `toml:"create_skeleton_plugconf"`
-	FallbackGitCmd         *bool `toml:"fallback_git_cmd"`
+	CreateSkeletonPlugconf *bool             `toml:"create_skeleton_plugconf"`
+	FallbackGitCmd         *bool             `toml:"fallback_git_cmd"`
+	Timeout                string            `toml:"timeout"`
+	CloneURL               map[string]string `toml:"clone_url"`
+	// ReposCloneURL is a map from a repository to the URL to clone it from
+	// (e.g. a private fork or a mirror)
+	ReposCloneURL         map[string]string `toml:"repos_clone_url"`
+	MaxConnectionsPerHost *int              `toml:"max_connections_per_host"`
+	MaxParallel           *int              `toml:"max_parallel"`
+	FetchDepth            *int              `toml:"fetch_depth"`
+	FetchShallowSince     string            `toml:"fetch_shallow_since"`
+	// Retry is how many times cloning, upgrading, or fetching a plugconf
+	// template is tried again after a network error
+	Retry      *int   `toml:"retry"`
+	RetryDelay string `toml:"retry_delay"`
+	// UpgradeGroups is a map from a group name to the repositories which are
+	// upgraded together by "volt get -u"
+	UpgradeGroups map[string][]string `toml:"upgrade_groups"`
+}
+
+// UpgradeGroupOf returns the name and the repositories of the group of
+// get.upgrade_groups which has reposPath.
+// An empty string and nil are returned if reposPath is not in any group.
+func (cfg *configGet) UpgradeGroupOf(reposPath pathutil.ReposPath) (string, []pathutil.ReposPath) {
+	for _, name := range groupNames(cfg.UpgradeGroups) {
+		members := make([]pathutil.ReposPath, 0, len(cfg.UpgradeGroups[name]))
+		found := false
+		for _, r := range cfg.UpgradeGroups[name] {
+			p, err := pathutil.NormalizeRepos(r)
+			if err != nil {
+				continue
+			}
+			if p.Equals(reposPath) {
+				found = true
+			}
+			members = append(members, p)
+		}
+		if found {
+			return name, members
+		}
+	}
+	return "", nil
+}
+
+// credentialHosts returns the sorted hosts of credentials.
+func credentialHosts(credentials map[string]configCredential) []string {
+	hosts := make([]string, 0, len(credentials))
+	for host := range credentials {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// groupNames returns the sorted names of groups.
+func groupNames(groups map[string][]string) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CloneURLOf returns the URL to clone reposPath from.
+// If reposPath is in get.repos_clone_url, the URL is the value.
+// If the host of reposPath is in get.clone_url, the URL is
+// "{get.clone_url[host]}/{user}/{name}". Otherwise it is "https://{reposPath}".
+func (cfg *configGet) CloneURLOf(reposPath pathutil.ReposPath) string {
+	if u := cfg.reposCloneURL(reposPath); u != "" {
+		return u
+	}
+	host := reposPath.Host()
+	for h, prefix := range cfg.CloneURL {
+		if strings.EqualFold(h, host) {
+			path := strings.TrimPrefix(filepath.ToSlash(reposPath.String()), host+"/")
+			return strings.TrimSuffix(prefix, "/") + "/" + path
+		}
+	}
+	return reposPath.CloneURL()
+}
+
+// HasReposCloneURL returns true if reposPath is in get.repos_clone_url.
+func (cfg *configGet) HasReposCloneURL(reposPath pathutil.ReposPath) bool {
+	return cfg.reposCloneURL(reposPath) != ""
+}
+
+// reposCloneURL returns the URL of reposPath in get.repos_clone_url.
+// The keys can be any format of repository (e.g. "tyru/caw.vim").
+func (cfg *configGet) reposCloneURL(reposPath pathutil.ReposPath) string {
+	for key, u := range cfg.ReposCloneURL {
+		if p, err := pathutil.NormalizeRepos(key); err == nil && p.Equals(reposPath) {
+			return u
+		}
+	}
+	return ""
+}
+
+// TimeoutDuration returns get.timeout as time.Duration.
+// 0 means no timeout.
+func (cfg *configGet) TimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(cfg.Timeout)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// RetryDelayDuration returns get.retry_delay as time.Duration.
+// DefaultRetryDelay is returned if it is invalid.
+func (cfg *configGet) RetryDelayDuration() time.Duration {
+	d, err := time.ParseDuration(cfg.RetryDelay)
+	if err != nil || d < 0 {
+		return DefaultRetryDelay
+	}
+	return d
 }
 
 // configEdit is a config for 'volt edit'.
@@ -31,6 +237,42 @@ type configEdit struct {
 	Editor string `toml:"editor"`
 }
 
+// configNotify is a config for 'volt notify'.
+type configNotify struct {
+	// Enable checks updates in background when volt commands are executed
+	Enable   *bool  `toml:"enable"`
+	Interval string `toml:"interval"`
+}
+
+// IntervalDuration returns notify.interval as time.Duration.
+// DefaultNotifyInterval is returned if it is invalid.
+func (cfg *configNotify) IntervalDuration() time.Duration {
+	d, err := time.ParseDuration(cfg.Interval)
+	if err != nil || d <= 0 {
+		return DefaultNotifyInterval
+	}
+	return d
+}
+
+// configNetwork is a config for network access of all commands.
+type configNetwork struct {
+	// Proxy is the URL of the proxy server for HTTP and HTTPS (e.g.
+	// "http://proxy.example.com:8080"). HTTP_PROXY and HTTPS_PROXY environment
+	// variables are used if it is empty
+	Proxy string `toml:"proxy"`
+}
+
+// configSelfUpgrade is a config for 'volt self-upgrade'.
+type configSelfUpgrade struct {
+	// Channel is the release channel (StableChannel or PreChannel)
+	Channel string `toml:"channel"`
+}
+
+// configRepos is a config for repository arguments of all commands.
+type configRepos struct {
+	Alias map[string]string `toml:"alias"`
+}
+
 const (
 	// SymlinkBuilder creates symlinks when 'volt build'.
 	SymlinkBuilder = "symlink"
@@ -38,20 +280,74 @@ const (
 	CopyBuilder = "copy"
 )
 
+const (
+	// VimTarget installs plugins to ~/.vim/pack/volt when 'volt build'.
+	VimTarget = "vim"
+	// NeovimTarget installs plugins to stdpath('data')/site/pack/volt when
+	// 'volt build'.
+	NeovimTarget = "neovim"
+	// AutoTarget selects VimTarget or NeovimTarget by the vim executable.
+	AutoTarget = "auto"
+)
+
+const (
+	// StableChannel upgrades volt to stable releases only when
+	// 'volt self-upgrade'.
+	StableChannel = "stable"
+	// PreChannel upgrades volt to pre-releases (e.g. "v0.4.0-rc1") too when
+	// 'volt self-upgrade'.
+	PreChannel = "pre"
+)
+
+// DefaultMaxConnectionsPerHost is the default value of
+// get.max_connections_per_host.
+const DefaultMaxConnectionsPerHost = 8
+
+// DefaultMaxParallel is the default value of get.max_parallel.
+const DefaultMaxParallel = 16
+
+// DefaultRetry is the default value of get.retry.
+const DefaultRetry = 2
+
+// DefaultRetryDelay is the default value of get.retry_delay.
+const DefaultRetryDelay = time.Second
+
+// DefaultNotifyInterval is the default value of notify.interval.
+const DefaultNotifyInterval = 24 * time.Hour
+
 func initialConfigTOML() *Config {
 	trueValue := true
 	falseValue := false
+	maxConns := DefaultMaxConnectionsPerHost
+	maxParallel := DefaultMaxParallel
+	fetchDepth := 0
+	retry := DefaultRetry
 	return &Config{
 		Build: configBuild{
-			Strategy: SymlinkBuilder,
+			Strategy:            SymlinkBuilder,
+			Target:              VimTarget,
+			RuntimeProfile:      &falseValue,
+			ConcatPluginScripts: &falseValue,
 		},
 		Get: configGet{
 			CreateSkeletonPlugconf: &trueValue,
 			FallbackGitCmd:         &falseValue,
+			MaxConnectionsPerHost:  &maxConns,
+			MaxParallel:            &maxParallel,
+			FetchDepth:             &fetchDepth,
+			Retry:                  &retry,
+			RetryDelay:             DefaultRetryDelay.String(),
 		},
 		Edit: configEdit{
 			Editor: "",
 		},
+		Notify: configNotify{
+			Enable:   &falseValue,
+			Interval: DefaultNotifyInterval.String(),
+		},
+		SelfUpgrade: configSelfUpgrade{
+			Channel: StableChannel,
+		},
 	}
 }
 
@@ -79,20 +375,241 @@ func merge(cfg, initCfg *Config) {
 	if cfg.Build.Strategy == "" {
 		cfg.Build.Strategy = initCfg.Build.Strategy
 	}
+	if cfg.Build.Target == "" {
+		cfg.Build.Target = initCfg.Build.Target
+	}
+	if cfg.Build.RuntimeProfile == nil {
+		cfg.Build.RuntimeProfile = initCfg.Build.RuntimeProfile
+	}
+	if cfg.Build.ConcatPluginScripts == nil {
+		cfg.Build.ConcatPluginScripts = initCfg.Build.ConcatPluginScripts
+	}
 	if cfg.Get.CreateSkeletonPlugconf == nil {
 		cfg.Get.CreateSkeletonPlugconf = initCfg.Get.CreateSkeletonPlugconf
 	}
 	if cfg.Get.FallbackGitCmd == nil {
 		cfg.Get.FallbackGitCmd = initCfg.Get.FallbackGitCmd
 	}
+	if cfg.Get.MaxConnectionsPerHost == nil {
+		cfg.Get.MaxConnectionsPerHost = initCfg.Get.MaxConnectionsPerHost
+	}
+	if cfg.Get.MaxParallel == nil {
+		cfg.Get.MaxParallel = initCfg.Get.MaxParallel
+	}
+	if cfg.Get.FetchDepth == nil {
+		cfg.Get.FetchDepth = initCfg.Get.FetchDepth
+	}
+	if cfg.Get.Retry == nil {
+		cfg.Get.Retry = initCfg.Get.Retry
+	}
+	if cfg.Get.RetryDelay == "" {
+		cfg.Get.RetryDelay = initCfg.Get.RetryDelay
+	}
 	if cfg.Edit.Editor == "" {
 		cfg.Edit.Editor = initCfg.Edit.Editor
 	}
+	if cfg.Notify.Enable == nil {
+		cfg.Notify.Enable = initCfg.Notify.Enable
+	}
+	if cfg.Notify.Interval == "" {
+		cfg.Notify.Interval = initCfg.Notify.Interval
+	}
+	if cfg.SelfUpgrade.Channel == "" {
+		cfg.SelfUpgrade.Channel = initCfg.SelfUpgrade.Channel
+	}
+}
+
+// scpLikeURLRx matches scp-like syntax of git URL (e.g. "git@host:path").
+var scpLikeURLRx = regexp.MustCompile(`^[^/@:]+@[^/@:]+:`)
+
+// isProxyURL returns true if u is a URL of a HTTP, HTTPS, or SOCKS5 proxy.
+func isProxyURL(u string) bool {
+	p, err := url.Parse(u)
+	if err != nil || p.Host == "" {
+		return false
+	}
+	return p.Scheme == "http" || p.Scheme == "https" || p.Scheme == "socks5"
+}
+
+// isCloneURL returns true if u is a URL with a scheme, or scp-like syntax.
+func isCloneURL(u string) bool {
+	if parsed, err := url.Parse(u); err == nil && parsed.Scheme != "" && parsed.Path != "" {
+		return true
+	}
+	return scpLikeURLRx.MatchString(u)
 }
 
 func validate(cfg *Config) error {
-	if cfg.Build.Strategy != "symlink" && cfg.Build.Strategy != "copy" {
-		return errors.Errorf("build.strategy is %q: valid values are %q or %q", cfg.Build.Strategy, "symlink", "copy")
+	if problems := checkValues(cfg); len(problems) > 0 {
+		return errors.New(problems[0].Msg)
 	}
 	return nil
 }
+
+// checkValues returns problems of invalid values in cfg.
+func checkValues(cfg *Config) []Problem {
+	var problems []Problem
+	if cfg.Build.Strategy != "symlink" && cfg.Build.Strategy != "copy" {
+		problems = append(problems, Problem{
+			Key: "build.strategy",
+			Msg: fmt.Sprintf("build.strategy is %q: valid values are %q or %q", cfg.Build.Strategy, "symlink", "copy"),
+		})
+	}
+	if cfg.Build.Target != VimTarget && cfg.Build.Target != NeovimTarget && cfg.Build.Target != AutoTarget {
+		problems = append(problems, Problem{
+			Key: "build.target",
+			Msg: fmt.Sprintf("build.target is %q: valid values are %q, %q, or %q", cfg.Build.Target, VimTarget, NeovimTarget, AutoTarget),
+		})
+	}
+	if cfg.Build.ConcatPluginScripts != nil && *cfg.Build.ConcatPluginScripts && cfg.Build.Strategy != CopyBuilder {
+		problems = append(problems, Problem{
+			Key: "build.concat_plugin_scripts",
+			Msg: fmt.Sprintf("build.concat_plugin_scripts requires build.strategy = %q", CopyBuilder),
+		})
+	}
+	if cfg.Get.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.Get.Timeout); err != nil || d < 0 {
+			problems = append(problems, Problem{
+				Key: "get.timeout",
+				Msg: fmt.Sprintf("get.timeout is %q: must be a non-negative duration like %q", cfg.Get.Timeout, "5m"),
+			})
+		}
+	}
+	if d, err := time.ParseDuration(cfg.Notify.Interval); cfg.Notify.Interval != "" && (err != nil || d <= 0) {
+		problems = append(problems, Problem{
+			Key: "notify.interval",
+			Msg: fmt.Sprintf("notify.interval is %q: must be a positive duration like %q", cfg.Notify.Interval, "24h"),
+		})
+	}
+	if n := cfg.Get.MaxConnectionsPerHost; n != nil && *n < 0 {
+		problems = append(problems, Problem{
+			Key: "get.max_connections_per_host",
+			Msg: fmt.Sprintf("get.max_connections_per_host is %d: must be a non-negative integer", *n),
+		})
+	}
+	if n := cfg.Get.MaxParallel; n != nil && *n < 0 {
+		problems = append(problems, Problem{
+			Key: "get.max_parallel",
+			Msg: fmt.Sprintf("get.max_parallel is %d: must be a non-negative integer", *n),
+		})
+	}
+	if n := cfg.Get.FetchDepth; n != nil && *n < 0 {
+		problems = append(problems, Problem{
+			Key: "get.fetch_depth",
+			Msg: fmt.Sprintf("get.fetch_depth is %d: must be a non-negative integer", *n),
+		})
+	}
+	if n := cfg.Get.Retry; n != nil && *n < 0 {
+		problems = append(problems, Problem{
+			Key: "get.retry",
+			Msg: fmt.Sprintf("get.retry is %d: must be a non-negative integer", *n),
+		})
+	}
+	if d, err := time.ParseDuration(cfg.Get.RetryDelay); cfg.Get.RetryDelay != "" && (err != nil || d < 0) {
+		problems = append(problems, Problem{
+			Key: "get.retry_delay",
+			Msg: fmt.Sprintf("get.retry_delay is %q: must be a non-negative duration like %q", cfg.Get.RetryDelay, "1s"),
+		})
+	}
+	if cfg.SelfUpgrade.Channel != StableChannel && cfg.SelfUpgrade.Channel != PreChannel {
+		problems = append(problems, Problem{
+			Key: "self_upgrade.channel",
+			Msg: fmt.Sprintf("self_upgrade.channel is %q: valid values are %q or %q", cfg.SelfUpgrade.Channel, StableChannel, PreChannel),
+		})
+	}
+	if cfg.Network.Proxy != "" && !isProxyURL(cfg.Network.Proxy) {
+		problems = append(problems, Problem{
+			Key: "network.proxy",
+			Msg: fmt.Sprintf("network.proxy is %q: must be a URL like %q", cfg.Network.Proxy, "http://proxy.example.com:8080"),
+		})
+	}
+	for _, host := range credentialHosts(cfg.Credentials) {
+		cred := cfg.Credentials[host]
+		key := "credentials." + host
+		switch {
+		case cred.Helper != "" && (cred.Password != "" || cred.Token != ""):
+			problems = append(problems, Problem{
+				Key: key,
+				Msg: fmt.Sprintf("%s: helper cannot be used with password or token", key),
+			})
+		case cred.Password != "" && cred.Token != "":
+			problems = append(problems, Problem{
+				Key: key,
+				Msg: fmt.Sprintf("%s: password and token cannot be used together", key),
+			})
+		case cred.Helper == "" && cred.Password == "" && cred.Token == "":
+			problems = append(problems, Problem{
+				Key: key,
+				Msg: fmt.Sprintf("%s: password, token, or helper is required", key),
+			})
+		}
+	}
+	if n := cfg.Get.FetchDepth; n != nil && *n > 0 && cfg.Get.FetchShallowSince != "" {
+		problems = append(problems, Problem{
+			Key: "get.fetch_shallow_since",
+			Msg: "get.fetch_depth and get.fetch_shallow_since cannot be used together",
+		})
+	}
+	for host, prefix := range cfg.Get.CloneURL {
+		if u, err := url.Parse(prefix); err != nil || u.Scheme == "" {
+			problems = append(problems, Problem{
+				Key: "get.clone_url." + host,
+				Msg: fmt.Sprintf("get.clone_url.%q is %q: must be a URL like %q", host, prefix, "https://"+host+"/scm"),
+			})
+		}
+	}
+	for key, u := range cfg.Get.ReposCloneURL {
+		if _, err := pathutil.NormalizeRepos(key); err != nil {
+			problems = append(problems, Problem{
+				Key: "get.repos_clone_url." + key,
+				Msg: fmt.Sprintf("get.repos_clone_url.%q: must be a repository like %q", key, "tyru/caw.vim"),
+			})
+		} else if !isCloneURL(u) {
+			problems = append(problems, Problem{
+				Key: "get.repos_clone_url." + key,
+				Msg: fmt.Sprintf("get.repos_clone_url.%q is %q: must be a URL like %q or %q", key, u, "https://example.com/user/caw.vim", "git@example.com:user/caw.vim.git"),
+			})
+		}
+	}
+	groupOf := make(map[pathutil.ReposPath]string)
+	for _, name := range groupNames(cfg.Get.UpgradeGroups) {
+		key := "get.upgrade_groups." + name
+		for _, r := range cfg.Get.UpgradeGroups[name] {
+			p, err := pathutil.NormalizeRepos(r)
+			if err != nil {
+				problems = append(problems, Problem{
+					Key: key,
+					Msg: fmt.Sprintf("get.upgrade_groups.%q has %q: must be a repository like %q", name, r, "tyru/caw.vim"),
+				})
+				continue
+			}
+			if other, exists := groupOf[p]; exists {
+				problems = append(problems, Problem{
+					Key: key,
+					Msg: fmt.Sprintf("get.upgrade_groups.%q has %q: it is already in group %q", name, r, other),
+				})
+				continue
+			}
+			groupOf[p] = name
+		}
+	}
+	for name, args := range cfg.Alias {
+		if msg := checkAlias(cfg.Alias, name, args); msg != "" {
+			problems = append(problems, Problem{Key: "alias." + name, Msg: msg})
+		}
+	}
+	for name, reposPath := range cfg.Repos.Alias {
+		if name == "" || strings.ContainsAny(name, "/:") {
+			problems = append(problems, Problem{
+				Key: "repos.alias." + name,
+				Msg: fmt.Sprintf("repos.alias.%q: alias name must not be empty or contain '/' or ':'", name),
+			})
+		} else if _, err := pathutil.NormalizeRepos(reposPath); err != nil {
+			problems = append(problems, Problem{
+				Key: "repos.alias." + name,
+				Msg: fmt.Sprintf("repos.alias.%q is %q: must be a repository like %q", name, reposPath, "junegunn/fzf.vim"),
+			})
+		}
+	}
+	return problems
+}
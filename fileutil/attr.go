@@ -0,0 +1,23 @@
+package fileutil
+
+import (
+	"os"
+	"time"
+)
+
+// copyAttrs sets the permission bits of dst to perm, and the modification
+// time of dst to the one of srcInfo.
+// The permission bits are set explicitly because os.OpenFile() and
+// os.MkdirAll() are affected by umask, and do not change the mode of an
+// existing file.
+func copyAttrs(dst string, perm os.FileMode, srcInfo os.FileInfo) error {
+	if err := os.Chmod(dst, perm.Perm()); err != nil {
+		return err
+	}
+	return SetModTime(dst, srcInfo.ModTime())
+}
+
+// SetModTime sets the access and modification time of path to mtime.
+func SetModTime(path string, mtime time.Time) error {
+	return os.Chtimes(path, mtime, mtime)
+}
@@ -0,0 +1,62 @@
+package fileutil
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCopyPreservesAttrs(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	mtime := time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC)
+	src := filepath.Join(tempDir, "src")
+	script := filepath.Join(src, "bin", "helper.sh")
+	os.MkdirAll(filepath.Dir(script), 0755)
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("failed to write %s: %s", script, err)
+	}
+	for _, path := range []string{script, filepath.Dir(script), src} {
+		if err := SetModTime(path, mtime); err != nil {
+			t.Fatalf("failed to set mtime of %s: %s", path, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		copy func(dst string) error
+	}{
+		{"CopyDir", func(dst string) error {
+			return CopyDir(src, dst, nil, 0755, 0)
+		}},
+		{"CopyDirParallel", func(dst string) error {
+			return CopyDirParallel(context.Background(), src, dst, 0755, 0, 0, nil)
+		}},
+	} {
+		dst := filepath.Join(tempDir, tt.name)
+		if err := tt.copy(dst); err != nil {
+			t.Fatalf("%s: failed to copy: %s", tt.name, err)
+		}
+		for _, rel := range []string{"", "bin", filepath.Join("bin", "helper.sh")} {
+			fi, err := os.Stat(filepath.Join(dst, rel))
+			if err != nil {
+				t.Errorf("%s: %s was not copied: %s", tt.name, rel, err)
+				continue
+			}
+			if !fi.ModTime().Equal(mtime) {
+				t.Errorf("%s: expected mtime of %q is %s but got %s", tt.name, rel, mtime, fi.ModTime())
+			}
+			if runtime.GOOS != "windows" && fi.Mode().Perm() != 0755 {
+				t.Errorf("%s: expected mode of %q is %s but got %s", tt.name, rel, os.FileMode(0755), fi.Mode().Perm())
+			}
+		}
+	}
+}
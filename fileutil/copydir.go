@@ -34,8 +34,6 @@ import (
 	"path/filepath"
 )
 
-// TODO: Parallelism
-
 // CopyDir recursively copies a directory tree, attempting to preserve permissions.
 // Source directory must exist, destination directory must *not* exist.
 func CopyDir(src, dst string, buf []byte, perm os.FileMode, ignoreType os.FileMode) error {
@@ -71,5 +69,12 @@ func CopyDir(src, dst string, buf []byte, perm os.FileMode, ignoreType os.FileMo
 			}
 		}
 	}
-	return nil
+
+	// Preserve the mode and modification time of src after its entries were
+	// copied, because creating entries updates the modification time of dst
+	si, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return copyAttrs(dst, perm, si)
 }
@@ -0,0 +1,160 @@
+package fileutil
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// ProgressFunc is called each time a file was copied by CopyDirParallel or
+// TryLinkDirParallel. path is the destination path of the copied file,
+// done is the number of copied files so far, and total is the number of all
+// files to be copied.
+// The calls are serialized, so the function need not be goroutine-safe.
+type ProgressFunc func(path string, done, total int)
+
+type copyFileFunc func(src, dst string, buf []byte, perm os.FileMode) error
+
+type copyJob struct {
+	src  string
+	dst  string
+	perm os.FileMode
+	info os.FileInfo
+}
+
+// CopyDirParallel is same as CopyDir, but copies files with up to workers
+// goroutines. If workers <= 0, runtime.NumCPU() is used.
+// progress may be nil.
+// If ctx is done, remaining files are not copied and ctx.Err() is returned.
+func CopyDirParallel(ctx context.Context, src, dst string, perm os.FileMode, ignoreType os.FileMode, workers int, progress ProgressFunc) error {
+	return copyDirParallel(ctx, src, dst, perm, ignoreType, workers, progress, CopyFile)
+}
+
+// TryLinkDirParallel is same as TryLinkDir, but links or copies files with
+// up to workers goroutines. If workers <= 0, runtime.NumCPU() is used.
+// progress may be nil.
+// If ctx is done, remaining files are not linked and ctx.Err() is returned.
+func TryLinkDirParallel(ctx context.Context, src, dst string, perm os.FileMode, ignoreType os.FileMode, workers int, progress ProgressFunc) error {
+	return copyDirParallel(ctx, src, dst, perm, ignoreType, workers, progress, TryLinkFile)
+}
+
+func copyDirParallel(ctx context.Context, src, dst string, perm os.FileMode, ignoreType os.FileMode, workers int, progress ProgressFunc, copyFile copyFileFunc) error {
+	// Create directories first, and collect files to be copied
+	jobs := make([]copyJob, 0, 64)
+	dirs := make([]copyJob, 0, 16)
+	if err := collectCopyJobs(src, dst, perm, ignoreType, &jobs, &dirs); err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		return copyDirAttrs(dirs)
+	}
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	jobCh := make(chan copyJob, len(jobs))
+	for i := range jobs {
+		jobCh <- jobs[i]
+	}
+	close(jobCh)
+
+	var (
+		mu   sync.Mutex
+		merr *multierror.Error
+		done int
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Avoid allocating in io.Copy() in CopyFile() each time
+			buf := make([]byte, 32*1024)
+			for job := range jobCh {
+				if ctx.Err() != nil {
+					continue
+				}
+				err := copyFile(job.src, job.dst, buf, job.perm)
+				mu.Lock()
+				if err != nil {
+					merr = multierror.Append(merr, err)
+				} else {
+					done++
+					if progress != nil {
+						progress(job.dst, done, len(jobs))
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if merr.ErrorOrNil() != nil {
+		return merr
+	}
+	return copyDirAttrs(dirs)
+}
+
+// copyDirAttrs preserves the mode and modification time of directories after
+// all entries were copied, because creating entries updates the modification
+// time of directories.
+func copyDirAttrs(dirs []copyJob) error {
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := copyAttrs(dirs[i].dst, dirs[i].perm, dirs[i].info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectCopyJobs creates dst directory tree of src, and appends files under
+// src to jobs, directories to dirs.
+func collectCopyJobs(src, dst string, perm os.FileMode, ignoreType os.FileMode, jobs *[]copyJob, dirs *[]copyJob) error {
+	if err := os.MkdirAll(dst, perm); err != nil {
+		return err
+	}
+	si, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	*dirs = append(*dirs, copyJob{src: src, dst: dst, perm: perm, info: si})
+
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		if entries[i].Mode()&ignoreType != 0 {
+			continue
+		}
+
+		srcPath := filepath.Join(src, entries[i].Name())
+		dstPath := filepath.Join(dst, entries[i].Name())
+
+		if entries[i].IsDir() {
+			if err = collectCopyJobs(srcPath, dstPath, entries[i].Mode(), ignoreType, jobs, dirs); err != nil {
+				return err
+			}
+		} else {
+			*jobs = append(*jobs, copyJob{
+				src:  srcPath,
+				dst:  dstPath,
+				perm: entries[i].Mode(),
+			})
+		}
+	}
+	return nil
+}
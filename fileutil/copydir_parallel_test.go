@@ -0,0 +1,89 @@
+package fileutil
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyDirParallel(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	src := filepath.Join(tempDir, "src")
+	files := []string{
+		"plugin/foo.vim",
+		"autoload/foo.vim",
+		"autoload/foo/bar.vim",
+		"doc/foo.txt",
+		"README.md",
+	}
+	for _, name := range files {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", path, err)
+		}
+	}
+
+	for _, workers := range []int{0, 1, 2, 100} {
+		dst := filepath.Join(tempDir, "dst")
+		calls := 0
+		err := CopyDirParallel(context.Background(), src, dst, 0755, 0, workers, func(path string, done, total int) {
+			calls++
+			if done != calls {
+				t.Errorf("workers=%d: expected done=%d but got %d", workers, calls, done)
+			}
+			if total != len(files) {
+				t.Errorf("workers=%d: expected total=%d but got %d", workers, len(files), total)
+			}
+		})
+		if err != nil {
+			t.Fatalf("workers=%d: CopyDirParallel failed: %s", workers, err)
+		}
+		if calls != len(files) {
+			t.Errorf("workers=%d: expected %d progress calls but got %d", workers, len(files), calls)
+		}
+		for _, name := range files {
+			content, err := ioutil.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+			if err != nil {
+				t.Errorf("workers=%d: %s was not copied: %s", workers, name, err)
+				continue
+			}
+			if string(content) != name {
+				t.Errorf("workers=%d: expected content of %s is %q but got %q", workers, name, name, string(content))
+			}
+		}
+		os.RemoveAll(dst)
+	}
+}
+
+func TestCopyDirParallelCanceled(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	src := filepath.Join(tempDir, "src")
+	os.MkdirAll(src, 0755)
+	if err := ioutil.WriteFile(filepath.Join(src, "foo.vim"), []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dst := filepath.Join(tempDir, "dst")
+	err = CopyDirParallel(ctx, src, dst, 0755, 0, 0, nil)
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled but got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "foo.vim")); err == nil {
+		t.Errorf("expected foo.vim is not copied after canceled")
+	}
+}
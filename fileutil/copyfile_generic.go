@@ -38,34 +38,52 @@ import (
 // CopyFile copies the contents of the file named src to the file named
 // by dst. The file will be created if it does not already exist. If the
 // destination file exists, all it's contents will be replaced by the contents
-// of the source file. The file mode is set to perm and
-// the copied data is synced/flushed to stable storage.
-func CopyFile(src, dst string, buf []byte, perm os.FileMode) (err error) {
+// of the source file. The file mode is set to perm, the modification time is
+// set to the one of src, and the copied data is synced/flushed to stable
+// storage.
+func CopyFile(src, dst string, buf []byte, perm os.FileMode) error {
+	fi, err := copyContents(src, dst, buf, perm)
+	if err != nil {
+		return err
+	}
+	return copyAttrs(dst, perm, fi)
+}
+
+// copyContents copies the contents of src to dst, and returns os.FileInfo of
+// src.
+func copyContents(src, dst string, buf []byte, perm os.FileMode) (_ os.FileInfo, err error) {
 	r, err := os.Open(src)
 	if err != nil {
-		return
+		return nil, err
 	}
 	defer func() {
-		if e := r.Close(); e != nil {
+		if e := r.Close(); e != nil && err == nil {
 			err = e
 		}
 	}()
+	fi, err := r.Stat()
+	if err != nil {
+		return nil, err
+	}
 
 	w, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
-		return
+		return nil, err
 	}
 	defer func() {
-		if e := w.Close(); e != nil {
+		if e := w.Close(); e != nil && err == nil {
 			err = e
 		}
 	}()
 
 	_, err = io.CopyBuffer(w, r, buf)
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	err = w.Sync()
-	return
+	if err != nil {
+		return nil, err
+	}
+	return fi, nil
 }
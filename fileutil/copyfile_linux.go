@@ -40,21 +40,42 @@ import (
 // CopyFile copies the contents of the file named src to the file named
 // by dst. The file will be created if it does not already exist. If the
 // destination file exists, all it's contents will be replaced by the contents
-// of the source file. The file mode is set to perm and
-// the copied data is synced/flushed to stable storage.
+// of the source file. The file mode is set to perm, the modification time is
+// set to the one of src, and the copied data is synced/flushed to stable
+// storage.
 func CopyFile(src, dst string, buf []byte, perm os.FileMode) error {
-	r, err := os.Open(src)
+	fi, err := sendFile(src, dst, perm)
 	if err != nil {
 		return err
 	}
+	return copyAttrs(dst, perm, fi)
+}
+
+// sendFile copies the contents of src to dst by sendfile(2), and returns
+// os.FileInfo of src.
+func sendFile(src, dst string, perm os.FileMode) (_ os.FileInfo, err error) {
+	r, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := r.Close(); e != nil && err == nil {
+			err = e
+		}
+	}()
 	fi, err := r.Stat()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	w, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer func() {
+		if e := w.Close(); e != nil && err == nil {
+			err = e
+		}
+	}()
 
 	wfd := int(w.Fd())
 	rfd := int(r.Fd())
@@ -64,7 +85,7 @@ func CopyFile(src, dst string, buf []byte, perm os.FileMode) error {
 		for {
 			n, err := syscall.Sendfile(wfd, rfd, nil, readsize)
 			if err != nil {
-				return errors.Errorf("sendfile(%q, %q) failed: %s", src, dst, err.Error())
+				return nil, errors.Errorf("sendfile(%q, %q) failed: %s", src, dst, err.Error())
 			}
 			written += int64(n)
 			if written >= fi.Size() {
@@ -73,8 +94,8 @@ func CopyFile(src, dst string, buf []byte, perm os.FileMode) error {
 		}
 	} else {
 		if _, err := syscall.Sendfile(wfd, rfd, nil, int(fi.Size())); err != nil {
-			return errors.Errorf("sendfile(%q, %q) failed: %s", src, dst, err.Error())
+			return nil, errors.Errorf("sendfile(%q, %q) failed: %s", src, dst, err.Error())
 		}
 	}
-	return nil
+	return fi, nil
 }
@@ -68,7 +68,14 @@ func TryLinkDir(src, dst string, buf []byte, perm os.FileMode, ignoreType os.Fil
 			}
 		}
 	}
-	return nil
+
+	// Preserve the mode and modification time of src after its entries were
+	// copied, because creating entries updates the modification time of dst
+	si, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return copyAttrs(dst, perm, si)
 }
 
 // TryLinkFile tries os.Link() at first, but if it failed call CopyFile to copy
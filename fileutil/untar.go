@@ -0,0 +1,196 @@
+package fileutil
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ExtractTarGz extracts gzipped tar archive r to dst directory.
+// The first stripComponents path elements of each entry are removed
+// (like "tar --strip-components"), entries which become empty are skipped.
+// Regular files, directories and symlinks are extracted, and other entries
+// are ignored. Non-nil error is returned if an entry points outside of dst,
+// or is written through a symlink extracted before.
+func ExtractTarGz(r io.Reader, dst string, stripComponents int) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return checkSymlinks(dst)
+		}
+		if err != nil {
+			return err
+		}
+
+		name, err := entryName(hdr.Name, stripComponents)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		target := filepath.Join(dst, filepath.FromSlash(name))
+		if err := checkNoSymlink(dst, name); err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := writeFile(target, tr, os.FileMode(hdr.Mode).Perm()|0600); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := writeSymlink(target, name, hdr.Linkname); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// entryName returns the path of the entry of an archive relative to the
+// destination directory, or "" if it becomes empty by stripComponents.
+// Backslashes and volume names (e.g. "C:") are rejected because they are
+// separators or absolute paths on Windows.
+func entryName(rawName string, stripComponents int) (string, error) {
+	name := path.Clean(strings.TrimLeft(rawName, "/"))
+	if name == ".." || strings.HasPrefix(name, "../") ||
+		strings.Contains(name, `\`) || hasVolumeName(name) {
+		return "", errors.New("invalid path in archive: " + rawName)
+	}
+	elems := strings.Split(name, "/")
+	if len(elems) <= stripComponents {
+		return "", nil
+	}
+	name = strings.Join(elems[stripComponents:], "/")
+	if hasVolumeName(name) {
+		return "", errors.New("invalid path in archive: " + rawName)
+	}
+	return name, nil
+}
+
+// hasVolumeName returns true if name starts with a drive letter (e.g. "C:"),
+// which is a volume name on Windows.
+func hasVolumeName(name string) bool {
+	if len(name) < 2 || name[1] != ':' {
+		return false
+	}
+	c := name[0] | 0x20 // lower case
+	return 'a' <= c && c <= 'z'
+}
+
+// writeSymlink creates a symlink of the entry name to linkname at target.
+// Non-nil error is returned if linkname points outside of the destination.
+func writeSymlink(target, name, linkname string) error {
+	link := path.Join(path.Dir(name), linkname)
+	if path.IsAbs(linkname) || link == ".." || strings.HasPrefix(link, "../") ||
+		strings.Contains(linkname, `\`) || hasVolumeName(linkname) {
+		return errors.New("invalid symlink in archive: " + name + " -> " + linkname)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.Symlink(linkname, target)
+}
+
+// checkNoSymlink returns non-nil error if name or its parent directories
+// under dst are symlinks. Entries must not be written through symlinks
+// extracted before, because symlinks may point outside of dst by other
+// symlinks (e.g. "x/y -> .." and "z -> x/y/..").
+func checkNoSymlink(dst, name string) error {
+	p := dst
+	for _, elem := range strings.Split(name, "/") {
+		p = filepath.Join(p, elem)
+		fi, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return errors.New("invalid path in archive: " + name + " is written through symlink")
+		}
+	}
+	return nil
+}
+
+// checkSymlinks returns non-nil error if any symlinks under dst point
+// outside of dst after they are resolved.
+func checkSymlinks(dst string) error {
+	if !exists(dst) {
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(dst)
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(dst, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+		resolved, err := filepath.EvalSymlinks(p)
+		if os.IsNotExist(err) {
+			// Dangling symlinks are resolved lexically
+			linkname, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			if filepath.IsAbs(linkname) {
+				resolved = linkname
+			} else {
+				resolved = filepath.Join(filepath.Dir(p), linkname)
+				if r, err := filepath.Rel(dst, resolved); err == nil {
+					resolved = filepath.Join(root, r)
+				}
+			}
+		} else if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, resolved)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			rel, _ := filepath.Rel(dst, p)
+			return errors.New("invalid symlink in archive: " + filepath.ToSlash(rel) + " points outside of the destination")
+		}
+		return nil
+	})
+}
+
+func exists(p string) bool {
+	_, err := os.Lstat(p)
+	return err == nil
+}
+
+func writeFile(target string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
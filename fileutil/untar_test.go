@@ -0,0 +1,125 @@
+package fileutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+func makeTarGz(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Mode:     0644,
+			Size:     int64(len(e.body)),
+			Linkname: e.linkname,
+		}
+		if e.typeflag != tar.TypeReg {
+			hdr.Size = 0
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if e.typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractTarGz(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	archive := makeTarGz(t, []tarEntry{
+		{name: "user-name-abc1234/", typeflag: tar.TypeDir},
+		{name: "user-name-abc1234/plugin/", typeflag: tar.TypeDir},
+		{name: "user-name-abc1234/plugin/name.vim", typeflag: tar.TypeReg, body: "echo 'hello'\n"},
+		{name: "user-name-abc1234/autoload/name.vim", typeflag: tar.TypeReg, body: "\" generated\n"},
+		{name: "user-name-abc1234/doc", typeflag: tar.TypeSymlink, linkname: "plugin"},
+	})
+	dst := filepath.Join(tempDir, "dst")
+	if err := ExtractTarGz(archive, dst, 1); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"plugin/name.vim":   "echo 'hello'\n",
+		"autoload/name.vim": "\" generated\n",
+		"doc/name.vim":      "echo 'hello'\n",
+	} {
+		got, err := ioutil.ReadFile(filepath.Join(dst, filepath.FromSlash(path)))
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+		} else if string(got) != want {
+			t.Errorf("%s: expected %q but got %q", path, want, got)
+		}
+	}
+}
+
+func TestExtractTarGzOutsideOfDst(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, entries := range [][]tarEntry{
+		{{name: "top/../../evil.vim", typeflag: tar.TypeReg, body: "evil"}},
+		{{name: "top/link", typeflag: tar.TypeSymlink, linkname: "../.."}},
+		{{name: "top/link", typeflag: tar.TypeSymlink, linkname: "/etc"}},
+		{{name: `top/..\..\evil.vim`, typeflag: tar.TypeReg, body: "evil"}},
+		{{name: `..\..\evil.vim`, typeflag: tar.TypeReg, body: "evil"}},
+		{{name: `C:\evil.vim`, typeflag: tar.TypeReg, body: "evil"}},
+		{{name: "top/C:/evil.vim", typeflag: tar.TypeReg, body: "evil"}},
+		{{name: "top/link", typeflag: tar.TypeSymlink, linkname: `..\..`}},
+		{{name: "top/link", typeflag: tar.TypeSymlink, linkname: "C:/evil"}},
+		// "z" points to the parent of dst through "x/y"
+		{
+			{name: "top/x/", typeflag: tar.TypeDir},
+			{name: "top/x/y", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "top/z", typeflag: tar.TypeSymlink, linkname: "x/y/.."},
+			{name: "top/z/evil.vim", typeflag: tar.TypeReg, body: "evil"},
+		},
+		{
+			{name: "top/x/", typeflag: tar.TypeDir},
+			{name: "top/x/y", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "top/z", typeflag: tar.TypeSymlink, linkname: "x/y/.."},
+		},
+	} {
+		dst := filepath.Join(tempDir, "dst")
+		if err := ExtractTarGz(makeTarGz(t, entries), dst, 1); err == nil {
+			t.Errorf("expected error for %+v", entries)
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, "evil.vim")); err == nil {
+			t.Errorf("evil.vim was extracted outside of dst")
+		}
+		os.RemoveAll(dst)
+	}
+}
@@ -0,0 +1,68 @@
+package fileutil
+
+import (
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ExtractZip extracts zip archive r of size bytes to dst directory.
+// stripComponents, the extracted entries, and errors are same as
+// ExtractTarGz().
+func ExtractZip(r io.ReaderAt, size int64, dst string, stripComponents int) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		name, err := entryName(f.Name, stripComponents)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		target := filepath.Join(dst, filepath.FromSlash(name))
+		if err := checkNoSymlink(dst, name); err != nil {
+			return err
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			linkname, err := readZipFile(f)
+			if err != nil {
+				return err
+			}
+			if err := writeSymlink(target, name, string(linkname)); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = writeFile(target, rc, mode.Perm()|0600)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return checkSymlinks(dst)
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
@@ -0,0 +1,104 @@
+package fileutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func makeZip(t *testing.T, files map[string]string) *bytes.Reader {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestExtractZip(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"name-1.0/plugin/name.vim":   "echo 'hello'\n",
+		"name-1.0/autoload/name.vim": "\" generated\n",
+	}
+	archive := makeZip(t, files)
+	dst := filepath.Join(tempDir, "dst")
+	if err := ExtractZip(archive, archive.Size(), dst, 1); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range files {
+		path := filepath.Join(dst, filepath.FromSlash(name[len("name-1.0/"):]))
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+		} else if string(got) != want {
+			t.Errorf("%s: expected %q but got %q", path, want, got)
+		}
+	}
+
+	for _, name := range []string{"top/../../evil.vim", `top/..\..\evil.vim`, `..\..\evil.vim`, `C:\evil.vim`, "top/C:/evil.vim"} {
+		evil := makeZip(t, map[string]string{name: "evil"})
+		if err := ExtractZip(evil, evil.Size(), filepath.Join(tempDir, "evil"), 1); err == nil {
+			t.Errorf("expected error for the path outside of dst: %s", name)
+		}
+	}
+}
+
+func TestExtractZipSymlinkChain(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	// "z" points to the parent of dst through "x/y"
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range []struct {
+		name string
+		mode os.FileMode
+		body string
+	}{
+		{"top/x/", os.ModeDir | 0755, ""},
+		{"top/x/y", os.ModeSymlink | 0777, ".."},
+		{"top/z", os.ModeSymlink | 0777, "x/y/.."},
+		{"top/z/evil.vim", 0644, "evil"},
+	} {
+		hdr := &zip.FileHeader{Name: e.name}
+		hdr.SetMode(e.mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := bytes.NewReader(buf.Bytes())
+	if err := ExtractZip(archive, archive.Size(), filepath.Join(tempDir, "dst"), 1); err == nil {
+		t.Error("expected error for the symlink chain")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "evil.vim")); err == nil {
+		t.Error("evil.vim was extracted outside of dst")
+	}
+}
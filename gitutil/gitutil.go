@@ -2,16 +2,70 @@ package gitutil
 
 import (
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/vim-volt/volt/pathutil"
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/client"
 )
 
 var refHeadsRx = regexp.MustCompile(`^refs/heads/(.+)$`)
 
+// ErrEmptyRepository is returned when HEAD of a repository refers to a branch
+// which has no commits (e.g. the upstream repository was just created, or the
+// repository was just initialized by "git init").
+var ErrEmptyRepository = errors.New("repository has no commits yet")
+
+// IsEmptyRepository returns true if HEAD of r refers to a branch which has no
+// commits.
+func IsEmptyRepository(r *git.Repository) bool {
+	_, err := r.Head()
+	return err == plumbing.ErrReferenceNotFound
+}
+
+// resolveHEAD returns the reference which HEAD refers to.
+// ErrEmptyRepository is returned if the reference does not exist.
+func resolveHEAD(r *git.Repository) (*plumbing.Reference, error) {
+	head, err := r.Head()
+	if err == plumbing.ErrReferenceNotFound {
+		return nil, ErrEmptyRepository
+	}
+	return head, err
+}
+
+// headBranch returns the branch name which HEAD refers to (e.g.
+// "refs/heads/master"), even if the repository is empty.
+func headBranch(r *git.Repository) (string, error) {
+	head, err := r.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", err
+	}
+	refBranch := head.Name().String()
+	if head.Type() == plumbing.SymbolicReference {
+		refBranch = head.Target().String()
+	}
+	if !refHeadsRx.MatchString(refBranch) {
+		return "", errors.New("HEAD is not matched to refs/heads/...: " + refBranch)
+	}
+	return refBranch, nil
+}
+
+// IsDetachedHEAD returns true if HEAD of r does not refer to a branch (e.g. a
+// tag or a commit is checked out).
+func IsDetachedHEAD(r *git.Repository) (bool, error) {
+	head, err := r.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return false, err
+	}
+	return head.Type() != plumbing.SymbolicReference, nil
+}
+
 // GetHEAD gets HEAD reference hash string from reposPath.
 // See GetHEADRepository.
 func GetHEAD(reposPath pathutil.ReposPath) (string, error) {
@@ -28,8 +82,9 @@ func GetHEAD(reposPath pathutil.ReposPath) (string, error) {
 //   where {branch} is default branch
 // If the repository is non-bare:
 //   Return the reference of current branch's HEAD
+// If the repository has no commits, ErrEmptyRepository is returned.
 func GetHEADRepository(repos *git.Repository) (string, error) {
-	head, err := repos.Head()
+	head, err := resolveHEAD(repos)
 	if err != nil {
 		return "", err
 	}
@@ -57,33 +112,84 @@ func GetHEADRepository(repos *git.Repository) (string, error) {
 
 	// Get reference of remote origin/{branch} HEAD
 	ref, err := repos.Reference(plumbing.ReferenceName("refs/remotes/origin/"+defaultBranch[1]), true)
-	if err != nil {
+	if err == plumbing.ErrReferenceNotFound {
+		return "", ErrEmptyRepository
+	} else if err != nil {
 		return "", err
 	}
 	return ref.Hash().String(), nil
 }
 
+// CountCommits counts commits which are reachable from toHash but not from
+// fromHash (like "git rev-list --count fromHash..toHash"), and returns the
+// count and the newest committer date of them.
+// If no commits are found, newest is zero time.
+// Parents which do not exist in a shallow repository are ignored.
+func CountCommits(reposPath pathutil.ReposPath, fromHash, toHash string) (count int, newest time.Time, err error) {
+	repos, err := git.PlainOpen(reposPath.FullPath())
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	// Collect commits reachable from fromHash, and skip them while walking
+	// commits from toHash
+	seen := make(map[plumbing.Hash]bool, 256)
+	err = walkCommits(repos, plumbing.NewHash(fromHash), seen, func(*object.Commit) {})
+	if err != nil {
+		return 0, time.Time{}, errors.Wrap(err, "could not walk commits from "+fromHash)
+	}
+	err = walkCommits(repos, plumbing.NewHash(toHash), seen, func(c *object.Commit) {
+		count++
+		if c.Committer.When.After(newest) {
+			newest = c.Committer.When
+		}
+	})
+	if err != nil {
+		return 0, time.Time{}, errors.Wrap(err, "could not walk commits from "+toHash)
+	}
+	return count, newest, nil
+}
+
+// walkCommits calls f for commits reachable from hash which are not in seen,
+// and adds them to seen.
+func walkCommits(repos *git.Repository, hash plumbing.Hash, seen map[plumbing.Hash]bool, f func(*object.Commit)) error {
+	stack := []plumbing.Hash{hash}
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		c, err := repos.CommitObject(h)
+		if err == plumbing.ErrObjectNotFound && h != hash {
+			continue
+		} else if err != nil {
+			return err
+		}
+		f(c)
+		stack = append(stack, c.ParentHashes...)
+	}
+	return nil
+}
+
 // SetUpstreamRemote sets current branch's upstream remote name to remote.
+// It can be set even if the repository is empty.
 func SetUpstreamRemote(r *git.Repository, remote string) error {
 	cfg, err := r.Config()
 	if err != nil {
 		return err
 	}
 
-	head, err := r.Head()
+	refBranch, err := headBranch(r)
 	if err != nil {
 		return err
 	}
-
-	refBranch := head.Name().String()
 	branch := refHeadsRx.FindStringSubmatch(refBranch)
-	if len(branch) == 0 {
-		return errors.New("HEAD is not matched to refs/heads/...: " + refBranch)
-	}
 
 	subsec := cfg.Raw.Section("branch").Subsection(branch[1])
-	subsec.AddOption("remote", remote)
-	subsec.AddOption("merge", refBranch)
+	subsec.SetOption("remote", remote)
+	subsec.SetOption("merge", refBranch)
 
 	return r.Storer.SetConfig(cfg)
 }
@@ -95,16 +201,11 @@ func GetUpstreamRemote(r *git.Repository) (string, error) {
 		return "", err
 	}
 
-	head, err := r.Head()
+	refBranch, err := headBranch(r)
 	if err != nil {
 		return "", err
 	}
-
-	refBranch := head.Name().String()
 	branch := refHeadsRx.FindStringSubmatch(refBranch)
-	if len(branch) == 0 {
-		return "", errors.New("HEAD is not matched to refs/heads/...: " + refBranch)
-	}
 
 	subsec := cfg.Raw.Section("branch").Subsection(branch[1])
 	remote := subsec.Option("remote")
@@ -113,3 +214,221 @@ func GetUpstreamRemote(r *git.Repository) (string, error) {
 	}
 	return remote, nil
 }
+
+// GetUpstreamBranch gets the upstream remote name (e.g. "origin") and the
+// branch (e.g. "refs/heads/master") of current branch.
+// An error is returned if HEAD is detached.
+func GetUpstreamBranch(r *git.Repository) (string, string, error) {
+	refBranch, err := headBranch(r)
+	if err != nil {
+		return "", "", err
+	}
+	remote, err := GetUpstreamRemote(r)
+	if err != nil {
+		return "", "", err
+	}
+	return remote, refBranch, nil
+}
+
+// LsRemote gets the hash of refName (e.g. "refs/heads/master") in remote like
+// "git ls-remote". Nothing is fetched.
+func LsRemote(r *git.Repository, remote, refName string) (string, error) {
+	url, err := RemoteURL(r, remote)
+	if err != nil {
+		return "", err
+	}
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return "", err
+	}
+	c, err := client.NewClient(ep)
+	if err != nil {
+		return "", err
+	}
+	sess, err := c.NewUploadPackSession(ep, nil)
+	if err != nil {
+		return "", err
+	}
+	defer sess.Close()
+	ar, err := sess.AdvertisedReferences()
+	if err != nil {
+		return "", err
+	}
+	refs, err := ar.AllReferences()
+	if err != nil {
+		return "", err
+	}
+	ref, ok := refs[plumbing.ReferenceName(refName)]
+	if !ok {
+		return "", errors.Errorf("%s is not found in remote '%s'", refName, remote)
+	}
+	return ref.Hash().String(), nil
+}
+
+// RemoteURL returns the first URL of remote (e.g. "origin").
+func RemoteURL(r *git.Repository, remote string) (string, error) {
+	cfg, err := r.Config()
+	if err != nil {
+		return "", err
+	}
+	remoteCfg, ok := cfg.Remotes[remote]
+	if !ok || len(remoteCfg.URLs) == 0 {
+		return "", errors.Errorf("remote %q has no URL", remote)
+	}
+	return remoteCfg.URLs[0], nil
+}
+
+// IsSSHURL returns true if u is a URL of SSH transport ("ssh://..." or
+// scp-like syntax "[user@]host:path").
+func IsSSHURL(u string) bool {
+	for _, scheme := range []string{"ssh://", "git+ssh://", "ssh+git://"} {
+		if strings.HasPrefix(u, scheme) {
+			return true
+		}
+	}
+	if strings.Contains(u, "://") {
+		return false
+	}
+	// "C:\path" is not scp-like syntax
+	i := strings.Index(u, ":")
+	return i > 1 && !strings.ContainsAny(u[:i], `/\`)
+}
+
+// SetRemoteURL sets URL of remote (e.g. "origin") to url.
+func SetRemoteURL(r *git.Repository, remote, url string) error {
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+	remoteCfg, exists := cfg.Remotes[remote]
+	if !exists {
+		return errors.New("remote '" + remote + "' does not exist")
+	}
+	remoteCfg.URLs = []string{url}
+	return r.Storer.SetConfig(cfg)
+}
+
+// CheckoutRef checks out ref in the worktree of r.
+// A branch is checked out as a local branch which tracks the branch of remote
+// (e.g. "origin"), and a tag or a commit is checked out as detached HEAD.
+// If a branch is not found but a tag of the same name exists (e.g.
+// "user/name@v1.0"), the tag is checked out.
+func CheckoutRef(r *git.Repository, remote string, ref pathutil.ReposRef) error {
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	if ref.Type != pathutil.ReposRefBranch {
+		hash, err := ResolveRef(r, ref)
+		if err != nil {
+			return err
+		}
+		return w.Checkout(&git.CheckoutOptions{Hash: hash})
+	}
+
+	branch := plumbing.ReferenceName("refs/heads/" + ref.Name)
+	opts := &git.CheckoutOptions{Branch: branch}
+	if _, err := r.Reference(branch, false); err == plumbing.ErrReferenceNotFound {
+		remoteBranch := plumbing.ReferenceName("refs/remotes/" + remote + "/" + ref.Name)
+		remoteRef, err := r.Reference(remoteBranch, true)
+		if err != nil {
+			tag := pathutil.ReposRef{Type: pathutil.ReposRefTag, Name: ref.Name}
+			if hash, err := ResolveRef(r, tag); err == nil {
+				return w.Checkout(&git.CheckoutOptions{Hash: hash})
+			}
+			return errors.Errorf("branch or tag %q is not found", ref.Name)
+		}
+		opts.Create = true
+		opts.Hash = remoteRef.Hash()
+	} else if err != nil {
+		return err
+	}
+	if err := w.Checkout(opts); err != nil {
+		return err
+	}
+	return SetUpstreamRemote(r, remote)
+}
+
+// ResetHEAD moves the current branch to hash and updates the worktree (like
+// "git reset --hard {hash}"), so the branch is followed again by upgrading.
+// If the repository is bare, refs/remotes/origin/{branch} is moved instead
+// (see GetHEADRepository).
+func ResetHEAD(r *git.Repository, hash string) error {
+	commit, err := resolveCommitHash(r, hash)
+	if err != nil {
+		return err
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+	if !cfg.Core.IsBare {
+		w, err := r.Worktree()
+		if err != nil {
+			return err
+		}
+		return w.Reset(&git.ResetOptions{Commit: commit, Mode: git.HardReset})
+	}
+	branch, err := headBranch(r)
+	if err != nil {
+		return err
+	}
+	name := plumbing.ReferenceName("refs/remotes/origin/" + refHeadsRx.FindStringSubmatch(branch)[1])
+	return r.Storer.SetReference(plumbing.NewHashReference(name, commit))
+}
+
+// ResolveRef returns the commit hash of a tag or a (abbreviated) commit hash.
+func ResolveRef(r *git.Repository, ref pathutil.ReposRef) (plumbing.Hash, error) {
+	switch ref.Type {
+	case pathutil.ReposRefTag:
+		tagRef, err := r.Reference(plumbing.ReferenceName("refs/tags/"+ref.Name), true)
+		if err != nil {
+			return plumbing.ZeroHash, errors.Errorf("tag %q is not found", ref.Name)
+		}
+		// Annotated tag points to a tag object
+		if tag, err := r.TagObject(tagRef.Hash()); err == nil {
+			c, err := tag.Commit()
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			return c.Hash, nil
+		}
+		return tagRef.Hash(), nil
+	case pathutil.ReposRefCommit:
+		return resolveCommitHash(r, ref.Name)
+	}
+	return plumbing.ZeroHash, errors.Errorf("cannot resolve %s %q", ref.Type, ref.Name)
+}
+
+func resolveCommitHash(r *git.Repository, prefix string) (plumbing.Hash, error) {
+	if len(prefix) == 40 {
+		hash := plumbing.NewHash(prefix)
+		if _, err := r.CommitObject(hash); err != nil {
+			return plumbing.ZeroHash, errors.Errorf("commit %q is not found", prefix)
+		}
+		return hash, nil
+	}
+
+	iter, err := r.CommitObjects()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	var found []plumbing.Hash
+	err = iter.ForEach(func(c *object.Commit) error {
+		if strings.HasPrefix(c.Hash.String(), prefix) {
+			found = append(found, c.Hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	switch len(found) {
+	case 0:
+		return plumbing.ZeroHash, errors.Errorf("commit %q is not found", prefix)
+	case 1:
+		return found[0], nil
+	}
+	return plumbing.ZeroHash, errors.Errorf("commit %q is ambiguous", prefix)
+}
@@ -0,0 +1,229 @@
+package gitutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vim-volt/volt/pathutil"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestCountCommits(t *testing.T) {
+	voltPath, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(voltPath)
+	old, exists := os.LookupEnv("VOLTPATH")
+	os.Setenv("VOLTPATH", voltPath)
+	defer func() {
+		if exists {
+			os.Setenv("VOLTPATH", old)
+		} else {
+			os.Unsetenv("VOLTPATH")
+		}
+	}()
+
+	reposPath := pathutil.ReposPath("github.com/user/plugin")
+	r, err := git.PlainInit(reposPath.FullPath(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2018, 4, 1, 12, 0, 0, 0, time.UTC)
+	hashes := make([]plumbing.Hash, 0, 4)
+	for i := 0; i < 4; i++ {
+		file := filepath.Join(reposPath.FullPath(), "file.txt")
+		if err := ioutil.WriteFile(file, []byte{byte('a' + i)}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add("file.txt"); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: "volt", Email: "volt@example.com", When: base.AddDate(0, 0, i)}
+		hash, err := wt.Commit("commit", &git.CommitOptions{Author: sig, Committer: sig})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	count, newest, err := CountCommits(reposPath, hashes[1].String(), hashes[3].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 commits but got %d", count)
+	}
+	if !newest.Equal(base.AddDate(0, 0, 3)) {
+		t.Errorf("expected newest date %s but got %s", base.AddDate(0, 0, 3), newest)
+	}
+
+	count, newest, err = CountCommits(reposPath, hashes[3].String(), hashes[3].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 || !newest.IsZero() {
+		t.Errorf("expected no commits but got %d (%s)", count, newest)
+	}
+}
+
+func TestEmptyRepository(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	r, err := git.PlainInit(tempDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEmptyRepository(r) {
+		t.Error("expected empty repository")
+	}
+	if _, err := GetHEADRepository(r); err != ErrEmptyRepository {
+		t.Errorf("expected ErrEmptyRepository but got %v", err)
+	}
+	// Upstream remote can be set before the first commit
+	if err := SetUpstreamRemote(r, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if remote, err := GetUpstreamRemote(r); err != nil || remote != "origin" {
+		t.Errorf("expected (origin, nil) but got (%q, %v)", remote, err)
+	}
+
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "volt", Email: "volt@example.com", When: time.Now()}
+	if _, err := wt.Commit("initial", &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+		t.Fatal(err)
+	}
+	if IsEmptyRepository(r) {
+		t.Error("expected non-empty repository")
+	}
+}
+
+func TestResetHEAD(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	r, err := git.PlainInit(tempDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(tempDir, "file.txt")
+	hashes := make([]plumbing.Hash, 0, 2)
+	for _, content := range []string{"old", "new"} {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add("file.txt"); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: "volt", Email: "volt@example.com", When: time.Now()}
+		hash, err := wt.Commit(content, &git.CommitOptions{Author: sig, Committer: sig})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	if err := ResetHEAD(r, hashes[0].String()); err != nil {
+		t.Fatal(err)
+	}
+	if head, err := GetHEADRepository(r); err != nil || head != hashes[0].String() {
+		t.Errorf("expected HEAD is (%s, nil) but got (%s, %v)", hashes[0], head, err)
+	}
+	// The branch is not detached
+	if branch, err := headBranch(r); err != nil || branch != "refs/heads/master" {
+		t.Errorf("expected (refs/heads/master, nil) but got (%q, %v)", branch, err)
+	}
+	if b, err := ioutil.ReadFile(file); err != nil || string(b) != "old" {
+		t.Errorf("expected the worktree is reset but got (%q, %v)", b, err)
+	}
+}
+
+func TestIsSSHURL(t *testing.T) {
+	for u, expected := range map[string]bool{
+		"ssh://git@github.com/tyru/caw.vim":     true,
+		"git+ssh://git@github.com/tyru/caw.vim": true,
+		"git@github.com:tyru/caw.vim.git":       true,
+		"github.com:tyru/caw.vim.git":           true,
+		"https://github.com/tyru/caw.vim":       false,
+		"file:///home/user/caw.vim":             false,
+		"/home/user/caw.vim":                    false,
+		`C:\Users\user\caw.vim`:                 false,
+		"./dir:with/colon":                      false,
+	} {
+		if got := IsSSHURL(u); got != expected {
+			t.Errorf("%s: expected %v but got %v", u, expected, got)
+		}
+	}
+}
+
+func TestCheckoutRefTag(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	r, err := git.PlainInit(tempDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := make([]plumbing.Hash, 0, 2)
+	for _, content := range []string{"v1.0", "master"} {
+		if err := ioutil.WriteFile(filepath.Join(tempDir, "file.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add("file.txt"); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: "volt", Email: "volt@example.com", When: time.Now()}
+		hash, err := wt.Commit(content, &git.CommitOptions{Author: sig, Committer: sig})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := r.Storer.SetReference(plumbing.NewHashReference("refs/tags/v1.0", hashes[0])); err != nil {
+		t.Fatal(err)
+	}
+
+	// "@v1.0" is not a branch, so the tag is checked out
+	if err := CheckoutRef(r, "origin", pathutil.ReposRef{Type: pathutil.ReposRefBranch, Name: "v1.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if head, err := GetHEADRepository(r); err != nil || head != hashes[0].String() {
+		t.Errorf("expected HEAD is (%s, nil) but got (%s, %v)", hashes[0], head, err)
+	}
+	if detached, err := IsDetachedHEAD(r); err != nil || !detached {
+		t.Errorf("expected (true, nil) but got (%v, %v)", detached, err)
+	}
+	if err := CheckoutRef(r, "origin", pathutil.ReposRef{Type: pathutil.ReposRefBranch, Name: "v2.0"}); err == nil {
+		t.Error("expected an error for unknown branch or tag")
+	}
+}
@@ -3,6 +3,7 @@ module github.com/vim-volt/volt
 require (
 	github.com/BurntSushi/toml v0.3.0
 	github.com/fatih/color v1.5.0
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/hashicorp/errwrap v0.0.0-20141028054710-7554cd9344ce
 	github.com/hashicorp/go-multierror v0.0.0-20171204182908-b7773ae21874
 	github.com/haya14busa/go-vimlparser v0.0.0-20171121121341-6c96c660fcd0
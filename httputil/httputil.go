@@ -1,29 +1,68 @@
 package httputil
 
 import (
-	"github.com/pkg/errors"
+	"context"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
 )
 
+// SetProxy makes volt connect to HTTP and HTTPS servers via proxyURL.
+// It sets the proxy environment variables which are used by net/http (so
+// this package and go-git), and "git" command. NO_PROXY environment variable
+// is still used.
+// It must be called before sending any requests, because net/http reads the
+// environment variables only once.
+func SetProxy(proxyURL string) {
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		os.Setenv(name, proxyURL)
+	}
+}
+
 // GetContentReader fetches url and returns io.ReadCloser.
 // Caller must close the reader.
-func GetContentReader(url string) (io.ReadCloser, error) {
-	// http.Get() allows up to 10 redirects
-	res, err := http.Get(url)
+// The request is aborted when ctx is done.
+func GetContentReader(ctx context.Context, url string) (io.ReadCloser, error) {
+	return GetContentReaderWithHeader(ctx, url, nil)
+}
+
+// GetContentReaderWithHeader is the same as GetContentReader, but it also
+// sends header.
+func GetContentReaderWithHeader(ctx context.Context, url string, header http.Header) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	// http.DefaultClient allows up to 10 redirects
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 	if res.StatusCode/100 != 2 {
-		return nil, errors.New(url + " returned non-successful status: " + res.Status)
+		res.Body.Close()
+		return nil, &StatusError{URL: url, Status: res.Status, StatusCode: res.StatusCode}
 	}
 	return res.Body, nil
 }
 
+// StatusError is returned when the server returned non-successful status.
+type StatusError struct {
+	URL        string
+	Status     string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return e.URL + " returned non-successful status: " + e.Status
+}
+
 // GetContent fetches url and returns []byte.
-func GetContent(url string) ([]byte, error) {
-	r, err := GetContentReader(url)
+func GetContent(ctx context.Context, url string) ([]byte, error) {
+	r, err := GetContentReader(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -32,7 +71,35 @@ func GetContent(url string) ([]byte, error) {
 }
 
 // GetContentString fetches url and returns string.
-func GetContentString(url string) (string, error) {
-	b, err := GetContent(url)
+func GetContentString(ctx context.Context, url string) (string, error) {
+	b, err := GetContent(ctx, url)
 	return string(b), err
 }
+
+// GetPermanentRedirect requests url without following redirects.
+// If url is permanently redirected (301 or 308), the absolute URL of the
+// redirect destination is returned. Otherwise empty string is returned.
+func GetPermanentRedirect(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMovedPermanently && res.StatusCode != http.StatusPermanentRedirect {
+		return "", nil
+	}
+	location, err := res.Location()
+	if err != nil {
+		return "", err
+	}
+	return location.String(), nil
+}
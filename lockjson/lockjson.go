@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -22,10 +23,13 @@ type ProfileList []Profile
 
 // LockJSON is marshallable content of lock.json
 type LockJSON struct {
-	Version            int64       `json:"version"`
-	CurrentProfileName string      `json:"current_profile_name"`
-	Repos              ReposList   `json:"repos"`
-	Profiles           ProfileList `json:"profiles"`
+	Version            int64  `json:"version"`
+	CurrentProfileName string `json:"current_profile_name"`
+	// ExtraProfileNames are current profiles other than CurrentProfileName.
+	// The repositories of them are also loaded.
+	ExtraProfileNames []string    `json:"extra_profile_names,omitempty"`
+	Repos             ReposList   `json:"repos"`
+	Profiles          ProfileList `json:"profiles"`
 }
 
 // ReposType = string
@@ -36,6 +40,10 @@ const (
 	ReposGitType ReposType = "git"
 	// ReposStaticType = "static"
 	ReposStaticType ReposType = "static"
+	// ReposReleaseType = "release"
+	ReposReleaseType ReposType = "release"
+	// ReposArchiveType = "archive"
+	ReposArchiveType ReposType = "archive"
 	// ReposSystemType = "system"
 	ReposSystemType ReposType = "system"
 )
@@ -45,6 +53,45 @@ type Repos struct {
 	Type    ReposType          `json:"type"`
 	Path    pathutil.ReposPath `json:"path"`
 	Version string             `json:"version"`
+	// Release is the tag pattern of GitHub releases to track (e.g. "v1.*"),
+	// or "latest" to track the latest release.
+	// Only release repository has this value, and its version is tag name.
+	Release string `json:"release,omitempty"`
+	// URL is the URL of the archive (.tar.gz, .tgz, or .zip) to download.
+	// Only archive repository has this value, and its version is the content
+	// hash of the archive (e.g. "sha256:{hex}").
+	URL string `json:"url,omitempty"`
+	// Clone is the options which the git repository was cloned with.
+	// They are used again when the repository is cloned on another machine.
+	Clone *CloneOptions `json:"clone,omitempty"`
+	// Pin is the version which the git repository is pinned to by
+	// "volt get {repository}{pin}" (e.g. "@dev", "#v1.0", "@v1.0", "@1a2b3c4").
+	Pin string `json:"pin,omitempty"`
+}
+
+// PinnedRef returns the version which the repository is pinned to.
+// Zero value is returned if it is not pinned.
+func (repos *Repos) PinnedRef() pathutil.ReposRef {
+	if repos.Pin == "" {
+		return pathutil.ReposRef{}
+	}
+	_, ref, _ := pathutil.NormalizeReposRef(repos.Path.String() + repos.Pin)
+	return ref
+}
+
+// CloneOptions is the options of cloning a git repository.
+type CloneOptions struct {
+	// Depth limits the history to the number of commits (0 is all history)
+	Depth int `json:"depth,omitempty"`
+	// Filter is the filter of partial clone (e.g. "blob:none")
+	Filter string `json:"filter,omitempty"`
+	// SingleBranch fetches only the history of one branch
+	SingleBranch bool `json:"single_branch,omitempty"`
+}
+
+// IsZero returns true if opts has no options.
+func (opts *CloneOptions) IsZero() bool {
+	return opts == nil || *opts == CloneOptions{}
 }
 
 type profReposPath []pathutil.ReposPath
@@ -53,6 +100,15 @@ type profReposPath []pathutil.ReposPath
 type Profile struct {
 	Name      string        `json:"name"`
 	ReposPath profReposPath `json:"repos_path"`
+	// NoBuild is the repositories of ReposPath which are not installed by
+	// "volt build" when this profile is used. Their plugconfs are kept.
+	NoBuild profReposPath `json:"no_build,omitempty"`
+}
+
+// Builds returns true if the profile has reposPath and it is installed by
+// "volt build" (not in NoBuild).
+func (profile *Profile) Builds(reposPath pathutil.ReposPath) bool {
+	return profile.ReposPath.Contains(reposPath) && !profile.NoBuild.Contains(reposPath)
 }
 
 const lockJSONVersion = 2
@@ -81,33 +137,60 @@ func ReadNoMigrationMsg() (*LockJSON, error) {
 	return read(false)
 }
 
+// ReadFile reads lockfile which has the format of lock.json (e.g. a snapshot
+// of lock.json). Unlike Read, an error is returned if lockfile does not exist.
+func ReadFile(lockfile string) (*LockJSON, error) {
+	return readFile(lockfile, false)
+}
+
 func read(doLog bool) (*LockJSON, error) {
 	// Return initial lock.json struct if lockfile does not exist
 	lockfile := pathutil.LockJSON()
 	if !pathutil.Exists(lockfile) {
 		return initialLockJSON(), nil
 	}
+	bytes, err := ioutil.ReadFile(lockfile)
+	if err != nil {
+		return nil, err
+	}
+	return parse(bytes, doLog)
+}
 
+// Parse parses content which has the format of lock.json (e.g. lock.json
+// of another machine). It is migrated and validated like Read.
+func Parse(content []byte) (*LockJSON, error) {
+	return parse(content, false)
+}
+
+func readFile(lockfile string, doLog bool) (*LockJSON, error) {
 	// Read lock.json
 	bytes, err := ioutil.ReadFile(lockfile)
 	if err != nil {
 		return nil, err
 	}
+	return parse(bytes, doLog)
+}
+
+func parse(bytes []byte, doLog bool) (*LockJSON, error) {
 	var lockJSON LockJSON
-	err = json.Unmarshal(bytes, &lockJSON)
+	err := json.Unmarshal(bytes, &lockJSON)
 	if err != nil {
 		return nil, err
 	}
 
-	if lockJSON.Version < lockJSONVersion {
+	if lockJSON.Version >= 1 && lockJSON.Version < lockJSONVersion {
 		if doLog {
 			logger.Warnf("Performing auto-migration of lock.json: v%d -> v%d", lockJSON.Version, lockJSONVersion)
-			logger.Warn("Please run 'volt migrate' to migrate explicitly if it's not updated by after operations")
+			logger.Warn("Please run 'volt migrate lockjson' to migrate explicitly if it's not updated by after operations")
 		}
-		err = migrate(bytes, &lockJSON)
+		migrated, err := migrateLatest(bytes, lockJSON.Version, doLog)
 		if err != nil {
 			return nil, err
 		}
+		lockJSON = LockJSON{}
+		if err := json.Unmarshal(migrated, &lockJSON); err != nil {
+			return nil, err
+		}
 	}
 
 	// Validate lock.json
@@ -161,11 +244,38 @@ func validate(lockJSON *LockJSON) error {
 		if _, err := pathutil.NormalizeRepos(repos.Path.String()); err != nil {
 			return errors.New("'" + repos.Path.String() + "' is invalid repos path")
 		}
+		// Validate if repos[]/path is under $VOLTPATH/repos (lock.json may be
+		// read from other users)
+		if _, err := pathutil.ReposPathOfDir(repos.Path.FullPath()); err != nil {
+			return errors.New("'" + repos.Path.String() + "' is invalid repos path")
+		}
 		// Validate if duplicate repos[]/path exist
 		if _, exists := dup[repos.Path.String()]; exists {
 			return errors.New("duplicate repos '" + repos.Path.String() + "'")
 		}
 		dup[repos.Path.String()] = true
+		// Validate if repos[]/clone is valid
+		if repos.Clone != nil {
+			if repos.Type != ReposGitType {
+				return errors.New("'" + repos.Path.String() + "' has clone options but is not a git repository")
+			}
+			if repos.Clone.Depth < 0 {
+				return errors.New("'" + repos.Path.String() + "' has negative clone depth")
+			}
+		}
+		// Validate if repos[]/url is valid
+		if repos.URL != "" && repos.Type != ReposArchiveType {
+			return errors.New("'" + repos.Path.String() + "' has url but is not an archive repository")
+		}
+		// Validate if repos[]/pin is valid
+		if repos.Pin != "" {
+			if repos.Type != ReposGitType {
+				return errors.New("'" + repos.Path.String() + "' is pinned but is not a git repository")
+			}
+			if repos.PinnedRef().IsZero() {
+				return errors.New("'" + repos.Path.String() + "' has invalid pin '" + repos.Pin + "'")
+			}
+		}
 	}
 
 	// Validate if duplicate profiles[]/name exist
@@ -192,6 +302,18 @@ func validate(lockJSON *LockJSON) error {
 			}
 			dup[reposPath.String()] = true
 		}
+		dup = make(map[string]bool, len(profile.NoBuild))
+		for _, reposPath := range profile.NoBuild {
+			// Validate if profiles[]/no_build[] exists in profiles[]/repos_path[]
+			if !profile.ReposPath.Contains(reposPath) {
+				return errors.New("'" + reposPath.String() + "' (no_build) is not in repos_path of profile '" + profile.Name + "'")
+			}
+			// Validate if duplicate profiles[]/no_build[] exist
+			if _, exists := dup[reposPath.String()]; exists {
+				return errors.New("duplicate '" + reposPath.String() + "' (no_build) in profile '" + profile.Name + "'")
+			}
+			dup[reposPath.String()] = true
+		}
 	}
 
 	// Validate if current_profile_name exists in profiles[]/name
@@ -207,6 +329,19 @@ func validate(lockJSON *LockJSON) error {
 		return errors.New("'" + lockJSON.CurrentProfileName + "' (current_profile_name) doesn't exist in profiles")
 	}
 
+	// Validate if extra_profile_names[] exist in profiles[]/name and are not
+	// duplicate
+	dupNames := map[string]bool{lockJSON.CurrentProfileName: true}
+	for _, name := range lockJSON.ExtraProfileNames {
+		if lockJSON.Profiles.FindIndexByName(name) < 0 {
+			return errors.New("'" + name + "' (extra_profile_names) doesn't exist in profiles")
+		}
+		if dupNames[name] {
+			return errors.New("duplicate '" + name + "' (extra_profile_names)")
+		}
+		dupNames[name] = true
+	}
+
 	// Validate if profiles[]/repos_path[] exists in repos[]/path
 	reposMap := make(map[string]*Repos, len(lockJSON.Repos))
 	for i := range lockJSON.Repos {
@@ -240,6 +375,16 @@ func validateMissing(lockJSON *LockJSON) error {
 			return errors.New("missing: repos[" + strconv.Itoa(i) + "].type")
 		}
 		switch repos.Type {
+		case ReposReleaseType:
+			if repos.Release == "" {
+				return errors.New("missing: repos[" + strconv.Itoa(i) + "].release")
+			}
+			fallthrough
+		case ReposArchiveType:
+			if repos.Type == ReposArchiveType && repos.URL == "" {
+				return errors.New("missing: repos[" + strconv.Itoa(i) + "].url")
+			}
+			fallthrough
 		case ReposGitType:
 			if repos.Version == "" {
 				return errors.New("missing: repos[" + strconv.Itoa(i) + "].version")
@@ -292,6 +437,11 @@ func (lockJSON *LockJSON) Write() error {
 		}
 	}
 
+	// Back up lock.json before it is overwritten by the latest version
+	if err := backupOldFile(); err != nil {
+		return err
+	}
+
 	// Write to lock.json
 	bytes, err := json.MarshalIndent(lockJSON, "", "  ")
 	if err != nil {
@@ -300,18 +450,102 @@ func (lockJSON *LockJSON) Write() error {
 	return ioutil.WriteFile(pathutil.LockJSON(), bytes, 0644)
 }
 
-// GetCurrentReposList returns current profile's repositories.
+// CurrentProfileNames returns current_profile_name and
+// extra_profile_names[].
+func (lockJSON *LockJSON) CurrentProfileNames() []string {
+	names := make([]string, 0, 1+len(lockJSON.ExtraProfileNames))
+	names = append(names, lockJSON.CurrentProfileName)
+	return append(names, lockJSON.ExtraProfileNames...)
+}
+
+// IsCurrentProfile returns true if name is one of current profiles.
+func (lockJSON *LockJSON) IsCurrentProfile(name string) bool {
+	for _, n := range lockJSON.CurrentProfileNames() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCurrentReposList returns current profiles' repositories.
+// If there are multiple current profiles, the repositories of them are
+// unioned (in the order of current profiles).
 func (lockJSON *LockJSON) GetCurrentReposList() (ReposList, error) {
-	// Find current profile
-	profile, err := lockJSON.Profiles.FindByName(lockJSON.CurrentProfileName)
+	reposList := make(ReposList, 0, len(lockJSON.Repos))
+	for _, name := range lockJSON.CurrentProfileNames() {
+		// Find current profile
+		profile, err := lockJSON.Profiles.FindByName(name)
+		if err != nil {
+			// this must not be occurred because lockjson.Read()
+			// validates that the matching profile exists
+			return nil, err
+		}
+		list, err := lockJSON.GetReposListByProfile(profile)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			if !reposList.Contains(list[i].Path) {
+				reposList = append(reposList, list[i])
+			}
+		}
+	}
+	return reposList, nil
+}
+
+// GetAllProfilesReposList returns repositories which are in any profile.
+// The order is same as "repos" of lock.json.
+func (lockJSON *LockJSON) GetAllProfilesReposList() ReposList {
+	reposList := make(ReposList, 0, len(lockJSON.Repos))
+	for i := range lockJSON.Repos {
+		for j := range lockJSON.Profiles {
+			if lockJSON.Profiles[j].ReposPath.Contains(lockJSON.Repos[i].Path) {
+				reposList = append(reposList, lockJSON.Repos[i])
+				break
+			}
+		}
+	}
+	return reposList
+}
+
+// GetCurrentBuildReposList returns current profiles' repositories except the
+// repositories which are not built by any current profile (no_build).
+func (lockJSON *LockJSON) GetCurrentBuildReposList() (ReposList, error) {
+	reposList, err := lockJSON.GetCurrentReposList()
 	if err != nil {
-		// this must not be occurred because lockjson.Read()
-		// validates that the matching profile exists
 		return nil, err
 	}
+	names := lockJSON.CurrentProfileNames()
+	result := make(ReposList, 0, len(reposList))
+	for i := range reposList {
+		for _, name := range names {
+			profile, err := lockJSON.Profiles.FindByName(name)
+			if err != nil {
+				return nil, err
+			}
+			if profile.Builds(reposList[i].Path) {
+				result = append(result, reposList[i])
+				break
+			}
+		}
+	}
+	return result, nil
+}
 
-	reposList, err := lockJSON.GetReposListByProfile(profile)
-	return reposList, err
+// GetAllProfilesBuildReposList returns repositories which are built by any
+// profile. The order is same as "repos" of lock.json.
+func (lockJSON *LockJSON) GetAllProfilesBuildReposList() ReposList {
+	reposList := make(ReposList, 0, len(lockJSON.Repos))
+	for i := range lockJSON.Repos {
+		for j := range lockJSON.Profiles {
+			if lockJSON.Profiles[j].Builds(lockJSON.Repos[i].Path) {
+				reposList = append(reposList, lockJSON.Repos[i])
+				break
+			}
+		}
+	}
+	return reposList
 }
 
 // FindByName finds name from all profiles and returns it.
@@ -352,6 +586,9 @@ func (plist ProfileList) RemoveAllReposPath(reposPath pathutil.ReposPath) error
 			}
 			j++
 		}
+		if idx := plist[i].NoBuild.IndexOf(reposPath); idx >= 0 {
+			plist[i].NoBuild = append(plist[i].NoBuild[:idx], plist[i].NoBuild[idx+1:]...)
+		}
 	}
 	if !removed {
 		return errors.New("no matching profiles[]/repos_path[]: " + reposPath.String())
@@ -376,6 +613,54 @@ func (reposList ReposList) FindByPath(reposPath pathutil.ReposPath) *Repos {
 	return nil
 }
 
+// FindAllByName finds repositories matched with name, which is a plugin name
+// (e.g. "caw.vim") or a trailing part of repos path (e.g. "tyru/caw.vim").
+// Names are compared case-insensitively. If no repository matches exactly,
+// repositories whose plugin name contains name are returned.
+func (reposList ReposList) FindAllByName(name string) []Repos {
+	name = strings.ToLower(strings.Trim(filepath.ToSlash(name), "/"))
+	if name == "" {
+		return nil
+	}
+	var exact, partial []Repos
+	for i := range reposList {
+		p := strings.ToLower(reposList[i].Path.String())
+		if p == name || strings.HasSuffix(p, "/"+name) {
+			exact = append(exact, reposList[i])
+		} else if !strings.Contains(name, "/") &&
+			strings.Contains(p[strings.LastIndex(p, "/")+1:], name) {
+			partial = append(partial, reposList[i])
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return partial
+}
+
+// RenameRepos renames repos[]/path and profiles[]/repos_path from "from" to
+// "to" (e.g. the repository was renamed or transferred).
+func (lockJSON *LockJSON) RenameRepos(from, to pathutil.ReposPath) error {
+	if lockJSON.Repos.Contains(to) {
+		return errors.New("repos '" + to.String() + "' already exists")
+	}
+	repos := lockJSON.Repos.FindByPath(from)
+	if repos == nil {
+		return errors.New("no matching repos[]/path: " + from.String())
+	}
+	repos.Path = to
+	for i := range lockJSON.Profiles {
+		for _, reposPathList := range []profReposPath{lockJSON.Profiles[i].ReposPath, lockJSON.Profiles[i].NoBuild} {
+			for j := range reposPathList {
+				if reposPathList[j].Equals(from) {
+					reposPathList[j] = to
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // RemoveAllReposPath removes all reposPath from all repos path list.
 func (reposList *ReposList) RemoveAllReposPath(reposPath pathutil.ReposPath) error {
 	for i := range *reposList {
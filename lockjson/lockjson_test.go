@@ -0,0 +1,257 @@
+package lockjson
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestRenameRepos(t *testing.T) {
+	lockJSON := &LockJSON{
+		Repos: ReposList{
+			{Path: pathutil.ReposPath("github.com/old/name")},
+			{Path: pathutil.ReposPath("github.com/user/other")},
+		},
+		Profiles: ProfileList{
+			{Name: "default", ReposPath: profReposPath{"github.com/old/name", "github.com/user/other"}},
+			{Name: "foo", ReposPath: profReposPath{"github.com/user/other"}},
+			{Name: "bar", ReposPath: profReposPath{"github.com/Old/Name"}},
+		},
+	}
+
+	if err := lockJSON.RenameRepos("github.com/old/name", "github.com/user/other"); err == nil {
+		t.Error("expected error when renaming to existing repos")
+	}
+	if err := lockJSON.RenameRepos("github.com/no/such", "github.com/new/name"); err == nil {
+		t.Error("expected error when renaming non-existing repos")
+	}
+
+	if err := lockJSON.RenameRepos("github.com/old/name", "github.com/new/name"); err != nil {
+		t.Fatal(err)
+	}
+	if lockJSON.Repos.Contains("github.com/old/name") || !lockJSON.Repos.Contains("github.com/new/name") {
+		t.Errorf("repos[] was not renamed: %+v", lockJSON.Repos)
+	}
+	for _, name := range []string{"default", "bar"} {
+		profile, err := lockJSON.Profiles.FindByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.ReposPath.Contains("github.com/old/name") || !profile.ReposPath.Contains("github.com/new/name") {
+			t.Errorf("profile %q was not renamed: %+v", name, profile.ReposPath)
+		}
+	}
+}
+
+func TestValidateCloneOptions(t *testing.T) {
+	newLockJSON := func(repos Repos) *LockJSON {
+		return &LockJSON{
+			Version:            lockJSONVersion,
+			CurrentProfileName: "default",
+			Repos:              ReposList{repos},
+			Profiles:           ProfileList{{Name: "default", ReposPath: profReposPath{}}},
+		}
+	}
+	valid := Repos{Type: ReposGitType, Path: "github.com/tyru/caw.vim", Version: "abc", Clone: &CloneOptions{Depth: 1, Filter: "blob:none"}}
+	if err := validate(newLockJSON(valid)); err != nil {
+		t.Errorf("expected valid but got %v", err)
+	}
+	for _, repos := range []Repos{
+		{Type: ReposGitType, Path: "github.com/tyru/caw.vim", Version: "abc", Clone: &CloneOptions{Depth: -1}},
+		{Type: ReposStaticType, Path: "localhost/local/hello", Clone: &CloneOptions{Depth: 1}},
+	} {
+		if err := validate(newLockJSON(repos)); err == nil {
+			t.Errorf("%+v: expected an error", repos)
+		}
+	}
+}
+
+func TestNoBuild(t *testing.T) {
+	lockJSON := &LockJSON{
+		Version:            lockJSONVersion,
+		CurrentProfileName: "server",
+		Repos: ReposList{
+			{Type: ReposGitType, Path: "github.com/tyru/caw.vim", Version: "abc"},
+			{Type: ReposGitType, Path: "github.com/Shougo/deoplete.nvim", Version: "def"},
+			{Type: ReposGitType, Path: "github.com/tyru/open-browser.vim", Version: "ghi"},
+		},
+		Profiles: ProfileList{
+			{
+				Name:      "server",
+				ReposPath: profReposPath{"github.com/tyru/caw.vim", "github.com/Shougo/deoplete.nvim"},
+				NoBuild:   profReposPath{"github.com/Shougo/deoplete.nvim"},
+			},
+			{
+				Name:      "desktop",
+				ReposPath: profReposPath{"github.com/Shougo/deoplete.nvim"},
+			},
+			{
+				Name:      "browser",
+				ReposPath: profReposPath{"github.com/tyru/open-browser.vim"},
+				NoBuild:   profReposPath{"github.com/tyru/open-browser.vim"},
+			},
+		},
+	}
+	if err := validate(lockJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := func(reposList ReposList) []pathutil.ReposPath {
+		result := make([]pathutil.ReposPath, 0, len(reposList))
+		for i := range reposList {
+			result = append(result, reposList[i].Path)
+		}
+		return result
+	}
+	current, err := lockJSON.GetCurrentBuildReposList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := paths(current), []pathutil.ReposPath{"github.com/tyru/caw.vim"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("current: expected %v but got %v", expected, got)
+	}
+	if got, expected := paths(lockJSON.GetAllProfilesBuildReposList()), []pathutil.ReposPath{"github.com/tyru/caw.vim", "github.com/Shougo/deoplete.nvim"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("all profiles: expected %v but got %v", expected, got)
+	}
+
+	// No-build of a current profile is overridden by another current profile
+	lockJSON.ExtraProfileNames = []string{"desktop"}
+	current, err = lockJSON.GetCurrentBuildReposList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := paths(current), []pathutil.ReposPath{"github.com/tyru/caw.vim", "github.com/Shougo/deoplete.nvim"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("current with extra profile: expected %v but got %v", expected, got)
+	}
+
+	if err := lockJSON.Profiles.RemoveAllReposPath("github.com/Shougo/deoplete.nvim"); err != nil {
+		t.Fatal(err)
+	}
+	if len(lockJSON.Profiles[0].NoBuild) != 0 {
+		t.Errorf("no_build was not removed: %v", lockJSON.Profiles[0].NoBuild)
+	}
+
+	lockJSON.Profiles[0].NoBuild = profReposPath{"github.com/tyru/open-browser.vim"}
+	if err := validate(lockJSON); err == nil {
+		t.Error("expected error for no_build which is not in repos_path")
+	}
+}
+
+func TestValidatePin(t *testing.T) {
+	lockJSON := initialLockJSON()
+	lockJSON.Repos = ReposList{
+		{Type: ReposGitType, Path: "github.com/tyru/caw.vim", Version: "abc", Pin: "#v1.0"},
+	}
+	if err := validate(lockJSON); err != nil {
+		t.Fatal(err)
+	}
+	expected := pathutil.ReposRef{Type: pathutil.ReposRefTag, Name: "v1.0"}
+	if ref := lockJSON.Repos[0].PinnedRef(); ref != expected {
+		t.Errorf("expected %v but got %v", expected, ref)
+	}
+
+	lockJSON.Repos[0].Pin = "v1.0"
+	if err := validate(lockJSON); err == nil {
+		t.Error("expected an error for invalid pin")
+	}
+	lockJSON.Repos[0] = Repos{Type: ReposStaticType, Path: "localhost/local/hello", Pin: "@dev"}
+	if err := validate(lockJSON); err == nil {
+		t.Error("expected an error for pinned static repository")
+	}
+}
+
+func TestValidateReposPath(t *testing.T) {
+	for _, path := range []pathutil.ReposPath{
+		"../../foo",
+		"github.com/../foo",
+		"github.com/tyru/..",
+		"/tmp/foo/bar",
+	} {
+		lockJSON := initialLockJSON()
+		lockJSON.Repos = ReposList{{Type: ReposGitType, Path: path, Version: "abc"}}
+		if err := validate(lockJSON); err == nil {
+			t.Errorf("expected an error for repos path '%s'", path)
+		}
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	for i, m := range Migrations() {
+		if m.From != int64(i+1) || m.Description == "" || m.migrate == nil {
+			t.Errorf("invalid migration: %+v", m)
+		}
+	}
+	if n := int64(len(Migrations())) + 1; n != LatestVersion() {
+		t.Errorf("expected migrations to v%d but got to v%d", LatestVersion(), n)
+	}
+}
+
+func TestMigrateTo(t *testing.T) {
+	v1 := []byte(`{"version": 1, "active_profile": "foo", "repos": [], "profiles": [{"name": "foo", "repos_path": []}]}`)
+	content, err := MigrateTo(v1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw["version"] != float64(2) || raw["current_profile_name"] != "foo" || raw["active_profile"] != nil {
+		t.Errorf("unexpected migrated lock.json: %s", content)
+	}
+	if content, err := MigrateTo(v1, 1); err != nil || !strings.Contains(string(content), `"active_profile"`) {
+		t.Errorf("expected lock.json v1 but got %s (%v)", content, err)
+	}
+	if _, err := MigrateTo(v1, LatestVersion()+1); err == nil {
+		t.Error("expected an error for unknown version")
+	}
+	if _, err := MigrateTo([]byte(`{"version": 0}`), 2); err == nil {
+		t.Error("expected an error for version 0")
+	}
+
+	lockJSON, err := Parse(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lockJSON.Version != LatestVersion() || lockJSON.CurrentProfileName != "foo" {
+		t.Errorf("unexpected parsed lock.json: %+v", lockJSON)
+	}
+}
+
+func TestBackupOnWrite(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	v1 := `{"version": 1, "active_profile": "default", "repos": [], "profiles": [{"name": "default", "repos_path": []}]}`
+	if err := ioutil.WriteFile(filepath.Join(tempDir, "lock.json"), []byte(v1), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading old lock.json does not write any files
+	lockJSON, err := ReadNoMigrationMsg()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pathutil.Exists(BackupPath(1)) {
+		t.Error("expected lock.json is not backed up by reading it")
+	}
+
+	// Writing it backs up old lock.json
+	if err := lockJSON.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(BackupPath(1)); err != nil || string(b) != v1 {
+		t.Errorf("expected lock.json v1 is backed up but got %q (%v)", b, err)
+	}
+}
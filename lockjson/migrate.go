@@ -2,40 +2,194 @@ package lockjson
 
 import (
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
 
 	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/transaction"
 )
 
-func migrate(rawJSON []byte, lockJSON *LockJSON) error {
-	// lockJSON.Version must be greater than 0 because it was validated
-	var err error
-	max := int64(len(migrateFunc))
-	for lockJSON.Version-1 < max {
-		logger.Infof("Migrating lock.json v%d to v%d ...", lockJSON.Version, lockJSON.Version+1)
-		err = migrateFunc[lockJSON.Version-1](rawJSON, lockJSON)
+// Migration converts lock.json of version From to version From+1.
+type Migration struct {
+	From        int64
+	Description string
+	migrate     func(raw map[string]interface{}) error
+}
+
+// migrations are all migrations of lock.json.
+// migrations[i] converts lock.json v(i+1) to v(i+2), so the last migration
+// converts lock.json to lockJSONVersion.
+var migrations = []Migration{
+	{
+		From:        1,
+		Description: `renames "active_profile" to "current_profile_name"`,
+		migrate:     migrate1To2,
+	},
+}
+
+// Migrations returns all migrations of lock.json in order of versions.
+func Migrations() []Migration {
+	return migrations
+}
+
+// LatestVersion returns the version of lock.json which this volt writes.
+func LatestVersion() int64 {
+	return lockJSONVersion
+}
+
+// ReadVersion returns the version of lock.json (0 if lock.json does not exist).
+func ReadVersion() (int64, error) {
+	lockfile := pathutil.LockJSON()
+	if !pathutil.Exists(lockfile) {
+		return 0, nil
+	}
+	content, err := ioutil.ReadFile(lockfile)
+	if err != nil {
+		return 0, err
+	}
+	return parseVersion(content)
+}
+
+func parseVersion(content []byte) (int64, error) {
+	var j struct {
+		Version int64 `json:"version"`
+	}
+	if err := json.Unmarshal(content, &j); err != nil {
+		return 0, err
+	}
+	return j.Version, nil
+}
+
+// MigrateTo converts content of lock.json to version to, and returns the
+// converted content. Keys unknown to the migrations are kept.
+func MigrateTo(content []byte, to int64) ([]byte, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+	from, err := parseVersion(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrate(raw, from, to, false); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(raw, "", "  ")
+}
+
+// MigrateFileTo converts lock.json to version to, and writes it.
+// lock.json is backed up to BackupPath() before it is converted.
+func MigrateFileTo(to int64) error {
+	if to == lockJSONVersion {
+		lockJSON, err := read(false)
 		if err != nil {
 			return err
 		}
+		return lockJSON.Write()
+	}
+
+	lockfile := pathutil.LockJSON()
+	content, err := ioutil.ReadFile(lockfile)
+	if err != nil {
+		return err
+	}
+	from, err := parseVersion(content)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	converted, err := MigrateTo(content, to)
+	if err != nil {
+		return err
+	}
+	if err := backup(content, from); err != nil {
+		return errors.Wrap(err, "could not back up lock.json before migration")
+	}
+	return transaction.WriteFile(lockfile, converted, 0644)
+}
+
+// migrate converts raw lock.json from version from to version to.
+func migrate(raw map[string]interface{}, from, to int64, doLog bool) error {
+	if from < 1 || from > lockJSONVersion {
+		return errors.Errorf("cannot migrate lock.json v%d", from)
+	}
+	if to < from || to > lockJSONVersion {
+		return errors.Errorf("cannot migrate lock.json v%d to v%d", from, to)
+	}
+	for v := from; v < to; v++ {
+		m := &migrations[v-1]
+		if doLog {
+			logger.Infof("Migrating lock.json v%d to v%d ...", v, v+1)
+		}
+		if err := m.migrate(raw); err != nil {
+			return errors.Wrapf(err, "failed to migrate lock.json v%d to v%d", v, v+1)
+		}
+		raw["version"] = v + 1
 	}
 	return nil
 }
 
-var migrateFunc = []func([]byte, *LockJSON) error{
-	migrate1To2,
+// migrateLatest converts content of lock.json to the latest version.
+func migrateLatest(content []byte, from int64, doLog bool) ([]byte, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+	if err := migrate(raw, from, lockJSONVersion, doLog); err != nil {
+		return nil, err
+	}
+	return json.Marshal(raw)
+}
+
+// BackupPath returns the path of the backup of lock.json of version, which
+// is created before lock.json is migrated (e.g. "lock.json.v1.bak").
+func BackupPath(version int64) string {
+	return fmt.Sprintf("%s.v%d.bak", pathutil.LockJSON(), version)
 }
 
-// Rename 'active_profile' to 'current_profile_name'
-func migrate1To2(rawJSON []byte, lockJSON *LockJSON) error {
-	var j struct {
-		ActiveProfile string `json:"active_profile"`
+// backupOldFile backs up lock.json if it is older than the latest version,
+// before it is overwritten.
+func backupOldFile() error {
+	content, err := ioutil.ReadFile(pathutil.LockJSON())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if version, err := parseVersion(content); err == nil && version >= 1 && version < lockJSONVersion {
+		if err := backup(content, version); err != nil {
+			return errors.Wrap(err, "could not back up lock.json before migration")
+		}
 	}
+	return nil
+}
 
-	err := json.Unmarshal(rawJSON, &j)
-	if err != nil {
+// backup copies content of lock.json of version to BackupPath(version) if it
+// does not exist.
+func backup(content []byte, version int64) error {
+	path := BackupPath(version)
+	if pathutil.Exists(path) {
+		return nil
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		os.Remove(path)
 		return err
 	}
-	lockJSON.CurrentProfileName = j.ActiveProfile
-	lockJSON.Version += 1
+	logger.Infof("Backed up lock.json v%d to %s", version, path)
+	return nil
+}
 
+// Rename 'active_profile' to 'current_profile_name'
+func migrate1To2(raw map[string]interface{}) error {
+	if name, ok := raw["active_profile"]; ok {
+		raw["current_profile_name"] = name
+		delete(raw, "active_profile")
+	}
 	return nil
 }
@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mattn/go-colorable"
+)
+
+// Buffer holds log messages of a task which runs in parallel with other tasks
+// (e.g. installing a repository), and outputs them as one grouped block by
+// Flush(). This keeps messages of parallel tasks from being interleaved.
+//
+// A nil *Buffer is valid and outputs messages immediately like package-level
+// functions.
+type Buffer struct {
+	m     sync.Mutex
+	lines []bufferedLine
+	// repos is the repository of the task, which is output in JSONFormat
+	repos string
+}
+
+type bufferedLine struct {
+	level LogLevel
+	msg   string
+}
+
+// NewBuffer creates an empty Buffer.
+func NewBuffer() *Buffer {
+	return &Buffer{lines: make([]bufferedLine, 0, 8)}
+}
+
+// NewReposBuffer creates an empty Buffer of a task of repository reposPath.
+func NewReposBuffer(reposPath string) *Buffer {
+	b := NewBuffer()
+	b.repos = reposPath
+	return b
+}
+
+// Errorf buffers formatted message of arguments.
+func (b *Buffer) Errorf(format string, msgs ...interface{}) {
+	b.addf(ErrorLevel, errorLabel, format, msgs)
+}
+
+// Error buffers message of arguments.
+func (b *Buffer) Error(msgs ...interface{}) {
+	b.add(ErrorLevel, errorLabel, msgs)
+}
+
+// Warnf buffers formatted message of arguments.
+func (b *Buffer) Warnf(format string, msgs ...interface{}) {
+	b.addf(WarnLevel, warnLabel, format, msgs)
+}
+
+// Warn buffers message of arguments.
+func (b *Buffer) Warn(msgs ...interface{}) {
+	b.add(WarnLevel, warnLabel, msgs)
+}
+
+// Infof buffers formatted message of arguments.
+func (b *Buffer) Infof(format string, msgs ...interface{}) {
+	b.addf(InfoLevel, infoLabel, format, msgs)
+}
+
+// Info buffers message of arguments.
+func (b *Buffer) Info(msgs ...interface{}) {
+	b.add(InfoLevel, infoLabel, msgs)
+}
+
+// Debugf buffers formatted message of arguments.
+func (b *Buffer) Debugf(format string, msgs ...interface{}) {
+	b.addf(DebugLevel, debugLabel, format, msgs)
+}
+
+// Debug buffers message of arguments.
+func (b *Buffer) Debug(msgs ...interface{}) {
+	b.add(DebugLevel, debugLabel, msgs)
+}
+
+func (b *Buffer) addf(level LogLevel, label, format string, msgs []interface{}) {
+	writeFile(3, level, fmt.Sprintf(format, msgs...))
+	if logLevel < level {
+		return
+	}
+	if level == WarnLevel {
+		collectWarning(fmt.Sprintf(format, msgs...))
+	}
+	if CurrentFormat() == JSONFormat {
+		b.push(level, jsonLine(3, level, b.reposPath(), fmt.Sprintf(format, msgs...)))
+		return
+	}
+	msgs = append([]interface{}{getDebugPrefixSkip(3)}, msgs...)
+	b.push(level, fmt.Sprintf(label+"%s "+format, msgs...))
+}
+
+func (b *Buffer) add(level LogLevel, label string, msgs []interface{}) {
+	writeFile(3, level, sprintln(msgs))
+	if logLevel < level {
+		return
+	}
+	if level == WarnLevel {
+		collectWarning(sprintln(msgs))
+	}
+	if CurrentFormat() == JSONFormat {
+		b.push(level, jsonLine(3, level, b.reposPath(), sprintln(msgs)))
+		return
+	}
+	msgs = append([]interface{}{label + getDebugPrefixSkip(3)}, msgs...)
+	b.push(level, sprintln(msgs))
+}
+
+func (b *Buffer) reposPath() string {
+	if b == nil {
+		return ""
+	}
+	return b.repos
+}
+
+func (b *Buffer) push(level LogLevel, msg string) {
+	if b == nil {
+		m.Lock()
+		defer m.Unlock()
+		writeLine(level, msg)
+		return
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.lines = append(b.lines, bufferedLine{level: level, msg: msg})
+}
+
+// Flush outputs all buffered messages at once, and clears the buffer.
+// Messages of other goroutines are not output until all buffered messages
+// are output.
+func (b *Buffer) Flush() {
+	if b == nil {
+		return
+	}
+	b.m.Lock()
+	lines := b.lines
+	b.lines = make([]bufferedLine, 0, 8)
+	b.m.Unlock()
+
+	m.Lock()
+	defer m.Unlock()
+	for i := range lines {
+		writeLine(lines[i].level, lines[i].msg)
+	}
+}
+
+// writeLine writes msg to stderr if level is ErrorLevel, otherwise to stdout.
+// The caller must hold the lock.
+func writeLine(level LogLevel, msg string) {
+	defer hideProgress()()
+	if level == ErrorLevel {
+		out.Fprintln(colorable.NewColorableStderr(), msg)
+	} else {
+		out.Println(msg)
+	}
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LogFileName is the basename of the log file.
+	LogFileName = "volt.log"
+	// maxLogFileSize is the size of the log file which triggers rotation.
+	maxLogFileSize = 1024 * 1024
+	// maxLogFiles is the number of rotated log files to keep
+	// (volt.log.1 ... volt.log.{maxLogFiles}).
+	maxLogFiles = 5
+)
+
+var logFile *os.File
+var fileM sync.Mutex
+
+var fileLabel = map[LogLevel]string{
+	ErrorLevel: "ERROR",
+	WarnLevel:  "WARN",
+	InfoLevel:  "INFO",
+	DebugLevel: "DEBUG",
+}
+
+// OpenFile starts writing all log messages to {dir}/volt.log regardless of
+// current log level. If the log file is larger than 1MiB, it is rotated to
+// {dir}/volt.log.1 (and volt.log.1 to volt.log.2, ...) before opening.
+// The returned function closes the log file.
+func OpenFile(dir string) (func() error, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, LogFileName)
+	if err := rotate(path); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	fileM.Lock()
+	logFile = f
+	fileM.Unlock()
+	return closeFile, nil
+}
+
+func closeFile() error {
+	fileM.Lock()
+	defer fileM.Unlock()
+	if logFile == nil {
+		return nil
+	}
+	err := logFile.Close()
+	logFile = nil
+	return err
+}
+
+// rotate renames path to path.1, path.1 to path.2, ... if the size of path
+// exceeds maxLogFileSize. The oldest file is removed.
+func rotate(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil || fi.Size() < maxLogFileSize {
+		return nil
+	}
+	os.Remove(fmt.Sprintf("%s.%d", path, maxLogFiles))
+	for i := maxLogFiles - 1; i >= 1; i-- {
+		old := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(old); err == nil {
+			if err := os.Rename(old, fmt.Sprintf("%s.%d", path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(path, path+".1")
+}
+
+// writeFile writes msg to the log file if it was opened by OpenFile().
+// skip is the number of stack frames to the caller of logging function.
+func writeFile(skip int, level LogLevel, msg string) {
+	fileM.Lock()
+	defer fileM.Unlock()
+	if logFile == nil {
+		return
+	}
+	caller := ""
+	const voltDirName = "github.com/vim-volt/volt/"
+	if _, fn, line, ok := runtime.Caller(skip); ok {
+		if idx := strings.Index(fn, voltDirName); idx >= 0 {
+			fn = fn[idx+len(voltDirName):]
+		}
+		caller = fmt.Sprintf("[%s:%d]", fn, line)
+	}
+	fmt.Fprintf(logFile, "%s [%d] [%s]%s %s\n",
+		time.Now().Format("2006-01-02T15:04:05.000Z07:00"), os.Getpid(),
+		fileLabel[level], caller, msg)
+}
+
+// sprintln formats msgs like fmt.Println() without a trailing newline.
+func sprintln(msgs []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(msgs...), "\n")
+}
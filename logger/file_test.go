@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenFileRotate(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, LogFileName)
+	big := make([]byte, maxLogFileSize)
+	for i := 1; i <= maxLogFiles; i++ {
+		if err := ioutil.WriteFile(fmt.Sprintf("%s.%d", path, i), []byte(fmt.Sprint(i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(path, big, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	closeLog, err := OpenFile(tempDir)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %s", err)
+	}
+	Info("hello")
+	if err := closeLog(); err != nil {
+		t.Fatalf("failed to close log file: %s", err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", path, err)
+	}
+	if !strings.Contains(string(content), "[INFO]") || !strings.HasSuffix(string(content), " hello\n") {
+		t.Errorf("unexpected log content: %q", string(content))
+	}
+	if fi, err := os.Stat(path + ".1"); err != nil || fi.Size() != maxLogFileSize {
+		t.Errorf("expected %s.1 is the rotated log file", path)
+	}
+	for i := 2; i <= maxLogFiles; i++ {
+		b, err := ioutil.ReadFile(fmt.Sprintf("%s.%d", path, i))
+		if err != nil || string(b) != fmt.Sprint(i-1) {
+			t.Errorf("expected %s.%d has %q but got %q", path, i, fmt.Sprint(i-1), string(b))
+		}
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s.%d", path, maxLogFiles+1)); err == nil {
+		t.Errorf("expected the oldest log file is removed")
+	}
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Format is the format of log messages.
+type Format string
+
+const (
+	// TextFormat outputs log messages with labels (e.g. "[INFO] ...").
+	TextFormat Format = "text"
+	// JSONFormat outputs one JSON object per log message.
+	JSONFormat Format = "json"
+)
+
+// FormatEnv is the environment variable which selects the format of log
+// messages ("text" or "json").
+const FormatEnv = "VOLT_LOG_FORMAT"
+
+var format = TextFormat
+
+// ParseFormat returns the format of name ("text" or "json").
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case TextFormat, JSONFormat:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid log format %q: valid formats are %q and %q", name, TextFormat, JSONFormat)
+}
+
+// SetFormat sets the format of log messages to f.
+func SetFormat(f Format) {
+	m.Lock()
+	defer m.Unlock()
+	format = f
+}
+
+// CurrentFormat returns the format of log messages.
+func CurrentFormat() Format {
+	m.Lock()
+	defer m.Unlock()
+	return format
+}
+
+// event is a log message of JSONFormat.
+type event struct {
+	Level   string `json:"level"`
+	Time    string `json:"time"`
+	Repos   string `json:"repos,omitempty"`
+	Message string `json:"message"`
+	// Source is the caller of the logging function (only in debug level)
+	Source string `json:"source,omitempty"`
+}
+
+// jsonLine returns msg of level as a line of JSONFormat.
+// skip is the number of stack frames to the caller of logging function.
+func jsonLine(skip int, level LogLevel, repos, msg string) string {
+	e := event{
+		Level:   strings.ToLower(fileLabel[level]),
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Repos:   repos,
+		Message: msg,
+	}
+	if logLevel >= DebugLevel {
+		if _, fn, line, ok := runtime.Caller(skip); ok {
+			const voltDirName = "github.com/vim-volt/volt/"
+			if idx := strings.Index(fn, voltDirName); idx >= 0 {
+				fn = fn[idx+len(voltDirName):]
+			}
+			e.Source = fmt.Sprintf("%s:%d", fn, line)
+		}
+	}
+	b, err := json.Marshal(&e)
+	if err != nil {
+		return msg
+	}
+	return string(b)
+}
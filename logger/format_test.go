@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	prev := SetOutput(&buf)
+	defer SetOutput(prev)
+	SetFormat(JSONFormat)
+	defer SetFormat(TextFormat)
+
+	Infof("Installing %s ...", "tyru/caw.vim")
+	log := NewReposBuffer("github.com/tyru/caw.vim")
+	log.Warn("HEAD is", "detached")
+	log.Flush()
+	Debug("not shown")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines but got %q", lines)
+	}
+	for i, expected := range []event{
+		{Level: "info", Message: "Installing tyru/caw.vim ..."},
+		{Level: "warn", Repos: "github.com/tyru/caw.vim", Message: "HEAD is detached"},
+	} {
+		var e event
+		if err := json.Unmarshal([]byte(lines[i]), &e); err != nil {
+			t.Errorf("line %d is not JSON: %q", i+1, lines[i])
+			continue
+		}
+		if e.Time == "" {
+			t.Errorf("line %d has no time: %q", i+1, lines[i])
+		}
+		e.Time = ""
+		if e != expected {
+			t.Errorf("line %d: expected %+v but got %+v", i+1, expected, e)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"text", "JSON"} {
+		if _, err := ParseFormat(name); err != nil {
+			t.Errorf("ParseFormat(%q): unexpected error: %s", name, err)
+		}
+	}
+	if _, err := ParseFormat("yaml"); err == nil {
+		t.Error("ParseFormat(\"yaml\"): expected an error but got nil")
+	}
+}
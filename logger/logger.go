@@ -2,13 +2,13 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"runtime"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/fatih/color"
-	"github.com/mattn/go-colorable"
 )
 
 // LogLevel = uint
@@ -36,6 +36,12 @@ var out *color.Color
 var m sync.Mutex
 
 func init() {
+	setLabels()
+	out = color.New()
+}
+
+// setLabels sets the labels of each level colored unless color.NoColor.
+func setLabels() {
 	if !color.NoColor {
 		errorLabel = "[" + color.New(color.FgRed).Sprint("ERROR") + "]"
 		warnLabel = "[" + color.New(color.FgYellow).Sprint("WARN") + "]"
@@ -47,109 +53,58 @@ func init() {
 		infoLabel = "[INFO]"
 		debugLabel = "[DEBUG]"
 	}
-	out = color.New()
 }
 
 var logLevel = InfoLevel
 
 // Errorf logs formatted message of arguments.
 func Errorf(format string, msgs ...interface{}) {
-	if logLevel < ErrorLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	msgs = append([]interface{}{getDebugPrefix()}, msgs...)
-	out.Fprintf(colorable.NewColorableStderr(), errorLabel+"%s "+format+"\n", msgs...)
+	(*Buffer)(nil).addf(ErrorLevel, errorLabel, format, msgs)
 }
 
 // Error logs message of arguments.
 func Error(msgs ...interface{}) {
-	if logLevel < ErrorLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	cmsg := getDebugPrefix()
-	msgs = append([]interface{}{errorLabel + cmsg}, msgs...)
-	out.Fprintln(colorable.NewColorableStderr(), msgs...)
+	(*Buffer)(nil).add(ErrorLevel, errorLabel, msgs)
 }
 
 // Warnf logs formatted message of arguments.
 func Warnf(format string, msgs ...interface{}) {
-	if logLevel < WarnLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	msgs = append([]interface{}{getDebugPrefix()}, msgs...)
-	out.Printf(warnLabel+"%s "+format+"\n", msgs...)
+	(*Buffer)(nil).addf(WarnLevel, warnLabel, format, msgs)
 }
 
 // Warn logs message of arguments.
 func Warn(msgs ...interface{}) {
-	if logLevel < WarnLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	cmsg := getDebugPrefix()
-	msgs = append([]interface{}{warnLabel + cmsg}, msgs...)
-	out.Println(msgs...)
+	(*Buffer)(nil).add(WarnLevel, warnLabel, msgs)
 }
 
 // Infof logs formatted message of arguments.
 func Infof(format string, msgs ...interface{}) {
-	if logLevel < InfoLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	msgs = append([]interface{}{getDebugPrefix()}, msgs...)
-	out.Printf(infoLabel+"%s "+format+"\n", msgs...)
+	(*Buffer)(nil).addf(InfoLevel, infoLabel, format, msgs)
 }
 
 // Info logs message of arguments.
 func Info(msgs ...interface{}) {
-	if logLevel < InfoLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	cmsg := getDebugPrefix()
-	msgs = append([]interface{}{infoLabel + cmsg}, msgs...)
-	out.Println(msgs...)
+	(*Buffer)(nil).add(InfoLevel, infoLabel, msgs)
 }
 
 // Debugf logs formatted message of arguments.
 func Debugf(format string, msgs ...interface{}) {
-	if logLevel < DebugLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	msgs = append([]interface{}{getDebugPrefix()}, msgs...)
-	out.Printf(debugLabel+"%s "+format+"\n", msgs...)
+	(*Buffer)(nil).addf(DebugLevel, debugLabel, format, msgs)
 }
 
 // Debug logs message of arguments.
 func Debug(msgs ...interface{}) {
-	if logLevel < DebugLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	cmsg := getDebugPrefix()
-	msgs = append([]interface{}{debugLabel + cmsg}, msgs...)
-	out.Println(msgs...)
+	(*Buffer)(nil).add(DebugLevel, debugLabel, msgs)
 }
 
-func getDebugPrefix() string {
+// getDebugPrefixSkip returns a prefix of debug message.
+// skip is the number of stack frames to the caller of logging function.
+func getDebugPrefixSkip(skip int) string {
 	const voltDirName = "github.com/vim-volt/volt/"
 	if logLevel < DebugLevel {
 		return ""
 	}
-	_, fn, line, _ := runtime.Caller(2)
+	_, fn, line, _ := runtime.Caller(skip)
 	idx := strings.Index(fn, voltDirName)
 	if idx >= 0 {
 		fn = fn[idx+len(voltDirName):]
@@ -161,3 +116,38 @@ func getDebugPrefix() string {
 func SetLevel(level LogLevel) {
 	logLevel = level
 }
+
+// ParseLevel returns the log level of name ("error", "warn", "info", or
+// "debug").
+func ParseLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(name) {
+	case "error":
+		return ErrorLevel, nil
+	case "warn":
+		return WarnLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "debug":
+		return DebugLevel, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q: valid levels are \"error\", \"warn\", \"info\", and \"debug\"", name)
+}
+
+// SetColor enables or disables colored output.
+// Colors are disabled by default if stdout is not a terminal.
+func SetColor(enabled bool) {
+	m.Lock()
+	defer m.Unlock()
+	color.NoColor = !enabled
+	setLabels()
+}
+
+// SetOutput sets the writer of the messages except errors (stdout by
+// default), and returns the previous one.
+func SetOutput(w io.Writer) io.Writer {
+	m.Lock()
+	defer m.Unlock()
+	prev := color.Output
+	color.Output = w
+	return prev
+}
@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// Progress shows one line per task at the bottom of stdout, which is updated
+// in place (like "docker pull"). Log messages are output above the lines
+// while it is shown.
+type Progress struct {
+	w      io.Writer
+	width  int
+	names  []string
+	mu     sync.Mutex
+	status map[string]string
+	dirty  bool
+	// shown is the number of the lines currently drawn
+	shown int
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// progress is the progress currently shown (guarded by m)
+var progress *Progress
+
+// progressInterval is the interval of redrawing the progress lines.
+const progressInterval = 100 * time.Millisecond
+
+// StartProgress shows the lines of names with an empty status, and returns
+// the progress. Only one progress can be shown at once. The caller must
+// check stdout is a terminal.
+func StartProgress(names []string) *Progress {
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	p := newProgress(color.Output, width, names)
+	m.Lock()
+	progress = p
+	p.draw()
+	m.Unlock()
+	go p.loop()
+	return p
+}
+
+func newProgress(w io.Writer, width int, names []string) *Progress {
+	return &Progress{
+		w:      w,
+		width:  width,
+		names:  names,
+		status: make(map[string]string, len(names)),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+func (p *Progress) loop() {
+	defer close(p.done)
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.Lock()
+			if progress == p && p.isDirty() {
+				p.clear()
+				p.draw()
+			}
+			m.Unlock()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Set sets the status of name.
+func (p *Progress) Set(name, status string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status[name] = status
+	p.dirty = true
+}
+
+// Writer returns io.Writer which sets the last line written to it as the
+// status of name. Lines can be terminated with "\r" to overwrite them (e.g.
+// sideband progress of git).
+// nil is returned if p is nil.
+func (p *Progress) Writer(name string) io.Writer {
+	if p == nil {
+		return nil
+	}
+	return &progressWriter{p: p, name: name}
+}
+
+// Stop stops updating the lines, and leaves the last statuses. Log messages
+// are output below them after that. It can be called twice or more.
+func (p *Progress) Stop() {
+	if p == nil {
+		return
+	}
+	m.Lock()
+	if progress != p {
+		m.Unlock()
+		return
+	}
+	progress = nil
+	p.clear()
+	p.draw()
+	p.shown = 0
+	m.Unlock()
+	close(p.stop)
+	<-p.done
+}
+
+func (p *Progress) isDirty() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dirty
+}
+
+// clear erases the lines. The caller must hold the lock of output.
+func (p *Progress) clear() {
+	if p.shown > 0 {
+		fmt.Fprint(p.w, strings.Repeat("\x1b[1A\x1b[2K", p.shown))
+		p.shown = 0
+	}
+}
+
+// draw draws the lines at the cursor. The caller must hold the lock of
+// output.
+func (p *Progress) draw() {
+	p.mu.Lock()
+	p.dirty = false
+	lines := make([]string, 0, len(p.names))
+	for _, name := range p.names {
+		lines = append(lines, p.line(name, p.status[name]))
+	}
+	p.mu.Unlock()
+	for _, line := range lines {
+		fmt.Fprintln(p.w, line)
+	}
+	p.shown = len(lines)
+}
+
+// line returns the line of name. It is truncated not to wrap in the terminal
+// because the wrapped lines cannot be erased.
+func (p *Progress) line(name, status string) string {
+	line := name
+	if status != "" {
+		line += "  " + status
+	}
+	if utf8.RuneCountInString(line) < p.width {
+		return line
+	}
+	runes := []rune(line)
+	return string(runes[:p.width-1])
+}
+
+// hideProgress erases the lines of current progress, and returns a function
+// to draw them again. It is used to output log messages above the lines.
+// The caller must hold the lock of output.
+func hideProgress() func() {
+	p := progress
+	if p == nil {
+		return func() {}
+	}
+	p.clear()
+	return p.draw
+}
+
+type progressWriter struct {
+	p    *Progress
+	name string
+	// pending is the incomplete line written last
+	pending string
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	s := w.pending + string(b)
+	i := strings.LastIndexAny(s, "\r\n")
+	if i < 0 {
+		w.pending = s
+		return len(b), nil
+	}
+	w.pending = s[i+1:]
+	if status := lastProgressLine(s[:i]); status != "" {
+		w.p.Set(w.name, status)
+	}
+	return len(b), nil
+}
+
+// lastProgressLine returns the last non-empty line of s separated by "\r" or
+// "\n".
+func lastProgressLine(s string) string {
+	lines := strings.FieldsFunc(s, func(r rune) bool { return r == '\r' || r == '\n' })
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestProgressWriter(t *testing.T) {
+	p := newProgress(&bytes.Buffer{}, 80, []string{"github.com/tyru/caw.vim"})
+	w := p.Writer("github.com/tyru/caw.vim")
+	for _, tt := range []struct {
+		input    string
+		expected string
+	}{
+		{"Counting objects: 3, done.\n", "Counting objects: 3, done."},
+		{"Receiving objects:  50% (1/2)\r", "Receiving objects:  50% (1/2)"},
+		// An incomplete line is not shown until it is terminated
+		{"Receiving obj", "Receiving objects:  50% (1/2)"},
+		{"ects: 100% (2/2)\rReceiving objects: 100% (2/2), done.\n\n", "Receiving objects: 100% (2/2), done."},
+	} {
+		fmt.Fprint(w, tt.input)
+		if got := p.status["github.com/tyru/caw.vim"]; got != tt.expected {
+			t.Errorf("%q: expected %q but got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	if w := (*Progress)(nil).Writer("github.com/tyru/caw.vim"); w != nil {
+		t.Errorf("expected nil but got %v", w)
+	}
+}
+
+func TestProgressDraw(t *testing.T) {
+	var out bytes.Buffer
+	p := newProgress(&out, 21, []string{"github.com/a/b", "github.com/c/d"})
+	p.Set("github.com/a/b", "Done")
+	p.Set("github.com/c/d", "Receiving objects")
+	p.draw()
+	expected := "github.com/a/b  Done\n" +
+		"github.com/c/d  Rece\n" // truncated not to wrap
+	if out.String() != expected {
+		t.Errorf("expected %q but got %q", expected, out.String())
+	}
+
+	// Log messages are output above the lines
+	out.Reset()
+	progress = p
+	defer func() { progress = nil }()
+	show := hideProgress()
+	fmt.Fprintln(&out, "message")
+	show()
+	expected = "\x1b[1A\x1b[2K\x1b[1A\x1b[2K" + "message\n" + expected
+	if out.String() != expected {
+		t.Errorf("expected %q but got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+)
+
+// summary holds the warning messages which are shown again at the end of a
+// command by PrintWarningSummary(), because warnings of parallel tasks (e.g.
+// installing repositories) scroll away among progress lines.
+// summary is nil if warnings are not collected.
+var summary []string
+var summaryM sync.Mutex
+
+// CollectWarnings starts collecting warning messages for
+// PrintWarningSummary().
+func CollectWarnings() {
+	summaryM.Lock()
+	defer summaryM.Unlock()
+	if summary == nil {
+		summary = make([]string, 0, 8)
+	}
+}
+
+// Warnings returns the collected warning messages.
+func Warnings() []string {
+	summaryM.Lock()
+	defer summaryM.Unlock()
+	return append([]string(nil), summary...)
+}
+
+// PrintWarningSummary shows the collected warning messages if any, and stops
+// collecting warnings.
+func PrintWarningSummary() {
+	summaryM.Lock()
+	warnings := summary
+	summary = nil
+	summaryM.Unlock()
+	if len(warnings) == 0 {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	// Each warning was already output as a JSON object
+	if format == JSONFormat {
+		return
+	}
+	defer hideProgress()()
+	out.Println()
+	out.Printf("%s %d warning(s):\n", warnLabel, len(warnings))
+	for _, msg := range warnings {
+		out.Println("  * " + strings.Replace(msg, "\n", "\n    ", -1))
+	}
+}
+
+// collectWarning adds msg to the warning summary if warnings are collected.
+func collectWarning(msg string) {
+	summaryM.Lock()
+	defer summaryM.Unlock()
+	if summary != nil {
+		summary = append(summary, msg)
+	}
+}
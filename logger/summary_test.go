@@ -0,0 +1,34 @@
+package logger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectWarnings(t *testing.T) {
+	Warn("not collected")
+	if warnings := Warnings(); len(warnings) != 0 {
+		t.Errorf("expected no warnings before CollectWarnings() but got %q", warnings)
+	}
+
+	CollectWarnings()
+	Warnf("%s: HEAD and locked revision are different", "github.com/tyru/caw.vim")
+	Info("info is not collected")
+	log := NewBuffer()
+	log.Warn("buffered", "warning")
+	log.Flush()
+
+	expected := []string{
+		"github.com/tyru/caw.vim: HEAD and locked revision are different",
+		"buffered warning",
+	}
+	if warnings := Warnings(); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("expected %q but got %q", expected, warnings)
+	}
+
+	PrintWarningSummary()
+	Warn("not collected")
+	if warnings := Warnings(); len(warnings) != 0 {
+		t.Errorf("expected no warnings after PrintWarningSummary() but got %q", warnings)
+	}
+}
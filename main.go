@@ -6,13 +6,30 @@ import (
 	"os"
 
 	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
 	"github.com/vim-volt/volt/subcmd"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	// Write all log messages to $VOLTPATH/logs/volt.log
+	// Root (e.g. "sudo volt list") does not write it, not to create the log
+	// files which the user cannot write
+	if !subcmd.IsLightweight(os.Args) && os.Geteuid() != 0 {
+		if closeLog, err := logger.OpenFile(pathutil.LogsDir()); err == nil {
+			defer closeLog()
+		}
+	}
+	logger.Debugf("Command line: %q", os.Args)
+
 	err := subcmd.Run(os.Args, subcmd.DefaultRunner)
 	if err != nil {
 		logger.Error(err.Msg)
-		os.Exit(err.Code)
+		logger.Debugf("Exit with status %d", err.Code)
+		return err.Code
 	}
+	return 0
 }
@@ -0,0 +1,111 @@
+// Package metadata fetches the information of repositories (description,
+// stars, topics, archived status) from GitHub API, and caches it on disk.
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/httputil"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// TTL is the duration while the cached metadata is used without fetching.
+const TTL = 24 * time.Hour
+
+// apiURL is the base URL of GitHub API (overwritten in tests).
+var apiURL = "https://api.github.com"
+
+// now returns current time (overwritten in tests).
+var now = time.Now
+
+// Metadata is the information of a repository on GitHub.
+type Metadata struct {
+	Description string    `json:"description"`
+	Stars       int       `json:"stargazers_count"`
+	Topics      []string  `json:"topics"`
+	Archived    bool      `json:"archived"`
+	URL         string    `json:"html_url"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// Get returns the metadata of reposPath.
+// The cached metadata at reposPath.MetadataCache() is returned if it was
+// fetched within TTL. Otherwise it is fetched from GitHub API, and the cache
+// is updated. If fetching failed, the expired cache is returned if exists.
+// If $GITHUB_TOKEN is set, it is used to authenticate the requests, which
+// raises the rate limit of GitHub API.
+// Zero value is returned if reposPath is not a GitHub repository.
+func Get(ctx context.Context, reposPath pathutil.ReposPath) (*Metadata, error) {
+	userName, ok := reposPath.GitHubUserName()
+	if !ok {
+		return &Metadata{}, nil
+	}
+
+	cached, cacheErr := readCache(reposPath)
+	if cacheErr == nil && now().Sub(cached.FetchedAt) < TTL {
+		return cached, nil
+	}
+	m, err := fetch(ctx, userName)
+	if err != nil {
+		if cacheErr == nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+	if err := writeCache(reposPath, m); err != nil {
+		return nil, errors.Wrap(err, "could not write cache of metadata")
+	}
+	return m, nil
+}
+
+func fetch(ctx context.Context, userName string) (*Metadata, error) {
+	header := make(http.Header)
+	// "topics" requires the preview media type
+	header.Set("Accept", "application/vnd.github.mercy-preview+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		header.Set("Authorization", "token "+token)
+	}
+	r, err := httputil.GetContentReaderWithHeader(ctx, apiURL+"/repos/"+userName, header)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var m Metadata
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	m.FetchedAt = now()
+	return &m, nil
+}
+
+func readCache(reposPath pathutil.ReposPath) (*Metadata, error) {
+	content, err := ioutil.ReadFile(reposPath.MetadataCache())
+	if err != nil {
+		return nil, err
+	}
+	var m Metadata
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func writeCache(reposPath pathutil.ReposPath, m *Metadata) error {
+	content, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	path := reposPath.MetadataCache()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
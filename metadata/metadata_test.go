@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestGet(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-metadata-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+	defer os.Setenv("GITHUB_TOKEN", os.Getenv("GITHUB_TOKEN"))
+	os.Setenv("GITHUB_TOKEN", "secret")
+
+	requests := 0
+	stars := 10
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/repos/tyru/caw.vim" || r.Header.Get("Authorization") != "token secret" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"description": "Vim comment plugin", "stargazers_count": ` + strconv.Itoa(stars) + `, "topics": ["vim"], "archived": true, "html_url": "https://github.com/tyru/caw.vim"}`))
+	}))
+	defer func(orig string) { apiURL = orig }(apiURL)
+	apiURL = server.URL
+	current := time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC)
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return current }
+
+	reposPath := pathutil.ReposPath("github.com/tyru/caw.vim")
+	get := func() *Metadata {
+		m, err := Get(context.Background(), reposPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	expected := &Metadata{
+		Description: "Vim comment plugin",
+		Stars:       10,
+		Topics:      []string{"vim"},
+		Archived:    true,
+		URL:         "https://github.com/tyru/caw.vim",
+		FetchedAt:   current,
+	}
+	if m := get(); !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %+v but got %+v", expected, m)
+	}
+
+	// Cached
+	stars = 20
+	current = current.Add(TTL - time.Second)
+	if m := get(); m.Stars != 10 || requests != 1 {
+		t.Errorf("expected cached metadata but got %+v (%d requests)", m, requests)
+	}
+
+	// Expired
+	current = current.Add(time.Second)
+	if m := get(); m.Stars != 20 || requests != 2 {
+		t.Errorf("expected fetched metadata but got %+v (%d requests)", m, requests)
+	}
+
+	// Expired cache is used if fetching failed
+	current = current.Add(TTL)
+	server.Close()
+	if m := get(); m.Stars != 20 {
+		t.Errorf("expected expired cache but got %+v", m)
+	}
+
+	// Non-GitHub repository
+	m, err := Get(context.Background(), pathutil.ReposPath("localhost/local/foo"))
+	if err != nil || !reflect.DeepEqual(m, &Metadata{}) {
+		t.Errorf("expected zero value but got (%+v, %v)", m, err)
+	}
+}
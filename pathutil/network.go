@@ -0,0 +1,17 @@
+package pathutil
+
+import "strings"
+
+// isUNCPath returns true if path is a UNC path like "\\server\share\..." or
+// "\\?\UNC\server\share\...".
+// Device paths like "\\?\C:\..." and "\\.\..." are not UNC paths.
+func isUNCPath(path string) bool {
+	path = strings.Replace(path, "/", `\`, -1)
+	if strings.HasPrefix(path, `\\?\`) {
+		return strings.HasPrefix(strings.ToUpper(path[4:]), `UNC\`)
+	}
+	if strings.HasPrefix(path, `\\.\`) {
+		return false
+	}
+	return strings.HasPrefix(path, `\\`) && len(path) > 2 && path[2] != '\\'
+}
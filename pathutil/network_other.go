@@ -0,0 +1,10 @@
+// +build !windows
+
+package pathutil
+
+// IsNetworkPath returns true if path is a UNC path or on a mapped network
+// drive. On platforms other than Windows, it always returns false because
+// network filesystems are mounted as normal directories.
+func IsNetworkPath(path string) bool {
+	return false
+}
@@ -0,0 +1,24 @@
+package pathutil
+
+import "testing"
+
+func TestIsUNCPath(t *testing.T) {
+	var tests = []struct {
+		in  string
+		out bool
+	}{
+		{`\\server\share\user`, true},
+		{`//server/share/user`, true},
+		{`\\?\UNC\server\share\user`, true},
+		{`\\?\C:\Users\user`, false},
+		{`\\.\pipe\name`, false},
+		{`C:\Users\user`, false},
+		{`/home/user`, false},
+		{`\\`, false},
+	}
+	for _, tt := range tests {
+		if result := isUNCPath(tt.in); result != tt.out {
+			t.Errorf("in:%s, got:%v, expected:%v", tt.in, result, tt.out)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package pathutil
+
+import (
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procGetDriveType = kernel32.NewProc("GetDriveTypeW")
+)
+
+// driveRemote is the return value of GetDriveTypeW() for network drives.
+const driveRemote = 4
+
+// IsNetworkPath returns true if path is a UNC path or on a mapped network
+// drive.
+func IsNetworkPath(path string) bool {
+	if isUNCPath(path) {
+		return true
+	}
+	vol := filepath.VolumeName(path)
+	if len(vol) != 2 || vol[1] != ':' {
+		return false
+	}
+	root, err := syscall.UTF16PtrFromString(vol + `\`)
+	if err != nil {
+		return false
+	}
+	ret, _, _ := procGetDriveType.Call(uintptr(unsafe.Pointer(root)))
+	return ret == driveRemote
+}
@@ -1,6 +1,8 @@
 package pathutil
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"github.com/pkg/errors"
 	"os"
 	"os/exec"
@@ -13,36 +15,128 @@ import (
 var rxReposPath = regexp.MustCompile(
 	// scheme
 	`^((?:https?|git)://)?` +
-		// host
-		`(?:([^/]+)/)?` +
+		// host[:port]
+		`(?:([^/:@#]+(?::[0-9]+)?)/)?` +
 		// user
-		`(?:([^/]+)/)` +
+		`(?:([^/:@#]+)/)` +
 		// name
-		`([^/]+?)` +
+		`([^/:@#]+?)` +
 		// trailing garbages
-		`(?:\.git)?(/?)$`,
+		`(?:\.git)?(/?)` +
+		// version ("@{branch}", "@{commit}", or "#{tag}")
+		`(?:([@#])(.+))?$`,
 )
 
+var rxCommitHash = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+var reposAliases map[string]string
+
+// SetReposAliases sets aliases of repositories (repos.alias in config.toml)
+// which are expanded by NormalizeRepos().
+// e.g. {"fzf": "junegunn/fzf.vim"}
+func SetReposAliases(aliases map[string]string) {
+	reposAliases = aliases
+}
+
 // NormalizeRepos normalizes name into the following forms into ReposPath:
 // 1. user/name[.git]
 // 2. github.com/user/name[.git]
 // 3. [git|http|https]://github.com/user/name[.git][/]
+// The host can have a port (e.g. git.company.com:8443/user/name).
+// If name is an alias set by SetReposAliases(), its repository is normalized.
+// A version suffix (e.g. "user/name@branch") is ignored, use
+// NormalizeReposRef() to get it.
 func NormalizeRepos(rawReposPath string) (ReposPath, error) {
+	reposPath, _, err := NormalizeReposRef(rawReposPath)
+	return reposPath, err
+}
+
+// NormalizeReposRef is the same as NormalizeRepos() but also returns the
+// version suffix of name:
+// 1. user/name@{branch}
+// 2. user/name@{commit} (7-40 hex digits)
+// 3. user/name#{tag}
+// If name does not have a version, zero value of ReposRef is returned.
+func NormalizeReposRef(rawReposPath string) (ReposPath, ReposRef, error) {
+	rawReposPath = expandReposAlias(rawReposPath)
 	p := filepath.ToSlash(rawReposPath)
 	m := rxReposPath.FindStringSubmatch(p)
 	if len(m) == 0 {
-		return "", errors.New("invalid format of repository: " + rawReposPath)
+		return "", ReposRef{}, errors.New("invalid format of repository: " + rawReposPath)
 	}
 	if m[2] == "" {
 		m[2] = "github.com"
 	}
 	disallowSlash := m[1] == ""
 	if disallowSlash && m[5] == "/" {
-		return "", errors.New("invalid format of repository: " + rawReposPath)
+		return "", ReposRef{}, errors.New("invalid format of repository: " + rawReposPath)
 	}
 	m[2] = strings.ToLower(m[2]) // ignore hostname's case
 	hostUserName := m[2:5]
-	return ReposPath(strings.Join(hostUserName, "/")), nil
+	// Reject "." and ".." not to point outside of ReposDir()
+	for _, name := range hostUserName {
+		if name == "." || name == ".." {
+			return "", ReposRef{}, errors.New("invalid format of repository: " + rawReposPath)
+		}
+	}
+
+	var ref ReposRef
+	switch {
+	case m[6] == "#":
+		ref = ReposRef{Type: ReposRefTag, Name: m[7]}
+	case m[6] == "@" && rxCommitHash.MatchString(m[7]):
+		ref = ReposRef{Type: ReposRefCommit, Name: m[7]}
+	case m[6] == "@":
+		ref = ReposRef{Type: ReposRefBranch, Name: m[7]}
+	}
+	return ReposPath(strings.Join(hostUserName, "/")), ref, nil
+}
+
+// expandReposAlias expands an alias (optionally with a version suffix like
+// "fzf@master") set by SetReposAliases().
+func expandReposAlias(rawReposPath string) string {
+	if aliased, exists := reposAliases[rawReposPath]; exists {
+		return aliased
+	}
+	if i := strings.IndexAny(rawReposPath, "@#"); i > 0 {
+		aliased, exists := reposAliases[rawReposPath[:i]]
+		if exists && !strings.ContainsAny(aliased, "@#") {
+			return aliased + rawReposPath[i:]
+		}
+	}
+	return rawReposPath
+}
+
+// ReposRefType is a type of ReposRef.
+type ReposRefType string
+
+const (
+	// ReposRefBranch is a branch name ("user/name@{branch}")
+	ReposRefBranch ReposRefType = "branch"
+	// ReposRefTag is a tag name ("user/name#{tag}")
+	ReposRefTag ReposRefType = "tag"
+	// ReposRefCommit is a (abbreviated) commit hash ("user/name@{commit}")
+	ReposRefCommit ReposRefType = "commit"
+)
+
+// ReposRef is a version of repository specified with repository path.
+type ReposRef struct {
+	Type ReposRefType
+	Name string
+}
+
+// IsZero returns true if ref is not specified.
+func (ref ReposRef) IsZero() bool {
+	return ref.Name == ""
+}
+
+// String returns the version suffix of repository path (e.g. "@master",
+// "#v1.0.0").
+func (ref ReposRef) String() string {
+	if ref.Type == ReposRefTag {
+		return "#" + ref.Name
+	}
+	return "@" + ref.Name
 }
 
 // ReposPath is string of "{site}/{user}/{repos}"
@@ -69,16 +163,57 @@ func (path ReposPath) ignoreCase() bool {
 	return true
 }
 
+// Host returns "{site}" part of ReposPath (including port if exists).
+func (path ReposPath) Host() string {
+	return strings.SplitN(filepath.ToSlash(path.String()), "/", 2)[0]
+}
+
+// GitHubUserName returns "{user}/{name}" part of ReposPath.
+// false is returned if ReposPath is not a GitHub repository.
+func (path ReposPath) GitHubUserName() (string, bool) {
+	if !strings.EqualFold(path.Host(), "github.com") {
+		return "", false
+	}
+	return strings.TrimPrefix(filepath.ToSlash(path.String()), path.Host()+"/"), true
+}
+
+// hostToDirName replaces ":" before port with "+" in "{site}" part, because
+// ":" cannot be used in filenames on some platforms.
+func hostToDirName(path string) string {
+	return strings.Replace(path, ":", "+", 1)
+}
+
+// dirNameToHost is a reverse function of hostToDirName.
+func dirNameToHost(path string) string {
+	host := strings.SplitN(path, "/", 2)
+	host[0] = strings.Replace(host[0], "+", ":", 1)
+	return strings.Join(host, "/")
+}
+
 // FullPath returns fullpath of ReposPath.
 func (path ReposPath) FullPath() string {
-	reposList := strings.Split(filepath.ToSlash(path.String()), "/")
-	paths := make([]string, 0, len(reposList)+2)
-	paths = append(paths, VoltPath())
-	paths = append(paths, "repos")
+	reposList := strings.Split(hostToDirName(filepath.ToSlash(path.String())), "/")
+	paths := make([]string, 0, len(reposList)+1)
+	paths = append(paths, ReposDir())
 	paths = append(paths, reposList...)
 	return filepath.Join(paths...)
 }
 
+// ReposDir returns fullpath of "$VOLTPATH/repos".
+func ReposDir() string {
+	return filepath.Join(VoltDataDir(), "repos")
+}
+
+// ReposPathOfDir returns the repository path of dir, which is the directory
+// of a repository under ReposDir() (the reverse of FullPath()).
+func ReposPathOfDir(dir string) (ReposPath, error) {
+	rel, err := filepath.Rel(ReposDir(), dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", errors.New(dir + " is not under " + ReposDir())
+	}
+	return ReposPath(dirNameToHost(filepath.ToSlash(rel))), nil
+}
+
 // CloneURL returns string "https://{reposPath}".
 func (path ReposPath) CloneURL() string {
 	return "https://" + filepath.ToSlash(path.String())
@@ -86,14 +221,47 @@ func (path ReposPath) CloneURL() string {
 
 // Plugconf returns fullpath of plugconf.
 func (path ReposPath) Plugconf() string {
-	filenameList := strings.Split(filepath.ToSlash(path.String()+".vim"), "/")
+	filenameList := strings.Split(hostToDirName(filepath.ToSlash(path.String()+".vim")), "/")
+	paths := make([]string, 0, len(filenameList)+1)
+	paths = append(paths, PlugconfDir())
+	paths = append(paths, filenameList...)
+	return filepath.Join(paths...)
+}
+
+// PlugconfDir returns fullpath of the directory which has plugconf files.
+func PlugconfDir() string {
+	return filepath.Join(VoltConfigDir(), "plugconf")
+}
+
+// ReposPathOfPlugconf returns the repository path of path, which is a
+// plugconf file under PlugconfDir() (the reverse of Plugconf()).
+func ReposPathOfPlugconf(path string) (ReposPath, error) {
+	rel, err := filepath.Rel(PlugconfDir(), path)
+	if err != nil || strings.HasPrefix(rel, "..") || filepath.Ext(rel) != ".vim" {
+		return "", errors.New(path + " is not a plugconf file under " + PlugconfDir())
+	}
+	rel = strings.TrimSuffix(rel, ".vim")
+	return ReposPath(dirNameToHost(filepath.ToSlash(rel))), nil
+}
+
+// MetadataCache returns fullpath of the cache file of the repository
+// information fetched from the hosting service.
+func (path ReposPath) MetadataCache() string {
+	filenameList := strings.Split(hostToDirName(filepath.ToSlash(path.String()+".json")), "/")
 	paths := make([]string, 0, len(filenameList)+2)
-	paths = append(paths, VoltPath())
-	paths = append(paths, "plugconf")
+	paths = append(paths, VoltCacheDir())
+	paths = append(paths, "metadata")
 	paths = append(paths, filenameList...)
 	return filepath.Join(paths...)
 }
 
+// VimAwesomeCache returns fullpath of the cache file of the search results
+// of query on Vim Awesome.
+func VimAwesomeCache(query string) string {
+	hash := sha1.Sum([]byte(query))
+	return filepath.Join(VoltCacheDir(), "metadata", "vimawesome.com", "search", hex.EncodeToString(hash[:])+".json")
+}
+
 // ReposPathList is []ReposPath
 type ReposPathList []ReposPath
 
@@ -134,9 +302,22 @@ const Vimrc = "vimrc"
 // Gvimrc is the basename of gvimrc in ~/.vim
 const Gvimrc = "gvimrc"
 
+// ProfileInitLua is the basename of profile init.lua, which is installed
+// only for Neovim.
+const ProfileInitLua = "init.lua"
+
+// NvimInitVim is the basename of init.vim in Neovim's config directory.
+const NvimInitVim = "init.vim"
+
+// NvimGinitVim is the basename of ginit.vim in Neovim's config directory.
+const NvimGinitVim = "ginit.vim"
+
+// NvimInitLua is the basename of init.lua in Neovim's config directory.
+const NvimInitLua = "init.lua"
+
 // RCDir returns fullpath of "$HOME/volt/rc/{profileName}"
 func RCDir(profileName string) string {
-	return filepath.Join([]string{VoltPath(), "rc", profileName}...)
+	return filepath.Join([]string{VoltConfigDir(), "rc", profileName}...)
 }
 
 var packer = strings.NewReplacer("_", "__", "/", "_")
@@ -146,7 +327,7 @@ var unpacker2 = strings.NewReplacer("//", "_")
 // EncodeToPlugDirName encodes path to directory name.
 // The directory name is: ~/.vim/pack/volt/opt/{name}
 func (path ReposPath) EncodeToPlugDirName() string {
-	p := packer.Replace(path.String())
+	p := packer.Replace(hostToDirName(filepath.ToSlash(path.String())))
 	return filepath.Join(VimVoltOptDir(), p)
 }
 
@@ -154,30 +335,45 @@ func (path ReposPath) EncodeToPlugDirName() string {
 // name is directory name: ~/.vim/pack/volt/opt/{name}
 func DecodeReposPath(name string) ReposPath {
 	name = filepath.Base(name)
-	return ReposPath(unpacker2.Replace(unpacker1.Replace(name)))
+	return ReposPath(dirNameToHost(unpacker2.Replace(unpacker1.Replace(name))))
 }
 
 // HomeDir detects HOME path.
 // If HOME environment variable is not set,
 // use USERPROFILE environment variable instead.
+// On Windows, HOMEDRIVE and HOMEPATH (e.g. a mapped network drive "H:\"), or
+// HOMESHARE (e.g. an UNC path "\\server\share\user") are also used if both
+// are not set.
 func HomeDir() string {
 	home := os.Getenv("HOME")
 	if home != "" {
-		return home
+		return filepath.Clean(home)
 	}
 
 	home = os.Getenv("USERPROFILE") // windows
 	if home != "" {
-		return home
+		return filepath.Clean(home)
+	}
+
+	if runtime.GOOS == "windows" {
+		if drive, path := os.Getenv("HOMEDRIVE"), os.Getenv("HOMEPATH"); drive != "" && path != "" {
+			return filepath.Clean(drive + path)
+		}
+		if share := os.Getenv("HOMESHARE"); share != "" {
+			return filepath.Clean(share)
+		}
 	}
 
 	panic("Couldn't look up HOME")
 }
 
-// VoltPath returns fullpath of "$HOME/volt".
+// VoltPath returns $VOLTPATH, or the path written in .voltpath file in
+// current directory or its ancestors, or fullpath of "$HOME/volt".
+// Note that volt files may be placed in XDG layout directories instead of
+// "$HOME/volt", use VoltConfigDir(), VoltDataDir(), VoltCacheDir() to get
+// directories.
 func VoltPath() string {
-	path := os.Getenv("VOLTPATH")
-	if path != "" {
+	if path := explicitVoltPath(); path != "" {
 		return path
 	}
 	return filepath.Join(HomeDir(), "volt")
@@ -185,37 +381,112 @@ func VoltPath() string {
 
 // LockJSON returns fullpath of "$HOME/volt/lock.json".
 func LockJSON() string {
-	return filepath.Join(VoltPath(), "lock.json")
+	return filepath.Join(VoltConfigDir(), "lock.json")
 }
 
 // ConfigTOML returns fullpath of "$HOME/volt/config.toml".
 func ConfigTOML() string {
-	return filepath.Join(VoltPath(), "config.toml")
+	return filepath.Join(VoltConfigDir(), "config.toml")
 }
 
 // TrxDir returns fullpath of "$HOME/volt/trx".
 func TrxDir() string {
-	return filepath.Join(VoltPath(), "trx")
+	return filepath.Join(VoltDataDir(), "trx")
+}
+
+// KeptReposFile returns fullpath of "$HOME/volt/kept-repos", which has the
+// repositories removed by "volt rm -keep-repos" (one per line).
+func KeptReposFile() string {
+	return filepath.Join(VoltDataDir(), "kept-repos")
+}
+
+// SnapshotsDir returns fullpath of "$HOME/volt/snapshots", which has the
+// snapshots created by "volt snapshot create".
+func SnapshotsDir() string {
+	return filepath.Join(VoltDataDir(), "snapshots")
+}
+
+// LogsDir returns fullpath of "$HOME/volt/logs".
+func LogsDir() string {
+	return filepath.Join(VoltCacheDir(), "logs")
+}
+
+// ObjectsDir returns fullpath of "$HOME/volt/objects", which is the store of
+// the files installed by "copy" builder.
+func ObjectsDir() string {
+	return filepath.Join(VoltCacheDir(), "objects")
+}
+
+// NotifyStatusFile returns fullpath of "$HOME/volt/notify.json", which has
+// the result of "volt notify -write".
+func NotifyStatusFile() string {
+	return filepath.Join(VoltCacheDir(), "notify.json")
 }
 
 // TempDir returns fullpath of "$HOME/tmp".
 func TempDir() string {
-	return filepath.Join(VoltPath(), "tmp")
+	return filepath.Join(VoltCacheDir(), "tmp")
 }
 
 // VimExecutable detects vim executable path.
 // If VOLT_VIM environment variable is set, use it.
-// Otherwise look up "vim" binary from PATH.
-func VimExecutable() (string, error) {
-	var vim string
-	if vim = os.Getenv("VOLT_VIM"); vim != "" {
+// Otherwise if configured (build.vim_executable of config.toml) is not empty,
+// look up it from PATH.
+// Otherwise look up "vim" binary from PATH, and then "nvim" binary.
+func VimExecutable(configured string) (string, error) {
+	if vim := os.Getenv("VOLT_VIM"); vim != "" {
 		return vim, nil
 	}
-	exeName := "vim"
+	if configured != "" {
+		return exec.LookPath(configured)
+	}
+	for _, exeName := range []string{"vim", "nvim"} {
+		if runtime.GOOS == "windows" {
+			exeName += ".exe"
+		}
+		if vim, err := exec.LookPath(exeName); err == nil {
+			return vim, nil
+		}
+	}
+	return "", errors.New("neither vim nor nvim executable was found in PATH")
+}
+
+// IsNeovim returns true if vimExePath is a path of Neovim executable.
+func IsNeovim(vimExePath string) bool {
+	name := strings.ToLower(filepath.Base(vimExePath))
+	return strings.HasPrefix(name, "nvim")
+}
+
+// NvimExecutable detects the executable to execute ":helptags" for Neovim.
+// If VimExecutable(configured) is Neovim, use it.
+// Otherwise look up "nvim" binary from PATH, and then falls back to
+// VimExecutable(configured) because the tags files are compatible.
+func NvimExecutable(configured string) (string, error) {
+	vim, err := VimExecutable(configured)
+	if err == nil && IsNeovim(vim) {
+		return vim, nil
+	}
+	exeName := "nvim"
 	if runtime.GOOS == "windows" {
-		exeName = "vim.exe"
+		exeName += ".exe"
+	}
+	if nvim, err := exec.LookPath(exeName); err == nil {
+		return nvim, nil
 	}
-	return exec.LookPath(exeName)
+	return vim, err
+}
+
+var neovimTarget bool
+
+// SetNeovimTarget makes VimVoltDir() and its subdirectories point to
+// NvimVoltDir() if neovim is true (build.target of config.toml).
+func SetNeovimTarget(neovim bool) {
+	neovimTarget = neovim
+}
+
+// IsNeovimTarget returns true if volt installs plugins for Neovim.
+func IsNeovimTarget() bool {
+	return neovimTarget
 }
 
 // VimDir returns the following fullpath:
@@ -229,19 +500,57 @@ func VimDir() string {
 	return filepath.Join(HomeDir(), vimdir)
 }
 
-// VimVoltDir returns "(vim dir)/pack/volt".
+// NvimDir returns "stdpath('data')/site" of Neovim:
+//   Windows: $XDG_DATA_HOME/nvim-data/site or %LOCALAPPDATA%/nvim-data/site
+//   Other: $XDG_DATA_HOME/nvim/site or $HOME/.local/share/nvim/site
+func NvimDir() string {
+	return filepath.Join(nvimStdpath(xdgData, "nvim-data"), "site")
+}
+
+// NvimConfigDir returns "stdpath('config')" of Neovim, which has init.vim:
+//   Windows: $XDG_CONFIG_HOME/nvim or %LOCALAPPDATA%/nvim
+//   Other: $XDG_CONFIG_HOME/nvim or $HOME/.config/nvim
+func NvimConfigDir() string {
+	return nvimStdpath(xdgConfig, "nvim")
+}
+
+// nvimStdpath returns "{base}/nvim" (or "{base}/{windowsName}" on Windows).
+func nvimStdpath(d xdgBaseDir, windowsName string) string {
+	if runtime.GOOS != "windows" {
+		return filepath.Join(d.base(), "nvim")
+	}
+	base := os.Getenv(d.env)
+	if base == "" || !filepath.IsAbs(base) {
+		base = os.Getenv("LOCALAPPDATA")
+	}
+	if base == "" {
+		base = filepath.Join(HomeDir(), "AppData", "Local")
+	}
+	return filepath.Join(base, windowsName)
+}
+
+// NvimVoltDir returns "(nvim dir)/pack/volt".
+func NvimVoltDir() string {
+	return filepath.Join(NvimDir(), "pack", "volt")
+}
+
+// VimVoltDir returns "(vim dir)/pack/volt", or NvimVoltDir() if
+// SetNeovimTarget(true) was called.
 func VimVoltDir() string {
+	if neovimTarget {
+		return NvimVoltDir()
+	}
 	return filepath.Join(VimDir(), "pack", "volt")
 }
 
 // VimVoltOptDir returns "(vim dir)/pack/volt/opt".
 func VimVoltOptDir() string {
-	return filepath.Join(VimDir(), "pack", "volt", "opt")
+	return filepath.Join(VimVoltDir(), "opt")
 }
 
 // VimVoltStartDir returns "(vim dir)/pack/volt/start".
 func VimVoltStartDir() string {
-	return filepath.Join(VimDir(), "pack", "volt", "start")
+	return filepath.Join(VimVoltDir(), "start")
 }
 
 // BuildInfoJSON returns "(vim dir)/pack/volt/build-info.json".
@@ -254,6 +563,11 @@ func BundledPlugConf() string {
 	return filepath.Join(VimVoltStartDir(), "system", "plugin", "bundled_plugconf.vim")
 }
 
+// BundledPlugConfAutoload returns "(vim dir)/pack/volt/start/system/autoload/volt/bundled_plugconf.vim".
+func BundledPlugConfAutoload() string {
+	return filepath.Join(VimVoltStartDir(), "system", "autoload", "volt", "bundled_plugconf.vim")
+}
+
 // LookUpVimrc looks up vimrc path from the following candidates:
 //   Windows  : $HOME/_vimrc
 //              (vim dir)/vimrc
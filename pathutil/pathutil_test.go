@@ -1,6 +1,13 @@
 package pathutil
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
 
 func TestNormalizeRepos(t *testing.T) {
 	var tests = []struct {
@@ -25,6 +32,8 @@ func TestNormalizeRepos(t *testing.T) {
 		{"git://github.com/user/name.git/", ReposPath("github.com/user/name")},
 		{"localhost/local/name", ReposPath("localhost/local/name")},
 		{"localhost/local/name.git", ReposPath("localhost/local/name")},
+		{"git.company.com:8443/team/name", ReposPath("git.company.com:8443/team/name")},
+		{"https://Git.Company.com:8443/team/name.git", ReposPath("git.company.com:8443/team/name")},
 	}
 	for _, tt := range tests {
 		result, err := NormalizeRepos(tt.in)
@@ -44,6 +53,12 @@ func TestNormalizeReposError(t *testing.T) {
 		"ftp://github.com/user/name.git",
 		"user/name/",
 		"github.com/user/name/",
+		"git.company.com:port/team/name",
+		"git@github.com:user/name",
+		"../../name",
+		"github.com/../name",
+		"github.com/user/..",
+		"https://github.com/./name",
 	}
 	for _, tt := range tests {
 		_, err := NormalizeRepos(tt)
@@ -52,3 +67,157 @@ func TestNormalizeReposError(t *testing.T) {
 		}
 	}
 }
+
+func TestGitHubUserName(t *testing.T) {
+	for reposPath, expected := range map[ReposPath]string{
+		"github.com/tyru/caw.vim": "tyru/caw.vim",
+		"GitHub.com/tyru/caw.vim": "tyru/caw.vim",
+		"gitlab.com/tyru/caw.vim": "",
+		"localhost/local/hello":   "",
+	} {
+		userName, ok := reposPath.GitHubUserName()
+		if userName != expected || ok != (expected != "") {
+			t.Errorf("%s: expected (%q, %v) but got (%q, %v)", reposPath, expected, expected != "", userName, ok)
+		}
+	}
+}
+
+func TestReposPathWithPort(t *testing.T) {
+	reposPath := ReposPath("git.company.com:8443/team/name")
+	if host := reposPath.Host(); host != "git.company.com:8443" {
+		t.Errorf("expected host is %q but got %q", "git.company.com:8443", host)
+	}
+	if strings.Contains(reposPath.FullPath(), ":8443") {
+		t.Errorf("expected ':' is not contained in %q", reposPath.FullPath())
+	}
+	if strings.Contains(reposPath.Plugconf(), ":8443") {
+		t.Errorf("expected ':' is not contained in %q", reposPath.Plugconf())
+	}
+	dir := reposPath.EncodeToPlugDirName()
+	if strings.Contains(filepath.Base(dir), ":") {
+		t.Errorf("expected ':' is not contained in %q", dir)
+	}
+	if decoded := DecodeReposPath(dir); decoded != reposPath {
+		t.Errorf("expected %q is decoded to %q but got %q", dir, reposPath, decoded)
+	}
+	if got, err := ReposPathOfDir(reposPath.FullPath()); err != nil || got != reposPath {
+		t.Errorf("expected (%q, nil) but got (%q, %v)", reposPath, got, err)
+	}
+	if got, err := ReposPathOfPlugconf(reposPath.Plugconf()); err != nil || got != reposPath {
+		t.Errorf("expected (%q, nil) but got (%q, %v)", reposPath, got, err)
+	}
+}
+
+func TestNormalizeReposAlias(t *testing.T) {
+	SetReposAliases(map[string]string{
+		"fzf":  "junegunn/fzf.vim",
+		"work": "https://git.company.com:8443/team/name.git",
+	})
+	defer SetReposAliases(nil)
+
+	var tests = []struct {
+		in  string
+		out ReposPath
+	}{
+		{"fzf", ReposPath("github.com/junegunn/fzf.vim")},
+		{"work", ReposPath("git.company.com:8443/team/name")},
+		{"user/name", ReposPath("github.com/user/name")},
+	}
+	for _, tt := range tests {
+		result, err := NormalizeRepos(tt.in)
+		if err != nil {
+			t.Errorf("in:%s, err:%s", tt.in, err.Error())
+		}
+		if result != tt.out {
+			t.Errorf("in:%s, got:%s, expected:%s", tt.in, result, tt.out)
+		}
+	}
+	if _, err := NormalizeRepos("FZF"); err == nil {
+		t.Error("expected aliases are case-sensitive but 'FZF' was expanded")
+	}
+}
+
+func TestNormalizeReposRef(t *testing.T) {
+	SetReposAliases(map[string]string{"fzf": "junegunn/fzf.vim"})
+	defer SetReposAliases(nil)
+
+	var tests = []struct {
+		in   string
+		path ReposPath
+		ref  ReposRef
+	}{
+		{"user/name", ReposPath("github.com/user/name"), ReposRef{}},
+		{"user/name@master", ReposPath("github.com/user/name"), ReposRef{ReposRefBranch, "master"}},
+		{"user/name@feature/foo", ReposPath("github.com/user/name"), ReposRef{ReposRefBranch, "feature/foo"}},
+		{"user/name.git@develop", ReposPath("github.com/user/name"), ReposRef{ReposRefBranch, "develop"}},
+		{"user/name#v1.0.0", ReposPath("github.com/user/name"), ReposRef{ReposRefTag, "v1.0.0"}},
+		{"user/name@0a1b2c3", ReposPath("github.com/user/name"), ReposRef{ReposRefCommit, "0a1b2c3"}},
+		{"https://github.com/user/name@v2", ReposPath("github.com/user/name"), ReposRef{ReposRefBranch, "v2"}},
+		{"git.company.com:8443/team/name#v1", ReposPath("git.company.com:8443/team/name"), ReposRef{ReposRefTag, "v1"}},
+		{"fzf@devel", ReposPath("github.com/junegunn/fzf.vim"), ReposRef{ReposRefBranch, "devel"}},
+	}
+	for _, tt := range tests {
+		path, ref, err := NormalizeReposRef(tt.in)
+		if err != nil {
+			t.Errorf("in:%s, err:%s", tt.in, err.Error())
+			continue
+		}
+		if path != tt.path || ref != tt.ref {
+			t.Errorf("in:%s, got:%s %+v, expected:%s %+v", tt.in, path, ref, tt.path, tt.ref)
+		}
+	}
+
+	for _, in := range []string{"user/name@", "user/name#", "user/name/@master"} {
+		if _, _, err := NormalizeReposRef(in); err == nil {
+			t.Errorf("in:%s -> expected error but no error", in)
+		}
+	}
+}
+
+func TestVimExecutable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake executables are not .exe files")
+	}
+	tempDir, err := ioutil.TempDir("", "volt-test-vim-executable-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", tempDir)
+	defer os.Setenv("VOLT_VIM", os.Getenv("VOLT_VIM"))
+	os.Setenv("VOLT_VIM", "")
+	install := func(name string) string {
+		path := filepath.Join(tempDir, name)
+		if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	if vim, err := VimExecutable(""); err == nil {
+		t.Errorf("expected error but got %q", vim)
+	}
+	// Falls back to nvim
+	nvim := install("nvim")
+	if vim, err := VimExecutable(""); err != nil || vim != nvim {
+		t.Errorf("expected (%q, nil) but got (%q, %v)", nvim, vim, err)
+	}
+	// vim is preferred
+	vim := install("vim")
+	if got, err := VimExecutable(""); err != nil || got != vim {
+		t.Errorf("expected (%q, nil) but got (%q, %v)", vim, got, err)
+	}
+	// Configured executable is preferred
+	if got, err := VimExecutable("nvim"); err != nil || got != nvim {
+		t.Errorf("expected (%q, nil) but got (%q, %v)", nvim, got, err)
+	}
+	if got, err := VimExecutable("gvim"); err == nil {
+		t.Errorf("expected error for missing executable but got %q", got)
+	}
+	// $VOLT_VIM is preferred
+	os.Setenv("VOLT_VIM", "/opt/vim/bin/vim")
+	if got, err := VimExecutable("nvim"); err != nil || got != "/opt/vim/bin/vim" {
+		t.Errorf("expected (%q, nil) but got (%q, %v)", "/opt/vim/bin/vim", got, err)
+	}
+}
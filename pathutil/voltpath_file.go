@@ -0,0 +1,67 @@
+package pathutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// VoltPathFileName is the name of a file which has a path of volt directory.
+// If the file exists in current directory or its ancestors, the path written
+// in the file is used as $VOLTPATH.
+const VoltPathFileName = ".voltpath"
+
+var voltPathFileOnce sync.Once
+var voltPathFromFile string
+
+// explicitVoltPath returns $VOLTPATH if it is set. Otherwise it returns the
+// path written in .voltpath file, or an empty string if not found.
+func explicitVoltPath() string {
+	if path := os.Getenv("VOLTPATH"); path != "" {
+		return path
+	}
+	voltPathFileOnce.Do(func() {
+		if wd, err := os.Getwd(); err == nil {
+			voltPathFromFile = lookUpVoltPathFile(wd)
+		}
+	})
+	return voltPathFromFile
+}
+
+// lookUpVoltPathFile walks up from dir looking for .voltpath file, and
+// returns the path written in the first found file.
+// A relative path is resolved from the directory of the file, and "~" is
+// expanded to home directory.
+// An empty string is returned if not found, or the found file is empty.
+func lookUpVoltPathFile(dir string) string {
+	for {
+		content, err := ioutil.ReadFile(filepath.Join(dir, VoltPathFileName))
+		if err == nil {
+			return parseVoltPathFile(dir, string(content))
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func parseVoltPathFile(dir, content string) string {
+	path := strings.TrimSpace(content)
+	if path == "" {
+		return ""
+	}
+	if path == "~" {
+		path = HomeDir()
+	} else if strings.HasPrefix(path, "~/") {
+		path = filepath.Join(HomeDir(), path[2:])
+	}
+	path = filepath.FromSlash(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path)
+}
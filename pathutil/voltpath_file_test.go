@@ -0,0 +1,42 @@
+package pathutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookUpVoltPathFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+
+	project := filepath.Join(tempDir, "project")
+	subdir := filepath.Join(project, "src", "foo")
+	os.MkdirAll(subdir, 0755)
+
+	if path := lookUpVoltPathFile(subdir); path != "" {
+		t.Errorf("expected no .voltpath file is found but got %q", path)
+	}
+
+	for _, tt := range []struct {
+		content  string
+		expected string
+	}{
+		{"/opt/volt\n", filepath.Clean("/opt/volt")},
+		{"  .volt  ", filepath.Join(project, ".volt")},
+		{"../shared/volt", filepath.Join(tempDir, "shared", "volt")},
+		{"~/volt-work", filepath.Join(HomeDir(), "volt-work")},
+		{"\n", ""},
+	} {
+		if err := ioutil.WriteFile(filepath.Join(project, VoltPathFileName), []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if path := lookUpVoltPathFile(subdir); path != tt.expected {
+			t.Errorf("content=%q: expected %q but got %q", tt.content, tt.expected, path)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package pathutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// Volt files are placed in the following directories:
+//   * If $VOLTPATH is set: $VOLTPATH
+//   * If .voltpath file is found: the path written in the file
+//   * On Windows or macOS: ~/volt
+//   * If ~/volt exists and XDG layout directories do not exist: ~/volt
+//   * Otherwise (XDG layout):
+//     * VoltConfigDir() = $XDG_CONFIG_HOME/volt: config.toml, lock.json, plugconf, rc
+//     * VoltDataDir()   = $XDG_DATA_HOME/volt: repos, trx
+//     * VoltCacheDir()  = $XDG_CACHE_HOME/volt: logs, tmp
+// ~/volt is moved to XDG layout by MigrateToXDG() ("volt migrate xdg").
+
+// xdgBaseDir is a base directory of XDG Base Directory Specification.
+type xdgBaseDir struct {
+	env        string
+	defaultDir string
+}
+
+var (
+	xdgConfig = xdgBaseDir{"XDG_CONFIG_HOME", ".config"}
+	xdgData   = xdgBaseDir{"XDG_DATA_HOME", filepath.Join(".local", "share")}
+	xdgCache  = xdgBaseDir{"XDG_CACHE_HOME", ".cache"}
+)
+
+// base returns "${env}". If the environment variable is not set or not an
+// absolute path, "$HOME/{defaultDir}" is returned.
+func (d xdgBaseDir) base() string {
+	base := os.Getenv(d.env)
+	if base == "" || !filepath.IsAbs(base) {
+		base = filepath.Join(HomeDir(), d.defaultDir)
+	}
+	return base
+}
+
+// voltDir returns "{base}/volt".
+func (d xdgBaseDir) voltDir() string {
+	return filepath.Join(d.base(), "volt")
+}
+
+// xdgEntries are the entries of ~/volt which are moved by MigrateToXDG().
+var xdgEntries = map[string]xdgBaseDir{
+	"config.toml": xdgConfig,
+	"lock.json":   xdgConfig,
+	"plugconf":    xdgConfig,
+	"rc":          xdgConfig,
+	"kept-repos":  xdgData,
+	"repos":       xdgData,
+	"snapshots":   xdgData,
+	"trx":         xdgData,
+	"logs":        xdgCache,
+	"metadata":    xdgCache,
+	"notify.json": xdgCache,
+	"objects":     xdgCache,
+	"tmp":         xdgCache,
+}
+
+// VoltConfigDir returns fullpath of the directory which has config.toml,
+// lock.json, plugconf, rc directories.
+func VoltConfigDir() string {
+	return voltDir(xdgConfig)
+}
+
+// VoltDataDir returns fullpath of the directory which has repos, snapshots,
+// trx directories.
+func VoltDataDir() string {
+	return voltDir(xdgData)
+}
+
+// VoltCacheDir returns fullpath of the directory which has logs, metadata, objects, tmp
+// directories.
+func VoltCacheDir() string {
+	return voltDir(xdgCache)
+}
+
+func voltDir(d xdgBaseDir) string {
+	if explicitVoltPath() != "" || !useXDG() {
+		return VoltPath()
+	}
+	return d.voltDir()
+}
+
+// useXDG returns true if volt files are placed in XDG layout.
+// The directories which have config.toml, lock.json and repos decide the
+// layout: once $XDG_CONFIG_HOME/volt or $XDG_DATA_HOME/volt is created (by a
+// new installation or MigrateToXDG()), XDG layout is used even if ~/volt is
+// created again (e.g. to put a symlink to lock.json).
+// ~/volt is used until it is migrated.
+func useXDG() bool {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return false
+	}
+	if Exists(xdgConfig.voltDir()) || Exists(xdgData.voltDir()) {
+		return true
+	}
+	return !Exists(filepath.Join(HomeDir(), "volt"))
+}
+
+// MigrateToXDG moves entries of ~/volt to XDG layout directories, and
+// removes ~/volt. An error is returned and nothing is done if:
+//   - $VOLTPATH is set, or .voltpath file is found
+//   - the platform does not use XDG layout
+//   - ~/volt does not exist, or is a symlink
+//   - ~/volt has unknown entries, or other volt process is running
+//   - XDG layout directories already have the entries
+//
+// If it failed to move an entry, moved entries are moved back to ~/volt.
+// Symlinks in ~/.vim/pack/volt still point to ~/volt/repos after the
+// migration, the caller must rebuild it.
+func MigrateToXDG() error {
+	if explicitVoltPath() != "" {
+		return errors.New("$VOLTPATH is set or .voltpath file is found")
+	}
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return errors.New("XDG layout is not used on " + runtime.GOOS)
+	}
+	legacy := filepath.Join(HomeDir(), "volt")
+	if fi, err := os.Lstat(legacy); err != nil {
+		return errors.Errorf("%s does not exist", legacy)
+	} else if !fi.IsDir() {
+		return errors.Errorf("%s is not a directory", legacy)
+	}
+	if Exists(filepath.Join(legacy, "trx", "lock")) {
+		return errors.New("other volt process is running (or " +
+			filepath.Join(legacy, "trx", "lock") + " was left)")
+	}
+	dir, err := os.Open(legacy)
+	if err != nil {
+		return err
+	}
+	names, err := dir.Readdirnames(0)
+	dir.Close()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, ok := xdgEntries[name]; !ok {
+			return errors.Errorf("%s has unknown entry '%s'", legacy, name)
+		}
+		dst := filepath.Join(xdgEntries[name].voltDir(), name)
+		if Exists(dst) {
+			return errors.Errorf("could not move %s: %s already exists", filepath.Join(legacy, name), dst)
+		}
+	}
+
+	moved := make([][2]string, 0, len(names))
+	moveBack := func() {
+		for i := len(moved) - 1; i >= 0; i-- {
+			os.Rename(moved[i][1], moved[i][0])
+		}
+	}
+	for _, name := range names {
+		src := filepath.Join(legacy, name)
+		dst := filepath.Join(xdgEntries[name].voltDir(), name)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			moveBack()
+			return err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			moveBack()
+			return err
+		}
+		moved = append(moved, [2]string{src, dst})
+	}
+	if err := os.Remove(legacy); err != nil {
+		moveBack()
+		return err
+	}
+	// Create XDG layout directories even if ~/volt was empty, they decide
+	// the layout
+	for _, d := range []xdgBaseDir{xdgConfig, xdgData} {
+		if err := os.MkdirAll(d.voltDir(), 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package pathutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func setEnvForTest(t *testing.T, name, value string) func() {
+	old, exists := os.LookupEnv(name)
+	if err := os.Setenv(name, value); err != nil {
+		t.Fatalf("failed to set %s: %s", name, err)
+	}
+	return func() {
+		if exists {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestMigrateToXDG(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("XDG layout is not used on " + runtime.GOOS)
+	}
+	home, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(home)
+	defer setEnvForTest(t, "HOME", home)()
+	defer setEnvForTest(t, "VOLTPATH", "")()
+	defer setEnvForTest(t, "XDG_CONFIG_HOME", filepath.Join(home, "config"))()
+	defer setEnvForTest(t, "XDG_DATA_HOME", "")()
+	defer setEnvForTest(t, "XDG_CACHE_HOME", "relative/path/is/ignored")()
+
+	legacy := filepath.Join(home, "volt")
+	os.MkdirAll(filepath.Join(legacy, "repos", "github.com", "tyru", "caw.vim"), 0755)
+	for name, content := range map[string]string{"lock.json": "{}", "kept-repos": ""} {
+		if err := ioutil.WriteFile(filepath.Join(legacy, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if VoltConfigDir() != legacy || VoltDataDir() != legacy {
+		t.Fatalf("expected ~/volt is used before migration")
+	}
+
+	if err := MigrateToXDG(); err != nil {
+		t.Fatalf("expected migration was performed: %s", err)
+	}
+	if Exists(legacy) {
+		t.Errorf("expected ~/volt is removed")
+	}
+	if expected := filepath.Join(home, "config", "volt", "lock.json"); LockJSON() != expected || !Exists(expected) {
+		t.Errorf("expected lock.json is %s but got %s", expected, LockJSON())
+	}
+	expected := filepath.Join(home, ".local", "share", "volt", "repos", "github.com", "tyru", "caw.vim")
+	if got := ReposPath("github.com/tyru/caw.vim").FullPath(); got != expected || !Exists(expected) {
+		t.Errorf("expected repository is %s but got %s", expected, got)
+	}
+	if expected := filepath.Join(home, ".local", "share", "volt", "kept-repos"); KeptReposFile() != expected || !Exists(expected) {
+		t.Errorf("expected kept-repos is %s but got %s", expected, KeptReposFile())
+	}
+	if expected := filepath.Join(home, ".cache", "volt", "logs"); LogsDir() != expected {
+		t.Errorf("expected logs dir is %s but got %s", expected, LogsDir())
+	}
+
+	// Nothing is done after migrated
+	if err := MigrateToXDG(); err == nil {
+		t.Errorf("expected migration was not performed")
+	}
+
+	// Creating ~/volt again does not change the layout
+	os.MkdirAll(legacy, 0755)
+	if err := os.Symlink(filepath.Join(home, "dotfiles", "lock.json"), filepath.Join(legacy, "lock.json")); err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(home, "config", "volt", "lock.json"); LockJSON() != expected {
+		t.Errorf("expected lock.json is %s after ~/volt was created but got %s", expected, LockJSON())
+	}
+}
+
+func TestMigrateToXDGUnknownEntry(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("XDG layout is not used on " + runtime.GOOS)
+	}
+	home, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(home)
+	defer setEnvForTest(t, "HOME", home)()
+	defer setEnvForTest(t, "VOLTPATH", "")()
+
+	// e.g. ~/volt is managed by git
+	os.MkdirAll(filepath.Join(home, "volt", ".git"), 0755)
+	if err := MigrateToXDG(); err == nil {
+		t.Errorf("expected migration was not performed")
+	}
+	if !Exists(filepath.Join(home, "volt", ".git")) {
+		t.Errorf("expected ~/volt is not changed")
+	}
+}
+
+func TestNvimDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Neovim uses %LOCALAPPDATA% on Windows")
+	}
+	home, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(home)
+	defer setEnvForTest(t, "HOME", home)()
+	defer setEnvForTest(t, "XDG_CONFIG_HOME", filepath.Join(home, "config"))()
+	defer setEnvForTest(t, "XDG_DATA_HOME", "")()
+
+	if expected := filepath.Join(home, ".local", "share", "nvim", "site"); NvimDir() != expected {
+		t.Errorf("expected %s but got %s", expected, NvimDir())
+	}
+	if expected := filepath.Join(home, "config", "nvim"); NvimConfigDir() != expected {
+		t.Errorf("expected %s but got %s", expected, NvimConfigDir())
+	}
+
+	if expected := filepath.Join(home, ".vim", "pack", "volt", "opt"); VimVoltOptDir() != expected {
+		t.Errorf("expected %s but got %s", expected, VimVoltOptDir())
+	}
+	SetNeovimTarget(true)
+	defer SetNeovimTarget(false)
+	if expected := filepath.Join(home, ".local", "share", "nvim", "site", "pack", "volt", "opt"); VimVoltOptDir() != expected {
+		t.Errorf("expected %s but got %s", expected, VimVoltOptDir())
+	}
+}
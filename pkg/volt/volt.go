@@ -0,0 +1,234 @@
+// Package volt is the Go API of volt. It performs the operations of volt
+// commands (installing, upgrading, removing, building, and managing profiles)
+// in the current process, so other tools (e.g. dotfiles managers and editor
+// frontends) can embed volt instead of executing volt command.
+//
+// The operations use $VOLTPATH and config.toml like volt command, and show
+// messages on stdout and stderr like volt command. They never exit the
+// process. They must not be called concurrently: like volt command, an
+// operation which modifies lock.json fails while another one is running.
+//
+// Repositories are specified like the arguments of volt command
+// (e.g. "tyru/caw.vim", "github.com/tyru/caw.vim@dev", or a plugin name of
+// installed repositories like "caw.vim" for some operations).
+package volt
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/subcmd"
+)
+
+// Error is the error of an operation.
+type Error struct {
+	// Code is the exit status of the corresponding volt command
+	Code int
+	// Msg is the error message
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// exec runs 'volt {subCmd} {args}'.
+func exec(ctx context.Context, subCmd string, args ...string) error {
+	if err := subcmd.Exec(ctx, subCmd, args); err != nil {
+		return &Error{Code: err.Code, Msg: err.Msg}
+	}
+	return nil
+}
+
+// GetOptions is the options of Install() and Upgrade().
+// nil is the same as the zero value.
+type GetOptions struct {
+	// Release installs GitHub releases whose tag matches the pattern
+	// ("latest" for the latest release) instead of cloning ("-release")
+	Release string
+	// Depth clones only the number of the latest commits ("-depth")
+	Depth int
+	// Filter clones with the partial clone filter ("-filter")
+	Filter string
+	// SingleBranch clones only the history of one branch ("-single-branch")
+	SingleBranch bool
+	// Verify rolls back the upgrades which broke Vim startup ("-verify").
+	// This is used only by Upgrade().
+	Verify bool
+	// FailFast aborts the remaining repositories on the first failure
+	// ("-fail-fast")
+	FailFast bool
+}
+
+// args returns the arguments of "volt get".
+// If repos is empty, all repositories of current profile are the targets.
+func (opts *GetOptions) args(upgrade bool, repos []string) []string {
+	var args []string
+	if len(repos) == 0 {
+		args = append(args, "-l")
+	}
+	if upgrade {
+		args = append(args, "-u")
+	}
+	if opts != nil {
+		if opts.Verify && upgrade {
+			args = append(args, "-verify")
+		}
+		if opts.FailFast {
+			args = append(args, "-fail-fast")
+		}
+		if opts.Release != "" {
+			args = append(args, "-release", opts.Release)
+		}
+		if opts.Depth > 0 {
+			args = append(args, "-depth", strconv.Itoa(opts.Depth))
+		}
+		if opts.Filter != "" {
+			args = append(args, "-filter", opts.Filter)
+		}
+		if opts.SingleBranch {
+			args = append(args, "-single-branch")
+		}
+	}
+	return append(args, repos...)
+}
+
+// Install installs repos (like "volt get"), adds them to current profile, and
+// builds ~/.vim/pack/volt. If repos is empty, the missing repositories of
+// current profile are installed (like "volt get -l").
+func Install(ctx context.Context, repos []string, opts *GetOptions) error {
+	return exec(ctx, "get", opts.args(false, repos)...)
+}
+
+// Upgrade upgrades repos (like "volt get -u"), and builds ~/.vim/pack/volt.
+// If repos is empty, all repositories of current profile are upgraded (like
+// "volt get -l -u").
+func Upgrade(ctx context.Context, repos []string, opts *GetOptions) error {
+	return exec(ctx, "get", opts.args(true, repos)...)
+}
+
+// RemoveOptions is the options of Remove().
+// nil is the same as the zero value.
+type RemoveOptions struct {
+	// Repos removes also repository directories ("-r")
+	Repos bool
+	// Plugconf removes also plugconf files ("-p")
+	Plugconf bool
+}
+
+// Remove removes repos from lock.json (like "volt rm"), and builds
+// ~/.vim/pack/volt.
+func Remove(ctx context.Context, repos []string, opts *RemoveOptions) error {
+	var args []string
+	if opts != nil && opts.Repos {
+		args = append(args, "-r")
+	}
+	if opts != nil && opts.Plugconf {
+		args = append(args, "-p")
+	}
+	return exec(ctx, "rm", append(args, repos...)...)
+}
+
+// BuildOptions is the options of Build().
+// nil is the same as the zero value.
+type BuildOptions struct {
+	// Full builds all repositories instead of only the changed ones
+	// ("-full")
+	Full bool
+}
+
+// Build builds ~/.vim/pack/volt (like "volt build").
+func Build(ctx context.Context, opts *BuildOptions) error {
+	if opts != nil && opts.Full {
+		return exec(ctx, "build", "-full")
+	}
+	return exec(ctx, "build")
+}
+
+// Profile is a profile of lock.json.
+type Profile struct {
+	// Name is the profile name
+	Name string
+	// Repos is the repositories of the profile
+	Repos []string
+	// NoBuild is the repositories of Repos which are not installed by
+	// building (see "volt profile no-build")
+	NoBuild []string
+	// Current is true if the profile is one of current profiles
+	Current bool
+}
+
+// Profiles returns all profiles of lock.json.
+func Profiles() ([]Profile, error) {
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]bool)
+	for _, name := range lockJSON.CurrentProfileNames() {
+		current[name] = true
+	}
+	profiles := make([]Profile, 0, len(lockJSON.Profiles))
+	for i := range lockJSON.Profiles {
+		p := &lockJSON.Profiles[i]
+		profile := Profile{
+			Name:    p.Name,
+			Repos:   make([]string, 0, len(p.ReposPath)),
+			Current: current[p.Name],
+		}
+		for _, reposPath := range p.ReposPath {
+			profile.Repos = append(profile.Repos, reposPath.String())
+		}
+		for _, reposPath := range p.NoBuild {
+			profile.NoBuild = append(profile.NoBuild, reposPath.String())
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// SetProfile switches current profiles to names (like "volt profile set").
+// The first name is the primary profile.
+func SetProfile(ctx context.Context, names ...string) error {
+	return exec(ctx, "profile", append([]string{"set"}, names...)...)
+}
+
+// NewProfile creates a profile of name (like "volt profile new").
+func NewProfile(ctx context.Context, name string) error {
+	return exec(ctx, "profile", "new", name)
+}
+
+// DestroyProfile deletes the profile of name (like "volt profile destroy").
+// Current profile cannot be deleted.
+func DestroyProfile(ctx context.Context, name string) error {
+	return exec(ctx, "profile", "destroy", name)
+}
+
+// RenameProfile renames the profile from oldName to newName (like
+// "volt profile rename").
+func RenameProfile(ctx context.Context, oldName, newName string) error {
+	return exec(ctx, "profile", "rename", oldName, newName)
+}
+
+// Enable adds installed repos to the profile of name (like
+// "volt profile add"), and builds ~/.vim/pack/volt.
+// If name is empty, current profile is used.
+func Enable(ctx context.Context, name string, repos ...string) error {
+	return exec(ctx, "profile", profileArgs("add", name, repos)...)
+}
+
+// Disable removes repos from the profile of name (like "volt profile rm"),
+// and builds ~/.vim/pack/volt.
+// If name is empty, current profile is used.
+func Disable(ctx context.Context, name string, repos ...string) error {
+	return exec(ctx, "profile", profileArgs("rm", name, repos)...)
+}
+
+// profileArgs returns the arguments of "volt profile {subCmd}".
+func profileArgs(subCmd, name string, repos []string) []string {
+	if name == "" {
+		name = "-current"
+	}
+	return append([]string{subCmd, name}, repos...)
+}
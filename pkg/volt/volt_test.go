@@ -0,0 +1,82 @@
+package volt
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestGetOptionsArgs(t *testing.T) {
+	for _, tt := range []struct {
+		opts     *GetOptions
+		upgrade  bool
+		repos    []string
+		expected []string
+	}{
+		{nil, false, []string{"tyru/caw.vim"}, []string{"tyru/caw.vim"}},
+		{nil, true, nil, []string{"-l", "-u"}},
+		{&GetOptions{Verify: true}, false, []string{"tyru/caw.vim"}, []string{"tyru/caw.vim"}},
+		{&GetOptions{Verify: true, FailFast: true}, true, nil, []string{"-l", "-u", "-verify", "-fail-fast"}},
+		{
+			&GetOptions{Depth: 1, Filter: "blob:none", SingleBranch: true}, false, []string{"tyru/caw.vim"},
+			[]string{"-depth", "1", "-filter", "blob:none", "-single-branch", "tyru/caw.vim"},
+		},
+		{&GetOptions{Release: "latest"}, false, []string{"junegunn/fzf"}, []string{"-release", "latest", "junegunn/fzf"}},
+	} {
+		if args := tt.opts.args(tt.upgrade, tt.repos); !reflect.DeepEqual(args, tt.expected) {
+			t.Errorf("%+v, %v, %v: expected %q but got %q", tt.opts, tt.upgrade, tt.repos, tt.expected, args)
+		}
+	}
+}
+
+func TestProfileArgs(t *testing.T) {
+	if args, expected := profileArgs("add", "", []string{"caw.vim"}), []string{"add", "-current", "caw.vim"}; !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %q but got %q", expected, args)
+	}
+	if args, expected := profileArgs("rm", "foo", []string{"caw.vim"}), []string{"rm", "foo", "caw.vim"}; !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %q but got %q", expected, args)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	const lockJSON = `{
+  "version": 2,
+  "current_profile_name": "default",
+  "extra_profile_names": ["server"],
+  "repos": [
+    {"type": "git", "path": "github.com/tyru/caw.vim", "version": "abc"}
+  ],
+  "profiles": [
+    {"name": "default", "repos_path": ["github.com/tyru/caw.vim"]},
+    {"name": "server", "repos_path": ["github.com/tyru/caw.vim"], "no_build": ["github.com/tyru/caw.vim"]},
+    {"name": "foo", "repos_path": []}
+  ]
+}`
+	if err := ioutil.WriteFile(pathutil.LockJSON(), []byte(lockJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Profile{
+		{Name: "default", Repos: []string{"github.com/tyru/caw.vim"}, Current: true},
+		{Name: "server", Repos: []string{"github.com/tyru/caw.vim"}, NoBuild: []string{"github.com/tyru/caw.vim"}, Current: true},
+		{Name: "foo", Repos: []string{}},
+	}
+	if !reflect.DeepEqual(profiles, expected) {
+		t.Errorf("expected %+v but got %+v", expected, profiles)
+	}
+}
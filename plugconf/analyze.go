@@ -0,0 +1,179 @@
+package plugconf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PluginInfo is the result of AnalyzePlugin().
+// All fields are sorted and do not have duplicate values.
+type PluginInfo struct {
+	// Commands are Ex commands defined in plugin/**/*.vim
+	Commands []string
+	// FileTypes are filetypes detected by ftdetect/*.vim, or handled by
+	// ftplugin/, indent/, syntax/ files
+	FileTypes []string
+	// Mappings are <Plug> mappings defined in plugin/**/*.vim
+	Mappings []Mapping
+	// Variables are global variables tagged in doc/*.txt
+	Variables []string
+}
+
+// Mapping is a <Plug> mapping provided by a plugin.
+type Mapping struct {
+	// Mode is the mode prefix of map command ("n", "x", "i", ...).
+	// "" means ":map" (Normal, Visual and Operator-pending mode).
+	Mode string
+	// Name is the lhs of the mapping (e.g. "<Plug>(caw:hatpos:toggle)")
+	Name string
+}
+
+func (pi *PluginInfo) isEmpty() bool {
+	return len(pi.Commands) == 0 && len(pi.FileTypes) == 0 &&
+		len(pi.Mappings) == 0 && len(pi.Variables) == 0
+}
+
+var (
+	// :command[!] [{attr}...] {cmd} {rep}
+	rxCommandDef = regexp.MustCompile(`^\s*com(?:m(?:a(?:n(?:d)?)?)?)?!?\s+((?:-\S+\s+)*)([A-Z][A-Za-z0-9]*)`)
+	// :{mode}map / :{mode}noremap [<silent>...] <Plug>...
+	rxPlugMapping = regexp.MustCompile(`^\s*([nvxsoilc]?)(?:nore)?map\s+(?:(?i:<(?:buffer|nowait|silent|special|script|expr|unique)>)\s*)*((?i:<Plug>)\S+)`)
+	// :setfiletype {ft} / :set filetype={ft}
+	rxSetFileType = regexp.MustCompile(`\b(?:setf(?:iletype)?\s+|set(?:l(?:ocal)?)?\s+(?:ft|filetype)=)([A-Za-z0-9_.]+)`)
+	// *g:foo_bar* in help files
+	rxDocVariable = regexp.MustCompile(`\*(g:[A-Za-z_][A-Za-z0-9_#]*)\*`)
+)
+
+// AnalyzePlugin reads runtime files of a plugin placed at dir, and returns
+// commands, filetypes, mappings, and variables the plugin provides.
+// These are guessed by simple pattern matching, so the result may not be
+// complete.
+func AnalyzePlugin(dir string) (*PluginInfo, error) {
+	commands := make(map[string]bool)
+	filetypes := make(map[string]bool)
+	mappings := make(map[Mapping]bool)
+	variables := make(map[string]bool)
+
+	err := walkFiles(filepath.Join(dir, "plugin"), ".vim", func(content []byte) {
+		for _, line := range strings.Split(string(content), "\n") {
+			if m := rxCommandDef.FindStringSubmatch(line); m != nil {
+				if !strings.Contains(m[1], "-buffer") {
+					commands[m[2]] = true
+				}
+			} else if m := rxPlugMapping.FindStringSubmatch(line); m != nil {
+				mappings[Mapping{Mode: m[1], Name: m[2]}] = true
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = walkFiles(filepath.Join(dir, "ftdetect"), ".vim", func(content []byte) {
+		for _, m := range rxSetFileType.FindAllSubmatch(content, -1) {
+			filetypes[string(m[1])] = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"ftplugin", "indent", "syntax"} {
+		if err := addFileTypesByName(filepath.Join(dir, name), filetypes); err != nil {
+			return nil, err
+		}
+	}
+
+	err = walkFiles(filepath.Join(dir, "doc"), ".txt", func(content []byte) {
+		for _, m := range rxDocVariable.FindAllSubmatch(content, -1) {
+			variables[string(m[1])] = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := &PluginInfo{
+		Commands:  sortedSet(commands),
+		FileTypes: sortedSet(filetypes),
+		Variables: sortedSet(variables),
+	}
+	for m := range mappings {
+		info.Mappings = append(info.Mappings, m)
+	}
+	sort.Slice(info.Mappings, func(i, j int) bool {
+		if info.Mappings[i].Name != info.Mappings[j].Name {
+			return info.Mappings[i].Name < info.Mappings[j].Name
+		}
+		return info.Mappings[i].Mode < info.Mappings[j].Mode
+	})
+	return info, nil
+}
+
+// walkFiles calls f with the content of each file which has ext under dir.
+// It does nothing if dir does not exist.
+func walkFiles(dir, ext string, f func(content []byte)) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(path) != ext {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		f(bytes.Replace(content, []byte("\r\n"), []byte("\n"), -1))
+		return nil
+	})
+}
+
+// addFileTypesByName adds filetypes from filenames under dir (e.g. ftplugin/).
+// See ":help ftplugin-name" for the naming rules.
+// * {filetype}.vim
+// * {filetype}_{name}.vim
+// * {filetype}/{name}.vim
+func addFileTypesByName(dir string, filetypes map[string]bool) error {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, fi := range infos {
+		name := fi.Name()
+		if !fi.IsDir() {
+			if filepath.Ext(name) != ".vim" {
+				continue
+			}
+			name = strings.TrimSuffix(name, ".vim")
+			if i := strings.Index(name, "_"); i > 0 {
+				name = name[:i]
+			}
+		}
+		if name != "" && !strings.HasPrefix(name, ".") {
+			filetypes[name] = true
+		}
+	}
+	return nil
+}
+
+func sortedSet(m map[string]bool) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
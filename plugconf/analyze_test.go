@@ -0,0 +1,125 @@
+package plugconf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/haya14busa/go-vimlparser"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAnalyzePlugin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "volt-test-analyze-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"plugin/hello.vim": strings.Join([]string{
+			`command! -nargs=* -complete=customlist,s:complete Hello call hello#hello(<q-args>)`,
+			`command -bar HelloWorld call hello#world()`,
+			`command! -buffer HelloLocal echo 'local'`,
+			`nnoremap <silent> <Plug>(hello) :<C-u>Hello<CR>`,
+			`xnoremap <silent> <Plug>(hello) :<C-u>Hello<CR>`,
+			`nmap <Leader>h <Plug>(hello)`,
+		}, "\r\n"),
+		"plugin/sub/more.vim":      "com! Hello2 echo 2\n",
+		"ftdetect/hello.vim":       "autocmd BufNewFile,BufRead *.hello setfiletype hello\nau BufRead *.hi set filetype=hi\n",
+		"ftplugin/go_hello.vim":    "",
+		"ftplugin/markdown/a.vim":  "",
+		"syntax/hello.vim":         "",
+		"doc/hello.txt":            "*g:hello_enabled*\n*hello#hello()* *g:hello#name* *b:hello_var*\n",
+		"autoload/hello.vim":       "command! NotCommand echo 1\n",
+		"plugin/README.md":         "command! NotVimFile echo 1\n",
+		"ftplugin/.hidden/foo.vim": "",
+	})
+
+	info, err := AnalyzePlugin(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &PluginInfo{
+		Commands:  []string{"Hello", "Hello2", "HelloWorld"},
+		FileTypes: []string{"go", "hello", "hi", "markdown"},
+		Mappings: []Mapping{
+			{Mode: "n", Name: "<Plug>(hello)"},
+			{Mode: "x", Name: "<Plug>(hello)"},
+		},
+		Variables: []string{"g:hello#name", "g:hello_enabled"},
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("expected %+v but got %+v", expected, info)
+	}
+
+	// Empty directory
+	info, err = AnalyzePlugin(filepath.Join(dir, "autoload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.isEmpty() {
+		t.Errorf("expected empty info but got %+v", info)
+	}
+}
+
+func TestGenerateSkeleton(t *testing.T) {
+	info := &PluginInfo{
+		Commands:  []string{"Hello", "HelloWorld"},
+		FileTypes: []string{"hello"},
+		Mappings:  []Mapping{{Mode: "n", Name: "<Plug>(hello)"}},
+		Variables: []string{"g:hello_enabled"},
+	}
+	for _, tt := range []struct {
+		info     *PluginInfo
+		contains []string
+	}{
+		{nil, nil},
+		{&PluginInfo{}, nil},
+		{info, []string{
+			"function! s:on_load_pre()\n  \" Variables documented in this plugin:\n  \" let g:hello_enabled = ...\nendfunction",
+			"function! s:on_load_post()\n  \" Mappings provided by this plugin:\n  \" nmap {lhs} <Plug>(hello)\nendfunction",
+			"  \" return 'excmd=Hello,HelloWorld'\n  \" return 'filetype=hello'\n  \" return 'mapping=<Plug>(hello)'\n  return 'start'\nendfunction",
+		}},
+	} {
+		content, err := GenerateSkeleton(tt.info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range tt.contains {
+			if !bytes.Contains(content, []byte(s)) {
+				t.Errorf("expected %q in generated plugconf:\n%s", s, content)
+			}
+		}
+		if tt.contains == nil && bytes.Contains(content, []byte("detected")) {
+			t.Errorf("expected no hints in generated plugconf:\n%s", content)
+		}
+
+		// The generated plugconf must be parsed without errors
+		file, err := vimlparser.ParseFile(bytes.NewReader(content), "test.vim", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pi, parseErr := ParsePlugconf(file, content, "test.vim")
+		if parseErr.HasErrs() {
+			t.Fatal(parseErr.ErrorsAndWarns())
+		}
+		if !pi.loadsOnStart() {
+			t.Errorf("expected load on start but got %v", pi.loadOn)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package plugconf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+
+	"github.com/haya14busa/go-vimlparser"
+	"github.com/haya14busa/go-vimlparser/ast"
+	"github.com/haya14busa/go-vimlparser/token"
+)
+
+// plugconfFuncNames are the functions of plugconf which volt calls.
+var plugconfFuncNames = []string{
+	"s:loaded_on",
+	"s:config",
+	"s:on_load_pre",
+	"s:on_load_post",
+	"s:on_first_use",
+	"s:depends",
+}
+
+func isPlugconfFuncName(name string) bool {
+	for i := range plugconfFuncNames {
+		if plugconfFuncNames[i] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint parses plugconf files of reposPathList, and checks them more strictly
+// than "volt build". In addition to the errors of ParsePlugconfFile(), it
+// reports:
+//
+//   - statements outside functions (they are ignored by "volt build")
+//   - plugconf functions which have arguments
+//   - script-local functions which are neither plugconf functions nor called
+//   - functions defined twice
+//   - invalid s:depends() entries, and dependency cycles
+//
+// reposList is repositories of lock.json, which is used to check
+// s:depends(). The returned value has only plugconf files which have errors
+// or warnings.
+func Lint(reposPathList pathutil.ReposPathList, reposList []lockjson.Repos) MultiParseError {
+	parseErrMap := make(map[pathutil.ReposPath]*ParseError, len(reposPathList))
+	for _, reposPath := range reposPathList {
+		parseErr := lintFile(reposPath.Plugconf(), reposPath)
+		if lockjson.ReposList(reposList).FindByPath(reposPath) == nil {
+			parseErr.mwarn = multierror.Append(parseErr.mwarn,
+				errors.New("the repository is not in lock.json"))
+		}
+		for _, dep := range dependsOfFile(reposPath) {
+			if lockjson.ReposList(reposList).FindByPath(dep) == nil {
+				parseErr.mwarn = multierror.Append(parseErr.mwarn, errors.Errorf(
+					"s:depends(): '%s' is not in lock.json (install it by \"volt get %s\")", dep, dep))
+			}
+		}
+		parseErrMap[reposPath] = parseErr
+	}
+
+	// Check dependency cycle in the same way as "volt build"
+	plugconfMap, _ := parsePlugconfAsMap(reposList)
+	sorted := append(make([]lockjson.Repos, 0, len(reposList)), reposList...)
+	if err := sortByDepends(sorted, plugconfMap); err != nil {
+		if parseErr, exists := parseErrMap[err.reposPath]; exists {
+			parseErr.merr = multierror.Append(parseErr.merr, err)
+		}
+	}
+
+	result := make(MultiParseError, 0, len(reposPathList))
+	for _, reposPath := range reposPathList {
+		if parseErr := parseErrMap[reposPath]; parseErr.HasErrsOrWarns() {
+			result = append(result, *parseErr)
+		}
+	}
+	return result
+}
+
+// dependsOfFile returns s:depends() of the plugconf of reposPath.
+// nil is returned if it cannot be parsed.
+func dependsOfFile(reposPath pathutil.ReposPath) pathutil.ReposPathList {
+	result, parseErr := ParsePlugconfFile(reposPath.Plugconf(), 1, reposPath)
+	if parseErr.HasErrs() {
+		return nil
+	}
+	return result.depends
+}
+
+// lintFile parses a plugconf file of path, and checks the problems which are
+// not reported by ParsePlugconf().
+func lintFile(path string, reposPath pathutil.ReposPath) *ParseError {
+	parseErr := newParseError(path)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		parseErr.merr = multierror.Append(parseErr.merr, err)
+		return parseErr
+	}
+	file, err := vimlparser.ParseFile(bytes.NewReader(content), path, nil)
+	if err != nil {
+		parseErr.merr = multierror.Append(parseErr.merr, err)
+		return parseErr
+	}
+	_, parseErr = ParsePlugconf(file, content, path)
+
+	// Statements outside functions are not copied to bundled plugconf
+	for _, stmt := range file.Body {
+		switch stmt.(type) {
+		case *ast.Function, *ast.Comment:
+		default:
+			parseErr.mwarn = multierror.Append(parseErr.mwarn, atLine(stmt,
+				errors.New("statements outside functions are ignored. please move it into s:on_load_pre()")))
+		}
+	}
+
+	defined := make(map[string]bool)
+	definedEmpty := make(map[string]bool)
+	ast.Inspect(file, func(node ast.Node) bool {
+		fn, ok := node.(*ast.Function)
+		if !ok {
+			return true
+		}
+		ident, ok := fn.Name.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		name := ident.Name
+
+		if isPlugconfFuncName(name) {
+			// ParsePlugconf() reports duplicate plugconf functions only if
+			// the first one is not empty
+			key := name
+			if key == "s:config" {
+				key = "s:on_load_pre"
+			}
+			if definedEmpty[key] {
+				parseErr.merr = multierror.Append(parseErr.merr,
+					atLine(fn, errors.Errorf("duplicate %s()", name)))
+			}
+			if !defined[key] {
+				definedEmpty[key] = isEmptyFunc(fn)
+			}
+			defined[key] = true
+			if len(fn.Params) > 0 {
+				parseErr.merr = multierror.Append(parseErr.merr,
+					atLine(fn, errors.Errorf("%s() must not have arguments", name)))
+			}
+			if name == "s:depends" {
+				lintDepends(fn, reposPath, parseErr)
+			}
+			return true
+		}
+
+		if defined[name] {
+			parseErr.mwarn = multierror.Append(parseErr.mwarn,
+				atLine(fn, errors.Errorf("duplicate %s()", name)))
+		}
+		defined[name] = true
+
+		if len(name) > 2 && name[:2] == "s:" && !isCalled(name[2:], content) {
+			msg := name + "() is not a plugconf function, and is never called"
+			if similar := similarPlugconfFuncName(name); similar != "" {
+				msg += ". did you mean " + similar + "()?"
+			}
+			parseErr.mwarn = multierror.Append(parseErr.mwarn, atLine(fn, errors.New(msg)))
+		}
+		return true
+	})
+	return parseErr
+}
+
+// lintDepends checks return values of s:depends() function fn.
+func lintDepends(fn *ast.Function, reposPath pathutil.ReposPath, parseErr *ParseError) {
+	ast.Inspect(fn, func(node ast.Node) bool {
+		ret, ok := node.(*ast.Return)
+		if !ok {
+			return true
+		}
+		list, ok := ret.Result.(*ast.List)
+		if !ok {
+			parseErr.merr = multierror.Append(parseErr.merr,
+				atLine(ret, errors.New("s:depends() must return a list literal of strings")))
+			return true
+		}
+		seen := make(map[pathutil.ReposPath]bool, len(list.Values))
+		for i := range list.Values {
+			str, ok := list.Values[i].(*ast.BasicLit)
+			if !ok || str.Kind != token.STRING {
+				parseErr.merr = multierror.Append(parseErr.merr,
+					atLine(ret, errors.New("s:depends() must return a list literal of strings")))
+				continue
+			}
+			dep, err := pathutil.NormalizeRepos(str.Value[1 : len(str.Value)-1])
+			if err != nil {
+				// ParsePlugconf() reports it
+				continue
+			}
+			if dep.Equals(reposPath) {
+				parseErr.merr = multierror.Append(parseErr.merr,
+					atLine(ret, errors.Errorf("s:depends(): '%s' depends on itself", dep)))
+			} else if seen[dep] {
+				parseErr.mwarn = multierror.Append(parseErr.mwarn,
+					atLine(ret, errors.Errorf("s:depends(): duplicate '%s'", dep)))
+			}
+			seen[dep] = true
+		}
+		return true
+	})
+}
+
+// isCalled returns true if script-local function name is referred in src
+// (e.g. "call s:foo()", "function('s:foo')", ":call <SID>foo()") other than
+// the definition.
+func isCalled(name string, src []byte) bool {
+	rx := regexp.MustCompile(`(?:s:|(?i:<SID>))` + regexp.QuoteMeta(name) + `\b`)
+	return len(rx.FindAllIndex(src, 2)) > 1
+}
+
+// similarPlugconfFuncName returns the plugconf function name which is similar
+// to name, which may be a typo. "" is returned if no such name.
+func similarPlugconfFuncName(name string) string {
+	candidates := make([]string, 0, len(plugconfFuncNames))
+	for i := range plugconfFuncNames {
+		if editDistance(name, plugconfFuncNames[i]) <= 2 {
+			candidates = append(candidates, plugconfFuncNames[i])
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return editDistance(name, candidates[i]) < editDistance(name, candidates[j])
+	})
+	return candidates[0]
+}
+
+// editDistance returns Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
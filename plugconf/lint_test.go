@@ -0,0 +1,59 @@
+package plugconf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestLintFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-lint-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	reposPath := pathutil.ReposPath("github.com/user/name")
+	for _, tt := range []struct {
+		src  string
+		err  string
+		warn string
+	}{
+		// No problems
+		{src: "\" comment\nfunction! s:on_load_pre()\n  call s:helper()\nendfunction\nfunction! s:helper()\nendfunction\n"},
+		{src: "function! s:on_load_pre()\n  nnoremap x :<C-u>call <SID>helper()<CR>\nendfunction\nfunction! s:helper()\nendfunction\n"},
+		{src: "function! s:depends()\n  return ['tyru/open-browser.vim']\nendfunction\n"},
+		// Errors
+		{src: "function! s:on_load_post(x)\nendfunction\n", err: "line 1: s:on_load_post() must not have arguments"},
+		{src: "function! s:depends()\n  return g:deps\nendfunction\n", err: "line 2: s:depends() must return a list literal of strings"},
+		{src: "function! s:depends()\n  return [g:dep]\nendfunction\n", err: "line 2: s:depends() must return a list literal of strings"},
+		{src: "function! s:depends()\n  return ['user/name']\nendfunction\n", err: "line 2: s:depends(): 'github.com/user/name' depends on itself"},
+		{src: "function! s:depends()\nendfunction\nfunction! s:depends()\nendfunction\n", err: "line 3: duplicate s:depends()"},
+		// Warnings
+		{src: "let g:foo = 1\n", warn: "line 1: statements outside functions are ignored"},
+		{src: "function! s:on_load_pr()\nendfunction\n", warn: "line 1: s:on_load_pr() is not a plugconf function, and is never called. did you mean s:on_load_pre()?"},
+		{src: "function! s:foo()\nendfunction\n", warn: "line 1: s:foo() is not a plugconf function, and is never called"},
+		{src: "function! Foo()\nendfunction\nfunction! Foo()\nendfunction\n", warn: "line 3: duplicate Foo()"},
+		{src: "function! s:depends()\n  return ['a/b', 'github.com/a/b']\nendfunction\n", warn: "line 2: s:depends(): duplicate 'github.com/a/b'"},
+	} {
+		path := filepath.Join(tempDir, "test.vim")
+		if err := ioutil.WriteFile(path, []byte(tt.src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		parseErr := lintFile(path, reposPath)
+		if tt.err == "" && parseErr.HasErrs() {
+			t.Errorf("%q: expected no errors but got %q", tt.src, parseErr.Errors().Error())
+		} else if tt.err != "" && !strings.Contains(parseErr.Errors().Error(), tt.err) {
+			t.Errorf("%q: expected %q in errors but got %q", tt.src, tt.err, parseErr.Errors().Error())
+		}
+		if tt.warn == "" && parseErr.HasWarns() {
+			t.Errorf("%q: expected no warnings but got %q", tt.src, parseErr.Warns().Error())
+		} else if tt.warn != "" && !strings.Contains(parseErr.Warns().Error(), tt.warn) {
+			t.Errorf("%q: expected %q in warnings but got %q", tt.src, tt.warn, parseErr.Warns().Error())
+		}
+	}
+}
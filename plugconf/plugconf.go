@@ -2,13 +2,14 @@ package plugconf
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -25,23 +26,49 @@ import (
 
 type loadOnType string
 
-// TODO: make this uint
 const (
-	loadOnStart    loadOnType = "(loadOnStart)"
-	loadOnFileType            = "FileType"
-	loadOnExcmd               = "(loadOnExcmd)"
+	loadOnFileType loadOnType = "filetype"
+	loadOnExcmd    loadOnType = "excmd"
+	loadOnMapping  loadOnType = "mapping"
+	loadOnEvent    loadOnType = "event"
 )
 
+// loadTrigger is a value of s:loaded_on() other than 'start'
+// (e.g. 'filetype=go,rust').
+type loadTrigger struct {
+	on   loadOnType
+	args []string
+}
+
 const (
 	// TODO: Check duplicate variable for excmdLoadPlugin
 	excmdLoadPlugin   = "s:__volt_excmd_load_plugin"
 	lazyLoadExcmdFunc = "s:__volt_lazy_load_excmd"
 	completeFunc      = "s:__volt_complete"
+	inProfilesFunc    = "s:__volt_in_profiles"
+	voltProfilesVar   = "s:__volt_profiles"
+	onFirstUseFunc    = "s:__volt_on_first_use"
+	firstUsedVar      = "s:__volt_first_used"
+	// lazyPluginsVar has the plugins which are not loaded yet
+	// (plugconf id -> the commands to load the plugin, dummy Ex commands,
+	// dummy mappings, and plugconf ids of dependencies)
+	lazyPluginsVar       = "s:__volt_lazy_plugins"
+	lazyLoadFunc         = "s:__volt_lazy_load"
+	lazyLoadFileTypeFunc = "s:__volt_lazy_load_filetype"
+	lazyLoadMappingFunc  = "s:__volt_lazy_load_mapping"
+	// onFirstUseAutoloadFunc is a prefix of autoload functions compiled from
+	// s:on_first_use() (see pathutil.BundledPlugConfAutoload())
+	onFirstUseAutoloadFunc = "volt#bundled_plugconf#on_first_use_"
 )
 
 func isProhibitedFuncName(name string) bool {
 	return name == lazyLoadExcmdFunc ||
-		name == completeFunc
+		name == completeFunc ||
+		name == inProfilesFunc ||
+		name == onFirstUseFunc ||
+		name == lazyLoadFunc ||
+		name == lazyLoadFileTypeFunc ||
+		name == lazyLoadMappingFunc
 }
 
 // ParsedInfo represents parsed info of plugconf.
@@ -51,13 +78,17 @@ type ParsedInfo struct {
 	functions      []string
 	onLoadPreFunc  string
 	onLoadPostFunc string
+	onFirstUseFunc string
 	loadOnFunc     string
-	loadOn         loadOnType
-	loadOnArg      string
+	loadOn         []loadTrigger // empty if the plugin is loaded on start
 	dependsFunc    string
 	depends        pathutil.ReposPathList
 }
 
+func (pi *ParsedInfo) loadsOnStart() bool {
+	return len(pi.loadOn) == 0
+}
+
 // ConvertConfigToOnLoadPreFunc converts s:config() function name to
 // s:on_load_pre() (see 'volt migrate plugconf/config-func' function).
 // If no s:config() function is found, returns false.
@@ -96,6 +127,12 @@ func (pi *ParsedInfo) GeneratePlugconf() ([]byte, error) {
 	}
 	buf.WriteString("\n\n")
 
+	// s:on_first_use()
+	if pi.onFirstUseFunc != "" {
+		buf.WriteString(pi.onFirstUseFunc)
+		buf.WriteString("\n\n")
+	}
+
 	// s:loaded_on()
 	if pi.loadOnFunc != "" {
 		buf.WriteString(pi.loadOnFunc)
@@ -169,6 +206,14 @@ func (e *ParseError) HasWarns() bool {
 
 // Errors returns multierror.Error of errors.
 func (e *ParseError) Errors() *multierror.Error {
+	if e == nil {
+		return nil
+	}
+	return e.merr
+}
+
+// Warns returns multierror.Error of warnings.
+func (e *ParseError) Warns() *multierror.Error {
 	if e == nil {
 		return nil
 	}
@@ -280,16 +325,17 @@ func (errs MultiParseError) ErrorsAndWarns() *multierror.Error {
 func ParsePlugconfFile(path string, reposID int, reposPath pathutil.ReposPath) (result *ParsedInfo, parseErr *ParseError) {
 	// this function always returns non-nil parseErr
 	// (which may have empty errors / warns)
-	parseErr = new(ParseError)
+	parseErr = newParseError(path)
 
 	content, err := ioutil.ReadFile(path)
 	if err != nil {
-		err = multierror.Append(nil, err)
+		parseErr.merr = multierror.Append(parseErr.merr, err)
 		return
 	}
 	file, err := vimlparser.ParseFile(bytes.NewReader(content), path, nil)
 	if err != nil {
-		err = multierror.Append(nil, err)
+		// e.g. "{path}:3:1: vimlparser: E171: Missing :endif:    ENDFUNCTION"
+		parseErr.merr = multierror.Append(parseErr.merr, err)
 		return
 	}
 	result, parseErr = ParsePlugconf(file, content, path)
@@ -303,11 +349,11 @@ func ParsePlugconfFile(path string, reposID int, reposPath pathutil.ReposPath) (
 // ParsePlugconf always returns non-nil parseErr
 // (which may have empty errors / warns)
 func ParsePlugconf(file *ast.File, src []byte, path string) (*ParsedInfo, *ParseError) {
-	var loadOn = loadOnStart
-	var loadOnArg string
+	var loadOn []loadTrigger
 	var loadOnFunc string
 	var onLoadPreFunc string
 	var onLoadPostFunc string
+	var onFirstUseFunc string
 	var functions []string
 	var dependsFunc string
 	var depends pathutil.ReposPathList
@@ -332,27 +378,27 @@ func ParsePlugconf(file *ast.File, src []byte, path string) (*ParsedInfo, *Parse
 		case ident.Name == "s:loaded_on":
 			if loadOnFunc != "" {
 				parseErr.merr = multierror.Append(parseErr.merr,
-					errors.New("duplicate s:loaded_on()"))
+					atLine(fn, errors.New("duplicate s:loaded_on()")))
 				return true
 			}
 			if !isEmptyFunc(fn) {
 				loadOnFunc = string(extractBody(fn, src))
 				var err error
-				loadOn, loadOnArg, err = inspectReturnValue(fn)
+				loadOn, err = inspectReturnValue(fn)
 				if err != nil {
-					parseErr.merr = multierror.Append(parseErr.merr, err)
+					parseErr.merr = multierror.Append(parseErr.merr, atLine(fn, err))
 				}
 			}
 		case ident.Name == "s:config":
 			if onLoadPreFunc != "" {
 				parseErr.merr = multierror.Append(parseErr.merr,
-					errors.New("duplicate s:on_load_pre() and s:config()"))
+					atLine(fn, errors.New("duplicate s:on_load_pre() and s:config()")))
 				return true
 			}
 			parseErr.mwarn = multierror.Append(parseErr.mwarn,
-				errors.New("s:config() is deprecated. "+
+				atLine(fn, errors.New("s:config() is deprecated. "+
 					"please use s:on_load_pre() instead, or run "+
-					"\"volt migrate plugconf/config-func\" to rewrite existing plugconf files"))
+					"\"volt migrate plugconf/config-func\" to rewrite existing plugconf files")))
 			if !isEmptyFunc(fn) {
 				onLoadPreFunc = string(extractBody(fn, src))
 				onLoadPreFunc = rxFuncName.ReplaceAllString(
@@ -362,7 +408,7 @@ func ParsePlugconf(file *ast.File, src []byte, path string) (*ParsedInfo, *Parse
 		case ident.Name == "s:on_load_pre":
 			if onLoadPreFunc != "" {
 				parseErr.merr = multierror.Append(parseErr.merr,
-					errors.New("duplicate s:on_load_pre() and s:config()"))
+					atLine(fn, errors.New("duplicate s:on_load_pre() and s:config()")))
 				return true
 			}
 			if !isEmptyFunc(fn) {
@@ -371,16 +417,25 @@ func ParsePlugconf(file *ast.File, src []byte, path string) (*ParsedInfo, *Parse
 		case ident.Name == "s:on_load_post":
 			if onLoadPostFunc != "" {
 				parseErr.merr = multierror.Append(parseErr.merr,
-					errors.New("duplicate s:on_load_post()"))
+					atLine(fn, errors.New("duplicate s:on_load_post()")))
 				return true
 			}
 			if !isEmptyFunc(fn) {
 				onLoadPostFunc = string(extractBody(fn, src))
 			}
+		case ident.Name == "s:on_first_use":
+			if onFirstUseFunc != "" {
+				parseErr.merr = multierror.Append(parseErr.merr,
+					atLine(fn, errors.New("duplicate s:on_first_use()")))
+				return true
+			}
+			if !isEmptyFunc(fn) {
+				onFirstUseFunc = string(extractBody(fn, src))
+			}
 		case ident.Name == "s:depends":
 			if dependsFunc != "" {
 				parseErr.merr = multierror.Append(parseErr.merr,
-					errors.New("duplicate s:depends()"))
+					atLine(fn, errors.New("duplicate s:depends()")))
 				return true
 			}
 			if !isEmptyFunc(fn) {
@@ -388,13 +443,13 @@ func ParsePlugconf(file *ast.File, src []byte, path string) (*ParsedInfo, *Parse
 				var err error
 				depends, err = getDependencies(fn)
 				if err != nil {
-					parseErr.merr = multierror.Append(parseErr.merr, err)
+					parseErr.merr = multierror.Append(parseErr.merr, atLine(fn, err))
 				}
 			}
 		case isProhibitedFuncName(ident.Name):
 			parseErr.merr = multierror.Append(parseErr.merr,
-				errors.Errorf(
-					"'%s' is prohibited function name. please use other function name", ident.Name))
+				atLine(fn, errors.Errorf(
+					"'%s' is prohibited function name. please use other function name", ident.Name)))
 		default:
 			functions = append(functions, string(extractBody(fn, src)))
 		}
@@ -410,18 +465,24 @@ func ParsePlugconf(file *ast.File, src []byte, path string) (*ParsedInfo, *Parse
 		functions:      functions,
 		onLoadPreFunc:  onLoadPreFunc,
 		onLoadPostFunc: onLoadPostFunc,
+		onFirstUseFunc: onFirstUseFunc,
 		loadOnFunc:     loadOnFunc,
 		loadOn:         loadOn,
-		loadOnArg:      loadOnArg,
 		dependsFunc:    dependsFunc,
 		depends:        depends,
 	}, parseErr
 }
 
-// Inspect return value of s:loaded_on() function in plugconf
-func inspectReturnValue(fn *ast.Function) (loadOnType, string, error) {
-	var loadOn loadOnType
-	var loadOnArg string
+// atLine prefixes the line number of node to err.
+func atLine(node ast.Node, err error) error {
+	return errors.Errorf("line %d: %s", node.Pos().Line, err.Error())
+}
+
+// Inspect return value of s:loaded_on() function in plugconf.
+// The returned value is empty if the plugin is loaded on start.
+func inspectReturnValue(fn *ast.Function) ([]loadTrigger, error) {
+	var loadOn []loadTrigger
+	var found bool
 	var err error
 	ast.Inspect(fn, func(node ast.Node) bool {
 		// Cast to return node (return if it's not a return node)
@@ -430,29 +491,71 @@ func inspectReturnValue(fn *ast.Function) (loadOnType, string, error) {
 			return true
 		}
 
-		// Parse the argument of :return
-		rhs, ok := ret.Result.(*ast.BasicLit)
-		if ok && rhs.Kind == token.STRING {
+		// Parse the argument of :return (a string or a list of strings)
+		values := []ast.Expr{ret.Result}
+		if list, ok := ret.Result.(*ast.List); ok {
+			values = list.Values
+		}
+		var triggers []loadTrigger
+		start := false
+		for i := range values {
+			rhs, ok := values[i].(*ast.BasicLit)
+			if !ok || rhs.Kind != token.STRING {
+				continue
+			}
 			value := rhs.Value[1 : len(rhs.Value)-1]
 			if value == "start" {
-				loadOn = loadOnStart
-			} else if strings.HasPrefix(value, "filetype=") {
-				loadOn = loadOnFileType
-				loadOnArg = strings.TrimPrefix(value, "filetype=")
-			} else if strings.HasPrefix(value, "excmd=") {
-				loadOn = loadOnExcmd
-				loadOnArg = strings.TrimPrefix(value, "excmd=")
-			} else {
-				err = errors.New("Invalid rhs of ':return': " + rhs.Value)
+				found = true
+				start = true
+				continue
+			}
+			trigger, e := parseLoadTrigger(value)
+			if e != nil {
+				err = errors.Errorf("Invalid rhs of ':return': %s (%s)", rhs.Value, e.Error())
+				continue
 			}
+			found = true
+			triggers = append(triggers, trigger)
 		}
+		if start && len(triggers) > 0 {
+			err = errors.New("'start' cannot be combined with other values in s:loaded_on()")
+		}
+		loadOn = triggers
 
 		return true
 	})
-	if string(loadOn) == "" {
-		return "", "", errors.New("can't detect return value of s:loaded_on()")
+	if !found {
+		return nil, errors.New("can't detect return value of s:loaded_on()")
+	}
+	return loadOn, err
+}
+
+// rxEventName is a pattern which matches to autocmd event name.
+var rxEventName = regexp.MustCompile(`\A[A-Za-z]+\z`)
+
+// parseLoadTrigger parses a value of s:loaded_on() other than 'start'
+// (e.g. 'filetype=go,rust').
+func parseLoadTrigger(value string) (loadTrigger, error) {
+	i := strings.Index(value, "=")
+	if i < 0 {
+		return loadTrigger{}, errors.New("unknown value")
 	}
-	return loadOn, loadOnArg, err
+	on := loadOnType(value[:i])
+	switch on {
+	case loadOnFileType, loadOnExcmd, loadOnMapping, loadOnEvent:
+	default:
+		return loadTrigger{}, errors.Errorf("unknown type '%s'", on)
+	}
+	args := strings.Split(value[i+1:], ",")
+	for _, arg := range args {
+		if arg == "" {
+			return loadTrigger{}, errors.Errorf("empty %s", on)
+		}
+		if on == loadOnEvent && !rxEventName.MatchString(arg) {
+			return loadTrigger{}, errors.Errorf("invalid event name '%s'", arg)
+		}
+	}
+	return loadTrigger{on: on, args: args}, nil
 }
 
 // Returns true if fn.Body is empty or has only comment nodes
@@ -522,6 +625,10 @@ func getDependencies(fn *ast.Function) (pathutil.ReposPathList, error) {
 // $1 is a string before a function name.
 var rxFuncName = regexp.MustCompile(`\A(fu\w+!?\s+s:)(\w+)`)
 
+// rxScriptLocalFuncName is a pattern which matches to script-local function
+// name including "s:". $1 is a string before a function name.
+var rxScriptLocalFuncName = regexp.MustCompile(`\A(fu\w+!?\s+)s:\w+`)
+
 func convertToDecodableFunc(funcBody string, reposPath pathutil.ReposPath, reposID int) string {
 	// Change function name (e.g. s:loaded_on() -> s:loaded_on_1())
 	funcBody = rxFuncName.ReplaceAllString(funcBody, fmt.Sprintf("${1}${2}_%d", reposID))
@@ -530,24 +637,18 @@ func convertToDecodableFunc(funcBody string, reposPath pathutil.ReposPath, repos
 	return funcBody
 }
 
-type reposDepTree struct {
-	// The nodes' dependTo are nil. These repos's ranks are always 0.
-	leaves []reposDepNode
-}
-
-type reposDepNode struct {
-	repos      *lockjson.Repos
-	dependTo   []reposDepNode
-	dependedBy []reposDepNode
-}
-
 // ParseMultiPlugconf parses plugconfs of given reposList.
 func ParseMultiPlugconf(reposList []lockjson.Repos) (*MultiParsedInfo, MultiParseError) {
 	plugconfMap, parseErr := parsePlugconfAsMap(reposList)
 	if parseErr.HasErrs() {
 		return nil, parseErr
 	}
-	sortByDepends(reposList, plugconfMap)
+	if err := sortByDepends(reposList, plugconfMap); err != nil {
+		e := newParseError(err.reposPath.Plugconf())
+		e.merr = multierror.Append(e.merr, err)
+		parseErr = append(parseErr, *e)
+		return nil, parseErr
+	}
 	return &MultiParsedInfo{
 		plugconfMap: plugconfMap,
 		reposList:   reposList,
@@ -568,57 +669,268 @@ type MultiParsedInfo struct {
 // vimrcPath and gvimrcPath are fullpath of vimrc and gvimrc.
 // They become an empty string when each path does not exist.
 func (mp *MultiParsedInfo) GenerateBundlePlugconf(vimrcPath, gvimrcPath string) ([]byte, error) {
-	functions := make([]string, 0, 64)
 	loadCmds := make([]string, 0, len(mp.reposList))
 	lazyExcmd := make(map[string]string, len(mp.reposList))
+	for _, repos := range mp.reposList {
+		cmds, excmds := mp.generateLoadCmds(repos.Path)
+		loadCmds = append(loadCmds, cmds...)
+		for excmd, invokedCmd := range excmds {
+			lazyExcmd[excmd] = invokedCmd
+		}
+	}
+
+	var buf bytes.Buffer
+	mp.writeHeader(&buf, len(lazyExcmd) > 0)
+	if len(lazyExcmd) > 0 {
+		lazyExcmdJSON, err := json.Marshal(lazyExcmd)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("\n\nlet " + excmdLoadPlugin + " = " + string(lazyExcmdJSON))
+	}
+	if len(loadCmds) > 0 {
+		buf.WriteString("\n\n")
+		buf.WriteString(`augroup volt-bundled-plugconf
+  autocmd!
+`)
+		buf.WriteString(strings.Join(loadCmds, "\n"))
+		buf.WriteString("\naugroup END")
+	}
+	writeRCPaths(&buf, vimrcPath, gvimrcPath)
+	return buf.Bytes(), nil
+}
 
+// GenerateRuntimeProfileBundlePlugconf generates bundled plugconf content
+// which loads the plugins of the profiles specified by g:volt_profile
+// (a profile name or a list of profile names).
+// If g:volt_profile is not defined, defaultProfiles are used.
+// mp must be parsed from the repositories of all profiles.
+// vimrcPath and gvimrcPath are same as GenerateBundlePlugconf.
+func (mp *MultiParsedInfo) GenerateRuntimeProfileBundlePlugconf(profiles lockjson.ProfileList, defaultProfiles []string, vimrcPath, gvimrcPath string) ([]byte, error) {
+	loadCmds := make([]string, 0, len(mp.reposList))
+	hasLazyExcmd := false
 	for _, repos := range mp.reposList {
-		p, hasPlugconf := mp.plugconfMap[repos.Path]
-		// :packadd <repos>
-		optName := filepath.Base(repos.Path.EncodeToPlugDirName())
-		packadd := fmt.Sprintf("packadd %s", optName)
-
-		// s:on_load_pre(), invoked command, s:on_load_post()
-		var invokedCmd string
-		if hasPlugconf {
-			cmds := make([]string, 0, 3)
-			if p.onLoadPreFunc != "" {
-				functions = append(functions, convertToDecodableFunc(p.onLoadPreFunc, p.reposPath, p.reposID))
-				cmds = append(cmds, fmt.Sprintf("call s:on_load_pre_%d()", p.reposID))
+		names := make([]string, 0, len(profiles))
+		for i := range profiles {
+			if profiles[i].Builds(repos.Path) {
+				names = append(names, profiles[i].Name)
 			}
-			cmds = append(cmds, packadd)
-			if p.onLoadPostFunc != "" {
-				functions = append(functions, convertToDecodableFunc(p.onLoadPostFunc, p.reposPath, p.reposID))
-				cmds = append(cmds, fmt.Sprintf("call s:on_load_post_%d()", p.reposID))
+		}
+		cmds, excmds := mp.generateLoadCmds(repos.Path)
+		if len(names) == 0 || len(cmds) == 0 {
+			continue
+		}
+		loadCmds = append(loadCmds, fmt.Sprintf("  if %s(%s)", inProfilesFunc, vimListLiteral(names)))
+		for _, excmd := range sortedKeys(excmds) {
+			loadCmds = append(loadCmds, fmt.Sprintf("    let %s[%s] = %s", excmdLoadPlugin, vimStringLiteral(excmd), vimStringLiteral(excmds[excmd])))
+			hasLazyExcmd = true
+		}
+		for i := range cmds {
+			loadCmds = append(loadCmds, "  "+cmds[i])
+		}
+		loadCmds = append(loadCmds, "  endif")
+	}
+
+	allNames := make([]string, 0, len(profiles))
+	for i := range profiles {
+		allNames = append(allNames, profiles[i].Name)
+	}
+
+	var buf bytes.Buffer
+	mp.writeHeader(&buf, hasLazyExcmd)
+	buf.WriteString(`
+
+let ` + voltProfilesVar + ` = get(g:, 'volt_profile', ` + vimListLiteral(defaultProfiles) + `)
+if type(` + voltProfilesVar + `) isnot# type([])
+  let ` + voltProfilesVar + ` = [` + voltProfilesVar + `]
+endif
+for s:__volt_name in ` + voltProfilesVar + `
+  if index(` + vimListLiteral(allNames) + `, s:__volt_name) is# -1
+    echohl ErrorMsg
+    echomsg printf('[volt] g:volt_profile: profile ''%s'' does not exist', s:__volt_name)
+    echohl None
+  endif
+endfor
+unlet! s:__volt_name
+
+function ` + inProfilesFunc + `(profiles) abort
+  for name in a:profiles
+    if index(` + voltProfilesVar + `, name) isnot# -1
+      return 1
+    endif
+  endfor
+  return 0
+endfunction`)
+	if hasLazyExcmd {
+		buf.WriteString("\n\nlet " + excmdLoadPlugin + " = {}")
+	}
+	if len(loadCmds) > 0 {
+		buf.WriteString("\n\n")
+		buf.WriteString(`augroup volt-bundled-plugconf
+  autocmd!
+`)
+		buf.WriteString(strings.Join(loadCmds, "\n"))
+		buf.WriteString("\naugroup END")
+	}
+	writeRCPaths(&buf, vimrcPath, gvimrcPath)
+	return buf.Bytes(), nil
+}
+
+// generateLoadCmds returns bootstrap statements and lazy-loaded Ex commands
+// (Ex command name -> invoked command) of reposPath.
+func (mp *MultiParsedInfo) generateLoadCmds(reposPath pathutil.ReposPath) ([]string, map[string]string) {
+	loadCmds := make([]string, 0, 1)
+	lazyExcmd := make(map[string]string)
+
+	p, hasPlugconf := mp.plugconfMap[reposPath]
+	// :packadd <repos>
+	optName := filepath.Base(reposPath.EncodeToPlugDirName())
+	packadd := fmt.Sprintf("packadd %s", optName)
+
+	// s:on_load_pre(), invoked command, s:on_load_post()
+	var invokedCmd string
+	if hasPlugconf {
+		cmds := make([]string, 0, 3)
+		if p.onLoadPreFunc != "" {
+			cmds = append(cmds, fmt.Sprintf("call s:on_load_pre_%d()", p.reposID))
+		}
+		cmds = append(cmds, packadd)
+		if p.onLoadPostFunc != "" {
+			cmds = append(cmds, fmt.Sprintf("call s:on_load_post_%d()", p.reposID))
+		}
+		if p.onFirstUseFunc != "" {
+			if pathutil.Exists(filepath.Join(reposPath.FullPath(), "autoload")) {
+				// Invoke s:on_first_use() before the first autoload script of
+				// the plugin is sourced
+				loadCmds = append(loadCmds, fmt.Sprintf("  autocmd SourcePre %s call %s(%d)",
+					autocmdPattern(reposPath), onFirstUseFunc, p.reposID))
+			} else {
+				cmds = append(cmds, fmt.Sprintf("call %s(%d)", onFirstUseFunc, p.reposID))
 			}
-			invokedCmd = strings.Join(cmds, " | ")
-		} else {
-			invokedCmd = packadd
 		}
+		invokedCmd = strings.Join(cmds, " | ")
+	} else {
+		invokedCmd = packadd
+	}
 
-		// Bootstrap statements
-		switch {
-		case !hasPlugconf || p.loadOn == loadOnStart:
-			loadCmds = append(loadCmds, "  "+invokedCmd)
-		case p.loadOn == loadOnFileType:
-			loadCmds = append(loadCmds,
-				fmt.Sprintf("  autocmd %s %s %s", string(p.loadOn), p.loadOnArg, invokedCmd))
-		case p.loadOn == loadOnExcmd:
+	// Bootstrap statements
+	if !hasPlugconf || p.loadsOnStart() {
+		loadCmds = append(loadCmds, "  "+invokedCmd)
+		return loadCmds, lazyExcmd
+	}
+
+	// Lazy-loaded plugin: the triggers call lazyLoadFunc, which loads the
+	// plugin only once
+	lazyLoad := fmt.Sprintf("call %s(%d)", lazyLoadFunc, p.reposID)
+	var excmds, mappings []string
+	var triggerCmds []string
+	for _, trigger := range p.loadOn {
+		switch trigger.on {
+		case loadOnFileType:
+			triggerCmds = append(triggerCmds,
+				fmt.Sprintf("  autocmd FileType %s call %s(%d, expand('<amatch>'))", strings.Join(trigger.args, ","), lazyLoadFileTypeFunc, p.reposID))
+		case loadOnEvent:
+			triggerCmds = append(triggerCmds,
+				fmt.Sprintf("  autocmd %s * %s", strings.Join(trigger.args, ","), lazyLoad))
+		case loadOnExcmd:
 			// Define dummy Ex commands
-			for _, excmd := range strings.Split(p.loadOnArg, ",") {
-				lazyExcmd[excmd] = invokedCmd
-				loadCmds = append(loadCmds,
+			for _, excmd := range trigger.args {
+				lazyExcmd[excmd] = lazyLoad
+				excmds = append(excmds, excmd)
+				triggerCmds = append(triggerCmds,
 					fmt.Sprintf("  command -complete=customlist,%[1]s -bang -bar -range -nargs=* %[3]s call %[2]s('%[3]s', <q-args>, expand('<bang>'), expand('<line1>'), expand('<line2>'))", completeFunc, lazyLoadExcmdFunc, excmd))
 			}
+		case loadOnMapping:
+			// Define dummy mappings
+			for _, lhs := range trigger.args {
+				mappings = append(mappings, lhs)
+				arg := strings.NewReplacer("<", "<lt>", "|", "<Bar>").Replace(vimStringLiteral(lhs))
+				for _, mode := range []string{"n", "x", "o"} {
+					triggerCmds = append(triggerCmds,
+						fmt.Sprintf("  %snoremap <silent> %s :<C-u>call <SID>%s(%d, '%s', %s)<CR>", mode, lhs, strings.TrimPrefix(lazyLoadMappingFunc, "s:"), p.reposID, mode, arg))
+				}
+			}
 		}
+	}
 
-		// User defined functions in plugconf
-		if hasPlugconf {
-			functions = append(functions, p.functions...)
+	// The plugconf ids of lazy-loaded dependencies
+	depends := make([]string, 0, len(p.depends))
+	for _, dep := range p.depends {
+		if d, exists := mp.plugconfMap[dep]; exists && !d.loadsOnStart() {
+			depends = append(depends, strconv.Itoa(d.reposID))
 		}
 	}
+	loadCmds = append(loadCmds, fmt.Sprintf("  let %s[%d] = {'cmd': %s, 'depends': [%s], 'excmds': %s, 'mappings': %s}",
+		lazyPluginsVar, p.reposID, vimStringLiteral(invokedCmd), strings.Join(depends, ", "), vimListLiteral(excmds), vimListLiteral(mappings)))
+	loadCmds = append(loadCmds, triggerCmds...)
+	return loadCmds, lazyExcmd
+}
+
+// autocmdPattern returns the autocmd pattern which matches the autoload
+// scripts of reposPath. Both the directory under pack/volt/opt and the
+// repository directory are matched because Vim resolves the former if it is
+// a symlink. The former does not depend on (vim dir) to work in an archive
+// created by "volt build -archive".
+func autocmdPattern(reposPath pathutil.ReposPath) string {
+	escape := strings.NewReplacer(" ", "\\ ", ",", "\\,").Replace
+	optDir := "*/pack/volt/opt/" + filepath.Base(reposPath.EncodeToPlugDirName())
+	dirs := []string{optDir, filepath.ToSlash(reposPath.FullPath())}
+	patterns := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		patterns = append(patterns, escape(dir)+"/autoload/*")
+	}
+	return strings.Join(patterns, ",")
+}
+
+// GenerateBundlePlugconfAutoload generates the autoload script which has
+// s:on_first_use() functions of all plugconfs.
+// Each function is renamed to "volt#bundled_plugconf#on_first_use_{id}()",
+// and its body is not parsed by Vim until the function is invoked.
+// It returns nil if no plugconf has s:on_first_use().
+func (mp *MultiParsedInfo) GenerateBundlePlugconfAutoload() []byte {
+	functions := make([]string, 0, len(mp.reposList))
+	for _, repos := range mp.reposList {
+		p, hasPlugconf := mp.plugconfMap[repos.Path]
+		if !hasPlugconf || p.onFirstUseFunc == "" {
+			continue
+		}
+		funcBody := rxScriptLocalFuncName.ReplaceAllString(p.onFirstUseFunc,
+			fmt.Sprintf("${1}%s%d", onFirstUseAutoloadFunc, p.reposID))
+		functions = append(functions, "\" "+p.reposPath.String()+"\n"+funcBody)
+	}
+	if len(functions) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(functions, "\n\n") + "\n")
+}
+
+// writeHeader writes the include guard, the functions of all plugconfs, and
+// the functions for lazy-loaded Ex commands if hasLazyExcmd is true.
+func (mp *MultiParsedInfo) writeHeader(buf *bytes.Buffer, hasLazyExcmd bool) {
+	functions := make([]string, 0, 64)
+	hasOnFirstUse := false
+	hasLazy := false
+	for _, repos := range mp.reposList {
+		p, hasPlugconf := mp.plugconfMap[repos.Path]
+		if !hasPlugconf {
+			continue
+		}
+		if p.onFirstUseFunc != "" {
+			hasOnFirstUse = true
+		}
+		if !p.loadsOnStart() {
+			hasLazy = true
+		}
+		if p.onLoadPreFunc != "" {
+			functions = append(functions, convertToDecodableFunc(p.onLoadPreFunc, p.reposPath, p.reposID))
+		}
+		if p.onLoadPostFunc != "" {
+			functions = append(functions, convertToDecodableFunc(p.onLoadPostFunc, p.reposPath, p.reposID))
+		}
+		// User defined functions in plugconf
+		functions = append(functions, p.functions...)
+	}
 
-	var buf bytes.Buffer
 	buf.WriteString(`if exists('g:loaded_volt_system_bundled_plugconf')
   finish
 endif
@@ -627,19 +939,83 @@ let g:loaded_volt_system_bundled_plugconf = 1`)
 		buf.WriteString("\n\n")
 		buf.WriteString(strings.Join(functions, "\n\n"))
 	}
-	if len(lazyExcmd) > 0 {
-		lazyExcmdJSON, err := json.Marshal(lazyExcmd)
-		if err != nil {
-			return nil, err
-		}
+	if hasOnFirstUse {
+		buf.WriteString(`
+
+let ` + firstUsedVar + ` = {}
+
+function ` + onFirstUseFunc + `(id) abort
+  if has_key(` + firstUsedVar + `, a:id)
+    return
+  endif
+  let ` + firstUsedVar + `[a:id] = 1
+  call call('` + onFirstUseAutoloadFunc + `' . a:id, [])
+endfunction`)
+	}
+	if hasLazy {
+		// * dein#autoload#_on_ft()
+		//   https://github.com/Shougo/dein.vim/blob/2adba7655b23f2fc1ddcd35e15d380c5069a3712/autoload/dein/autoload.vim#L97-L120
+		// * dein#autoload#_on_map()
+		//   https://github.com/Shougo/dein.vim/blob/2adba7655b23f2fc1ddcd35e15d380c5069a3712/autoload/dein/autoload.vim#L177-L214
+		buf.WriteString(`
+
+let ` + lazyPluginsVar + ` = {}
+
+function ` + lazyLoadFunc + `(id) abort
+  if !has_key(` + lazyPluginsVar + `, a:id)
+    return 0
+  endif
+  let plugin = remove(` + lazyPluginsVar + `, a:id)
+  for id in plugin.depends
+    call ` + lazyLoadFunc + `(id)
+  endfor
+  for command in plugin.excmds
+    if exists(':' . command) is# 2
+      execute 'delcommand' command
+    endif
+  endfor
+  for lhs in plugin.mappings
+    for mode in ['n', 'x', 'o']
+      execute 'silent!' mode . 'unmap' lhs
+    endfor
+  endfor
+  execute plugin.cmd
+  return 1
+endfunction
+
+function ` + lazyLoadFileTypeFunc + `(id, filetype) abort
+  if !` + lazyLoadFunc + `(a:id)
+    return
+  endif
+  " ftplugin, indent, and syntax files of the plugin are not sourced yet
+  for group in ['filetypeplugin', 'filetypeindent', 'syntaxset']
+    if exists('#' . group . '#FileType')
+      execute 'doautocmd <nomodeline>' group 'FileType' a:filetype
+    endif
+  endfor
+endfunction
+
+function ` + lazyLoadMappingFunc + `(id, mode, lhs) abort
+  let cnt = v:count > 0 ? v:count : ''
+  let operator = v:operator
+  call ` + lazyLoadFunc + `(a:id)
+  if a:mode is# 'x'
+    call feedkeys('gv', 'n')
+  elseif a:mode is# 'o'
+    call feedkeys("\<Esc>" . cnt . operator, 'n')
+  else
+    call feedkeys(cnt, 'n')
+  endif
+  call feedkeys(substitute(a:lhs, '<[^<>]\+>', '\=eval(''"\'' . submatch(0) . ''"'')', 'g'), 'm')
+endfunction`)
+	}
+	if hasLazyExcmd {
 		// * dein#autoload#_on_cmd()
 		//   https://github.com/Shougo/dein.vim/blob/2adba7655b23f2fc1ddcd35e15d380c5069a3712/autoload/dein/autoload.vim#L157-L175
 		// * dein#autoload#_dummy_complete()
 		//   https://github.com/Shougo/dein.vim/blob/2adba7655b23f2fc1ddcd35e15d380c5069a3712/autoload/dein/autoload.vim#L216-L232
 		buf.WriteString(`
 
-let ` + excmdLoadPlugin + ` = ` + string(lazyExcmdJSON) + `
-
 function ` + lazyLoadExcmdFunc + `(command, args, bang, line1, line2) abort
   if exists(':' . a:command) is# 2
     execute 'delcommand' a:command
@@ -672,33 +1048,46 @@ function ` + completeFunc + `(arglead, cmdline, cursorpos) abort
     call feedkeys("\<C-d>", 'n')
   endif
   return [a:arglead]
-endfunction
-`)
-	}
-	if len(loadCmds) > 0 {
-		buf.WriteString("\n\n")
-		buf.WriteString(`augroup volt-bundled-plugconf
-  autocmd!
-`)
-		buf.WriteString(strings.Join(loadCmds, "\n"))
-		buf.WriteString("\naugroup END")
+endfunction`)
 	}
+}
 
+// writeRCPaths writes statements to set $MYVIMRC and $MYGVIMRC.
+func writeRCPaths(buf *bytes.Buffer, vimrcPath, gvimrcPath string) {
 	if vimrcPath != "" || gvimrcPath != "" {
 		buf.WriteString("\n")
 		if vimrcPath != "" {
 			buf.WriteString("\n")
-			vimrcPath = strings.Replace(vimrcPath, "'", "''", -1)
-			buf.WriteString("let $MYVIMRC = '" + vimrcPath + "'")
+			buf.WriteString("let $MYVIMRC = " + vimStringLiteral(vimrcPath))
 		}
 		if gvimrcPath != "" {
 			buf.WriteString("\n")
-			gvimrcPath = strings.Replace(gvimrcPath, "'", "''", -1)
-			buf.WriteString("let $MYGVIMRC = '" + gvimrcPath + "'")
+			buf.WriteString("let $MYGVIMRC = " + vimStringLiteral(gvimrcPath))
 		}
 	}
+}
 
-	return buf.Bytes(), nil
+// vimStringLiteral returns single-quoted Vim script string literal of s.
+func vimStringLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// vimListLiteral returns Vim script list literal of strings.
+func vimListLiteral(list []string) string {
+	elems := make([]string, 0, len(list))
+	for i := range list {
+		elems = append(elems, vimStringLiteral(list[i]))
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // Each iterates each repository by given func.
@@ -715,14 +1104,29 @@ func RdepsOf(reposPath pathutil.ReposPath, reposList []lockjson.Repos) (pathutil
 	if parseErr.HasErrs() {
 		return nil, parseErr.ErrorsAndWarns()
 	}
-	_, _, rdepsMap := getDepMaps(reposList, plugconfMap)
-	rdeps := rdepsMap[reposPath]
-	if rdeps == nil {
-		rdeps = make(pathutil.ReposPathList, 0)
+	rdeps := make(pathutil.ReposPathList, 0)
+	for i := range reposList {
+		if p, exists := plugconfMap[reposList[i].Path]; exists && p.depends.Contains(reposPath) {
+			rdeps = append(rdeps, reposList[i].Path)
+		}
 	}
 	return rdeps, nil
 }
 
+// DependsOf returns the plugins which reposPath depends on by s:depends() of
+// its plugconf. nil is returned if the plugconf does not exist.
+func DependsOf(reposPath pathutil.ReposPath) (pathutil.ReposPathList, error) {
+	path := reposPath.Plugconf()
+	if !pathutil.Exists(path) {
+		return nil, nil
+	}
+	result, parseErr := ParsePlugconfFile(path, 1, reposPath)
+	if parseErr.HasErrs() {
+		return nil, parseErr.Errors()
+	}
+	return result.depends, nil
+}
+
 // Parse plugconf of reposList and return parsed plugconf info as map
 func parsePlugconfAsMap(reposList []lockjson.Repos) (map[pathutil.ReposPath]*ParsedInfo, MultiParseError) {
 	parseErrAll := make(MultiParseError, 0, len(reposList))
@@ -745,90 +1149,74 @@ func parsePlugconfAsMap(reposList []lockjson.Repos) (map[pathutil.ReposPath]*Par
 	return plugconfMap, parseErrAll
 }
 
-// Move the plugins which was depended to previous plugin which depends to them.
-// reposList is sorted in-place.
-func sortByDepends(reposList []lockjson.Repos, plugconfMap map[pathutil.ReposPath]*ParsedInfo) {
-	reposMap, depsMap, rdepsMap := getDepMaps(reposList, plugconfMap)
-	rank := make(map[pathutil.ReposPath]int, len(reposList))
-	for i := range reposList {
-		if _, exists := rank[reposList[i].Path]; !exists {
-			tree := makeDepTree(reposList[i].Path, reposMap, depsMap, rdepsMap)
-			for i := range tree.leaves {
-				makeRank(rank, &tree.leaves[i], 0)
-			}
-		}
-	}
-	sort.Slice(reposList, func(i, j int) bool {
-		return rank[reposList[i].Path] < rank[reposList[j].Path]
-	})
+// dependencyCycleError is returned by sortByDepends() when plugins depend on
+// each other by s:depends().
+type dependencyCycleError struct {
+	reposPath pathutil.ReposPath
+	cycle     pathutil.ReposPathList
 }
 
-func getDepMaps(reposList []lockjson.Repos, plugconfMap map[pathutil.ReposPath]*ParsedInfo) (map[pathutil.ReposPath]*lockjson.Repos, map[pathutil.ReposPath]pathutil.ReposPathList, map[pathutil.ReposPath]pathutil.ReposPathList) {
+func (e *dependencyCycleError) Error() string {
+	return "dependency cycle in s:depends(): " + strings.Join(e.cycle.Strings(), " -> ")
+}
+
+// sortByDepends sorts reposList in topological order of s:depends(), that is,
+// the plugins which are depended are moved before the plugins which depend on
+// them. The order of independent plugins is kept.
+// Dependencies not in reposList are ignored.
+// reposList is sorted in-place.
+func sortByDepends(reposList []lockjson.Repos, plugconfMap map[pathutil.ReposPath]*ParsedInfo) *dependencyCycleError {
 	reposMap := make(map[pathutil.ReposPath]*lockjson.Repos, len(reposList))
-	depsMap := make(map[pathutil.ReposPath]pathutil.ReposPathList, len(reposList))
-	rdepsMap := make(map[pathutil.ReposPath]pathutil.ReposPathList, len(reposList))
 	for i := range reposList {
-		reposPath := reposList[i].Path
-		reposMap[reposPath] = &reposList[i]
+		reposMap[reposList[i].Path] = &reposList[i]
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[pathutil.ReposPath]int, len(reposList))
+	sorted := make([]lockjson.Repos, 0, len(reposList))
+	var stack pathutil.ReposPathList
+	var visit func(reposPath pathutil.ReposPath) *dependencyCycleError
+	visit = func(reposPath pathutil.ReposPath) *dependencyCycleError {
+		switch state[reposPath] {
+		case visiting:
+			// stack is "... -> reposPath -> ... -> (top)"
+			i := len(stack) - 1
+			for stack[i] != reposPath {
+				i--
+			}
+			cycle := append(append(pathutil.ReposPathList{}, stack[i:]...), reposPath)
+			return &dependencyCycleError{reposPath: reposPath, cycle: cycle}
+		case visited:
+			return nil
+		}
+		state[reposPath] = visiting
+		stack = append(stack, reposPath)
 		if p, exists := plugconfMap[reposPath]; exists {
-			depsMap[reposPath] = p.depends
 			for _, dep := range p.depends {
-				rdepsMap[dep] = append(rdepsMap[dep], reposPath)
+				if _, exists := reposMap[dep]; !exists {
+					continue
+				}
+				if err := visit(dep); err != nil {
+					return err
+				}
 			}
 		}
+		stack = stack[:len(stack)-1]
+		state[reposPath] = visited
+		sorted = append(sorted, *reposMap[reposPath])
+		return nil
 	}
-	return reposMap, depsMap, rdepsMap
-}
 
-func makeDepTree(reposPath pathutil.ReposPath, reposMap map[pathutil.ReposPath]*lockjson.Repos, depsMap map[pathutil.ReposPath]pathutil.ReposPathList, rdepsMap map[pathutil.ReposPath]pathutil.ReposPathList) *reposDepTree {
-	visitedNodes := make(map[pathutil.ReposPath]*reposDepNode, len(reposMap))
-	node := makeNodes(reposPath, reposMap, depsMap, rdepsMap, visitedNodes)
-	leaves := make([]reposDepNode, 0, 10)
-	visitedMarks := make(map[pathutil.ReposPath]bool, 10)
-	visitNode(node, func(n *reposDepNode) {
-		if len(n.dependTo) == 0 {
-			leaves = append(leaves, *n)
+	for i := range reposList {
+		if err := visit(reposList[i].Path); err != nil {
+			return err
 		}
-	}, visitedMarks)
-	return &reposDepTree{leaves: leaves}
-}
-
-func makeNodes(reposPath pathutil.ReposPath, reposMap map[pathutil.ReposPath]*lockjson.Repos, depsMap map[pathutil.ReposPath]pathutil.ReposPathList, rdepsMap map[pathutil.ReposPath]pathutil.ReposPathList, visited map[pathutil.ReposPath]*reposDepNode) *reposDepNode {
-	if node, exists := visited[reposPath]; exists {
-		return node
-	}
-	node := &reposDepNode{repos: reposMap[reposPath]}
-	visited[reposPath] = node
-	for i := range depsMap[reposPath] {
-		dep := makeNodes(depsMap[reposPath][i], reposMap, depsMap, rdepsMap, visited)
-		node.dependTo = append(node.dependTo, *dep)
-	}
-	for i := range rdepsMap[reposPath] {
-		rdep := makeNodes(rdepsMap[reposPath][i], reposMap, depsMap, rdepsMap, visited)
-		node.dependedBy = append(node.dependedBy, *rdep)
-	}
-	return node
-}
-
-func visitNode(node *reposDepNode, callback func(*reposDepNode), visited map[pathutil.ReposPath]bool) {
-	if node == nil || node.repos == nil || visited[node.repos.Path] {
-		return
-	}
-	visited[node.repos.Path] = true
-	callback(node)
-	for i := range node.dependTo {
-		visitNode(&node.dependTo[i], callback, visited)
-	}
-	for i := range node.dependedBy {
-		visitNode(&node.dependedBy[i], callback, visited)
-	}
-}
-
-func makeRank(rank map[pathutil.ReposPath]int, node *reposDepNode, value int) {
-	rank[node.repos.Path] = value
-	for i := range node.dependedBy {
-		makeRank(rank, &node.dependedBy[i], value+1)
 	}
+	copy(reposList, sorted)
+	return nil
 }
 
 // Template is a content of plugconf template.
@@ -839,9 +1227,10 @@ type Template struct {
 // FetchPlugconfTemplate fetches reposPath's plugconf from vim-volt/plugconf-templates
 // repository.
 // Fetched URL: https://raw.githubusercontent.com/vim-volt/plugconf-templates/master/templates/{reposPath}.vim
-func FetchPlugconfTemplate(reposPath pathutil.ReposPath) (*Template, error) {
-	url := path.Join("https://raw.githubusercontent.com/vim-volt/plugconf-templates/master/templates", reposPath.String()+".vim")
-	content, err := httputil.GetContent(url)
+func FetchPlugconfTemplate(ctx context.Context, reposPath pathutil.ReposPath) (*Template, error) {
+	// path.Join() cannot be used because it changes "https://" to "https:/"
+	url := "https://raw.githubusercontent.com/vim-volt/plugconf-templates/master/templates/" + reposPath.String() + ".vim"
+	content, err := httputil.GetContent(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -864,16 +1253,21 @@ const skeletonPlugconfLoadOn = `" This function determines when a plugin is load
 " * 'start' (a plugin will be loaded at VimEnter event)
 " * 'filetype=<filetypes>' (a plugin will be loaded at FileType event)
 " * 'excmd=<excmds>' (a plugin will be loaded at CmdUndefined event)
-" <filetypes> and <excmds> can be multiple values separated by comma.
+" * 'mapping=<mappings>' (a plugin will be loaded when one of <mappings> is
+"   typed in Normal, Visual, or Operator-pending mode)
+" * 'event=<events>' (a plugin will be loaded at one of <events>)
+" <filetypes>, <excmds>, <mappings>, and <events> can be multiple values
+" separated by comma. To load a plugin by multiple triggers, return a list
+" (e.g. ['filetype=go', 'excmd=GoRun']).
 "
-" This function must contain 'return "<str>"' code.
-" (the argument of :return must be string literal)
+" This function must contain 'return "<str>"' or 'return [<str>, ...]' code.
+" (the argument of :return must be string literal, or list literal of them)
 function! s:loaded_on()
   return 'start'
 endfunction`
 
 const skeletonPlugconfDepends = `" Dependencies of this plugin.
-" The specified dependencies are loaded after this plugin is loaded.
+" The specified dependencies are loaded before this plugin is loaded.
 "
 " This function must contain 'return [<repos>, ...]' code.
 " (the argument of :return must be list literal, and the elements are string)
@@ -903,3 +1297,71 @@ func (pt *Template) Generate(path string) ([]byte, *multierror.Error) {
 	}
 	return content, nil
 }
+
+// maxVariableHints is the max number of variables shown in skeleton plugconf.
+const maxVariableHints = 30
+
+// GenerateSkeleton generates skeleton plugconf content.
+// If info is not nil, the skeleton is pre-filled with commented hints from
+// the analyzed plugin: lazy-load candidates in s:loaded_on(), global variables
+// in s:on_load_pre(), and <Plug> mappings in s:on_load_post().
+func GenerateSkeleton(info *PluginInfo) ([]byte, error) {
+	result := &ParsedInfo{}
+	if info != nil && !info.isEmpty() {
+		var lines []string
+		if len(info.Variables) > 0 {
+			lines = append(lines, "\" Variables documented in this plugin:")
+			for i, v := range info.Variables {
+				if i >= maxVariableHints {
+					lines = append(lines, "\" (and more, see the document)")
+					break
+				}
+				lines = append(lines, "\" let "+v+" = ...")
+			}
+		}
+		result.onLoadPreFunc = addSkeletonHints(skeletonPlugconfOnLoadPre, lines)
+
+		lines = nil
+		if len(info.Mappings) > 0 {
+			lines = append(lines, "\" Mappings provided by this plugin:")
+			for _, m := range info.Mappings {
+				lines = append(lines, "\" "+m.Mode+"map {lhs} "+m.Name)
+			}
+		}
+		result.onLoadPostFunc = addSkeletonHints(skeletonPlugconfOnLoadPost, lines)
+
+		lines = nil
+		if len(info.Commands) > 0 || len(info.FileTypes) > 0 || len(info.Mappings) > 0 {
+			lines = append(lines, "\" Lazy-load candidates detected in this plugin:")
+			if len(info.Commands) > 0 {
+				lines = append(lines, "\" return 'excmd="+strings.Join(info.Commands, ",")+"'")
+			}
+			if len(info.FileTypes) > 0 {
+				lines = append(lines, "\" return 'filetype="+strings.Join(info.FileTypes, ",")+"'")
+			}
+			if len(info.Mappings) > 0 {
+				names := make([]string, 0, len(info.Mappings))
+				for _, m := range info.Mappings {
+					// Mappings are sorted by name
+					if len(names) == 0 || names[len(names)-1] != m.Name {
+						names = append(names, m.Name)
+					}
+				}
+				lines = append(lines, "\" return 'mapping="+strings.Join(names, ",")+"'")
+			}
+		}
+		result.loadOnFunc = addSkeletonHints(skeletonPlugconfLoadOn, lines)
+	}
+	return result.GeneratePlugconf()
+}
+
+// addSkeletonHints inserts comment lines at the beginning of the function
+// body of skeleton.
+func addSkeletonHints(skeleton string, lines []string) string {
+	if len(lines) == 0 {
+		return skeleton
+	}
+	i := strings.Index(skeleton, "function! ")
+	j := strings.Index(skeleton[i:], "\n") + i + 1
+	return skeleton[:j] + "  " + strings.Join(lines, "\n  ") + "\n" + skeleton[j:]
+}
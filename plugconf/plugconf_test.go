@@ -0,0 +1,283 @@
+package plugconf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/haya14busa/go-vimlparser"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func parsePlugconfString(t *testing.T, src string) *ParsedInfo {
+	file, err := vimlparser.ParseFile(strings.NewReader(src), "test.vim", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pi, parseErr := ParsePlugconf(file, []byte(src), "test.vim")
+	if parseErr.HasErrs() {
+		t.Fatal(parseErr.ErrorsAndWarns())
+	}
+	return pi
+}
+
+func TestOnFirstUse(t *testing.T) {
+	const src = `function! s:on_load_pre()
+  let g:foo_enabled = 1
+endfunction
+
+function! s:on_first_use() abort
+  let g:foo_config = {'a': 1}
+endfunction`
+
+	pi := parsePlugconfString(t, src)
+	if !strings.HasPrefix(pi.onFirstUseFunc, "function! s:on_first_use() abort") {
+		t.Fatalf("s:on_first_use() was not parsed: %q", pi.onFirstUseFunc)
+	}
+	if len(pi.functions) != 0 {
+		t.Errorf("s:on_first_use() must not be a user function: %v", pi.functions)
+	}
+
+	// GeneratePlugconf() keeps s:on_first_use()
+	content, err := pi.GeneratePlugconf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(content, []byte(pi.onFirstUseFunc)) {
+		t.Errorf("s:on_first_use() is not in generated plugconf:\n%s", content)
+	}
+
+	// Bundled plugconf invokes the compiled autoload function
+	reposPath := pathutil.ReposPath("github.com/tyru/foo.vim")
+	pi.reposID = 3
+	pi.reposPath = reposPath
+	mp := &MultiParsedInfo{
+		plugconfMap: parsedInfoMap{reposPath: pi},
+		reposList:   []lockjson.Repos{{Path: reposPath}},
+	}
+	bundled, err := mp.GenerateBundlePlugconf("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"function " + onFirstUseFunc + "(id) abort",
+		"call " + onFirstUseFunc + "(3)",
+	} {
+		if !bytes.Contains(bundled, []byte(s)) {
+			t.Errorf("expected %q in bundled plugconf:\n%s", s, bundled)
+		}
+	}
+	if bytes.Contains(bundled, []byte("g:foo_config")) {
+		t.Errorf("s:on_first_use() body must not be in bundled plugconf:\n%s", bundled)
+	}
+
+	autoload := mp.GenerateBundlePlugconfAutoload()
+	expected := "\" github.com/tyru/foo.vim\nfunction! volt#bundled_plugconf#on_first_use_3() abort\n  let g:foo_config = {'a': 1}\nendfunction\n"
+	if string(autoload) != expected {
+		t.Errorf("expected %q but got %q", expected, autoload)
+	}
+
+	// No autoload script without s:on_first_use()
+	pi.onFirstUseFunc = ""
+	if autoload := mp.GenerateBundlePlugconfAutoload(); autoload != nil {
+		t.Errorf("expected nil but got %q", autoload)
+	}
+}
+
+func TestOnFirstUseProhibitedName(t *testing.T) {
+	const src = `function! ` + onFirstUseFunc + `()
+endfunction`
+	file, err := vimlparser.ParseFile(strings.NewReader(src), "test.vim", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, parseErr := ParsePlugconf(file, []byte(src), "test.vim")
+	if !parseErr.HasErrs() {
+		t.Errorf("expected error for %s()", onFirstUseFunc)
+	}
+}
+
+func TestParsePlugconfFileErrors(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-plugconf-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, tt := range []struct {
+		src      string
+		expected string
+	}{
+		// Syntax errors
+		{"function! s:loaded_on()\n  if 1\n  return 'start'\nendfunction\n", ":4:1: vimlparser: E171: Missing :endif"},
+		// Errors are reported with the line number of the function
+		{"\" comment\nfunction! s:loaded_on()\n  return 'bogus'\nendfunction\n", "* line 2: can't detect return value of s:loaded_on()"},
+	} {
+		path := filepath.Join(tempDir, "test.vim")
+		if err := ioutil.WriteFile(path, []byte(tt.src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, parseErr := ParsePlugconfFile(path, 1, pathutil.ReposPath("github.com/user/name"))
+		if !parseErr.HasErrs() {
+			t.Errorf("expected errors for %q", tt.src)
+			continue
+		}
+		msg := parseErr.Errors().Error()
+		if !strings.HasPrefix(msg, "parse errors in "+path) || !strings.Contains(msg, tt.expected) {
+			t.Errorf("expected %q in errors of %s but got %q", tt.expected, path, msg)
+		}
+	}
+
+	if _, parseErr := ParsePlugconfFile(filepath.Join(tempDir, "missing.vim"), 1, pathutil.ReposPath("github.com/user/name")); !parseErr.HasErrs() {
+		t.Error("expected an error for missing file")
+	}
+}
+
+func TestSortByDepends(t *testing.T) {
+	reposList := func(paths ...string) []lockjson.Repos {
+		list := make([]lockjson.Repos, 0, len(paths))
+		for _, p := range paths {
+			list = append(list, lockjson.Repos{Path: pathutil.ReposPath(p)})
+		}
+		return list
+	}
+	plugconfMap := func(deps map[string][]string) map[pathutil.ReposPath]*ParsedInfo {
+		m := make(map[pathutil.ReposPath]*ParsedInfo, len(deps))
+		for p, list := range deps {
+			var depends pathutil.ReposPathList
+			for _, d := range list {
+				depends = append(depends, pathutil.ReposPath(d))
+			}
+			m[pathutil.ReposPath(p)] = &ParsedInfo{reposPath: pathutil.ReposPath(p), depends: depends}
+		}
+		return m
+	}
+
+	for i, tt := range []struct {
+		repos    []string
+		deps     map[string][]string
+		expected string
+		cycle    string
+	}{
+		{
+			repos:    []string{"a", "b", "c", "d"},
+			deps:     nil,
+			expected: "a,b,c,d",
+		},
+		{
+			// a -> c -> d, b -> d
+			repos:    []string{"a", "b", "c", "d", "e"},
+			deps:     map[string][]string{"a": {"c"}, "c": {"d"}, "b": {"d", "notfound"}},
+			expected: "d,c,a,b,e",
+		},
+		{
+			repos: []string{"a", "b", "c", "d"},
+			deps:  map[string][]string{"b": {"c"}, "c": {"d"}, "d": {"b"}},
+			cycle: "dependency cycle in s:depends(): b -> c -> d -> b",
+		},
+		{
+			repos: []string{"a"},
+			deps:  map[string][]string{"a": {"a"}},
+			cycle: "dependency cycle in s:depends(): a -> a",
+		},
+	} {
+		list := reposList(tt.repos...)
+		err := sortByDepends(list, plugconfMap(tt.deps))
+		if tt.cycle != "" {
+			if err == nil || err.Error() != tt.cycle {
+				t.Errorf("[%d] expected error %q but got %v", i, tt.cycle, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err)
+			continue
+		}
+		var paths []string
+		for _, r := range list {
+			paths = append(paths, r.Path.String())
+		}
+		if strings.Join(paths, ",") != tt.expected {
+			t.Errorf("[%d] expected %s but got %s", i, tt.expected, strings.Join(paths, ","))
+		}
+	}
+}
+
+func TestLazyLoad(t *testing.T) {
+	const src = `function! s:loaded_on()
+  return ['filetype=go,rust', 'excmd=Foo', 'mapping=<Plug>(foo)', 'event=InsertEnter,CursorHold']
+endfunction
+
+function! s:depends()
+  return ['tyru/bar.vim']
+endfunction`
+
+	pi := parsePlugconfString(t, src)
+	expected := []loadTrigger{
+		{on: loadOnFileType, args: []string{"go", "rust"}},
+		{on: loadOnExcmd, args: []string{"Foo"}},
+		{on: loadOnMapping, args: []string{"<Plug>(foo)"}},
+		{on: loadOnEvent, args: []string{"InsertEnter", "CursorHold"}},
+	}
+	if !reflect.DeepEqual(pi.loadOn, expected) {
+		t.Fatalf("expected %v but got %v", expected, pi.loadOn)
+	}
+
+	// A lazy-loaded dependency is loaded before the plugin
+	dep := parsePlugconfString(t, "function! s:loaded_on()\n  return 'excmd=Bar'\nendfunction")
+	reposPath := pathutil.ReposPath("github.com/tyru/foo.vim")
+	depPath := pathutil.ReposPath("github.com/tyru/bar.vim")
+	pi.reposID, pi.reposPath = 2, reposPath
+	dep.reposID, dep.reposPath = 1, depPath
+	mp := &MultiParsedInfo{
+		plugconfMap: parsedInfoMap{reposPath: pi, depPath: dep},
+		reposList:   []lockjson.Repos{{Path: depPath}, {Path: reposPath}},
+	}
+	bundled, err := mp.GenerateBundlePlugconf("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"function " + lazyLoadFunc + "(id) abort",
+		"  let " + lazyPluginsVar + "[2] = {'cmd': 'packadd github.com_tyru_foo.vim', 'depends': [1], 'excmds': ['Foo'], 'mappings': ['<Plug>(foo)']}",
+		"  autocmd FileType go,rust call " + lazyLoadFileTypeFunc + "(2, expand('<amatch>'))",
+		"  autocmd InsertEnter,CursorHold * call " + lazyLoadFunc + "(2)",
+		"  nnoremap <silent> <Plug>(foo) :<C-u>call <SID>__volt_lazy_load_mapping(2, 'n', '<lt>Plug>(foo)')<CR>",
+		"  onoremap <silent> <Plug>(foo) :<C-u>call <SID>__volt_lazy_load_mapping(2, 'o', '<lt>Plug>(foo)')<CR>",
+		`"Foo":"call ` + lazyLoadFunc + `(2)"`,
+	} {
+		if !bytes.Contains(bundled, []byte(s)) {
+			t.Errorf("expected %q in bundled plugconf:\n%s", s, bundled)
+		}
+	}
+	if bytes.Contains(bundled, []byte("\n  packadd")) {
+		t.Errorf("lazy-loaded plugins must not be loaded at startup:\n%s", bundled)
+	}
+}
+
+func TestLoadedOnErrors(t *testing.T) {
+	for _, tt := range []struct {
+		src      string
+		expected string
+	}{
+		{"return ['start', 'filetype=go']", "'start' cannot be combined with other values"},
+		{"return ['start', 'event=Insert Enter']", "invalid event name 'Insert Enter'"},
+		{"return ['excmd=Foo', 'excmd=']", "empty excmd"},
+		{"return ['excmd=Foo', 'bogus=Foo']", "unknown type 'bogus'"},
+	} {
+		src := "function! s:loaded_on()\n  " + tt.src + "\nendfunction"
+		file, err := vimlparser.ParseFile(strings.NewReader(src), "test.vim", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, parseErr := ParsePlugconf(file, []byte(src), "test.vim")
+		if !parseErr.HasErrs() || !strings.Contains(parseErr.Errors().Error(), tt.expected) {
+			t.Errorf("%s: expected %q in errors but got %v", tt.src, tt.expected, parseErr.Errors())
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func init() {
+	cmdMap["alias"] = &aliasCmd{}
+}
+
+type aliasCmd struct {
+	helped bool
+}
+
+func (cmd *aliasCmd) ProhibitRootExecution(args []string) bool {
+	return len(args) > 0 && (args[0] == "set" || args[0] == "rm")
+}
+
+func (cmd *aliasCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  alias [-help] {command}
+
+Command
+  alias list
+    Show aliases of [alias] table in $VOLTPATH/config.toml .
+
+  alias set {name} {command} [{argument} ...]
+    Set alias {name}, which runs "volt {command} {argument} ...".
+    Arguments given to "volt {name}" are appended to them.
+
+  alias rm {name}
+    Remove alias {name}.
+
+  An alias cannot have the name of volt command (e.g. "get"), and {command}
+  must be a volt command, not an alias (aliases are not expanded
+  recursively).
+  "alias set" and "alias rm" keep the other lines of config.toml including
+  comments.
+
+Quick example
+  $ volt alias set up get -l -u   # "volt up" runs "volt get -l -u"
+  $ volt alias list
+  up = get -l -u
+  $ volt alias rm up` + "\n\n")
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *aliasCmd) Run(cmdctx *CmdContext) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(cmdctx.Args)
+	if cmd.helped {
+		return nil
+	}
+	if len(fs.Args()) == 0 {
+		fs.Usage()
+		logger.Error("must specify subcommand")
+		return nil
+	}
+
+	// config.toml is read again because cmdctx.Config is nil if an alias is
+	// invalid
+	cfg, problems, err := config.Check()
+	if err != nil {
+		return &Error{Code: 12, Msg: "Invalid config.toml: " + err.Error()}
+	}
+	for i := range problems {
+		if strings.HasPrefix(problems[i].Key, "alias.") {
+			logger.Warn(pathutil.ConfigTOML() + ": " + problems[i].String())
+		}
+	}
+
+	args := fs.Args()
+	switch args[0] {
+	case "list":
+		err = cmd.doList(cfg, args[1:])
+	case "set":
+		err = cmd.doSet(cfg, args[1:])
+	case "rm":
+		err = cmd.doRm(cfg, args[1:])
+	default:
+		return &Error{Code: 11, Msg: "Unknown subcommand: " + args[0]}
+	}
+	if err != nil {
+		return &Error{Code: 20, Msg: err.Error()}
+	}
+	return nil
+}
+
+func (cmd *aliasCmd) doList(cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		return errors.New("'volt alias list' receives no arguments")
+	}
+	names := make([]string, 0, len(cfg.Alias))
+	for name := range cfg.Alias {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println(name + " = " + quoteAliasArgs(cfg.Alias[name]))
+	}
+	return nil
+}
+
+func (cmd *aliasCmd) doSet(cfg *config.Config, args []string) error {
+	if len(args) < 2 {
+		return errors.New("'volt alias set' receives alias name and command")
+	}
+	name, aliasArgs := args[0], args[1:]
+	if err := config.ValidateAlias(cfg, name, aliasArgs); err != nil {
+		return err
+	}
+	if err := config.SetAlias(name, aliasArgs); err != nil {
+		return errors.Wrap(err, "could not set alias '"+name+"'")
+	}
+	logger.Infof("Set alias '%s' to '%s'", name, quoteAliasArgs(aliasArgs))
+	return nil
+}
+
+func (cmd *aliasCmd) doRm(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("'volt alias rm' receives alias name")
+	}
+	name := args[0]
+	if _, exists := cfg.Alias[name]; !exists {
+		return errors.New("alias '" + name + "' does not exist")
+	}
+	if err := config.RemoveAlias(name); err != nil {
+		return errors.Wrap(err, "could not remove alias '"+name+"'")
+	}
+	logger.Info("Removed alias '" + name + "'")
+	return nil
+}
+
+// quoteAliasArgs joins args with spaces. Arguments which have white spaces or
+// are empty are quoted.
+func quoteAliasArgs(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "" || strings.IndexFunc(arg, unicode.IsSpace) >= 0 || strings.ContainsAny(arg, `"'`) {
+			arg = strconv.Quote(arg)
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -5,7 +5,12 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
 	"github.com/vim-volt/volt/subcmd/builder"
+	"github.com/vim-volt/volt/subcmd/buildinfo"
 	"github.com/vim-volt/volt/transaction"
 )
 
@@ -14,8 +19,26 @@ func init() {
 }
 
 type buildCmd struct {
-	helped bool
-	full   bool
+	helped  bool
+	full    bool
+	archive string
+	target  string
+	watch   bool
+}
+
+// buildResult is the result of "volt -output json build".
+type buildResult struct {
+	Dir      string             `json:"dir"`
+	Strategy string             `json:"strategy"`
+	Archive  string             `json:"archive,omitempty"`
+	Repos    []buildResultRepos `json:"repos"`
+}
+
+// buildResultRepos is an installed repository of buildResult.
+type buildResultRepos struct {
+	Path    pathutil.ReposPath `json:"path"`
+	Type    lockjson.ReposType `json:"type"`
+	Version string             `json:"version"`
 }
 
 func (cmd *buildCmd) ProhibitRootExecution(args []string) bool { return true }
@@ -26,11 +49,14 @@ func (cmd *buildCmd) FlagSet() *flag.FlagSet {
 	fs.Usage = func() {
 		fmt.Print(`
 Usage
-  volt build [-help] [-full]
+  volt build [-help] [-full] [-archive {file}] [-target {vim|neovim}] [-watch]
 
 Quick example
   $ volt build        # builds directories under ~/.vim/pack/volt
   $ volt build -full  # full build (remove ~/.vim/pack/volt, and re-create all)
+  $ volt build -archive vim.tar.gz  # builds, and exports the result to vim.tar.gz
+  $ volt build -target neovim       # builds directories under ~/.local/share/nvim/site/pack/volt
+  $ volt build -watch               # builds again whenever repositories, plugconf, or rc files are changed
 
 Description
   Build ~/.vim/pack/volt/opt/ directory:
@@ -42,41 +68,177 @@ Description
   ~/.vim/pack/volt/build-info.json is a file which holds the information that what vim plugins are installed in ~/.vim/pack/volt/ and its type (git repository, static repository, or system repository), its version. A user normally doesn't need to know the contents of build-info.json .
 
   If -full option was given, remove all directories in ~/.vim/pack/volt/opt/ , and copy repositories' files into above vim directories.
-  Otherwise, it will perform smart build: copy / remove only changed repositories' files.` + "\n\n")
+  Otherwise, it will perform smart build: copy / remove only changed repositories' files.
+  If build.strategy is "copy", only added, changed, and removed files of each
+  changed repository are updated.
+  If build.strategy is "symlink", "volt build" always performs full build,
+  but "volt enable", "volt disable", and "volt profile add/rm/no-build/build"
+  install / remove only the added or removed repositories.
+  If a full build fails or is interrupted, the previous ~/.vim/pack/volt is
+  restored.
+
+  If two or more enabled plugins have the same runtime file (e.g.
+  autoload/foo.vim, ftplugin/python.vim, or ftdetect/foo.vim), "volt build"
+  warns the conflict and which plugin is found first in 'runtimepath' (the
+  plugin loaded last). The order can be different if a plugin is loaded
+  lazily by plugconf.
+
+  If build.target of config.toml is "neovim" (or -target neovim was given),
+  plugins are installed to stdpath('data')/site/pack/volt of Neovim instead
+  (~/.local/share/nvim/site/pack/volt), and vimrc.vim, gvimrc.vim, and
+  init.lua of the profile are installed as init.vim, ginit.vim, and init.lua
+  in stdpath('config') (~/.config/nvim). ":helptags" is executed by nvim if
+  it is found. -target is useful to build for both Vim and Neovim.
+
+  If neither vim nor nvim executable is found, the help tags files are
+  generated by volt itself, so ~/.vim/pack/volt can be built on machines
+  without Vim (e.g. CI or a container image).
+
+  If -watch option was given, "volt build" watches $VOLTPATH/repos,
+  $VOLTPATH/plugconf, and $VOLTPATH/rc after building, and performs smart
+  build again whenever files are changed (e.g. while developing a plugin or
+  editing plugconf), until interrupted by Ctrl-C.
+
+  If -dry-run global option was given ("volt -dry-run build"), the
+  repositories which would be installed or uninstalled are shown, and no
+  files are changed.
+
+  If -archive option was given, the built environment (~/.vim/pack/volt/,
+  ~/.vim/vimrc and ~/.vim/gvimrc) is also written to {file}. The format is
+  determined by the extension: .zip, .tar.gz, .tgz, or .tar.
+  The archive does not depend on volt, git, nor $VOLTPATH. Extract it into
+  ~/.vim (or ~/vimfiles on Windows) of another host to use the same plugins:
+
+    $ mkdir -p ~/.vim && tar xzf vim.tar.gz -C ~/.vim` + "\n\n")
 		fmt.Println("Options")
 		fs.PrintDefaults()
 		fmt.Println()
 		cmd.helped = true
 	}
 	fs.BoolVar(&cmd.full, "full", false, "full build")
+	fs.StringVar(&cmd.archive, "archive", "", "write the built environment to the archive file")
+	fs.BoolVar(&cmd.watch, "watch", false, "build again whenever files are changed until interrupted")
+	fs.StringVar(&cmd.target, "target", "", "build for \"vim\" or \"neovim\" instead of build.target of config.toml")
 	return fs
 }
 
-func (cmd *buildCmd) Run(args []string) (result *Error) {
+func (cmd *buildCmd) Run(cmdctx *CmdContext) (result *Error) {
 	// Parse args
 	fs := cmd.FlagSet()
-	fs.Parse(args)
+	fs.Parse(cmdctx.Args)
 	if cmd.helped {
 		return nil
 	}
+	if cmd.archive != "" {
+		if err := builder.ValidateArchivePath(cmd.archive); err != nil {
+			return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
+		}
+	}
+	if cmd.watch && cmd.archive != "" {
+		return &Error{Code: 10, Msg: "Failed to parse args: -watch and -archive cannot be used together"}
+	}
+	switch cmd.target {
+	case "":
+	case config.VimTarget, config.NeovimTarget:
+		pathutil.SetNeovimTarget(cmd.target == config.NeovimTarget)
+	default:
+		return &Error{Code: 10, Msg: fmt.Sprintf("Failed to parse args: -target must be %q or %q", config.VimTarget, config.NeovimTarget)}
+	}
+
+	if cmdctx.DryRun {
+		return cmd.dryRun(cmdctx)
+	}
+
+	// Show warnings of parallel tasks again after building
+	logger.CollectWarnings()
+	defer logger.PrintWarningSummary()
+
+	if result = cmd.build(cmdctx); result != nil || !cmd.watch {
+		return
+	}
+	if err := cmd.watchBuild(cmdctx.Ctx); err != nil {
+		result = &Error{Code: 15, Msg: "Failed to watch files: " + err.Error()}
+	}
+	return
+}
+
+// dryRun shows what cmd.build() would change ("volt -dry-run build").
+func (cmd *buildCmd) dryRun(cmdctx *CmdContext) *Error {
+	if cmd.watch {
+		return &Error{Code: 10, Msg: "Failed to parse args: -watch cannot be used with -dry-run"}
+	}
+	if err := dryRunBuild(cmdctx.LockJSON, cmd.full, false); err != nil {
+		return &Error{Code: 12, Msg: "Failed to build: " + err.Error()}
+	}
+	if cmd.archive != "" {
+		logDryRun("Would write %s", cmd.archive)
+	}
+	return nil
+}
 
+func (cmd *buildCmd) build(cmdctx *CmdContext) (result *Error) {
 	// Begin transaction
 	trx, err := transaction.Start()
 	if err != nil {
 		result = &Error{Code: 11, Msg: "Failed to begin transaction: " + err.Error()}
 		return
 	}
+	built := false
 	defer func() {
+		// Restore ~/.vim/pack/volt if failed to build or interrupted
+		if !built {
+			if err := trx.Rollback(); err != nil {
+				logger.Error("Failed to rollback: " + err.Error())
+			}
+			return
+		}
 		if err := trx.Done(); err != nil {
 			result = &Error{Code: 13, Msg: "Failed to end transaction: " + err.Error()}
 		}
 	}()
 
-	err = builder.Build(cmd.full)
+	err = builder.Build(cmdctx.Ctx, cmd.full)
 	if err != nil {
 		result = &Error{Code: 12, Msg: "Failed to build: " + err.Error()}
 		return
 	}
+	built = true
 
+	if cmd.archive != "" {
+		logger.Info("Writing " + cmd.archive + " ...")
+		err = builder.Archive(cmdctx.Ctx, cmd.archive)
+		if err != nil {
+			result = &Error{Code: 14, Msg: "Failed to write archive: " + err.Error()}
+			return
+		}
+	}
+
+	if summary, err := cmd.summary(); err != nil {
+		logger.Debug("Could not read build-info.json: " + err.Error())
+	} else {
+		cmdctx.Result = summary
+	}
 	return
 }
+
+// summary returns the repositories installed by the last build.
+func (cmd *buildCmd) summary() (*buildResult, error) {
+	buildInfo, err := buildinfo.Read()
+	if err != nil {
+		return nil, err
+	}
+	summary := &buildResult{
+		Dir:      pathutil.VimVoltDir(),
+		Strategy: buildInfo.Strategy,
+		Archive:  cmd.archive,
+		Repos:    make([]buildResultRepos, 0, len(buildInfo.Repos)),
+	}
+	for i := range buildInfo.Repos {
+		summary.Repos = append(summary.Repos, buildResultRepos{
+			Path:    buildInfo.Repos[i].Path,
+			Type:    buildInfo.Repos[i].Type,
+			Version: buildInfo.Repos[i].Version,
+		})
+	}
+	return summary, nil
+}
@@ -0,0 +1,181 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/builder"
+	"github.com/vim-volt/volt/transaction"
+)
+
+// watchDelay is the time to wait for subsequent changes (e.g. "git checkout",
+// or saving several files) before building.
+var watchDelay = 300 * time.Millisecond
+
+// watchBuild re-runs the incremental build whenever files under
+// $VOLTPATH/repos, $VOLTPATH/plugconf, and $VOLTPATH/rc are changed, until
+// ctx is done.
+func (cmd *buildCmd) watchBuild(ctx context.Context) error {
+	dirs := []string{
+		pathutil.ReposDir(),
+		pathutil.PlugconfDir(),
+		filepath.Join(pathutil.VoltConfigDir(), "rc"),
+	}
+	w, err := newDirWatcher(dirs)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	logger.Info("Watching " + strings.Join(dirs, ", ") + " for changes (Ctrl-C to stop) ...")
+	for {
+		changed, err := w.wait(ctx, watchDelay)
+		if err != nil {
+			return err
+		}
+		if changed == nil { // ctx is done
+			return nil
+		}
+		msg := "Detected changes in " + changed[0]
+		if len(changed) > 1 {
+			msg += fmt.Sprintf(" and %d more", len(changed)-1)
+		}
+		logger.Info(msg + ", building ...")
+		if err := rebuild(ctx); err != nil {
+			logger.Error("Failed to build: " + err.Error())
+		}
+	}
+}
+
+// rebuild runs the incremental build in a transaction.
+func rebuild(ctx context.Context) (result error) {
+	trx, err := transaction.Start()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer func() {
+		// Restore ~/.vim/pack/volt if failed to build or interrupted
+		if result != nil || ctx.Err() != nil {
+			if err := trx.Rollback(); err != nil {
+				logger.Error("Failed to rollback: " + err.Error())
+			}
+			return
+		}
+		if err := trx.Done(); err != nil {
+			result = errors.Wrap(err, "failed to end transaction")
+		}
+	}()
+	return builder.Build(ctx, false)
+}
+
+// dirWatcher watches the files of directories recursively.
+type dirWatcher struct {
+	*fsnotify.Watcher
+}
+
+// newDirWatcher watches dirs and their subdirectories. The directories which
+// do not exist are ignored.
+func newDirWatcher(dirs []string) (*dirWatcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to watch files")
+	}
+	w := &dirWatcher{fw}
+	for _, dir := range dirs {
+		if !pathutil.Exists(dir) {
+			continue
+		}
+		if err := w.addRecursive(dir); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// addRecursive watches dir and its subdirectories except ".git".
+func (w *dirWatcher) addRecursive(dir string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return nil
+		}
+		if fi.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if err := w.Add(path); err != nil {
+			return errors.Wrap(err, "failed to watch "+path)
+		}
+		return nil
+	})
+}
+
+// wait waits until files are changed, and returns the changed paths after
+// no more changes were made for delay. nil is returned if ctx is done.
+func (w *dirWatcher) wait(ctx context.Context, delay time.Duration) ([]string, error) {
+	var changed []string
+	seen := make(map[string]bool)
+	var timer <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-timer:
+			return changed, nil
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil, errors.New("watcher was closed")
+			}
+			return nil, errors.Wrap(err, "failed to watch files")
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil, errors.New("watcher was closed")
+			}
+			if ev.Op == fsnotify.Chmod || isIgnoredWatchPath(ev.Name) {
+				continue
+			}
+			// Watch created directories (e.g. a new repository)
+			if ev.Op&fsnotify.Create != 0 {
+				if fi, err := os.Lstat(ev.Name); err == nil && fi.IsDir() {
+					if err := w.addRecursive(ev.Name); err != nil {
+						logger.Warn(err.Error())
+					}
+				}
+			}
+			if !seen[ev.Name] {
+				seen[ev.Name] = true
+				changed = append(changed, ev.Name)
+			}
+			timer = time.After(delay)
+		}
+	}
+}
+
+// isIgnoredWatchPath returns true if the change of path does not need
+// building: files under ".git", temporary files of editors, and doc/tags
+// which ":helptags" writes (to the repository itself if build.strategy is
+// "symlink").
+func isIgnoredWatchPath(path string) bool {
+	slashed := filepath.ToSlash(path)
+	if strings.Contains(slashed, "/.git/") || strings.HasSuffix(slashed, "/.git") {
+		return true
+	}
+	name := filepath.Base(path)
+	if strings.HasSuffix(slashed, "/doc/tags") || name == "4913" ||
+		strings.HasSuffix(name, "~") || strings.HasSuffix(name, ".swp") ||
+		strings.HasSuffix(name, ".swx") || strings.HasPrefix(name, ".#") {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package subcmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIsIgnoredWatchPath(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"/volt/repos/github.com/tyru/caw.vim/plugin/caw.vim":        false,
+		"/volt/repos/github.com/tyru/caw.vim/doc/caw.txt":           false,
+		"/volt/plugconf/github.com/tyru/caw.vim.vim":                false,
+		"/volt/repos/github.com/tyru/caw.vim/doc/tags":              true,
+		"/volt/repos/github.com/tyru/caw.vim/.git":                  true,
+		"/volt/repos/github.com/tyru/caw.vim/.git/index":            true,
+		"/volt/plugconf/github.com/tyru/.caw.vim.vim.swp":           true,
+		"/volt/plugconf/github.com/tyru/caw.vim.vim~":               true,
+		"/volt/plugconf/github.com/tyru/4913":                       true,
+		"/volt/repos/github.com/tyru/caw.vim/autoload/.#caw.vim":    true,
+		"/volt/repos/github.com/tyru/caw.vim/autoload/caw/tags.vim": false,
+	} {
+		if got := isIgnoredWatchPath(filepath.FromSlash(path)); got != expected {
+			t.Errorf("%s: expected %v but got %v", path, expected, got)
+		}
+	}
+}
+
+func TestDirWatcher(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-watch-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	w, err := newDirWatcher([]string{tempDir, filepath.Join(tempDir, "missing")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// Files in a created directory are also watched
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		dir := filepath.Join(tempDir, "plugin")
+		os.Mkdir(dir, 0755)
+		time.Sleep(50 * time.Millisecond)
+		ioutil.WriteFile(filepath.Join(dir, "foo.vim"), []byte("foo"), 0644)
+	}()
+	changed, err := w.wait(ctx, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join(tempDir, "plugin"), filepath.Join(tempDir, "plugin", "foo.vim")}
+	if len(changed) != len(expected) || changed[0] != expected[0] || changed[1] != expected[1] {
+		t.Errorf("expected %v but got %v", expected, changed)
+	}
+
+	// nil is returned when ctx is done
+	cancel()
+	if changed, err := w.wait(ctx, 200*time.Millisecond); changed != nil || err != nil {
+		t.Errorf("expected (nil, nil) but got (%v, %v)", changed, err)
+	}
+}
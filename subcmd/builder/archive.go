@@ -0,0 +1,296 @@
+package builder
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+)
+
+// ValidateArchivePath returns non-nil error if the archive format cannot be
+// determined by the extension of path.
+func ValidateArchivePath(path string) error {
+	_, err := newArchiveWriter(path, ioutil.Discard)
+	return err
+}
+
+// Archive writes the built environment of (vim dir) to an archive file dst.
+// The archive has "pack/volt/" directory, and vimrc and gvimrc if they were
+// installed. It can be extracted into (vim dir) of a host without volt and
+// git. If volt installs plugins for Neovim, (nvim dir) is written instead,
+// and init.vim and ginit.vim are not written.
+// The format is determined by the extension of dst (".zip", ".tar.gz",
+// ".tgz", or ".tar").
+// Build() must be invoked before this function.
+func Archive(ctx context.Context, dst string) error {
+	cfg, err := config.Read()
+	if err != nil {
+		return errors.Wrap(err, "could not read config.toml")
+	}
+	builder := &BaseBuilder{runtimeProfile: *cfg.Build.RuntimeProfile}
+
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return errors.Wrap(err, "could not read lock.json")
+	}
+	reposList, err := builder.reposListToInstall(lockJSON)
+	if err != nil {
+		return err
+	}
+
+	// Re-generate bundled plugconf without $MYVIMRC and $MYGVIMRC, because
+	// they are the paths of this host
+	plugconfs, parseErr := plugconf.ParseMultiPlugconf(reposList)
+	if parseErr.HasErrs() {
+		return parseErr.Errors()
+	}
+	bundled, err := builder.generateBundledPlugconf(lockJSON, plugconfs, "", "")
+	if err != nil {
+		return err
+	}
+
+	vimDir := pathutil.VimDir()
+	if pathutil.IsNeovimTarget() {
+		vimDir = pathutil.NvimDir()
+	}
+	relPath := func(path string) string {
+		rel, _ := filepath.Rel(vimDir, path)
+		return filepath.ToSlash(rel)
+	}
+	replaced := map[string][]byte{
+		relPath(pathutil.BundledPlugConf()): bundled,
+	}
+	skipped := map[string]bool{
+		relPath(pathutil.BuildInfoJSON()): true,
+	}
+	return writeArchive(ctx, dst, vimDir, replaced, skipped)
+}
+
+// writeArchive writes the files of vimDir to dst.
+// The content of the file of replaced key (a slash-separated path relative to
+// vimDir) is replaced with the value, and skipped files are not written.
+// Symlinks are dereferenced, and ".git" files and directories are ignored.
+// dst is not created if this function failed.
+func writeArchive(ctx context.Context, dst, vimDir string, replaced map[string][]byte, skipped map[string]bool) (result error) {
+	if err := ValidateArchivePath(dst); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(dst), "."+filepath.Base(dst)+"-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if result != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	w, err := newArchiveWriter(dst, f)
+	if err != nil {
+		return err
+	}
+	a := &archiver{
+		w:        w,
+		replaced: replaced,
+		skipped:  skipped,
+		visited:  make(map[string]bool),
+	}
+	for _, name := range []string{pathutil.Vimrc, pathutil.Gvimrc} {
+		src := filepath.Join(vimDir, name)
+		if fi, err := os.Stat(src); err == nil && fi.Mode().IsRegular() {
+			if err := a.addFile(src, name, fi); err != nil {
+				return err
+			}
+		}
+	}
+	if err := a.addDirs(ctx, filepath.Join(vimDir, "pack", "volt"), "pack/volt"); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), dst)
+}
+
+type archiver struct {
+	w        archiveWriter
+	replaced map[string][]byte
+	skipped  map[string]bool
+	// visited holds the resolved paths of added directories to prevent
+	// infinite loop by symlinks
+	visited map[string]bool
+}
+
+// addDirs adds dir as name, and its files recursively.
+func (a *archiver) addDirs(ctx context.Context, dir, name string) error {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	if a.visited[realDir] {
+		return nil
+	}
+	a.visited[realDir] = true
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if err := a.w.writeDir(name, fi.Mode(), fi.ModTime()); err != nil {
+		return err
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range infos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if fi.Name() == ".git" {
+			continue
+		}
+		src := filepath.Join(dir, fi.Name())
+		entry := path.Join(name, fi.Name())
+		if a.skipped[entry] {
+			continue
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if fi, err = os.Stat(src); err != nil {
+				// Ignore broken symlink
+				continue
+			}
+		}
+		switch {
+		case fi.IsDir():
+			err = a.addDirs(ctx, src, entry)
+		case fi.Mode().IsRegular():
+			err = a.addFile(src, entry, fi)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *archiver) addFile(src, name string, fi os.FileInfo) error {
+	content, ok := a.replaced[name]
+	if !ok {
+		var err error
+		content, err = ioutil.ReadFile(src)
+		if err != nil {
+			return err
+		}
+	}
+	return a.w.writeFile(name, fi.Mode(), fi.ModTime(), content)
+}
+
+// archiveWriter writes entries of an archive.
+// name is a slash-separated path.
+type archiveWriter interface {
+	writeDir(name string, mode os.FileMode, modTime time.Time) error
+	writeFile(name string, mode os.FileMode, modTime time.Time, content []byte) error
+	Close() error
+}
+
+func newArchiveWriter(path string, w io.Writer) (archiveWriter, error) {
+	switch {
+	case strings.HasSuffix(path, ".zip"):
+		return &zipWriter{zip.NewWriter(w)}, nil
+	case strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		gw := gzip.NewWriter(w)
+		return &tarWriter{tar.NewWriter(gw), gw}, nil
+	case strings.HasSuffix(path, ".tar"):
+		return &tarWriter{tar.NewWriter(w), nil}, nil
+	default:
+		return nil, errors.New("unknown archive format (must be .zip, .tar.gz, .tgz, or .tar): " + path)
+	}
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (w *zipWriter) writeDir(name string, mode os.FileMode, modTime time.Time) error {
+	hdr := &zip.FileHeader{Name: name + "/"}
+	hdr.SetMode(mode.Perm() | os.ModeDir)
+	hdr.SetModTime(modTime)
+	_, err := w.zw.CreateHeader(hdr)
+	return err
+}
+
+func (w *zipWriter) writeFile(name string, mode os.FileMode, modTime time.Time, content []byte) error {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	hdr.SetMode(mode.Perm())
+	hdr.SetModTime(modTime)
+	fw, err := w.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(content)
+	return err
+}
+
+func (w *zipWriter) Close() error {
+	return w.zw.Close()
+}
+
+type tarWriter struct {
+	tw *tar.Writer
+	gw *gzip.Writer
+}
+
+func (w *tarWriter) writeDir(name string, mode os.FileMode, modTime time.Time) error {
+	return w.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     int64(mode.Perm()),
+		ModTime:  modTime,
+	})
+}
+
+func (w *tarWriter) writeFile(name string, mode os.FileMode, modTime time.Time, content []byte) error {
+	err := w.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(mode.Perm()),
+		ModTime:  modTime,
+		Size:     int64(len(content)),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.tw.Write(content)
+	return err
+}
+
+func (w *tarWriter) Close() error {
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	if w.gw != nil {
+		return w.gw.Close()
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package builder
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteArchive(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-archive-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// (vim dir)/pack/volt/opt/foo is a symlink to the repository which has
+	// .git directory
+	repos := filepath.Join(tempDir, "repos", "foo")
+	vimDir := filepath.Join(tempDir, "vim")
+	files := map[string]string{
+		filepath.Join(repos, "plugin", "foo.vim"):                                        "foo",
+		filepath.Join(repos, ".git", "HEAD"):                                             "ref: refs/heads/master",
+		filepath.Join(vimDir, "vimrc"):                                                   "vimrc",
+		filepath.Join(vimDir, "pack", "volt", "build-info.json"):                         "{}",
+		filepath.Join(vimDir, "pack", "volt", "start", "system", "plugin", "bundle.vim"): "old",
+		filepath.Join(vimDir, "other", "other.vim"):                                      "other",
+	}
+	for path, content := range files {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.MkdirAll(filepath.Join(vimDir, "pack", "volt", "opt"), 0755)
+	if err := os.Symlink(repos, filepath.Join(vimDir, "pack", "volt", "opt", "foo")); err != nil {
+		t.Skip("could not create symlink: " + err.Error())
+	}
+	// Symlink loop
+	if err := os.Symlink(repos, filepath.Join(repos, "plugin", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"vimrc":                                    "vimrc",
+		"pack/volt/":                               "",
+		"pack/volt/opt/":                           "",
+		"pack/volt/opt/foo/":                       "",
+		"pack/volt/opt/foo/plugin/":                "",
+		"pack/volt/opt/foo/plugin/foo.vim":         "foo",
+		"pack/volt/start/":                         "",
+		"pack/volt/start/system/":                  "",
+		"pack/volt/start/system/plugin/":           "",
+		"pack/volt/start/system/plugin/bundle.vim": "new",
+	}
+	replaced := map[string][]byte{"pack/volt/start/system/plugin/bundle.vim": []byte("new")}
+	skipped := map[string]bool{"pack/volt/build-info.json": true}
+
+	for _, ext := range []string{".zip", ".tar.gz", ".tar"} {
+		dst := filepath.Join(tempDir, "vim"+ext)
+		if err := writeArchive(context.Background(), dst, vimDir, replaced, skipped); err != nil {
+			t.Fatalf("%s: %s", ext, err)
+		}
+		var entries map[string]string
+		if ext == ".zip" {
+			entries = readZip(t, dst)
+		} else {
+			entries = readTar(t, dst, ext == ".tar.gz")
+		}
+		if !reflect.DeepEqual(entries, expected) {
+			t.Errorf("%s: expected %v but got %v", ext, expected, entries)
+		}
+	}
+
+	dst := filepath.Join(tempDir, "vim.rar")
+	if err := writeArchive(context.Background(), dst, vimDir, nil, nil); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("%s must not be created", dst)
+	}
+}
+
+func readZip(t *testing.T, path string) map[string]string {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	entries := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[f.Name] = string(content)
+	}
+	return entries
+}
+
+func readTar(t *testing.T, path string, gzipped bool) map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if gzipped {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+	entries := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasSuffix(hdr.Name, "/") != (hdr.Typeflag == tar.TypeDir) {
+			t.Errorf("invalid entry: %s", hdr.Name)
+		}
+		entries[hdr.Name] = string(content)
+	}
+	return entries
+}
@@ -1,12 +1,16 @@
 package builder
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -15,63 +19,229 @@ import (
 	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
 	"github.com/vim-volt/volt/subcmd/buildinfo"
 )
 
 // BaseBuilder is a base struct which all builders must implement
-type BaseBuilder struct{}
+type BaseBuilder struct {
+	// runtimeProfile is build.runtime_profile of config.toml
+	runtimeProfile bool
+	// concatPluginScripts is build.concat_plugin_scripts of config.toml
+	concatPluginScripts bool
+	// vimExecutableOf returns build.vim_executable of config.toml for the
+	// profile
+	vimExecutableOf func(profileName string) string
+}
 
-func (builder *BaseBuilder) installVimrcAndGvimrc(profileName, vimrcPath, gvimrcPath string) error {
-	// Save old vimrc file as {vimrc}.bak
-	vimrcInfo, err := os.Stat(vimrcPath)
-	if err != nil && !os.IsNotExist(err) {
-		return err
+// vimExecutable returns the path of vim executable to execute ":helptags".
+// The executable of current profile is used even if build.runtime_profile is
+// true. If volt installs plugins for Neovim, nvim is preferred.
+// "" is returned if no vim executable was found, then tags files are
+// generated without vim.
+func (builder *BaseBuilder) vimExecutable(lockJSON *lockjson.LockJSON) string {
+	configured := ""
+	if builder.vimExecutableOf != nil {
+		configured = builder.vimExecutableOf(lockJSON.CurrentProfileName)
 	}
-	vimrcExists := !os.IsNotExist(err)
-	if vimrcExists {
-		err = fileutil.CopyFile(vimrcPath, vimrcPath+".bak", make([]byte, vimrcInfo.Size()), vimrcInfo.Mode())
-		if err != nil {
-			return err
+	var vim string
+	var err error
+	if pathutil.IsNeovimTarget() {
+		vim, err = pathutil.NvimExecutable(configured)
+	} else {
+		vim, err = pathutil.VimExecutable(configured)
+	}
+	if err != nil {
+		if configured != "" {
+			logger.Warnf("Generating tags files without vim: %s: %s", configured, err)
+		} else {
+			logger.Debugf("Generating tags files without vim: %s", err)
 		}
+		return ""
+	}
+	return vim
+}
+
+// reposListToInstall returns the repositories to install.
+// If build.runtime_profile is true, they are the repositories of all
+// profiles. Otherwise they are the repositories of current profile.
+// The repositories marked as no_build by the profiles are not installed.
+func (builder *BaseBuilder) reposListToInstall(lockJSON *lockjson.LockJSON) (lockjson.ReposList, error) {
+	if builder.runtimeProfile {
+		return lockJSON.GetAllProfilesBuildReposList(), nil
 	}
-	defer os.Remove(vimrcPath + ".bak")
+	return lockJSON.GetCurrentBuildReposList()
+}
 
-	// Install vimrc
-	err = builder.installRCFile(
-		profileName,
-		pathutil.ProfileVimrc,
-		vimrcPath,
-	)
+// writeBundledPlugconf writes the bundled plugconf file of reposList.
+// If build.runtime_profile is true, the plugins to load are selected by
+// g:volt_profile at Vim startup.
+func (builder *BaseBuilder) writeBundledPlugconf(lockJSON *lockjson.LockJSON, reposList lockjson.ReposList) error {
+	profileNames := lockJSON.CurrentProfileNames()
+	vimrc := ""
+	if path := rcFileOf(profileNames, pathutil.ProfileVimrc); pathutil.Exists(path) {
+		vimrc = path
+	}
+	gvimrc := ""
+	if path := rcFileOf(profileNames, pathutil.ProfileGvimrc); pathutil.Exists(path) {
+		gvimrc = path
+	}
+	plugconfs, parseErr := plugconf.ParseMultiPlugconf(reposList)
+	if parseErr.HasErrs() {
+		// Vim script parse errors / other errors
+		return parseErr.Errors()
+	}
+	if parseErr.HasWarns() {
+		// Vim script parse warnings
+		merr := parseErr.Warns()
+		for _, err := range merr.Errors {
+			logger.Warn(err)
+		}
+	}
+	// reposList was sorted in load order by ParseMultiPlugconf()
+	builder.warnRuntimeConflicts(reposList)
+
+	content, err := builder.generateBundledPlugconf(lockJSON, plugconfs, vimrc, gvimrc)
 	if err != nil {
 		return err
 	}
+	os.MkdirAll(filepath.Dir(pathutil.BundledPlugConf()), 0755)
+	if err := ioutil.WriteFile(pathutil.BundledPlugConf(), content, 0644); err != nil {
+		return err
+	}
+
+	// s:on_first_use() functions are compiled into an autoload script
+	autoloadPath := pathutil.BundledPlugConfAutoload()
+	content = plugconfs.GenerateBundlePlugconfAutoload()
+	if content == nil {
+		if err := os.Remove(autoloadPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	os.MkdirAll(filepath.Dir(autoloadPath), 0755)
+	return ioutil.WriteFile(autoloadPath, content, 0644)
+}
 
-	// Install gvimrc
-	err = builder.installRCFile(
-		profileName,
-		pathutil.ProfileGvimrc,
-		gvimrcPath,
-	)
+// warnRuntimeConflicts shows the runtime files which are provided by two or
+// more repositories of reposList (in load order).
+func (*BaseBuilder) warnRuntimeConflicts(reposList lockjson.ReposList) {
+	pathList := make([]pathutil.ReposPath, 0, len(reposList))
+	for i := range reposList {
+		pathList = append(pathList, reposList[i].Path)
+	}
+	conflicts, err := findRuntimeConflicts(pathList)
 	if err != nil {
-		// Restore old vimrc
-		if vimrcExists {
-			err2 := os.Rename(vimrcPath+".bak", vimrcPath)
-			if err2 != nil {
-				return multierror.Append(err, err2)
+		logger.Warn("Could not check conflicts of runtime files: " + err.Error())
+		return
+	}
+	for i := range conflicts {
+		logger.Warn(formatRuntimeConflict(&conflicts[i]))
+	}
+}
+
+// generateBundledPlugconf generates the content of bundled plugconf file.
+// vimrc and gvimrc are the paths set to $MYVIMRC and $MYGVIMRC, and they are
+// not set if empty.
+func (builder *BaseBuilder) generateBundledPlugconf(lockJSON *lockjson.LockJSON, plugconfs *plugconf.MultiParsedInfo, vimrc, gvimrc string) ([]byte, error) {
+	if builder.runtimeProfile {
+		return plugconfs.GenerateRuntimeProfileBundlePlugconf(lockJSON.Profiles, lockJSON.CurrentProfileNames(), vimrc, gvimrc)
+	}
+	return plugconfs.GenerateBundlePlugconf(vimrc, gvimrc)
+}
+
+// rcFileOf returns "$VOLTPATH/rc/{profile}/{rcFileName}" of the first profile
+// in profileNames which has the file.
+// If no profiles have it, the path of the first profile is returned.
+func rcFileOf(profileNames []string, rcFileName string) string {
+	for _, name := range profileNames {
+		path := filepath.Join(pathutil.RCDir(name), rcFileName)
+		if pathutil.Exists(path) {
+			return path
+		}
+	}
+	return filepath.Join(pathutil.RCDir(profileNames[0]), rcFileName)
+}
+
+// rcFilesToInstall returns the pairs of the basename of profile rc file and
+// the path where it is installed:
+//
+//	Vim: vimrc.vim -> (vim dir)/vimrc, gvimrc.vim -> (vim dir)/gvimrc
+//	Neovim: vimrc.vim -> (nvim config dir)/init.vim,
+//	        init.lua -> (nvim config dir)/init.lua,
+//	        gvimrc.vim -> (nvim config dir)/ginit.vim
+func rcFilesToInstall() [][2]string {
+	if pathutil.IsNeovimTarget() {
+		nvimConfigDir := pathutil.NvimConfigDir()
+		return [][2]string{
+			{pathutil.ProfileVimrc, filepath.Join(nvimConfigDir, pathutil.NvimInitVim)},
+			{pathutil.ProfileInitLua, filepath.Join(nvimConfigDir, pathutil.NvimInitLua)},
+			{pathutil.ProfileGvimrc, filepath.Join(nvimConfigDir, pathutil.NvimGinitVim)},
+		}
+	}
+	vimDir := pathutil.VimDir()
+	return [][2]string{
+		{pathutil.ProfileVimrc, filepath.Join(vimDir, pathutil.Vimrc)},
+		{pathutil.ProfileGvimrc, filepath.Join(vimDir, pathutil.Gvimrc)},
+	}
+}
+
+// installRCFiles installs vimrc and gvimrc (and init.lua for Neovim) of
+// current profiles. Each file is installed from the first profile in
+// profileNames which has it. If it failed to install a file, the installed
+// files are restored.
+func (builder *BaseBuilder) installRCFiles(profileNames []string) error {
+	// Neovim does not allow both init.vim and init.lua
+	if pathutil.IsNeovimTarget() {
+		vimrc := rcFileOf(profileNames, pathutil.ProfileVimrc)
+		initLua := rcFileOf(profileNames, pathutil.ProfileInitLua)
+		if pathutil.Exists(vimrc) && pathutil.Exists(initLua) {
+			return errors.Errorf("both '%s' and '%s' exist: Neovim cannot load both init.vim and init.lua", vimrc, initLua)
+		}
+	}
+
+	rcFiles := rcFilesToInstall()
+	restore := func(installed [][2]string) error {
+		var merr *multierror.Error
+		for _, rc := range installed {
+			dst := rc[1]
+			if pathutil.Exists(dst + ".bak") {
+				if err := os.Rename(dst+".bak", dst); err != nil {
+					merr = multierror.Append(merr, err)
+				}
+			} else if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+				merr = multierror.Append(merr, err)
 			}
-		} else {
-			err2 := os.Remove(vimrcPath)
-			if err2 != nil {
+		}
+		return merr.ErrorOrNil()
+	}
+	for i, rc := range rcFiles {
+		// Save old rc file as {dst}.bak
+		dst := rc[1]
+		info, err := os.Stat(dst)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil {
+			err = fileutil.CopyFile(dst, dst+".bak", make([]byte, info.Size()), info.Mode())
+			if err != nil {
+				return err
+			}
+		}
+		defer os.Remove(dst + ".bak")
+
+		if err := builder.installRCFile(profileNames, rc[0], dst); err != nil {
+			if err2 := restore(rcFiles[:i+1]); err2 != nil {
 				return multierror.Append(err, err2)
 			}
+			return err
 		}
-		return err
 	}
 	return nil
 }
 
-func (builder *BaseBuilder) installRCFile(profileName, srcRCFileName, dst string) error {
-	src := filepath.Join(pathutil.RCDir(profileName), srcRCFileName)
+func (builder *BaseBuilder) installRCFile(profileNames []string, srcRCFileName, dst string) error {
+	src := rcFileOf(profileNames, srcRCFileName)
 
 	// Return error if destination file does not have magic comment
 	if pathutil.Exists(dst) {
@@ -80,11 +250,11 @@ func (builder *BaseBuilder) installRCFile(profileName, srcRCFileName, dst string
 			if !pathutil.Exists(src) {
 				return nil
 			}
-			return errors.Errorf("'%s' is not an auto-generated file. please move to '%s' and re-run 'volt build'", dst, pathutil.RCDir(profileName))
+			return errors.Errorf("'%s' is not an auto-generated file. please move to '%s' and re-run 'volt build'", dst, filepath.Dir(src))
 		}
 	}
 
-	// Remove destination (~/.vim/vimrc or ~/.vim/gvimrc)
+	// Remove destination (e.g. ~/.vim/vimrc or ~/.vim/gvimrc)
 	os.Remove(dst)
 	if pathutil.Exists(dst) {
 		return errors.New("failed to remove " + dst)
@@ -101,6 +271,18 @@ func (builder *BaseBuilder) installRCFile(profileName, srcRCFileName, dst string
 const magicComment = "\" NOTE: this file was generated by volt. please modify original file.\n"
 const magicCommentNext = "\" Original file: %s\n\n"
 
+// magicCommentLua and magicCommentLuaNext are written to init.lua.
+const magicCommentLua = "-- NOTE: this file was generated by volt. please modify original file.\n"
+const magicCommentLuaNext = "-- Original file: %s\n\n"
+
+// magicCommentOf returns the magic comment and the next line for path.
+func magicCommentOf(path string) (string, string) {
+	if strings.EqualFold(filepath.Ext(path), ".lua") {
+		return magicCommentLua, magicCommentLuaNext
+	}
+	return magicComment, magicCommentNext
+}
+
 // HasMagicComment returns true if the magic comment exists
 func (*BaseBuilder) HasMagicComment(dst string) bool {
 	r, err := os.Open(dst)
@@ -109,10 +291,11 @@ func (*BaseBuilder) HasMagicComment(dst string) bool {
 	}
 	defer r.Close()
 
-	magic := []byte(magicComment)
+	comment, _ := magicCommentOf(dst)
+	magic := []byte(comment)
 	read := make([]byte, len(magic))
 	n, err := r.Read(read)
-	if err != nil || n < len(magicComment) {
+	if err != nil || n < len(magic) {
 		return false
 	}
 
@@ -146,11 +329,12 @@ func (builder *BaseBuilder) copyFileWithMagicComment(src, dst string) (err error
 		}
 	}()
 
-	_, err = w.Write([]byte(magicComment))
+	comment, next := magicCommentOf(dst)
+	_, err = w.Write([]byte(comment))
 	if err != nil {
 		return
 	}
-	_, err = w.Write([]byte(fmt.Sprintf(magicCommentNext, src)))
+	_, err = w.Write([]byte(fmt.Sprintf(next, src)))
 	if err != nil {
 		return
 	}
@@ -163,31 +347,160 @@ type actionReposResult struct {
 	err   error
 	repos *lockjson.Repos
 	files buildinfo.FileMap
+	// dirty is true if the installed files are not the same as the locked
+	// revision (e.g. submodules are not checked out). The repository is
+	// installed again at the next build.
+	dirty bool
+	// log holds messages of the goroutine which sent this result.
+	// The receiver flushes it to output them as one block.
+	log *logger.Buffer
 }
 
-func (builder *BaseBuilder) helptags(reposPath pathutil.ReposPath, vimExePath string) error {
-	// Do nothing if <reposPath>/doc directory doesn't exist
-	docdir := filepath.Join(reposPath.EncodeToPlugDirName(), "doc")
-	if !pathutil.Exists(docdir) {
+// helptagsTimeout is the time limit of executing ":helptags" for each plugin.
+var helptagsTimeout = 30 * time.Second
+
+// helptagsScript is the Vim script which executes ":helptags doc" in each
+// directory listed in the file %[1]s, and writes "{dir}\t{error}" lines to
+// the file %[2]s.
+// Vim cannot expand the path which has some characters (e.g. "'"), so the
+// current directory is changed instead of giving the path to ":helptags".
+const helptagsScript = `let s:errors = []
+for s:dir in readfile('%[1]s')
+  try
+    execute 'cd' fnameescape(s:dir)
+    helptags doc
+  catch
+    call add(s:errors, s:dir . "\t" . v:exception)
+  endtry
+endfor
+call writefile(s:errors, '%[2]s')
+qall!
+`
+
+// helptags generates tags files of doc directories of reposPathList by
+// executing ":helptags" in one Vim process (or without vim if vimExePath is
+// empty), and returns the errors of each repository. Errors in the documents (e.g. duplicate tags) are only
+// warned because the tags file is created even if they exist.
+func (builder *BaseBuilder) helptags(ctx context.Context, reposPathList []pathutil.ReposPath, vimExePath string) map[pathutil.ReposPath]error {
+	// Do nothing for the repositories which don't have doc directory
+	reposDirs := make(map[string]pathutil.ReposPath, len(reposPathList))
+	dirs := make([]string, 0, len(reposPathList))
+	for _, reposPath := range reposPathList {
+		dir := reposPath.EncodeToPlugDirName()
+		if pathutil.Exists(filepath.Join(dir, "doc")) {
+			reposDirs[dir] = reposPath
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
 		return nil
 	}
-	// Execute ":helptags doc" in reposPath
-	vimArgs := builder.makeVimArgs(reposPath)
-	logger.Debugf("Executing '%s %s' ...", vimExePath, strings.Join(vimArgs, " "))
-	err := exec.Command(vimExePath, vimArgs...).Run()
+	if vimExePath == "" {
+		return builder.helptagsWithoutVim(reposDirs, dirs)
+	}
+	failAll := func(err error) map[pathutil.ReposPath]error {
+		errs := make(map[pathutil.ReposPath]error, len(dirs))
+		for _, dir := range dirs {
+			errs[reposDirs[dir]] = err
+		}
+		return errs
+	}
+
+	tempDir, err := ioutil.TempDir("", "volt-helptags-")
 	if err != nil {
-		return errors.Wrap(err, "failed to make tags file")
+		return failAll(errors.Wrap(err, "failed to make tags file"))
 	}
-	return nil
+	defer os.RemoveAll(tempDir)
+	listFile := filepath.Join(tempDir, "dirs")
+	resultFile := filepath.Join(tempDir, "errors")
+	scriptFile := filepath.Join(tempDir, "helptags.vim")
+	quote := func(s string) string { return strings.Replace(s, "'", "''", -1) }
+	script := fmt.Sprintf(helptagsScript, quote(listFile), quote(resultFile))
+	if err := ioutil.WriteFile(listFile, []byte(strings.Join(dirs, "\n")+"\n"), 0644); err != nil {
+		return failAll(errors.Wrap(err, "failed to make tags file"))
+	}
+	if err := ioutil.WriteFile(scriptFile, []byte(script), 0644); err != nil {
+		return failAll(errors.Wrap(err, "failed to make tags file"))
+	}
+
+	// Execute the script in silent Ex mode not to wait for input (e.g.
+	// "Press ENTER" prompt), and kill Vim if it hangs
+	timeout := helptagsTimeout * time.Duration(len(dirs))
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	vimArgs := builder.makeVimArgs(vimExePath, scriptFile)
+	logger.Debugf("Executing '%s %s' for %d repositories ...", vimExePath, strings.Join(vimArgs, " "), len(dirs))
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, vimExePath, vimArgs...)
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return failAll(errors.Errorf("failed to make tags file: %s did not exit in %s", vimExePath, timeout))
+	}
+	if ctx.Err() != nil {
+		return failAll(ctx.Err())
+	}
+	content, readErr := ioutil.ReadFile(resultFile)
+	if readErr != nil {
+		// The script did not finish
+		if err == nil {
+			err = readErr
+		}
+		// "-V1" writes error messages to stderr
+		if msg := strings.TrimSpace(strings.Replace(stderr.String(), "\r", "", -1)); msg != "" {
+			err = errors.Errorf("%s\n%s", err, msg)
+		}
+		return failAll(errors.Wrap(err, "failed to make tags file"))
+	}
+
+	var errs map[pathutil.ReposPath]error
+	for _, line := range strings.Split(strings.Replace(string(content), "\r", "", -1), "\n") {
+		kv := strings.SplitN(line, "\t", 2)
+		reposPath, ok := reposDirs[kv[0]]
+		if len(kv) != 2 || !ok {
+			continue
+		}
+		if pathutil.Exists(filepath.Join(kv[0], "doc", "tags")) {
+			logger.Warnf("%s: errors in the documents:\n%s", reposPath, kv[1])
+			continue
+		}
+		if errs == nil {
+			errs = make(map[pathutil.ReposPath]error)
+		}
+		errs[reposPath] = errors.New("failed to make tags file: " + kv[1])
+	}
+	return errs
 }
 
-func (*BaseBuilder) makeVimArgs(reposPath pathutil.ReposPath) []string {
-	path := reposPath.EncodeToPlugDirName()
-	return []string{
-		"-u", "NONE", "-i", "NONE", "-N",
-		"--cmd", "cd " + path,
-		"--cmd", "set rtp+=" + path,
-		"--cmd", "helptags doc",
-		"--cmd", "quit",
+// helptagsWithoutVim generates tags files of dirs (the keys of reposDirs) without
+// vim, and returns the errors of each repository.
+func (*BaseBuilder) helptagsWithoutVim(reposDirs map[string]pathutil.ReposPath, dirs []string) map[pathutil.ReposPath]error {
+	var errs map[pathutil.ReposPath]error
+	for _, dir := range dirs {
+		reposPath := reposDirs[dir]
+		docErrs, err := makeHelptags(filepath.Join(dir, "doc"))
+		if err != nil {
+			if errs == nil {
+				errs = make(map[pathutil.ReposPath]error)
+			}
+			errs[reposPath] = errors.Wrap(err, "failed to make tags file")
+			continue
+		}
+		if len(docErrs) > 0 {
+			logger.Warnf("%s: errors in the documents:\n%s", reposPath, strings.Join(docErrs, "\n"))
+		}
+	}
+	return errs
+}
+
+func (*BaseBuilder) makeVimArgs(vimExePath, scriptFile string) []string {
+	args := []string{
+		"-u", "NONE", "-i", "NONE", "-N", "-es", "-V1",
+		"-S", scriptFile,
+	}
+	if pathutil.IsNeovim(vimExePath) {
+		// Neovim does not start UI
+		args = append([]string{"--headless"}, args...)
 	}
+	return args
 }
@@ -0,0 +1,119 @@
+package builder
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestHelptags(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake vim is a shell script")
+	}
+	tempDir, err := ioutil.TempDir("", "volt-test-helptags-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", tempDir)
+	defer func(orig time.Duration) { helptagsTimeout = orig }(helptagsTimeout)
+	helptagsTimeout = time.Second
+
+	reposPath := pathutil.ReposPath("localhost/local/it's plug")
+	docdir := filepath.Join(reposPath.EncodeToPlugDirName(), "doc")
+	os.MkdirAll(docdir, 0755)
+	reposPath2 := pathutil.ReposPath("localhost/local/plug2")
+	docdir2 := filepath.Join(reposPath2.EncodeToPlugDirName(), "doc")
+	os.MkdirAll(docdir2, 0755)
+	noDoc := pathutil.ReposPath("localhost/local/nodoc")
+	writeFile := func(path, content string, perm os.FileMode) {
+		if err := ioutil.WriteFile(path, []byte(content), perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	builder := &BaseBuilder{}
+	helptags := func(vim string) map[pathutil.ReposPath]error {
+		return builder.helptags(context.Background(), []pathutil.ReposPath{reposPath, reposPath2, noDoc}, vim)
+	}
+
+	if vim, err := exec.LookPath("vim"); err == nil {
+		writeFile(filepath.Join(docdir, "foo.txt"), "*foo*\n", 0644)
+		writeFile(filepath.Join(docdir2, "bar.txt"), "*bar*\n", 0644)
+		if errs := helptags(vim); len(errs) != 0 {
+			t.Errorf("unexpected errors: %v", errs)
+		}
+		if content, _ := ioutil.ReadFile(filepath.Join(docdir, "tags")); !strings.HasPrefix(string(content), "foo\tfoo.txt") {
+			t.Errorf("unexpected tags file: %q", content)
+		}
+		if content, _ := ioutil.ReadFile(filepath.Join(docdir2, "tags")); !strings.HasPrefix(string(content), "bar\tbar.txt") {
+			t.Errorf("unexpected tags file: %q", content)
+		}
+
+		// Errors in the documents are not build errors
+		writeFile(filepath.Join(docdir, "foo.txt"), "*foo*\n*foo*\n", 0644)
+		if errs := helptags(vim); len(errs) != 0 {
+			t.Errorf("unexpected errors: %v", errs)
+		}
+
+		// Only the repository which failed has an error
+		os.Remove(filepath.Join(docdir2, "tags"))
+		os.Chmod(docdir2, 0555)
+		errs := helptags(vim)
+		os.Chmod(docdir2, 0755)
+		if os.Geteuid() != 0 && (len(errs) != 1 || errs[reposPath2] == nil) {
+			t.Errorf("expected an error of %s but got %v", reposPath2, errs)
+		}
+	}
+	os.Remove(filepath.Join(docdir, "tags"))
+
+	// Tags files are generated without vim
+	writeFile(filepath.Join(docdir, "foo.txt"), "*foo*\n", 0644)
+	if errs := helptags(""); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if content, _ := ioutil.ReadFile(filepath.Join(docdir, "tags")); string(content) != "foo\tfoo.txt\t/*foo*\n" {
+		t.Errorf("unexpected tags file: %q", content)
+	}
+	os.Remove(filepath.Join(docdir, "tags"))
+
+	// Hanging vim is killed
+	hangVim := filepath.Join(tempDir, "hang-vim")
+	writeFile(hangVim, "#!/bin/sh\nexec sleep 10\n", 0755)
+	start := time.Now()
+	errs := helptags(hangVim)
+	if err := errs[reposPath]; err == nil || !strings.Contains(err.Error(), "did not exit") {
+		t.Errorf("expected timeout error but got %v", err)
+	}
+	if errs[reposPath2] == nil || errs[noDoc] != nil {
+		t.Errorf("expected errors of repositories with doc directory but got %v", errs)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("vim was not killed in %s", d)
+	}
+
+	// Error messages are reported
+	brokenVim := filepath.Join(tempDir, "broken-vim")
+	writeFile(brokenVim, "#!/bin/sh\necho 'E999: broken' >&2\nexit 1\n", 0755)
+	if err := helptags(brokenVim)[reposPath]; err == nil || !strings.Contains(err.Error(), "E999: broken") {
+		t.Errorf("expected error message but got %v", err)
+	}
+}
+
+func TestMakeVimArgs(t *testing.T) {
+	builder := &BaseBuilder{}
+	if args := builder.makeVimArgs("/usr/bin/vim", "helptags.vim"); args[0] == "--headless" {
+		t.Errorf("unexpected --headless for vim: %q", args)
+	}
+	if args := builder.makeVimArgs("/usr/bin/nvim", "helptags.vim"); args[0] != "--headless" {
+		t.Errorf("expected --headless for nvim but got %q", args)
+	}
+}
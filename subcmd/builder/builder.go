@@ -1,24 +1,51 @@
 package builder
 
 import (
+	"context"
 	"github.com/pkg/errors"
-	"os"
 
 	"github.com/vim-volt/volt/config"
 	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
 	"github.com/vim-volt/volt/subcmd/buildinfo"
+	"github.com/vim-volt/volt/transaction"
 )
 
 // Builder creates/updates ~/.vim/pack/volt directory
 type Builder interface {
-	Build(buildInfo *buildinfo.BuildInfo, buildReposMap map[pathutil.ReposPath]*buildinfo.Repos) error
+	Build(ctx context.Context, buildInfo *buildinfo.BuildInfo, buildReposMap map[pathutil.ReposPath]*buildinfo.Repos) error
 }
 
 const currentBuildInfoVersion = 2
 
-// Build creates/updates ~/.vim/pack/volt directory
-func Build(full bool) error {
+// skipBuild is true if building is deferred by "volt -no-build".
+var skipBuild bool
+
+// SkipBuild makes Build() and BuildDelta() do nothing if skip is true.
+// This is used to run "volt build" once after several changes.
+func SkipBuild(skip bool) {
+	skipBuild = skip
+}
+
+// IsBuildSkipped returns true if building is skipped by SkipBuild().
+func IsBuildSkipped() bool {
+	return skipBuild
+}
+
+// logSkipped shows that building was skipped.
+func logSkipped() {
+	logger.Info("Skipped building " + pathutil.VimVoltDir() + " (-no-build): run \"volt build\" to apply the changes")
+}
+
+// Build creates/updates ~/.vim/pack/volt directory.
+// If ctx is done, remaining repositories are not installed and an error is
+// returned.
+func Build(ctx context.Context, full bool) error {
+	if skipBuild {
+		logSkipped()
+		return nil
+	}
+
 	// Read config.toml
 	cfg, err := config.Read()
 	if err != nil {
@@ -26,7 +53,7 @@ func Build(full bool) error {
 	}
 
 	// Get builder
-	blder, err := getBuilder(cfg.Build.Strategy)
+	blder, err := getBuilder(cfg)
 	if err != nil {
 		return err
 	}
@@ -66,25 +93,70 @@ func Build(full bool) error {
 		logger.Info("Building " + optDir + " directory ...")
 	}
 
-	// Remove ~/.vim/pack/volt/ if -full option was given
+	// Remove ~/.vim/pack/volt/ if -full option was given.
+	// In a transaction, it is kept to be restored by rollback.
 	if full {
 		vimVoltDir := pathutil.VimVoltDir()
-		os.RemoveAll(vimVoltDir)
+		transaction.RemoveAll(vimVoltDir)
 		if pathutil.Exists(vimVoltDir) {
 			return errors.New("failed to remove " + vimVoltDir)
 		}
 	}
 
-	return blder.Build(buildInfo, buildReposMap)
+	return blder.Build(ctx, buildInfo, buildReposMap)
+}
+
+// BuildDelta updates ~/.vim/pack/volt directory after repositories were added
+// to or removed from profiles (e.g. "volt enable", "volt disable"). If
+// build.strategy is "symlink", only the added repositories are installed, the
+// removed ones are uninstalled, and the bundled plugconf is regenerated.
+// Otherwise, or if installed repositories are changed (e.g. lock.json was
+// updated by other commands), this is the same as Build(ctx, false).
+func BuildDelta(ctx context.Context) error {
+	if skipBuild {
+		logSkipped()
+		return nil
+	}
+
+	// Read config.toml
+	cfg, err := config.Read()
+	if err != nil {
+		return errors.Wrap(err, "could not read config.toml")
+	}
+
+	// Read ~/.vim/pack/volt/opt/build-info.json
+	buildInfo, err := buildinfo.Read()
+	if err != nil {
+		return err
+	}
+
+	if buildInfo.Version == currentBuildInfoVersion &&
+		buildInfo.Strategy == cfg.Build.Strategy &&
+		cfg.Build.Strategy == config.SymlinkBuilder {
+		blder := &symlinkBuilder{newBaseBuilder(cfg)}
+		if built, err := blder.buildDelta(ctx, buildInfo); err != nil || built {
+			return err
+		}
+	}
+	return Build(ctx, false)
+}
+
+func newBaseBuilder(cfg *config.Config) BaseBuilder {
+	return BaseBuilder{
+		runtimeProfile:      *cfg.Build.RuntimeProfile,
+		concatPluginScripts: *cfg.Build.ConcatPluginScripts,
+		vimExecutableOf:     cfg.Build.VimExecutableOf,
+	}
 }
 
-func getBuilder(strategy string) (Builder, error) {
-	switch strategy {
+func getBuilder(cfg *config.Config) (Builder, error) {
+	base := newBaseBuilder(cfg)
+	switch cfg.Build.Strategy {
 	case config.SymlinkBuilder:
-		return &symlinkBuilder{}, nil
+		return &symlinkBuilder{base}, nil
 	case config.CopyBuilder:
-		return &copyBuilder{}, nil
+		return &copyBuilder{BaseBuilder: base}, nil
 	default:
-		return nil, errors.New("unknown builder type: " + strategy)
+		return nil, errors.New("unknown builder type: " + cfg.Build.Strategy)
 	}
 }
@@ -0,0 +1,176 @@
+package builder
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// concatScriptName is the filename of the script which plugin/*.vim files are
+// concatenated into.
+const concatScriptName = "volt_concat.vim"
+
+var (
+	// :if {cond} | finish | endif
+	rxGuardOneLine = regexp.MustCompile(`^\s*if\s+(.+?)\s*\|\s*fini(?:s(?:h)?)?\s*\|\s*en(?:d(?:i(?:f)?)?)?\s*$`)
+	rxGuardIf      = regexp.MustCompile(`^\s*if\s+(.+?)\s*$`)
+	rxGuardFinish  = regexp.MustCompile(`^\s*fini(?:s(?:h)?)?\s*$`)
+	rxGuardEndif   = regexp.MustCompile(`^\s*en(?:d(?:i(?:f)?)?)?\s*$`)
+	rxFinish       = regexp.MustCompile(`(?:^|\|)\s*fini(?:s(?:h)?)?\b`)
+	// The script name, or the encoding of the script would be changed
+	rxUnsafeScript = regexp.MustCompile(`(?m)<sfile>|<script>|<stack>|^\s*scripte(?:n(?:c(?:o(?:d(?:i(?:n(?:g)?)?)?)?)?)?)?\b`)
+	rxScriptLocal  = regexp.MustCompile(`\bs:[A-Za-z_][A-Za-z0-9_#]*|<SID>[A-Za-z_][A-Za-z0-9_#]*`)
+)
+
+// sharableScriptLocals are script-local variables which can be used in
+// multiple scripts because they are always set and unset in the script
+// (e.g. "let s:save_cpo = &cpo" ... "unlet s:save_cpo").
+var sharableScriptLocals = map[string]bool{
+	"s:save_cpo":  true,
+	"s:cpo_save":  true,
+	"s:saved_cpo": true,
+}
+
+type vimScript struct {
+	name    string
+	content string
+}
+
+// concatPluginScripts concatenates plugin/*.vim files of the plugin placed at
+// dir into plugin/volt_concat.vim, and removes them.
+// It returns the number of concatenated files, or the reason why the files
+// were not concatenated (see concatScripts()).
+func concatPluginScripts(dir string) (int, string, error) {
+	pluginDir := filepath.Join(dir, "plugin")
+	infos, err := ioutil.ReadDir(pluginDir)
+	if os.IsNotExist(err) {
+		return 0, "", nil
+	} else if err != nil {
+		return 0, "", err
+	}
+
+	// ioutil.ReadDir() sorts by filename like Vim sources plugin/*.vim
+	var scripts []vimScript
+	for _, fi := range infos {
+		if fi.IsDir() {
+			// Files under plugin/{dir}/ are sourced in the middle of plugin/*.vim
+			return 0, "plugin/ has subdirectories", nil
+		}
+		if filepath.Ext(fi.Name()) != ".vim" || !fi.Mode().IsRegular() {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(pluginDir, fi.Name()))
+		if err != nil {
+			return 0, "", err
+		}
+		scripts = append(scripts, vimScript{
+			name:    "plugin/" + fi.Name(),
+			content: strings.Replace(string(content), "\r\n", "\n", -1),
+		})
+	}
+	if len(scripts) < 2 {
+		return 0, "", nil
+	}
+
+	content, reason := concatScripts(scripts)
+	if reason != "" {
+		return 0, reason, nil
+	}
+	// Remove files before writing because they may be hard links to the files
+	// of the repository
+	for i := range scripts {
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(scripts[i].name))); err != nil {
+			return 0, "", err
+		}
+	}
+	dst := filepath.Join(pluginDir, concatScriptName)
+	if err := ioutil.WriteFile(dst, content, 0644); err != nil {
+		return 0, "", err
+	}
+	return len(scripts), "", nil
+}
+
+// concatScripts returns the concatenated content of scripts.
+// A load guard at the beginning of a script ("if {cond} | finish | endif")
+// is converted to "if !({cond})" which encloses the rest of the script.
+// If it is not safe to concatenate scripts, the reason is returned:
+// * a script has ":finish" which is not a load guard
+// * a script uses its filename ("<sfile>") or has ":scriptencoding"
+// * the same script-local name is used in multiple scripts
+func concatScripts(scripts []vimScript) ([]byte, string) {
+	usedBy := make(map[string]string)
+	var buf bytes.Buffer
+	for i, script := range scripts {
+		if rxUnsafeScript.MatchString(script.content) {
+			return nil, script.name + " uses <sfile> or :scriptencoding"
+		}
+		for _, name := range rxScriptLocal.FindAllString(script.content, -1) {
+			name = strings.Replace(name, "<SID>", "s:", 1)
+			if sharableScriptLocals[name] {
+				continue
+			}
+			if other, ok := usedBy[name]; ok && other != script.name {
+				return nil, name + " is used in both " + other + " and " + script.name
+			}
+			usedBy[name] = script.name
+		}
+
+		lines := strings.Split(strings.TrimSuffix(script.content, "\n"), "\n")
+		head, cond, body := splitLoadGuard(lines)
+		for _, line := range body {
+			if rxFinish.MatchString(line) {
+				return nil, script.name + " has :finish which is not a load guard"
+			}
+		}
+
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("\" " + script.name + "\n")
+		for _, line := range head {
+			buf.WriteString(line + "\n")
+		}
+		if cond != "" {
+			buf.WriteString("if !(" + cond + ")\n")
+		}
+		for _, line := range body {
+			buf.WriteString(line + "\n")
+		}
+		if cond != "" {
+			buf.WriteString("endif\n")
+		}
+	}
+	return buf.Bytes(), ""
+}
+
+// splitLoadGuard splits lines into the leading comment and blank lines, the
+// condition of the load guard, and the rest.
+// If the first statement is not a load guard, cond is empty and body has the
+// statement.
+func splitLoadGuard(lines []string) (head []string, cond string, body []string) {
+	i := 0
+	for i < len(lines) {
+		s := strings.TrimSpace(lines[i])
+		if s != "" && !strings.HasPrefix(s, "\"") {
+			break
+		}
+		i++
+	}
+	head = lines[:i]
+	if i < len(lines) {
+		if m := rxGuardOneLine.FindStringSubmatch(lines[i]); m != nil {
+			return head, m[1], lines[i+1:]
+		}
+		if i+2 < len(lines) &&
+			rxGuardFinish.MatchString(lines[i+1]) &&
+			rxGuardEndif.MatchString(lines[i+2]) {
+			if m := rxGuardIf.FindStringSubmatch(lines[i]); m != nil {
+				return head, m[1], lines[i+3:]
+			}
+		}
+	}
+	return head, "", lines[i:]
+}
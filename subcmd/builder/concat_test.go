@@ -0,0 +1,109 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConcatScripts(t *testing.T) {
+	for i, tt := range []struct {
+		scripts  []vimScript
+		expected string
+		reason   string
+	}{
+		{
+			scripts: []vimScript{
+				{"plugin/a.vim", "\" comment\nif exists('g:loaded_a')\n  finish\nendif\nlet g:loaded_a = 1\n"},
+				{"plugin/b.vim", "if exists('g:loaded_b') | finish | endif\r\nlet s:save_cpo = &cpo\r\nfunction! s:b()\r\nendfunction\r\nunlet s:save_cpo\r\n"},
+				{"plugin/c.vim", "let s:save_cpo = &cpo\nnnoremap <Plug>(c) :<C-u>call <SID>c()<CR>\nunlet s:save_cpo"},
+			},
+			expected: "\" plugin/a.vim\n\" comment\nif !(exists('g:loaded_a'))\nlet g:loaded_a = 1\nendif\n" +
+				"\n\" plugin/b.vim\nif !(exists('g:loaded_b'))\nlet s:save_cpo = &cpo\nfunction! s:b()\nendfunction\nunlet s:save_cpo\nendif\n" +
+				"\n\" plugin/c.vim\nlet s:save_cpo = &cpo\nnnoremap <Plug>(c) :<C-u>call <SID>c()<CR>\nunlet s:save_cpo\n",
+		},
+		{
+			scripts: []vimScript{
+				{"plugin/a.vim", "if has('nvim')\n  finish\nendif\n"},
+				{"plugin/b.vim", "if !has('patch-8.0.0')\n  echo 'old'\n  finish\nendif\n"},
+			},
+			reason: "plugin/b.vim has :finish which is not a load guard",
+		},
+		{
+			scripts: []vimScript{
+				{"plugin/a.vim", "let s:dir = expand('<sfile>:p:h')\n"},
+				{"plugin/b.vim", ""},
+			},
+			reason: "plugin/a.vim uses <sfile> or :scriptencoding",
+		},
+		{
+			scripts: []vimScript{
+				{"plugin/a.vim", "scriptencoding utf-8\n"},
+				{"plugin/b.vim", ""},
+			},
+			reason: "plugin/a.vim uses <sfile> or :scriptencoding",
+		},
+		{
+			scripts: []vimScript{
+				{"plugin/a.vim", "function! s:init()\nendfunction\n"},
+				{"plugin/b.vim", "nnoremap <Plug>(b) :<C-u>call <SID>init()<CR>\n"},
+			},
+			reason: "s:init is used in both plugin/a.vim and plugin/b.vim",
+		},
+	} {
+		for j := range tt.scripts {
+			tt.scripts[j].content = strings.Replace(tt.scripts[j].content, "\r\n", "\n", -1)
+		}
+		content, reason := concatScripts(tt.scripts)
+		if reason != tt.reason {
+			t.Errorf("[%d] expected reason %q but got %q", i, tt.reason, reason)
+		}
+		if string(content) != tt.expected {
+			t.Errorf("[%d] expected %q but got %q", i, tt.expected, content)
+		}
+	}
+}
+
+func TestConcatPluginScripts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "volt-test-concat-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pluginDir := filepath.Join(dir, "plugin")
+	os.MkdirAll(pluginDir, 0755)
+	for name, content := range map[string]string{
+		"a.vim":     "let g:a = 1\n",
+		"b.vim":     "let g:b = 1\n",
+		"README.md": "not a script",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(pluginDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, reason, err := concatPluginScripts(dir)
+	if err != nil || reason != "" || n != 2 {
+		t.Fatalf("expected (2, \"\", nil) but got (%d, %q, %v)", n, reason, err)
+	}
+	infos, err := ioutil.ReadDir(pluginDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	if strings.Join(names, ",") != "README.md,"+concatScriptName {
+		t.Errorf("unexpected files: %v", names)
+	}
+
+	// Subdirectory in plugin/
+	os.MkdirAll(filepath.Join(pluginDir, "sub"), 0755)
+	n, reason, err = concatPluginScripts(dir)
+	if err != nil || reason == "" || n != 0 {
+		t.Errorf("expected (0, reason, nil) but got (%d, %q, %v)", n, reason, err)
+	}
+}
@@ -0,0 +1,105 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// runtimeDirs are the directories of a plugin whose files are looked up in
+// 'runtimepath' by the relative path. If two or more plugins have the same
+// file, only one of them is effectively used: e.g. an autoload script is
+// loaded from the first directory in 'runtimepath', and ftplugin, indent, and
+// syntax scripts are guarded by b:did_ftplugin and so on. The files of plugin
+// and ftdetect directories are all sourced, but the same file name usually
+// means the same definitions.
+var runtimeDirs = []string{
+	"autoload", "colors", "compiler", "ftdetect", "ftplugin", "import",
+	"indent", "keymap", "lua", "plugin", "python3", "pythonx", "syntax",
+}
+
+// runtimeConflict is a runtime-relative file which is provided by two or more
+// plugins.
+type runtimeConflict struct {
+	// file is a slash-separated relative path (e.g. "autoload/foo.vim")
+	file string
+	// reposList is the plugins which have file in load order
+	reposList []pathutil.ReposPath
+}
+
+// winner returns the plugin whose file is found first in 'runtimepath'.
+// ":packadd" inserts the directory just after the directory which has the
+// package (~/.vim), so the plugin loaded last comes first.
+func (c *runtimeConflict) winner() pathutil.ReposPath {
+	return c.reposList[len(c.reposList)-1]
+}
+
+// findRuntimeConflicts returns the files of runtimeDirs (and "after/"
+// runtimeDirs) which are provided by two or more repositories of reposList
+// in ~/.vim/pack/volt/opt. reposList must be in load order.
+// The conflicts are sorted by the file.
+func findRuntimeConflicts(reposList []pathutil.ReposPath) ([]runtimeConflict, error) {
+	providers := make(map[string][]pathutil.ReposPath)
+	for _, reposPath := range reposList {
+		plugDir := reposPath.EncodeToPlugDirName()
+		for _, dir := range runtimeDirs {
+			for _, root := range []string{dir, filepath.Join("after", dir)} {
+				files, err := runtimeFiles(plugDir, root)
+				if err != nil {
+					return nil, err
+				}
+				for _, file := range files {
+					providers[file] = append(providers[file], reposPath)
+				}
+			}
+		}
+	}
+
+	conflicts := make([]runtimeConflict, 0, len(providers))
+	for file, list := range providers {
+		if len(list) > 1 {
+			conflicts = append(conflicts, runtimeConflict{file: file, reposList: list})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].file < conflicts[j].file
+	})
+	return conflicts, nil
+}
+
+// runtimeFiles returns slash-separated relative paths from plugDir of the
+// regular files under plugDir/root.
+func runtimeFiles(plugDir, root string) ([]string, error) {
+	if !pathutil.Exists(filepath.Join(plugDir, root)) {
+		return nil, nil
+	}
+	var files []string
+	err := filepath.Walk(filepath.Join(plugDir, root), func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(plugDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// formatRuntimeConflict returns the warning message of c.
+func formatRuntimeConflict(c *runtimeConflict) string {
+	names := make([]string, 0, len(c.reposList))
+	for _, reposPath := range c.reposList {
+		names = append(names, reposPath.String())
+	}
+	return c.file + " is provided by " + strings.Join(names, ", ") +
+		": " + c.winner().String() + " is found first in 'runtimepath'"
+}
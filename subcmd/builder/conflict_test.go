@@ -0,0 +1,58 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestFindRuntimeConflicts(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", tempDir)
+
+	files := map[pathutil.ReposPath][]string{
+		"github.com/tyru/caw.vim":          {"autoload/caw.vim", "plugin/caw.vim", "after/ftplugin/python.vim"},
+		"github.com/tyru/open-browser.vim": {"autoload/caw.vim", "after/ftplugin/python.vim", "doc/caw.txt"},
+		"localhost/local/hello":            {"autoload/caw.vim", "doc/caw.txt"},
+	}
+	for reposPath, list := range files {
+		for _, file := range list {
+			path := filepath.Join(reposPath.EncodeToPlugDirName(), filepath.FromSlash(file))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	reposList := []pathutil.ReposPath{
+		"localhost/local/hello",
+		"github.com/tyru/caw.vim",
+		"github.com/tyru/open-browser.vim",
+	}
+	conflicts, err := findRuntimeConflicts(reposList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []runtimeConflict{
+		{file: "after/ftplugin/python.vim", reposList: reposList[1:]},
+		{file: "autoload/caw.vim", reposList: reposList},
+	}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Fatalf("expected %v but got %v", expected, conflicts)
+	}
+	if winner := conflicts[1].winner(); winner != "github.com/tyru/open-browser.vim" {
+		t.Errorf("expected github.com/tyru/open-browser.vim but got %s", winner)
+	}
+}
@@ -1,9 +1,12 @@
 package builder
 
 import (
+	"context"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -14,44 +17,37 @@ import (
 	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
-	"github.com/vim-volt/volt/plugconf"
 	"github.com/vim-volt/volt/subcmd/buildinfo"
+	"github.com/vim-volt/volt/transaction"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
 type copyBuilder struct {
 	BaseBuilder
+	store *objectStore
 }
 
-func (builder *copyBuilder) Build(buildInfo *buildinfo.BuildInfo, buildReposMap map[pathutil.ReposPath]*buildinfo.Repos) error {
-	// Exit if vim executable was not found in PATH
-	vimExePath, err := pathutil.VimExecutable()
-	if err != nil {
-		return err
-	}
-
+func (builder *copyBuilder) Build(ctx context.Context, buildInfo *buildinfo.BuildInfo, buildReposMap map[pathutil.ReposPath]*buildinfo.Repos) error {
 	// Read lock.json
 	lockJSON, err := lockjson.Read()
 	if err != nil {
 		return errors.New("could not read lock.json: " + err.Error())
 	}
 
-	// Get current profile's repos list
-	reposList, err := lockJSON.GetCurrentReposList()
+	vimExePath := builder.vimExecutable(lockJSON)
+
+	// Get repos list to install
+	reposList, err := builder.reposListToInstall(lockJSON)
 	if err != nil {
 		return err
 	}
 
 	logger.Info("Installing vimrc and gvimrc ...")
 
-	vimDir := pathutil.VimDir()
-	vimrcPath := filepath.Join(vimDir, pathutil.Vimrc)
-	gvimrcPath := filepath.Join(vimDir, pathutil.Gvimrc)
-	err = builder.installVimrcAndGvimrc(
-		lockJSON.CurrentProfileName, vimrcPath, gvimrcPath,
-	)
+	err = builder.installRCFiles(lockJSON.CurrentProfileNames())
 	if err != nil {
 		return err
 	}
@@ -69,21 +65,34 @@ func (builder *copyBuilder) Build(buildInfo *buildinfo.BuildInfo, buildReposMap
 	}
 
 	// Copy volt repos files to optDir
-	copyDone, copyCount := builder.copyReposList(buildReposMap, reposList, optDir, vimExePath)
+	builder.store = newObjectStore(pathutil.ObjectsDir())
+	copyDone, copyCount := builder.copyReposList(ctx, buildReposMap, reposList, optDir)
 
 	// Remove vim repos not found in lock.json current repos list
 	removeDone, removeCount := builder.removeReposList(reposList, reposDirList)
 
 	// Wait copy
 	var copyModified bool
+	copied := make([]pathutil.ReposPath, 0, copyCount)
 	copyErr := builder.waitCopyRepos(copyDone, copyCount, func(result *actionReposResult) error {
 		logger.Info("Installing " + string(result.repos.Type) + " repository " + result.repos.Path.String() + " ... Done.")
 		// Construct buildInfo from the result
 		builder.constructBuildInfo(buildInfo, result)
+		copied = append(copied, result.repos.Path)
 		copyModified = true
 		return nil
 	})
 
+	// Run ":helptags" to generate tags files of copied repositories at once
+	helptagsErrs := builder.helptags(ctx, copied, vimExePath)
+	for _, reposPath := range copied {
+		if err := helptagsErrs[reposPath]; err != nil {
+			// Copy the repository again at the next build
+			buildInfo.Repos.RemoveByReposPath(reposPath)
+			copyErr = multierror.Append(copyErr, errors.Wrap(err, "failed to copy repository '"+reposPath.String()+"'"))
+		}
+	}
+
 	// Wait remove
 	var removeModified bool
 	removeErr := builder.waitRemoveRepos(removeDone, removeCount, func(result *actionReposResult) {
@@ -98,30 +107,7 @@ func (builder *copyBuilder) Build(buildInfo *buildinfo.BuildInfo, buildReposMap
 	}
 
 	// Write bundled plugconf file
-	rcDir := pathutil.RCDir(lockJSON.CurrentProfileName)
-	vimrc := ""
-	if path := filepath.Join(rcDir, pathutil.ProfileVimrc); pathutil.Exists(path) {
-		vimrc = path
-	}
-	gvimrc := ""
-	if path := filepath.Join(rcDir, pathutil.ProfileGvimrc); pathutil.Exists(path) {
-		gvimrc = path
-	}
-	plugconfs, parseErr := plugconf.ParseMultiPlugconf(reposList)
-	if parseErr.HasErrs() {
-		// Vim script parse errors / other errors
-		return parseErr.Errors()
-	}
-	if parseErr.HasWarns() {
-		// Vim script parse warnings
-		merr := parseErr.Warns()
-		for _, err := range merr.Errors {
-			logger.Warn(err)
-		}
-	}
-	content, err := plugconfs.GenerateBundlePlugconf(vimrc, gvimrc)
-	os.MkdirAll(filepath.Dir(pathutil.BundledPlugConf()), 0755)
-	err = ioutil.WriteFile(pathutil.BundledPlugConf(), content, 0644)
+	err = builder.writeBundledPlugconf(lockJSON, reposList)
 	if err != nil {
 		return err
 	}
@@ -132,17 +118,24 @@ func (builder *copyBuilder) Build(buildInfo *buildinfo.BuildInfo, buildReposMap
 		if err != nil {
 			return err
 		}
+
+		// Remove objects which are no longer used
+		if n, err := builder.store.gc(); err != nil {
+			logger.Warn("Could not remove unused objects in " + pathutil.ObjectsDir() + ": " + err.Error())
+		} else if n > 0 {
+			logger.Debugf("Removed %d unused objects in %s", n, pathutil.ObjectsDir())
+		}
 	}
 
 	return nil
 }
 
-func (builder *copyBuilder) copyReposList(buildReposMap map[pathutil.ReposPath]*buildinfo.Repos, reposList []lockjson.Repos, optDir, vimExePath string) (chan actionReposResult, int) {
+func (builder *copyBuilder) copyReposList(ctx context.Context, buildReposMap map[pathutil.ReposPath]*buildinfo.Repos, reposList []lockjson.Repos, optDir string) (chan actionReposResult, int) {
 	copyDone := make(chan actionReposResult, len(reposList))
 	copyCount := 0
 	for i := range reposList {
 		if reposList[i].Type == lockjson.ReposGitType {
-			n, err := builder.copyReposGit(&reposList[i], buildReposMap[reposList[i].Path], vimExePath, copyDone)
+			n, err := builder.copyReposGit(ctx, &reposList[i], buildReposMap[reposList[i].Path], copyDone)
 			if err != nil {
 				copyDone <- actionReposResult{
 					err:   errors.Wrap(err, "failed to copy "+string(reposList[i].Type)+" repos"),
@@ -150,8 +143,10 @@ func (builder *copyBuilder) copyReposList(buildReposMap map[pathutil.ReposPath]*
 				}
 			}
 			copyCount += n
-		} else if reposList[i].Type == lockjson.ReposStaticType {
-			copyCount += builder.copyReposStatic(&reposList[i], buildReposMap[reposList[i].Path], optDir, vimExePath, copyDone)
+		} else if reposList[i].Type == lockjson.ReposStaticType || reposList[i].Type == lockjson.ReposReleaseType || reposList[i].Type == lockjson.ReposArchiveType {
+			// Release and archive repositories are extracted archives, copy
+			// them as static ones
+			copyCount += builder.copyReposStatic(ctx, &reposList[i], buildReposMap[reposList[i].Path], copyDone)
 		} else {
 			copyDone <- actionReposResult{
 				err:   errors.New("invalid repository type: " + string(reposList[i].Type)),
@@ -162,7 +157,7 @@ func (builder *copyBuilder) copyReposList(buildReposMap map[pathutil.ReposPath]*
 	return copyDone, copyCount
 }
 
-func (builder *copyBuilder) copyReposGit(repos *lockjson.Repos, buildRepos *buildinfo.Repos, vimExePath string, done chan actionReposResult) (int, error) {
+func (builder *copyBuilder) copyReposGit(ctx context.Context, repos *lockjson.Repos, buildRepos *buildinfo.Repos, done chan actionReposResult) (int, error) {
 	src := repos.Path.FullPath()
 
 	// Open ~/volt/repos/{repos}
@@ -177,10 +172,9 @@ func (builder *copyBuilder) copyReposGit(repos *lockjson.Repos, buildRepos *buil
 		return 0, errors.Errorf("failed to get HEAD revision of %q: %s", src, err.Error())
 	}
 	if head != repos.Version {
-		logger.Warnf("%s: HEAD and locked revision are different", repos.Path)
-		logger.Warn("  HEAD: " + head)
-		logger.Warn("  locked revision: " + repos.Version)
-		logger.Warn("  Please run 'volt get -l' to update locked revision.")
+		logger.Warnf("%s: HEAD and locked revision are different\n"+
+			"  HEAD: %s\n  locked revision: %s\n"+
+			"  Please run 'volt get -l' to update locked revision.", repos.Path, head, repos.Version)
 	}
 
 	cfg, err := r.Config()
@@ -200,15 +194,26 @@ func (builder *copyBuilder) copyReposGit(repos *lockjson.Repos, buildRepos *buil
 		// * bare repository
 		// * or worktree is clean
 		copyFromGitObjects := cfg.Core.IsBare || isClean
-		go builder.updateGitRepos(repos, r, copyFromGitObjects, vimExePath, done)
+		if !copyFromGitObjects {
+			logger.Warnf("%s: worktree has uncommitted changes, they are installed", repos.Path)
+		}
+		var oldFiles buildinfo.FileMap
+		if buildRepos != nil && !buildRepos.DirtyWorktree {
+			oldFiles = buildRepos.Files
+		}
+		go builder.updateGitRepos(ctx, repos, r, copyFromGitObjects, oldFiles, done)
 		return 1, nil
 	}
 	return 0, nil
 }
 
-func (builder *copyBuilder) copyReposStatic(repos *lockjson.Repos, buildRepos *buildinfo.Repos, optDir, vimExePath string, done chan actionReposResult) int {
-	if builder.hasChangedStaticRepos(repos, buildRepos, optDir) {
-		go builder.updateStaticRepos(repos, vimExePath, done)
+func (builder *copyBuilder) copyReposStatic(ctx context.Context, repos *lockjson.Repos, buildRepos *buildinfo.Repos, done chan actionReposResult) int {
+	if builder.hasChangedStaticRepos(repos, buildRepos) {
+		var oldFiles buildinfo.FileMap
+		if buildRepos != nil {
+			oldFiles = buildRepos.Files
+		}
+		go builder.updateStaticRepos(ctx, repos, oldFiles, done)
 		return 1
 	}
 	return 0
@@ -226,7 +231,7 @@ func (builder *copyBuilder) removeReposList(reposList lockjson.ReposList, reposD
 	removeDone := make(chan actionReposResult, len(removeList))
 	for i := range removeList {
 		go func(reposPath pathutil.ReposPath) {
-			err := os.RemoveAll(reposPath.EncodeToPlugDirName())
+			err := transaction.RemoveAll(reposPath.EncodeToPlugDirName())
 			logger.Info("Removing " + reposPath + " ... Done.")
 			removeDone <- actionReposResult{
 				err:   err,
@@ -241,6 +246,7 @@ func (*copyBuilder) waitCopyRepos(copyDone chan actionReposResult, copyCount int
 	var merr *multierror.Error
 	for i := 0; i < copyCount; i++ {
 		result := <-copyDone
+		result.log.Flush()
 		if result.err != nil {
 			merr = multierror.Append(
 				merr,
@@ -263,18 +269,20 @@ func (*copyBuilder) constructBuildInfo(buildInfo *buildinfo.BuildInfo, result *a
 		if r != nil {
 			r.Version = result.repos.Version
 			r.Files = result.files
+			r.DirtyWorktree = result.dirty
 		} else {
 			buildInfo.Repos = append(
 				buildInfo.Repos,
 				buildinfo.Repos{
-					Type:    lockjson.ReposGitType,
-					Path:    result.repos.Path,
-					Version: result.repos.Version,
-					Files:   result.files,
+					Type:          lockjson.ReposGitType,
+					Path:          result.repos.Path,
+					Version:       result.repos.Version,
+					Files:         result.files,
+					DirtyWorktree: result.dirty,
 				},
 			)
 		}
-	} else if result.repos.Type == lockjson.ReposStaticType {
+	} else if result.repos.Type == lockjson.ReposStaticType || result.repos.Type == lockjson.ReposReleaseType || result.repos.Type == lockjson.ReposArchiveType {
 		r := buildInfo.Repos.FindByReposPath(result.repos.Path)
 		if r != nil {
 			r.Version = time.Now().Format(time.RFC3339)
@@ -283,7 +291,7 @@ func (*copyBuilder) constructBuildInfo(buildInfo *buildinfo.BuildInfo, result *a
 			buildInfo.Repos = append(
 				buildInfo.Repos,
 				buildinfo.Repos{
-					Type:    lockjson.ReposStaticType,
+					Type:    result.repos.Type,
 					Path:    result.repos.Path,
 					Version: time.Now().Format(time.RFC3339),
 					Files:   result.files,
@@ -313,24 +321,9 @@ func (*copyBuilder) waitRemoveRepos(removeDone chan actionReposResult, removeCou
 	return merr
 }
 
-func (*copyBuilder) getLatestModTime(path string) (time.Time, error) {
-	mtime := time.Unix(0, 0)
-	err := filepath.Walk(path, func(_ string, fi os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		t := fi.ModTime()
-		if mtime.Before(t) {
-			mtime = t
-		}
-		return nil
-	})
-	if err != nil {
-		return time.Now(), errors.Wrap(err, "failed to readdir")
-	}
-	return mtime, nil
-}
-
+// hasChangedGitRepos returns true if the repository must be installed again.
+// If both the previous build and current worktree are dirty, the content
+// hashes of the worktree files are compared with the installed ones.
 func (*copyBuilder) hasChangedGitRepos(repos *lockjson.Repos, buildRepos *buildinfo.Repos, isDirty bool) bool {
 	if buildRepos == nil { // Full build
 		return true
@@ -338,43 +331,59 @@ func (*copyBuilder) hasChangedGitRepos(repos *lockjson.Repos, buildRepos *buildi
 	if repos.Version != buildRepos.Version {
 		return true
 	}
-	if buildRepos.DirtyWorktree || isDirty {
+	if !pathutil.Exists(repos.Path.EncodeToPlugDirName()) {
+		return true
+	}
+	if !buildRepos.DirtyWorktree && !isDirty {
+		return false
+	}
+	if !buildRepos.DirtyWorktree || !isDirty || len(buildRepos.Files) == 0 {
 		return true
 	}
-	return false
+	files, err := worktreeFiles(repos.Path.FullPath())
+	return err != nil || !files.Equals(buildRepos.Files)
 }
 
-// Remove ~/.vim/volt/opt/{repos} and copy from ~/volt/repos/{repos}
-func (builder *copyBuilder) updateGitRepos(repos *lockjson.Repos, r *git.Repository, copyFromGitObjects bool, vimExePath string, done chan actionReposResult) {
+// Update ~/.vim/volt/opt/{repos} from ~/volt/repos/{repos}.
+// If files are copied from git objects and oldFiles (the files installed by
+// the previous build) is known, only added, changed, and removed files are
+// updated. Otherwise ~/.vim/volt/opt/{repos} is removed and all files are
+// copied.
+func (builder *copyBuilder) updateGitRepos(ctx context.Context, repos *lockjson.Repos, r *git.Repository, copyFromGitObjects bool, oldFiles buildinfo.FileMap, done chan actionReposResult) {
 	src := repos.Path.FullPath()
 	dst := repos.Path.EncodeToPlugDirName()
+	log := logger.NewReposBuffer(repos.Path.String())
 
 	// Remove ~/.vim/volt/opt/{repos}
-	// TODO: Do not remove here, copy newer files only after
-	err := os.RemoveAll(dst)
-	if err != nil {
-		done <- actionReposResult{
-			err:   errors.Wrap(err, "failed to remove repository"),
-			repos: repos,
+	if !copyFromGitObjects || !builder.canSyncFiles(dst, oldFiles) {
+		oldFiles = nil
+		err := transaction.RemoveAll(dst)
+		if err != nil {
+			done <- actionReposResult{
+				log:   log,
+				err:   errors.Wrap(err, "failed to remove repository"),
+				repos: repos,
+			}
+			return
 		}
-		return
 	}
 
 	if copyFromGitObjects {
-		logger.Debug("Copy from git objects: " + repos.Path)
-		builder.updateBareGitRepos(r, src, dst, repos, vimExePath, done)
+		log.Debug("Copy from git objects: " + repos.Path)
+		builder.updateBareGitRepos(ctx, r, src, dst, repos, oldFiles, log, done)
 	} else {
-		logger.Debug("Copy from filesystem: " + repos.Path)
-		builder.updateNonBareGitRepos(r, src, dst, repos, vimExePath, done)
+		log.Debug("Copy from filesystem: " + repos.Path)
+		builder.updateNonBareGitRepos(ctx, r, src, dst, repos, log, done)
 	}
 }
 
-func (builder *copyBuilder) updateBareGitRepos(r *git.Repository, src, dst string, repos *lockjson.Repos, vimExePath string, done chan actionReposResult) {
+func (builder *copyBuilder) updateBareGitRepos(ctx context.Context, r *git.Repository, src, dst string, repos *lockjson.Repos, oldFiles buildinfo.FileMap, log *logger.Buffer, done chan actionReposResult) {
 	// Get locked commit hash
 	commit := plumbing.NewHash(repos.Version)
 	commitObj, err := r.CommitObject(commit)
 	if err != nil {
 		done <- actionReposResult{
+			log:   log,
 			err:   errors.Wrap(err, "failed to get HEAD commit object"),
 			repos: repos,
 		}
@@ -385,44 +394,98 @@ func (builder *copyBuilder) updateBareGitRepos(r *git.Repository, src, dst strin
 	tree, err := r.TreeObject(commitObj.TreeHash)
 	if err != nil {
 		done <- actionReposResult{
+			log:   log,
 			err:   errors.Wrap(err, "failed to get tree "+commit.String()),
 			repos: repos,
 		}
 		return
 	}
 
-	// Copy files
+	// Collect files, and remove the files of the previous build which are
+	// no longer in the tree
 	files := make(buildinfo.FileMap, 512)
+	var treeFiles []*object.File
 	err = tree.Files().ForEach(func(file *object.File) error {
+		files[file.Name] = file.Hash.String() // blob hash
+		treeFiles = append(treeFiles, file)
+		return nil
+	})
+	if err == nil {
+		err = removeStaleFiles(dst, oldFiles, files)
+	}
+
+	// Copy files
+	kept := 0
+	copyFile := func(file *object.File) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		osMode, err := file.Mode.ToOSFileMode()
 		if err != nil {
 			return errors.Wrap(err, "failed to convert file mode")
 		}
 
+		// Skip unchanged files
+		filename := filepath.Join(dst, file.Name)
+		if oldFiles[file.Name] == files[file.Name] && isInstalledFile(filename, osMode) {
+			kept++
+			return nil
+		}
+		// Remove the file of the previous build because it may be a hard
+		// link to the object store
+		os.Remove(filename)
+		os.MkdirAll(filepath.Dir(filename), 0755)
+
+		// Link to the object in the store. doc/tags is not linked because
+		// ":helptags" overwrites it
+		if osMode.IsRegular() && file.Name != "doc/tags" &&
+			builder.store.link(filename, file, osMode, commitObj.Committer.When, log) {
+			return nil
+		}
+
 		contents, err := file.Contents()
 		if err != nil {
 			return errors.Wrap(err, "failed to get file contents")
 		}
-
-		filename := filepath.Join(dst, file.Name)
-		os.MkdirAll(filepath.Dir(filename), 0755)
-		ioutil.WriteFile(filename, []byte(contents), osMode)
-
-		files[file.Name] = file.Hash.String() // blob hash
+		if err := ioutil.WriteFile(filename, []byte(contents), osMode); err != nil {
+			return errors.Wrap(err, "failed to write file")
+		}
+		// Preserve mode bits (ioutil.WriteFile() is affected by umask), and
+		// set modification time to the committed time because git objects
+		// do not have it
+		if err := os.Chmod(filename, osMode.Perm()); err != nil {
+			return errors.Wrap(err, "failed to change file mode")
+		}
+		if err := fileutil.SetModTime(filename, commitObj.Committer.When); err != nil {
+			return errors.Wrap(err, "failed to change modification time")
+		}
 		return nil
-	})
+	}
+	for i := 0; err == nil && i < len(treeFiles); i++ {
+		err = copyFile(treeFiles[i])
+	}
+	if err == nil && kept > 0 {
+		log.Debugf("%s: %d of %d files are not changed", repos.Path, kept, len(treeFiles))
+	}
+	var dirty bool
+	if err == nil {
+		dirty, err = builder.copySubmodules(ctx, tree, src, dst, repos, log)
+	}
 	if err != nil {
 		done <- actionReposResult{
+			log:   log,
 			err:   err,
 			repos: repos,
 		}
 		return
 	}
 
-	// Run ":helptags" to generate tags file
-	err = builder.helptags(repos.Path, vimExePath)
+	// Concatenate plugin/*.vim files
+	err = builder.tryConcatPluginScripts(repos.Path, log)
 	if err != nil {
 		done <- actionReposResult{
+			log:   log,
 			err:   err,
 			repos: repos,
 		}
@@ -430,34 +493,76 @@ func (builder *copyBuilder) updateBareGitRepos(r *git.Repository, src, dst strin
 	}
 
 	done <- actionReposResult{
+		log:   log,
 		err:   nil,
 		repos: repos,
 		files: files,
+		dirty: dirty,
 	}
 }
 
 // BuildModeInvalidType is invalid types of files which copy builder cannot handle.
 var BuildModeInvalidType = os.ModeSymlink | os.ModeNamedPipe | os.ModeSocket | os.ModeDevice
 
-func (builder *copyBuilder) updateNonBareGitRepos(r *git.Repository, src, dst string, repos *lockjson.Repos, vimExePath string, done chan actionReposResult) {
-	files, err := ioutil.ReadDir(src)
+func (builder *copyBuilder) updateNonBareGitRepos(ctx context.Context, r *git.Repository, src, dst string, repos *lockjson.Repos, log *logger.Buffer, done chan actionReposResult) {
+	err := builder.copyWorktree(ctx, src, dst, repos, log)
 	if err != nil {
 		done <- actionReposResult{
+			log:   log,
 			err:   err,
 			repos: repos,
 		}
 		return
 	}
 
+	// Concatenate plugin/*.vim files
+	err = builder.tryConcatPluginScripts(repos.Path, log)
+	if err != nil {
+		done <- actionReposResult{
+			log:   log,
+			err:   err,
+			repos: repos,
+		}
+		return
+	}
+
+	// The content hashes of the worktree files are compared at next build
+	// to skip installing the same worktree
+	files, err := worktreeFiles(src)
+	if err != nil {
+		log.Debugf("%s: could not get the content hashes of the worktree: %s", repos.Path, err)
+		files = nil
+	}
+	done <- actionReposResult{
+		log:   log,
+		err:   nil,
+		repos: repos,
+		files: files,
+		dirty: true,
+	}
+}
+
+// copyWorktree copies the files of the worktree src to dst except ".git" and
+// ".gitignore".
+func (builder *copyBuilder) copyWorktree(ctx context.Context, src, dst string, repos *lockjson.Repos, log *logger.Buffer) error {
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
 	buf := make([]byte, 32*1024)
 	created := make(map[string]bool, len(files))
 	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		// Skip ".git" and ".gitignore"
 		if file.Name() == ".git" || file.Name() == ".gitignore" {
 			continue
 		}
 		if file.Mode()&BuildModeInvalidType != 0 {
 			// Currenly skip the invalid files...
+			log.Warnf("%s: skipped %s (symlinks and special files are not installed)", repos.Path, file.Name())
 			continue
 		}
 		if !created[dst] {
@@ -468,112 +573,163 @@ func (builder *copyBuilder) updateNonBareGitRepos(r *git.Repository, src, dst st
 		to := filepath.Join(dst, file.Name())
 		var err error
 		if file.IsDir() {
-			err = fileutil.TryLinkDir(from, to, buf, file.Mode(), BuildModeInvalidType)
+			err = fileutil.TryLinkDirParallel(ctx, from, to, file.Mode(), BuildModeInvalidType, 0, builder.copyProgress(repos, log))
 		} else {
 			err = fileutil.TryLinkFile(from, to, buf, file.Mode())
 		}
 		if err != nil {
-			done <- actionReposResult{
-				err:   err,
-				repos: repos,
-			}
-			return
+			return err
 		}
 	}
+	return nil
+}
 
-	// Run ":helptags" to generate tags file
-	err = builder.helptags(repos.Path, vimExePath)
-	if err != nil {
-		done <- actionReposResult{
-			err:   err,
-			repos: repos,
+// copySubmodules copies the submodules in tree from the worktree src to dst,
+// because their files are not in the git objects of the repository. The
+// submodules which are not checked out are skipped with a warning, and
+// skipped is true then.
+func (builder *copyBuilder) copySubmodules(ctx context.Context, tree *object.Tree, src, dst string, repos *lockjson.Repos, log *logger.Buffer) (skipped bool, err error) {
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			return skipped, nil
+		} else if err != nil {
+			return false, err
+		}
+		if entry.Mode != filemode.Submodule {
+			continue
+		}
+		from := filepath.Join(src, filepath.FromSlash(name))
+		if !pathutil.Exists(filepath.Join(from, ".git")) {
+			log.Warnf("%s: skipped submodule %s (it is not checked out, run 'volt get -u %s')", repos.Path, name, repos.Path)
+			skipped = true
+			continue
+		}
+		if sub, err := git.PlainOpen(from); err == nil {
+			if head, err := gitutil.GetHEADRepository(sub); err == nil && head != entry.Hash.String() {
+				log.Warnf("%s: submodule %s is not at the recorded revision %s, installed %s", repos.Path, name, entry.Hash, head)
+			}
+		}
+		// Remove the submodule of the previous build not to overwrite the
+		// files linked to the worktree
+		to := filepath.Join(dst, filepath.FromSlash(name))
+		if err := transaction.RemoveAll(to); err != nil {
+			return false, err
+		}
+		if err := builder.copyWorktree(ctx, from, to, repos, log); err != nil {
+			return false, errors.Wrap(err, "failed to copy submodule "+name)
 		}
-		return
 	}
+}
 
-	done <- actionReposResult{
-		err:   nil,
-		repos: repos,
-		files: nil, // all files are overwritten next time even when timestamp is older
+// copyProgress returns a progress callback which shows copied files of repos
+// in debug log.
+func (*copyBuilder) copyProgress(repos *lockjson.Repos, log *logger.Buffer) fileutil.ProgressFunc {
+	return func(path string, done, total int) {
+		log.Debugf("Copying %s files ... (%d/%d)", repos.Path, done, total)
 	}
 }
 
-func (builder *copyBuilder) hasChangedStaticRepos(repos *lockjson.Repos, buildRepos *buildinfo.Repos, optDir string) bool {
-	if buildRepos == nil { // Full build
-		return true
+// tryConcatPluginScripts concatenates plugin/*.vim files of reposPath under
+// ~/.vim/pack/volt/opt if build.concat_plugin_scripts is true.
+func (builder *copyBuilder) tryConcatPluginScripts(reposPath pathutil.ReposPath, log *logger.Buffer) error {
+	if !builder.concatPluginScripts {
+		return nil
 	}
-
-	src := repos.Path.FullPath()
-
-	// Get latest mtime of src
-	// TODO: Don't check mtime here, do it when copy altogether
-	srcModTime, err := builder.getLatestModTime(src)
+	n, reason, err := concatPluginScripts(reposPath.EncodeToPlugDirName())
 	if err != nil {
-		// failed to readdir, do copy again
-		return true
+		return errors.Wrap(err, "failed to concatenate plugin scripts")
+	}
+	if reason != "" {
+		log.Debugf("Skip concatenating plugin scripts of %s: %s", reposPath, reason)
+	} else if n > 0 {
+		log.Debugf("Concatenated %d plugin scripts of %s", n, reposPath)
 	}
+	return nil
+}
 
-	if buildRepos.Version == "" {
-		// not found mtime, do copy again
+// hasChangedStaticRepos returns true if the content hashes of the files of
+// the repository are not the same as the installed ones.
+func (*copyBuilder) hasChangedStaticRepos(repos *lockjson.Repos, buildRepos *buildinfo.Repos) bool {
+	if buildRepos == nil || len(buildRepos.Files) == 0 { // Full build
 		return true
 	}
-
-	// Get latest mtime of dst from build-info.json
-	dstModTime, err := time.Parse(time.RFC3339, buildRepos.Version)
-	if err != nil {
-		// failed to parse datetime, do copy again
+	if !pathutil.Exists(repos.Path.EncodeToPlugDirName()) {
 		return true
 	}
-
-	return dstModTime.Before(srcModTime)
+	files, err := staticFiles(repos.Path.FullPath())
+	return err != nil || !files.Equals(buildRepos.Files)
 }
 
-// Remove ~/.vim/volt/opt/{repos} and copy from ~/volt/repos/{repos}
-func (builder *copyBuilder) updateStaticRepos(repos *lockjson.Repos, vimExePath string, done chan actionReposResult) {
+// Update ~/.vim/volt/opt/{repos} from ~/volt/repos/{repos}.
+// If oldFiles (the files installed by the previous build) is known, only
+// added, changed, and removed files are updated. Otherwise
+// ~/.vim/volt/opt/{repos} is removed and all files are copied.
+func (builder *copyBuilder) updateStaticRepos(ctx context.Context, repos *lockjson.Repos, oldFiles buildinfo.FileMap, done chan actionReposResult) {
 	src := repos.Path.FullPath()
 	dst := repos.Path.EncodeToPlugDirName()
+	log := logger.NewReposBuffer(repos.Path.String())
 
-	// Remove ~/.vim/volt/opt/{repos}
-	// TODO: Do not remove here, copy newer files only after
-	err := os.RemoveAll(dst)
+	si, err := os.Stat(src)
 	if err != nil {
 		done <- actionReposResult{
-			err:   errors.Wrap(err, "failed to remove repository"),
+			log:   log,
+			err:   errors.Wrap(err, "failed to copy static directory"),
 			repos: repos,
 		}
 		return
 	}
-
-	// Copy ~/volt/repos/{repos} to ~/.vim/volt/opt/{repos}
-	buf := make([]byte, 32*1024)
-	si, err := os.Stat(src)
-	if err != nil {
+	if !si.IsDir() {
 		done <- actionReposResult{
-			err:   errors.Wrap(err, "failed to copy static directory"),
+			log:   log,
+			err:   errors.New("failed to copy static directory: source is not a directory"),
 			repos: repos,
 		}
 		return
 	}
-	if !si.IsDir() {
+	files, err := staticFiles(src)
+	if err != nil {
 		done <- actionReposResult{
-			err:   errors.New("failed to copy static directory: source is not a directory"),
+			log:   log,
+			err:   errors.Wrap(err, "failed to copy static directory"),
 			repos: repos,
 		}
 		return
 	}
-	err = fileutil.TryLinkDir(src, dst, buf, si.Mode(), BuildModeInvalidType)
+
+	if builder.canSyncFiles(dst, oldFiles) {
+		// Copy only changed files
+		err = builder.syncStaticFiles(ctx, src, dst, oldFiles, files, repos, log)
+	} else {
+		// Remove ~/.vim/volt/opt/{repos}
+		err = transaction.RemoveAll(dst)
+		if err != nil {
+			done <- actionReposResult{
+				log:   log,
+				err:   errors.Wrap(err, "failed to remove repository"),
+				repos: repos,
+			}
+			return
+		}
+		// Copy ~/volt/repos/{repos} to ~/.vim/volt/opt/{repos}
+		err = fileutil.TryLinkDirParallel(ctx, src, dst, si.Mode(), BuildModeInvalidType, 0, builder.copyProgress(repos, log))
+	}
 	if err != nil {
 		done <- actionReposResult{
+			log:   log,
 			err:   errors.Wrap(err, "failed to copy static directory"),
 			repos: repos,
 		}
 		return
 	}
 
-	// Run ":helptags" to generate tags file
-	err = builder.helptags(repos.Path, vimExePath)
+	// Concatenate plugin/*.vim files
+	err = builder.tryConcatPluginScripts(repos.Path, log)
 	if err != nil {
 		done <- actionReposResult{
+			log:   log,
 			err:   err,
 			repos: repos,
 		}
@@ -581,7 +737,134 @@ func (builder *copyBuilder) updateStaticRepos(repos *lockjson.Repos, vimExePath
 	}
 
 	done <- actionReposResult{
+		log:   log,
 		err:   nil,
 		repos: repos,
+		files: files,
+	}
+}
+
+// staticFiles returns the files under src except symlinks and special files.
+// The value of FileMap is the content hash of the file (the same as the blob
+// hash of git), which is compared at next build to detect changed files.
+func staticFiles(src string) (buildinfo.FileMap, error) {
+	return contentHashes(src, nil)
+}
+
+// worktreeFiles returns the files of the worktree src like staticFiles()
+// except ".git" and ".gitignore", which are not installed.
+func worktreeFiles(src string) (buildinfo.FileMap, error) {
+	return contentHashes(src, map[string]bool{".git": true, ".gitignore": true})
+}
+
+func contentHashes(src string, skip map[string]bool) (buildinfo.FileMap, error) {
+	files := make(buildinfo.FileMap, 64)
+	err := filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if skip[rel] {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if fi.Mode()&BuildModeInvalidType != 0 || fi.IsDir() {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = plumbing.ComputeHash(plumbing.BlobObject, content).String()
+		return nil
+	})
+	return files, err
+}
+
+// syncStaticFiles links or copies the files of src which are not the same as
+// oldFiles to dst, and removes the files of oldFiles which are no longer in
+// files.
+func (builder *copyBuilder) syncStaticFiles(ctx context.Context, src, dst string, oldFiles, files buildinfo.FileMap, repos *lockjson.Repos, log *logger.Buffer) error {
+	if err := removeStaleFiles(dst, oldFiles, files); err != nil {
+		return err
+	}
+	buf := make([]byte, 32*1024)
+	kept := 0
+	for name, version := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		from := filepath.Join(src, filepath.FromSlash(name))
+		to := filepath.Join(dst, filepath.FromSlash(name))
+		fi, err := os.Lstat(from)
+		if err != nil {
+			return err
+		}
+		if oldFiles[name] == version && isInstalledFile(to, fi.Mode()) {
+			kept++
+			continue
+		}
+		os.Remove(to)
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return err
+		}
+		if err := fileutil.TryLinkFile(from, to, buf, fi.Mode()); err != nil {
+			return err
+		}
+	}
+	log.Debugf("%s: %d of %d files are not changed", repos.Path, kept, len(files))
+	return nil
+}
+
+// canSyncFiles returns true if dst can be updated incrementally from
+// oldFiles. It is false if oldFiles is unknown (e.g. full build, or the
+// previous build installed a dirty worktree), or if plugin scripts are
+// concatenated because the installed files differ from the repository.
+func (builder *copyBuilder) canSyncFiles(dst string, oldFiles buildinfo.FileMap) bool {
+	return len(oldFiles) > 0 && !builder.concatPluginScripts && pathutil.Exists(dst)
+}
+
+// isInstalledFile returns true if path is a regular file which has the
+// permission of mode.
+func isInstalledFile(path string, mode os.FileMode) bool {
+	fi, err := os.Lstat(path)
+	return err == nil && fi.Mode().IsRegular() && fi.Mode().Perm() == mode.Perm()
+}
+
+// removeStaleFiles removes the files of oldFiles which are not in files from
+// dst, and their parent directories under dst if they become empty.
+// doc/tags generated by ":helptags" is also removed if help files were
+// removed, because it is not re-generated if doc directory is removed.
+func removeStaleFiles(dst string, oldFiles, files buildinfo.FileMap) error {
+	stale := make([]string, 0, len(oldFiles))
+	for name := range oldFiles {
+		if _, exists := files[name]; !exists {
+			stale = append(stale, name)
+		}
+	}
+	if _, exists := files["doc/tags"]; !exists {
+		for _, name := range stale {
+			if strings.HasPrefix(name, "doc/") {
+				stale = append(stale, "doc/tags")
+				break
+			}
+		}
 	}
+	for _, name := range stale {
+		path := filepath.Join(dst, filepath.FromSlash(name))
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for dir := filepath.Dir(path); dir != dst && strings.HasPrefix(dir, dst); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return nil
 }
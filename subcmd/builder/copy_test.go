@@ -0,0 +1,183 @@
+package builder
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/buildinfo"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestCopySubmodules(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-copy-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// "sub" is checked out, "missing" is not
+	src := filepath.Join(tempDir, "src")
+	os.MkdirAll(filepath.Join(src, "sub", "autoload"), 0755)
+	os.MkdirAll(filepath.Join(src, "missing"), 0755)
+	for name, content := range map[string]string{
+		"sub/.git":             "gitdir: ../.git/modules/sub\n",
+		"sub/autoload/sub.vim": "sub",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(src, filepath.FromSlash(name)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tree := &object.Tree{Entries: []object.TreeEntry{
+		{Name: "missing", Mode: filemode.Submodule, Hash: plumbing.NewHash("1111111111111111111111111111111111111111")},
+		{Name: "sub", Mode: filemode.Submodule, Hash: plumbing.NewHash("2222222222222222222222222222222222222222")},
+	}}
+
+	dst := filepath.Join(tempDir, "dst")
+	repos := &lockjson.Repos{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim"}
+	skipped, err := (&copyBuilder{}).copySubmodules(context.Background(), tree, src, dst, repos, logger.NewBuffer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !skipped {
+		t.Error("expected skipped is true but got false")
+	}
+	if !pathutil.Exists(filepath.Join(dst, "sub", "autoload", "sub.vim")) {
+		t.Error("sub/autoload/sub.vim was not copied")
+	}
+	for _, name := range []string{"sub/.git", "missing"} {
+		if pathutil.Exists(filepath.Join(dst, filepath.FromSlash(name))) {
+			t.Errorf("%s should not be copied", name)
+		}
+	}
+}
+
+func TestSyncStaticFiles(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-copy-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	src := filepath.Join(tempDir, "src")
+	dst := filepath.Join(tempDir, "dst")
+	write := func(name, content string) {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		// Create a new file not to change the linked file in dst
+		os.Remove(path)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sync := func(oldFiles buildinfo.FileMap) buildinfo.FileMap {
+		files, err := staticFiles(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repos := &lockjson.Repos{Type: lockjson.ReposStaticType, Path: "localhost/local/foo"}
+		if err := (&copyBuilder{}).syncStaticFiles(context.Background(), src, dst, oldFiles, files, repos, logger.NewBuffer()); err != nil {
+			t.Fatal(err)
+		}
+		return files
+	}
+
+	write("plugin/foo.vim", "foo")
+	write("autoload/foo.vim", "foo")
+	write("doc/foo.txt", "foo")
+	oldFiles := sync(nil)
+	unchanged, err := os.Stat(filepath.Join(dst, "doc", "foo.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Change autoload/foo.vim, remove plugin/foo.vim, and add plugin/foo
+	write("autoload/foo.vim", "changed")
+	os.RemoveAll(filepath.Join(src, "plugin"))
+	write("plugin", "now a file")
+	sync(oldFiles)
+
+	for name, content := range map[string]string{
+		"autoload/foo.vim": "changed",
+		"doc/foo.txt":      "foo",
+		"plugin":           "now a file",
+	} {
+		if b, err := ioutil.ReadFile(filepath.Join(dst, filepath.FromSlash(name))); err != nil || string(b) != content {
+			t.Errorf("%s: expected %q but got (%q, %v)", name, content, string(b), err)
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(dst, "doc", "foo.txt")); err != nil || !os.SameFile(fi, unchanged) {
+		t.Error("expected doc/foo.txt is not installed again")
+	}
+}
+
+func TestHasChangedStaticRepos(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-copy-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", tempDir)
+
+	repos := &lockjson.Repos{Type: lockjson.ReposStaticType, Path: "localhost/local/foo"}
+	src := repos.Path.FullPath()
+	path := filepath.Join(src, "plugin", "foo.vim")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := staticFiles(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(repos.Path.EncodeToPlugDirName(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	buildRepos := &buildinfo.Repos{Type: repos.Type, Path: repos.Path, Files: files}
+	builder := &copyBuilder{}
+
+	// Touching a file does not change its content hash
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if builder.hasChangedStaticRepos(repos, buildRepos) {
+		t.Error("expected touched repository is not changed")
+	}
+
+	// Changing the content with an older mtime is detected
+	if err := ioutil.WriteFile(path, []byte("bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Unix(0, 0)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if !builder.hasChangedStaticRepos(repos, buildRepos) {
+		t.Error("expected changed repository is detected")
+	}
+
+	// Removed installation is detected
+	files, err = staticFiles(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buildRepos.Files = files
+	if err := os.RemoveAll(repos.Path.EncodeToPlugDirName()); err != nil {
+		t.Fatal(err)
+	}
+	if !builder.hasChangedStaticRepos(repos, buildRepos) {
+		t.Error("expected removed installation is detected")
+	}
+}
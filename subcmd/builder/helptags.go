@@ -0,0 +1,160 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// helpTag is a "*tag*" defined in a help file.
+type helpTag struct {
+	name string
+	// file is the path of the help file relative to doc directory
+	file string
+}
+
+// makeHelptags generates tags files of docdir like ":helptags" of Vim, which
+// is used if no vim executable was found.
+// The tags of "*.txt" files are written to "tags", and the tags of "*.{lang}x"
+// files (e.g. "*.jax") are written to "tags-{lang}".
+// The tags files are written even if the documents have errors (e.g.
+// duplicate tags), and the errors are returned as docErrs.
+func makeHelptags(docdir string) (docErrs []string, err error) {
+	// language -> help files ("en" is "*.txt")
+	langFiles := make(map[string][]string)
+	err = filepath.Walk(docdir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		if lang := helpLangOf(fi.Name()); lang != "" {
+			rel, err := filepath.Rel(docdir, path)
+			if err != nil {
+				return err
+			}
+			langFiles[lang] = append(langFiles[lang], filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for lang, files := range langFiles {
+		tagsFile := "tags"
+		if lang != "en" {
+			tagsFile += "-" + lang
+		}
+		errs, err := writeHelptags(docdir, files, filepath.Join(docdir, tagsFile))
+		if err != nil {
+			return nil, err
+		}
+		docErrs = append(docErrs, errs...)
+	}
+	sort.Strings(docErrs)
+	return docErrs, nil
+}
+
+// helpLangOf returns the language of help file name ("en" for "*.txt",
+// "ja" for "*.jax"), or "" if name is not a help file.
+func helpLangOf(name string) string {
+	ext := filepath.Ext(name)
+	if strings.EqualFold(ext, ".txt") {
+		return "en"
+	}
+	if len(ext) == 4 && (ext[3] == 'x' || ext[3] == 'X') {
+		return strings.ToLower(ext[1:3])
+	}
+	return ""
+}
+
+// writeHelptags writes the tags of files to tagsPath in the format of
+// ":helptags", and returns duplicate tags as errors.
+func writeHelptags(docdir string, files []string, tagsPath string) ([]string, error) {
+	var tags []helpTag
+	utf8Encoding := false
+	for _, file := range files {
+		content, err := ioutil.ReadFile(filepath.Join(docdir, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		// Vim detects the encoding by the first line
+		firstLine := content
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			firstLine = content[:i]
+		}
+		if !isASCII(firstLine) && utf8.Valid(firstLine) {
+			utf8Encoding = true
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+		for scanner.Scan() {
+			for _, name := range parseHelpTags(scanner.Text()) {
+				tags = append(tags, helpTag{name: name, file: file})
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, errors.Wrap(err, "could not read "+file)
+		}
+	}
+
+	// Vim sorts "{tag}\t{file}" lines in byte order
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].name+"\t"+tags[i].file < tags[j].name+"\t"+tags[j].file
+	})
+	var docErrs []string
+	var buf bytes.Buffer
+	if utf8Encoding {
+		buf.WriteString("!_TAG_FILE_ENCODING\tutf-8\t//\n")
+	}
+	for i := range tags {
+		if i > 0 && tags[i].name == tags[i-1].name {
+			docErrs = append(docErrs, fmt.Sprintf("E154: Duplicate tag \"%s\" in file %s", tags[i].name, tags[i].file))
+		}
+		pattern := strings.NewReplacer(`\`, `\\`, `/`, `\/`).Replace(tags[i].name)
+		fmt.Fprintf(&buf, "%s\t%s\t/*%s*\n", tags[i].name, tags[i].file, pattern)
+	}
+	return docErrs, ioutil.WriteFile(tagsPath, buf.Bytes(), 0644)
+}
+
+// parseHelpTags returns "*tag*" in line. Like Vim, a tag must not have white
+// spaces and "|", must be at the start of line or after a white space, and
+// must be followed by a white space or the end of line.
+func parseHelpTags(line string) []string {
+	var tags []string
+	p1 := strings.IndexByte(line, '*')
+	for p1 >= 0 {
+		n := strings.IndexByte(line[p1+1:], '*')
+		if n < 0 {
+			break
+		}
+		p2 := p1 + 1 + n
+		name := line[p1+1 : p2]
+		if name != "" && !strings.ContainsAny(name, " \t|") &&
+			(p1 == 0 || line[p1-1] == ' ' || line[p1-1] == '\t') &&
+			(p2+1 == len(line) || strings.IndexByte(" \t\r", line[p2+1]) >= 0) {
+			tags = append(tags, name)
+		}
+		p1 = p2
+	}
+	return tags
+}
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
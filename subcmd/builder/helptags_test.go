@@ -0,0 +1,108 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseHelpTags(t *testing.T) {
+	for _, tt := range []struct {
+		line     string
+		expected []string
+	}{
+		{"*foo.txt*  Foo plugin", []string{"foo.txt"}},
+		{"  *foo* *bar*", []string{"foo", "bar"}},
+		{"*g:foo_bar*\t*:FooBar*", []string{"g:foo_bar", ":FooBar"}},
+		{"a*b* is not a tag", nil},
+		{"*foo*bar is not a tag", nil},
+		{"*foo bar* *** ** *", nil},
+		{"*a|b* has a bar", nil},
+		{"the end *foo*", []string{"foo"}},
+		{"*foo/bar\\baz*", []string{"foo/bar\\baz"}},
+	} {
+		if got := parseHelpTags(tt.line); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("parseHelpTags(%q): expected %q but got %q", tt.line, tt.expected, got)
+		}
+	}
+}
+
+func TestMakeHelptags(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-make-helptags-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	writeDocs := func(docdir string) {
+		os.MkdirAll(filepath.Join(docdir, "sub"), 0755)
+		for name, content := range map[string]string{
+			"foo.txt":     "*foo.txt*  Foo plugin\n\n*foo* *:Foo*\n  *foo-usage*\n",
+			"sub/bar.txt": "*bar.txt*\n*foo/bar*\n",
+			"foo.jax":     "*foo.txt*  日本語\n*foo*\n",
+			"README.md":   "*readme*\n",
+		} {
+			if err := ioutil.WriteFile(filepath.Join(docdir, filepath.FromSlash(name)), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	docdir := filepath.Join(tempDir, "go", "doc")
+	writeDocs(docdir)
+	docErrs, err := makeHelptags(docdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docErrs) != 0 {
+		t.Errorf("unexpected errors in the documents: %q", docErrs)
+	}
+	expected := map[string]string{
+		"tags": ":Foo\tfoo.txt\t/*:Foo*\n" +
+			"bar.txt\tsub/bar.txt\t/*bar.txt*\n" +
+			"foo\tfoo.txt\t/*foo*\n" +
+			"foo-usage\tfoo.txt\t/*foo-usage*\n" +
+			"foo.txt\tfoo.txt\t/*foo.txt*\n" +
+			"foo/bar\tsub/bar.txt\t/*foo\\/bar*\n",
+		"tags-ja": "!_TAG_FILE_ENCODING\tutf-8\t//\n" +
+			"foo\tfoo.jax\t/*foo*\n" +
+			"foo.txt\tfoo.jax\t/*foo.txt*\n",
+	}
+	for name, content := range expected {
+		if got, _ := ioutil.ReadFile(filepath.Join(docdir, name)); string(got) != content {
+			t.Errorf("%s: expected %q but got %q", name, content, got)
+		}
+	}
+
+	// The tags file is the same as the one generated by Vim
+	if vim, err := exec.LookPath("vim"); err == nil {
+		vimDocdir := filepath.Join(tempDir, "vim", "doc")
+		writeDocs(vimDocdir)
+		cmd := exec.Command(vim, "-u", "NONE", "-i", "NONE", "-N", "-es", "--cmd", "helptags doc", "--cmd", "quit")
+		cmd.Dir = filepath.Dir(vimDocdir)
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		for name := range expected {
+			want, _ := ioutil.ReadFile(filepath.Join(vimDocdir, name))
+			if got, _ := ioutil.ReadFile(filepath.Join(docdir, name)); string(got) != string(want) {
+				t.Errorf("%s: expected the same as vim %q but got %q", name, want, got)
+			}
+		}
+	}
+
+	// Duplicate tags are errors in the documents, but the tags file is written
+	ioutil.WriteFile(filepath.Join(docdir, "dup.txt"), []byte("*foo*\n"), 0644)
+	docErrs, err = makeHelptags(docdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docErrs) != 1 {
+		t.Errorf("expected an error of duplicate tag but got %q", docErrs)
+	}
+	if _, err := os.Stat(filepath.Join(docdir, "tags")); err != nil {
+		t.Errorf("tags file was not written: %s", err)
+	}
+}
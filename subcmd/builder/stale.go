@@ -0,0 +1,133 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/buildinfo"
+)
+
+// StaleBuild is the differences between build-info.json and the repositories
+// which "volt build" installs.
+type StaleBuild struct {
+	// All is the reason why all repositories must be installed again (e.g.
+	// build.strategy was changed), or an empty string
+	All string
+	// Repos is the list of repositories whose installed files are stale
+	Repos []StaleRepos
+}
+
+// StaleRepos is a repository whose installed files are stale.
+type StaleRepos struct {
+	Path   pathutil.ReposPath
+	Reason string
+}
+
+// IsZero returns true if the build is not stale.
+func (stale *StaleBuild) IsZero() bool {
+	return stale.All == "" && len(stale.Repos) == 0
+}
+
+// CheckStale compares build-info.json with the repositories which
+// "volt build" installs for lockJSON and cfg.
+func CheckStale(lockJSON *lockjson.LockJSON, cfg *config.Config) (*StaleBuild, error) {
+	buildInfo, err := buildinfo.Read()
+	if err != nil {
+		return nil, err
+	}
+	base := newBaseBuilder(cfg)
+	reposList, err := base.reposListToInstall(lockJSON)
+	if err != nil {
+		return nil, err
+	}
+	return checkStale(buildInfo, reposList, cfg.Build.Strategy), nil
+}
+
+func checkStale(buildInfo *buildinfo.BuildInfo, reposList lockjson.ReposList, strategy string) *StaleBuild {
+	stale := &StaleBuild{}
+	switch {
+	case buildInfo.Version == 0:
+		stale.All = "not built yet"
+		return stale
+	case buildInfo.Version != currentBuildInfoVersion:
+		stale.All = "built by another version of volt"
+	case buildInfo.Strategy != strategy:
+		stale.All = fmt.Sprintf("built with %q strategy but build.strategy is %q", buildInfo.Strategy, strategy)
+	}
+
+	for i := range reposList {
+		repos := &reposList[i]
+		built := buildInfo.Repos.FindByReposPath(repos.Path)
+		var reason string
+		switch {
+		case built == nil:
+			reason = "not installed"
+		case built.Type != repos.Type:
+			reason = fmt.Sprintf("installed as %s repository but it is %s repository", built.Type, repos.Type)
+		case repos.Type == lockjson.ReposGitType && built.Version != repos.Version:
+			reason = fmt.Sprintf("installed version is %s but locked version is %s", built.Version, repos.Version)
+		default:
+			continue
+		}
+		stale.Repos = append(stale.Repos, StaleRepos{Path: repos.Path, Reason: reason})
+	}
+	for i := range buildInfo.Repos {
+		if !reposList.Contains(buildInfo.Repos[i].Path) {
+			stale.Repos = append(stale.Repos, StaleRepos{
+				Path:   buildInfo.Repos[i].Path,
+				Reason: "installed but not in the profiles to build",
+			})
+		}
+	}
+	return stale
+}
+
+// DryRun returns what Build(ctx, full) would change if lock.json were
+// lockJSON, without changing any files ("volt -dry-run"). If delta is true,
+// the changes of BuildDelta(ctx) are returned instead.
+func DryRun(lockJSON *lockjson.LockJSON, cfg *config.Config, full, delta bool) ([]string, error) {
+	buildInfo, err := buildinfo.Read()
+	if err != nil {
+		return nil, err
+	}
+	base := newBaseBuilder(cfg)
+	reposList, err := base.reposListToInstall(lockJSON)
+	if err != nil {
+		return nil, err
+	}
+	return dryRun(buildInfo, reposList, cfg.Build.Strategy, full, delta), nil
+}
+
+func dryRun(buildInfo *buildinfo.BuildInfo, reposList lockjson.ReposList, strategy string, full, delta bool) []string {
+	stale := checkStale(buildInfo, reposList, strategy)
+	var result []string
+	switch {
+	case full || stale.All != "" || strategy == config.SymlinkBuilder && !delta:
+		reason := "-full"
+		if stale.All != "" {
+			reason = stale.All
+		} else if !full {
+			reason = `build.strategy is "symlink"`
+		}
+		result = append(result, fmt.Sprintf("Would remove %s and install %d repositories (%s)",
+			pathutil.VimVoltDir(), len(reposList), reason))
+		for i := range reposList {
+			result = append(result, "Would install "+reposList[i].Path.String())
+		}
+	default:
+		for i := range stale.Repos {
+			if reposList.Contains(stale.Repos[i].Path) {
+				result = append(result, fmt.Sprintf("Would install %s (%s)", stale.Repos[i].Path, stale.Repos[i].Reason))
+			} else {
+				result = append(result, "Would uninstall "+stale.Repos[i].Path.String())
+			}
+		}
+		if len(stale.Repos) == 0 {
+			result = append(result, "Would install no repositories (up to date)")
+		}
+	}
+	result = append(result, "Would write "+pathutil.BundledPlugConf()+" and "+pathutil.BuildInfoJSON())
+	return result
+}
@@ -0,0 +1,87 @@
+package builder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/subcmd/buildinfo"
+)
+
+func TestCheckStale(t *testing.T) {
+	reposList := lockjson.ReposList{
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "aaa"},
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/skk.vim", Version: "bbb"},
+		{Type: lockjson.ReposStaticType, Path: "localhost/local/hello"},
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/open-browser.vim", Version: "ccc"},
+	}
+	buildInfo := &buildinfo.BuildInfo{
+		Version:  currentBuildInfoVersion,
+		Strategy: config.SymlinkBuilder,
+		Repos: buildinfo.ReposList{
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "aaa"},
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/skk.vim", Version: "old"},
+			{Type: lockjson.ReposStaticType, Path: "localhost/local/hello"},
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/removed.vim", Version: "ddd"},
+		},
+	}
+
+	stale := checkStale(buildInfo, reposList, config.SymlinkBuilder)
+	expected := &StaleBuild{Repos: []StaleRepos{
+		{Path: "github.com/tyru/skk.vim", Reason: "installed version is old but locked version is bbb"},
+		{Path: "github.com/tyru/open-browser.vim", Reason: "not installed"},
+		{Path: "github.com/tyru/removed.vim", Reason: "installed but not in the profiles to build"},
+	}}
+	if !reflect.DeepEqual(stale, expected) {
+		t.Errorf("expected %+v but got %+v", expected, stale)
+	}
+
+	stale = checkStale(buildInfo, reposList[:1], config.CopyBuilder)
+	if stale.All != `built with "symlink" strategy but build.strategy is "copy"` {
+		t.Errorf("unexpected reason: %q", stale.All)
+	}
+
+	stale = checkStale(&buildinfo.BuildInfo{}, reposList, config.SymlinkBuilder)
+	if stale.All != "not built yet" || len(stale.Repos) != 0 {
+		t.Errorf("unexpected result: %+v", stale)
+	}
+	if stale.IsZero() {
+		t.Error("expected not zero")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	reposList := lockjson.ReposList{
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "aaa"},
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/skk.vim", Version: "bbb"},
+	}
+	buildInfo := &buildinfo.BuildInfo{
+		Version:  currentBuildInfoVersion,
+		Strategy: config.CopyBuilder,
+		Repos: buildinfo.ReposList{
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "aaa"},
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/removed.vim", Version: "ddd"},
+		},
+	}
+
+	got := dryRun(buildInfo, reposList, config.CopyBuilder, false, false)
+	expected := []string{
+		"Would install github.com/tyru/skk.vim (not installed)",
+		"Would uninstall github.com/tyru/removed.vim",
+	}
+	if len(got) != len(expected)+1 || !reflect.DeepEqual(got[:len(expected)], expected) {
+		t.Errorf("expected %q but got %q", expected, got)
+	}
+
+	buildInfo.Strategy = config.SymlinkBuilder
+	if got := dryRun(buildInfo, reposList, config.SymlinkBuilder, false, true); !reflect.DeepEqual(got[:len(expected)], expected) {
+		t.Errorf("expected %q for BuildDelta() but got %q", expected, got)
+	}
+
+	got = dryRun(buildInfo, reposList, config.SymlinkBuilder, true, false)
+	if len(got) != len(reposList)+2 || !strings.HasSuffix(got[0], "(-full)") {
+		t.Errorf("unexpected result of full build: %q", got)
+	}
+}
@@ -0,0 +1,162 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// unusedObjectTTL is the duration to keep objects which are not linked from
+// anywhere. They are kept for a while to be reused when the plugin is
+// enabled again (e.g. switching profiles, or downgrading the plugin).
+const unusedObjectTTL = 30 * 24 * time.Hour
+
+// unusedIndexName is the file in the store which holds the time when each
+// object was found to be unused.
+const unusedIndexName = "unused.json"
+
+// objectStore is a content-addressed store of the files of git repositories.
+// Files installed by copy builder are hard links to the objects, so the same
+// file of multiple repositories, revisions, and profiles is stored only once,
+// and is not extracted from git objects again on rebuild.
+// The object of a blob is "{dir}/{hash[:2]}/{hash[2:]}-{perm}".
+type objectStore struct {
+	dir string
+	// disabled is set to 1 when an object could not be created or linked
+	// (e.g. the store and ~/.vim are on different filesystems)
+	disabled int32
+}
+
+func newObjectStore(dir string) *objectStore {
+	s := &objectStore{dir: dir}
+	if !linkCountSupported {
+		s.disabled = 1
+	}
+	return s
+}
+
+// link creates dst as a hard link to the object of file, and returns true.
+// The object is created from the contents of file if it does not exist.
+// false is returned if the store is not usable, then the caller must write
+// dst by itself.
+func (s *objectStore) link(dst string, file *object.File, perm os.FileMode, mtime time.Time, log *logger.Buffer) bool {
+	if atomic.LoadInt32(&s.disabled) != 0 {
+		return false
+	}
+	obj, err := s.object(file, perm, mtime)
+	if err == nil {
+		err = os.Link(obj, dst)
+	}
+	if err != nil {
+		if atomic.CompareAndSwapInt32(&s.disabled, 0, 1) {
+			log.Debug("Disabled the object store: " + err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+// object returns the path of the object of file, and creates it if it does
+// not exist.
+func (s *objectStore) object(file *object.File, perm os.FileMode, mtime time.Time) (string, error) {
+	hash := file.Hash.String()
+	path := filepath.Join(s.dir, hash[:2], fmt.Sprintf("%s-%o", hash[2:], perm.Perm()))
+	if fi, err := os.Lstat(path); err == nil && fi.Mode().IsRegular() {
+		return path, nil
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	// Write to a temporary file and rename it, because other goroutines may
+	// create the same object
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(contents)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), perm.Perm())
+	}
+	if err == nil {
+		err = fileutil.SetModTime(f.Name(), mtime)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return path, nil
+}
+
+// gc removes objects which have not been linked from anywhere for
+// unusedObjectTTL, and returns the number of removed objects.
+func (s *objectStore) gc() (int, error) {
+	if !linkCountSupported || !pathutil.Exists(s.dir) {
+		return 0, nil
+	}
+	indexPath := filepath.Join(s.dir, unusedIndexName)
+	unused := make(map[string]time.Time)
+	if content, err := ioutil.ReadFile(indexPath); err == nil {
+		// Broken index is ignored, the objects are kept for unusedObjectTTL
+		json.Unmarshal(content, &unused)
+	}
+
+	now := time.Now()
+	newUnused := make(map[string]time.Time, len(unused))
+	removed := 0
+	err := filepath.Walk(s.dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() || path == indexPath {
+			return nil
+		}
+		name := filepath.ToSlash(strings.TrimPrefix(path, s.dir+string(filepath.Separator)))
+		if strings.HasPrefix(fi.Name(), ".tmp-") {
+			// Left by interrupted build
+			return os.Remove(path)
+		}
+		if n, ok := linkCount(fi); !ok || n > 1 {
+			return nil
+		}
+		since, ok := unused[name]
+		if !ok {
+			since = now
+		}
+		if now.Sub(since) < unusedObjectTTL {
+			newUnused[name] = since
+			return nil
+		}
+		removed++
+		return os.Remove(path)
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	content, err := json.Marshal(newUnused)
+	if err != nil {
+		return removed, err
+	}
+	return removed, ioutil.WriteFile(indexPath, content, 0644)
+}
@@ -0,0 +1,105 @@
+package builder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vim-volt/volt/logger"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestObjectStore(t *testing.T) {
+	if !linkCountSupported {
+		t.Skip("object store is not supported")
+	}
+	tempDir, err := ioutil.TempDir("", "volt-test-store-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Commit plugin/foo.vim
+	reposDir := filepath.Join(tempDir, "repos")
+	os.MkdirAll(filepath.Join(reposDir, "plugin"), 0755)
+	if err := ioutil.WriteFile(filepath.Join(reposDir, "plugin", "foo.vim"), []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := git.PlainInit(reposDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("plugin/foo.vim"); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "volt", Email: "volt@example.com", When: time.Now()}
+	hash, err := wt.Commit("initial", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := r.CommitObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := tree.File("plugin/foo.vim")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same object is linked from two directories
+	store := newObjectStore(filepath.Join(tempDir, "objects"))
+	log := logger.NewBuffer()
+	dsts := []string{filepath.Join(tempDir, "a.vim"), filepath.Join(tempDir, "b.vim")}
+	for _, dst := range dsts {
+		if !store.link(dst, file, 0644, sig.When, log) {
+			t.Fatal("could not link " + dst)
+		}
+	}
+	fi1, _ := os.Stat(dsts[0])
+	fi2, _ := os.Stat(dsts[1])
+	if !os.SameFile(fi1, fi2) {
+		t.Error("expected the same file")
+	}
+	if content, _ := ioutil.ReadFile(dsts[1]); string(content) != "foo" {
+		t.Errorf("expected %q but got %q", "foo", content)
+	}
+
+	// Used objects and unused objects within unusedObjectTTL are not removed
+	if n, err := store.gc(); err != nil || n != 0 {
+		t.Errorf("expected (0, nil) but got (%d, %v)", n, err)
+	}
+	for _, dst := range dsts {
+		os.Remove(dst)
+	}
+	if n, err := store.gc(); err != nil || n != 0 {
+		t.Errorf("expected (0, nil) but got (%d, %v)", n, err)
+	}
+	indexPath := filepath.Join(store.dir, unusedIndexName)
+	var unused map[string]time.Time
+	content, _ := ioutil.ReadFile(indexPath)
+	if err := json.Unmarshal(content, &unused); err != nil || len(unused) != 1 {
+		t.Fatalf("expected 1 unused object in %s but got %s", unusedIndexName, content)
+	}
+
+	// Objects unused for unusedObjectTTL are removed
+	for name := range unused {
+		unused[name] = time.Now().Add(-unusedObjectTTL)
+	}
+	content, _ = json.Marshal(unused)
+	ioutil.WriteFile(indexPath, content, 0644)
+	if n, err := store.gc(); err != nil || n != 1 {
+		t.Errorf("expected (1, nil) but got (%d, %v)", n, err)
+	}
+}
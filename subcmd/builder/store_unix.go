@@ -0,0 +1,19 @@
+// +build !windows
+
+package builder
+
+import (
+	"os"
+	"syscall"
+)
+
+const linkCountSupported = true
+
+// linkCount returns the number of hard links to the file of fi.
+func linkCount(fi os.FileInfo) (uint64, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Nlink), true
+}
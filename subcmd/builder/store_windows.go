@@ -0,0 +1,11 @@
+package builder
+
+import "os"
+
+// The object store is not used on Windows because os.FileInfo does not have
+// the number of hard links, which is needed to remove unused objects.
+const linkCountSupported = false
+
+func linkCount(fi os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -1,22 +1,23 @@
 package builder
 
 import (
-	"io/ioutil"
+	"context"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 
 	"gopkg.in/src-d/go-git.v4"
 
+	"github.com/vim-volt/volt/fileutil"
 	"github.com/vim-volt/volt/gitutil"
 	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
-	"github.com/vim-volt/volt/plugconf"
 	"github.com/vim-volt/volt/subcmd/buildinfo"
+	"github.com/vim-volt/volt/transaction"
 )
 
 type symlinkBuilder struct {
@@ -24,30 +25,22 @@ type symlinkBuilder struct {
 }
 
 // TODO: rollback when return err (!= nil)
-func (builder *symlinkBuilder) Build(buildInfo *buildinfo.BuildInfo, buildReposMap map[pathutil.ReposPath]*buildinfo.Repos) error {
-	// Exit if vim executable was not found in PATH
-	if _, err := pathutil.VimExecutable(); err != nil {
-		return err
-	}
-
-	// Get current profile's repos list
+func (builder *symlinkBuilder) Build(ctx context.Context, buildInfo *buildinfo.BuildInfo, buildReposMap map[pathutil.ReposPath]*buildinfo.Repos) error {
+	// Get repos list to install
 	lockJSON, err := lockjson.Read()
 	if err != nil {
 		return errors.Wrap(err, "could not read lock.json")
 	}
-	reposList, err := lockJSON.GetCurrentReposList()
+
+	vimExePath := builder.vimExecutable(lockJSON)
+	reposList, err := builder.reposListToInstall(lockJSON)
 	if err != nil {
 		return err
 	}
 
 	logger.Info("Installing vimrc and gvimrc ...")
 
-	vimDir := pathutil.VimDir()
-	vimrcPath := filepath.Join(vimDir, pathutil.Vimrc)
-	gvimrcPath := filepath.Join(vimDir, pathutil.Gvimrc)
-	err = builder.installVimrcAndGvimrc(
-		lockJSON.CurrentProfileName, vimrcPath, gvimrcPath,
-	)
+	err = builder.installRCFiles(lockJSON.CurrentProfileNames())
 	if err != nil {
 		return err
 	}
@@ -59,15 +52,10 @@ func (builder *symlinkBuilder) Build(buildInfo *buildinfo.BuildInfo, buildReposM
 		return errors.New("could not create " + optDir)
 	}
 
-	vimExePath, err := pathutil.VimExecutable()
-	if err != nil {
-		return err
-	}
-
 	buildInfo.Repos = make([]buildinfo.Repos, 0, len(reposList))
 	done := make(chan actionReposResult, len(reposList))
 	for i := range reposList {
-		go builder.installRepos(&reposList[i], vimExePath, done)
+		go builder.installRepos(ctx, &reposList[i], done)
 		// Make build-info.json data
 		buildInfo.Repos = append(buildInfo.Repos, buildinfo.Repos{
 			Type:    reposList[i].Type,
@@ -75,41 +63,24 @@ func (builder *symlinkBuilder) Build(buildInfo *buildinfo.BuildInfo, buildReposM
 			Version: reposList[i].Version,
 		})
 	}
+	installed := make([]pathutil.ReposPath, 0, len(reposList))
 	for i := 0; i < len(reposList); i++ {
 		result := <-done
+		result.log.Flush()
 		if result.err != nil {
-			return err
+			return result.err
 		}
 		if result.repos != nil {
 			logger.Debug("Installing " + string(result.repos.Type) + " repository " + result.repos.Path.String() + " ... Done.")
+			installed = append(installed, result.repos.Path)
 		}
 	}
+	if err := builder.helptagsAll(ctx, installed, vimExePath); err != nil {
+		return err
+	}
 
 	// Write bundled plugconf file
-	rcDir := pathutil.RCDir(lockJSON.CurrentProfileName)
-	vimrc := ""
-	if path := filepath.Join(rcDir, pathutil.ProfileVimrc); pathutil.Exists(path) {
-		vimrc = path
-	}
-	gvimrc := ""
-	if path := filepath.Join(rcDir, pathutil.ProfileGvimrc); pathutil.Exists(path) {
-		gvimrc = path
-	}
-	plugconfs, parseErr := plugconf.ParseMultiPlugconf(reposList)
-	if parseErr.HasErrs() {
-		// Vim script parse errors / other errors
-		return parseErr.Errors()
-	}
-	if parseErr.HasWarns() {
-		// Vim script parse warnings
-		merr := parseErr.Warns()
-		for _, err := range merr.Errors {
-			logger.Warn(err)
-		}
-	}
-	content, err := plugconfs.GenerateBundlePlugconf(vimrc, gvimrc)
-	os.MkdirAll(filepath.Dir(pathutil.BundledPlugConf()), 0755)
-	err = ioutil.WriteFile(pathutil.BundledPlugConf(), content, 0644)
+	err = builder.writeBundledPlugconf(lockJSON, reposList)
 	if err != nil {
 		return err
 	}
@@ -118,9 +89,128 @@ func (builder *symlinkBuilder) Build(buildInfo *buildinfo.BuildInfo, buildReposM
 	return buildInfo.Write()
 }
 
-func (builder *symlinkBuilder) installRepos(repos *lockjson.Repos, vimExePath string, done chan actionReposResult) {
+// buildDelta installs the repositories which are not in build-info.json, and
+// removes the repositories which are no longer installed.
+// false is returned without changing anything if the installed repositories
+// were changed (then full build is needed).
+func (builder *symlinkBuilder) buildDelta(ctx context.Context, buildInfo *buildinfo.BuildInfo) (bool, error) {
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return false, errors.Wrap(err, "could not read lock.json")
+	}
+	reposList, err := builder.reposListToInstall(lockJSON)
+	if err != nil {
+		return false, err
+	}
+	added, removed, ok := diffBuildRepos(reposList, buildInfo.Repos)
+	if !ok {
+		return false, nil
+	}
+	for i := range reposList {
+		if !added.Contains(reposList[i].Path) && !pathutil.Exists(reposList[i].Path.EncodeToPlugDirName()) {
+			return false, nil
+		}
+	}
+
+	vimExePath := builder.vimExecutable(lockJSON)
+
+	logger.Info("Building " + pathutil.VimVoltOptDir() + " directory (only added and removed repositories) ...")
+
+	for _, reposPath := range removed {
+		if err := transaction.RemoveAll(reposPath.EncodeToPlugDirName()); err != nil {
+			return false, err
+		}
+		logger.Info("Removing " + reposPath + " ... Done.")
+	}
+
+	done := make(chan actionReposResult, len(added))
+	for i := range added {
+		go builder.installRepos(ctx, &added[i], done)
+	}
+	installed := make([]pathutil.ReposPath, 0, len(added))
+	for i := 0; i < len(added); i++ {
+		result := <-done
+		result.log.Flush()
+		if result.err != nil {
+			return false, result.err
+		}
+		if result.repos != nil {
+			logger.Info("Installing " + string(result.repos.Type) + " repository " + result.repos.Path.String() + " ... Done.")
+			installed = append(installed, result.repos.Path)
+		}
+	}
+	if err := builder.helptagsAll(ctx, installed, vimExePath); err != nil {
+		return false, err
+	}
+
+	// Write bundled plugconf file
+	if err := builder.writeBundledPlugconf(lockJSON, reposList); err != nil {
+		return false, err
+	}
+
+	// Write build-info.json
+	buildInfo.Repos = make([]buildinfo.Repos, 0, len(reposList))
+	for i := range reposList {
+		buildInfo.Repos = append(buildInfo.Repos, buildinfo.Repos{
+			Type:    reposList[i].Type,
+			Path:    reposList[i].Path,
+			Version: reposList[i].Version,
+		})
+	}
+	return true, buildInfo.Write()
+}
+
+// diffBuildRepos returns the repositories of reposList which are not
+// installed, and the installed repositories which are not in reposList.
+// installed is the repositories of build-info.json.
+// ok is false if the type or the version of an installed repository is
+// different from reposList.
+func diffBuildRepos(reposList lockjson.ReposList, installed []buildinfo.Repos) (added lockjson.ReposList, removed []pathutil.ReposPath, ok bool) {
+	installedMap := make(map[pathutil.ReposPath]*buildinfo.Repos, len(installed))
+	for i := range installed {
+		installedMap[installed[i].Path] = &installed[i]
+	}
+	for i := range reposList {
+		repos := &reposList[i]
+		b, exists := installedMap[repos.Path]
+		if !exists {
+			added = append(added, *repos)
+			continue
+		}
+		if b.Type != repos.Type || b.Version != repos.Version {
+			return nil, nil, false
+		}
+	}
+	for i := range installed {
+		if !reposList.Contains(installed[i].Path) {
+			removed = append(removed, installed[i].Path)
+		}
+	}
+	return added, removed, true
+}
+
+// helptagsAll runs ":helptags" to generate tags files of installed
+// repositories at once, and returns the errors of all failed repositories.
+func (builder *symlinkBuilder) helptagsAll(ctx context.Context, installed []pathutil.ReposPath, vimExePath string) error {
+	errs := builder.helptags(ctx, installed, vimExePath)
+	var merr *multierror.Error
+	for _, reposPath := range installed {
+		if err := errs[reposPath]; err != nil {
+			merr = multierror.Append(merr, errors.Wrapf(err, "repository '%s'", reposPath))
+		}
+	}
+	return merr.ErrorOrNil()
+}
+
+func (builder *symlinkBuilder) installRepos(ctx context.Context, repos *lockjson.Repos, done chan actionReposResult) {
 	src := repos.Path.FullPath()
 	dst := repos.Path.EncodeToPlugDirName()
+	log := logger.NewReposBuffer(repos.Path.String())
+
+	if err := ctx.Err(); err != nil {
+		done <- actionReposResult{err: err, log: log}
+		return
+	}
 
 	copied := false
 	if repos.Type == lockjson.ReposGitType {
@@ -128,6 +218,7 @@ func (builder *symlinkBuilder) installRepos(repos *lockjson.Repos, vimExePath st
 		r, err := git.PlainOpen(src)
 		if err != nil {
 			done <- actionReposResult{
+				log: log,
 				err: errors.Errorf("repository %q: %s", src, err.Error()),
 			}
 			return
@@ -137,32 +228,32 @@ func (builder *symlinkBuilder) installRepos(repos *lockjson.Repos, vimExePath st
 		head, err := gitutil.GetHEADRepository(r)
 		if err != nil {
 			done <- actionReposResult{
+				log: log,
 				err: errors.Errorf("failed to get HEAD revision of %q: %s", src, err.Error()),
 			}
 			return
 		}
 		if head != repos.Version {
-			logger.Warnf("%s: HEAD and locked revision are different", repos.Path)
-			logger.Warn("  HEAD: " + head)
-			logger.Warn("  locked revision: " + repos.Version)
-			logger.Warn("  Please run 'volt get -l' to update locked revision.")
+			log.Warnf("%s: HEAD and locked revision are different\n"+
+				"  HEAD: %s\n  locked revision: %s\n"+
+				"  Please run 'volt get -l' to update locked revision.", repos.Path, head, repos.Version)
 		}
 
 		cfg, err := r.Config()
 		if err != nil {
 			done <- actionReposResult{
+				log: log,
 				err: errors.Errorf("failed to get repository config of %q: %s", src, err.Error()),
 			}
 			return
 		}
 		if cfg.Core.IsBare {
-			// * Copy files from git objects under vim dir
-			// * Run ":helptags" to generate tags file
+			// Copy files from git objects under vim dir
 			updateDone := make(chan actionReposResult)
-			(&copyBuilder{}).updateBareGitRepos(r, src, dst, repos, vimExePath, updateDone)
+			(&copyBuilder{}).updateBareGitRepos(ctx, r, src, dst, repos, nil, log, updateDone)
 			result := <-updateDone
 			if result.err != nil {
-				done <- actionReposResult{err: result.err}
+				done <- actionReposResult{err: result.err, log: log}
 				return
 			}
 			copied = true
@@ -171,22 +262,27 @@ func (builder *symlinkBuilder) installRepos(repos *lockjson.Repos, vimExePath st
 
 	if !copied {
 		// Make symlinks under vim dir
-		if err := builder.symlink(src, dst); err != nil {
-			done <- actionReposResult{err: err}
-			return
-		}
-		// Run ":helptags" to generate tags file
-		if err := builder.helptags(repos.Path, vimExePath); err != nil {
-			done <- actionReposResult{err: err}
+		if err := builder.symlink(ctx, src, dst, log); err != nil {
+			done <- actionReposResult{err: err, log: log}
 			return
 		}
 	}
-	done <- actionReposResult{repos: repos}
+	done <- actionReposResult{repos: repos, log: log}
 }
 
-func (*symlinkBuilder) symlink(src, dst string) error {
+func (*symlinkBuilder) symlink(ctx context.Context, src, dst string, log *logger.Buffer) error {
 	if runtime.GOOS == "windows" {
-		return exec.Command("cmd", "/c", "mklink", "/J", dst, src).Run()
+		// A junction cannot refer to or be created on a network drive,
+		// copy files instead
+		if pathutil.IsNetworkPath(src) || pathutil.IsNetworkPath(dst) {
+			log.Debugf("Copying %s to %s instead of creating a junction on network drive ...", src, dst)
+			si, err := os.Stat(src)
+			if err != nil {
+				return err
+			}
+			return fileutil.TryLinkDirParallel(ctx, src, dst, si.Mode(), BuildModeInvalidType, 0, nil)
+		}
+		return exec.CommandContext(ctx, "cmd", "/c", "mklink", "/J", dst, src).Run()
 	}
 	return os.Symlink(src, dst)
 }
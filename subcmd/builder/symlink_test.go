@@ -0,0 +1,38 @@
+package builder
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/buildinfo"
+)
+
+func TestDiffBuildRepos(t *testing.T) {
+	installed := []buildinfo.Repos{
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "abc"},
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/open-browser.vim", Version: "def"},
+	}
+	reposList := lockjson.ReposList{
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "abc"},
+		{Type: lockjson.ReposStaticType, Path: "localhost/local/hello"},
+	}
+
+	added, removed, ok := diffBuildRepos(reposList, installed)
+	if !ok {
+		t.Fatal("expected ok but got false")
+	}
+	if expected := (lockjson.ReposList{reposList[1]}); !reflect.DeepEqual(added, expected) {
+		t.Errorf("added: expected %v but got %v", expected, added)
+	}
+	if expected := []pathutil.ReposPath{"github.com/tyru/open-browser.vim"}; !reflect.DeepEqual(removed, expected) {
+		t.Errorf("removed: expected %v but got %v", expected, removed)
+	}
+
+	// The version of an installed repository was changed
+	reposList[0].Version = "xyz"
+	if _, _, ok := diffBuildRepos(reposList, installed); ok {
+		t.Error("expected not ok for the changed version")
+	}
+}
@@ -28,6 +28,19 @@ type Repos struct {
 // key: filepath, value: version
 type FileMap map[string]string
 
+// Equals returns true if m and other have the same files and versions.
+func (m FileMap) Equals(other FileMap) bool {
+	if len(m) != len(other) {
+		return false
+	}
+	for name, version := range m {
+		if v, exists := other[name]; !exists || v != version {
+			return false
+		}
+	}
+	return true
+}
+
 func Read() (*BuildInfo, error) {
 	// Return initial build-info.json struct
 	// if the file does not exist
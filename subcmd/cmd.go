@@ -1,29 +1,83 @@
 package subcmd
 
 import (
+	"context"
 	"flag"
 	"github.com/pkg/errors"
+	"io/ioutil"
 	"os"
 	"os/user"
+	"reflect"
 	"runtime"
+	"strings"
 
 	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/httputil"
+	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/builder"
 )
 
 var cmdMap = make(map[string]Cmd)
 
+// noBuildCmds are the commands which can skip building by "volt -no-build".
+var noBuildCmds = map[string]bool{
+	"get":     true,
+	"rm":      true,
+	"enable":  true,
+	"disable": true,
+	"profile": true,
+}
+
+// dryRunCmds are the commands which show what they would change instead of
+// changing files by "volt -dry-run".
+var dryRunCmds = map[string]bool{
+	"get":     true,
+	"rm":      true,
+	"build":   true,
+	"profile": true,
+}
+
+// noLockJSONCmds are the commands which do not use CmdContext.LockJSON.
+// lock.json is not read for them.
+var noLockJSONCmds = map[string]bool{
+	"alias":        true,
+	"config":       true,
+	"doctor":       true,
+	"self-upgrade": true,
+	"unlock":       true,
+}
+
+// CmdContext is passed to subcommands.
+// It holds the arguments of a subcommand, and the values which are read once
+// before invoking a subcommand.
+// Ctx is done when the subcommand should be cancelled, long operations
+// (e.g. cloning repositories, building) must stop when Ctx is done.
+type CmdContext struct {
+	Ctx      context.Context
+	Cmd      string
+	Args     []string
+	LockJSON *lockjson.LockJSON
+	Config   *config.Config
+	// DryRun is true if "volt -dry-run" was given
+	DryRun bool
+	// Result is the result of the command, which is written to stdout as
+	// JSON by "volt -output json". nil is written if it is not set.
+	Result interface{}
+}
+
 // Cmd represents volt's subcommand interface.
 // All subcommands must implement this.
 type Cmd interface {
 	ProhibitRootExecution(args []string) bool
-	Run(args []string) *Error
+	Run(cmdctx *CmdContext) *Error
 	FlagSet() *flag.FlagSet
 }
 
-// RunnerFunc invokes c with args.
+// RunnerFunc invokes c with cmdctx.
 // On unit testing, a mock function was given.
-type RunnerFunc func(c Cmd, args []string) *Error
+type RunnerFunc func(c Cmd, cmdctx *CmdContext) *Error
 
 // Error is a command error.
 // It also has a exit code.
@@ -36,15 +90,19 @@ func (e *Error) Error() string {
 	return e.Msg
 }
 
-// DefaultRunner simply runs command with args
-func DefaultRunner(c Cmd, args []string) *Error {
-	return c.Run(args)
+// DefaultRunner simply runs command with cmdctx
+func DefaultRunner(c Cmd, cmdctx *CmdContext) *Error {
+	return c.Run(cmdctx)
 }
 
 // Run is invoked by main(), each argument means 'volt {subcmd} {args}'.
 func Run(args []string, cont RunnerFunc) *Error {
-	if os.Getenv("VOLT_DEBUG") != "" {
-		logger.SetLevel(logger.DebugLevel)
+	args, opts, err := parseGlobalOptions(args)
+	if err != nil {
+		return &Error{Code: 5, Msg: err.Error()}
+	}
+	if err := opts.setupLogger(); err != nil {
+		return &Error{Code: 5, Msg: err.Error()}
 	}
 
 	if len(args) <= 1 {
@@ -53,38 +111,329 @@ func Run(args []string, cont RunnerFunc) *Error {
 	subCmd := args[1]
 	args = args[2:]
 
-	// Expand subcommand alias
-	subCmd, args, err := expandAlias(subCmd, args)
+	if opts.output == jsonOutput {
+		return runJSON(subCmd, args, opts, cont)
+	}
+	return run(subCmd, args, opts, cont)
+}
+
+// parseGlobalOptions parses the global options of args (e.g. os.Args), and
+// returns args without them.
+func parseGlobalOptions(args []string) ([]string, *globalOptions, error) {
+	var opts globalOptions
+	for len(args) > 1 {
+		n, err := opts.parse(args[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		if n == 0 {
+			break
+		}
+		args = append(args[:1:1], args[1+n:]...)
+	}
+	return args, &opts, nil
+}
+
+// globalOptions are the options given before a subcommand.
+type globalOptions struct {
+	noBuild bool
+	dryRun  bool
+	// output is the format of the result ("text" or "json")
+	output string
+	// logLevel is the name of the log level ("error", "warn", "info", or
+	// "debug")
+	logLevel string
+	quiet    bool
+	noColor  bool
+	// logFormat is the format of log messages ("text" or "json")
+	logFormat string
+}
+
+// parse parses a global option at the head of args, and returns the number
+// of consumed arguments. 0 is returned if args does not start with a global
+// option.
+func (opts *globalOptions) parse(args []string) (int, error) {
+	switch {
+	case args[0] == "-no-build" || args[0] == "--no-build":
+		opts.noBuild = true
+		return 1, nil
+	case args[0] == "-dry-run" || args[0] == "--dry-run":
+		opts.dryRun = true
+		return 1, nil
+	case args[0] == "-output" || args[0] == "--output":
+		if len(args) < 2 {
+			return 0, errors.New("-output needs a format (\"text\" or \"json\")")
+		}
+		return 2, opts.setOutput(args[1])
+	case strings.HasPrefix(args[0], "-output=") || strings.HasPrefix(args[0], "--output="):
+		return 1, opts.setOutput(args[0][strings.Index(args[0], "=")+1:])
+	case args[0] == "-log-level" || args[0] == "--log-level":
+		if len(args) < 2 {
+			return 0, errors.New("-log-level needs a level (\"error\", \"warn\", \"info\", or \"debug\")")
+		}
+		opts.logLevel = args[1]
+		return 2, nil
+	case strings.HasPrefix(args[0], "-log-level=") || strings.HasPrefix(args[0], "--log-level="):
+		opts.logLevel = args[0][strings.Index(args[0], "=")+1:]
+		return 1, nil
+	case args[0] == "-log-format" || args[0] == "--log-format":
+		if len(args) < 2 {
+			return 0, errors.New("-log-format needs a format (\"text\" or \"json\")")
+		}
+		opts.logFormat = args[1]
+		return 2, nil
+	case strings.HasPrefix(args[0], "-log-format=") || strings.HasPrefix(args[0], "--log-format="):
+		opts.logFormat = args[0][strings.Index(args[0], "=")+1:]
+		return 1, nil
+	case args[0] == "-quiet" || args[0] == "--quiet":
+		opts.quiet = true
+		return 1, nil
+	case args[0] == "-no-color" || args[0] == "--no-color":
+		opts.noColor = true
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// setupLogger sets the log level, colors, and format of logger by
+// -log-level, -quiet, -no-color, and -log-format. If -log-level and -quiet
+// were not given, $VOLT_DEBUG enables debug logs. If -log-format was not
+// given, $VOLT_LOG_FORMAT is used.
+func (opts *globalOptions) setupLogger() error {
+	if opts.noColor {
+		logger.SetColor(false)
+	}
+	if name := opts.logFormat; name != "" || os.Getenv(logger.FormatEnv) != "" {
+		if name == "" {
+			name = os.Getenv(logger.FormatEnv)
+		}
+		f, err := logger.ParseFormat(name)
+		if err != nil {
+			return err
+		}
+		logger.SetFormat(f)
+	}
+	switch {
+	case opts.quiet && opts.logLevel != "":
+		return errors.New("-quiet and -log-level cannot be used together")
+	case opts.quiet:
+		logger.SetLevel(logger.WarnLevel)
+	case opts.logLevel != "":
+		level, err := logger.ParseLevel(opts.logLevel)
+		if err != nil {
+			return err
+		}
+		logger.SetLevel(level)
+	case os.Getenv("VOLT_DEBUG") != "":
+		logger.SetLevel(logger.DebugLevel)
+	}
+	return nil
+}
+
+func (opts *globalOptions) setOutput(format string) error {
+	if format != textOutput && format != jsonOutput {
+		return errors.Errorf("-output must be %q or %q but got %q", textOutput, jsonOutput, format)
+	}
+	opts.output = format
+	return nil
+}
+
+// run runs 'volt {subCmd} {args}' with opts.
+func run(subCmd string, args []string, opts *globalOptions, cont RunnerFunc) *Error {
+	// Show messages without reading any files, because volt is often invoked
+	// from shell prompts or statuslines where latency matters
+	if c, exists := cmdMap[subCmd]; exists && isLightweight(subCmd, args) {
+		return cont(c, &CmdContext{
+			Ctx:  context.Background(),
+			Cmd:  subCmd,
+			Args: args,
+		})
+	}
+
+	// Cancel the context on Ctrl-C
+	ctx, stop := cancelOnInterrupt()
+	defer stop()
+
+	c, cmdctx, err := prepare(ctx, subCmd, args, true)
 	if err != nil {
-		return &Error{Code: 1, Msg: err.Error()}
+		return err
+	}
+	if cmdctx.Config != nil && cmdctx.Cmd != "notify" {
+		startNotifyCheck(cmdctx.Config)
+	}
+	if opts.noBuild {
+		if !noBuildCmds[cmdctx.Cmd] {
+			return &Error{Code: 5, Msg: "-no-build cannot be used with '" + cmdctx.Cmd + "'"}
+		}
+		builder.SkipBuild(true)
+		defer builder.SkipBuild(false)
+	}
+	if opts.dryRun {
+		if !dryRunCmds[cmdctx.Cmd] {
+			return &Error{Code: 5, Msg: "-dry-run cannot be used with '" + cmdctx.Cmd + "'"}
+		}
+		cmdctx.DryRun = true
+	}
+	return cont(c, cmdctx)
+}
+
+// Exec runs 'volt {subCmd} {args}' like Run(), but ctx is used instead of
+// handling signals, and aliases of config.toml are not expanded.
+// A new instance of the subcommand is created for each call not to leave the
+// flags of previous calls. This is used by the Go API (pkg/volt).
+func Exec(ctx context.Context, subCmd string, args []string) *Error {
+	if os.Getenv("VOLT_DEBUG") != "" {
+		logger.SetLevel(logger.DebugLevel)
+	}
+	c, cmdctx, err := prepare(ctx, subCmd, args, false)
+	if err != nil {
+		return err
+	}
+	return newCmd(c).Run(cmdctx)
+}
+
+// commandNames returns the names of all volt commands.
+func commandNames() []string {
+	names := make([]string, 0, len(cmdMap))
+	for name := range cmdMap {
+		names = append(names, name)
+	}
+	return names
+}
+
+// newCmd returns a new zero value of the subcommand c.
+// External commands are returned as is because they are not shared in cmdMap
+// and hold the path looked up by prepare().
+func newCmd(c Cmd) Cmd {
+	if ext, ok := c.(*externalCmd); ok {
+		return ext
+	}
+	return reflect.New(reflect.TypeOf(c).Elem()).Interface().(Cmd)
+}
+
+// prepare reads config.toml and lock.json for subCmd, and returns the
+// subcommand and its context.
+// If expandsAlias is true, subCmd is expanded by aliases of config.toml.
+func prepare(ctx context.Context, subCmd string, args []string, expandsAlias bool) (Cmd, *CmdContext, *Error) {
+	// Read config.toml
+	// 'volt config' and 'volt doctor' can run even if config.toml is invalid
+	// to report errors, and 'volt alias' to fix invalid aliases
+	config.SetCommandNames(commandNames())
+	cfg, err := config.Read()
+	if err != nil && subCmd != "config" && subCmd != "doctor" && subCmd != "alias" {
+		return nil, nil, &Error{Code: 1, Msg: "could not read config.toml: " + err.Error()}
+	}
+
+	// Expand subcommand alias
+	if expandsAlias {
+		subCmd, args = expandAlias(subCmd, args, cfg)
+	}
+
+	// Expand repository aliases in NormalizeRepos()
+	if cfg != nil {
+		pathutil.SetReposAliases(cfg.Repos.Alias)
+	}
+
+	// Connect via network.proxy
+	if cfg != nil && cfg.Network.Proxy != "" {
+		httputil.SetProxy(cfg.Network.Proxy)
 	}
 
 	c, exists := cmdMap[subCmd]
 	if !exists {
-		return &Error{Code: 3, Msg: "unknown command '" + subCmd + "'"}
+		// Run "volt-{subCmd}" in $PATH
+		external := lookUpExternalCmd(subCmd)
+		if external == nil {
+			return nil, nil, &Error{Code: 3, Msg: "unknown command '" + subCmd + "'"}
+		}
+		c = external
 	}
 
 	// Disallow executing the commands which may modify files in root priviledge
 	if c.ProhibitRootExecution(args) {
 		err := detectPriviledgedUser()
 		if err != nil {
-			return &Error{Code: 4, Msg: err.Error()}
+			return nil, nil, &Error{Code: 4, Msg: err.Error()}
+		}
+	}
+
+	// Read lock.json
+	// 'volt migrate' does not show auto-migration message because it
+	// performs migration explicitly.
+	var lockJSON *lockjson.LockJSON
+	if !noLockJSONCmds[subCmd] {
+		readLockJSON := lockjson.Read
+		if subCmd == "migrate" {
+			readLockJSON = lockjson.ReadNoMigrationMsg
+		}
+		lockJSON, err = readLockJSON()
+		if err != nil {
+			return nil, nil, &Error{Code: 2, Msg: "could not read lock.json: " + err.Error()}
+		}
+	}
+
+	// Install plugins to Neovim's directory if build.target is "neovim"
+	if cfg != nil {
+		profileName := ""
+		if lockJSON != nil {
+			profileName = lockJSON.CurrentProfileName
 		}
+		pathutil.SetNeovimTarget(cfg.Build.IsNeovimTarget(profileName))
 	}
 
-	return cont(c, args)
+	return c, &CmdContext{
+		Ctx:      ctx,
+		Cmd:      subCmd,
+		Args:     args,
+		LockJSON: lockJSON,
+		Config:   cfg,
+	}, nil
 }
 
-func expandAlias(subCmd string, args []string) (string, []string, error) {
-	cfg, err := config.Read()
+// IsLightweight returns true if args (e.g. os.Args) invokes a command which
+// only shows messages ("volt help", "volt version", "volt notify -read", and
+// "volt {cmd} -help"). Such commands do not read config.toml, lock.json, nor
+// write log files. So aliases of config.toml are not expanded for them.
+// Global options (e.g. "volt -output json version") are skipped.
+func IsLightweight(args []string) bool {
+	args, _, err := parseGlobalOptions(args)
 	if err != nil {
-		return "", nil, errors.Wrap(err, "could not read config.toml")
+		return false
+	}
+	if len(args) <= 1 {
+		return true
+	}
+	if _, exists := cmdMap[args[1]]; !exists {
+		return false
+	}
+	return isLightweight(args[1], args[2:])
+}
+
+func isLightweight(subCmd string, args []string) bool {
+	if subCmd == "help" || subCmd == "version" {
+		return true
+	}
+	// "volt notify -read" reads only notify.json
+	if subCmd == "notify" && len(args) == 1 && (args[0] == "-read" || args[0] == "--read") {
+		return true
+	}
+	fs := cmdMap[subCmd].FlagSet()
+	fs.SetOutput(ioutil.Discard)
+	fs.Usage = func() {}
+	return fs.Parse(args) == flag.ErrHelp
+}
+
+// expandAlias expands subCmd by aliases of config.toml. Volt commands are
+// not overridden by aliases, and aliases are not expanded recursively.
+func expandAlias(subCmd string, args []string, cfg *config.Config) (string, []string) {
+	if _, isCmd := cmdMap[subCmd]; cfg == nil || isCmd {
+		return subCmd, args
 	}
 	if newArgs, exists := cfg.Alias[subCmd]; exists && len(newArgs) > 0 {
 		subCmd = newArgs[0]
-		args = append(newArgs[1:], args...)
+		args = append(append([]string{}, newArgs[1:]...), args...)
 	}
-	return subCmd, args, nil
+	return subCmd, args
 }
 
 // On Windows, this function always returns nil.
@@ -0,0 +1,164 @@
+package subcmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/subcmd/builder"
+)
+
+// Checks:
+// (a) "volt help", "volt version", and "volt {cmd} -help" do not read
+//
+//	config.toml and lock.json (they are broken in this test)
+//
+// (b) The commands do not create any files
+// (c) Other commands read lock.json
+func TestRunLightweight(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-lightweight-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+	for _, name := range []string{"config.toml", "lock.json"} {
+		if err := ioutil.WriteFile(filepath.Join(tempDir, name), []byte("broken"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Suppress usages
+	stdout := os.Stdout
+	defer func() { os.Stdout = stdout }()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+
+	names := make([]string, 0, len(cmdMap))
+	for name := range cmdMap {
+		if name == "help" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	argsList := [][]string{
+		{"volt"}, {"volt", "version"}, {"volt", "help", "get"},
+		{"volt", "-output", "json", "version"}, {"volt", "-no-color", "-log-level=debug", "help"},
+	}
+	for _, name := range names {
+		argsList = append(argsList, []string{"volt", name, "-help"})
+	}
+
+	for _, args := range argsList {
+		if !IsLightweight(args) {
+			t.Errorf("%q must be lightweight", args)
+		}
+		if err := Run(args, DefaultRunner); err != nil {
+			t.Errorf("%q: %s", args, err.Msg) // (a)
+		}
+	}
+	infos, err := ioutil.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 {
+		t.Errorf("files were created in %s: %d files", tempDir, len(infos)) // (b)
+	}
+
+	for _, args := range [][]string{{"volt", "list"}, {"volt", "list", "-f", "-help"}, {"volt", "profile", "show", "-help"}} {
+		if IsLightweight(args) {
+			t.Errorf("%q must not be lightweight", args)
+		}
+		if err := Run(args, DefaultRunner); err == nil {
+			t.Errorf("%q: expected error for broken config.toml", args) // (c)
+		}
+	}
+}
+
+func TestRunNoBuild(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	skipped := false
+	runner := func(c Cmd, cmdctx *CmdContext) *Error {
+		skipped = builder.IsBuildSkipped()
+		return nil
+	}
+	if err := Run([]string{"volt", "-no-build", "profile", "show", "-current"}, runner); err != nil {
+		t.Fatal(err.Msg)
+	}
+	if !skipped {
+		t.Error("expected building is skipped while running the command")
+	}
+	if builder.IsBuildSkipped() {
+		t.Error("expected building is not skipped after running the command")
+	}
+
+	if err := Run([]string{"volt", "-no-build", "list"}, runner); err == nil || err.Code != 5 {
+		t.Errorf("expected error code 5 for a command which does not build but got %v", err)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	dryRun := false
+	runner := func(c Cmd, cmdctx *CmdContext) *Error {
+		dryRun = cmdctx.DryRun
+		return nil
+	}
+	if err := Run([]string{"volt", "-dry-run", "-no-build", "profile", "show", "-current"}, runner); err != nil {
+		t.Fatal(err.Msg)
+	}
+	if !dryRun {
+		t.Error("expected CmdContext.DryRun is true")
+	}
+
+	if err := Run([]string{"volt", "--dry-run", "list"}, runner); err == nil || err.Code != 5 {
+		t.Errorf("expected error code 5 for a command which does not change files but got %v", err)
+	}
+}
+
+func TestExpandAlias(t *testing.T) {
+	cfg := &config.Config{Alias: map[string][]string{
+		"up":   {"get", "-l", "-u"},
+		"get":  {"get", "-u"},
+		"upup": {"up"},
+	}}
+	for _, tt := range []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"up", "tyru/caw.vim"}, []string{"get", "-l", "-u", "tyru/caw.vim"}},
+		// Commands are not overridden
+		{[]string{"get", "tyru/caw.vim"}, []string{"get", "tyru/caw.vim"}},
+		// Aliases are not expanded recursively
+		{[]string{"upup"}, []string{"up"}},
+	} {
+		subCmd, args := expandAlias(tt.args[0], tt.args[1:], cfg)
+		if got := append([]string{subCmd}, args...); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q: expected %q but got %q", tt.args, tt.expected, got)
+		}
+	}
+}
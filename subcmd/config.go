@@ -0,0 +1,86 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func init() {
+	cmdMap["config"] = &configCmd{}
+}
+
+type configCmd struct {
+	helped bool
+}
+
+func (cmd *configCmd) ProhibitRootExecution(args []string) bool { return false }
+
+func (cmd *configCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  config [-help] {command}
+
+Command
+  config validate
+    Check $VOLTPATH/config.toml, and show the merged configuration (including
+    default values) if it is valid. Passwords and tokens of [credentials] are
+    not shown.
+    Unknown keys, type mismatches, and invalid values are reported with line
+    numbers.
+
+Quick example
+  $ volt config validate
+  [alias]
+    update = ["get", "-u"]
+  ...` + "\n\n")
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *configCmd) Run(cmdctx *CmdContext) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(cmdctx.Args)
+	if cmd.helped {
+		return nil
+	}
+	if len(fs.Args()) == 0 {
+		fs.Usage()
+		logger.Error("must specify subcommand")
+		return nil
+	}
+
+	switch fs.Arg(0) {
+	case "validate":
+		return cmd.doValidate()
+	default:
+		return &Error{Code: 11, Msg: "Unknown subcommand: " + fs.Arg(0)}
+	}
+}
+
+func (cmd *configCmd) doValidate() *Error {
+	cfg, problems, err := config.Check()
+	for i := range problems {
+		logger.Error(pathutil.ConfigTOML() + ": " + problems[i].String())
+	}
+	if err != nil {
+		return &Error{Code: 12, Msg: "Invalid config.toml: " + err.Error()}
+	}
+	if len(problems) > 0 {
+		return &Error{Code: 13, Msg: fmt.Sprintf("Invalid config.toml: %d error(s) found", len(problems))}
+	}
+	if err := toml.NewEncoder(os.Stdout).Encode(cfg.Redacted()); err != nil {
+		return &Error{Code: 14, Msg: "Failed to show config: " + err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,408 @@
+package subcmd
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+	"github.com/vim-volt/volt/subcmd/builder"
+	"github.com/vim-volt/volt/transaction"
+)
+
+func init() {
+	cmdMap["doctor"] = &doctorCmd{}
+}
+
+type doctorCmd struct {
+	helped bool
+}
+
+func (cmd *doctorCmd) ProhibitRootExecution(args []string) bool { return false }
+
+func (cmd *doctorCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt doctor [-help]
+
+Quick example
+  $ volt doctor
+
+    vim > /usr/bin/vim (VIM - Vi IMproved 8.1)
+    symlink > ok
+    writable > ok
+  ! trx lock > /home/user/.local/share/volt/trx/lock exists (created 3 days ago)
+      fix: make sure no other volt process is running, and run "volt unlock"
+    config.toml > ok
+    lock.json > ok
+    repos > ok
+    plugconf > ok
+    build > ok
+
+Description
+  Check the environment of volt, and show how to fix the problems:
+
+    vim       vim (or nvim) executable to make help tags files, and its version
+    symlink   symlinks can be created if build.strategy is "symlink"
+              (Windows requires Developer Mode or administrator privilege)
+    writable  volt directories and ~/.vim are writable
+    trx lock  no lock of a crashed volt process remains
+    config.toml, lock.json
+              the files are valid
+    repos     $VOLTPATH/repos exists, and HEAD of each repository is the
+              locked revision
+    plugconf  plugconf files of current profile have no errors
+    build     ~/.vim/pack/volt is not stale
+
+  It exits with an error if problems were found. Nothing is changed by this
+  command.` + "\n\n")
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *doctorCmd) Run(cmdctx *CmdContext) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(cmdctx.Args)
+	if cmd.helped {
+		return nil
+	}
+	if len(fs.Args()) > 0 {
+		return &Error{Code: 10, Msg: "Failed to parse args: doctor command does not accept arguments"}
+	}
+
+	if n := cmd.diagnose(cmdctx.Ctx, os.Stdout); n > 0 {
+		return &Error{Code: 11, Msg: fmt.Sprintf("Found %d problem(s)", n)}
+	}
+	return nil
+}
+
+// doctorResult is a result of a check of "volt doctor".
+type doctorResult struct {
+	name string
+	// problem is empty if no problems were found
+	problem string
+	// detail is shown instead of "ok" if there are no problems
+	detail string
+	// fix is how to fix the problem
+	fix string
+}
+
+func (r *doctorResult) write(w io.Writer) {
+	switch {
+	case r.problem != "":
+		fmt.Fprintf(w, "! %s > %s\n", r.name, r.problem)
+		if r.fix != "" {
+			fmt.Fprintf(w, "    fix: %s\n", r.fix)
+		}
+	case r.detail != "":
+		fmt.Fprintf(w, "  %s > %s\n", r.name, r.detail)
+	default:
+		fmt.Fprintf(w, "  %s > ok\n", r.name)
+	}
+}
+
+// diagnose writes the results of all checks to w, and returns the number of
+// problems. config.toml and lock.json are read again because they may be
+// invalid.
+func (cmd *doctorCmd) diagnose(ctx context.Context, w io.Writer) int {
+	cfg, cfgResult := cmd.checkConfig()
+	lockJSON, lockResult := cmd.checkLockJSON()
+
+	var results []doctorResult
+	add := func(rs ...doctorResult) {
+		for i := range rs {
+			rs[i].write(w)
+			results = append(results, rs[i])
+		}
+	}
+	profileName := ""
+	if lockJSON != nil {
+		profileName = lockJSON.CurrentProfileName
+	}
+	if cfg != nil {
+		pathutil.SetNeovimTarget(cfg.Build.IsNeovimTarget(profileName))
+	}
+	add(cmd.checkVim(ctx, cfg, profileName))
+	add(cmd.checkSymlink(cfg))
+	add(cmd.checkWritable())
+	add(cmd.checkTrxLock())
+	add(cfgResult, lockResult)
+	if lockJSON != nil {
+		add(cmd.checkRepos(lockJSON)...)
+		add(cmd.checkPlugconf(lockJSON)...)
+		if cfg != nil {
+			add(cmd.checkBuild(lockJSON, cfg))
+		}
+	}
+
+	n := 0
+	for i := range results {
+		if results[i].problem != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func (*doctorCmd) checkConfig() (*config.Config, doctorResult) {
+	result := doctorResult{name: "config.toml"}
+	fix := "fix " + pathutil.ConfigTOML() + " (see \"volt config validate\")"
+	cfg, problems, err := config.Check()
+	if err != nil {
+		result.problem = "invalid: " + err.Error()
+		result.fix = fix
+		return nil, result
+	}
+	if len(problems) > 0 {
+		result.problem = fmt.Sprintf("invalid: %s (and %d more)", problems[0].String(), len(problems)-1)
+		if len(problems) == 1 {
+			result.problem = "invalid: " + problems[0].String()
+		}
+		result.fix = fix
+		return nil, result
+	}
+	return cfg, result
+}
+
+func (*doctorCmd) checkLockJSON() (*lockjson.LockJSON, doctorResult) {
+	result := doctorResult{name: "lock.json"}
+	lockJSON, err := lockjson.ReadNoMigrationMsg()
+	if err != nil {
+		result.problem = "invalid: " + err.Error()
+		result.fix = "fix " + pathutil.LockJSON() + ", or restore it by \"volt snapshot restore\""
+		return nil, result
+	}
+	return lockJSON, result
+}
+
+// vimVersionTimeout is the time limit of executing "vim --version".
+var vimVersionTimeout = 10 * time.Second
+
+// rxVimVersion matches the first line of "vim --version".
+var rxVimVersion = regexp.MustCompile(`^VIM - Vi IMproved ([0-9]+)\.([0-9]+)`)
+
+func (*doctorCmd) checkVim(ctx context.Context, cfg *config.Config, profileName string) doctorResult {
+	result := doctorResult{name: "vim"}
+	configured := ""
+	if cfg != nil {
+		configured = cfg.Build.VimExecutableOf(profileName)
+	}
+	vimExecutable := pathutil.VimExecutable
+	if pathutil.IsNeovimTarget() {
+		vimExecutable = pathutil.NvimExecutable
+	}
+	vim, err := vimExecutable(configured)
+	if err != nil {
+		result.problem = err.Error()
+		result.fix = "install Vim 8.0 or later (or Neovim), or set build.vim_executable of config.toml"
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, vimVersionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, vim, "--version").Output()
+	if err != nil {
+		result.problem = fmt.Sprintf("failed to execute '%s --version': %s", vim, err.Error())
+		result.fix = "check if " + vim + " works, or set build.vim_executable of config.toml"
+		return result
+	}
+	version := string(bytes.TrimSpace(bytes.SplitN(out, []byte("\n"), 2)[0]))
+	if ok, err := isSupportedVimVersion(version); !ok {
+		result.problem = fmt.Sprintf("%s (%s): %s", vim, version, err)
+		result.fix = "install Vim 8.0 or later (or Neovim), or set build.vim_executable of config.toml"
+		return result
+	}
+	if i := strings.Index(version, " ("); i >= 0 {
+		version = version[:i] // strip the date
+	}
+	result.detail = fmt.Sprintf("%s (%s)", vim, version)
+	return result
+}
+
+// isSupportedVimVersion returns true if version (the first line of "vim
+// --version") supports packages.
+func isSupportedVimVersion(version string) (bool, string) {
+	if strings.HasPrefix(version, "NVIM ") {
+		return true, ""
+	}
+	m := rxVimVersion.FindStringSubmatch(version)
+	if m == nil {
+		return true, "" // unknown, but it may work
+	}
+	major, _ := strconv.Atoi(m[1])
+	if major < 8 {
+		return false, "Vim 8.0 or later is required for packages"
+	}
+	return true, ""
+}
+
+func (*doctorCmd) checkSymlink(cfg *config.Config) doctorResult {
+	result := doctorResult{name: "symlink"}
+	if cfg == nil {
+		result.detail = "skipped (config.toml is invalid)"
+		return result
+	}
+	if cfg.Build.Strategy != config.SymlinkBuilder {
+		result.detail = "not used (build.strategy is not \"symlink\")"
+		return result
+	}
+	dir, err := ioutil.TempDir("", "volt-doctor-")
+	if err != nil {
+		result.problem = "could not create a temporary directory: " + err.Error()
+		return result
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Symlink(dir, filepath.Join(dir, "link")); err != nil {
+		result.problem = "could not create a symlink: " + err.Error()
+		result.fix = "set build.strategy = \"copy\" in config.toml"
+		if runtime.GOOS == "windows" {
+			result.fix = "enable Developer Mode of Windows, or " + result.fix
+		}
+	}
+	return result
+}
+
+func (*doctorCmd) checkWritable() doctorResult {
+	result := doctorResult{name: "writable"}
+	dirs := []string{pathutil.VoltConfigDir(), pathutil.VoltDataDir(), pathutil.VoltCacheDir(), pathutil.VimVoltDir()}
+	seen := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		// Check the nearest existing ancestor if dir does not exist yet
+		for !pathutil.Exists(dir) && filepath.Dir(dir) != dir {
+			dir = filepath.Dir(dir)
+		}
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		f, err := ioutil.TempFile(dir, ".volt-doctor-")
+		if err != nil {
+			result.problem = dir + " is not writable: " + err.Error()
+			result.fix = "check the permission of " + dir + " (did you run volt with sudo?)"
+			return result
+		}
+		f.Close()
+		os.Remove(f.Name())
+	}
+	return result
+}
+
+func (*doctorCmd) checkTrxLock() doctorResult {
+	result := doctorResult{name: "trx lock"}
+	info, err := transaction.ReadLock()
+	if err != nil {
+		result.problem = "could not read the lock: " + err.Error()
+		return result
+	}
+	if info == nil {
+		return result
+	}
+	result.problem = fmt.Sprintf("%s exists (created %s ago", info.Dir, time.Since(info.ModTime).Round(time.Second))
+	switch {
+	case info.Running:
+		result.problem += fmt.Sprintf(", PID %d is running)", info.PID)
+		result.fix = "wait for the volt process, or run \"volt unlock -force\" if it hangs"
+	case info.PID != 0:
+		result.problem += fmt.Sprintf(", PID %d is not running)", info.PID)
+		result.fix = "run \"volt unlock\" (or it is taken over by the next command)"
+	default:
+		result.problem += ")"
+		result.fix = "make sure no other volt process is running, and run \"volt unlock\""
+	}
+	if info.HasJournal {
+		// The removed or overwritten files may need to be restored by hand
+		result.fix += fmt.Sprintf(" (see %s for what the interrupted operation changed, the previous files are in %s)",
+			filepath.Join(info.Dir, "journal"), filepath.Join(info.Dir, "backup"))
+	}
+	return result
+}
+
+func (*doctorCmd) checkRepos(lockJSON *lockjson.LockJSON) []doctorResult {
+	problems, err := findLockProblems(lockJSON)
+	if err != nil {
+		return []doctorResult{{name: "repos", problem: err.Error()}}
+	}
+	var results []doctorResult
+	for i := range problems {
+		line := problems[i].String()
+		// Strip the mark and the repository of the line of "volt status"
+		if j := strings.Index(line, " > "); j >= 0 {
+			line = line[j+len(" > "):]
+		}
+		r := doctorResult{name: problems[i].reposPath.String(), problem: line}
+		switch problems[i].kind {
+		case lockProblemMissing:
+			r.fix = "run \"volt get " + problems[i].reposPath.String() + "\", or \"volt verify-lock -repair\""
+		case lockProblemHEAD:
+			r.fix = "run \"volt get -l\" to lock HEAD, or \"volt verify-lock -repair\" to check out the locked revision"
+		default:
+			r.fix = "run \"volt verify-lock -repair\" to add it to lock.json or remove it"
+		}
+		results = append(results, r)
+	}
+	if len(results) == 0 {
+		results = append(results, doctorResult{name: "repos"})
+	}
+	return results
+}
+
+func (*doctorCmd) checkPlugconf(lockJSON *lockjson.LockJSON) []doctorResult {
+	reposList, err := lockJSON.GetCurrentBuildReposList()
+	if err != nil {
+		return []doctorResult{{name: "plugconf", problem: err.Error()}}
+	}
+	_, parseErr := plugconf.ParseMultiPlugconf(reposList)
+	if !parseErr.HasErrs() {
+		return []doctorResult{{name: "plugconf"}}
+	}
+	var results []doctorResult
+	for i := range parseErr {
+		if !parseErr[i].HasErrs() {
+			continue
+		}
+		results = append(results, doctorResult{
+			name:    "plugconf",
+			problem: strings.Replace(parseErr[i].Errors().Error(), "\n", "\n    ", -1),
+			fix:     "fix the plugconf file (\"volt lint\" shows more problems)",
+		})
+	}
+	return results
+}
+
+func (*doctorCmd) checkBuild(lockJSON *lockjson.LockJSON, cfg *config.Config) doctorResult {
+	result := doctorResult{name: "build"}
+	stale, err := builder.CheckStale(lockJSON, cfg)
+	if err != nil {
+		result.problem = "could not read build-info.json: " + err.Error()
+		result.fix = "run \"volt build -full\""
+		return result
+	}
+	switch {
+	case stale.All != "":
+		result.problem = pathutil.VimVoltDir() + " is stale: " + stale.All
+	case len(stale.Repos) > 0:
+		result.problem = fmt.Sprintf("%s is stale: %s: %s", pathutil.VimVoltDir(), stale.Repos[0].Path, stale.Repos[0].Reason)
+	default:
+		return result
+	}
+	result.fix = "run \"volt build\""
+	return result
+}
@@ -0,0 +1,52 @@
+package subcmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestIsSupportedVimVersion(t *testing.T) {
+	for version, expected := range map[string]bool{
+		"VIM - Vi IMproved 8.1 (2018 May 18, compiled Jun 20 2018 12:00:00)": true,
+		"VIM - Vi IMproved 9.0 (2022 Jun 28, compiled Jul 01 2022 12:00:00)": true,
+		"VIM - Vi IMproved 7.4 (2013 Aug 10, compiled Nov 24 2016 16:44:48)": false,
+		"NVIM v0.3.1":   true,
+		"unknown vim 1": true,
+	} {
+		if got, _ := isSupportedVimVersion(version); got != expected {
+			t.Errorf("%q: expected %v but got %v", version, expected, got)
+		}
+	}
+}
+
+func TestDoctorCheckTrxLock(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	cmd := &doctorCmd{}
+	if result := cmd.checkTrxLock(); result.problem != "" {
+		t.Errorf("expected no problems but got %q", result.problem)
+	}
+
+	lockDir := filepath.Join(pathutil.TrxDir(), "lock")
+	if err := os.MkdirAll(lockDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	result := cmd.checkTrxLock()
+	if !strings.HasPrefix(result.problem, lockDir+" exists") {
+		t.Errorf("expected %q exists but got %q", lockDir, result.problem)
+	}
+	if result.fix == "" {
+		t.Error("expected a fix but got empty")
+	}
+}
@@ -0,0 +1,42 @@
+package subcmd
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/builder"
+)
+
+// logDryRun shows a change which is not made because of "volt -dry-run".
+func logDryRun(format string, args ...interface{}) {
+	logger.Infof("(dry-run) "+format, args...)
+}
+
+// dryRunWriteLockJSON shows that lock.json would be written.
+func dryRunWriteLockJSON() {
+	logDryRun("Would write %s", pathutil.LockJSON())
+}
+
+// dryRunBuild shows what builder.Build(ctx, full) (or builder.BuildDelta(ctx)
+// if delta is true) would change if lock.json were lockJSON.
+func dryRunBuild(lockJSON *lockjson.LockJSON, full, delta bool) error {
+	if builder.IsBuildSkipped() {
+		logDryRun("Would skip building %s (-no-build)", pathutil.VimVoltDir())
+		return nil
+	}
+	cfg, err := config.Read()
+	if err != nil {
+		return errors.Wrap(err, "could not read config.toml")
+	}
+	changes, err := builder.DryRun(lockJSON, cfg, full, delta)
+	if err != nil {
+		return errors.Wrap(err, "could not check "+pathutil.VimVoltDir())
+	}
+	for _, change := range changes {
+		logDryRun("%s", change)
+	}
+	return nil
+}
@@ -1,15 +1,23 @@
 package subcmd
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
+
+	"github.com/mattn/go-isatty"
 
 	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
 	"github.com/vim-volt/volt/subcmd/builder"
 )
 
@@ -37,9 +45,21 @@ Quick example
 
 Description
   Open the plugconf file(s) of one or more {repository} for editing.
+  {repository} can also be a plugin name (e.g. "caw.vim") of installed
+  repositories. If it matches multiple repositories, you are asked to choose one.
 
   If the -e option was given, use the given editor for editing those files (unless it cannot be found)
 
+  If the plugconf file does not exist, it is created like "volt get" does (a
+  template of vim-volt/plugconf-templates, or a skeleton).
+
+  The editor is the first one found in: -e option, edit.editor of
+  config.toml, $VISUAL, $EDITOR, vim (or build.vim_executable), and
+  sensible-editor.
+
+  After the file was saved, it is checked like "volt build" does. If it has
+  errors, they are shown and you are asked to edit it again.
+
   It also calls "volt build" afterwards if modifications were made to the plugconf file(s).` + "\n\n")
 		//fmt.Println("Options")
 		//fs.PrintDefaults()
@@ -51,8 +71,8 @@ Description
 	return fs
 }
 
-func (cmd *editCmd) Run(args []string) *Error {
-	reposPathList, err := cmd.parseArgs(args)
+func (cmd *editCmd) Run(cmdctx *CmdContext) *Error {
+	reposPathList, err := cmd.parseArgs(cmdctx.Args, cmdctx.LockJSON)
 	if err == ErrShowedHelp {
 		return nil
 	}
@@ -60,14 +80,14 @@ func (cmd *editCmd) Run(args []string) *Error {
 		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
 	}
 
-	hasChanges, err := cmd.doEdit(reposPathList)
+	hasChanges, err := cmd.doEdit(cmdctx.Ctx, reposPathList, cmdctx.Config, cmdctx.LockJSON.CurrentProfileName)
 	if err != nil {
 		return &Error{Code: 15, Msg: "Failed to edit plugconf file: " + err.Error()}
 	}
 
 	// Build opt dir
 	if hasChanges {
-		err = builder.Build(false)
+		err = builder.Build(cmdctx.Ctx, false)
 		if err != nil {
 			return &Error{Code: 12, Msg: "Could not build " + pathutil.VimVoltDir() + ": " + err.Error()}
 		}
@@ -76,14 +96,8 @@ func (cmd *editCmd) Run(args []string) *Error {
 	return nil
 }
 
-func (cmd *editCmd) doEdit(reposPathList []pathutil.ReposPath) (bool, error) {
-	// Read config.toml
-	cfg, err := config.Read()
-	if err != nil {
-		return false, errors.New("could not read config.toml: " + err.Error())
-	}
-
-	editor, err := cmd.identifyEditor(cfg)
+func (cmd *editCmd) doEdit(ctx context.Context, reposPathList []pathutil.ReposPath, cfg *config.Config, profileName string) (bool, error) {
+	editor, err := cmd.identifyEditor(cfg, profileName)
 	if err != nil || editor == "" {
 		return false, &Error{Code: 30, Msg: "No usable editor found"}
 	}
@@ -96,42 +110,110 @@ func (cmd *editCmd) doEdit(reposPathList []pathutil.ReposPath) (bool, error) {
 
 		// Install a new template if none exists
 		if !pathutil.Exists(plugconfPath) {
-			getCmd := new(getCmd)
 			logger.Debugf("Installing new plugconf for '%s'.", reposPath)
-			getCmd.downloadPlugconf(reposPath)
+			log := logger.NewReposBuffer(reposPath.String())
+			err := new(getCmd).downloadPlugconf(ctx, reposPath, cfg, log)
+			log.Flush()
+			if _, ok := err.(*plugconfParseError); ok {
+				// Let the user fix the errors
+				logger.Warn(err.Error())
+			} else if err != nil {
+				return changeWasMade, err
+			}
+		}
+
+		changed, err := cmd.editPlugconf(editor, reposPath)
+		changeWasMade = changeWasMade || changed
+		if err != nil {
+			return changeWasMade, err
 		}
+	}
 
+	return changeWasMade, nil
+}
+
+// editPlugconf opens the plugconf of reposPath with editor, and checks it
+// after it was changed. If it has errors, the user is asked to edit it again.
+// It returns true if the plugconf was changed.
+func (cmd *editCmd) editPlugconf(editor string, reposPath pathutil.ReposPath) (bool, error) {
+	plugconfPath := reposPath.Plugconf()
+	interactive := isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd())
+	in := bufio.NewReader(os.Stdin)
+	changed := false
+	for {
 		// Remember modification time before opening the editor
 		info, err := os.Stat(plugconfPath)
 		if err != nil {
-			return false, err
+			return changed, err
 		}
 		mTimeBefore := info.ModTime()
 
 		// Call the editor with the plugconf file
-		editorCmd := exec.Command(editor, plugconfPath)
+		args := strings.Fields(editor)
+		editorCmd := exec.Command(args[0], append(args[1:], plugconfPath)...)
 		editorCmd.Stdin = os.Stdin
 		editorCmd.Stdout = os.Stdout
+		editorCmd.Stderr = os.Stderr
 		if err = editorCmd.Run(); err != nil {
-			logger.Error("Error calling editor for '%s': %s", reposPath, err.Error)
-			continue
+			logger.Errorf("Error calling editor for '%s': %s", reposPath, err.Error())
+			return changed, nil
 		}
 
 		// Get modification time after closing the editor
 		info, err = os.Stat(plugconfPath)
 		if err != nil {
-			return false, err
+			return changed, err
 		}
-		mTimeAfter := info.ModTime()
 
 		// A change was made if the modification time was updated
-		changeWasMade = changeWasMade || mTimeAfter.After(mTimeBefore)
+		if !info.ModTime().After(mTimeBefore) {
+			return changed, nil
+		}
+		changed = true
+
+		// Check the plugconf like "volt build" does
+		_, parseErr := plugconf.ParsePlugconfFile(plugconfPath, 0, reposPath)
+		if parseErr.HasWarns() {
+			logger.Warn(parseErr.Warns().Error())
+		}
+		if !parseErr.HasErrs() {
+			return changed, nil
+		}
+		logger.Error(parseErr.Errors().Error())
+		if !interactive {
+			return changed, errors.New(plugconfPath + " has errors")
+		}
+		again, err := askEditAgain(plugconfPath, in, os.Stdout)
+		if err != nil {
+			return changed, err
+		}
+		if !again {
+			return changed, errors.New(plugconfPath + " has errors")
+		}
 	}
+}
 
-	return changeWasMade, nil
+// askEditAgain asks whether to edit path which has errors again.
+func askEditAgain(path string, in *bufio.Reader, out io.Writer) (bool, error) {
+	for {
+		fmt.Fprintf(out, "%s has errors. Edit it again? [Y/n]: ", path)
+		line, err := in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes", "":
+			return err != io.EOF || line != "", nil
+		case "n", "no":
+			return false, nil
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+	}
 }
 
-func (cmd *editCmd) parseArgs(args []string) (pathutil.ReposPathList, error) {
+func (cmd *editCmd) parseArgs(args []string, lockJSON *lockjson.LockJSON) (pathutil.ReposPathList, error) {
 	fs := cmd.FlagSet()
 	fs.Parse(args)
 	if cmd.helped {
@@ -143,20 +225,10 @@ func (cmd *editCmd) parseArgs(args []string) (pathutil.ReposPathList, error) {
 		return nil, errors.New("repository was not given")
 	}
 
-	// Normalize repos path
-	reposPathList := make(pathutil.ReposPathList, 0, len(fs.Args()))
-	for _, arg := range fs.Args() {
-		reposPath, err := pathutil.NormalizeRepos(arg)
-		if err != nil {
-			return nil, err
-		}
-		reposPathList = append(reposPathList, reposPath)
-	}
-
-	return reposPathList, nil
+	return resolveReposPathList(fs.Args(), lockJSON)
 }
 
-func (cmd *editCmd) identifyEditor(cfg *config.Config) (string, error) {
+func (cmd *editCmd) identifyEditor(cfg *config.Config, profileName string) (string, error) {
 	editors := make([]string, 0, 6)
 
 	// if an editor is specified as commandline argument, consider it
@@ -171,15 +243,18 @@ func (cmd *editCmd) identifyEditor(cfg *config.Config) (string, error) {
 		editors = append(editors, cfg.Edit.Editor)
 	}
 
-	vimExecutable, err := pathutil.VimExecutable()
+	// the editor of the user
+	editors = append(editors, "$VISUAL", "$EDITOR")
+
+	vimExecutable, err := pathutil.VimExecutable(cfg.Build.VimExecutableOf(profileName))
 	if err != nil {
-		logger.Debug("No vim executable found in $PATH")
+		logger.Debug("No vim executable found: " + err.Error())
 	} else {
 		editors = append(editors, vimExecutable)
 	}
 
 	// specifiy a fixed list of other alternatives
-	editors = append(editors, "$VISUAL", "sensible-editor", "$EDITOR")
+	editors = append(editors, "sensible-editor")
 
 	for _, editor := range editors {
 		// resolve content of environment variables
@@ -190,7 +265,12 @@ func (cmd *editCmd) identifyEditor(cfg *config.Config) (string, error) {
 			editorName = editor
 		}
 
-		path, err := exec.LookPath(editorName)
+		// the editor may have arguments (e.g. "code --wait")
+		fields := strings.Fields(editorName)
+		if len(fields) == 0 {
+			continue
+		}
+		path, err := exec.LookPath(fields[0])
 		if err != nil {
 			logger.Debug(editorName + " not found in $PATH")
 		} else if path != "" {
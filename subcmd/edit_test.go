@@ -0,0 +1,30 @@
+package subcmd
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAskEditAgain(t *testing.T) {
+	for _, tt := range []struct {
+		input    string
+		expected bool
+	}{
+		{"\n", true},
+		{"y\n", true},
+		{"N\n", false},
+		{"", false},
+		{"foo\nno\n", false},
+	} {
+		var out bytes.Buffer
+		again, err := askEditAgain("plugconf.vim", bufio.NewReader(strings.NewReader(tt.input)), &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again != tt.expected {
+			t.Errorf("%q: expected %v but got %v", tt.input, tt.expected, again)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/pkg/errors"
 	"os"
 
+	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/pathutil"
 )
 
@@ -29,6 +30,7 @@ Usage
 
 Quick example
   $ volt enable tyru/caw.vim # will enable tyru/caw.vim plugin in current profile
+  $ volt enable caw.vim      # same as above if only tyru/caw.vim matches "caw.vim"
 
 Description
   This is shortcut of:
@@ -41,8 +43,8 @@ Description
 	return fs
 }
 
-func (cmd *enableCmd) Run(args []string) *Error {
-	reposPathList, err := cmd.parseArgs(args)
+func (cmd *enableCmd) Run(cmdctx *CmdContext) *Error {
+	reposPathList, err := cmd.parseArgs(cmdctx.Args, cmdctx.LockJSON)
 	if err == ErrShowedHelp {
 		return nil
 	}
@@ -51,7 +53,7 @@ func (cmd *enableCmd) Run(args []string) *Error {
 	}
 
 	profCmd := profileCmd{}
-	err = profCmd.doAdd(append(
+	err = profCmd.doAdd(cmdctx.Ctx, append(
 		[]string{"-current"},
 		reposPathList.Strings()...,
 	))
@@ -62,7 +64,7 @@ func (cmd *enableCmd) Run(args []string) *Error {
 	return nil
 }
 
-func (cmd *enableCmd) parseArgs(args []string) (pathutil.ReposPathList, error) {
+func (cmd *enableCmd) parseArgs(args []string, lockJSON *lockjson.LockJSON) (pathutil.ReposPathList, error) {
 	fs := cmd.FlagSet()
 	fs.Parse(args)
 	if cmd.helped {
@@ -74,15 +76,5 @@ func (cmd *enableCmd) parseArgs(args []string) (pathutil.ReposPathList, error) {
 		return nil, errors.New("repository was not given")
 	}
 
-	// Normalize repos path
-	reposPathList := make(pathutil.ReposPathList, 0, len(fs.Args()))
-	for _, arg := range fs.Args() {
-		reposPath, err := pathutil.NormalizeRepos(arg)
-		if err != nil {
-			return nil, err
-		}
-		reposPathList = append(reposPathList, reposPath)
-	}
-
-	return reposPathList, nil
+	return resolveReposPathList(fs.Args(), lockJSON)
 }
@@ -0,0 +1,113 @@
+package subcmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+)
+
+func init() {
+	cmdMap["export"] = &exportCmd{}
+}
+
+type exportCmd struct {
+	helped bool
+	format string
+}
+
+func (cmd *exportCmd) ProhibitRootExecution(args []string) bool { return false }
+
+func (cmd *exportCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt export [-help] -format {format}
+
+Quick example
+  $ volt export -format nix > volt-plugins.nix
+  $ volt export -format json > plugins.json
+  $ volt import plugins.json  # on another machine
+
+Description
+  Print the plugins of current profile in lock.json as {format}.
+
+Formats
+  json
+    A manifest which has current profiles, their repositories with the
+    locked versions, plugconfs, and vimrc and gvimrc of the profiles.
+    "volt import" installs the plugins and merges them into lock.json on
+    another machine (see "volt import -help").
+
+  nix
+    A Nix expression which evaluates to a list of vim plugin packages.
+    Each plugin is built by pkgs.vimUtils.buildVimPlugin from the locked
+    revision, and the sha256 is computed from the files of the revision.
+    It can be used with home-manager:
+
+      programs.vim.plugins = import ./volt-plugins.nix { inherit pkgs; };
+
+    GitHub repositories are fetched by pkgs.fetchFromGitHub, and other git
+    repositories are fetched by pkgs.fetchgit (without submodules).
+    Repositories which have "export-ignore" or "export-subst" in
+    .gitattributes are also fetched by pkgs.fetchgit, because they are
+    different from the archive of GitHub.
+    Static repositories refer to the directories under $VOLTPATH/repos/
+    as absolute paths. Plugconfs are not exported.` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.StringVar(&cmd.format, "format", "", "output format (json, nix)")
+	return fs
+}
+
+func (cmd *exportCmd) Run(cmdctx *CmdContext) *Error {
+	reposList, err := cmd.parseArgs(cmdctx.Args, cmdctx.LockJSON)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if cmd.format == "json" {
+		err = writeJSONManifest(w, cmdctx.LockJSON)
+	} else {
+		err = writeNixExpression(w, reposList)
+	}
+	if err != nil {
+		return &Error{Code: 11, Msg: "Failed to export: " + err.Error()}
+	}
+	if err := w.Flush(); err != nil {
+		return &Error{Code: 11, Msg: "Failed to export: " + err.Error()}
+	}
+	return nil
+}
+
+func (cmd *exportCmd) parseArgs(args []string, lockJSON *lockjson.LockJSON) (lockjson.ReposList, error) {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, ErrShowedHelp
+	}
+	if len(fs.Args()) > 0 {
+		return nil, errors.New("export command does not accept arguments")
+	}
+	switch cmd.format {
+	case "json", "nix":
+	case "":
+		return nil, errors.New("-format is required")
+	default:
+		return nil, errors.New("unknown format: " + cmd.format)
+	}
+	return lockJSON.GetCurrentReposList()
+}
@@ -0,0 +1,143 @@
+package subcmd
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// manifestVersion is the version of the format of exportManifest.
+const manifestVersion = 1
+
+// manifestRCFiles are the basenames of the files in rc/{profile} which are
+// exported.
+var manifestRCFiles = []string{pathutil.ProfileVimrc, pathutil.ProfileGvimrc}
+
+// exportManifest is the output of "volt export -format json", which is
+// read by "volt import".
+type exportManifest struct {
+	Version int `json:"version"`
+	// LockJSON is lock.json which has only current profiles and their
+	// repositories
+	LockJSON json.RawMessage `json:"lock_json"`
+	// Plugconf is a map from a repository to the content of its plugconf
+	Plugconf map[pathutil.ReposPath]string `json:"plugconf,omitempty"`
+	// RC is a map from a profile name to the contents of its vimrc and gvimrc
+	RC map[string]map[string]string `json:"rc,omitempty"`
+}
+
+// writeJSONManifest writes current profiles of lockJSON, their repositories,
+// plugconfs, and vimrc and gvimrc to w.
+func writeJSONManifest(w io.Writer, lockJSON *lockjson.LockJSON) error {
+	reposList, err := lockJSON.GetCurrentReposList()
+	if err != nil {
+		return err
+	}
+	exported := &lockjson.LockJSON{
+		Version:            lockJSON.Version,
+		CurrentProfileName: lockJSON.CurrentProfileName,
+		ExtraProfileNames:  lockJSON.ExtraProfileNames,
+		Repos:              reposList,
+		Profiles:           make(lockjson.ProfileList, 0, len(lockJSON.ExtraProfileNames)+1),
+	}
+	manifest := &exportManifest{
+		Version:  manifestVersion,
+		Plugconf: make(map[pathutil.ReposPath]string),
+		RC:       make(map[string]map[string]string),
+	}
+	for _, name := range lockJSON.CurrentProfileNames() {
+		profile, err := lockJSON.Profiles.FindByName(name)
+		if err != nil {
+			return err
+		}
+		exported.Profiles = append(exported.Profiles, *profile)
+		rc := make(map[string]string)
+		for _, file := range manifestRCFiles {
+			content, err := readFileIfExists(filepath.Join(pathutil.RCDir(name), file))
+			if err != nil {
+				return err
+			}
+			if content != nil {
+				rc[file] = string(content)
+			}
+		}
+		if len(rc) > 0 {
+			manifest.RC[name] = rc
+		}
+	}
+	for i := range reposList {
+		content, err := readFileIfExists(reposList[i].Path.Plugconf())
+		if err != nil {
+			return err
+		}
+		if content != nil {
+			manifest.Plugconf[reposList[i].Path] = string(content)
+		}
+	}
+
+	if manifest.LockJSON, err = json.Marshal(exported); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
+
+// readJSONManifest parses the output of "volt export -format json", and
+// returns the manifest and its lock.json.
+func readJSONManifest(content []byte) (*exportManifest, *lockjson.LockJSON, error) {
+	var manifest exportManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, nil, err
+	}
+	if manifest.Version != manifestVersion {
+		return nil, nil, errors.Errorf("unknown version %d (must be %d)", manifest.Version, manifestVersion)
+	}
+	if len(manifest.LockJSON) == 0 {
+		return nil, nil, errors.New("lock_json is missing")
+	}
+	lockJSON, err := lockjson.Parse(manifest.LockJSON)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid lock_json")
+	}
+
+	// Reject the paths which may be outside of the config directory
+	for reposPath := range manifest.Plugconf {
+		if normalized, err := pathutil.NormalizeRepos(reposPath.String()); err != nil || normalized != reposPath {
+			return nil, nil, errors.Errorf("invalid repository of plugconf: %q", reposPath)
+		}
+	}
+	for name, rc := range manifest.RC {
+		if lockJSON.Profiles.FindIndexByName(name) < 0 {
+			return nil, nil, errors.Errorf("rc of unknown profile: %q", name)
+		}
+		if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+			return nil, nil, errors.Errorf("invalid profile name: %q", name)
+		}
+		for file := range rc {
+			if file != pathutil.ProfileVimrc && file != pathutil.ProfileGvimrc {
+				return nil, nil, errors.Errorf("invalid rc file of profile %s: %q", name, file)
+			}
+		}
+	}
+	return &manifest, lockJSON, nil
+}
+
+// readFileIfExists returns the content of path, or nil if it does not exist.
+func readFileIfExists(path string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return content, err
+}
@@ -0,0 +1,124 @@
+package subcmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestJSONManifest(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	const commit = "41c2a0d7e76d6d5bb1c6a8c57e8c8e1f42a54f09"
+	lockJSON := &lockjson.LockJSON{
+		Version:            2,
+		CurrentProfileName: "default",
+		Repos: lockjson.ReposList{
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: commit},
+			{Type: lockjson.ReposStaticType, Path: "localhost/local/hello"},
+		},
+		Profiles: lockjson.ProfileList{
+			{Name: "default", ReposPath: []pathutil.ReposPath{"github.com/tyru/caw.vim"}},
+			{Name: "other", ReposPath: []pathutil.ReposPath{"localhost/local/hello"}},
+		},
+	}
+	writeFile := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(pathutil.ReposPath("github.com/tyru/caw.vim").Plugconf(), "\" caw\n")
+	writeFile(pathutil.ReposPath("localhost/local/hello").Plugconf(), "\" hello\n")
+	writeFile(filepath.Join(pathutil.RCDir("default"), pathutil.ProfileVimrc), "set number\n")
+
+	var buf bytes.Buffer
+	if err := writeJSONManifest(&buf, lockJSON); err != nil {
+		t.Fatal(err)
+	}
+	manifest, exported, err := readJSONManifest(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only current profile and its repositories are exported
+	if len(exported.Profiles) != 1 || exported.Profiles[0].Name != "default" {
+		t.Errorf("unexpected profiles: %+v", exported.Profiles)
+	}
+	if !reflect.DeepEqual(exported.Repos, lockJSON.Repos[:1]) {
+		t.Errorf("expected %+v but got %+v", lockJSON.Repos[:1], exported.Repos)
+	}
+	expectedPlugconf := map[pathutil.ReposPath]string{"github.com/tyru/caw.vim": "\" caw\n"}
+	if !reflect.DeepEqual(manifest.Plugconf, expectedPlugconf) {
+		t.Errorf("expected %q but got %q", expectedPlugconf, manifest.Plugconf)
+	}
+	expectedRC := map[string]map[string]string{"default": {pathutil.ProfileVimrc: "set number\n"}}
+	if !reflect.DeepEqual(manifest.RC, expectedRC) {
+		t.Errorf("expected %q but got %q", expectedRC, manifest.RC)
+	}
+}
+
+func TestReadJSONManifestErrors(t *testing.T) {
+	const lock = `{"version":2,"current_profile_name":"default","repos":[],"profiles":[{"name":"default","repos_path":[]}]}`
+	for _, tt := range []struct {
+		content  string
+		expected string
+	}{
+		{`{"version":2,"lock_json":` + lock + `}`, "unknown version 2"},
+		{`{"version":1}`, "lock_json is missing"},
+		{`{"version":1,"lock_json":` + lock + `,"plugconf":{"github.com/../../x":""}}`, "invalid repository of plugconf"},
+		{`{"version":1,"lock_json":` + lock + `,"rc":{"other":{"vimrc":""}}}`, "rc of unknown profile"},
+		{`{"version":1,"lock_json":` + lock + `,"rc":{"default":{"../vimrc":""}}}`, "invalid rc file"},
+	} {
+		_, _, err := readJSONManifest([]byte(tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error %q but got %v", tt.content, tt.expected, err)
+		}
+	}
+}
+
+func TestImportMergeRepos(t *testing.T) {
+	lockJSON := &lockjson.LockJSON{Repos: lockjson.ReposList{
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "aaa"},
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/skk.vim", Version: "bbb"},
+	}}
+	src := &lockjson.LockJSON{Repos: lockjson.ReposList{
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "ccc"},
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/skk.vim", Version: "bbb"},
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/open-browser.vim", Version: "ddd"},
+	}}
+	var asked []string
+	keep := func(what string) (bool, error) {
+		asked = append(asked, what)
+		return false, nil
+	}
+	statusList, err := (&importCmd{}).mergeRepos(src, lockJSON, keep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedAsked := []string{"github.com/tyru/caw.vim is locked at aaa but imported one is ccc"}
+	if !reflect.DeepEqual(asked, expectedAsked) {
+		t.Errorf("expected %q but got %q", expectedAsked, asked)
+	}
+	expectedStatus := []string{"# github.com/tyru/caw.vim > kept locked version aaa"}
+	if !reflect.DeepEqual(statusList, expectedStatus) {
+		t.Errorf("expected %q but got %q", expectedStatus, statusList)
+	}
+	if src.Repos[0].Version != "aaa" || src.Repos[2].Version != "ddd" {
+		t.Errorf("unexpected repos: %+v", src.Repos)
+	}
+}
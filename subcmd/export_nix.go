@@ -0,0 +1,376 @@
+package subcmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// nixPlugin is a plugin package in the Nix expression.
+type nixPlugin struct {
+	pname   string
+	version string
+	// src is a Nix expression of the source (e.g. "pkgs.fetchFromGitHub")
+	src string
+	// attrs are the arguments of src. If nil, src is used as is
+	attrs [][2]string
+}
+
+// writeNixExpression writes reposList as a Nix expression which evaluates to
+// a list of vim plugin packages built by pkgs.vimUtils.buildVimPlugin.
+func writeNixExpression(w io.Writer, reposList lockjson.ReposList) error {
+	fmt.Fprintln(w, `# Generated by "volt export -format nix".`)
+	fmt.Fprintln(w, "# home-manager example:")
+	fmt.Fprintln(w, "#   programs.vim.plugins = import ./volt-plugins.nix { inherit pkgs; };")
+	fmt.Fprintln(w, "{ pkgs, ... }:")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "[")
+	for i := range reposList {
+		p, err := newNixPlugin(&reposList[i])
+		if err != nil {
+			return errors.Wrap(err, reposList[i].Path.String())
+		}
+		fmt.Fprintf(w, "  # %s\n", reposList[i].Path)
+		fmt.Fprintln(w, "  (pkgs.vimUtils.buildVimPlugin {")
+		fmt.Fprintf(w, "    pname = %s;\n", nixString(p.pname))
+		fmt.Fprintf(w, "    version = %s;\n", nixString(p.version))
+		if p.attrs == nil {
+			fmt.Fprintf(w, "    src = %s;\n", p.src)
+		} else {
+			fmt.Fprintf(w, "    src = %s {\n", p.src)
+			for _, attr := range p.attrs {
+				fmt.Fprintf(w, "      %s = %s;\n", attr[0], attr[1])
+			}
+			fmt.Fprintln(w, "    };")
+		}
+		fmt.Fprintln(w, "  })")
+	}
+	fmt.Fprintln(w, "]")
+	return nil
+}
+
+func newNixPlugin(repos *lockjson.Repos) (*nixPlugin, error) {
+	p := &nixPlugin{pname: nixPackageName(filepath.Base(repos.Path.FullPath()))}
+	switch repos.Type {
+	case lockjson.ReposGitType:
+		return p, p.setGitSource(repos)
+	case lockjson.ReposReleaseType:
+		// The repository directory is the extracted archive of the tag
+		userName, ok := repos.Path.GitHubUserName()
+		if !ok {
+			return nil, errReleaseNotGitHub(repos.Path)
+		}
+		hash, err := narHashDir(repos.Path.FullPath())
+		if err != nil {
+			return nil, err
+		}
+		p.version = repos.Version
+		p.setGitHubSource(userName, repos.Version, hash)
+		return p, nil
+	case lockjson.ReposArchiveType:
+		// fetchzip also extracts the contents of the only top directory
+		hash, err := narHashDir(repos.Path.FullPath())
+		if err != nil {
+			return nil, err
+		}
+		p.version = shortArchiveHash(repos.Version)
+		p.src = "pkgs.fetchzip"
+		p.attrs = [][2]string{
+			{"url", nixString(repos.URL)},
+			{"sha256", nixString(hash)},
+		}
+		return p, nil
+	case lockjson.ReposStaticType:
+		p.version = "static"
+		p.src = "/. + " + nixString(repos.Path.FullPath())
+		return p, nil
+	default:
+		return nil, errors.Errorf("unknown type of repository: %s", repos.Type)
+	}
+}
+
+func (p *nixPlugin) setGitSource(repos *lockjson.Repos) error {
+	r, err := git.PlainOpen(repos.Path.FullPath())
+	if err != nil {
+		return err
+	}
+	commit, err := r.CommitObject(plumbing.NewHash(repos.Version))
+	if err != nil {
+		return errors.Wrap(err, "could not get commit "+repos.Version)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	hash, exportAttrs, err := narHashGitTree(r, tree)
+	if err != nil {
+		return err
+	}
+	p.version = commit.Committer.When.UTC().Format("2006-01-02")
+
+	// GitHub archives differ from the tree if .gitattributes has "export-ignore"
+	// or "export-subst"
+	if userName, ok := repos.Path.GitHubUserName(); ok && !exportAttrs {
+		p.setGitHubSource(userName, repos.Version, hash)
+		return nil
+	}
+	p.src = "pkgs.fetchgit"
+	p.attrs = [][2]string{
+		{"url", nixString(repos.Path.CloneURL())},
+		{"rev", nixString(repos.Version)},
+		{"sha256", nixString(hash)},
+		{"fetchSubmodules", "false"},
+	}
+	return nil
+}
+
+func (p *nixPlugin) setGitHubSource(userName, rev, hash string) {
+	ownerRepo := strings.SplitN(userName, "/", 2)
+	p.src = "pkgs.fetchFromGitHub"
+	p.attrs = [][2]string{
+		{"owner", nixString(ownerRepo[0])},
+		{"repo", nixString(ownerRepo[1])},
+		{"rev", nixString(rev)},
+		{"sha256", nixString(hash)},
+	}
+}
+
+// nixString returns a double-quoted Nix string literal of s.
+func nixString(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s)
+	return `"` + s + `"`
+}
+
+// nixPackageName replaces characters which cannot be used in a derivation
+// name with "-".
+func nixPackageName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("+-._?=", r) {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// nixBase32Chars omits "e", "o", "u", and "t".
+const nixBase32Chars = "0123456789abcdfghijklmnpqrsvwxyz"
+
+// nixBase32 encodes hash in base32 of Nix, which is used for sha256 of
+// fetchers.
+func nixBase32(hash []byte) string {
+	n := (len(hash)*8-1)/5 + 1
+	buf := make([]byte, 0, n)
+	for i := n - 1; i >= 0; i-- {
+		b := uint(i * 5)
+		j := b / 8
+		k := b % 8
+		c := hash[j] >> k
+		if int(j)+1 < len(hash) {
+			c |= hash[j+1] << (8 - k)
+		}
+		buf = append(buf, nixBase32Chars[c&0x1f])
+	}
+	return string(buf)
+}
+
+// narWriter writes files in NAR (Nix ARchive) format, whose sha256 is the
+// sha256 of fixed-output derivations of fetchers.
+type narWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (nw *narWriter) str(s string) {
+	nw.bytes([]byte(s))
+}
+
+func (nw *narWriter) bytes(b []byte) {
+	if nw.err != nil {
+		return
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(b)))
+	if _, nw.err = nw.w.Write(buf[:]); nw.err != nil {
+		return
+	}
+	if _, nw.err = nw.w.Write(b); nw.err != nil {
+		return
+	}
+	if pad := (8 - len(b)%8) % 8; pad > 0 {
+		_, nw.err = nw.w.Write(make([]byte, pad))
+	}
+}
+
+func (nw *narWriter) regular(content []byte, executable bool) {
+	nw.str("(")
+	nw.str("type")
+	nw.str("regular")
+	if executable {
+		nw.str("executable")
+		nw.str("")
+	}
+	nw.str("contents")
+	nw.bytes(content)
+	nw.str(")")
+}
+
+func (nw *narWriter) symlink(target string) {
+	nw.str("(")
+	nw.str("type")
+	nw.str("symlink")
+	nw.str("target")
+	nw.str(target)
+	nw.str(")")
+}
+
+// directory writes a directory whose entries are written by node() in order
+// of names.
+func (nw *narWriter) directory(names []string, node func(name string) error) error {
+	sort.Strings(names)
+	nw.str("(")
+	nw.str("type")
+	nw.str("directory")
+	for _, name := range names {
+		nw.str("entry")
+		nw.str("(")
+		nw.str("name")
+		nw.str(name)
+		nw.str("node")
+		if err := node(name); err != nil {
+			return err
+		}
+		nw.str(")")
+	}
+	nw.str(")")
+	return nw.err
+}
+
+// narHashGitTree returns the sha256 of tree in NAR format.
+// Submodules are empty directories like "git clone" without submodules.
+// exportAttrs is true if a .gitattributes file has "export-ignore" or
+// "export-subst".
+func narHashGitTree(r *git.Repository, tree *object.Tree) (hash string, exportAttrs bool, err error) {
+	h := sha256.New()
+	nw := &narWriter{w: h}
+	nw.str("nix-archive-1")
+
+	var writeTree func(tree *object.Tree) error
+	writeTree = func(tree *object.Tree) error {
+		entries := make(map[string]object.TreeEntry, len(tree.Entries))
+		names := make([]string, 0, len(tree.Entries))
+		for _, e := range tree.Entries {
+			entries[e.Name] = e
+			names = append(names, e.Name)
+		}
+		return nw.directory(names, func(name string) error {
+			e := entries[name]
+			switch e.Mode {
+			case filemode.Dir:
+				subtree, err := r.TreeObject(e.Hash)
+				if err != nil {
+					return err
+				}
+				return writeTree(subtree)
+			case filemode.Submodule:
+				return nw.directory(nil, nil)
+			}
+			content, err := readBlob(r, e.Hash)
+			if err != nil {
+				return err
+			}
+			switch e.Mode {
+			case filemode.Symlink:
+				nw.symlink(string(content))
+			case filemode.Regular, filemode.Deprecated, filemode.Executable:
+				nw.regular(content, e.Mode == filemode.Executable)
+				if name == ".gitattributes" &&
+					(bytes.Contains(content, []byte("export-ignore")) || bytes.Contains(content, []byte("export-subst"))) {
+					exportAttrs = true
+				}
+			default:
+				return errors.Errorf("unknown file mode %s: %s", e.Mode, name)
+			}
+			return nw.err
+		})
+	}
+	if err := writeTree(tree); err != nil {
+		return "", false, err
+	}
+	return nixBase32(h.Sum(nil)), exportAttrs, nil
+}
+
+func readBlob(r *git.Repository, hash plumbing.Hash) ([]byte, error) {
+	blob, err := r.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// narHashDir returns the sha256 of the files under dir in NAR format.
+func narHashDir(dir string) (string, error) {
+	h := sha256.New()
+	nw := &narWriter{w: h}
+	nw.str("nix-archive-1")
+
+	var writeFile func(path string) error
+	writeFile = func(path string) error {
+		fi, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case fi.IsDir():
+			infos, err := ioutil.ReadDir(path)
+			if err != nil {
+				return err
+			}
+			names := make([]string, 0, len(infos))
+			for _, info := range infos {
+				names = append(names, info.Name())
+			}
+			return nw.directory(names, func(name string) error {
+				return writeFile(filepath.Join(path, name))
+			})
+		case fi.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			nw.symlink(filepath.ToSlash(target))
+		case fi.Mode().IsRegular():
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			nw.regular(content, fi.Mode()&0100 != 0)
+		default:
+			return errors.New("unsupported file type: " + path)
+		}
+		return nw.err
+	}
+	if err := writeFile(dir); err != nil {
+		return "", err
+	}
+	return nixBase32(h.Sum(nil)), nil
+}
@@ -0,0 +1,108 @@
+package subcmd
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestNixBase32(t *testing.T) {
+	// "nix-hash --type sha256 --to-base32" of sha256("")
+	sum := sha256.Sum256(nil)
+	const expected = "0mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73"
+	if got := nixBase32(sum[:]); got != expected {
+		t.Errorf("expected %s but got %s", expected, got)
+	}
+}
+
+func TestNixString(t *testing.T) {
+	expected := `"a\"b\\c\${d}$e"`
+	if got := nixString(`a"b\c${d}$e`); got != expected {
+		t.Errorf("expected %s but got %s", expected, got)
+	}
+}
+
+// TestNarHashGitTree tests that the hash of a git tree is the same as the
+// hash of the checked out files.
+func TestNarHashGitTree(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-export-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	reposDir := filepath.Join(tempDir, "repos")
+	plainDir := filepath.Join(tempDir, "plain")
+
+	files := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+	}{
+		{"plugin/foo.vim", "let g:loaded_foo = 1\n", 0644},
+		{"autoload/foo/bar.vim", "", 0644},
+		{"bin/foo", "#!/bin/sh\n", 0755},
+		{"README.md", "foo", 0644},
+	}
+	for _, dir := range []string{reposDir, plainDir} {
+		for _, f := range files {
+			path := filepath.Join(dir, filepath.FromSlash(f.name))
+			os.MkdirAll(filepath.Dir(path), 0755)
+			if err := ioutil.WriteFile(path, []byte(f.content), f.mode); err != nil {
+				t.Fatal(err)
+			}
+			// Not to be affected by umask
+			os.Chmod(path, f.mode)
+		}
+		if err := os.Symlink("plugin/foo.vim", filepath.Join(dir, "link")); err != nil {
+			t.Skip("could not create symlink: " + err.Error())
+		}
+	}
+
+	r, err := git.PlainInit(reposDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"plugin/foo.vim", "autoload/foo/bar.vim", "bin/foo", "README.md", "link"} {
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sig := &object.Signature{Name: "volt", Email: "volt@example.com", When: time.Now()}
+	hash, err := wt.Commit("initial", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := r.CommitObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, exportAttrs, err := narHashGitTree(r, tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := narHashDir(plainDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != expected {
+		t.Errorf("expected %s but got %s", expected, got)
+	}
+	if exportAttrs {
+		t.Error("exportAttrs must be false")
+	}
+}
@@ -0,0 +1,86 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// externalCmdPrefix is the prefix of the executables of external commands.
+// "volt foo" runs "volt-foo" in $PATH if foo is not a volt command.
+const externalCmdPrefix = "volt-"
+
+// externalCmd runs an executable "volt-{name}" in $PATH like git.
+type externalCmd struct {
+	name string
+	path string
+}
+
+// lookUpExternalCmd returns the external command of name, or nil if
+// "volt-{name}" is not found in $PATH.
+func lookUpExternalCmd(name string) *externalCmd {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil
+	}
+	path, err := exec.LookPath(externalCmdPrefix + name)
+	if err != nil {
+		return nil
+	}
+	return &externalCmd{name: name, path: path}
+}
+
+// External commands decide it by themselves
+func (cmd *externalCmd) ProhibitRootExecution(args []string) bool { return false }
+
+// FlagSet does not define any flags, all arguments (including "-help") are
+// passed to the executable.
+func (cmd *externalCmd) FlagSet() *flag.FlagSet {
+	return flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+}
+
+func (cmd *externalCmd) Run(cmdctx *CmdContext) *Error {
+	c := exec.CommandContext(cmdctx.Ctx, cmd.path, cmdctx.Args...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(), cmd.environ(cmdctx)...)
+	logger.Debugf("Running external command %s %q", cmd.path, cmdctx.Args)
+	if err := c.Run(); err != nil {
+		code := 1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.ExitStatus() > 0 {
+				code = status.ExitStatus()
+			}
+		}
+		return &Error{Code: code, Msg: fmt.Sprintf("%s%s: %s", externalCmdPrefix, cmd.name, err)}
+	}
+	return nil
+}
+
+// environ returns the environment variables passed to the external command
+// in addition to the environment of volt.
+func (cmd *externalCmd) environ(cmdctx *CmdContext) []string {
+	env := []string{
+		"VOLT_CONFIG=" + pathutil.ConfigTOML(),
+		"VOLT_LOCK_JSON=" + pathutil.LockJSON(),
+		"VOLT_REPOS_DIR=" + pathutil.ReposDir(),
+	}
+	// $VOLTPATH is not set for XDG layout directories not to change the
+	// directories of "volt" invoked by the external command
+	if pathutil.VoltDataDir() == pathutil.VoltPath() {
+		env = append(env, "VOLTPATH="+pathutil.VoltPath())
+	}
+	if cmdctx.LockJSON != nil {
+		env = append(env, "VOLT_PROFILE="+cmdctx.LockJSON.CurrentProfileName)
+	}
+	if exe, err := os.Executable(); err == nil {
+		env = append(env, "VOLT_EXECUTABLE="+exe)
+	}
+	return env
+}
@@ -0,0 +1,86 @@
+package subcmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunExternalCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts cannot be executed on Windows")
+	}
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", tempDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	out := filepath.Join(tempDir, "out")
+	script := "#!/bin/sh\necho \"$@:$VOLTPATH:$VOLT_PROFILE\" >'" + out + "'\nexit $1\n"
+	if err := ioutil.WriteFile(filepath.Join(tempDir, "volt-hello"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Run([]string{"volt", "hello", "0", "-help"}, DefaultRunner); err != nil {
+		t.Fatal(err.Msg)
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "0 -help:" + tempDir + ":default"; strings.TrimSpace(string(b)) != expected {
+		t.Errorf("expected %q but got %q", expected, string(b))
+	}
+
+	// The exit status of the external command is returned
+	if err := Run([]string{"volt", "hello", "3"}, DefaultRunner); err == nil || err.Code != 3 {
+		t.Errorf("expected error code 3 but got %v", err)
+	}
+	if err := Run([]string{"volt", "no-such-command"}, DefaultRunner); err == nil || err.Code != 3 {
+		t.Errorf("expected error code 3 for unknown command but got %v", err)
+	}
+}
+
+func TestExecExternalCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts cannot be executed on Windows")
+	}
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", tempDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	out := filepath.Join(tempDir, "out")
+	script := "#!/bin/sh\necho \"$@\" >'" + out + "'\nexit $1\n"
+	if err := ioutil.WriteFile(filepath.Join(tempDir, "volt-hello"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Exec(context.Background(), "hello", []string{"0", "world"}); err != nil {
+		t.Fatal(err.Msg)
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "0 world"; strings.TrimSpace(string(b)) != expected {
+		t.Errorf("expected %q but got %q", expected, string(b))
+	}
+	if err := Exec(context.Background(), "hello", []string{"3"}); err == nil || err.Code != 3 {
+		t.Errorf("expected error code 3 but got %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package subcmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func init() {
+	cmdMap["freeze"] = &freezeCmd{}
+}
+
+type freezeCmd struct {
+	helped bool
+}
+
+func (cmd *freezeCmd) ProhibitRootExecution(args []string) bool { return false }
+
+func (cmd *freezeCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt freeze [-help]
+
+Quick example
+  $ volt freeze > plugins.lock
+  $ volt get -from-freeze plugins.lock  # on another machine
+
+Description
+  Print the plugins of current profile in lock.json with their exact
+  revisions, one plugin per line:
+
+    {repository} {revision}
+
+  {revision} is a commit hash for git repositories, and "release:{tag}" for
+  release repositories (see "volt get -help"). Archive repositories have the
+  content hash and the URL of the archive ("archive:sha256:{hex} {url}").
+  Static repositories have no {revision}. Lines beginning with "#" and empty lines are ignored by
+  "volt get -from-freeze".
+
+  The output is a lighter-weight and human-diffable alternative to sharing
+  lock.json. Plugconfs and profiles are not included.` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *freezeCmd) Run(cmdctx *CmdContext) *Error {
+	reposList, err := cmd.parseArgs(cmdctx.Args, cmdctx.LockJSON)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if err := writeFreeze(w, reposList); err != nil {
+		return &Error{Code: 11, Msg: "Failed to freeze: " + err.Error()}
+	}
+	if err := w.Flush(); err != nil {
+		return &Error{Code: 11, Msg: "Failed to freeze: " + err.Error()}
+	}
+	return nil
+}
+
+func (cmd *freezeCmd) parseArgs(args []string, lockJSON *lockjson.LockJSON) (lockjson.ReposList, error) {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, ErrShowedHelp
+	}
+	if len(fs.Args()) > 0 {
+		return nil, errors.New("freeze command does not accept arguments")
+	}
+	return lockJSON.GetCurrentReposList()
+}
+
+// freezeReleasePrefix is the prefix of {revision} of release repositories.
+const freezeReleasePrefix = "release:"
+
+// freezeArchivePrefix is the prefix of {revision} of archive repositories,
+// which is followed by the URL.
+const freezeArchivePrefix = "archive:"
+
+// writeFreeze writes "{repository} {revision}" lines of reposList to w.
+func writeFreeze(w io.Writer, reposList lockjson.ReposList) error {
+	for i := range reposList {
+		repos := &reposList[i]
+		var err error
+		switch repos.Type {
+		case lockjson.ReposGitType:
+			_, err = fmt.Fprintf(w, "%s %s\n", repos.Path, repos.Version)
+		case lockjson.ReposReleaseType:
+			_, err = fmt.Fprintf(w, "%s %s%s\n", repos.Path, freezeReleasePrefix, repos.Version)
+		case lockjson.ReposArchiveType:
+			_, err = fmt.Fprintf(w, "%s %s%s %s\n", repos.Path, freezeArchivePrefix, repos.Version, repos.URL)
+		case lockjson.ReposStaticType:
+			_, err = fmt.Fprintf(w, "%s\n", repos.Path)
+		default:
+			err = errors.Errorf("%s: unknown repository type %q", repos.Path, repos.Type)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// freezeEntry is a line of the output of "volt freeze".
+type freezeEntry struct {
+	path pathutil.ReposPath
+	// ref is the commit of git repository
+	ref pathutil.ReposRef
+	// releaseTag is the tag of release repository
+	releaseTag string
+	// archiveHash and archiveURL are the content hash and the URL of
+	// archive repository
+	archiveHash string
+	archiveURL  string
+}
+
+// readFreeze parses the output of "volt freeze".
+// name is used in error messages.
+func readFreeze(r io.Reader, name string) ([]freezeEntry, error) {
+	var entries []freezeEntry
+	scanner := bufio.NewScanner(r)
+	for lnum := 1; scanner.Scan(); lnum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 3 && strings.HasPrefix(fields[1], freezeArchivePrefix) {
+			entry, err := readFreezeArchive(fields)
+			if err != nil {
+				return nil, errors.Errorf("%s:%d: %s", name, lnum, err)
+			}
+			entries = append(entries, *entry)
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, errors.Errorf("%s:%d: expected \"{repository} {revision}\" but got %q", name, lnum, line)
+		}
+		reposPath, ref, err := pathutil.NormalizeReposRef(fields[0])
+		if err == nil && !ref.IsZero() {
+			err = errors.New("version must be given as {revision}: " + fields[0])
+		}
+		if err != nil {
+			return nil, errors.Errorf("%s:%d: %s", name, lnum, err)
+		}
+		entry := freezeEntry{path: reposPath}
+		if len(fields) == 2 {
+			rev := fields[1]
+			if strings.HasPrefix(rev, freezeReleasePrefix) {
+				entry.releaseTag = strings.TrimPrefix(rev, freezeReleasePrefix)
+			} else if _, ref, err := pathutil.NormalizeReposRef(fields[0] + "@" + rev); err == nil && ref.Type == pathutil.ReposRefCommit {
+				entry.ref = ref
+			}
+			if entry.releaseTag == "" && entry.ref.IsZero() {
+				return nil, errors.Errorf("%s:%d: invalid revision %q: must be a commit hash or %q", name, lnum, rev, freezeReleasePrefix+"{tag}")
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// readFreezeArchive parses "{repository} archive:{hash} {url}" fields.
+func readFreezeArchive(fields []string) (*freezeEntry, error) {
+	reposPath, ok, err := parseArchiveURL(fields[2])
+	if !ok {
+		return nil, errors.New("invalid archive URL: " + fields[2])
+	} else if err != nil {
+		return nil, err
+	}
+	if p, err := pathutil.NormalizeRepos(fields[0]); err != nil || !p.Equals(reposPath) {
+		return nil, errors.Errorf("the archive URL is not of %s: %s", fields[0], fields[2])
+	}
+	hash := strings.TrimPrefix(fields[1], freezeArchivePrefix)
+	if !strings.HasPrefix(hash, archiveHashPrefix) {
+		return nil, errors.Errorf("invalid content hash %q: must be %q", hash, archiveHashPrefix+"{hex}")
+	}
+	return &freezeEntry{path: reposPath, archiveHash: hash, archiveURL: fields[2]}, nil
+}
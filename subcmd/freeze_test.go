@@ -0,0 +1,77 @@
+package subcmd
+
+import (
+	"bytes"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestFreeze(t *testing.T) {
+	const commit = "41c2a0d7e76d6d5bb1c6a8c57e8c8e1f42a54f09"
+	reposList := lockjson.ReposList{
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: commit},
+		{Type: lockjson.ReposReleaseType, Path: "github.com/junegunn/fzf", Version: "0.17.0", Release: "latest"},
+		{Type: lockjson.ReposArchiveType, Path: "example.com/user/name", Version: "sha256:0123abcd", URL: "https://example.com/user/name/v1.0.zip"},
+		{Type: lockjson.ReposStaticType, Path: "localhost/local/hello"},
+	}
+	var buf bytes.Buffer
+	if err := writeFreeze(&buf, reposList); err != nil {
+		t.Fatal(err)
+	}
+	expected := "github.com/tyru/caw.vim " + commit + "\n" +
+		"github.com/junegunn/fzf release:0.17.0\n" +
+		"example.com/user/name archive:sha256:0123abcd https://example.com/user/name/v1.0.zip\n" +
+		"localhost/local/hello\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q but got %q", expected, buf.String())
+	}
+
+	entries, err := readFreeze(strings.NewReader("# comment\n\n"+buf.String()), "plugins.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedEntries := []freezeEntry{
+		{path: "github.com/tyru/caw.vim", ref: pathutil.ReposRef{Type: pathutil.ReposRefCommit, Name: commit}},
+		{path: "github.com/junegunn/fzf", releaseTag: "0.17.0"},
+		{path: "example.com/user/name", archiveHash: "sha256:0123abcd", archiveURL: "https://example.com/user/name/v1.0.zip"},
+		{path: "localhost/local/hello"},
+	}
+	if !reflect.DeepEqual(entries, expectedEntries) {
+		t.Errorf("expected %+v but got %+v", expectedEntries, entries)
+	}
+}
+
+func TestReadFreezeErrors(t *testing.T) {
+	for _, tt := range []struct {
+		content  string
+		expected string
+	}{
+		{"tyru/caw.vim master\n", "plugins.lock:1: invalid revision \"master\""},
+		{"\ntyru/caw.vim 41c2a0d extra\n", "plugins.lock:2: expected"},
+		{"tyru/caw.vim@41c2a0d\n", "plugins.lock:1: version must be given as {revision}"},
+		{"caw.vim\n", "plugins.lock:1: invalid format of repository"},
+		{"example.com/a/b archive:sha256:00 https://example.com/c/d.zip\n", "plugins.lock:1: the archive URL is not of"},
+	} {
+		_, err := readFreeze(strings.NewReader(tt.content), "plugins.lock")
+		if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error %q but got %v", tt.content, tt.expected, err)
+		}
+	}
+}
+
+func TestExactReleasePattern(t *testing.T) {
+	for _, tag := range []string{"v1.0", "v1.*", "[beta]", `a\b`} {
+		pattern := exactReleasePattern(tag)
+		if matched, err := path.Match(pattern, tag); err != nil || !matched {
+			t.Errorf("%q: expected %q to match but got (%v, %v)", tag, pattern, matched, err)
+		}
+		if matched, _ := path.Match(pattern, tag+"x"); matched {
+			t.Errorf("%q: expected %q not to match %q", tag, pattern, tag+"x")
+		}
+	}
+}
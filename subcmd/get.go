@@ -1,25 +1,34 @@
 package subcmd
 
 import (
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/mattn/go-isatty"
 	"github.com/pkg/errors"
 
 	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 
 	"github.com/vim-volt/volt/config"
 	"github.com/vim-volt/volt/fileutil"
 	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/httputil"
 	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/metadata"
 	"github.com/vim-volt/volt/pathutil"
 	"github.com/vim-volt/volt/plugconf"
 	"github.com/vim-volt/volt/subcmd/builder"
@@ -33,9 +42,49 @@ func init() {
 }
 
 type getCmd struct {
-	helped   bool
-	lockJSON bool
-	upgrade  bool
+	helped     bool
+	lockJSON   bool
+	upgrade    bool
+	release    string
+	fromFreeze string
+	fromLock   string
+	verify     bool
+	// failFast cancels the remaining repositories on the first failure
+	failFast bool
+	// noDeps does not install the dependencies in s:depends() of plugconf
+	noDeps bool
+	// noBuild does not build ~/.vim/pack/volt after getting (-no-build)
+	noBuild bool
+	// jobs is the number of repositories processed at once (-j). If it is 0,
+	// get.max_parallel of config.toml is used.
+	jobs int
+	// progress shows the progress of each repository in one line (-progress)
+	progress   bool
+	progressUI *logger.Progress
+	// depth, filter, and singleBranch are the options to clone repositories
+	depth        int
+	filter       string
+	singleBranch bool
+	// releases are the release patterns of each repository given by
+	// -from-freeze or -from-lock. They take precedence over -release.
+	releases map[pathutil.ReposPath]string
+	// archives are the archive URLs of each repository given by the
+	// arguments, -from-freeze, or -from-lock
+	archives map[pathutil.ReposPath]string
+	// archiveHashes are the content hashes of the archives given by
+	// -from-freeze or -from-lock
+	archiveHashes map[pathutil.ReposPath]string
+	// srcLockJSON is the lock.json given by -from-lock
+	srcLockJSON *lockjson.LockJSON
+	// pins are the versions which repositories are pinned to in lock.json
+	// (the versions given with the arguments, or "pin" of -from-lock)
+	pins map[pathutil.ReposPath]pathutil.ReposRef
+	// statusList is the results of repositories shown by doGet(), which is
+	// the result of "volt -output json get"
+	statusList []string
+	// dryRun shows what would be changed without changing files ("volt
+	// -dry-run get")
+	dryRun bool
 }
 
 func (cmd *getCmd) ProhibitRootExecution(args []string) bool { return true }
@@ -46,13 +95,27 @@ func (cmd *getCmd) FlagSet() *flag.FlagSet {
 	fs.Usage = func() {
 		fmt.Println(`
 Usage
-  volt get [-help] [-l] [-u] [{repository} ...]
+  volt get [-help] [-l] [-u [-verify]] [-no-build] [-fail-fast] [-j {N}] [-progress] [-release {pattern}] [-depth {depth}] [-filter {filter}] [-single-branch] [{repository} ...]
+  volt get [-help] -from-freeze {file}
+  volt get [-help] -from-lock {url or file}
 
 Quick example
   $ volt get tyru/caw.vim     # will install tyru/caw.vim plugin
   $ volt get -u tyru/caw.vim  # will upgrade tyru/caw.vim plugin
   $ volt get -l -u            # will upgrade all plugins in current profile
-  $ VOLT_DEBUG=1 volt get tyru/caw.vim  # will output more verbosely
+  $ volt get -l -u -verify    # will roll back upgrades which break Vim startup
+  $ volt -log-level debug get tyru/caw.vim  # will output more verbosely
+  $ volt get -release latest tyru/caw.vim  # will install the latest GitHub release of tyru/caw.vim
+  $ volt get -release 'v1.*' tyru/caw.vim  # will install the newest release whose tag matches "v1.*"
+  $ volt get tyru/caw.vim#v1.0  # will check out tag "v1.0" of tyru/caw.vim, and pin it
+  $ volt get tyru/caw.vim@dev   # will check out branch "dev" of tyru/caw.vim
+  $ volt get -from-freeze plugins.lock  # will install plugins written by "volt freeze"
+  $ volt get -from-lock https://example.com/lock.json  # will install plugins and profiles of the lock.json
+  $ volt get -depth 1 tyru/caw.vim  # will clone only the latest commit of tyru/caw.vim
+  $ volt get -l -u -fail-fast  # will stop upgrading on the first failure
+  $ volt get -l -u -j 4        # will upgrade at most 4 plugins at once
+  $ volt get -l -u -progress   # will show the progress of each plugin in one line
+  $ volt get -no-build tyru/caw.vim  # will not build ~/.vim/pack/volt until "volt build"
 
   $ mkdir -p ~/volt/repos/localhost/local/hello/plugin
   $ echo 'command! Hello echom "hello"' >~/volt/repos/localhost/local/hello/plugin/hello.vim
@@ -66,6 +129,9 @@ Description
     https://github.com/vim-volt/plugconf-templates
   and install it to:
     $VOLTPATH/plugconf/{repository}.vim
+  If no template is found, a skeleton plugconf is created. It has commented
+  hints detected from the plugin's files: commands and filetypes (lazy-load
+  candidates), <Plug> mappings, and global variables in the document.
 
 Repository List
   {repository} list (=target to perform installing, upgrading, and so on) is determined as followings:
@@ -81,6 +147,192 @@ Action
       * Fetch {repository} list from remotes
       * Add {repository} list to lock.json (if not found)
 
+Verify upgrades
+  If -verify option is specified with -u, volt launches Vim headlessly after
+  upgrading and building, to load plugins and plugconfs like startup (vimrc is
+  not loaded). If Vim reports errors, the upgraded repositories whose files
+  appear in the error messages (or all upgraded repositories if none appear)
+  are rolled back to the previous versions in lock.json, and shown as:
+
+    ! {repository} > rolled back to {old} (the upgrade to {new} broke Vim startup)
+
+  A git repository is rolled back by moving its branch to the old commit, so
+  the next "volt get -u" upgrades it again. Repositories checked out with a
+  version (e.g. "{repository}@{branch}") are not rolled back.
+  If Vim already has errors before upgrading, verification is skipped.
+  The Vim executable is determined like "volt build" (see build.vim_executable
+  of "volt help config").
+
+Upgrade group
+  Related repositories (e.g. a plugin and its extensions) can be grouped by
+  get.upgrade_groups of config.toml. If -u option is specified, the other
+  repositories in the groups of {repository} list are also upgraded. If any
+  repository in a group failed to upgrade, the other upgraded repositories in
+  the group are rolled back to the previous versions in lock.json, and shown as:
+
+    ! {repository} > rolled back to {old} ({failed repository} in upgrade group "{name}" failed)
+
+Dependencies
+  The plugins in s:depends() of the plugconfs of {repository} list are also
+  installed and added to current profile if they are not in current profile,
+  and so are their dependencies. volt fails without installing them if the
+  plugins depend on each other. The dependencies are not installed if
+  -no-deps option is specified, or -from-lock or -from-freeze option is
+  specified (they should have the dependencies).
+
+Parallelism
+  Repositories are installed or upgraded in parallel: at most
+  get.max_parallel (default 16) repositories at once, and at most
+  get.max_connections_per_host (default 8) repositories of the same host at
+  once. -j option overrides get.max_parallel. 0 of config.toml means no limit.
+
+  If -progress option is specified and stdout is a terminal, the progress of
+  each repository (e.g. "Receiving objects:  45% (123/273)") is shown in one
+  line which is updated in place, instead of "(N/M) {repository} ... Done.":
+
+    github.com/tyru/caw.vim  Done
+    github.com/tyru/open-browser.vim  Receiving objects:  45% (123/273)
+    github.com/junegunn/fzf  Waiting
+
+Private repositories
+  Private repositories are cloned and fetched via HTTP(S) with the credential
+  of the host in config.toml (e.g. [credentials."github.com"]): an access
+  token, a username and a password, or a git credential helper.
+
+Failure
+  When cloning or upgrading a repository, or fetching its plugconf template
+  fails because of a network error, it is tried again at most get.retry
+  (default 2) times. volt waits get.retry_delay (default "1s") before the
+  first retry, and doubles the delay before each next retry.
+
+  If some repositories failed, the others are still installed or upgraded, and
+  lock.json is updated for them. If -fail-fast option is specified, the
+  remaining repositories are aborted on the first failure, and shown as:
+
+    ! {repository} > aborted (-fail-fast)
+
+  If volt is interrupted (e.g. Ctrl-C), or fails to write lock.json or to
+  build ~/.vim/pack/volt, the whole operation is rolled back: the installed
+  repositories and the created plugconfs are removed, and lock.json and
+  ~/.vim/pack/volt are restored. Upgraded repositories are not rolled back.
+
+  The exit status is:
+    0   all repositories succeeded
+    20  some repositories failed ("failed to install N of M plugins"), or
+        other errors
+    21  all repositories failed
+    22  the dependencies could not be installed
+
+Release repository
+  If -release option is specified, volt installs the source archive of a GitHub
+  release instead of cloning the repository. This is useful for plugins whose
+  releases include generated files absent from the default branch.
+  {pattern} is "latest" (the latest release) or a tag pattern like "v1.*"
+  (the newest release whose tag matches it). Drafts and prereleases are ignored.
+  The pattern is saved in lock.json, and "volt get -u" upgrades the repository
+  to the newest release matched with it. "volt get -l" installs the locked
+  release.
+
+Archive repository
+  If {repository} is an http or https URL of .tar.gz, .tgz, or .zip archive
+  (e.g. "https://example.com/user/name/archive/v1.0.tar.gz"), volt downloads
+  and extracts it instead of cloning. The repository is "{host}/{user}/{name}"
+  of the URL ("https://{host}/{user}/{name}.zip" is also allowed). If the
+  archive has only one top directory, its contents are installed.
+  The URL and the content hash of the archive ("sha256:{hex}") are saved in
+  lock.json. "volt get -u" downloads the URL again, and "volt get -l" installs
+  the archive only if its content hash is the locked one. To change the URL,
+  run "volt get" with the new URL.
+
+Version
+  A branch, a tag, or a commit can be specified after {repository}:
+
+    {repository}@{branch}   check out {branch}
+    {repository}#{tag}      check out {tag}
+    {repository}@{tag}      check out {tag} (if no branch has the name)
+    {repository}@{commit}   check out {commit} (7-40 hex digits)
+
+  The version is checked out when installing, and also when the repository is
+  already installed (the remote is fetched if the version is not found locally).
+  The repository is pinned to the version: it is saved as "pin" of lock.json,
+  and checked out when the repository is installed again (e.g. "volt get -l"
+  on another machine).
+  A tag or a commit is checked out as detached HEAD, and "volt get -u" does not
+  upgrade it:
+
+    # {repository} > pinned to {version} (not upgraded)
+
+  A branch is checked out as a local branch tracking the remote one, and
+  "volt get -u" upgrades it. To change the pin, specify another version
+  (e.g. "volt get {repository}@master" to follow the master branch again).
+
+Submodule
+  Git submodules of a repository (e.g. bundled dependencies) are initialized
+  and updated recursively after installing, upgrading, or checking out it, and
+  installed with the repository by "volt build". "git" command is used if it
+  exists, because go-git does not support relative submodule URLs.
+  If the update failed, a warning is shown and the repository is installed
+  without the submodules.
+
+Freeze file
+  If -from-freeze option is specified, the plugins in {file} (the output of
+  "volt freeze", or "-" for stdin) are installed with the exact revisions:
+  a commit of git repository is checked out as detached HEAD (like
+  "{repository}@{commit}"), and a release repository installs the release of
+  the tag (the tag is saved as the release pattern, so "volt get -u" does not
+  upgrade it). Static repositories are added if they exist.
+
+Lock file
+  If -from-lock option is specified, the repositories in lock.json of another
+  environment ({url} of http or https, or a local {file}) are installed with
+  the locked revisions like -from-freeze option, and the clone options saved in
+  it are used. Static repositories are added only if they exist.
+  The profiles of the lock.json are merged into lock.json: the profiles which
+  do not exist are created, and the installed repositories are added to the
+  existing profiles of the same names (and to "no_build" if they are not built
+  in the profile). Repositories are not added to current profile unless it
+  has them. Current profile is not changed. Plugconfs are not included in
+  lock.json, so the templates are installed like "volt get".
+
+    $ volt get -from-lock https://example.com/dotfiles/volt/lock.json
+    $ volt profile set {profile}  # use a merged profile
+
+Clone options
+  -depth, -filter, and -single-branch options are passed to "git clone"
+  (--depth, --filter, and --single-branch) when installing git repositories.
+  The options are saved in lock.json ("clone" of each repository), and the
+  repository is cloned with the same options when it is installed again (e.g.
+  "volt get -l" on another machine). If the options are given for a
+  repository which is already in lock.json, they replace the saved ones when it
+  is installed.
+  -filter (partial clone) needs "git" command.
+  If -depth is not given, get.fetch_depth and get.fetch_shallow_since of
+  config.toml make shallow clones (they are not saved in lock.json).
+
+SSH repository
+  If the clone URL of a repository is an SSH URL (see get.clone_url and
+  get.repos_clone_url of config.toml) and a custom SSH command is configured
+  (GIT_SSH_COMMAND, GIT_SSH, or core.sshCommand of git config), volt executes
+  "git" command to clone and upgrade the repository, so the custom port, jump
+  host, identity, and so on are used.
+
+Renamed repository
+  If the remote permanently redirects a repository to another path (e.g. the
+  repository was renamed or transferred on GitHub), volt follows it when
+  installing or upgrading: the repository directory and plugconf are moved to
+  the new path, and lock.json is updated.
+
+Archived repository
+  When installing or upgrading a GitHub repository, a warning is shown if it has
+  been archived upstream. The information is fetched from GitHub API, and
+  cached for 24 hours (see "metadata" of "volt list -help").
+
+Dry run
+  If -dry-run global option was given ("volt -dry-run get"), the repositories
+  which would be cloned, upgraded, or added to lock.json are shown without
+  accessing the remotes nor changing files. The dependencies in s:depends()
+  of plugconfs which are not downloaded yet are not shown.
+
 Static repository
     Volt can manage a local directory as a repository. It's called "static repository".
     When you have unpublished plugins, or you want to manage ~/.vim/* files as one repository
@@ -106,6 +358,8 @@ Repository path
   3. https://{site}/{user}/{name}
   4. http://{site}/{user}/{name}
 
+  Each format can be followed by a version (see "Version").
+
 Options`)
 		fs.PrintDefaults()
 		fmt.Println()
@@ -113,35 +367,75 @@ Options`)
 	}
 	fs.BoolVar(&cmd.lockJSON, "l", false, "use all plugins in current profile as targets")
 	fs.BoolVar(&cmd.upgrade, "u", false, "upgrade plugins")
+	fs.StringVar(&cmd.release, "release", "", "install GitHub releases whose tag matches the pattern (\"latest\" for the latest release) instead of cloning")
+	fs.StringVar(&cmd.fromFreeze, "from-freeze", "", "install plugins written by \"volt freeze\" (\"-\" for stdin)")
+	fs.StringVar(&cmd.fromLock, "from-lock", "", "install plugins and merge profiles of lock.json at the URL or the file")
+	fs.BoolVar(&cmd.verify, "verify", false, "check Vim startup after upgrading, and roll back the upgrades which broke it")
+	fs.BoolVar(&cmd.failFast, "fail-fast", false, "abort the remaining repositories on the first failure")
+	fs.BoolVar(&cmd.noDeps, "no-deps", false, "do not install the dependencies in s:depends() of plugconf")
+	fs.BoolVar(&cmd.noBuild, "no-build", false, "do not build ~/.vim/pack/volt (run \"volt build\" later)")
+	fs.BoolVar(&cmd.progress, "progress", false, "show the progress of each repository in one line")
+	fs.IntVar(&cmd.jobs, "j", 0, "install or upgrade at most N repositories at once (default: get.max_parallel of config.toml)")
+	fs.IntVar(&cmd.depth, "depth", 0, "clone only the number of the latest commits")
+	fs.StringVar(&cmd.filter, "filter", "", "clone with the partial clone filter (e.g. \"blob:none\")")
+	fs.BoolVar(&cmd.singleBranch, "single-branch", false, "clone only the history of one branch")
 	return fs
 }
 
-func (cmd *getCmd) Run(args []string) *Error {
+func (cmd *getCmd) Run(cmdctx *CmdContext) *Error {
 	// Parse args
-	args, err := cmd.parseArgs(args)
+	args, err := cmd.parseArgs(cmdctx.Args)
 	if err == ErrShowedHelp {
 		return nil
 	}
 	if err != nil {
 		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
 	}
+	cmd.dryRun = cmdctx.DryRun
+	if cmd.noBuild && !builder.IsBuildSkipped() {
+		builder.SkipBuild(true)
+		defer builder.SkipBuild(false)
+	}
 
-	// Read lock.json
-	lockJSON, err := lockjson.Read()
-	if err != nil {
-		return &Error{Code: 11, Msg: "Could not read lock.json: " + err.Error()}
+	if cmd.fromLock != "" {
+		cmd.srcLockJSON, err = readLockJSONFrom(cmdctx.Ctx, cmd.fromLock)
+		if err != nil {
+			return &Error{Code: 12, Msg: "Could not read " + cmd.fromLock + ": " + err.Error()}
+		}
 	}
 
-	reposPathList, err := cmd.getReposPathList(args, lockJSON)
+	reposPathList, refs, err := cmd.getReposPathList(args, cmdctx.LockJSON)
 	if err != nil {
 		return &Error{Code: 12, Msg: "Could not get repos list: " + err.Error()}
 	}
 	if len(reposPathList) == 0 {
 		return &Error{Code: 13, Msg: "No repositories are specified"}
 	}
+	if cmd.upgrade {
+		reposPathList = cmd.addUpgradeGroups(reposPathList, cmdctx.LockJSON, cmdctx.Config)
+	}
 
-	err = cmd.doGet(reposPathList, lockJSON)
-	if err != nil {
+	if cmd.dryRun {
+		if err := cmd.dryRunGet(reposPathList, refs, cmdctx.LockJSON, cmdctx.Config); err != nil {
+			return &Error{Code: 20, Msg: err.Error()}
+		}
+		return nil
+	}
+
+	// Show warnings of parallel tasks again after the result
+	logger.CollectWarnings()
+	defer logger.PrintWarningSummary()
+
+	err = cmd.doGet(cmdctx.Ctx, reposPathList, refs, cmdctx.LockJSON, cmdctx.Config)
+	defer func() { cmdctx.Result = parseStatusList(cmd.statusList) }()
+	if e, ok := err.(*getFailedError); (err == nil || ok && e.failed < e.total) && cmd.installsDepends() {
+		if depErr := cmd.installDepends(cmdctx.Ctx, reposPathList, cmdctx.LockJSON, cmdctx.Config); depErr != nil {
+			return &Error{Code: 22, Msg: "Could not install dependencies: " + depErr.Error()}
+		}
+	}
+	if e, ok := err.(*getFailedError); ok && e.failed == e.total {
+		return &Error{Code: 21, Msg: err.Error()}
+	} else if err != nil {
 		return &Error{Code: 20, Msg: err.Error()}
 	}
 
@@ -155,20 +449,115 @@ func (cmd *getCmd) parseArgs(args []string) ([]string, error) {
 		return nil, ErrShowedHelp
 	}
 
+	if cmd.verify && !cmd.upgrade {
+		return nil, errors.New("-verify must be used with -u")
+	}
+	if cmd.verify && (cmd.noBuild || builder.IsBuildSkipped()) {
+		return nil, errors.New("-verify cannot be used with -no-build")
+	}
+
+	if cmd.jobs < 0 {
+		return nil, errors.New("-j must not be negative")
+	}
+	if cmd.depth < 0 {
+		return nil, errors.New("-depth must not be negative")
+	}
+	if cmd.cloneOptions(nil) != nil && cmd.release != "" {
+		return nil, errors.New("-depth, -filter, and -single-branch cannot be used with -release")
+	}
+
+	if cmd.fromFreeze != "" {
+		if cmd.lockJSON || cmd.release != "" || len(fs.Args()) > 0 {
+			return nil, errors.New("-from-freeze cannot be used with -l, -release, or repositories")
+		}
+		return nil, nil
+	}
+	if cmd.fromLock != "" {
+		if cmd.lockJSON || cmd.upgrade || cmd.release != "" || cmd.fromFreeze != "" || len(fs.Args()) > 0 {
+			return nil, errors.New("-from-lock cannot be used with -l, -u, -release, -from-freeze, or repositories")
+		}
+		return nil, nil
+	}
+
 	if !cmd.lockJSON && len(fs.Args()) == 0 {
 		fs.Usage()
 		return nil, errors.New("repository was not given")
 	}
 
+	if cmd.release != "" {
+		if cmd.lockJSON {
+			return nil, errors.New("-release cannot be used with -l")
+		}
+		if err := validateReleasePattern(cmd.release); err != nil {
+			return nil, err
+		}
+	}
+
 	return fs.Args(), nil
 }
 
-func (cmd *getCmd) getReposPathList(args []string, lockJSON *lockjson.LockJSON) ([]pathutil.ReposPath, error) {
+// getReposPathList returns the target repositories, and the versions specified
+// with the arguments (e.g. "user/name@branch").
+func (cmd *getCmd) getReposPathList(args []string, lockJSON *lockjson.LockJSON) ([]pathutil.ReposPath, map[pathutil.ReposPath]pathutil.ReposRef, error) {
 	var reposPathList []pathutil.ReposPath
-	if cmd.lockJSON {
+	refs := make(map[pathutil.ReposPath]pathutil.ReposRef)
+	cmd.pins = make(map[pathutil.ReposPath]pathutil.ReposRef)
+	cmd.archives = make(map[pathutil.ReposPath]string)
+	cmd.archiveHashes = make(map[pathutil.ReposPath]string)
+	if cmd.fromFreeze != "" {
+		entries, err := cmd.readFreezeFile(cmd.fromFreeze)
+		if err != nil {
+			return nil, nil, err
+		}
+		reposPathList = make([]pathutil.ReposPath, 0, len(entries))
+		cmd.releases = make(map[pathutil.ReposPath]string)
+		for _, entry := range entries {
+			reposPath := entry.path
+			if r := lockJSON.Repos.FindByPath(reposPath); r != nil {
+				reposPath = r.Path
+			}
+			if !entry.ref.IsZero() {
+				refs[reposPath] = entry.ref
+			}
+			if entry.releaseTag != "" {
+				cmd.releases[reposPath] = exactReleasePattern(entry.releaseTag)
+			}
+			if entry.archiveURL != "" {
+				cmd.archives[reposPath] = entry.archiveURL
+				cmd.archiveHashes[reposPath] = entry.archiveHash
+			}
+			reposPathList = append(reposPathList, reposPath)
+		}
+	} else if cmd.srcLockJSON != nil {
+		reposPathList = make([]pathutil.ReposPath, 0, len(cmd.srcLockJSON.Repos))
+		cmd.releases = make(map[pathutil.ReposPath]string)
+		for i := range cmd.srcLockJSON.Repos {
+			repos := &cmd.srcLockJSON.Repos[i]
+			switch repos.Type {
+			case lockjson.ReposGitType:
+				if pin := repos.PinnedRef(); !pin.IsZero() {
+					refs[repos.Path] = pin
+					cmd.pins[repos.Path] = pin
+				} else if repos.Version != "" {
+					refs[repos.Path] = pathutil.ReposRef{Type: pathutil.ReposRefCommit, Name: repos.Version}
+				}
+			case lockjson.ReposReleaseType:
+				cmd.releases[repos.Path] = exactReleasePattern(repos.Version)
+			case lockjson.ReposArchiveType:
+				cmd.archives[repos.Path] = repos.URL
+				cmd.archiveHashes[repos.Path] = repos.Version
+			default:
+				if !pathutil.Exists(repos.Path.FullPath()) {
+					logger.Warnf("%s: skipped %s repository which does not exist", repos.Path, repos.Type)
+					continue
+				}
+			}
+			reposPathList = append(reposPathList, repos.Path)
+		}
+	} else if cmd.lockJSON {
 		reposList, err := lockJSON.GetCurrentReposList()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		reposPathList = make([]pathutil.ReposPath, 0, len(reposList))
 		for i := range reposList {
@@ -177,22 +566,116 @@ func (cmd *getCmd) getReposPathList(args []string, lockJSON *lockjson.LockJSON)
 	} else {
 		reposPathList = make([]pathutil.ReposPath, 0, len(args))
 		for _, arg := range args {
-			reposPath, err := pathutil.NormalizeRepos(arg)
+			if reposPath, ok, err := parseArchiveURL(arg); ok {
+				if err != nil {
+					return nil, nil, err
+				}
+				if cmd.release != "" || cmd.cloneOptions(nil) != nil {
+					return nil, nil, errors.New("-release, -depth, -filter, and -single-branch cannot be used with an archive URL: " + arg)
+				}
+				if r := lockJSON.Repos.FindByPath(reposPath); r != nil {
+					reposPath = r.Path
+				}
+				cmd.archives[reposPath] = arg
+				reposPathList = append(reposPathList, reposPath)
+				continue
+			}
+			reposPath, ref, err := pathutil.NormalizeReposRef(arg)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			// Get the existing entries if already have it
 			// (e.g. github.com/tyru/CaW.vim -> github.com/tyru/caw.vim)
 			if r := lockJSON.Repos.FindByPath(reposPath); r != nil {
 				reposPath = r.Path
 			}
+			if !ref.IsZero() {
+				if cmd.release != "" {
+					return nil, nil, errors.New("-release cannot be used with a version: " + arg)
+				}
+				refs[reposPath] = ref
+				cmd.pins[reposPath] = ref
+			}
 			reposPathList = append(reposPathList, reposPath)
 		}
 	}
-	return reposPathList, nil
+
+	// Check out the pinned versions when installing
+	for _, reposPath := range reposPathList {
+		if _, exists := refs[reposPath]; exists || pathutil.Exists(reposPath.FullPath()) {
+			continue
+		}
+		if r := lockJSON.Repos.FindByPath(reposPath); r != nil && r.Pin != "" {
+			refs[reposPath] = r.PinnedRef()
+		}
+	}
+	return reposPathList, refs, nil
 }
 
-func (cmd *getCmd) doGet(reposPathList []pathutil.ReposPath, lockJSON *lockjson.LockJSON) (err error) {
+// readLockJSONFrom reads lock.json from src (a URL of http or https, or a
+// file path).
+func readLockJSONFrom(ctx context.Context, src string) (*lockjson.LockJSON, error) {
+	var content []byte
+	var err error
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		content, err = httputil.GetContent(ctx, src)
+	} else {
+		content, err = ioutil.ReadFile(src)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lockjson.Parse(content)
+}
+
+// mergeProfiles merges the profiles of src into dst. Only the repositories
+// in dst are added to the profiles. The names of the created or changed
+// profiles are returned.
+func mergeProfiles(dst, src *lockjson.LockJSON) []string {
+	var names []string
+	for i := range src.Profiles {
+		srcProfile := &src.Profiles[i]
+		profile, err := dst.Profiles.FindByName(srcProfile.Name)
+		changed := false
+		if err != nil {
+			dst.Profiles = append(dst.Profiles, lockjson.Profile{
+				Name:      srcProfile.Name,
+				ReposPath: make([]pathutil.ReposPath, 0, len(srcProfile.ReposPath)),
+			})
+			profile = &dst.Profiles[len(dst.Profiles)-1]
+			changed = true
+		}
+		for _, reposPath := range srcProfile.ReposPath {
+			if dst.Repos.FindByPath(reposPath) == nil || profile.ReposPath.Contains(reposPath) {
+				continue
+			}
+			profile.ReposPath = append(profile.ReposPath, reposPath)
+			if srcProfile.NoBuild.Contains(reposPath) {
+				profile.NoBuild = append(profile.NoBuild, reposPath)
+			}
+			changed = true
+		}
+		if changed {
+			names = append(names, srcProfile.Name)
+		}
+	}
+	return names
+}
+
+// readFreezeFile reads the output of "volt freeze" from path ("-" is stdin).
+func (*getCmd) readFreezeFile(path string) ([]freezeEntry, error) {
+	if path == "-" {
+		return readFreeze(os.Stdin, "<stdin>")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readFreeze(f, path)
+}
+
+func (cmd *getCmd) doGet(ctx context.Context, reposPathList []pathutil.ReposPath, refs map[pathutil.ReposPath]pathutil.ReposRef, lockJSON *lockjson.LockJSON, cfg *config.Config) (err error) {
 	// Find matching profile
 	profile, err := lockJSON.Profiles.FindByName(lockJSON.CurrentProfileName)
 	if err != nil {
@@ -201,85 +684,379 @@ func (cmd *getCmd) doGet(reposPathList []pathutil.ReposPath, lockJSON *lockjson.
 		return
 	}
 
+	// -from-lock adds repositories to the profiles of the source lock.json
+	// instead of current profile
+	if cmd.srcLockJSON != nil {
+		profile = nil
+	}
+
 	// Begin transaction
+	// If interrupted, or failed to write lock.json or build, remove installed
+	// repositories and plugconfs, and restore lock.json and ~/.vim/pack/volt
 	trx, err := transaction.Start()
 	if err != nil {
 		return
 	}
+	rollback := false
 	defer func() {
+		if ctx.Err() != nil || rollback {
+			if e := trx.Rollback(); e != nil {
+				err = multierror.Append(err, errors.Wrap(e, "failed to rollback"))
+			}
+			return
+		}
 		if e := trx.Done(); e != nil {
 			err = e
 		}
 	}()
 
-	// Read config.toml
-	cfg, err := config.Read()
-	if err != nil {
-		err = errors.Wrap(err, "could not read config.toml")
-		return
+	// Check Vim startup before upgrading not to roll back upgrades because of
+	// existing errors
+	var vimExePath string
+	var previous map[pathutil.ReposPath]lockjson.Repos
+	if cmd.verify {
+		vimExePath, err = pathutil.VimExecutable(cfg.Build.VimExecutableOf(lockJSON.CurrentProfileName))
+		if err != nil {
+			err = errors.Wrap(err, "-verify needs Vim")
+			return
+		}
+		var msg string
+		if msg, err = checkStartup(ctx, vimExePath); err != nil {
+			err = errors.Wrap(err, "could not check Vim startup")
+			return
+		}
+		if msg == "" {
+			previous = make(map[pathutil.ReposPath]lockjson.Repos)
+		} else {
+			logger.Warnf("Vim startup already has errors, upgrades are not verified:\n%s", msg)
+		}
 	}
 
+	// taskCtx is canceled on the first failure if -fail-fast is specified.
+	// ctx is not canceled, so lock.json is written for succeeded repositories.
+	taskCtx, cancelTasks := context.WithCancel(ctx)
+	defer cancelTasks()
+
 	done := make(chan getParallelResult, len(reposPathList))
 	getCount := 0
-	// Invoke installing / upgrading tasks
+	maxParallel := *cfg.Get.MaxParallel
+	if cmd.jobs > 0 {
+		maxParallel = cmd.jobs
+	}
+	limiter := newHostLimiter(*cfg.Get.MaxConnectionsPerHost, maxParallel)
+	targets := make([]pathutil.ReposPath, 0, len(reposPathList))
 	for _, reposPath := range reposPathList {
 		repos := lockJSON.Repos.FindByPath(reposPath)
-		if repos == nil || repos.Type == lockjson.ReposGitType {
-			go cmd.getParallel(reposPath, repos, cfg, done)
-			getCount++
+		if repos == nil || repos.Type == lockjson.ReposGitType || repos.Type == lockjson.ReposReleaseType || repos.Type == lockjson.ReposArchiveType {
+			targets = append(targets, reposPath)
+		}
+	}
+	if cmd.progress && len(targets) > 0 {
+		if logger.CurrentFormat() == logger.JSONFormat {
+			logger.Debug("Progress is not shown because log format is json")
+		} else if isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+			cmd.progressUI = logger.StartProgress(pathutil.ReposPathList(targets).Strings())
+			defer cmd.progressUI.Stop()
+		} else {
+			logger.Debug("Progress is not shown because stdout is not a terminal")
 		}
 	}
+	// Invoke installing / upgrading tasks
+	for _, reposPath := range targets {
+		cmd.progressUI.Set(reposPath.String(), "Waiting")
+		go cmd.getParallel(taskCtx, reposPath, refs[reposPath], lockJSON.Repos.FindByPath(reposPath), cfg, limiter, done)
+		getCount++
+	}
 
 	// Wait results
-	failed := false
+	var failed, aborted int
 	statusList := make([]string, 0, getCount)
+	var renamedList []getParallelResult
 	var updatedLockJSON bool
+	// statusIndex is the index of statusList of upgraded repositories
+	statusIndex := make(map[pathutil.ReposPath]int)
+	// grouped is the entries of lock.json before upgrading of the upgraded
+	// repositories in get.upgrade_groups, and failedRepos is the failed ones
+	grouped := make(map[pathutil.ReposPath]lockjson.Repos)
+	failedRepos := make(map[pathutil.ReposPath]bool)
 	for i := 0; i < getCount; i++ {
 		r := <-done
+		r.log.Flush()
 		status := cmd.formatStatus(&r)
 		// Update repos[]/version
 		if strings.HasPrefix(status, statusPrefixFailed) {
-			failed = true
+			if r.err != nil && taskCtx.Err() != nil && ctx.Err() == nil {
+				cmd.reportResult(i+1, getCount, r.reposPath, "Aborted")
+				status = fmt.Sprintf(fmtAborted, r.reposPath)
+				aborted++
+			} else {
+				cmd.reportResult(i+1, getCount, r.reposPath, "Failed")
+			}
+			failed++
+			failedRepos[r.reposPath] = true
+			if cmd.failFast && taskCtx.Err() == nil {
+				logger.Warnf("Aborting the remaining repositories because %s failed (-fail-fast)", r.reposPath)
+				cancelTasks()
+			}
 		} else {
-			added := cmd.updateReposVersion(lockJSON, r.reposPath, r.reposType, r.hash, profile)
-			if added && strings.Contains(status, "already exists") {
+			cmd.reportResult(i+1, getCount, r.reposPath, "Done")
+			if r.installed {
+				trx.Created(r.reposPath.FullPath())
+			}
+			if repos := lockJSON.Repos.FindByPath(r.reposPath); repos != nil &&
+				repos.Type == r.reposType && repos.Version != r.hash &&
+				r.renamedTo == "" && refs[r.reposPath].IsZero() {
+				statusIndex[r.reposPath] = len(statusList)
+				if previous != nil {
+					previous[r.reposPath] = *repos
+				}
+				if name, _ := cfg.Get.UpgradeGroupOf(r.reposPath); cmd.upgrade && name != "" {
+					grouped[r.reposPath] = *repos
+				}
+			}
+			added := cmd.updateReposVersion(lockJSON, &r, profile)
+			if added && profile != nil && strings.Contains(status, "already exists") {
 				status = fmt.Sprintf(fmtAddedRepos, r.reposPath)
 			}
+			if r.renamedTo != "" {
+				renamedList = append(renamedList, r)
+			}
 			updatedLockJSON = true
 		}
 		statusList = append(statusList, status)
 	}
+	cmd.progressUI.Stop()
 
-	// Sort by status
-	sort.Strings(statusList)
+	// Do not write lock.json and build if interrupted
+	if ctx.Err() != nil {
+		err = errors.New("interrupted")
+		return
+	}
+
+	// Roll back the upgrade groups which have failed repositories
+	for reposPath, status := range cmd.rollbackUpgradeGroups(ctx, grouped, failedRepos, lockJSON, cfg) {
+		statusList[statusIndex[reposPath]] = status
+		delete(previous, reposPath)
+	}
+
+	// Follow renamed or transferred repositories
+	for _, r := range renamedList {
+		if e := cmd.renameRepos(lockJSON, r.reposPath, r.renamedTo, cfg); e != nil {
+			logger.Warnf("Could not rename %s to %s: %s", r.reposPath, r.renamedTo, e)
+			continue
+		}
+		statusList = append(statusList, fmt.Sprintf(fmtRenamed, r.reposPath, r.renamedTo))
+	}
+
+	// Merge the profiles of the source lock.json
+	if cmd.srcLockJSON != nil {
+		for _, name := range mergeProfiles(lockJSON, cmd.srcLockJSON) {
+			statusList = append(statusList, fmt.Sprintf(fmtMergedProfile, name))
+			updatedLockJSON = true
+		}
+	}
 
 	if updatedLockJSON {
 		// Write to lock.json
 		err = lockJSON.Write()
 		if err != nil {
 			err = errors.Wrap(err, "could not write to lock.json")
+			rollback = true
 			return
 		}
+		if e := removeNotifyUpdates(lockJSON); e != nil {
+			logger.Debugf("Could not update %s: %s", pathutil.NotifyStatusFile(), e)
+		}
 	}
 
 	// Build ~/.vim/pack/volt dir
-	err = builder.Build(false)
+	err = builder.Build(ctx, false)
 	if err != nil {
 		err = errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+		rollback = true
 		return
 	}
 
-	// Show results
+	// Roll back upgrades which broke Vim startup
+	var verifyErr error
+	if len(previous) > 0 {
+		var rolledBack map[pathutil.ReposPath]string
+		rolledBack, verifyErr = cmd.verifyUpgrades(ctx, vimExePath, previous, lockJSON)
+		for reposPath, status := range rolledBack {
+			statusList[statusIndex[reposPath]] = status
+		}
+	}
+
+	// Show results sorted by status
+	sort.Strings(statusList)
 	for i := range statusList {
 		fmt.Println(statusList[i])
 	}
-	if failed {
-		err = errors.New("failed to install some plugins")
+	cmd.statusList = append(cmd.statusList, statusList...)
+	if verifyErr != nil {
+		err = verifyErr
+		return
+	}
+	if failed > 0 {
+		err = &getFailedError{failed: failed, aborted: aborted, total: getCount}
 		return
 	}
 	return
 }
 
+// dryRunGet shows what doGet() would do for reposPathList without cloning,
+// fetching, nor writing files ("volt -dry-run get").
+func (cmd *getCmd) dryRunGet(reposPathList []pathutil.ReposPath, refs map[pathutil.ReposPath]pathutil.ReposRef, lockJSON *lockjson.LockJSON, cfg *config.Config) error {
+	profile, err := lockJSON.Profiles.FindByName(lockJSON.CurrentProfileName)
+	if err != nil {
+		return err
+	}
+	if cmd.srcLockJSON != nil {
+		profile = nil
+	}
+
+	writesLockJSON := false
+	for _, reposPath := range reposPathList {
+		repos := lockJSON.Repos.FindByPath(reposPath)
+		if repos != nil && repos.Type == lockjson.ReposStaticType {
+			continue
+		}
+		fullReposPath := reposPath.FullPath()
+		ref := refs[reposPath]
+		release := cmd.release != "" || cmd.releases[reposPath] != "" || repos != nil && repos.Type == lockjson.ReposReleaseType
+		archive := cmd.archives[reposPath] != "" || repos != nil && repos.Type == lockjson.ReposArchiveType
+		switch {
+		case !pathutil.Exists(fullReposPath) && archive:
+			logDryRun("Would download an archive of %s to %s", reposPath, fullReposPath)
+		case !pathutil.Exists(fullReposPath) && release:
+			logDryRun("Would download a release of %s to %s", reposPath, fullReposPath)
+		case !pathutil.Exists(fullReposPath):
+			logDryRun("Would clone %s to %s", reposPath.CloneURL(), fullReposPath)
+		case !ref.IsZero():
+			logDryRun("Would fetch %s and check out %s", reposPath, ref)
+		case cmd.upgrade && archive:
+			logDryRun("Would download the archive of %s again", reposPath)
+		case cmd.upgrade && release:
+			logDryRun("Would upgrade the release of %s", reposPath)
+		case cmd.upgrade && cmd.isPinnedToFixedRef(reposPath, repos):
+			logDryRun("Would skip upgrading %s pinned to %s", reposPath, repos.Pin)
+		case cmd.upgrade:
+			logDryRun("Would pull %s", reposPath)
+		}
+		if *cfg.Get.CreateSkeletonPlugconf && !pathutil.Exists(reposPath.Plugconf()) {
+			logDryRun("Would download or create %s", reposPath.Plugconf())
+		}
+		if repos == nil {
+			logDryRun("Would add %s to lock.json", reposPath)
+		}
+		if profile != nil && !profile.ReposPath.Contains(reposPath) {
+			logDryRun("Would add %s to profile '%s'", reposPath, profile.Name)
+		}
+		writesLockJSON = true
+	}
+	if cmd.srcLockJSON != nil {
+		for i := range cmd.srcLockJSON.Profiles {
+			logDryRun("Would merge profile '%s' of %s", cmd.srcLockJSON.Profiles[i].Name, cmd.fromLock)
+			writesLockJSON = true
+		}
+	}
+	if writesLockJSON {
+		dryRunWriteLockJSON()
+	}
+	if builder.IsBuildSkipped() {
+		logDryRun("Would skip building %s (-no-build)", pathutil.VimVoltDir())
+	} else {
+		logDryRun("Would build %s", pathutil.VimVoltDir())
+	}
+	return nil
+}
+
+// addUpgradeGroups adds the repositories in the same groups of
+// get.upgrade_groups as reposPathList to reposPathList, so they are upgraded
+// together. Only the repositories in lock.json are added.
+func (*getCmd) addUpgradeGroups(reposPathList []pathutil.ReposPath, lockJSON *lockjson.LockJSON, cfg *config.Config) []pathutil.ReposPath {
+	result := append([]pathutil.ReposPath(nil), reposPathList...)
+	contains := func(reposPath pathutil.ReposPath) bool {
+		for i := range result {
+			if result[i].Equals(reposPath) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, reposPath := range reposPathList {
+		name, members := cfg.Get.UpgradeGroupOf(reposPath)
+		for _, member := range members {
+			repos := lockJSON.Repos.FindByPath(member)
+			if repos == nil || contains(repos.Path) {
+				continue
+			}
+			logger.Infof("Upgrading %s together with %s (upgrade group %q)", repos.Path, reposPath, name)
+			result = append(result, repos.Path)
+		}
+	}
+	return result
+}
+
+// rollbackUpgradeGroups rolls back the upgraded repositories of grouped (the
+// entries of lock.json before upgrading) if any repository in the same group
+// of get.upgrade_groups failed. lockJSON is updated, and the statuses of
+// rolled back repositories are returned.
+func (cmd *getCmd) rollbackUpgradeGroups(ctx context.Context, grouped map[pathutil.ReposPath]lockjson.Repos, failedRepos map[pathutil.ReposPath]bool, lockJSON *lockjson.LockJSON, cfg *config.Config) map[pathutil.ReposPath]string {
+	statuses := make(map[pathutil.ReposPath]string)
+	if len(failedRepos) == 0 {
+		return statuses
+	}
+	for reposPath, prev := range grouped {
+		name, members := cfg.Get.UpgradeGroupOf(reposPath)
+		var failedMember pathutil.ReposPath
+		for _, member := range members {
+			for p := range failedRepos {
+				if p.Equals(member) {
+					failedMember = p
+				}
+			}
+		}
+		repos := lockJSON.Repos.FindByPath(reposPath)
+		if failedMember == "" || repos == nil {
+			continue
+		}
+		log := logger.NewReposBuffer(reposPath.String())
+		err := cmd.rollbackRepos(ctx, &prev, log)
+		log.Flush()
+		if err != nil {
+			logger.Warnf("Could not roll back %s to %s: %s", reposPath, prev.Version, err)
+			continue
+		}
+		statuses[reposPath] = fmt.Sprintf(fmtGroupRolledBack, reposPath, prev.Version, failedMember, name)
+		*repos = prev
+	}
+	return statuses
+}
+
+// getFailedError is the error of "volt get" when some repositories failed.
+type getFailedError struct {
+	// failed is the number of failed repositories including aborted ones
+	failed int
+	// aborted is the number of repositories aborted by -fail-fast
+	aborted int
+	// total is the number of target repositories
+	total int
+}
+
+func (e *getFailedError) Error() string {
+	var msg string
+	if e.failed == e.total {
+		msg = fmt.Sprintf("failed to install all %d plugins", e.total)
+	} else {
+		msg = fmt.Sprintf("failed to install %d of %d plugins", e.failed, e.total)
+	}
+	if e.aborted > 0 {
+		msg += fmt.Sprintf(" (%d aborted by -fail-fast)", e.aborted)
+	}
+	return msg
+}
+
 func (*getCmd) formatStatus(r *getParallelResult) string {
 	if r.err == nil {
 		return r.status
@@ -304,43 +1081,226 @@ type getParallelResult struct {
 	status    string
 	hash      string
 	reposType lockjson.ReposType
+	release   string
+	url       string
+	clone     *lockjson.CloneOptions
+	renamedTo pathutil.ReposPath
+	// installed is true if the repository was newly installed
+	installed bool
 	err       error
+	log       *logger.Buffer
 }
 
 const (
 	statusPrefixFailed = "!"
 	// Failed
-	fmtInstallFailed = "! %s > install failed"
-	fmtUpgradeFailed = "! %s > upgrade failed"
+	fmtInstallFailed   = "! %s > install failed"
+	fmtUpgradeFailed   = "! %s > upgrade failed"
+	fmtPlugconfError   = "! %s > plugconf has errors"
+	fmtAborted         = "! %s > aborted (-fail-fast)"
+	fmtGroupRolledBack = "! %s > rolled back to %s (%s in upgrade group %q failed)"
 	// No change
 	fmtNoChange      = "# %s > no change"
 	fmtAlreadyExists = "# %s > already exists"
 	// Installed
 	fmtAddedRepos = "+ %s > added repository to current profile"
 	fmtInstalled  = "+ %s > installed"
+	// Merged the profile of -from-lock
+	fmtMergedProfile = "+ profile %s > merged"
 	// Upgraded
 	fmtRevUpdate = "* %s > updated lock.json revision (%s..%s)"
-	fmtUpgraded  = "* %s > upgraded (%s..%s)"
+	fmtUpgraded  = "* %s > upgraded (%s..%s, %s)"
 	fmtFetched   = "* %s > fetched objects (worktree is not updated)"
+	fmtRenamed   = "* %s > renamed to %s"
+	// Not upgraded because the repository is pinned to a tag or a commit
+	fmtPinned = "# %s > pinned to %s (not upgraded)"
+	// Checked out the version specified with "user/name@branch" and so on
+	fmtCheckedOut = "* %s > checked out %s (%s..%s)"
+
+	// Upgraded release repository
+	fmtReleaseUpgraded = "* %s > upgraded release (%s..%s)"
+	// Upgraded archive repository (the content hashes)
+	fmtArchiveUpgraded = "* %s > upgraded archive (%s..%s)"
 )
 
+// reportResult shows the result of the i-th repository of total ("Done",
+// "Failed", or "Aborted"). It is shown in the progress lines if they are
+// shown.
+func (cmd *getCmd) reportResult(i, total int, reposPath pathutil.ReposPath, result string) {
+	if cmd.progressUI != nil {
+		cmd.progressUI.Set(reposPath.String(), result)
+		logger.Debugf("(%d/%d) %s ... %s.", i, total, reposPath, result)
+		return
+	}
+	logger.Infof("(%d/%d) %s ... %s.", i, total, reposPath, result)
+}
+
+// progressWriter returns io.Writer which shows the progress of git command
+// for the repository of dir in the progress lines. nil is returned if they
+// are not shown.
+func (cmd *getCmd) progressWriter(dir string) io.Writer {
+	if cmd.progressUI == nil {
+		return nil
+	}
+	reposPath, err := pathutil.ReposPathOfDir(dir)
+	if err != nil {
+		return nil
+	}
+	return cmd.progressUI.Writer(reposPath.String())
+}
+
+// hostLimiter limits the number of repositories which connect to the same
+// host at once, and the number of all repositories processed at once.
+type hostLimiter struct {
+	max  int
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+	// total is the semaphore of all repositories (nil if not limited)
+	total chan struct{}
+}
+
+// newHostLimiter returns hostLimiter which allows max connections per host,
+// and maxTotal repositories at once. If max or maxTotal is 0, it does not
+// limit them.
+func newHostLimiter(max, maxTotal int) *hostLimiter {
+	l := &hostLimiter{max: max, sems: make(map[string]chan struct{})}
+	if maxTotal > 0 {
+		l.total = make(chan struct{}, maxTotal)
+	}
+	return l
+}
+
+// acquire waits until a connection to host is available, and returns a
+// function to release it.
+// Non-nil error is returned if ctx is done while waiting.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	releaseHost, err := l.acquireHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	// The host is acquired first, so that a repository does not occupy
+	// the total while it is waiting for a busy host
+	if l.total == nil {
+		return releaseHost, nil
+	}
+	select {
+	case l.total <- struct{}{}:
+		return func() {
+			<-l.total
+			releaseHost()
+		}, nil
+	case <-ctx.Done():
+		releaseHost()
+		return nil, ctx.Err()
+	}
+}
+
+func (l *hostLimiter) acquireHost(ctx context.Context, host string) (func(), error) {
+	if l.max <= 0 {
+		return func() {}, nil
+	}
+	host = strings.ToLower(host)
+	l.mu.Lock()
+	sem, ok := l.sems[host]
+	if !ok {
+		sem = make(chan struct{}, l.max)
+		l.sems[host] = sem
+	}
+	l.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // This function is executed in goroutine of each plugin.
 // 1. install plugin if it does not exist
 // 2. install plugconf if it does not exist and createPlugconf=true
-func (cmd *getCmd) getParallel(reposPath pathutil.ReposPath, repos *lockjson.Repos, cfg *config.Config, done chan<- getParallelResult) {
+// Both operations are done after a connection to the host is acquired from
+// limiter. If get.timeout is set in config.toml, both operations are
+// cancelled after the timeout.
+func (cmd *getCmd) getParallel(ctx context.Context, reposPath pathutil.ReposPath, ref pathutil.ReposRef, repos *lockjson.Repos, cfg *config.Config, limiter *hostLimiter, done chan<- getParallelResult) {
+	release, err := limiter.acquire(ctx, reposPath.Host())
+	if err != nil {
+		format := fmtInstallFailed
+		if cmd.upgrade && pathutil.Exists(reposPath.FullPath()) {
+			format = fmtUpgradeFailed
+		}
+		done <- getParallelResult{
+			reposPath: reposPath,
+			status:    fmt.Sprintf(format, reposPath),
+			err:       err,
+			log:       logger.NewReposBuffer(reposPath.String()),
+		}
+		return
+	}
+	defer release()
+	if cmd.upgrade && pathutil.Exists(reposPath.FullPath()) {
+		cmd.progressUI.Set(reposPath.String(), "Upgrading")
+	} else {
+		cmd.progressUI.Set(reposPath.String(), "Installing")
+	}
+
+	if timeout := cfg.Get.TimeoutDuration(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	log := logger.NewReposBuffer(reposPath.String())
 	pluginDone := make(chan getParallelResult)
-	go cmd.installPlugin(reposPath, repos, cfg, pluginDone)
+	if cmd.archives[reposPath] != "" || repos != nil && repos.Type == lockjson.ReposArchiveType {
+		go cmd.installArchive(ctx, reposPath, repos, log, pluginDone)
+	} else if cmd.release != "" || cmd.releases[reposPath] != "" || repos != nil && repos.Type == lockjson.ReposReleaseType {
+		go cmd.installRelease(ctx, reposPath, repos, log, pluginDone)
+	} else {
+		go cmd.installPlugin(ctx, reposPath, ref, repos, cfg, log, pluginDone)
+	}
 	pluginResult := <-pluginDone
+	if pluginResult.err == nil {
+		cmd.warnArchived(ctx, reposPath, log)
+	}
 	if pluginResult.err != nil || !*cfg.Get.CreateSkeletonPlugconf {
+		pluginResult.log = log
 		done <- pluginResult
 		return
 	}
 	plugconfDone := make(chan getParallelResult)
-	go cmd.installPlugconf(reposPath, &pluginResult, plugconfDone)
-	done <- (<-plugconfDone)
+	go cmd.installPlugconf(ctx, reposPath, &pluginResult, cfg, log, plugconfDone)
+	result := <-plugconfDone
+	result.log = log
+	done <- result
+}
+
+// warnArchived shows a warning if the repository has been archived upstream.
+func (*getCmd) warnArchived(ctx context.Context, reposPath pathutil.ReposPath, log *logger.Buffer) {
+	m, err := metadata.Get(ctx, reposPath)
+	if err != nil {
+		log.Debug("Could not get metadata of " + reposPath.String() + ": " + err.Error())
+		return
+	}
+	if m.Archived {
+		log.Warnf("%s has been archived upstream, it is no longer maintained", reposPath)
+	}
+}
+
+// commitSummary returns the number of new commits and the newest commit date
+// between fromHash and toHash (e.g. "3 commits, newest 2018-04-01").
+func commitSummary(reposPath pathutil.ReposPath, fromHash, toHash string, log *logger.Buffer) string {
+	count, newest, err := gitutil.CountCommits(reposPath, fromHash, toHash)
+	if err != nil {
+		log.Debug("Could not count new commits of " + reposPath.String() + ": " + err.Error())
+		return "unknown commits"
+	}
+	if count == 1 {
+		return "1 commit, " + newest.Local().Format("2006-01-02")
+	}
+	return fmt.Sprintf("%d commits, newest %s", count, newest.Local().Format("2006-01-02"))
 }
 
-func (cmd *getCmd) installPlugin(reposPath pathutil.ReposPath, repos *lockjson.Repos, cfg *config.Config, done chan<- getParallelResult) {
+func (cmd *getCmd) installPlugin(ctx context.Context, reposPath pathutil.ReposPath, ref pathutil.ReposRef, repos *lockjson.Repos, cfg *config.Config, log *logger.Buffer, done chan<- getParallelResult) {
 	// true:upgrade, false:install
 	fullReposPath := reposPath.FullPath()
 	doInstall := !pathutil.Exists(fullReposPath)
@@ -348,7 +1308,7 @@ func (cmd *getCmd) installPlugin(reposPath pathutil.ReposPath, repos *lockjson.R
 
 	var fromHash string
 	var err error
-	if doUpgrade {
+	if doUpgrade || !doInstall && !ref.IsZero() {
 		// Get HEAD hash string
 		fromHash, err = gitutil.GetHEAD(reposPath)
 		if err != nil {
@@ -363,10 +1323,16 @@ func (cmd *getCmd) installPlugin(reposPath pathutil.ReposPath, repos *lockjson.R
 	}
 
 	var status string
+	var installed bool
 	var upgraded bool
 	var checkRevision bool
+	var cloneOpts *lockjson.CloneOptions
 
-	if doUpgrade {
+	if doUpgrade && ref.IsZero() && cmd.isPinnedToFixedRef(reposPath, repos) {
+		// Keep the tag or the commit which the repository is pinned to
+		log.Debugf("Skipped upgrading %s pinned to %s", reposPath, repos.Pin)
+		status = fmt.Sprintf(fmtPinned, reposPath, repos.Pin)
+	} else if doUpgrade && ref.IsZero() {
 		// when cmd.upgrade is true, repos must not be nil.
 		if repos == nil {
 			done <- getParallelResult{
@@ -377,8 +1343,10 @@ func (cmd *getCmd) installPlugin(reposPath pathutil.ReposPath, repos *lockjson.R
 			return
 		}
 		// Upgrade plugin
-		logger.Debug("Upgrading " + reposPath + " ...")
-		err := cmd.upgradePlugin(reposPath, cfg)
+		log.Debug("Upgrading " + reposPath + " ...")
+		err := retry(ctx, cfg, log, "upgrade "+reposPath.String(), func() error {
+			return cmd.upgradePlugin(ctx, reposPath, cfg, log)
+		})
 		if err != git.NoErrAlreadyUpToDate && err != nil {
 			result := errors.Wrap(err, "failed to upgrade plugin")
 			done <- getParallelResult{
@@ -395,11 +1363,24 @@ func (cmd *getCmd) installPlugin(reposPath pathutil.ReposPath, repos *lockjson.R
 		}
 	} else if doInstall {
 		// Install plugin
-		logger.Debug("Installing " + reposPath + " ...")
-		err := cmd.clonePlugin(reposPath, cfg)
+		log.Debug("Installing " + reposPath + " ...")
+		cloneRepos := repos
+		if cloneRepos == nil && cmd.srcLockJSON != nil {
+			// Use the clone options of the source lock.json (-from-lock)
+			cloneRepos = cmd.srcLockJSON.Repos.FindByPath(reposPath)
+		}
+		cloneOpts = cmd.cloneOptions(cloneRepos)
+		err := retry(ctx, cfg, log, "clone "+reposPath.String(), func() error {
+			err := cmd.clonePlugin(ctx, reposPath, cloneOpts, cfg, log)
+			if err != nil && err != errRepoExists {
+				// Remove the partially cloned repository before retrying
+				cmd.removeDir(fullReposPath)
+			}
+			return err
+		})
 		if err != nil {
 			result := errors.Wrap(err, "failed to install plugin")
-			logger.Debug("Rollbacking " + fullReposPath + " ...")
+			log.Debug("Rollbacking " + fullReposPath + " ...")
 			err = cmd.removeDir(fullReposPath)
 			if err != nil {
 				result = multierror.Append(result, err)
@@ -412,11 +1393,43 @@ func (cmd *getCmd) installPlugin(reposPath pathutil.ReposPath, repos *lockjson.R
 			return
 		}
 		status = fmt.Sprintf(fmtInstalled, reposPath)
-	} else {
+		installed = true
+	} else if ref.IsZero() {
 		status = fmt.Sprintf(fmtAlreadyExists, reposPath)
 		checkRevision = true
 	}
 
+	// Check out the version specified with the argument (e.g. "user/name@branch")
+	var checkedOut bool
+	if !ref.IsZero() {
+		log.Debugf("Checking out %s%s ...", reposPath, ref)
+		if err := cmd.checkoutRef(ctx, reposPath, ref, doUpgrade, cfg, log); err != nil {
+			result := errors.Wrap(err, "failed to check out "+ref.String())
+			format := fmtUpgradeFailed
+			if doInstall {
+				format = fmtInstallFailed
+				log.Debug("Rollbacking " + fullReposPath + " ...")
+				if err := cmd.removeDir(fullReposPath); err != nil {
+					result = multierror.Append(result, err)
+				}
+			}
+			done <- getParallelResult{
+				reposPath: reposPath,
+				status:    fmt.Sprintf(format, reposPath),
+				err:       result,
+			}
+			return
+		}
+		checkedOut = !doInstall
+	}
+
+	// Plugins may bundle their dependencies as submodules (e.g. fzf.vim)
+	if doInstall || doUpgrade || !ref.IsZero() {
+		if err := cmd.updateSubmodules(ctx, reposPath, log); err != nil {
+			log.Warnf("%s: failed to update submodules, the plugin may not work: %s", reposPath, err)
+		}
+	}
+
 	var toHash string
 	reposType, err := cmd.detectReposType(fullReposPath)
 	if err == nil && reposType == lockjson.ReposGitType {
@@ -425,7 +1438,7 @@ func (cmd *getCmd) installPlugin(reposPath pathutil.ReposPath, repos *lockjson.R
 		if err != nil {
 			result := errors.Wrap(err, "failed to get HEAD commit hash")
 			if doInstall {
-				logger.Debug("Rollbacking " + fullReposPath + " ...")
+				log.Debug("Rollbacking " + fullReposPath + " ...")
 				err = cmd.removeDir(fullReposPath)
 				if err != nil {
 					result = multierror.Append(result, err)
@@ -442,28 +1455,138 @@ func (cmd *getCmd) installPlugin(reposPath pathutil.ReposPath, repos *lockjson.R
 
 	if upgraded {
 		if fromHash != toHash {
-			status = fmt.Sprintf(fmtUpgraded, reposPath, fromHash, toHash)
+			summary := commitSummary(reposPath, fromHash, toHash, log)
+			status = fmt.Sprintf(fmtUpgraded, reposPath, fromHash, toHash, summary)
 		} else {
 			status = fmt.Sprintf(fmtFetched, reposPath)
 		}
 	}
 
+	if checkedOut {
+		if fromHash != toHash {
+			status = fmt.Sprintf(fmtCheckedOut, reposPath, ref, fromHash, toHash)
+		} else {
+			status = fmt.Sprintf(fmtNoChange, reposPath)
+		}
+	}
+
 	if checkRevision && repos != nil && repos.Version != toHash {
 		status = fmt.Sprintf(fmtRevUpdate, reposPath, repos.Version, toHash)
 	}
 
+	var renamedTo pathutil.ReposPath
+	if doInstall || doUpgrade {
+		var e error
+		renamedTo, e = cmd.detectRenamedRepos(ctx, reposPath, cfg)
+		if e != nil {
+			log.Debug("Could not detect if " + reposPath.String() + " was renamed: " + e.Error())
+		} else if renamedTo != "" {
+			log.Infof("%s was renamed or transferred to %s", reposPath, renamedTo)
+		}
+	}
+
 	done <- getParallelResult{
 		reposPath: reposPath,
 		status:    status,
 		reposType: reposType,
 		hash:      toHash,
+		clone:     cloneOpts,
+		renamedTo: renamedTo,
+		installed: installed,
+	}
+}
+
+// detectRenamedRepos returns the new repos path if the repository of reposPath
+// was renamed or transferred, that is, the server permanently redirects
+// "{clone URL}/info/refs" to the URL of the new repository (e.g. GitHub).
+// Empty string is returned if it was not renamed.
+func (*getCmd) detectRenamedRepos(ctx context.Context, reposPath pathutil.ReposPath, cfg *config.Config) (pathutil.ReposPath, error) {
+	// The repository of get.repos_clone_url is not the upstream
+	if cfg.Get.HasReposCloneURL(reposPath) {
+		return "", nil
+	}
+	cloneURL := cfg.Get.CloneURLOf(reposPath)
+	if !strings.HasPrefix(cloneURL, "https://") && !strings.HasPrefix(cloneURL, "http://") {
+		return "", nil
+	}
+	host := reposPath.Host()
+	prefix := strings.TrimSuffix(cloneURL, "/"+strings.TrimPrefix(reposPath.String(), host+"/"))
+
+	location, err := httputil.GetPermanentRedirect(ctx, cloneURL+"/info/refs?service=git-upload-pack")
+	if err != nil || location == "" {
+		return "", err
+	}
+	location = strings.SplitN(location, "?", 2)[0]
+	location = strings.TrimSuffix(location, "/info/refs")
+	if !strings.HasPrefix(location, prefix+"/") {
+		return "", errors.New("redirected to unknown URL: " + location)
+	}
+	newPath, err := pathutil.NormalizeRepos(host + "/" + strings.TrimPrefix(location, prefix+"/"))
+	if err != nil {
+		return "", err
+	}
+	if newPath.Equals(reposPath) {
+		return "", nil
+	}
+	return newPath, nil
+}
+
+// renameRepos moves the repository and plugconf of "from" to "to", and
+// updates lock.json and the remote URL of the repository.
+func (*getCmd) renameRepos(lockJSON *lockjson.LockJSON, from, to pathutil.ReposPath, cfg *config.Config) error {
+	if lockJSON.Repos.Contains(to) {
+		return errors.New(to.String() + " already exists in lock.json")
+	}
+	if pathutil.Exists(to.FullPath()) {
+		return errors.New(to.FullPath() + " already exists")
+	}
+	if err := os.MkdirAll(filepath.Dir(to.FullPath()), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(from.FullPath(), to.FullPath()); err != nil {
+		return err
+	}
+	fileutil.RemoveDirs(filepath.Dir(from.FullPath()))
+
+	// Update remote URL not to be redirected
+	if r, err := git.PlainOpen(to.FullPath()); err == nil {
+		remote, err := gitutil.GetUpstreamRemote(r)
+		if err != nil {
+			remote = "origin"
+		}
+		if err := gitutil.SetRemoteURL(r, remote, cfg.Get.CloneURLOf(to)); err != nil {
+			logger.Warnf("Could not update remote URL of %s: %s", to, err)
+		}
+	}
+
+	// Move plugconf
+	if pathutil.Exists(from.Plugconf()) {
+		if pathutil.Exists(to.Plugconf()) {
+			logger.Warnf("%s already exists, %s was not moved", to.Plugconf(), from.Plugconf())
+		} else if err := os.MkdirAll(filepath.Dir(to.Plugconf()), 0755); err != nil {
+			logger.Warnf("Could not move plugconf of %s: %s", from, err)
+		} else if err := os.Rename(from.Plugconf(), to.Plugconf()); err != nil {
+			logger.Warnf("Could not move plugconf of %s: %s", from, err)
+		} else {
+			fileutil.RemoveDirs(filepath.Dir(from.Plugconf()))
+		}
 	}
+
+	return lockJSON.RenameRepos(from, to)
 }
 
-func (cmd *getCmd) installPlugconf(reposPath pathutil.ReposPath, pluginResult *getParallelResult, done chan<- getParallelResult) {
+func (cmd *getCmd) installPlugconf(ctx context.Context, reposPath pathutil.ReposPath, pluginResult *getParallelResult, cfg *config.Config, log *logger.Buffer, done chan<- getParallelResult) {
 	// Install plugconf
-	logger.Debug("Installing plugconf " + reposPath + " ...")
-	err := cmd.downloadPlugconf(reposPath)
+	log.Debug("Installing plugconf " + reposPath + " ...")
+	err := cmd.downloadPlugconf(ctx, reposPath, cfg, log)
+	if e, ok := err.(*plugconfParseError); ok {
+		done <- getParallelResult{
+			reposPath: reposPath,
+			status:    fmt.Sprintf(fmtPlugconfError, reposPath),
+			err:       e,
+		}
+		return
+	}
 	if err != nil {
 		result := errors.Wrap(err, "failed to install plugconf")
 		// TODO: Call cmd.removeDir() only when the repos *did not* exist previously
@@ -484,11 +1607,18 @@ func (cmd *getCmd) installPlugconf(reposPath pathutil.ReposPath, pluginResult *g
 	done <- *pluginResult
 }
 
+// detectReposType returns git type if fullpath is a git repository.
+// A git repository which has no commits yet (e.g. a static repository which
+// was just initialized by "git init") is a static repository.
 func (*getCmd) detectReposType(fullpath string) (lockjson.ReposType, error) {
 	if pathutil.Exists(filepath.Join(fullpath, ".git")) {
-		if _, err := git.PlainOpen(fullpath); err != nil {
+		r, err := git.PlainOpen(fullpath)
+		if err != nil {
 			return "", err
 		}
+		if gitutil.IsEmptyRepository(r) {
+			return lockjson.ReposStaticType, nil
+		}
 		return lockjson.ReposGitType, nil
 	}
 	return lockjson.ReposStaticType, nil
@@ -506,7 +1636,22 @@ func (*getCmd) removeDir(fullReposPath string) error {
 	return nil
 }
 
-func (cmd *getCmd) upgradePlugin(reposPath pathutil.ReposPath, cfg *config.Config) error {
+// isPinnedToFixedRef returns true if repos is pinned to a tag or a commit
+// (HEAD is detached), which is not upgraded by "volt get -u".
+// A repository pinned to a branch follows the branch.
+func (*getCmd) isPinnedToFixedRef(reposPath pathutil.ReposPath, repos *lockjson.Repos) bool {
+	if repos == nil || repos.Pin == "" {
+		return false
+	}
+	r, err := git.PlainOpen(reposPath.FullPath())
+	if err != nil {
+		return false
+	}
+	detached, err := gitutil.IsDetachedHEAD(r)
+	return err == nil && detached
+}
+
+func (cmd *getCmd) upgradePlugin(ctx context.Context, reposPath pathutil.ReposPath, cfg *config.Config, log *logger.Buffer) error {
 	fullpath := reposPath.FullPath()
 
 	repos, err := git.PlainOpen(fullpath)
@@ -524,15 +1669,73 @@ func (cmd *getCmd) upgradePlugin(reposPath pathutil.ReposPath, cfg *config.Confi
 		return err
 	}
 
+	// Follow get.repos_clone_url which was changed after cloning
+	if cfg.Get.HasReposCloneURL(reposPath) {
+		if err := gitutil.SetRemoteURL(repos, remote, cfg.Get.CloneURLOf(reposPath)); err != nil {
+			return err
+		}
+	}
+
 	if reposCfg.Core.IsBare {
-		return cmd.gitFetch(repos, fullpath, remote, cfg)
+		return cmd.gitFetch(ctx, repos, fullpath, remote, cfg, log)
 	}
-	return cmd.gitPull(repos, fullpath, remote, cfg)
+	return cmd.gitPull(ctx, repos, fullpath, remote, cfg, log)
+}
+
+// checkoutRef checks out ref of reposPath. The repository is fetched before
+// checking out if fetch is true or ref was not found.
+func (cmd *getCmd) checkoutRef(ctx context.Context, reposPath pathutil.ReposPath, ref pathutil.ReposRef, fetch bool, cfg *config.Config, log *logger.Buffer) error {
+	fullpath := reposPath.FullPath()
+	r, err := git.PlainOpen(fullpath)
+	if err != nil {
+		return err
+	}
+	remote, err := gitutil.GetUpstreamRemote(r)
+	if err != nil {
+		// e.g. HEAD is detached
+		remote = "origin"
+	}
+
+	if fetch {
+		err := cmd.gitFetch(ctx, r, fullpath, remote, cfg, log)
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			return err
+		}
+		// Reopen to read objects fetched by "git" command
+		if r, err = git.PlainOpen(fullpath); err != nil {
+			return err
+		}
+	}
+	err = gitutil.CheckoutRef(r, remote, ref)
+	if err != nil && !fetch {
+		log.Debugf("Could not check out %s%s, fetching: %s", reposPath, ref, err)
+		return cmd.checkoutRef(ctx, reposPath, ref, true, cfg, log)
+	}
+	return err
 }
 
 var errRepoExists = errors.New("repository exists")
 
-func (cmd *getCmd) clonePlugin(reposPath pathutil.ReposPath, cfg *config.Config) error {
+// cloneOptions returns the options to clone repos (nil if it is not in
+// lock.json). The options given by the arguments take precedence over the
+// options saved in lock.json. nil is returned if there are no options.
+func (cmd *getCmd) cloneOptions(repos *lockjson.Repos) *lockjson.CloneOptions {
+	opts := &lockjson.CloneOptions{
+		Depth:        cmd.depth,
+		Filter:       cmd.filter,
+		SingleBranch: cmd.singleBranch,
+	}
+	if !opts.IsZero() {
+		return opts
+	}
+	if repos != nil && !repos.Clone.IsZero() {
+		return repos.Clone
+	}
+	return nil
+}
+
+// clonePlugin clones reposPath with opts (can be nil).
+func (cmd *getCmd) clonePlugin(ctx context.Context, reposPath pathutil.ReposPath, opts *lockjson.CloneOptions, cfg *config.Config, log *logger.Buffer) error {
 	fullpath := reposPath.FullPath()
 	if pathutil.Exists(fullpath) {
 		return errRepoExists
@@ -544,38 +1747,79 @@ func (cmd *getCmd) clonePlugin(reposPath pathutil.ReposPath, cfg *config.Config)
 	}
 
 	// Clone repository to $VOLTPATH/repos/{site}/{user}/{name}
-	return cmd.gitClone(reposPath.CloneURL(), fullpath, cfg)
+	return cmd.gitClone(ctx, cfg.Get.CloneURLOf(reposPath), fullpath, opts, cfg, log)
 }
 
-func (cmd *getCmd) downloadPlugconf(reposPath pathutil.ReposPath) error {
+// downloadPlugconf fetches and installs plugconf of reposPath.
+// log may be nil.
+func (cmd *getCmd) downloadPlugconf(ctx context.Context, reposPath pathutil.ReposPath, cfg *config.Config, log *logger.Buffer) error {
 	path := reposPath.Plugconf()
 	if pathutil.Exists(path) {
-		logger.Debugf("plugconf '%s' exists... skip", path)
+		log.Debugf("plugconf '%s' exists... skip", path)
 		return nil
 	}
 
 	// If non-nil error returned from FetchPlugconfTemplate(),
-	// create skeleton plugconf file
-	tmpl, err := plugconf.FetchPlugconfTemplate(reposPath)
+	// create skeleton plugconf file from the plugin's runtime files
+	var content []byte
+	var tmpl *plugconf.Template
+	err := retry(ctx, cfg, log, "fetch plugconf template of "+reposPath.String(), func() error {
+		var err error
+		tmpl, err = plugconf.FetchPlugconfTemplate(ctx, reposPath)
+		return err
+	})
 	if err != nil {
-		logger.Debug(err.Error())
-		// empty tmpl is returned when err != nil
-	}
-	content, merr := tmpl.Generate(path)
-	if merr.ErrorOrNil() != nil {
-		return errors.Errorf("parse error in fetched plugconf %s: %s", reposPath, merr.Error())
+		// Do not create skeleton plugconf if cancelled
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Debug(err.Error())
+		info, err := plugconf.AnalyzePlugin(reposPath.FullPath())
+		if err != nil {
+			log.Debug("Could not analyze " + reposPath.String() + ": " + err.Error())
+		}
+		content, err = plugconf.GenerateSkeleton(info)
+		if err != nil {
+			return err
+		}
+	} else {
+		var merr *multierror.Error
+		content, merr = tmpl.Generate(path)
+		if merr.ErrorOrNil() != nil {
+			return &plugconfParseError{
+				errors.Errorf("parse error in fetched plugconf %s: %s", reposPath, merr.Error()),
+			}
+		}
 	}
 	os.MkdirAll(filepath.Dir(path), 0755)
-	err = ioutil.WriteFile(path, content, 0644)
+	err = transaction.WriteFile(path, content, 0644)
 	if err != nil {
 		return err
 	}
+
+	// Report errors now, otherwise "volt build" fails with them later
+	if _, parseErr := plugconf.ParsePlugconfFile(path, 0, reposPath); parseErr.HasErrs() {
+		return &plugconfParseError{
+			errors.Errorf("%s\nfix the file and run \"volt get %s\" again", parseErr.Errors().Error(), reposPath),
+		}
+	}
 	return nil
 }
 
+// plugconfParseError is returned by downloadPlugconf when the plugconf has
+// errors.
+type plugconfParseError struct {
+	err error
+}
+
+func (e *plugconfParseError) Error() string {
+	return e.err.Error()
+}
+
 // * Add repos to 'repos' if not found
 // * Add repos to 'profiles[]/repos_path' if not found
-func (*getCmd) updateReposVersion(lockJSON *lockjson.LockJSON, reposPath pathutil.ReposPath, reposType lockjson.ReposType, version string, profile *lockjson.Profile) bool {
+func (cmd *getCmd) updateReposVersion(lockJSON *lockjson.LockJSON, r *getParallelResult, profile *lockjson.Profile) bool {
+	reposPath := r.reposPath
 	repos := lockJSON.Repos.FindByPath(reposPath)
 
 	added := false
@@ -584,9 +1828,12 @@ func (*getCmd) updateReposVersion(lockJSON *lockjson.LockJSON, reposPath pathuti
 		// repos is not found in lock.json
 		// -> previous operation is install
 		repos = &lockjson.Repos{
-			Type:    reposType,
+			Type:    r.reposType,
 			Path:    reposPath,
-			Version: version,
+			Version: r.hash,
+			Release: r.release,
+			URL:     r.url,
+			Clone:   r.clone,
 		}
 		// Add repos to 'repos'
 		lockJSON.Repos = append(lockJSON.Repos, *repos)
@@ -594,10 +1841,20 @@ func (*getCmd) updateReposVersion(lockJSON *lockjson.LockJSON, reposPath pathuti
 	} else {
 		// repos is found in lock.json
 		// -> previous operation is upgrade
-		repos.Version = version
+		repos.Version = r.hash
+		repos.Release = r.release
+		repos.URL = r.url
+		if r.clone != nil {
+			repos.Clone = r.clone
+		}
+	}
+
+	// Pin the version given with the argument (e.g. "user/name#v1.0")
+	if pin, exists := cmd.pins[reposPath]; exists && r.reposType == lockjson.ReposGitType {
+		lockJSON.Repos.FindByPath(reposPath).Pin = pin.String()
 	}
 
-	if !profile.ReposPath.Contains(reposPath) {
+	if profile != nil && !profile.ReposPath.Contains(reposPath) {
 		// Add repos to 'profiles[]/repos_path'
 		profile.ReposPath = append(profile.ReposPath, reposPath)
 		added = true
@@ -605,9 +1862,28 @@ func (*getCmd) updateReposVersion(lockJSON *lockjson.LockJSON, reposPath pathuti
 	return added
 }
 
-func (cmd *getCmd) gitFetch(r *git.Repository, workDir string, remote string, cfg *config.Config) error {
-	err := r.Fetch(&git.FetchOptions{
+func (cmd *getCmd) gitFetch(ctx context.Context, r *git.Repository, workDir string, remote string, cfg *config.Config, log *logger.Buffer) error {
+	// go-git does not support --shallow-since and custom SSH command
+	limit := fetchLimitArgs(r, workDir, cfg, log)
+	if (len(limit) > 0 || cmd.useCustomSSHRemote(ctx, r, remote, workDir, log)) && cmd.hasGitCmd() {
+		args := append(append([]string{"fetch"}, limit...), remote)
+		return cmd.execGitUpdate(ctx, r, workDir, func() error {
+			return execGitProgress(ctx, workDir, cmd.progressWriter(workDir), args...)
+		})
+	}
+	if cfg.Get.FetchShallowSince != "" {
+		log.Warn("get.fetch_shallow_since is ignored because \"git\" command is not found")
+	}
+
+	auth, err := cmd.remoteAuthMethod(ctx, r, remote, cfg)
+	if err != nil {
+		return err
+	}
+	err = r.FetchContext(ctx, &git.FetchOptions{
 		RemoteName: remote,
+		Depth:      fetchDepth(r, cfg),
+		Progress:   cmd.progressWriter(workDir),
+		Auth:       auth,
 	})
 	if err == nil || err == git.NoErrAlreadyUpToDate {
 		return err
@@ -615,36 +1891,66 @@ func (cmd *getCmd) gitFetch(r *git.Repository, workDir string, remote string, cf
 
 	// When fallback_git_cmd is true and git command is installed,
 	// try to invoke git-fetch command
-	if !*cfg.Get.FallbackGitCmd || !cmd.hasGitCmd() {
+	if ctx.Err() != nil || !*cfg.Get.FallbackGitCmd || !cmd.hasGitCmd() {
 		return err
 	}
-	logger.Warnf("failed to fetch, try to execute \"git fetch %s\" instead...: %s", remote, err.Error())
+	log.Warnf("failed to fetch, try to execute \"git fetch %s\" instead...: %s", remote, err.Error())
 
-	before, err := gitutil.GetHEADRepository(r)
-	fetch := exec.Command("git", "fetch", remote)
-	fetch.Dir = workDir
-	err = fetch.Run()
-	if err != nil {
-		return err
+	return cmd.execGitUpdate(ctx, r, workDir, func() error {
+		return execGitProgress(ctx, workDir, cmd.progressWriter(workDir), "fetch", remote)
+	})
+}
+
+func (cmd *getCmd) gitPull(ctx context.Context, r *git.Repository, workDir string, remote string, cfg *config.Config, log *logger.Buffer) error {
+	// go-git does not support --shallow-since and custom SSH command, and
+	// cannot update the branch when the fetched history is not connected to
+	// HEAD
+	limit := fetchLimitArgs(r, workDir, cfg, log)
+	if (len(limit) > 0 || cmd.useCustomSSHRemote(ctx, r, remote, workDir, log)) && cmd.hasGitCmd() {
+		args := append(append([]string{"fetch"}, limit...), remote)
+		return cmd.execGitUpdate(ctx, r, workDir, func() error {
+			// Check local commits before the fetch, the limited history may
+			// not be connected to HEAD after it
+			var hasLocal bool
+			if len(limit) > 0 {
+				var err error
+				if hasLocal, err = hasLocalCommits(ctx, workDir, remote); err != nil {
+					return err
+				}
+			}
+			if err := execGitProgress(ctx, workDir, cmd.progressWriter(workDir), args...); err != nil {
+				return err
+			}
+			err := execGit(ctx, workDir, "merge", "--ff-only", "FETCH_HEAD")
+			if err == nil || len(limit) == 0 {
+				return err
+			}
+			if hasLocal {
+				return errors.Errorf("could not fast-forward %s: HEAD has commits which are not in remote '%s'", workDir, remote)
+			}
+			log.Warnf("could not fast-forward %s because the fetched history is limited by config.toml, resetting to the fetched commit", workDir)
+			return execGit(ctx, workDir, "reset", "--keep", "FETCH_HEAD")
+		})
 	}
-	if changed, err := cmd.getWorktreeChanges(r, before); err != nil {
-		return err
-	} else if !changed {
-		return git.NoErrAlreadyUpToDate
+	if cfg.Get.FetchShallowSince != "" {
+		log.Warn("get.fetch_shallow_since is ignored because \"git\" command is not found")
 	}
-	return nil
-}
 
-func (cmd *getCmd) gitPull(r *git.Repository, workDir string, remote string, cfg *config.Config) error {
 	wt, err := r.Worktree()
 	if err != nil {
 		return err
 	}
-	err = wt.Pull(&git.PullOptions{
+	auth, err := cmd.remoteAuthMethod(ctx, r, remote, cfg)
+	if err != nil {
+		return err
+	}
+	err = wt.PullContext(ctx, &git.PullOptions{
 		RemoteName: remote,
-		// TODO: Temporarily recursive clone is disabled, because go-git does
-		// not support relative submodule url in .gitmodules and it causes an
-		// error
+		Depth:      fetchDepth(r, cfg),
+		Progress:   cmd.progressWriter(workDir),
+		Auth:       auth,
+		// go-git does not support relative submodule url in .gitmodules,
+		// submodules are updated by updateSubmodules() instead
 		RecurseSubmodules: 0,
 	})
 	if err == nil || err == git.NoErrAlreadyUpToDate {
@@ -653,18 +1959,125 @@ func (cmd *getCmd) gitPull(r *git.Repository, workDir string, remote string, cfg
 
 	// When fallback_git_cmd is true and git command is installed,
 	// try to invoke git-pull command
-	if !*cfg.Get.FallbackGitCmd || !cmd.hasGitCmd() {
+	if ctx.Err() != nil || !*cfg.Get.FallbackGitCmd || !cmd.hasGitCmd() {
 		return err
 	}
-	logger.Warnf("failed to pull, try to execute \"git pull\" instead...: %s", err.Error())
+	log.Warnf("failed to pull, try to execute \"git pull\" instead...: %s", err.Error())
+
+	return cmd.execGitUpdate(ctx, r, workDir, func() error {
+		return execGitProgress(ctx, workDir, cmd.progressWriter(workDir), "pull")
+	})
+}
+
+// useCustomSSH returns true if url is SSH URL and a custom SSH command is
+// configured for "git" command (GIT_SSH_COMMAND, GIT_SSH, or core.sshCommand
+// of git config in workDir), which go-git does not support.
+func (cmd *getCmd) useCustomSSH(ctx context.Context, url, workDir string, log *logger.Buffer) bool {
+	if !gitutil.IsSSHURL(url) || !cmd.hasGitCmd() {
+		return false
+	}
+	for _, name := range []string{"GIT_SSH_COMMAND", "GIT_SSH"} {
+		if os.Getenv(name) != "" {
+			log.Debugf("Use \"git\" command for %s because %s is set", url, name)
+			return true
+		}
+	}
+	gitCmd := exec.CommandContext(ctx, "git", "config", "--get", "core.sshCommand")
+	gitCmd.Dir = workDir
+	if out, err := gitCmd.Output(); err == nil && strings.TrimSpace(string(out)) != "" {
+		log.Debugf("Use \"git\" command for %s because core.sshCommand is set", url)
+		return true
+	}
+	return false
+}
+
+// useCustomSSHRemote is same as useCustomSSH for the URL of remote.
+func (cmd *getCmd) useCustomSSHRemote(ctx context.Context, r *git.Repository, remote, workDir string, log *logger.Buffer) bool {
+	url, err := gitutil.RemoteURL(r, remote)
+	if err != nil {
+		return false
+	}
+	return cmd.useCustomSSH(ctx, url, workDir, log)
+}
+
+// fetchLimitArgs returns arguments of git-fetch to limit the history to fetch
+// (get.fetch_depth or get.fetch_shallow_since).
+// The history is not limited if r is not a shallow repository, not to make
+// a full clone shallow.
+func fetchLimitArgs(r *git.Repository, workDir string, cfg *config.Config, log *logger.Buffer) []string {
+	limit := historyLimitArgs(cfg)
+	if len(limit) > 0 && !isShallowRepos(r) {
+		log.Warnf("get.fetch_depth and get.fetch_shallow_since are ignored for %s because it is a full clone", workDir)
+		return nil
+	}
+	return limit
+}
+
+// historyLimitArgs returns arguments of git-fetch and git-clone for
+// get.fetch_depth or get.fetch_shallow_since.
+func historyLimitArgs(cfg *config.Config) []string {
+	if n := cfg.Get.FetchDepth; n != nil && *n > 0 {
+		return []string{"--depth", strconv.Itoa(*n)}
+	}
+	if since := cfg.Get.FetchShallowSince; since != "" {
+		return []string{"--shallow-since=" + since}
+	}
+	return nil
+}
 
+// cloneLimitArgs returns arguments of git-clone to make a shallow clone by
+// get.fetch_depth or get.fetch_shallow_since, so that "volt get -u" limits
+// the history to fetch. -depth option (opts.Depth) takes precedence over them.
+func cloneLimitArgs(opts *lockjson.CloneOptions, cfg *config.Config) []string {
+	if opts != nil && opts.Depth > 0 {
+		return nil
+	}
+	limit := historyLimitArgs(cfg)
+	// --depth and --shallow-since imply --single-branch
+	if len(limit) > 0 && (opts == nil || !opts.SingleBranch) {
+		limit = append(limit, "--no-single-branch")
+	}
+	return limit
+}
+
+// fetchDepth returns get.fetch_depth for go-git, or 0 if r is not a shallow
+// repository.
+func fetchDepth(r *git.Repository, cfg *config.Config) int {
+	if !isShallowRepos(r) {
+		return 0
+	}
+	return *cfg.Get.FetchDepth
+}
+
+// isShallowRepos returns true if r is a shallow repository.
+func isShallowRepos(r *git.Repository) bool {
+	shallows, err := r.Storer.Shallow()
+	return err == nil && len(shallows) > 0
+}
+
+// hasLocalCommits returns true if HEAD of workDir has commits which are not
+// in the remote-tracking branches of remote.
+func hasLocalCommits(ctx context.Context, workDir, remote string) (bool, error) {
+	args := []string{"rev-list", "--max-count=1", "HEAD", "--not", "--remotes=" + remote}
+	gitCmd := exec.CommandContext(ctx, "git", args...)
+	gitCmd.Dir = workDir
+	out, err := gitCmd.Output()
+	if err != nil {
+		return false, errors.Errorf("\"git %s\" failed: %s", strings.Join(args, " "), err.Error())
+	}
+	return len(bytes.TrimSpace(out)) > 0, nil
+}
+
+// execGitUpdate calls update, and returns git.NoErrAlreadyUpToDate if HEAD
+// was not changed.
+func (cmd *getCmd) execGitUpdate(ctx context.Context, r *git.Repository, workDir string, update func() error) error {
 	before, err := gitutil.GetHEADRepository(r)
-	pull := exec.Command("git", "pull")
-	pull.Dir = workDir
-	err = pull.Run()
 	if err != nil {
 		return err
 	}
+	if err := update(); err != nil {
+		return err
+	}
 	if changed, err := cmd.getWorktreeChanges(r, before); err != nil {
 		return err
 	} else if !changed {
@@ -673,6 +2086,35 @@ func (cmd *getCmd) gitPull(r *git.Repository, workDir string, remote string, cfg
 	return nil
 }
 
+// execGitProgress is same as execGit, but writes the progress to progress
+// (can be nil). args[0] must be a subcommand which has --progress option
+// (e.g. "fetch").
+func execGitProgress(ctx context.Context, workDir string, progress io.Writer, args ...string) error {
+	if progress == nil {
+		return execGit(ctx, workDir, args...)
+	}
+	args = append([]string{args[0], "--progress"}, args[1:]...)
+	gitCmd := exec.CommandContext(ctx, "git", args...)
+	gitCmd.Dir = workDir
+	var out bytes.Buffer
+	gitCmd.Stdout = &out
+	gitCmd.Stderr = io.MultiWriter(&out, progress)
+	if err := gitCmd.Run(); err != nil {
+		return errors.Errorf("\"git %s\" failed, out=%s: %s", strings.Join(args, " "), out.String(), err.Error())
+	}
+	return nil
+}
+
+// execGit executes "git {args}" in workDir.
+func execGit(ctx context.Context, workDir string, args ...string) error {
+	gitCmd := exec.CommandContext(ctx, "git", args...)
+	gitCmd.Dir = workDir
+	if out, err := gitCmd.CombinedOutput(); err != nil {
+		return errors.Errorf("\"git %s\" failed, out=%s: %s", strings.Join(args, " "), string(out), err.Error())
+	}
+	return nil
+}
+
 func (cmd *getCmd) getWorktreeChanges(r *git.Repository, before string) (bool, error) {
 	after, err := gitutil.GetHEADRepository(r)
 	if err != nil {
@@ -681,35 +2123,115 @@ func (cmd *getCmd) getWorktreeChanges(r *git.Repository, before string) (bool, e
 	return before != after, nil
 }
 
-func (cmd *getCmd) gitClone(cloneURL, dstDir string, cfg *config.Config) error {
-	isBare := false
-	r, err := git.PlainClone(dstDir, isBare, &git.CloneOptions{
-		URL: cloneURL,
-		// TODO: Temporarily recursive clone is disabled, because go-git does
-		// not support relative submodule url in .gitmodules and it causes an
-		// error
+// gitClone clones cloneURL to dstDir with opts (can be nil).
+func (cmd *getCmd) gitClone(ctx context.Context, cloneURL, dstDir string, opts *lockjson.CloneOptions, cfg *config.Config, log *logger.Buffer) error {
+	limit := cloneLimitArgs(opts, cfg)
+	gitArgs := append([]string{"clone", "--recursive"}, cloneArgs(opts)...)
+	gitArgs = append(append(gitArgs, limit...), cloneURL, dstDir)
+
+	// go-git does not support partial clone, --shallow-since, and custom SSH
+	// command
+	shallowSince := len(limit) > 0 && cfg.Get.FetchShallowSince != ""
+	if shallowSince && !cmd.hasGitCmd() {
+		log.Warn("get.fetch_shallow_since is ignored because \"git\" command is not found")
+		shallowSince = false
+	}
+	if opts != nil && opts.Filter != "" || shallowSince || cmd.useCustomSSH(ctx, cloneURL, filepath.Dir(dstDir), log) {
+		if !cmd.hasGitCmd() {
+			return errors.New("partial clone (-filter) needs \"git\" command")
+		}
+		r, err := cmd.execGitClone(ctx, cloneURL, dstDir, gitArgs)
+		if err != nil {
+			return err
+		}
+		return gitutil.SetUpstreamRemote(r, "origin")
+	}
+
+	auth, err := cmd.authMethod(ctx, cloneURL, cfg)
+	if err != nil {
+		return err
+	}
+	cloneOpts := &git.CloneOptions{
+		URL:      cloneURL,
+		Progress: cmd.progressWriter(dstDir),
+		Auth:     auth,
+		// go-git does not support relative submodule url in .gitmodules,
+		// submodules are updated by updateSubmodules() instead
 		RecurseSubmodules: 0,
-	})
+	}
+	if opts != nil {
+		cloneOpts.Depth = opts.Depth
+		cloneOpts.SingleBranch = opts.SingleBranch
+	}
+	if cloneOpts.Depth == 0 && cfg.Get.FetchDepth != nil {
+		cloneOpts.Depth = *cfg.Get.FetchDepth
+	}
+	isBare := false
+	r, err := git.PlainCloneContext(ctx, dstDir, isBare, cloneOpts)
+	if err == transport.ErrEmptyRemoteRepository {
+		return errEmptyRemote(cloneURL)
+	}
 	if err != nil {
 		// When fallback_git_cmd is true and git command is installed,
 		// try to invoke git-clone command
-		if !*cfg.Get.FallbackGitCmd || !cmd.hasGitCmd() {
+		if ctx.Err() != nil || !*cfg.Get.FallbackGitCmd || !cmd.hasGitCmd() {
 			return err
 		}
-		logger.Warnf("failed to clone, try to execute \"git clone --recursive %s %s\" instead...: %s", cloneURL, dstDir, err.Error())
+		log.Warnf("failed to clone, try to execute \"git %s\" instead...: %s", strings.Join(gitArgs, " "), err.Error())
 		err = os.RemoveAll(dstDir)
 		if err != nil {
 			return err
 		}
-		out, err := exec.Command("git", "clone", "--recursive", cloneURL, dstDir).CombinedOutput()
-		if err != nil {
-			return errors.Errorf("\"git clone --recursive %s %s\" failed, out=%s: %s", cloneURL, dstDir, string(out), err.Error())
+		if r, err = cmd.execGitClone(ctx, cloneURL, dstDir, gitArgs); err != nil {
+			return err
 		}
 	}
 
 	return gitutil.SetUpstreamRemote(r, "origin")
 }
 
+// execGitClone executes "git {gitArgs}" to clone cloneURL to dstDir, and
+// opens the cloned repository.
+func (cmd *getCmd) execGitClone(ctx context.Context, cloneURL, dstDir string, gitArgs []string) (*git.Repository, error) {
+	if err := execGitProgress(ctx, "", cmd.progressWriter(dstDir), gitArgs...); err != nil {
+		return nil, err
+	}
+	r, err := git.PlainOpen(dstDir)
+	if err != nil {
+		return nil, err
+	}
+	if gitutil.IsEmptyRepository(r) {
+		return nil, errEmptyRemote(cloneURL)
+	}
+	return r, nil
+}
+
+// cloneArgs returns the arguments of git-clone for opts (can be nil).
+func cloneArgs(opts *lockjson.CloneOptions) []string {
+	if opts == nil {
+		return nil
+	}
+	var args []string
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+		// --depth implies --single-branch
+		if !opts.SingleBranch {
+			args = append(args, "--no-single-branch")
+		}
+	}
+	if opts.Filter != "" {
+		args = append(args, "--filter="+opts.Filter)
+	}
+	if opts.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	return args
+}
+
+func errEmptyRemote(cloneURL string) error {
+	return errors.Errorf("%s is an empty repository (it has no commits yet), try again after commits are pushed", cloneURL)
+}
+
 func (cmd *getCmd) hasGitCmd() bool {
 	exeName := "git"
 	if runtime.GOOS == "windows" {
@@ -0,0 +1,259 @@
+package subcmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/httputil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// archiveExts are the extensions of the archives which "volt get {url}"
+// installs as archive repositories.
+var archiveExts = []string{".tar.gz", ".tgz", ".zip"}
+
+// archiveHashPrefix is the prefix of the content hash of archive repository.
+const archiveHashPrefix = "sha256:"
+
+// archiveExtOf returns the extension of archive URL, or "" if rawurl is not
+// an http(s) URL of an archive.
+func archiveExtOf(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(strings.ToLower(u.Path), ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// parseArchiveURL returns the repository of archive URL rawurl
+// ("https://{host}/{user}/{name}/..." or "https://{host}/{user}/{name}.zip").
+// ok is false if rawurl is not an http(s) URL of an archive.
+func parseArchiveURL(rawurl string) (reposPath pathutil.ReposPath, ok bool, err error) {
+	ext := archiveExtOf(rawurl)
+	if ext == "" {
+		return "", false, nil
+	}
+	u, _ := url.Parse(rawurl)
+	var elems []string
+	for _, e := range strings.Split(u.Path, "/") {
+		if e == "." || e == ".." {
+			// The repository must not be outside of the repos directory
+			return "", true, errors.New("invalid path of the archive URL: " + rawurl)
+		}
+		if e != "" {
+			elems = append(elems, e)
+		}
+	}
+	if len(elems) < 2 {
+		return "", true, errors.New("cannot determine {user}/{name} of the archive URL: " + rawurl)
+	}
+	name := elems[1]
+	if len(elems) == 2 {
+		name = name[:len(name)-len(ext)]
+	}
+	reposPath, err = pathutil.NormalizeRepos(u.Host + "/" + elems[0] + "/" + name)
+	if err != nil {
+		return "", true, errors.Wrap(err, "cannot determine the repository of the archive URL")
+	}
+	return reposPath, true, nil
+}
+
+// This function is executed in goroutine of each archive repository.
+// It downloads the archive and installs the repository if it does not exist,
+// or downloads it again if cmd.upgrade is true or the URL was changed.
+// When installing a repository in lock.json (e.g. "volt get -l"), the
+// content hash of the archive must be the locked one.
+func (cmd *getCmd) installArchive(ctx context.Context, reposPath pathutil.ReposPath, repos *lockjson.Repos, log *logger.Buffer, done chan<- getParallelResult) {
+	fullReposPath := reposPath.FullPath()
+	doInstall := !pathutil.Exists(fullReposPath)
+	doUpgrade := cmd.upgrade && !doInstall
+	failed := func(format string, err error) {
+		done <- getParallelResult{
+			reposPath: reposPath,
+			status:    fmt.Sprintf(format, reposPath),
+			err:       err,
+		}
+	}
+
+	if repos != nil && repos.Type != lockjson.ReposArchiveType {
+		failed(fmtInstallFailed, errors.Errorf(
+			"already installed as %s repository (run 'volt rm -r %s' first)", repos.Type, reposPath))
+		return
+	}
+	if repos == nil && !doInstall {
+		failed(fmtInstallFailed, errors.New(fullReposPath+" already exists"))
+		return
+	}
+
+	archiveURL := cmd.archives[reposPath]
+	if archiveURL == "" {
+		archiveURL = repos.URL
+	}
+	var fromHash string
+	if repos != nil {
+		fromHash = repos.Version
+	}
+	changeURL := repos != nil && archiveURL != repos.URL
+
+	if !doInstall && !doUpgrade && !changeURL {
+		done <- getParallelResult{
+			reposPath: reposPath,
+			status:    fmt.Sprintf(fmtAlreadyExists, reposPath),
+			reposType: lockjson.ReposArchiveType,
+			hash:      fromHash,
+			url:       archiveURL,
+		}
+		return
+	}
+
+	// The locked archive must be installed unless it is upgraded
+	expected := cmd.archiveHashes[reposPath]
+	if expected == "" && doInstall && !changeURL {
+		expected = fromHash
+	}
+	log.Debugf("Downloading %s ...", archiveURL)
+	toHash, err := cmd.downloadArchive(ctx, reposPath, archiveURL, expected, fromHash)
+	if err != nil {
+		format := fmtInstallFailed
+		if doUpgrade {
+			format = fmtUpgradeFailed
+		}
+		if doInstall {
+			fileutil.RemoveDirs(filepath.Dir(fullReposPath))
+		}
+		failed(format, errors.Wrap(err, "failed to download "+archiveURL))
+		return
+	}
+
+	var status string
+	if doInstall {
+		status = fmt.Sprintf(fmtInstalled, reposPath)
+	} else if toHash == fromHash {
+		status = fmt.Sprintf(fmtNoChange, reposPath)
+	} else {
+		status = fmt.Sprintf(fmtArchiveUpgraded, reposPath, shortArchiveHash(fromHash), shortArchiveHash(toHash))
+	}
+	done <- getParallelResult{
+		reposPath: reposPath,
+		status:    status,
+		reposType: lockjson.ReposArchiveType,
+		hash:      toHash,
+		url:       archiveURL,
+		installed: doInstall,
+	}
+}
+
+// downloadArchive downloads the archive of archiveURL, and replaces the
+// repository directory with the extracted files. If the archive has only one
+// top directory, its contents are extracted.
+// It returns the content hash of the archive. Non-nil error is returned if
+// expected is not empty and the content hash is not expected. If the content
+// hash is current, the repository directory is not replaced.
+func (*getCmd) downloadArchive(ctx context.Context, reposPath pathutil.ReposPath, archiveURL, expected, current string) (string, error) {
+	ext := archiveExtOf(archiveURL)
+	if ext == "" {
+		return "", errors.Errorf("not an archive URL (must be http or https URL of %s): %s", strings.Join(archiveExts, ", "), archiveURL)
+	}
+	fullpath := reposPath.FullPath()
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		return "", err
+	}
+
+	// Extract to a temporary directory not to break the repository on failure
+	tempDir, err := ioutil.TempDir(filepath.Dir(fullpath), "."+filepath.Base(fullpath)+"-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tempDir)
+
+	archive := filepath.Join(tempDir, "archive"+ext)
+	hash, err := saveArchive(ctx, archiveURL, archive)
+	if err != nil {
+		return "", err
+	}
+	if expected != "" && hash != expected {
+		return "", errors.Errorf("the content hash is %s but locked one is %s (run \"volt get -u %s\" to accept the new content)", hash, expected, reposPath)
+	}
+	if hash == current && pathutil.Exists(fullpath) {
+		return hash, nil
+	}
+
+	extracted := filepath.Join(tempDir, "repos")
+	if err := extractArchive(archive, ext, extracted); err != nil {
+		return "", errors.Wrap(err, "could not extract the archive")
+	}
+	root := extracted
+	if infos, err := ioutil.ReadDir(extracted); err == nil && len(infos) == 1 && infos[0].IsDir() {
+		root = filepath.Join(extracted, infos[0].Name())
+	}
+
+	if err := os.RemoveAll(fullpath); err != nil {
+		return "", err
+	}
+	return hash, os.Rename(root, fullpath)
+}
+
+// saveArchive downloads archiveURL to path, and returns its content hash.
+func saveArchive(ctx context.Context, archiveURL, path string) (string, error) {
+	r, err := httputil.GetContentReader(ctx, archiveURL)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return archiveHashPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func extractArchive(archive, ext, dst string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if ext == ".zip" {
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return fileutil.ExtractZip(f, fi.Size(), dst, 0)
+	}
+	return fileutil.ExtractTarGz(f, dst, 0)
+}
+
+// shortArchiveHash returns the first 12 hex digits of the content hash.
+func shortArchiveHash(hash string) string {
+	hash = strings.TrimPrefix(hash, archiveHashPrefix)
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
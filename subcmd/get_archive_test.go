@@ -0,0 +1,120 @@
+package subcmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestParseArchiveURL(t *testing.T) {
+	for _, tt := range []struct {
+		url       string
+		reposPath pathutil.ReposPath
+		ok        bool
+		err       bool
+	}{
+		{"https://example.com/user/name/archive/v1.0.tar.gz", "example.com/user/name", true, false},
+		{"https://Example.com/user/name.zip", "example.com/user/name", true, false},
+		{"http://example.com/user/name/name-1.0.tgz?dl=1", "example.com/user/name", true, false},
+		{"https://example.com/name.zip", "", true, true},
+		{"https://example.com/a/../x.zip", "", true, true},
+		{"https://example.com/a/./x.zip", "", true, true},
+		{"https://example.com/user/name", "", false, false},
+		{"tyru/caw.vim", "", false, false},
+		{"file:///tmp/user/name.zip", "", false, false},
+	} {
+		reposPath, ok, err := parseArchiveURL(tt.url)
+		if ok != tt.ok || (err != nil) != tt.err || reposPath != tt.reposPath {
+			t.Errorf("%s: expected (%q, %v, error=%v) but got (%q, %v, %v)", tt.url, tt.reposPath, tt.ok, tt.err, reposPath, ok, err)
+		}
+	}
+}
+
+func TestInstallArchive(t *testing.T) {
+	// The zip has "plugin/name.vim" under the top directory, whose content
+	// is version
+	version := "v1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		f, _ := zw.Create("name-master/plugin/name.vim")
+		f.Write([]byte(version))
+		zw.Close()
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	archiveURL := server.URL + "/user/name/archive/master.zip"
+	reposPath, _, err := parseArchiveURL(archiveURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	install := func(cmd *getCmd, repos *lockjson.Repos) getParallelResult {
+		done := make(chan getParallelResult, 1)
+		cmd.installArchive(context.Background(), reposPath, repos, logger.NewBuffer(), done)
+		return <-done
+	}
+	assertContent := func(want string) {
+		b, err := ioutil.ReadFile(filepath.Join(reposPath.FullPath(), "plugin", "name.vim"))
+		if err != nil {
+			t.Fatal(err)
+		} else if string(b) != want {
+			t.Errorf("expected %q is installed but got %q", want, b)
+		}
+	}
+
+	// Install
+	archives := map[pathutil.ReposPath]string{reposPath: archiveURL}
+	r := install(&getCmd{archives: archives}, nil)
+	if r.err != nil || !strings.HasSuffix(r.status, "> installed") || !strings.HasPrefix(r.hash, archiveHashPrefix) || r.url != archiveURL || r.reposType != lockjson.ReposArchiveType {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+	assertContent("v1")
+	repos := &lockjson.Repos{Type: r.reposType, Path: reposPath, Version: r.hash, URL: r.url}
+
+	// No change
+	r = install(&getCmd{upgrade: true}, repos)
+	if r.err != nil || !strings.HasSuffix(r.status, "> no change") || r.hash != repos.Version {
+		t.Errorf("unexpected result: %+v", r)
+	}
+
+	// The locked archive is not installed if the content was changed
+	version = "v2"
+	os.RemoveAll(reposPath.FullPath())
+	r = install(&getCmd{}, repos)
+	if r.err == nil || !strings.Contains(r.err.Error(), "locked one is "+repos.Version) {
+		t.Errorf("expected the content hash mismatch but got %+v", r)
+	}
+	if pathutil.Exists(reposPath.FullPath()) {
+		t.Errorf("expected %s is not installed", reposPath.FullPath())
+	}
+
+	// Upgrade
+	version = "v1"
+	install(&getCmd{}, repos)
+	version = "v2"
+	r = install(&getCmd{upgrade: true}, repos)
+	if r.err != nil || !strings.Contains(r.status, "> upgraded archive") || r.hash == repos.Version {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	assertContent("v2")
+}
@@ -0,0 +1,116 @@
+package subcmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/gitutil"
+)
+
+// defaultTokenUsername is the username sent with credentials.{host}.token
+// if credentials.{host}.username is not set.
+const defaultTokenUsername = "x-access-token"
+
+// authMethod returns the authentication for go-git to clone or fetch
+// cloneURL, by [credentials] of config.toml.
+// nil is returned if cloneURL is not HTTP(S) URL or no credential is
+// configured for the host.
+func (cmd *getCmd) authMethod(ctx context.Context, cloneURL string, cfg *config.Config) (transport.AuthMethod, error) {
+	u, err := url.Parse(cloneURL)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return nil, nil
+	}
+	cred := cfg.CredentialOf(u.Host)
+	if cred == nil {
+		cred = cfg.CredentialOf(u.Hostname())
+	}
+	if cred == nil {
+		return nil, nil
+	}
+
+	switch {
+	case cred.Helper != "":
+		if !cmd.hasGitCmd() {
+			return nil, errors.New("credentials." + u.Host + ".helper needs \"git\" command")
+		}
+		username, password, err := fillCredential(ctx, cred.Helper, u, cred.Username)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get the credential of "+u.Host)
+		}
+		return githttp.NewBasicAuth(username, password), nil
+	case cred.Token != "":
+		username := cred.Username
+		if username == "" {
+			username = defaultTokenUsername
+		}
+		return githttp.NewBasicAuth(username, cred.Token), nil
+	default:
+		return githttp.NewBasicAuth(cred.Username, cred.Password), nil
+	}
+}
+
+// remoteAuthMethod is same as authMethod for the URL of remote.
+func (cmd *getCmd) remoteAuthMethod(ctx context.Context, r *git.Repository, remote string, cfg *config.Config) (transport.AuthMethod, error) {
+	url, err := gitutil.RemoteURL(r, remote)
+	if err != nil {
+		return nil, nil
+	}
+	return cmd.authMethod(ctx, url, cfg)
+}
+
+// fillCredential gets the username and the password of u from the git
+// credential helper ("git credential fill"). Other helpers of git config are
+// not used, and the user is not prompted.
+func fillCredential(ctx context.Context, helper string, u *url.URL, username string) (string, string, error) {
+	var input bytes.Buffer
+	input.WriteString("protocol=" + u.Scheme + "\n")
+	input.WriteString("host=" + u.Host + "\n")
+	input.WriteString("path=" + strings.TrimPrefix(u.Path, "/") + "\n")
+	if username != "" {
+		input.WriteString("username=" + username + "\n")
+	}
+	input.WriteString("\n")
+
+	gitCmd := exec.CommandContext(ctx, "git",
+		"-c", "credential.helper=", "-c", "credential.helper="+helper,
+		"credential", "fill")
+	gitCmd.Stdin = &input
+	gitCmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	gitCmd.Stderr = &stderr
+	out, err := gitCmd.Output()
+	if err != nil {
+		return "", "", errors.Errorf("\"git credential fill\" failed, out=%s: %s", stderr.String(), err.Error())
+	}
+	return parseCredential(out)
+}
+
+// parseCredential parses the output of "git credential fill"
+// ("{key}={value}" lines), and returns the username and the password.
+func parseCredential(out []byte) (string, string, error) {
+	var username, password string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "username=") {
+			username = strings.TrimPrefix(line, "username=")
+		} else if strings.HasPrefix(line, "password=") {
+			password = strings.TrimPrefix(line, "password=")
+		}
+	}
+	if password == "" {
+		return "", "", errors.New("the credential helper returned no password")
+	}
+	return username, password, nil
+}
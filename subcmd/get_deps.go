@@ -0,0 +1,110 @@
+package subcmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+)
+
+// installDepends installs the plugins which reposPathList depends on by
+// s:depends() of their plugconfs, and which are not in current profile.
+// It is repeated for the dependencies of the installed plugins, because their
+// plugconfs may be created by installing them.
+func (cmd *getCmd) installDepends(ctx context.Context, reposPathList []pathutil.ReposPath, lockJSON *lockjson.LockJSON, cfg *config.Config) error {
+	// The dependencies are installed, not upgraded
+	cmd.upgrade = false
+	cmd.verify = false
+
+	queued := make(map[pathutil.ReposPath]bool, len(reposPathList))
+	for _, reposPath := range reposPathList {
+		queued[reposPath] = true
+	}
+	for {
+		deps, err := missingDepends(reposPathList, lockJSON, queued)
+		if err != nil || len(deps) == 0 {
+			return err
+		}
+		for _, reposPath := range deps {
+			queued[reposPath] = true
+		}
+		logger.Info("Installing dependencies: " + strings.Join(deps.Strings(), ", "))
+		err = cmd.doGet(ctx, deps, make(map[pathutil.ReposPath]pathutil.ReposRef), lockJSON, cfg)
+		if err != nil {
+			return err
+		}
+		reposPathList = deps
+	}
+}
+
+// missingDepends follows s:depends() of the plugconfs of reposPathList
+// recursively, and returns the dependencies which are neither in current
+// profile nor in queued. An error is returned if the plugins depend on each
+// other.
+func missingDepends(reposPathList []pathutil.ReposPath, lockJSON *lockjson.LockJSON, queued map[pathutil.ReposPath]bool) (pathutil.ReposPathList, error) {
+	current, err := lockJSON.GetCurrentReposList()
+	if err != nil {
+		return nil, err
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[pathutil.ReposPath]int)
+	var missing pathutil.ReposPathList
+	var stack pathutil.ReposPathList
+	var visit func(reposPath pathutil.ReposPath) error
+	visit = func(reposPath pathutil.ReposPath) error {
+		switch state[reposPath] {
+		case visiting:
+			i := len(stack) - 1
+			for stack[i] != reposPath {
+				i--
+			}
+			cycle := append(append(pathutil.ReposPathList{}, stack[i:]...), reposPath)
+			return errors.New("dependency cycle in s:depends(): " + strings.Join(cycle.Strings(), " -> "))
+		case visited:
+			return nil
+		}
+		state[reposPath] = visiting
+		stack = append(stack, reposPath)
+		deps, err := plugconf.DependsOf(reposPath)
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			if r := lockJSON.Repos.FindByPath(dep); r != nil {
+				dep = r.Path
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+			if !queued[dep] && !current.Contains(dep) && !missing.Contains(dep) {
+				missing = append(missing, dep)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[reposPath] = visited
+		return nil
+	}
+
+	for _, reposPath := range reposPathList {
+		if err := visit(reposPath); err != nil {
+			return nil, err
+		}
+	}
+	return missing, nil
+}
+
+// installsDepends returns true if the dependencies of the given repositories
+// are installed.
+func (cmd *getCmd) installsDepends() bool {
+	return !cmd.noDeps && cmd.fromLock == "" && cmd.fromFreeze == ""
+}
@@ -0,0 +1,64 @@
+package subcmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestMissingDepends(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	writeDepends := func(reposPath pathutil.ReposPath, deps ...string) {
+		path := reposPath.Plugconf()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := "function! s:depends()\n  return ['" + strings.Join(deps, "', '") + "']\nendfunction\n"
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lockJSON := &lockjson.LockJSON{
+		CurrentProfileName: "default",
+		Repos: lockjson.ReposList{
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/open-browser-github.vim"},
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/open-browser.vim"},
+		},
+		Profiles: lockjson.ProfileList{
+			{Name: "default", ReposPath: []pathutil.ReposPath{"github.com/tyru/open-browser-github.vim"}},
+		},
+	}
+	// open-browser.vim is in lock.json but not in current profile, and
+	// webapi-vim is a dependency of a dependency
+	writeDepends("github.com/tyru/open-browser-github.vim", "tyru/open-browser.vim", "mattn/webapi-vim")
+	writeDepends("github.com/tyru/open-browser.vim", "mattn/webapi-vim", "tyru/caw.vim")
+	queued := map[pathutil.ReposPath]bool{"github.com/tyru/caw.vim": true}
+	missing, err := missingDepends([]pathutil.ReposPath{"github.com/tyru/open-browser-github.vim"}, lockJSON, queued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := pathutil.ReposPathList{"github.com/mattn/webapi-vim", "github.com/tyru/open-browser.vim"}
+	if !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected %v but got %v", expected, missing)
+	}
+
+	writeDepends("github.com/mattn/webapi-vim", "tyru/open-browser-github.vim")
+	_, err = missingDepends([]pathutil.ReposPath{"github.com/tyru/open-browser-github.vim"}, lockJSON, queued)
+	expectedErr := "dependency cycle in s:depends(): github.com/tyru/open-browser-github.vim -> github.com/tyru/open-browser.vim -> github.com/mattn/webapi-vim -> github.com/tyru/open-browser-github.vim"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q but got %v", expectedErr, err)
+	}
+}
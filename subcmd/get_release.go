@@ -0,0 +1,242 @@
+package subcmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/httputil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// latestReleasePattern is the release pattern to track the latest release.
+const latestReleasePattern = "latest"
+
+// githubAPIURL is the base URL of GitHub API (overwritten in tests).
+var githubAPIURL = "https://api.github.com"
+
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// validateReleasePattern returns non-nil error if pattern is neither "latest"
+// nor a valid tag pattern of path.Match().
+func validateReleasePattern(pattern string) error {
+	if pattern == latestReleasePattern {
+		return nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return errors.Errorf("invalid release pattern %q: %s", pattern, err)
+	}
+	return nil
+}
+
+// exactReleasePattern returns the release pattern which matches only tag.
+func exactReleasePattern(tag string) string {
+	var buf bytes.Buffer
+	for _, c := range tag {
+		if strings.ContainsRune(`*?[\`, c) {
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(c)
+	}
+	return buf.String()
+}
+
+// This function is executed in goroutine of each release repository.
+// It installs the release matched with the pattern if the repository does not
+// exist, or upgrades to the newest release matched with the pattern if
+// cmd.upgrade is true or the pattern was changed by -release option.
+// When installing a repository in lock.json (e.g. "volt get -l"), the locked
+// release is installed.
+func (cmd *getCmd) installRelease(ctx context.Context, reposPath pathutil.ReposPath, repos *lockjson.Repos, log *logger.Buffer, done chan<- getParallelResult) {
+	fullReposPath := reposPath.FullPath()
+	doInstall := !pathutil.Exists(fullReposPath)
+	doUpgrade := cmd.upgrade && !doInstall
+	failed := func(format string, err error) {
+		done <- getParallelResult{
+			reposPath: reposPath,
+			status:    fmt.Sprintf(format, reposPath),
+			err:       err,
+		}
+	}
+
+	if repos != nil && repos.Type != lockjson.ReposReleaseType {
+		failed(fmtInstallFailed, errors.Errorf(
+			"already installed as %s repository (run 'volt rm -r %s' first)", repos.Type, reposPath))
+		return
+	}
+	if repos == nil && !doInstall {
+		failed(fmtInstallFailed, errors.New(fullReposPath+" already exists"))
+		return
+	}
+
+	pattern := cmd.release
+	if p := cmd.releases[reposPath]; p != "" {
+		pattern = p
+	}
+	if pattern == "" {
+		pattern = repos.Release
+	}
+	var fromTag string
+	if repos != nil {
+		fromTag = repos.Version
+	}
+	changePattern := repos != nil && pattern != repos.Release
+
+	if !doInstall && !doUpgrade && !changePattern {
+		done <- getParallelResult{
+			reposPath: reposPath,
+			status:    fmt.Sprintf(fmtAlreadyExists, reposPath),
+			reposType: lockjson.ReposReleaseType,
+			hash:      fromTag,
+			release:   pattern,
+		}
+		return
+	}
+
+	// Resolve the release to install
+	toTag := fromTag
+	if toTag == "" || doUpgrade || changePattern {
+		log.Debugf("Resolving release %q of %s ...", pattern, reposPath)
+		release, err := cmd.resolveRelease(ctx, reposPath, pattern)
+		if err != nil {
+			format := fmtInstallFailed
+			if doUpgrade {
+				format = fmtUpgradeFailed
+			}
+			failed(format, errors.Wrap(err, "failed to resolve release"))
+			return
+		}
+		toTag = release.TagName
+	}
+
+	var status string
+	if doInstall {
+		status = fmt.Sprintf(fmtInstalled, reposPath)
+	} else if toTag == fromTag {
+		status = fmt.Sprintf(fmtNoChange, reposPath)
+	} else {
+		status = fmt.Sprintf(fmtReleaseUpgraded, reposPath, fromTag, toTag)
+	}
+
+	if doInstall || toTag != fromTag {
+		log.Debugf("Downloading release %s of %s ...", toTag, reposPath)
+		if err := cmd.downloadRelease(ctx, reposPath, toTag); err != nil {
+			format := fmtInstallFailed
+			if doUpgrade {
+				format = fmtUpgradeFailed
+			}
+			if doInstall {
+				fileutil.RemoveDirs(filepath.Dir(fullReposPath))
+			}
+			failed(format, errors.Wrap(err, "failed to download release "+toTag))
+			return
+		}
+	}
+
+	done <- getParallelResult{
+		reposPath: reposPath,
+		status:    status,
+		reposType: lockjson.ReposReleaseType,
+		hash:      toTag,
+		release:   pattern,
+		installed: doInstall,
+	}
+}
+
+// resolveRelease returns the newest GitHub release of reposPath matched with
+// pattern. Draft and prerelease are ignored.
+func (*getCmd) resolveRelease(ctx context.Context, reposPath pathutil.ReposPath, pattern string) (*githubRelease, error) {
+	userName, ok := reposPath.GitHubUserName()
+	if !ok {
+		return nil, errReleaseNotGitHub(reposPath)
+	}
+
+	if pattern == latestReleasePattern {
+		content, err := httputil.GetContent(ctx, githubAPIURL+"/repos/"+userName+"/releases/latest")
+		if err != nil {
+			return nil, err
+		}
+		var release githubRelease
+		if err := json.Unmarshal(content, &release); err != nil {
+			return nil, err
+		}
+		return &release, nil
+	}
+
+	// Releases are sorted by created date in descending order
+	content, err := httputil.GetContent(ctx, githubAPIURL+"/repos/"+userName+"/releases?per_page=100")
+	if err != nil {
+		return nil, err
+	}
+	var releases []githubRelease
+	if err := json.Unmarshal(content, &releases); err != nil {
+		return nil, err
+	}
+	for i := range releases {
+		if releases[i].Draft || releases[i].Prerelease {
+			continue
+		}
+		if ok, _ := path.Match(pattern, releases[i].TagName); ok {
+			return &releases[i], nil
+		}
+	}
+	return nil, errors.Errorf("no release matched with %q", pattern)
+}
+
+// downloadRelease downloads the source archive of tag, and replaces the
+// repository directory with the extracted files.
+func (*getCmd) downloadRelease(ctx context.Context, reposPath pathutil.ReposPath, tag string) error {
+	userName, ok := reposPath.GitHubUserName()
+	if !ok {
+		return errReleaseNotGitHub(reposPath)
+	}
+	fullpath := reposPath.FullPath()
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		return err
+	}
+
+	// Extract to a temporary directory not to break the repository on failure
+	tempDir, err := ioutil.TempDir(filepath.Dir(fullpath), "."+filepath.Base(fullpath)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	r, err := httputil.GetContentReader(ctx, githubAPIURL+"/repos/"+userName+"/tarball/"+url.PathEscape(tag))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	// The archive has one top directory (e.g. "{user}-{name}-{sha}/")
+	extracted := filepath.Join(tempDir, "repos")
+	if err := fileutil.ExtractTarGz(r, extracted, 1); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(fullpath); err != nil {
+		return err
+	}
+	return os.Rename(extracted, fullpath)
+}
+
+// errReleaseNotGitHub returns the error for the release of reposPath which is
+// not a GitHub repository.
+func errReleaseNotGitHub(reposPath pathutil.ReposPath) error {
+	return errors.New("releases are supported only for github.com repositories: " + reposPath.String())
+}
@@ -0,0 +1,147 @@
+package subcmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// newFakeGitHubAPI returns a server which serves releases of "tyru/caw.vim".
+// The tarball of each tag has "plugin/caw.vim" whose content is the tag name.
+func newFakeGitHubAPI(t *testing.T) *httptest.Server {
+	const releases = `[
+		{"tag_name": "v2.0.0-rc1", "prerelease": true},
+		{"tag_name": "v2.0.0-draft", "draft": true},
+		{"tag_name": "v1.1.0"},
+		{"tag_name": "v1.0.0"},
+		{"tag_name": "v0.9.0"}
+	]`
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/tyru/caw.vim/releases":
+			w.Write([]byte(releases))
+		case r.URL.Path == "/repos/tyru/caw.vim/releases/latest":
+			w.Write([]byte(`{"tag_name": "v1.1.0"}`))
+		case strings.HasPrefix(r.URL.Path, "/repos/tyru/caw.vim/tarball/"):
+			tag := strings.TrimPrefix(r.URL.Path, "/repos/tyru/caw.vim/tarball/")
+			var buf bytes.Buffer
+			gw := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gw)
+			tw.WriteHeader(&tar.Header{Name: "tyru-caw.vim-abc1234/plugin/caw.vim", Mode: 0644, Size: int64(len(tag)), Typeflag: tar.TypeReg})
+			tw.Write([]byte(tag))
+			tw.Close()
+			gw.Close()
+			w.Write(buf.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestResolveRelease(t *testing.T) {
+	server := newFakeGitHubAPI(t)
+	defer server.Close()
+	defer func(orig string) { githubAPIURL = orig }(githubAPIURL)
+	githubAPIURL = server.URL
+
+	cmd := &getCmd{}
+	for pattern, want := range map[string]string{
+		"latest": "v1.1.0",
+		"v1.*":   "v1.1.0",
+		"v1.0.*": "v1.0.0",
+		"v0.*":   "v0.9.0",
+		"v2.*":   "",
+	} {
+		release, err := cmd.resolveRelease(context.Background(), "github.com/tyru/caw.vim", pattern)
+		if want == "" {
+			if err == nil {
+				t.Errorf("%s: expected error but got %q", pattern, release.TagName)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", pattern, err)
+		} else if release.TagName != want {
+			t.Errorf("%s: expected %q but got %q", pattern, want, release.TagName)
+		}
+	}
+
+	if _, err := cmd.resolveRelease(context.Background(), "gitlab.com/tyru/caw.vim", "latest"); err == nil {
+		t.Error("expected error for non-GitHub repository")
+	}
+}
+
+func TestInstallRelease(t *testing.T) {
+	server := newFakeGitHubAPI(t)
+	defer server.Close()
+	defer func(orig string) { githubAPIURL = orig }(githubAPIURL)
+	githubAPIURL = server.URL
+
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	reposPath := pathutil.ReposPath("github.com/tyru/caw.vim")
+	install := func(cmd *getCmd, repos *lockjson.Repos) getParallelResult {
+		done := make(chan getParallelResult, 1)
+		cmd.installRelease(context.Background(), reposPath, repos, logger.NewBuffer(), done)
+		r := <-done
+		if r.err != nil {
+			t.Fatalf("%s: %s", r.status, r.err)
+		}
+		return r
+	}
+	assertContent := func(want string) {
+		b, err := ioutil.ReadFile(filepath.Join(reposPath.FullPath(), "plugin", "caw.vim"))
+		if err != nil {
+			t.Fatal(err)
+		} else if string(b) != want {
+			t.Errorf("expected %q is installed but got %q", want, b)
+		}
+	}
+
+	// Install
+	r := install(&getCmd{release: "v1.0.*"}, nil)
+	if r.status != "+ github.com/tyru/caw.vim > installed" || r.hash != "v1.0.0" || r.release != "v1.0.*" || r.reposType != lockjson.ReposReleaseType {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	assertContent("v1.0.0")
+	repos := &lockjson.Repos{Type: r.reposType, Path: reposPath, Version: r.hash, Release: r.release}
+
+	// No change
+	r = install(&getCmd{upgrade: true}, repos)
+	if r.status != "# github.com/tyru/caw.vim > no change" || r.hash != "v1.0.0" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+
+	// Change the pattern and upgrade
+	r = install(&getCmd{release: "latest"}, repos)
+	if r.status != "* github.com/tyru/caw.vim > upgraded release (v1.0.0..v1.1.0)" || r.release != "latest" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	assertContent("v1.1.0")
+
+	// Install the locked release if it does not exist
+	os.RemoveAll(reposPath.FullPath())
+	r = install(&getCmd{}, repos)
+	if r.hash != "v1.0.0" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	assertContent("v1.0.0")
+}
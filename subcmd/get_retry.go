@@ -0,0 +1,67 @@
+package subcmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/httputil"
+	"github.com/vim-volt/volt/logger"
+)
+
+// retry calls f, and calls it again at most get.retry times while it returns
+// a transient error (e.g. a network error). The delay between the calls
+// starts from get.retry_delay and is doubled each time.
+// what is shown in the log (e.g. "clone github.com/tyru/caw.vim").
+func retry(ctx context.Context, cfg *config.Config, log *logger.Buffer, what string, f func() error) error {
+	delay := cfg.Get.RetryDelayDuration()
+	max := 0
+	if cfg.Get.Retry != nil {
+		max = *cfg.Get.Retry
+	}
+	for i := 1; ; i++ {
+		err := f()
+		if err == nil || i > max || ctx.Err() != nil || !isTransientError(err) {
+			return err
+		}
+		log.Warnf("failed to %s, retrying in %s (%d/%d): %s", what, delay, i, max, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// isTransientError returns false if err will be returned again however many
+// times the operation is retried (e.g. the repository does not exist).
+func isTransientError(err error) bool {
+	switch e := errors.Cause(err).(type) {
+	case nil:
+		return false
+	case *plugconfParseError:
+		return false
+	case *httputil.StatusError:
+		// Server errors, "429 Too Many Requests", and "408 Request Timeout"
+		return e.StatusCode/100 == 5 || e.StatusCode == 429 || e.StatusCode == 408
+	}
+	switch errors.Cause(err) {
+	case context.Canceled,
+		context.DeadlineExceeded,
+		errRepoExists,
+		git.NoErrAlreadyUpToDate,
+		git.ErrUnstaggedChanges,
+		git.ErrWorktreeNotClean,
+		transport.ErrRepositoryNotFound,
+		transport.ErrEmptyRemoteRepository,
+		transport.ErrAuthenticationRequired,
+		transport.ErrAuthorizationFailed:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package subcmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/httputil"
+	"github.com/vim-volt/volt/logger"
+)
+
+func TestIsTransientError(t *testing.T) {
+	for _, tt := range []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{errors.New("dial tcp: lookup github.com: no such host"), true},
+		{errors.Wrap(transport.ErrRepositoryNotFound, "clone"), false},
+		{transport.ErrAuthenticationRequired, false},
+		{git.NoErrAlreadyUpToDate, false},
+		{context.Canceled, false},
+		{errRepoExists, false},
+		{&httputil.StatusError{Status: "404 Not Found", StatusCode: 404}, false},
+		{&httputil.StatusError{Status: "503 Service Unavailable", StatusCode: 503}, true},
+		{&httputil.StatusError{Status: "429 Too Many Requests", StatusCode: 429}, true},
+	} {
+		if got := isTransientError(tt.err); got != tt.expected {
+			t.Errorf("%v: expected %v but got %v", tt.err, tt.expected, got)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	cfg := &config.Config{}
+	n := 2
+	cfg.Get.Retry = &n
+	cfg.Get.RetryDelay = "1ms"
+	log := logger.NewBuffer()
+
+	calls := 0
+	err := retry(context.Background(), cfg, log, "test", func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success after 3 calls but got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = retry(context.Background(), cfg, log, "test", func() error {
+		calls++
+		return errors.New("connection reset by peer")
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("expected an error after 3 calls but got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = retry(context.Background(), cfg, log, "test", func() error {
+		calls++
+		return transport.ErrRepositoryNotFound
+	})
+	if err != transport.ErrRepositoryNotFound || calls != 1 {
+		t.Errorf("expected no retries but got %v after %d calls", err, calls)
+	}
+}
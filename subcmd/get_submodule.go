@@ -0,0 +1,46 @@
+package subcmd
+
+import (
+	"context"
+	"path/filepath"
+
+	"gopkg.in/src-d/go-git.v4"
+
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// updateSubmodules initializes and updates the submodules of reposPath
+// recursively to the commits recorded in the repository. Nothing is done if
+// the repository has no submodules (or is a bare repository).
+// "git submodule update" is used if "git" command exists, because go-git does
+// not support relative submodule URLs in .gitmodules.
+func (cmd *getCmd) updateSubmodules(ctx context.Context, reposPath pathutil.ReposPath, log *logger.Buffer) error {
+	fullpath := reposPath.FullPath()
+	if !pathutil.Exists(filepath.Join(fullpath, ".gitmodules")) {
+		return nil
+	}
+	log.Debugf("Updating submodules of %s ...", reposPath)
+	if cmd.hasGitCmd() {
+		return execGit(ctx, fullpath, "submodule", "update", "--init", "--recursive")
+	}
+
+	r, err := git.PlainOpen(fullpath)
+	if err != nil {
+		return err
+	}
+	wt, err := r.Worktree()
+	if err == git.ErrIsBareRepository {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	subs, err := wt.Submodules()
+	if err != nil {
+		return err
+	}
+	return subs.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+	})
+}
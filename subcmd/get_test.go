@@ -2,17 +2,24 @@ package subcmd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/vim-volt/volt/config"
 	"github.com/vim-volt/volt/internal/testutil"
 	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
@@ -34,7 +41,7 @@ import (
 // (L) Output contains "+ {repos} > added repository to current profile"
 // (M) Output contains "+ {repos} > installed"
 // (N) Output contains "* {repos} > updated lock.json revision ({from}..{to})"
-// (O) Output contains "* {repos} > upgraded ({from}..{to})"
+// (O) Output contains "* {repos} > upgraded ({from}..{to}, {n} commits, newest {date})"
 // (P) Output contains "{repos}: HEAD and locked revision are different ..."
 
 // TODO: Add test cases
@@ -290,7 +297,8 @@ func TestVoltGetMsg(t *testing.T) {
 		testutil.SuccessExit(t, out, err)
 
 		// (O)
-		msg = fmt.Sprintf(fmtUpgraded, reposPath, prev.String(), head.String())
+		msg = fmt.Sprintf(fmtUpgraded, reposPath, prev.String(), head.String(),
+			commitSummary(reposPath, prev.String(), head.String(), logger.NewBuffer()))
 		if !bytes.Contains(out, []byte(msg)) {
 			t.Errorf("Output does not contain %q\n%s", msg, string(out))
 		}
@@ -665,3 +673,388 @@ func gitResetHard(reposPath pathutil.ReposPath, ref string) (current plumbing.Ha
 	})
 	return
 }
+
+func TestHostLimiter(t *testing.T) {
+	limiter := newHostLimiter(2, 0)
+	ctx := context.Background()
+
+	release1, err := limiter.acquire(ctx, "github.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release2, err := limiter.acquire(ctx, "GitHub.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Other hosts are not limited
+	releaseOther, err := limiter.acquire(ctx, "gitlab.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseOther()
+
+	// The third connection to github.com waits until ctx is done
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(timeoutCtx, "github.com"); err != context.DeadlineExceeded {
+		t.Errorf("expected %v but got %v", context.DeadlineExceeded, err)
+	}
+
+	release1()
+	release3, err := limiter.acquire(ctx, "github.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release2()
+	release3()
+
+	// 0 means unlimited
+	unlimited := newHostLimiter(0, 0)
+	for i := 0; i < 10; i++ {
+		if _, err := unlimited.acquire(timeoutCtx, "github.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHostLimiterTotal(t *testing.T) {
+	limiter := newHostLimiter(1, 2)
+	ctx := context.Background()
+
+	release1, err := limiter.acquire(ctx, "github.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release2, err := limiter.acquire(ctx, "gitlab.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The third repository waits even if its host is not busy
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(timeoutCtx, "bitbucket.org"); err != context.DeadlineExceeded {
+		t.Errorf("expected %v but got %v", context.DeadlineExceeded, err)
+	}
+	// The host is released when waiting was canceled
+	release2()
+	release3, err := limiter.acquire(ctx, "bitbucket.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release1()
+	release3()
+}
+
+func TestDetectRenamedRepos(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old/name/info/refs":
+			http.Redirect(w, r, "/new/name.git/info/refs?"+r.URL.RawQuery, http.StatusMovedPermanently)
+		case "/temp/name/info/refs":
+			http.Redirect(w, r, "/other/name/info/refs", http.StatusFound)
+		case "/away/name/info/refs":
+			http.Redirect(w, r, "https://example.org/new/name/info/refs", http.StatusMovedPermanently)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{}
+	cfg.Get.CloneURL = map[string]string{"example.com": server.URL}
+	cmd := &getCmd{}
+	for _, tt := range []struct {
+		reposPath string
+		want      string
+		wantErr   bool
+	}{
+		{reposPath: "example.com/old/name", want: "example.com/new/name"},
+		{reposPath: "example.com/same/name", want: ""},
+		{reposPath: "example.com/temp/name", want: ""},
+		{reposPath: "example.com/away/name", wantErr: true},
+	} {
+		got, err := cmd.detectRenamedRepos(context.Background(), pathutil.ReposPath(tt.reposPath), cfg)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error but got %q", tt.reposPath, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tt.reposPath, err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s: expected %q but got %q", tt.reposPath, tt.want, got)
+		}
+	}
+}
+
+func TestCloneOptions(t *testing.T) {
+	saved := &lockjson.CloneOptions{Depth: 1}
+	repos := &lockjson.Repos{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Clone: saved}
+	if opts := (&getCmd{}).cloneOptions(nil); opts != nil {
+		t.Errorf("expected no options but got %+v", opts)
+	}
+	if opts := (&getCmd{}).cloneOptions(repos); opts != saved {
+		t.Errorf("expected the saved options but got %+v", opts)
+	}
+	expected := &lockjson.CloneOptions{Filter: "blob:none", SingleBranch: true}
+	if opts := (&getCmd{filter: "blob:none", singleBranch: true}).cloneOptions(repos); *opts != *expected {
+		t.Errorf("expected %+v but got %+v", expected, opts)
+	}
+
+	for _, tt := range []struct {
+		opts     *lockjson.CloneOptions
+		expected []string
+	}{
+		{nil, nil},
+		{&lockjson.CloneOptions{Depth: 1}, []string{"--depth", "1", "--no-single-branch"}},
+		{&lockjson.CloneOptions{Depth: 1, SingleBranch: true}, []string{"--depth", "1", "--single-branch"}},
+		{&lockjson.CloneOptions{Filter: "blob:none"}, []string{"--filter=blob:none"}},
+	} {
+		if args := cloneArgs(tt.opts); strings.Join(args, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("%+v: expected %q but got %q", tt.opts, tt.expected, args)
+		}
+	}
+}
+
+func TestUseCustomSSH(t *testing.T) {
+	cmd := &getCmd{}
+	if !cmd.hasGitCmd() {
+		t.Skip("git command is not found")
+	}
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	for _, name := range []string{"HOME", "GIT_CONFIG_NOSYSTEM", "GIT_SSH_COMMAND", "GIT_SSH"} {
+		defer os.Setenv(name, os.Getenv(name))
+	}
+	os.Setenv("HOME", tempDir)
+	os.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	os.Unsetenv("GIT_SSH_COMMAND")
+	os.Unsetenv("GIT_SSH")
+
+	const sshURL = "git@github.com:tyru/caw.vim.git"
+	log := logger.NewBuffer()
+	if cmd.useCustomSSH(context.Background(), sshURL, tempDir, log) {
+		t.Error("expected false without custom SSH command")
+	}
+	os.Setenv("GIT_SSH_COMMAND", "ssh -p 2222")
+	if !cmd.useCustomSSH(context.Background(), sshURL, tempDir, log) {
+		t.Error("expected true with GIT_SSH_COMMAND")
+	}
+	if cmd.useCustomSSH(context.Background(), "https://github.com/tyru/caw.vim", tempDir, log) {
+		t.Error("expected false for HTTPS URL")
+	}
+	os.Unsetenv("GIT_SSH_COMMAND")
+	if err := ioutil.WriteFile(filepath.Join(tempDir, ".gitconfig"), []byte("[core]\n\tsshCommand = ssh -i key\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !cmd.useCustomSSH(context.Background(), sshURL, tempDir, log) {
+		t.Error("expected true with core.sshCommand")
+	}
+}
+
+func TestGitPullLimitedHistory(t *testing.T) {
+	cmd := &getCmd{}
+	if !cmd.hasGitCmd() {
+		t.Skip("git command is not found")
+	}
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	for _, name := range []string{"HOME", "GIT_CONFIG_NOSYSTEM"} {
+		defer os.Setenv(name, os.Getenv(name))
+	}
+	os.Setenv("HOME", tempDir)
+	os.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	ctx := context.Background()
+	upstream := filepath.Join(tempDir, "upstream")
+	commit := func(dir, msg string) {
+		if err := execGit(ctx, dir, "-c", "user.name=volt", "-c", "user.email=volt@example.com",
+			"commit", "--allow-empty", "-m", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clone := func(name string, args ...string) (string, *git.Repository) {
+		dir := filepath.Join(tempDir, name)
+		args = append(append([]string{"clone"}, args...), "file://"+upstream, dir)
+		if err := execGit(ctx, tempDir, args...); err != nil {
+			t.Fatal(err)
+		}
+		r, err := git.PlainOpen(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dir, r
+	}
+	if err := execGit(ctx, tempDir, "init", upstream); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		commit(upstream, fmt.Sprintf("commit %d", i))
+	}
+	shallowDir, shallow := clone("shallow", "--depth", "1")
+	divergedDir, diverged := clone("diverged", "--depth", "1")
+	fullDir, full := clone("full")
+	commit(divergedDir, "local commit")
+	for i := 3; i < 6; i++ {
+		commit(upstream, fmt.Sprintf("commit %d", i))
+	}
+
+	cfg := &config.Config{}
+	depth, fallback := 1, true
+	cfg.Get.FetchDepth = &depth
+	cfg.Get.FallbackGitCmd = &fallback
+	log := logger.NewBuffer()
+	if args := fetchLimitArgs(full, fullDir, cfg, log); args != nil {
+		t.Errorf("expected a full clone is not limited but got %v", args)
+	}
+
+	// A shallow clone without local commits is reset to the fetched commit
+	if err := cmd.gitPull(ctx, shallow, shallowDir, "origin", cfg, log); err != nil {
+		t.Errorf("expected the shallow clone is upgraded but got %s", err)
+	}
+	// A shallow clone with local commits is not reset
+	before, err := diverged.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.gitPull(ctx, diverged, divergedDir, "origin", cfg, log); err == nil {
+		t.Error("expected an error for the local commit")
+	}
+	if after, err := diverged.Head(); err != nil || after.Hash() != before.Hash() {
+		t.Errorf("expected HEAD is not changed: %s -> %v (%v)", before.Hash(), after, err)
+	}
+	// A full clone is fast-forwarded and stays a full clone
+	if err := cmd.gitPull(ctx, full, fullDir, "origin", cfg, log); err != nil {
+		t.Errorf("expected the full clone is upgraded but got %s", err)
+	}
+	if isShallowRepos(full) {
+		t.Error("expected the full clone is not made shallow")
+	}
+
+	// A new clone is made shallow by get.fetch_depth
+	clonedDir := filepath.Join(tempDir, "cloned")
+	if err := cmd.gitClone(ctx, "file://"+upstream, clonedDir, nil, cfg, log); err != nil {
+		t.Fatal(err)
+	}
+	if cloned, err := git.PlainOpen(clonedDir); err != nil || !isShallowRepos(cloned) {
+		t.Errorf("expected a shallow clone (%v)", err)
+	}
+}
+
+func TestCloneLimitArgs(t *testing.T) {
+	depth, noDepth := 10, 0
+	for _, tt := range []struct {
+		depth    *int
+		since    string
+		opts     *lockjson.CloneOptions
+		expected []string
+	}{
+		{&noDepth, "", nil, nil},
+		{&depth, "", nil, []string{"--depth", "10", "--no-single-branch"}},
+		{&noDepth, "1 month ago", nil, []string{"--shallow-since=1 month ago", "--no-single-branch"}},
+		{&depth, "", &lockjson.CloneOptions{SingleBranch: true}, []string{"--depth", "10"}},
+		// -depth option takes precedence
+		{&depth, "", &lockjson.CloneOptions{Depth: 1}, nil},
+	} {
+		cfg := &config.Config{}
+		cfg.Get.FetchDepth = tt.depth
+		cfg.Get.FetchShallowSince = tt.since
+		if got := cloneLimitArgs(tt.opts, cfg); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("expected %q but got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestGetFailedError(t *testing.T) {
+	for _, tt := range []struct {
+		err      getFailedError
+		expected string
+	}{
+		{getFailedError{failed: 2, total: 5}, "failed to install 2 of 5 plugins"},
+		{getFailedError{failed: 3, total: 3}, "failed to install all 3 plugins"},
+		{getFailedError{failed: 4, aborted: 3, total: 5}, "failed to install 4 of 5 plugins (3 aborted by -fail-fast)"},
+	} {
+		if msg := tt.err.Error(); msg != tt.expected {
+			t.Errorf("expected %q but got %q", tt.expected, msg)
+		}
+	}
+}
+
+func TestAddUpgradeGroups(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Get.UpgradeGroups = map[string][]string{
+		"lsp": {"prabirshrestha/vim-lsp", "mattn/vim-lsp-settings", "prabirshrestha/asyncomplete-lsp.vim"},
+	}
+	lockJSON := &lockjson.LockJSON{
+		Repos: lockjson.ReposList{
+			{Type: lockjson.ReposGitType, Path: "github.com/prabirshrestha/vim-lsp"},
+			{Type: lockjson.ReposGitType, Path: "github.com/mattn/vim-lsp-settings"},
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim"},
+		},
+	}
+	got := (&getCmd{}).addUpgradeGroups(
+		[]pathutil.ReposPath{"github.com/tyru/caw.vim", "github.com/mattn/vim-lsp-settings"},
+		lockJSON, cfg)
+	// asyncomplete-lsp.vim is not added because it is not installed
+	expected := []pathutil.ReposPath{
+		"github.com/tyru/caw.vim",
+		"github.com/mattn/vim-lsp-settings",
+		"github.com/prabirshrestha/vim-lsp",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+}
+
+func TestMergeProfiles(t *testing.T) {
+	dst := &lockjson.LockJSON{
+		Repos: lockjson.ReposList{
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim"},
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/open-browser.vim"},
+		},
+		Profiles: lockjson.ProfileList{
+			{Name: "default", ReposPath: []pathutil.ReposPath{"github.com/tyru/caw.vim"}},
+		},
+	}
+	src := &lockjson.LockJSON{
+		Profiles: lockjson.ProfileList{
+			{Name: "default", ReposPath: []pathutil.ReposPath{"github.com/tyru/caw.vim"}},
+			{
+				Name:      "work",
+				ReposPath: []pathutil.ReposPath{"github.com/tyru/open-browser.vim", "github.com/tyru/failed.vim"},
+				NoBuild:   []pathutil.ReposPath{"github.com/tyru/open-browser.vim"},
+			},
+		},
+	}
+
+	names := mergeProfiles(dst, src)
+	if expected := []string{"work"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v but got %v", expected, names)
+	}
+	work, err := dst.Profiles.FindByName("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// github.com/tyru/failed.vim is not in dst.Repos
+	expected := []pathutil.ReposPath{"github.com/tyru/open-browser.vim"}
+	if !reflect.DeepEqual([]pathutil.ReposPath(work.ReposPath), expected) {
+		t.Errorf("repos_path: expected %v but got %v", expected, work.ReposPath)
+	}
+	if !reflect.DeepEqual([]pathutil.ReposPath(work.NoBuild), expected) {
+		t.Errorf("no_build: expected %v but got %v", expected, work.NoBuild)
+	}
+
+	// Merging again changes nothing
+	if names := mergeProfiles(dst, src); len(names) != 0 {
+		t.Errorf("expected no changes but got %v", names)
+	}
+}
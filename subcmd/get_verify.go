@@ -0,0 +1,175 @@
+package subcmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4"
+
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/builder"
+)
+
+// fmtRolledBack is the status of the repository whose upgrade was rolled
+// back because Vim reported errors at startup.
+const fmtRolledBack = "! %s > rolled back to %s (the upgrade to %s broke Vim startup)"
+
+// startupCheckTimeout is the time limit of launching Vim to check startup
+// errors.
+var startupCheckTimeout = 30 * time.Second
+
+// checkStartup launches Vim headlessly to load plugins and plugconfs like
+// startup (vimrc is not loaded), and returns the error messages if Vim
+// reported errors. err is returned if Vim could not be executed.
+func checkStartup(ctx context.Context, vimExePath string) (msg string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, startupCheckTimeout)
+	defer cancel()
+	// "-V1" writes error messages to stderr
+	args := []string{"-u", "NORC", "-i", "NONE", "-N", "-es", "-V1", "-c", "qall!"}
+	if pathutil.IsNeovim(vimExePath) {
+		args = append([]string{"--headless"}, args...)
+	}
+	logger.Debugf("Executing '%s %s' ...", vimExePath, strings.Join(args, " "))
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, vimExePath, args...)
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err == nil {
+		return "", nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Sprintf("%s did not exit in %s", vimExePath, startupCheckTimeout), nil
+	}
+	if _, ok := err.(*exec.ExitError); !ok {
+		return "", err
+	}
+	msg = startupErrorMessage(stderr.String())
+	if msg == "" {
+		msg = err.Error()
+	}
+	return msg, nil
+}
+
+// startupErrorMessage removes the verbose messages which are not errors
+// from the output of Vim.
+func startupErrorMessage(output string) string {
+	lines := strings.Split(strings.Replace(output, "\r", "", -1), "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if strings.HasPrefix(line, "not found in '") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return strings.TrimSpace(strings.Join(result, "\n"))
+}
+
+// offendingRepos returns the repositories in candidates whose files appear in
+// msg (the error messages of Vim).
+func offendingRepos(msg string, candidates []pathutil.ReposPath) []pathutil.ReposPath {
+	msg = filepath.ToSlash(msg)
+	var result []pathutil.ReposPath
+	for _, reposPath := range candidates {
+		for _, dir := range []string{reposPath.EncodeToPlugDirName(), reposPath.FullPath()} {
+			if strings.Contains(msg, filepath.ToSlash(dir)+"/") {
+				result = append(result, reposPath)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// verifyUpgrades checks Vim startup after upgrading, and rolls the offending
+// repositories back to previous (the entries of lock.json before upgrading)
+// if Vim reported errors. If the offending repositories are not found in the
+// error messages, all upgraded repositories are rolled back.
+// The statuses of rolled back repositories are returned.
+func (cmd *getCmd) verifyUpgrades(ctx context.Context, vimExePath string, previous map[pathutil.ReposPath]lockjson.Repos, lockJSON *lockjson.LockJSON) (map[pathutil.ReposPath]string, error) {
+	msg, err := checkStartup(ctx, vimExePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not check Vim startup")
+	}
+	if msg == "" {
+		logger.Info("Vim started without errors after upgrading")
+		return nil, nil
+	}
+
+	candidates := make([]pathutil.ReposPath, 0, len(previous))
+	for reposPath := range previous {
+		candidates = append(candidates, reposPath)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+	targets := offendingRepos(msg, candidates)
+	if len(targets) == 0 {
+		logger.Warnf("Could not find which upgrade broke Vim startup, rolling back all upgrades:\n%s", msg)
+		targets = candidates
+	} else {
+		logger.Warnf("Vim startup has errors, rolling back the offending upgrades:\n%s", msg)
+	}
+
+	statuses := make(map[pathutil.ReposPath]string, len(targets))
+	for _, reposPath := range targets {
+		prev := previous[reposPath]
+		repos := lockJSON.Repos.FindByPath(reposPath)
+		if repos == nil {
+			continue
+		}
+		log := logger.NewReposBuffer(reposPath.String())
+		err := cmd.rollbackRepos(ctx, &prev, log)
+		log.Flush()
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not roll back %s to %s", reposPath, prev.Version)
+		}
+		statuses[reposPath] = fmt.Sprintf(fmtRolledBack, reposPath, prev.Version, repos.Version)
+		*repos = prev
+	}
+
+	if err := lockJSON.Write(); err != nil {
+		return nil, errors.Wrap(err, "could not write to lock.json")
+	}
+	if err := builder.Build(ctx, false); err != nil {
+		return nil, errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+	}
+
+	if msg, err := checkStartup(ctx, vimExePath); err != nil {
+		return nil, errors.Wrap(err, "could not check Vim startup")
+	} else if msg != "" {
+		return statuses, errors.New("vim startup still has errors after rolling back:\n" + msg)
+	}
+	return statuses, nil
+}
+
+// rollbackRepos restores the repository to the version of prev.
+func (cmd *getCmd) rollbackRepos(ctx context.Context, prev *lockjson.Repos, log *logger.Buffer) error {
+	switch prev.Type {
+	case lockjson.ReposGitType:
+		log.Debugf("Resetting %s to %s ...", prev.Path, prev.Version)
+		r, err := git.PlainOpen(prev.Path.FullPath())
+		if err != nil {
+			return err
+		}
+		if err := gitutil.ResetHEAD(r, prev.Version); err != nil {
+			return err
+		}
+		return cmd.updateSubmodules(ctx, prev.Path, log)
+	case lockjson.ReposReleaseType:
+		log.Debugf("Downloading release %s of %s ...", prev.Version, prev.Path)
+		return cmd.downloadRelease(ctx, prev.Path, prev.Version)
+	case lockjson.ReposArchiveType:
+		log.Debugf("Downloading %s ...", prev.URL)
+		_, err := cmd.downloadArchive(ctx, prev.Path, prev.URL, prev.Version, "")
+		return err
+	}
+	return errors.Errorf("%s repository cannot be rolled back", prev.Type)
+}
@@ -0,0 +1,42 @@
+package subcmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestStartupErrorMessage(t *testing.T) {
+	output := "Error detected while processing /home/user/.vim/plugin/a.vim:\r\n" +
+		"line    1:\r\n" +
+		"E117: Unknown function: Foo\r\n" +
+		"not found in 'runtimepath': \"plugin/**/*.vim\"\r\n"
+	expected := "Error detected while processing /home/user/.vim/plugin/a.vim:\n" +
+		"line    1:\n" +
+		"E117: Unknown function: Foo"
+	if msg := startupErrorMessage(output); msg != expected {
+		t.Errorf("expected %q but got %q", expected, msg)
+	}
+}
+
+func TestOffendingRepos(t *testing.T) {
+	caw := pathutil.ReposPath("github.com/tyru/caw.vim")
+	cawExtra := pathutil.ReposPath("github.com/tyru/caw.vim-extra")
+	hello := pathutil.ReposPath("localhost/local/hello")
+	candidates := []pathutil.ReposPath{caw, cawExtra, hello}
+
+	msg := "Error detected while processing " + cawExtra.EncodeToPlugDirName() + "/plugin/caw.vim:\n" +
+		"line    1:\n" +
+		"E117: Unknown function: Foo\n" +
+		"Error detected while processing " + hello.FullPath() + "/autoload/hello.vim:\n" +
+		"E492: Not an editor command: foo"
+	expected := []pathutil.ReposPath{cawExtra, hello}
+	if got := offendingRepos(msg, candidates); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+
+	if got := offendingRepos("E117: Unknown function: Foo", candidates); len(got) != 0 {
+		t.Errorf("expected no repositories but got %v", got)
+	}
+}
@@ -37,18 +37,79 @@ func (cmd *helpCmd) FlagSet() *flag.FlagSet {
 				" '----------------'  '----------------'  '----------------'  '----------------'\n" +
 				`
 Usage
-  volt COMMAND ARGS
+  volt [-no-build] [-dry-run] [-output {text|json}] [-log-level {level}] [-quiet] [-no-color] [-log-format {text|json}] COMMAND ARGS
+
+Global option
+  -no-build
+    Do not build ~/.vim/pack/volt/ after get, rm, enable, disable, and
+    profile. Run "volt build" after the changes:
+      $ volt -no-build get tyru/caw.vim
+      $ volt -no-build disable tyru/open-browser.vim
+      $ volt build
+
+  -dry-run
+    Show which repositories would be cloned, upgraded, or removed, and which
+    files would be written (lock.json, ~/.vim/pack/volt/) by get, rm, build,
+    and profile without changing anything:
+      $ volt -dry-run get -l -u
+      $ volt -dry-run rm -r tyru/caw.vim
+
+  -output {text|json}
+    If "json" was given, write the result of the command to stdout as one
+    JSON object, and write the other messages to stderr. This is useful for
+    editor integrations and CI:
+      $ volt -output json get -l -u
+      {"command":"get","ok":true,"result":[{"status":"updated","target":...}]}
+    "result" is the status of each repository for get, the removed
+    repositories for rm, the installed repositories for build, and null for
+    the other commands. "error" ({"code":...,"message":...}) is added if the
+    command failed.
+
+  -log-level {error|warn|info|debug}
+    Show the messages of {level} or more severe levels ("info" by default).
+    "debug" shows verbose messages with their source locations (same as
+    $VOLT_DEBUG=1):
+      $ volt -log-level debug get tyru/caw.vim
+
+  -quiet
+    Show only warnings and errors (same as "-log-level warn").
+
+  -no-color
+    Do not colorize the messages (colors are disabled also when stdout is not
+    a terminal).
+
+  -log-format {text|json}
+    If "json" was given, write each message as one JSON object per line
+    ({"level":...,"time":...,"repos":...,"message":...}) instead of
+    "[INFO] ..." lines, so that CI and wrapper tools can parse them.
+    "repos" is added to the messages about a repository. $VOLT_LOG_FORMAT is
+    used if this option was not given:
+      $ VOLT_LOG_FORMAT=json volt get -l -u
+
+External command
+  If COMMAND is not a volt command nor an alias, "volt-COMMAND" in $PATH is
+  executed with ARGS. These environment variables are passed to it:
+    VOLT_PROFILE     current profile name
+    VOLT_CONFIG      fullpath of config.toml
+    VOLT_LOCK_JSON   fullpath of lock.json
+    VOLT_REPOS_DIR   fullpath of the directory of repositories
+    VOLT_EXECUTABLE  fullpath of volt command
+    VOLTPATH         $VOLTPATH (unless XDG layout directories are used)
 
 Command
   get [-l] [-u] [{repository} ...]
     Install or upgrade given {repository} list, or add local {repository} list as plugins
 
-  rm [-r] [-p] {repository} [{repository2} ...]
+  search [-n {N}] [-vimawesome] [-install] {keyword} [{keyword2} ...]
+    Search vim plugins on GitHub (and Vim Awesome), and install chosen ones
+
+  rm [-r] [-p] [-keep-repos] {repository} [{repository2} ...]
     Remove vim plugin from ~/.vim/pack/volt/opt/ directory
 
-  list [-f {text/template string}]
+  list [-f {text/template string} | -profile {name} | -all-profiles]
     Vim plugin information extractor.
     Unless -f flag was given, this command shows vim plugins of **current profile** (not all installed plugins) by default.
+    -profile and -all-profiles show which plugins are enabled or missing, and which are not in any profile.
 
   enable {repository} [{repository2} ...]
     This is shortcut of:
@@ -61,6 +122,9 @@ Command
   edit [-e|--editor {editor}] {repository} [{repository2} ...]
     Open the plugconf file(s) of one or more {repository} for editing.
 
+  lint [-strict] [{repository} ...]
+    Check plugconf files more strictly than "volt build" (e.g. in CI)
+
   profile set {name}
     Set profile name
 
@@ -85,15 +149,79 @@ Command
   profile rm {name} {repository} [{repository2} ...]
     Remove one or more repositories to profile
 
-  build [-full]
-    Build ~/.vim/pack/volt/ directory
+  profile no-build {name} {repository} [{repository2} ...]
+    Do not install one or more repositories of profile (keep plugconfs)
+
+  profile build {name} {repository} [{repository2} ...]
+    Install one or more repositories marked by "profile no-build" again
+
+  snapshot create [-plugconf] {name}
+    Save a copy of lock.json (and plugconf) as snapshot {name}
+
+  snapshot restore {name}
+    Restore lock.json and repositories of snapshot {name}
+
+  snapshot list
+    List all snapshots
+
+  build [-full] [-target {vim|neovim}] [-watch]
+    Build ~/.vim/pack/volt/ directory (or Neovim's one if build.target is
+    "neovim")
+
+  export -format {format}
+    Print the plugins of current profile as {format} (e.g. Nix expression)
+
+  import [-overwrite] {file}
+    Install the plugins, plugconfs, and rc files of "volt export -format json"
+
+  freeze
+    Print the plugins of current profile with their exact revisions
+    (install them by "volt get -from-freeze {file}")
+
+  size
+    Show disk usage of each repository, and repositories which can be cleaned up
+
+  verify-lock [-repair]
+    Check lock.json and $VOLTPATH/repos are consistent, and fix them if -repair was given
+
+  doctor
+    Check the environment (vim executable, permissions, lock.json, plugconf
+    files, ...), and show how to fix the problems
+
+  unlock [-force]
+    Release the lock of a volt process which crashed or hangs
+
+  sync [-prune]
+    Install the repositories of lock.json which do not exist, and remove the
+    others if -prune was given
+
+  status
+    Show the locked and checked out versions, local changes, untracked
+    repositories, and whether ~/.vim/pack/volt is stale
+
+  outdated
+    Show plugins of current profile whose upstream branches have newer commits
+    (nothing is fetched or upgraded)
+
+  notify -write | -read
+    Check updates of plugins and volt, or show the result in one line
+    (for shell prompts and statuslines)
+
+  config validate
+    Check config.toml and show the merged configuration
+
+  alias list | set {name} {command} [{argument} ...] | rm {name}
+    Show, set, or remove aliases of volt commands in config.toml
 
   migrate {migration operation}
     Perform miscellaneous migration operations.
     See 'volt migrate -help' for all available operations
 
-  self-upgrade [-check]
-    Upgrade to the latest volt command, or if -check was given, it only checks the newer version is available
+  migrate -list
+    Show the versions of lock.json and what each migration changes
+
+  self-upgrade [-check] [-insecure] [-channel {stable|pre}]
+    Upgrade to the latest volt command (verified with SHA256SUMS of the release), or if -check was given, it only checks the newer version is available
 
   version
     Show volt command version` + "\n\n")
@@ -102,7 +230,8 @@ Command
 	return fs
 }
 
-func (cmd *helpCmd) Run(args []string) *Error {
+func (cmd *helpCmd) Run(cmdctx *CmdContext) *Error {
+	args := cmdctx.Args
 	if len(args) == 0 {
 		cmd.FlagSet().Usage()
 		return nil
@@ -115,7 +244,12 @@ func (cmd *helpCmd) Run(args []string) *Error {
 	if !exists {
 		return &Error{Code: 1, Msg: fmt.Sprintf("Unknown command '%s'", args[0])}
 	}
-	args = append([]string{"-help"}, args[1:]...)
-	fs.Run(args)
+	fs.Run(&CmdContext{
+		Ctx:      cmdctx.Ctx,
+		Cmd:      args[0],
+		Args:     append([]string{"-help"}, args[1:]...),
+		LockJSON: cmdctx.LockJSON,
+		Config:   cmdctx.Config,
+	})
 	return nil
 }
@@ -0,0 +1,288 @@
+package subcmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/httputil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func init() {
+	cmdMap["import"] = &importCmd{}
+}
+
+type importCmd struct {
+	helped    bool
+	overwrite bool
+}
+
+func (cmd *importCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *importCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt import [-help] [-overwrite] {file}
+
+Quick example
+  $ volt export -format json > plugins.json
+  $ volt import plugins.json  # on another machine
+  $ volt import https://example.com/dotfiles/volt/plugins.json
+
+Description
+  Import the plugins written by "volt export -format json" from {file} (a
+  file path, an URL of http or https, or "-" for stdin):
+
+  * The repositories are installed at the exported versions, and added to
+    lock.json like "volt get -from-lock".
+  * The exported profiles are merged into the profiles of the same names
+    (they are created if they do not exist). Current profile is not changed.
+  * The plugconfs and vimrc and gvimrc of the profiles are written.
+
+  If a repository is already in lock.json with another version, or a file
+  already exists with other content, volt asks whether to keep the local one
+  or to use the imported one. The local ones are kept without asking if stdin
+  is not a terminal, and the imported ones are used if -overwrite was given.` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.BoolVar(&cmd.overwrite, "overwrite", false, "use the imported versions and files instead of the local ones")
+	return fs
+}
+
+func (cmd *importCmd) Run(cmdctx *CmdContext) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(cmdctx.Args)
+	if cmd.helped {
+		return nil
+	}
+	if len(fs.Args()) != 1 {
+		fs.Usage()
+		return &Error{Code: 10, Msg: "Failed to parse args: import command receives exactly one file"}
+	}
+	src := fs.Args()[0]
+
+	content, err := cmd.readSource(cmdctx.Ctx, src)
+	if err != nil {
+		return &Error{Code: 11, Msg: "Could not read " + src + ": " + err.Error()}
+	}
+	manifest, srcLockJSON, err := readJSONManifest(content)
+	if err != nil {
+		return &Error{Code: 11, Msg: "Could not read " + src + ": " + err.Error()}
+	}
+
+	resolve := cmd.conflictResolver()
+	statusList, err := cmd.mergeRepos(srcLockJSON, cmdctx.LockJSON, resolve)
+	if err != nil {
+		return &Error{Code: 12, Msg: "Failed to import: " + err.Error()}
+	}
+	files, err := cmd.writeFiles(manifest, resolve)
+	if err != nil {
+		return &Error{Code: 12, Msg: "Failed to import: " + err.Error()}
+	}
+	for _, status := range append(statusList, files...) {
+		fmt.Println(status)
+	}
+
+	if len(srcLockJSON.Repos) == 0 {
+		if len(mergeProfiles(cmdctx.LockJSON, srcLockJSON)) == 0 {
+			return nil
+		}
+		if err := cmdctx.LockJSON.Write(); err != nil {
+			return &Error{Code: 13, Msg: "Could not write to lock.json: " + err.Error()}
+		}
+		return nil
+	}
+	return cmd.installRepos(cmdctx.Ctx, srcLockJSON)
+}
+
+// readSource reads src (a file path, an URL, or "-" for stdin).
+func (*importCmd) readSource(ctx context.Context, src string) ([]byte, error) {
+	switch {
+	case src == "-":
+		return ioutil.ReadAll(os.Stdin)
+	case strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://"):
+		return httputil.GetContent(ctx, src)
+	default:
+		return ioutil.ReadFile(src)
+	}
+}
+
+// conflictFunc returns true if the imported one is used instead of the
+// local one of what.
+type conflictFunc func(what string) (bool, error)
+
+func (cmd *importCmd) conflictResolver() conflictFunc {
+	if cmd.overwrite {
+		return func(string) (bool, error) { return true, nil }
+	}
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return func(string) (bool, error) { return false, nil }
+	}
+	in := bufio.NewReader(os.Stdin)
+	return func(what string) (bool, error) {
+		return askConflict(what, in, os.Stdout)
+	}
+}
+
+// askConflict asks whether to use the imported one of what, and returns true
+// if it is used.
+func askConflict(what string, in *bufio.Reader, out io.Writer) (bool, error) {
+	for {
+		fmt.Fprintf(out, "%s. [k]eep local or [i]mport? [K/i]: ", what)
+		line, err := in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return false, errors.Wrap(err, "could not read the answer")
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "i":
+			return true, nil
+		case "k", "":
+			return false, nil
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+	}
+}
+
+// mergeRepos replaces the repositories of srcLockJSON which are in lockJSON
+// with other versions by the locked ones, unless resolve chooses the imported
+// ones.
+func (*importCmd) mergeRepos(srcLockJSON, lockJSON *lockjson.LockJSON, resolve conflictFunc) ([]string, error) {
+	var statusList []string
+	for i := range srcLockJSON.Repos {
+		repos := &srcLockJSON.Repos[i]
+		local := lockJSON.Repos.FindByPath(repos.Path)
+		if local == nil || (local.Type == repos.Type && local.Version == repos.Version && local.Pin == repos.Pin) {
+			continue
+		}
+		imported, err := resolve(fmt.Sprintf("%s is locked at %s but imported one is %s",
+			repos.Path, importVersionString(local), importVersionString(repos)))
+		if err != nil {
+			return nil, err
+		}
+		if !imported {
+			*repos = *local
+			statusList = append(statusList, fmt.Sprintf("# %s > kept locked version %s", repos.Path, importVersionString(local)))
+		}
+	}
+	return statusList, nil
+}
+
+func importVersionString(repos *lockjson.Repos) string {
+	version := repos.Version
+	if repos.Type != lockjson.ReposGitType {
+		version = fmt.Sprintf("%s (%s)", version, repos.Type)
+	}
+	if repos.Pin != "" {
+		version += " pinned to " + repos.Pin
+	}
+	return version
+}
+
+// writeFiles writes plugconfs and rc files of manifest.
+func (*importCmd) writeFiles(manifest *exportManifest, resolve conflictFunc) ([]string, error) {
+	var statusList []string
+	write := func(path, name, content string) error {
+		local, err := readFileIfExists(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case local == nil:
+			statusList = append(statusList, fmt.Sprintf("+ %s > imported", name))
+		case bytes.Equal(local, []byte(content)):
+			return nil
+		default:
+			imported, err := resolve(name + " is different from imported one")
+			if err != nil {
+				return err
+			}
+			if !imported {
+				statusList = append(statusList, fmt.Sprintf("# %s > kept local file", name))
+				return nil
+			}
+			statusList = append(statusList, fmt.Sprintf("* %s > overwritten", name))
+		}
+		logger.Debug("Writing " + path + " ...")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, []byte(content), 0644)
+	}
+
+	for _, reposPath := range sortedReposPaths(manifest.Plugconf) {
+		if err := write(reposPath.Plugconf(), "plugconf of "+reposPath.String(), manifest.Plugconf[reposPath]); err != nil {
+			return nil, err
+		}
+	}
+	names := make([]string, 0, len(manifest.RC))
+	for name := range manifest.RC {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, file := range manifestRCFiles {
+			content, exists := manifest.RC[name][file]
+			if !exists {
+				continue
+			}
+			if err := write(filepath.Join(pathutil.RCDir(name), file), file+" of profile "+name, content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return statusList, nil
+}
+
+func sortedReposPaths(m map[pathutil.ReposPath]string) pathutil.ReposPathList {
+	list := make(pathutil.ReposPathList, 0, len(m))
+	for reposPath := range m {
+		list = append(list, reposPath)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// installRepos installs the repositories and merges the profiles of
+// srcLockJSON by "volt get -from-lock".
+func (*importCmd) installRepos(ctx context.Context, srcLockJSON *lockjson.LockJSON) *Error {
+	content, err := json.Marshal(srcLockJSON)
+	if err != nil {
+		return &Error{Code: 13, Msg: "Failed to import: " + err.Error()}
+	}
+	f, err := ioutil.TempFile("", "volt-import-")
+	if err != nil {
+		return &Error{Code: 13, Msg: "Failed to import: " + err.Error()}
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(content)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return &Error{Code: 13, Msg: "Failed to import: " + err.Error()}
+	}
+	return Exec(ctx, "get", []string{"-from-lock", f.Name()})
+}
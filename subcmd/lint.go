@@ -0,0 +1,160 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+)
+
+func init() {
+	cmdMap["lint"] = &lintCmd{}
+}
+
+type lintCmd struct {
+	helped bool
+	strict bool
+}
+
+func (cmd *lintCmd) ProhibitRootExecution(args []string) bool { return false }
+
+func (cmd *lintCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt lint [-help] [-strict] [{repository} ...]
+
+Quick example
+  $ volt lint              # checks all plugconf files
+  $ volt lint tyru/caw.vim # checks the plugconf file of tyru/caw.vim
+  $ volt lint -strict      # exits with an error also for warnings
+
+Description
+  Parse plugconf files under $VOLTPATH/plugconf (or the plugconf files of
+  {repository} list) like "volt build" does, and check them more strictly:
+
+  Errors:
+    * Vim script syntax errors
+    * duplicate plugconf functions (e.g. s:on_load_pre() and s:config())
+    * plugconf functions which have arguments
+    * invalid return value of s:loaded_on() and s:depends()
+    * a plugin which depends on itself, or dependency cycles
+
+  Warnings:
+    * statements outside functions, which are ignored
+    * script-local functions which are neither plugconf functions nor
+      called (e.g. typo of s:on_load_pre())
+    * functions defined twice, and duplicate s:depends() entries
+    * deprecated s:config()
+    * s:depends() entries, or plugconf files which are not in lock.json
+
+  It exits with an error if there are errors (or warnings if -strict was
+  given), so that it can be used to check dotfiles in CI.` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.BoolVar(&cmd.strict, "strict", false, "exit with an error also for warnings")
+	return fs
+}
+
+func (cmd *lintCmd) Run(cmdctx *CmdContext) *Error {
+	reposPathList, err := cmd.parseArgs(cmdctx.Args, cmdctx.LockJSON)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
+	}
+	if len(reposPathList) == 0 {
+		reposPathList, err = cmd.allPlugconfs()
+		if err != nil {
+			return &Error{Code: 11, Msg: "Failed to find plugconf files: " + err.Error()}
+		}
+	}
+
+	parseErr := plugconf.Lint(reposPathList, cmdctx.LockJSON.Repos)
+	errCount, warnCount := 0, 0
+	for i := range parseErr {
+		if e := &parseErr[i]; e.HasErrs() {
+			logger.Error(e.Errors().Error())
+			errCount += len(e.Errors().Errors)
+		}
+	}
+	for i := range parseErr {
+		if e := &parseErr[i]; e.HasWarns() {
+			logger.Warn(e.Warns().Error())
+			warnCount += len(e.Warns().Errors)
+		}
+	}
+	logger.Infof("Checked %d plugconf files: %d errors, %d warnings", len(reposPathList), errCount, warnCount)
+
+	if errCount > 0 {
+		return &Error{Code: 12, Msg: fmt.Sprintf("Found %d errors in plugconf files", errCount)}
+	}
+	if cmd.strict && warnCount > 0 {
+		return &Error{Code: 13, Msg: fmt.Sprintf("Found %d warnings in plugconf files", warnCount)}
+	}
+	return nil
+}
+
+func (cmd *lintCmd) parseArgs(args []string, lockJSON *lockjson.LockJSON) (pathutil.ReposPathList, error) {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, ErrShowedHelp
+	}
+	if len(fs.Args()) == 0 {
+		return nil, nil
+	}
+
+	reposPathList, err := resolveReposPathList(fs.Args(), lockJSON)
+	if err != nil {
+		return nil, err
+	}
+	for _, reposPath := range reposPathList {
+		if !pathutil.Exists(reposPath.Plugconf()) {
+			return nil, errors.Errorf("no plugconf file of '%s'", reposPath)
+		}
+	}
+	return reposPathList, nil
+}
+
+// allPlugconfs returns the repositories of all plugconf files under
+// $VOLTPATH/plugconf.
+func (*lintCmd) allPlugconfs() (pathutil.ReposPathList, error) {
+	var result pathutil.ReposPathList
+	dir := pathutil.PlugconfDir()
+	if !pathutil.Exists(dir) {
+		return result, nil
+	}
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(path) != ".vim" {
+			return nil
+		}
+		reposPath, err := pathutil.ReposPathOfPlugconf(path)
+		if err != nil {
+			return err
+		}
+		result = append(result, reposPath)
+		return nil
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result, err
+}
@@ -1,14 +1,18 @@
 package subcmd
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"github.com/pkg/errors"
+	"io"
 	"os"
 	"text/template"
 
 	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/metadata"
+	"github.com/vim-volt/volt/pathutil"
 )
 
 func init() {
@@ -18,6 +22,15 @@ func init() {
 type listCmd struct {
 	helped bool
 	format string
+	json   bool
+	// profile is the profile whose repositories are shown with their
+	// status (-profile)
+	profile string
+	// allProfiles shows the status of the repositories of every profile
+	// (-all-profiles)
+	allProfiles bool
+	// ctx is used to fetch metadata of repositories
+	ctx context.Context
 }
 
 func (cmd *listCmd) ProhibitRootExecution(args []string) bool { return false }
@@ -29,6 +42,9 @@ func (cmd *listCmd) FlagSet() *flag.FlagSet {
 		fmt.Print(`
 Usage
   volt list [-help] [-f {text/template string}]
+  volt list [-help] -json
+  volt list [-help] -profile {name}
+  volt list [-help] -all-profiles
 
 Quick example
   $ volt list # will list installed plugins
@@ -45,6 +61,19 @@ Quick example
 
   $ volt list -f '{{ range currentProfile.ReposPath }}{{ println . }}{{ end }}'
 
+  Show stars and description of repositories with "[archived]" mark (see "metadata"):
+
+  $ volt list -f '{{ range currentProfile.ReposPath }}{{ $m := metadata . }}{{ . }} ({{ $m.Stars }} stars){{ if $m.Archived }} [archived]{{ end }}: {{ println $m.Description }}{{ end }}'
+
+  Show all repositories and profiles in JSON (see "JSON output"):
+
+  $ volt list -json
+
+  Show which repositories of profile "server" are enabled or missing, and
+  which installed repositories are not in any profile (see "Profile status"):
+
+  $ volt list -profile server
+
 Template functions
 
   json value [prefix [indent]] (string)
@@ -54,9 +83,16 @@ Template functions
   currentProfile (Profile (see "Structures"))
     Returns current profile
 
-  currentProfile (Profile (see "Structures"))
+  profile {name} (Profile (see "Structures"))
     Returns given name's profile
 
+  metadata {repository} (Metadata (see "Structures"))
+    Returns the information of {repository} on GitHub.
+    It is fetched from GitHub API, and cached for 24 hours in
+    $VOLTPATH/metadata/ . If $GITHUB_TOKEN is set, it is used for
+    the requests to raise the rate limit.
+    All fields are empty if {repository} is not a GitHub repository.
+
   version (string)
     Returns volt version string. format is "v{major}.{minor}.{patch}" (e.g. "v0.3.0")
 
@@ -82,14 +118,20 @@ Structures
     // ("volt list" shows current profile's repositories, which is not the same as this)
     "repos": [
       {
-        // "git" (git repository) or "static" (static repository)
+        // "git" (git repository), "static" (static repository),
+        // or "release" (GitHub release archive)
         "type": <string>,
 
         // Repository path like "github.com/vim-volt/vim-volt"
         "path": <string>,
 
-        // Git commit hash. if "type" is "static" this property does not exist
+        // Git commit hash, or tag name if "type" is "release".
+        // if "type" is "static" this property does not exist
         "version": <string>,
+
+        // Tag pattern of releases (e.g. "latest", "v1.*").
+        // Only exists if "type" is "release"
+        "release": <string>,
       },
     ],
 
@@ -103,6 +145,56 @@ Structures
     ]
   }
 
+  Metadata has the following fields:
+  {
+    Description: <string>,
+    Stars: <int>,
+    Topics: [ <string> ],
+    // true if the repository has been archived (no longer maintained)
+    Archived: <bool>,
+    // URL of the repository page (e.g. "https://github.com/tyru/caw.vim")
+    URL: <string>,
+  }
+
+JSON output
+  -json outputs the following JSON for scripts and editors. Unlike -f, it
+  does not depend on the internal structure of lock.json:
+  {
+    "current_profile_name": <string>,
+    // All installed repositories
+    "repos": [
+      {
+        "type": <string>,        // "git", "static", or "release"
+        "path": <string>,        // e.g. "github.com/tyru/caw.vim"
+        "version": <string>,     // commit hash, or tag name of release
+        "release": <string>,     // tag pattern (only release repository)
+        "pin": <string>,         // pinned version (e.g. "#v1.0") if any
+        "enabled": <bool>,       // true if current profile has it
+        "built": <bool>,         // true if "volt build" installs it
+        "installed": <bool>,     // true if $VOLTPATH/repos/{path} exists
+        "profiles": [ <string> ] // names of profiles which have it
+      },
+    ],
+    "profiles": [
+      {
+        "name": <string>,
+        "repos_path": [ <string> ],
+        "no_build": [ <string> ] // only exists if any
+      },
+    ]
+  }
+
+Profile status
+  -profile {name} shows the repositories of profile {name}, and
+  -all-profiles shows them of every profile, with the following marks:
+    enabled    installed by "volt build" when the profile is current
+    no-build   marked by "volt profile no-build"
+    missing    not installed (not in lock.json, or $VOLTPATH/repos/{path}
+               does not exist). Run "volt get -l" on the profile to install
+    disabled   installed but not in the profile
+  Installed repositories which are not in any profile are shown at the end.
+  They can be removed by "volt rm".
+
 Description
   Vim plugin information extractor.
   If -f flag is not given, this command shows vim plugins of **current profile** (not all installed plugins) by default.
@@ -113,6 +205,9 @@ Description
 		cmd.helped = true
 	}
 	fs.StringVar(&cmd.format, "f", cmd.defaultTemplate(), "text/template format string")
+	fs.BoolVar(&cmd.json, "json", false, "output repositories and profiles in JSON")
+	fs.StringVar(&cmd.profile, "profile", "", "show the status of the repositories of the profile")
+	fs.BoolVar(&cmd.allProfiles, "all-profiles", false, "show the status of the repositories of every profile")
 	return fs
 }
 
@@ -125,24 +220,41 @@ repos path:
 `
 }
 
-func (cmd *listCmd) Run(args []string) *Error {
+func (cmd *listCmd) Run(cmdctx *CmdContext) *Error {
 	fs := cmd.FlagSet()
-	fs.Parse(args)
+	fs.Parse(cmdctx.Args)
 	if cmd.helped {
 		return nil
 	}
-	if err := cmd.list(cmd.format); err != nil {
+	cmd.ctx = cmdctx.Ctx
+	if cmd.profile != "" || cmd.allProfiles {
+		if cmd.json || cmd.format != cmd.defaultTemplate() {
+			return &Error{Code: 10, Msg: "Failed to parse args: -profile and -all-profiles cannot be used with -json and -f"}
+		}
+		if cmd.profile != "" && cmd.allProfiles {
+			return &Error{Code: 10, Msg: "Failed to parse args: -profile cannot be used with -all-profiles"}
+		}
+		if err := cmd.listProfiles(os.Stdout, cmdctx.LockJSON); err != nil {
+			return &Error{Code: 12, Msg: err.Error()}
+		}
+		return nil
+	}
+	if cmd.json {
+		if cmd.format != cmd.defaultTemplate() {
+			return &Error{Code: 10, Msg: "Failed to parse args: -json cannot be used with -f"}
+		}
+		if err := cmd.listJSON(os.Stdout, cmdctx.LockJSON); err != nil {
+			return &Error{Code: 11, Msg: "Failed to output JSON: " + err.Error()}
+		}
+		return nil
+	}
+	if err := cmd.list(cmd.format, cmdctx.LockJSON); err != nil {
 		return &Error{Code: 10, Msg: "Failed to render template: " + err.Error()}
 	}
 	return nil
 }
 
-func (cmd *listCmd) list(format string) error {
-	// Read lock.json
-	lockJSON, err := lockjson.Read()
-	if err != nil {
-		return errors.Wrap(err, "failed to read lock.json")
-	}
+func (cmd *listCmd) list(format string, lockJSON *lockjson.LockJSON) error {
 	// Parse template string
 	t, err := template.New("volt").Funcs(cmd.funcMap(lockJSON)).Parse(format)
 	if err != nil {
@@ -152,7 +264,175 @@ func (cmd *listCmd) list(format string) error {
 	return t.Execute(os.Stdout, lockJSON)
 }
 
-func (*listCmd) funcMap(lockJSON *lockjson.LockJSON) template.FuncMap {
+// listJSON is the output of "volt list -json".
+type listJSON struct {
+	CurrentProfileName string             `json:"current_profile_name"`
+	Repos              []listJSONRepos    `json:"repos"`
+	Profiles           []lockjson.Profile `json:"profiles"`
+}
+
+type listJSONRepos struct {
+	Type    lockjson.ReposType `json:"type"`
+	Path    pathutil.ReposPath `json:"path"`
+	Version string             `json:"version,omitempty"`
+	Release string             `json:"release,omitempty"`
+	Pin     string             `json:"pin,omitempty"`
+	// Enabled is true if current profile has the repository
+	Enabled bool `json:"enabled"`
+	// Built is true if the repository is installed by "volt build" (enabled
+	// and not marked by "volt profile no-build")
+	Built bool `json:"built"`
+	// Installed is true if the directory of the repository exists
+	Installed bool     `json:"installed"`
+	Profiles  []string `json:"profiles"`
+}
+
+// listJSON writes the repositories and the profiles of lockJSON to w in JSON.
+func (cmd *listCmd) listJSON(w io.Writer, lockJSON *lockjson.LockJSON) error {
+	current, err := lockJSON.Profiles.FindByName(lockJSON.CurrentProfileName)
+	if err != nil {
+		return err
+	}
+	result := listJSON{
+		CurrentProfileName: lockJSON.CurrentProfileName,
+		Repos:              make([]listJSONRepos, 0, len(lockJSON.Repos)),
+		Profiles:           lockJSON.Profiles,
+	}
+	for i := range lockJSON.Repos {
+		repos := &lockJSON.Repos[i]
+		profiles := make([]string, 0, len(lockJSON.Profiles))
+		for j := range lockJSON.Profiles {
+			if lockJSON.Profiles[j].ReposPath.Contains(repos.Path) {
+				profiles = append(profiles, lockJSON.Profiles[j].Name)
+			}
+		}
+		result.Repos = append(result.Repos, listJSONRepos{
+			Type:      repos.Type,
+			Path:      repos.Path,
+			Version:   repos.Version,
+			Release:   repos.Release,
+			Pin:       repos.Pin,
+			Enabled:   current.ReposPath.Contains(repos.Path),
+			Built:     current.Builds(repos.Path),
+			Installed: pathutil.Exists(repos.Path.FullPath()),
+			Profiles:  profiles,
+		})
+	}
+	b, err := json.MarshalIndent(&result, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// profileStatus is the repositories of a profile classified by their status,
+// which is shown by "volt list -profile".
+type profileStatus struct {
+	name    string
+	current bool
+	// enabled is the repositories installed by "volt build"
+	enabled []pathutil.ReposPath
+	// noBuild is the repositories marked by "volt profile no-build"
+	noBuild []pathutil.ReposPath
+	// missing is the repositories which are not in lock.json or whose
+	// directories do not exist
+	missing []pathutil.ReposPath
+	// disabled is the installed repositories not in the profile
+	disabled []pathutil.ReposPath
+}
+
+func newProfileStatus(lockJSON *lockjson.LockJSON, profile *lockjson.Profile) *profileStatus {
+	status := &profileStatus{
+		name:    profile.Name,
+		current: lockJSON.IsCurrentProfile(profile.Name),
+	}
+	for _, reposPath := range profile.ReposPath {
+		switch {
+		case !lockJSON.Repos.Contains(reposPath) || !pathutil.Exists(reposPath.FullPath()):
+			status.missing = append(status.missing, reposPath)
+		case profile.Builds(reposPath):
+			status.enabled = append(status.enabled, reposPath)
+		default:
+			status.noBuild = append(status.noBuild, reposPath)
+		}
+	}
+	for i := range lockJSON.Repos {
+		if !profile.ReposPath.Contains(lockJSON.Repos[i].Path) {
+			status.disabled = append(status.disabled, lockJSON.Repos[i].Path)
+		}
+	}
+	return status
+}
+
+// unusedReposList returns the repositories of lock.json which are not in any
+// profile.
+func unusedReposList(lockJSON *lockjson.LockJSON) []pathutil.ReposPath {
+	var unused []pathutil.ReposPath
+	for i := range lockJSON.Repos {
+		used := false
+		for j := range lockJSON.Profiles {
+			if lockJSON.Profiles[j].ReposPath.Contains(lockJSON.Repos[i].Path) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, lockJSON.Repos[i].Path)
+		}
+	}
+	return unused
+}
+
+// listProfiles writes the status of the repositories of -profile or every
+// profile (-all-profiles), and the repositories not in any profile to w.
+func (cmd *listCmd) listProfiles(w io.Writer, lockJSON *lockjson.LockJSON) error {
+	profiles := lockJSON.Profiles
+	if cmd.profile != "" {
+		profile, err := lockJSON.Profiles.FindByName(cmd.profile)
+		if err != nil {
+			return err
+		}
+		profiles = lockjson.ProfileList{*profile}
+	}
+
+	var buf bytes.Buffer
+	for i := range profiles {
+		status := newProfileStatus(lockJSON, &profiles[i])
+		buf.WriteString("name: " + status.name)
+		if status.current {
+			buf.WriteString(" (current)")
+		}
+		buf.WriteString("\n")
+		for _, list := range []struct {
+			mark  string
+			repos []pathutil.ReposPath
+		}{
+			{"enabled", status.enabled},
+			{"no-build", status.noBuild},
+			{"missing", status.missing},
+			{"disabled", status.disabled},
+		} {
+			for _, reposPath := range list.repos {
+				fmt.Fprintf(&buf, "  %-9s %s\n", list.mark, reposPath)
+			}
+		}
+		buf.WriteString("\n")
+	}
+	unused := unusedReposList(lockJSON)
+	if len(unused) > 0 {
+		buf.WriteString("not in any profile:\n")
+		for _, reposPath := range unused {
+			buf.WriteString("  " + reposPath.String() + "\n")
+		}
+	} else {
+		buf.WriteString("not in any profile: (none)\n")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (cmd *listCmd) funcMap(lockJSON *lockjson.LockJSON) template.FuncMap {
 	profileOf := func(name string) *lockjson.Profile {
 		profile, err := lockJSON.Profiles.FindByName(name)
 		if err != nil {
@@ -178,6 +458,13 @@ func (*listCmd) funcMap(lockJSON *lockjson.LockJSON) template.FuncMap {
 			return profileOf(lockJSON.CurrentProfileName)
 		},
 		"profile": profileOf,
+		"metadata": func(reposPath pathutil.ReposPath) (*metadata.Metadata, error) {
+			ctx := cmd.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			return metadata.Get(ctx, reposPath)
+		},
 		"version": func() string {
 			return voltVersion
 		},
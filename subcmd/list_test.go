@@ -1,11 +1,17 @@
 package subcmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
 	"strconv"
 	"testing"
 
 	"github.com/vim-volt/volt/internal/testutil"
 	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
 )
 
 // Checks:
@@ -173,3 +179,113 @@ func TestVoltListFunctions(t *testing.T) {
 		}
 	})
 }
+
+func TestListJSON(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+	if err := os.MkdirAll(pathutil.ReposPath("github.com/tyru/caw.vim").FullPath(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	lockJSON := &lockjson.LockJSON{
+		CurrentProfileName: "default",
+		Repos: lockjson.ReposList{
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "1a2b3c4", Pin: "#v1.0"},
+			{Type: lockjson.ReposReleaseType, Path: "github.com/junegunn/fzf", Version: "0.17.0", Release: "latest"},
+		},
+		Profiles: lockjson.ProfileList{
+			{Name: "default", ReposPath: []pathutil.ReposPath{"github.com/tyru/caw.vim", "github.com/junegunn/fzf"}, NoBuild: []pathutil.ReposPath{"github.com/junegunn/fzf"}},
+			{Name: "server", ReposPath: []pathutil.ReposPath{"github.com/junegunn/fzf"}},
+		},
+	}
+	var out bytes.Buffer
+	if err := (&listCmd{}).listJSON(&out, lockJSON); err != nil {
+		t.Fatal(err)
+	}
+	var got listJSON
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	expected := []listJSONRepos{
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "1a2b3c4", Pin: "#v1.0",
+			Enabled: true, Built: true, Installed: true, Profiles: []string{"default"}},
+		{Type: lockjson.ReposReleaseType, Path: "github.com/junegunn/fzf", Version: "0.17.0", Release: "latest",
+			Enabled: true, Built: false, Installed: false, Profiles: []string{"default", "server"}},
+	}
+	if !reflect.DeepEqual(got.Repos, expected) {
+		t.Errorf("expected %+v but got %+v", expected, got.Repos)
+	}
+	if got.CurrentProfileName != "default" || len(got.Profiles) != 2 {
+		t.Errorf("unexpected current profile or profiles: %s", out.String())
+	}
+}
+
+func TestListProfiles(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+	for _, reposPath := range []pathutil.ReposPath{"github.com/tyru/caw.vim", "github.com/junegunn/fzf", "github.com/tyru/open-browser.vim"} {
+		if err := os.MkdirAll(reposPath.FullPath(), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lockJSON := &lockjson.LockJSON{
+		CurrentProfileName: "default",
+		Repos: lockjson.ReposList{
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim"},
+			{Type: lockjson.ReposGitType, Path: "github.com/junegunn/fzf"},
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/open-browser.vim"},
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/removed.vim"},
+		},
+		Profiles: lockjson.ProfileList{
+			{Name: "default", ReposPath: []pathutil.ReposPath{"github.com/tyru/caw.vim", "github.com/junegunn/fzf", "github.com/tyru/removed.vim"}, NoBuild: []pathutil.ReposPath{"github.com/junegunn/fzf"}},
+			{Name: "server", ReposPath: []pathutil.ReposPath{"github.com/junegunn/fzf", "github.com/tyru/unknown.vim"}},
+		},
+	}
+	expected := `name: server
+  enabled   github.com/junegunn/fzf
+  missing   github.com/tyru/unknown.vim
+  disabled  github.com/tyru/caw.vim
+  disabled  github.com/tyru/open-browser.vim
+  disabled  github.com/tyru/removed.vim
+
+not in any profile:
+  github.com/tyru/open-browser.vim
+`
+	var out bytes.Buffer
+	if err := (&listCmd{profile: "server"}).listProfiles(&out, lockJSON); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\nbut got:\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	if err := (&listCmd{allProfiles: true}).listProfiles(&out, lockJSON); err != nil {
+		t.Fatal(err)
+	}
+	expected = `name: default (current)
+  enabled   github.com/tyru/caw.vim
+  no-build  github.com/junegunn/fzf
+  missing   github.com/tyru/removed.vim
+  disabled  github.com/tyru/open-browser.vim
+
+` + expected
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\nbut got:\n%s", expected, out.String())
+	}
+
+	if err := (&listCmd{profile: "nonexistent"}).listProfiles(&out, lockJSON); err == nil {
+		t.Error("expected error for a nonexistent profile")
+	}
+}
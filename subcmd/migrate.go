@@ -6,6 +6,7 @@ import (
 	"github.com/pkg/errors"
 	"os"
 
+	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/subcmd/migrate"
 )
@@ -16,6 +17,8 @@ func init() {
 
 type migrateCmd struct {
 	helped bool
+	list   bool
+	to     int64
 }
 
 func (cmd *migrateCmd) ProhibitRootExecution(args []string) bool { return true }
@@ -38,25 +41,33 @@ func (cmd *migrateCmd) FlagSet() *flag.FlagSet {
 
 		fmt.Println(`Usage
   volt migrate [-help] {migration operation}
+  volt migrate -to {version} [lockjson]
+  volt migrate -list
 
 Description
   Perform miscellaneous migration operations.
   See detailed help for 'volt migrate -help {migration operation}'.
 
+  -to option migrates lock.json only to {version}, and -list option shows the
+  versions of lock.json and what each migration changes.
+
 Available operations`)
 		cmd.showAvailableOps(func(line string) {
 			fmt.Println(line)
 		})
-		//fmt.Println("Options")
-		//fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Options")
+		fs.PrintDefaults()
 		fmt.Println()
 		cmd.helped = true
 	}
+	fs.BoolVar(&cmd.list, "list", false, "show the migrations of lock.json")
+	fs.Int64Var(&cmd.to, "to", 0, "migrate lock.json to the version (default: the latest version)")
 	return fs
 }
 
-func (cmd *migrateCmd) Run(args []string) *Error {
-	op, err := cmd.parseArgs(args)
+func (cmd *migrateCmd) Run(cmdctx *CmdContext) *Error {
+	op, err := cmd.parseArgs(cmdctx.Args)
 	if err == ErrShowedHelp {
 		return nil
 	}
@@ -64,7 +75,22 @@ func (cmd *migrateCmd) Run(args []string) *Error {
 		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
 	}
 
-	if err := op.Migrate(); err != nil {
+	if cmd.list {
+		if err := cmd.showMigrations(); err != nil {
+			return &Error{Code: 11, Msg: "Failed to read lock.json: " + err.Error()}
+		}
+		return nil
+	}
+
+	if cmd.to != 0 {
+		if err := migrate.MigrateLockJSONTo(cmdctx.Ctx, cmd.to); err != nil {
+			return &Error{Code: 11, Msg: "Failed to migrate: " + err.Error()}
+		}
+		logger.Infof("lock.json was successfully migrated to v%d!", cmd.to)
+		return nil
+	}
+
+	if err := op.Migrate(cmdctx.Ctx); err != nil {
 		return &Error{Code: 11, Msg: "Failed to migrate: " + err.Error()}
 	}
 
@@ -79,6 +105,21 @@ func (cmd *migrateCmd) parseArgs(args []string) (migrate.Migrater, error) {
 		return nil, ErrShowedHelp
 	}
 	args = fs.Args()
+	if cmd.list {
+		if len(args) > 0 || cmd.to != 0 {
+			return nil, errors.New("-list option cannot be used with other arguments")
+		}
+		return nil, nil
+	}
+	if cmd.to != 0 {
+		if cmd.to < 0 {
+			return nil, errors.Errorf("invalid version: %d", cmd.to)
+		}
+		if len(args) > 0 && args[0] != "lockjson" || len(args) > 1 {
+			return nil, errors.New("-to option can be used only for 'lockjson' operation")
+		}
+		return migrate.GetMigrater("lockjson")
+	}
 	if len(args) == 0 {
 		fs.Usage()
 		return nil, errors.New("please specify migration operation")
@@ -86,6 +127,29 @@ func (cmd *migrateCmd) parseArgs(args []string) (migrate.Migrater, error) {
 	return migrate.GetMigrater(args[0])
 }
 
+// showMigrations shows the versions of lock.json and the migrations between
+// them.
+func (cmd *migrateCmd) showMigrations() error {
+	current, err := lockjson.ReadVersion()
+	if err != nil {
+		return err
+	}
+	if current == 0 {
+		fmt.Printf("lock.json does not exist (the latest version is v%d)\n", lockjson.LatestVersion())
+	} else {
+		fmt.Printf("lock.json is v%d (the latest version is v%d)\n", current, lockjson.LatestVersion())
+	}
+	for _, m := range lockjson.Migrations() {
+		status := "pending"
+		if current == 0 || m.From < current {
+			status = "applied"
+		}
+		fmt.Printf("  v%d -> v%d (%s)\n", m.From, m.From+1, status)
+		fmt.Printf("    %s\n", m.Description)
+	}
+	return nil
+}
+
 func (cmd *migrateCmd) showAvailableOps(write func(string)) {
 	for _, m := range migrate.ListMigraters() {
 		write(fmt.Sprintf("  %s", m.Name()))
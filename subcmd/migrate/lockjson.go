@@ -1,9 +1,11 @@
 package migrate
 
 import (
+	"context"
 	"github.com/pkg/errors"
 
 	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/transaction"
 )
 
@@ -28,15 +30,30 @@ func (m *lockjsonMigrater) Description(brief bool) string {
 Description
   Perform migration of $VOLTPATH/lock.json, which means volt converts old version lock.json structure into the latest version. This is always done automatically when reading lock.json content. For example, 'volt get <repos>' will install plugin, and migrate lock.json structure, and write it to lock.json after all. so the migrated content is written to lock.json automatically.
   But, for example, 'volt list' does not write to lock.json but does read, so every time when running 'volt list' shows warning about lock.json is old.
-  To suppress this, running this command simply reads and writes migrated structure to lock.json.`
+  To suppress this, running this command simply reads and writes migrated structure to lock.json.
+  If -to option is specified, lock.json is migrated only to the version (see "volt migrate -list").
+  The old lock.json is backed up to "lock.json.v{version}.bak" before it is migrated.`
 }
 
-func (*lockjsonMigrater) Migrate() (err error) {
-	// Read lock.json
-	lockJSON, err := lockjson.ReadNoMigrationMsg()
+func (*lockjsonMigrater) Migrate(ctx context.Context) error {
+	return MigrateLockJSONTo(ctx, lockjson.LatestVersion())
+}
+
+// MigrateLockJSONTo migrates lock.json to version to.
+func MigrateLockJSONTo(ctx context.Context, to int64) (err error) {
+	from, err := lockjson.ReadVersion()
 	if err != nil {
 		return errors.Wrap(err, "could not read lock.json")
 	}
+	if from == 0 && to != lockjson.LatestVersion() {
+		return errors.New("lock.json does not exist")
+	}
+	if from > to {
+		return errors.Errorf("lock.json is v%d, which is newer than v%d", from, to)
+	}
+	if to > lockjson.LatestVersion() {
+		return errors.Errorf("v%d is unknown lock.json version (the latest version is v%d)", to, lockjson.LatestVersion())
+	}
 
 	// Begin transaction
 	trx, err := transaction.Start()
@@ -50,7 +67,10 @@ func (*lockjsonMigrater) Migrate() (err error) {
 	}()
 
 	// Write to lock.json
-	err = lockJSON.Write()
+	for v := from; v >= 1 && v < to; v++ {
+		logger.Infof("Migrating lock.json v%d to v%d ...", v, v+1)
+	}
+	err = lockjson.MigrateFileTo(to)
 	if err != nil {
 		return errors.Wrap(err, "could not write to lock.json")
 	}
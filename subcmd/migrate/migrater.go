@@ -1,13 +1,14 @@
 package migrate
 
 import (
+	"context"
 	"github.com/pkg/errors"
 	"sort"
 )
 
 // Migrater migrates many kinds of data.
 type Migrater interface {
-	Migrate() error
+	Migrate(ctx context.Context) error
 	Name() string
 	Description(brief bool) string
 }
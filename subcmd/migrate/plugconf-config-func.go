@@ -1,6 +1,7 @@
 package migrate
 
 import (
+	"context"
 	"github.com/pkg/errors"
 	"io/ioutil"
 	"os"
@@ -39,7 +40,7 @@ Description
   All plugconf files are replaced with new contents.`
 }
 
-func (*plugconfConfigMigrater) Migrate() (err error) {
+func (*plugconfConfigMigrater) Migrate(ctx context.Context) (err error) {
 	// Read lock.json
 	lockJSON, err := lockjson.ReadNoMigrationMsg()
 	if err != nil {
@@ -102,7 +103,7 @@ func (*plugconfConfigMigrater) Migrate() (err error) {
 	}()
 
 	// Build ~/.vim/pack/volt dir
-	err = builder.Build(false)
+	err = builder.Build(ctx, false)
 	if err != nil {
 		err = errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
 		return
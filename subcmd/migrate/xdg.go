@@ -0,0 +1,66 @@
+package migrate
+
+import (
+	"context"
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/builder"
+	"github.com/vim-volt/volt/transaction"
+)
+
+func init() {
+	m := &xdgMigrater{}
+	migrateOps[m.Name()] = m
+}
+
+type xdgMigrater struct{}
+
+func (*xdgMigrater) Name() string {
+	return "xdg"
+}
+
+func (m *xdgMigrater) Description(brief bool) string {
+	if brief {
+		return "moves ~/volt to XDG layout directories"
+	}
+	return `Usage
+  volt migrate [-help] ` + m.Name() + `
+
+Description
+  Move files of ~/volt to the following directories, and remove ~/volt.
+    * $XDG_CONFIG_HOME/volt (~/.config/volt): config.toml, lock.json, plugconf, rc
+    * $XDG_DATA_HOME/volt (~/.local/share/volt): kept-repos, repos, snapshots, trx
+    * $XDG_CACHE_HOME/volt (~/.cache/volt): logs, metadata, notify.json, objects, tmp
+  After the migration, ~/.vim/pack/volt is fully rebuilt because it has symlinks to ~/volt/repos.
+  Once migrated, volt keeps using the above directories even if ~/volt is created again.
+  Nothing is moved if $VOLTPATH is set, ~/volt is a symlink, or ~/volt has other files (e.g. .git).`
+}
+
+func (*xdgMigrater) Migrate(ctx context.Context) (err error) {
+	if err = pathutil.MigrateToXDG(); err != nil {
+		return errors.Wrap(err, "could not move ~/volt")
+	}
+	logger.Infof("Moved ~/volt to %s, %s, %s",
+		pathutil.VoltConfigDir(), pathutil.VoltDataDir(), pathutil.VoltCacheDir())
+
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := trx.Done(); e != nil {
+			err = e
+		}
+	}()
+
+	// Rebuild ~/.vim/pack/volt dir to point to the moved repositories
+	err = builder.Build(ctx, true)
+	if err != nil {
+		err = errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+		return
+	}
+	return
+}
@@ -0,0 +1,302 @@
+package subcmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func init() {
+	cmdMap["notify"] = &notifyCmd{}
+}
+
+type notifyCmd struct {
+	helped bool
+	read   bool
+	write  bool
+}
+
+func (cmd *notifyCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *notifyCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt notify [-help] -write
+  volt notify [-help] -read
+
+Quick example
+  $ volt notify -write  # checks updates of plugins and volt
+  $ volt notify -read   # shows the result (e.g. "3 plugin updates, volt v0.4.0")
+
+Description
+  Check updates of plugins and volt, and show the result in a shell prompt or
+  Vim's statusline.
+
+  -write checks whether the upstream branches of git repositories in lock.json
+  have other commits than the locked ones (nothing is fetched), and whether a
+  newer volt is released. The result is written to $VOLTPATH/notify.json.
+  Repositories whose HEAD is detached (e.g. checked out with a tag) are not
+  checked.
+
+  -read shows the result in one line, or nothing if there are no updates.
+  It reads only notify.json, so it is fast enough to run on every prompt:
+
+    PS1='$(volt notify -read)\$ '                      # bash
+    let g:volt_notify = trim(system('volt notify -read'))  " vimrc
+
+  "volt get" removes the upgraded repositories from the result.
+
+Background check
+  If notify.enable of config.toml is true, other volt commands run
+  "volt notify -write" in background when notify.interval (default "24h") has
+  passed since the last check:
+
+    [notify]
+    enable = true
+    interval = "12h"` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.BoolVar(&cmd.read, "read", false, "show the result of the last check")
+	fs.BoolVar(&cmd.write, "write", false, "check updates and write the result")
+	return fs
+}
+
+func (cmd *notifyCmd) Run(cmdctx *CmdContext) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(cmdctx.Args)
+	if cmd.helped {
+		return nil
+	}
+	if cmd.read == cmd.write || len(fs.Args()) > 0 {
+		return &Error{Code: 10, Msg: "Failed to parse args: either -read or -write must be specified"}
+	}
+
+	if cmd.read {
+		status, err := readNotifyStatus()
+		if err != nil {
+			return &Error{Code: 11, Msg: "Failed to read " + pathutil.NotifyStatusFile() + ": " + err.Error()}
+		}
+		if msg := status.String(); msg != "" {
+			fmt.Println(msg)
+		}
+		return nil
+	}
+
+	status := checkUpdates(cmdctx.Ctx, cmdctx.LockJSON, cmdctx.Config)
+	if cmdctx.Ctx.Err() != nil {
+		return &Error{Code: 12, Msg: "Interrupted"}
+	}
+	if err := status.write(); err != nil {
+		return &Error{Code: 13, Msg: "Failed to write " + pathutil.NotifyStatusFile() + ": " + err.Error()}
+	}
+	return nil
+}
+
+// notifyStatus is the content of notify.json.
+type notifyStatus struct {
+	CheckedAt time.Time `json:"checked_at"`
+	// Updates is a map from a repository which has updates to its version of
+	// lock.json at the check
+	Updates map[pathutil.ReposPath]string `json:"updates"`
+	// Volt is the tag of newer volt release, or empty if volt is the latest
+	Volt string `json:"volt,omitempty"`
+}
+
+// String returns the one-line message of status, or an empty string if there
+// are no updates.
+func (status *notifyStatus) String() string {
+	var msgs []string
+	if n := len(status.Updates); n == 1 {
+		msgs = append(msgs, "1 plugin update")
+	} else if n > 1 {
+		msgs = append(msgs, fmt.Sprintf("%d plugin updates", n))
+	}
+	if status.Volt != "" {
+		msgs = append(msgs, "volt "+status.Volt)
+	}
+	return strings.Join(msgs, ", ")
+}
+
+func (status *notifyStatus) write() error {
+	path := pathutil.NotifyStatusFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	content, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// readNotifyStatus reads notify.json. An empty status is returned if it does
+// not exist.
+func readNotifyStatus() (*notifyStatus, error) {
+	var status notifyStatus
+	content, err := ioutil.ReadFile(pathutil.NotifyStatusFile())
+	if os.IsNotExist(err) {
+		return &status, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// checkUpdates checks updates of the git repositories in lockJSON and volt.
+// The repositories which could not be checked are ignored.
+func checkUpdates(ctx context.Context, lockJSON *lockjson.LockJSON, cfg *config.Config) *notifyStatus {
+	status := &notifyStatus{
+		CheckedAt: time.Now(),
+		Updates:   make(map[pathutil.ReposPath]string),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	limiter := newHostLimiter(*cfg.Get.MaxConnectionsPerHost, *cfg.Get.MaxParallel)
+	for i := range lockJSON.Repos {
+		repos := &lockJSON.Repos[i]
+		if repos.Type != lockjson.ReposGitType {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.acquire(ctx, repos.Path.Host())
+			if err != nil {
+				return
+			}
+			defer release()
+			hash, err := upstreamHEAD(ctx, repos.Path)
+			if err != nil {
+				logger.Debugf("Could not check updates of %s: %s", repos.Path, err)
+				return
+			}
+			if hash != repos.Version {
+				mu.Lock()
+				status.Updates[repos.Path] = repos.Version
+				mu.Unlock()
+			}
+		}()
+	}
+
+	latest, err := (&selfUpgradeCmd{}).checkLatest(ctx, cfg.SelfUpgrade.Channel)
+	if err == nil {
+		var v versionInfo
+		if v, err = parseVersion(latest.TagName); err == nil && compareVersion(v, voltVersionInfo()) > 0 {
+			status.Volt = latest.TagName
+		}
+	}
+	if err != nil {
+		logger.Debugf("Could not check updates of volt: %s", err)
+	}
+
+	wg.Wait()
+	return status
+}
+
+// upstreamHEAD returns the commit hash of the upstream branch of reposPath.
+// "git ls-remote" is used if "git" command exists.
+func upstreamHEAD(ctx context.Context, reposPath pathutil.ReposPath) (string, error) {
+	r, err := git.PlainOpen(reposPath.FullPath())
+	if err != nil {
+		return "", err
+	}
+	remote, refBranch, err := gitutil.GetUpstreamBranch(r)
+	if err != nil {
+		return "", err
+	}
+	if !(&getCmd{}).hasGitCmd() {
+		return gitutil.LsRemote(r, remote, refBranch)
+	}
+	c := exec.CommandContext(ctx, "git", "ls-remote", remote, refBranch)
+	c.Dir = reposPath.FullPath()
+	out, err := c.Output()
+	if err != nil {
+		return "", errors.Wrap(err, "git ls-remote failed")
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", errors.Errorf("%s is not found in remote '%s'", refBranch, remote)
+	}
+	return fields[0], nil
+}
+
+// removeNotifyUpdates removes the repositories whose versions were changed in
+// lockJSON (e.g. upgraded) from the updates of notify.json.
+func removeNotifyUpdates(lockJSON *lockjson.LockJSON) error {
+	status, err := readNotifyStatus()
+	if err != nil || len(status.Updates) == 0 {
+		return err
+	}
+	changed := false
+	for reposPath, version := range status.Updates {
+		if repos := lockJSON.Repos.FindByPath(reposPath); repos == nil || repos.Version != version {
+			delete(status.Updates, reposPath)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := status.write(); err != nil {
+		return err
+	}
+	// Keep the time of the last check for notify.interval
+	return os.Chtimes(pathutil.NotifyStatusFile(), status.CheckedAt, status.CheckedAt)
+}
+
+// startNotifyCheck runs "volt notify -write" in background if notify.enable
+// of cfg is true and notify.interval has passed since the last check.
+func startNotifyCheck(cfg *config.Config) {
+	if !*cfg.Notify.Enable || detectPriviledgedUser() != nil {
+		return
+	}
+	path := pathutil.NotifyStatusFile()
+	if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) < cfg.Notify.IntervalDuration() {
+		return
+	}
+	// Update the modification time not to run more checks until the running
+	// one finishes
+	now := time.Now()
+	err := os.Chtimes(path, now, now)
+	if os.IsNotExist(err) {
+		err = (&notifyStatus{}).write()
+	}
+	if err != nil {
+		logger.Debugf("Could not update %s: %s", path, err)
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		logger.Debugf("Could not check updates in background: %s", err)
+		return
+	}
+	if err := exec.Command(exe, "notify", "-write").Start(); err != nil {
+		logger.Debugf("Could not check updates in background: %s", err)
+	}
+}
@@ -0,0 +1,76 @@
+package subcmd
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestNotifyStatusString(t *testing.T) {
+	for _, tt := range []struct {
+		status   notifyStatus
+		expected string
+	}{
+		{notifyStatus{}, ""},
+		{notifyStatus{Updates: map[pathutil.ReposPath]string{"github.com/tyru/caw.vim": "abc"}}, "1 plugin update"},
+		{notifyStatus{
+			Updates: map[pathutil.ReposPath]string{"github.com/tyru/caw.vim": "abc", "github.com/tyru/open-browser.vim": "def"},
+			Volt:    "v0.4.0",
+		}, "2 plugin updates, volt v0.4.0"},
+	} {
+		if got := tt.status.String(); got != tt.expected {
+			t.Errorf("expected %q but got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestRemoveNotifyUpdates(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	checkedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	status := &notifyStatus{
+		CheckedAt: checkedAt,
+		Updates: map[pathutil.ReposPath]string{
+			"github.com/tyru/caw.vim":          "abc",
+			"github.com/tyru/open-browser.vim": "def",
+		},
+	}
+	if err := status.write(); err != nil {
+		t.Fatal(err)
+	}
+	lockJSON := &lockjson.LockJSON{
+		Repos: lockjson.ReposList{
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "abc"},
+			{Type: lockjson.ReposGitType, Path: "github.com/tyru/open-browser.vim", Version: "xyz"},
+		},
+	}
+	if err := removeNotifyUpdates(lockJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readNotifyStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[pathutil.ReposPath]string{"github.com/tyru/caw.vim": "abc"}
+	if !reflect.DeepEqual(got.Updates, expected) {
+		t.Errorf("expected %v but got %v", expected, got.Updates)
+	}
+	// The time of the last check is kept
+	if fi, err := os.Stat(pathutil.NotifyStatusFile()); err != nil {
+		t.Fatal(err)
+	} else if !fi.ModTime().Equal(checkedAt) {
+		t.Errorf("expected modification time %s but got %s", checkedAt, fi.ModTime())
+	}
+}
@@ -0,0 +1,135 @@
+package subcmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+)
+
+func init() {
+	cmdMap["outdated"] = &outdatedCmd{}
+}
+
+type outdatedCmd struct {
+	helped bool
+}
+
+func (cmd *outdatedCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *outdatedCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt outdated [-help]
+
+Quick example
+  $ volt outdated
+
+  * github.com/tyru/caw.vim > outdated (3c6e3b0..41e5f4b)
+    github.com/tyru/open-browser.vim > up to date
+  # github.com/junegunn/fzf > pinned to #0.17.0 (not checked)
+
+Description
+  Check whether the upstream branches of the git repositories in current
+  profile have other commits than the versions of lock.json:
+
+    * {repository} > outdated ({locked version}..{upstream commit})
+        "volt get -u {repository}" upgrades it
+      {repository} > up to date
+    # {repository} > pinned to {version} (not checked)
+        The repository is pinned to a tag or a commit
+    ! {repository} > could not check: {error}
+
+  Only the remote refs are read (like "git ls-remote"). The repositories and
+  lock.json are not changed, and no objects are fetched.
+
+  The number of repositories checked at once is limited by get.max_parallel
+  and get.max_connections_per_host of config.toml.` + "\n\n")
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *outdatedCmd) Run(cmdctx *CmdContext) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(cmdctx.Args)
+	if cmd.helped {
+		return nil
+	}
+	if len(fs.Args()) > 0 {
+		return &Error{Code: 10, Msg: "Failed to parse args: outdated command does not accept arguments"}
+	}
+
+	reposList, err := cmdctx.LockJSON.GetCurrentReposList()
+	if err != nil {
+		return &Error{Code: 11, Msg: err.Error()}
+	}
+	failed := cmd.showOutdated(cmdctx.Ctx, os.Stdout, reposList, cmdctx.Config)
+	if cmdctx.Ctx.Err() != nil {
+		return &Error{Code: 12, Msg: "Interrupted"}
+	}
+	if failed > 0 {
+		return &Error{Code: 13, Msg: fmt.Sprintf("Failed to check %d repositories", failed)}
+	}
+	return nil
+}
+
+// showOutdated checks the upstream branches of the git repositories in
+// reposList, and writes the results to w in the order of reposList.
+// It returns the number of repositories which could not be checked.
+func (cmd *outdatedCmd) showOutdated(ctx context.Context, w io.Writer, reposList lockjson.ReposList, cfg *config.Config) int {
+	results := make([]string, len(reposList))
+	failed := 0
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	limiter := newHostLimiter(*cfg.Get.MaxConnectionsPerHost, *cfg.Get.MaxParallel)
+	for i := range reposList {
+		repos := &reposList[i]
+		if repos.Type != lockjson.ReposGitType {
+			continue
+		}
+		if (&getCmd{}).isPinnedToFixedRef(repos.Path, repos) {
+			results[i] = fmt.Sprintf("# %s > pinned to %s (not checked)", repos.Path, repos.Pin)
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			release, err := limiter.acquire(ctx, repos.Path.Host())
+			if err != nil {
+				return
+			}
+			defer release()
+			hash, err := upstreamHEAD(ctx, repos.Path)
+			switch {
+			case err != nil:
+				logger.Debugf("Could not check updates of %s: %s", repos.Path, err)
+				mu.Lock()
+				failed++
+				mu.Unlock()
+				results[i] = fmt.Sprintf("! %s > could not check: %s", repos.Path, err)
+			case hash != repos.Version:
+				results[i] = fmt.Sprintf("* %s > outdated (%s..%s)", repos.Path, repos.Version, hash)
+			default:
+				results[i] = fmt.Sprintf("  %s > up to date", repos.Path)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result != "" {
+			fmt.Fprintln(w, result)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,41 @@
+package subcmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+)
+
+func TestShowOutdated(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	cfg, err := config.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reposList := lockjson.ReposList{
+		{Type: lockjson.ReposStaticType, Path: "localhost/local/hello"},
+		{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim", Version: "aaa"},
+	}
+	var out bytes.Buffer
+	failed := (&outdatedCmd{}).showOutdated(context.Background(), &out, reposList, cfg)
+	if failed != 1 {
+		t.Errorf("expected 1 failure but got %d", failed)
+	}
+	// Only git repositories are checked
+	expected := "! github.com/tyru/caw.vim > could not check: repository not exists\n"
+	if out.String() != expected {
+		t.Errorf("expected %q but got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,106 @@
+package subcmd
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-colorable"
+
+	"github.com/vim-volt/volt/logger"
+)
+
+// The formats of "volt -output {format}".
+const (
+	textOutput = "text"
+	jsonOutput = "json"
+)
+
+// jsonResult is written to stdout by "volt -output json".
+type jsonResult struct {
+	Command string      `json:"command"`
+	OK      bool        `json:"ok"`
+	DryRun  bool        `json:"dry_run,omitempty"`
+	Error   *jsonError  `json:"error,omitempty"`
+	Result  interface{} `json:"result"`
+}
+
+type jsonError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// runJSON runs 'volt {subCmd} {args}' like run(), but all messages of the
+// command are written to stderr, and the result (CmdContext.Result) is
+// written to stdout as JSON.
+func runJSON(subCmd string, args []string, opts *globalOptions, cont RunnerFunc) *Error {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	prevOutput := logger.SetOutput(colorable.NewColorableStderr())
+	defer func() {
+		os.Stdout = stdout
+		logger.SetOutput(prevOutput)
+	}()
+
+	var cmdctx *CmdContext
+	err := run(subCmd, args, opts, func(c Cmd, ctx *CmdContext) *Error {
+		cmdctx = ctx
+		return cont(c, ctx)
+	})
+
+	result := &jsonResult{Command: subCmd, OK: err == nil}
+	if cmdctx != nil {
+		result.Command = cmdctx.Cmd
+		result.DryRun = cmdctx.DryRun
+		result.Result = cmdctx.Result
+	}
+	if err != nil {
+		result.Error = &jsonError{Code: err.Code, Message: err.Msg}
+	}
+	if e := json.NewEncoder(stdout).Encode(result); e != nil && err == nil {
+		return &Error{Code: 6, Msg: "Could not write the result: " + e.Error()}
+	}
+	return err
+}
+
+// statusResult is a line of the results of "volt get" (e.g. "+ {repos} >
+// installed") in JSON.
+type statusResult struct {
+	// Status is "added", "updated", "unchanged", or "failed"
+	Status  string   `json:"status"`
+	Target  string   `json:"target"`
+	Message string   `json:"message"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+var statusOfMark = map[string]string{
+	"+": "added",
+	"*": "updated",
+	"#": "unchanged",
+	"!": "failed",
+}
+
+// parseStatusList converts status lines like "! {repos} > install failed\n
+// * {error}" to statusResult.
+func parseStatusList(statusList []string) []statusResult {
+	results := make([]statusResult, 0, len(statusList))
+	for _, status := range statusList {
+		lines := strings.Split(status, "\n")
+		var r statusResult
+		if i := strings.Index(lines[0], " "); i >= 0 {
+			r.Status = statusOfMark[lines[0][:i]]
+			lines[0] = lines[0][i+1:]
+		}
+		if i := strings.Index(lines[0], " > "); i >= 0 {
+			r.Target = lines[0][:i]
+			r.Message = lines[0][i+len(" > "):]
+		} else {
+			r.Message = lines[0]
+		}
+		for _, line := range lines[1:] {
+			r.Errors = append(r.Errors, strings.TrimPrefix(strings.TrimSpace(line), "* "))
+		}
+		results = append(results, r)
+	}
+	return results
+}
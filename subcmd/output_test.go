@@ -0,0 +1,65 @@
+package subcmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStatusList(t *testing.T) {
+	got := parseStatusList([]string{
+		"+ github.com/tyru/caw.vim > installed",
+		"! github.com/tyru/skk.vim > install failed\n  * failed to clone\n  * no such repository",
+		"* github.com/tyru/open-browser.vim > upgraded (aaa..bbb, 2 commits)",
+		"# github.com/tyru/eskk.vim > no change",
+		"+ profile foo > merged",
+	})
+	expected := []statusResult{
+		{Status: "added", Target: "github.com/tyru/caw.vim", Message: "installed"},
+		{Status: "failed", Target: "github.com/tyru/skk.vim", Message: "install failed", Errors: []string{"failed to clone", "no such repository"}},
+		{Status: "updated", Target: "github.com/tyru/open-browser.vim", Message: "upgraded (aaa..bbb, 2 commits)"},
+		{Status: "unchanged", Target: "github.com/tyru/eskk.vim", Message: "no change"},
+		{Status: "added", Target: "profile foo", Message: "merged"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v but got %+v", expected, got)
+	}
+}
+
+func TestGlobalOptionsParse(t *testing.T) {
+	for _, tt := range []struct {
+		args     []string
+		n        int
+		expected globalOptions
+		isErr    bool
+	}{
+		{args: []string{"-no-build", "get"}, n: 1, expected: globalOptions{noBuild: true}},
+		{args: []string{"--dry-run", "get"}, n: 1, expected: globalOptions{dryRun: true}},
+		{args: []string{"-output", "json", "get"}, n: 2, expected: globalOptions{output: jsonOutput}},
+		{args: []string{"--output=text", "get"}, n: 1, expected: globalOptions{output: textOutput}},
+		{args: []string{"-log-level", "debug", "get"}, n: 2, expected: globalOptions{logLevel: "debug"}},
+		{args: []string{"--log-level=warn", "get"}, n: 1, expected: globalOptions{logLevel: "warn"}},
+		{args: []string{"--quiet", "get"}, n: 1, expected: globalOptions{quiet: true}},
+		{args: []string{"-no-color", "get"}, n: 1, expected: globalOptions{noColor: true}},
+		{args: []string{"-log-format", "json", "get"}, n: 2, expected: globalOptions{logFormat: "json"}},
+		{args: []string{"--log-format=text", "get"}, n: 1, expected: globalOptions{logFormat: "text"}},
+		{args: []string{"get", "-l"}, n: 0},
+		{args: []string{"-output", "yaml"}, isErr: true},
+		{args: []string{"-output"}, isErr: true},
+		{args: []string{"-log-level"}, isErr: true},
+		{args: []string{"-log-format"}, isErr: true},
+	} {
+		var opts globalOptions
+		n, err := opts.parse(tt.args)
+		if tt.isErr {
+			if err == nil {
+				t.Errorf("%q: expected an error but got nil", tt.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.args, err)
+		} else if n != tt.n || opts != tt.expected {
+			t.Errorf("%q: expected (%d, %+v) but got (%d, %+v)", tt.args, tt.n, tt.expected, n, opts)
+		}
+	}
+}
@@ -1,13 +1,16 @@
 package subcmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/hashicorp/go-multierror"
+	"github.com/vim-volt/volt/config"
 	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
@@ -17,6 +20,7 @@ import (
 
 type profileCmd struct {
 	helped bool
+	dryRun bool
 }
 
 var profileSubCmd = make(map[string]func([]string) error)
@@ -29,6 +33,7 @@ func (cmd *profileCmd) ProhibitRootExecution(args []string) bool {
 	if len(args) == 0 {
 		return true
 	}
+
 	subCmd := args[0]
 	switch subCmd {
 	case "show":
@@ -49,8 +54,16 @@ Usage
   profile [-help] {command}
 
 Command
-  profile set [-n] {name}
+  profile set [-n] {name} [{name2} ...]
     Set profile name to {name}.
+    If two or more names are given, all of them become current profiles and
+    their repositories are loaded. {name} is the primary profile: "volt get"
+    and "volt enable" add repositories to it. vimrc and gvimrc are installed
+    from the first profile in the list which has them.
+    If -n option was given, create the profiles which do not exist.
+    If build.runtime_profile is true in config.toml, this only changes
+    current profile in lock.json. Set g:volt_profile in vimrc to change
+    the profile to load.
 
   profile show [-current | {name}]
     Show profile info of {name}.
@@ -74,6 +87,20 @@ Command
   profile rm [-current | {name}] {repository} [{repository2} ...]
     Remove one or more repositories from profile {name}.
 
+  profile no-build [-current | {name}] {repository} [{repository2} ...]
+    Do not install one or more repositories of profile {name} by "volt build"
+    (e.g. heavyweight plugins which are not used on a remote server).
+    The repositories stay in profile {name} and their plugconfs are kept.
+    NOTE: If two or more profiles are current profiles, the repository is
+    installed when any of them does not mark it as no-build.
+
+  profile build [-current | {name}] {repository} [{repository2} ...]
+    Install one or more repositories marked by "profile no-build" again.
+
+  {repository} of "profile add", "profile rm", "profile no-build" and
+  "profile build" can also be a plugin name (e.g. "caw.vim") of installed
+  repositories.
+
 Quick example
   $ volt profile list   # default profile is "default"
   * default
@@ -88,21 +115,31 @@ Quick example
 
   $ volt profile set default   # on profile "default"
 
+  $ volt profile set base foo   # load plugins of both "base" and "foo"
+
   $ volt enable tyru/caw.vim    # enable loading tyru/caw.vim on current profile
   $ volt profile add foo tyru/caw.vim    # enable loading tyru/caw.vim on "foo" profile
 
   $ volt disable tyru/caw.vim   # disable loading tyru/caw.vim on current profile
+  $ volt disable caw.vim        # same as above if only tyru/caw.vim matches "caw.vim"
   $ volt profile rm foo tyru/caw.vim    # disable loading tyru/caw.vim on "foo" profile
 
-  $ volt profile destroy foo   # will delete profile "foo"` + "\n\n")
+  $ volt profile no-build server Shougo/deoplete.nvim   # do not install Shougo/deoplete.nvim on "server" profile
+  $ volt profile build server Shougo/deoplete.nvim      # install Shougo/deoplete.nvim on "server" profile again
+
+  $ volt profile destroy foo   # will delete profile "foo"
+
+  If -dry-run global option was given (e.g. "volt -dry-run profile set foo"),
+  the changes of lock.json and ~/.vim/pack/volt are shown, and no files are
+  changed.` + "\n\n")
 		cmd.helped = true
 	}
 	return fs
 }
 
-func (cmd *profileCmd) Run(args []string) *Error {
+func (cmd *profileCmd) Run(cmdctx *CmdContext) *Error {
 	// Parse args
-	args, err := cmd.parseArgs(args)
+	args, err := cmd.parseArgs(cmdctx.Args)
 	if err == ErrShowedHelp {
 		return nil
 	}
@@ -110,14 +147,16 @@ func (cmd *profileCmd) Run(args []string) *Error {
 		return &Error{Code: 10, Msg: err.Error()}
 	}
 
+	cmd.dryRun = cmdctx.DryRun
+
 	subCmd := args[0]
 	switch subCmd {
 	case "set":
-		err = cmd.doSet(args[1:])
+		err = cmd.doSet(cmdctx.Ctx, cmdctx.Config, args[1:])
 	case "show":
-		err = cmd.doShow(args[1:])
+		err = cmd.doShow(args[1:], cmdctx.LockJSON)
 	case "list":
-		err = cmd.doList(args[1:])
+		err = cmd.doList(args[1:], cmdctx.LockJSON)
 	case "new":
 		err = cmd.doNew(args[1:])
 	case "destroy":
@@ -125,9 +164,13 @@ func (cmd *profileCmd) Run(args []string) *Error {
 	case "rename":
 		err = cmd.doRename(args[1:])
 	case "add":
-		err = cmd.doAdd(args[1:])
+		err = cmd.doAdd(cmdctx.Ctx, args[1:])
 	case "rm":
-		err = cmd.doRm(args[1:])
+		err = cmd.doRm(cmdctx.Ctx, args[1:])
+	case "no-build":
+		err = cmd.doNoBuild(cmdctx.Ctx, args[1:], true)
+	case "build":
+		err = cmd.doNoBuild(cmdctx.Ctx, args[1:], false)
 	default:
 		return &Error{Code: 11, Msg: "Unknown subcommand: " + subCmd}
 	}
@@ -161,7 +204,7 @@ func (*profileCmd) getCurrentProfile() (string, error) {
 	return lockJSON.CurrentProfileName, nil
 }
 
-func (cmd *profileCmd) doSet(args []string) (err error) {
+func (cmd *profileCmd) doSet(ctx context.Context, cfg *config.Config, args []string) (err error) {
 	// Parse args
 	createProfile := false
 	if len(args) > 0 && args[0] == "-n" {
@@ -173,7 +216,15 @@ func (cmd *profileCmd) doSet(args []string) (err error) {
 		logger.Error("'volt profile set' receives profile name.")
 		return
 	}
-	profileName := args[0]
+	profileNames := args
+	for i := range profileNames {
+		for j := 0; j < i; j++ {
+			if profileNames[i] == profileNames[j] {
+				err = errors.Errorf("profile '%s' is specified more than once", profileNames[i])
+				return
+			}
+		}
+	}
 
 	// Read lock.json
 	lockJSON, err := lockjson.Read()
@@ -182,55 +233,78 @@ func (cmd *profileCmd) doSet(args []string) (err error) {
 		return
 	}
 
-	// Exit if current profile is same as profileName
-	if lockJSON.CurrentProfileName == profileName {
-		err = errors.Errorf("'%s' is current profile", profileName)
+	// Exit if current profiles are same as profileNames
+	if strings.Join(lockJSON.CurrentProfileNames(), "\n") == strings.Join(profileNames, "\n") {
+		if len(profileNames) == 1 {
+			err = errors.Errorf("'%s' is current profile", profileNames[0])
+		} else {
+			err = errors.Errorf("'%s' are current profiles", strings.Join(profileNames, "', '"))
+		}
 		return
 	}
 
-	// Create given profile unless the profile exists
-	if _, err = lockJSON.Profiles.FindByName(profileName); err != nil {
-		if !createProfile {
-			return
-		}
-		if err = cmd.doNew([]string{profileName}); err != nil {
-			return
-		}
-		// Read lock.json again
-		lockJSON, err = lockjson.Read()
-		if err != nil {
-			err = errors.Wrap(err, "failed to read lock.json")
-			return
-		}
+	// Create given profiles unless the profiles exist
+	for _, profileName := range profileNames {
 		if _, err = lockJSON.Profiles.FindByName(profileName); err != nil {
-			return
+			if !createProfile {
+				return
+			}
+			if err = cmd.doNew([]string{profileName}); err != nil {
+				return
+			}
+			if cmd.dryRun {
+				lockJSON.Profiles = append(lockJSON.Profiles, lockjson.Profile{
+					Name:      profileName,
+					ReposPath: make([]pathutil.ReposPath, 0),
+				})
+				continue
+			}
+			// Read lock.json again
+			lockJSON, err = lockjson.Read()
+			if err != nil {
+				err = errors.Wrap(err, "failed to read lock.json")
+				return
+			}
+			if _, err = lockJSON.Profiles.FindByName(profileName); err != nil {
+				return
+			}
 		}
 	}
 
 	// Begin transaction
-	trx, err := transaction.Start()
-	if err != nil {
-		return
-	}
-	defer func() {
-		if e := trx.Done(); e != nil {
-			err = e
+	if !cmd.dryRun {
+		var trx transaction.Transaction
+		if trx, err = transaction.Start(); err != nil {
+			return
 		}
-	}()
+		defer func() {
+			if e := trx.Done(); e != nil {
+				err = e
+			}
+		}()
+	}
 
-	// Set profile name
-	lockJSON.CurrentProfileName = profileName
+	// Set profile names
+	lockJSON.CurrentProfileName = profileNames[0]
+	lockJSON.ExtraProfileNames = profileNames[1:]
 
 	// Write to lock.json
-	err = lockJSON.Write()
+	err = cmd.writeLockJSON(lockJSON)
 	if err != nil {
 		return
 	}
 
-	logger.Info("Changed current profile: " + profileName)
+	cmd.info("Changed current profile: " + strings.Join(profileNames, ", "))
+
+	// All profiles are already built and g:volt_profile selects the profile
+	// to load
+	if *cfg.Build.RuntimeProfile {
+		cmd.info("build.runtime_profile is enabled. Set g:volt_profile in vimrc to load profile: " + strings.Join(profileNames, ", "))
+		return
+	}
 
 	// Build ~/.vim/pack/volt dir
-	err = builder.Build(false)
+	err = cmd.build(ctx, lockJSON, false)
 	if err != nil {
 		err = errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
 		return
@@ -239,19 +313,13 @@ func (cmd *profileCmd) doSet(args []string) (err error) {
 	return
 }
 
-func (cmd *profileCmd) doShow(args []string) error {
+func (cmd *profileCmd) doShow(args []string, lockJSON *lockjson.LockJSON) error {
 	if len(args) == 0 {
 		cmd.FlagSet().Usage()
 		logger.Error("'volt profile show' receives profile name.")
 		return nil
 	}
 
-	// Read lock.json
-	lockJSON, err := lockjson.Read()
-	if err != nil {
-		return errors.Wrap(err, "failed to read lock.json")
-	}
-
 	var profileName string
 	if args[0] == "-current" {
 		profileName = lockJSON.CurrentProfileName
@@ -268,16 +336,22 @@ repos path:
 {{- range .ReposPath }}
   {{ . }}
 {{- end -}}
+{{- if .NoBuild }}
+no build:
+{{- range .NoBuild }}
+  {{ . }}
+{{- end -}}
+{{- end -}}
 {{- end }}
-`, profileName, profileName))
+`, profileName, profileName), lockJSON)
 }
 
-func (cmd *profileCmd) doList(args []string) error {
+func (cmd *profileCmd) doList(args []string, lockJSON *lockjson.LockJSON) error {
 	return (&listCmd{}).list(`
 {{- range .Profiles -}}
-{{- if eq .Name $.CurrentProfileName -}}*{{- else }} {{ end }} {{ .Name }}
+{{- if $.IsCurrentProfile .Name -}}*{{- else }} {{ end }} {{ .Name }}
 {{ end -}}
-`)
+`, lockJSON)
 }
 
 func (cmd *profileCmd) doNew(args []string) (err error) {
@@ -303,15 +377,17 @@ func (cmd *profileCmd) doNew(args []string) (err error) {
 	}
 
 	// Begin transaction
-	trx, err := transaction.Start()
-	if err != nil {
-		return
-	}
-	defer func() {
-		if e := trx.Done(); e != nil {
-			err = e
+	if !cmd.dryRun {
+		var trx transaction.Transaction
+		if trx, err = transaction.Start(); err != nil {
+			return
 		}
-	}()
+		defer func() {
+			if e := trx.Done(); e != nil {
+				err = e
+			}
+		}()
+	}
 
 	// Add profile
 	lockJSON.Profiles = append(lockJSON.Profiles, lockjson.Profile{
@@ -320,12 +396,12 @@ func (cmd *profileCmd) doNew(args []string) (err error) {
 	})
 
 	// Write to lock.json
-	err = lockJSON.Write()
+	err = cmd.writeLockJSON(lockJSON)
 	if err != nil {
 		return
 	}
 
-	logger.Info("Created new profile '" + profileName + "'")
+	cmd.info("Created new profile '" + profileName + "'")
 
 	return
 }
@@ -345,22 +421,24 @@ func (cmd *profileCmd) doDestroy(args []string) (err error) {
 	}
 
 	// Begin transaction
-	trx, err := transaction.Start()
-	if err != nil {
-		return
-	}
-	defer func() {
-		if e := trx.Done(); e != nil {
-			err = e
+	if !cmd.dryRun {
+		var trx transaction.Transaction
+		if trx, err = transaction.Start(); err != nil {
+			return
 		}
-	}()
+		defer func() {
+			if e := trx.Done(); e != nil {
+				err = e
+			}
+		}()
+	}
 
 	var merr *multierror.Error
 	for i := range args {
 		profileName := args[i]
 
-		// Skip if current profile matches profileName
-		if lockJSON.CurrentProfileName == profileName {
+		// Skip if current profiles contain profileName
+		if lockJSON.IsCurrentProfile(profileName) {
 			merr = multierror.Append(merr, errors.New("cannot destroy current profile: "+profileName))
 			continue
 		}
@@ -376,17 +454,21 @@ func (cmd *profileCmd) doDestroy(args []string) (err error) {
 
 		// Remove $VOLTPATH/rc/{profile} dir
 		rcDir := pathutil.RCDir(profileName)
-		os.RemoveAll(rcDir)
-		if pathutil.Exists(rcDir) {
-			err = errors.New("failed to remove " + rcDir)
-			return
+		if !cmd.dryRun {
+			os.RemoveAll(rcDir)
+			if pathutil.Exists(rcDir) {
+				err = errors.New("failed to remove " + rcDir)
+				return
+			}
+		} else if pathutil.Exists(rcDir) {
+			logDryRun("Would remove %s", rcDir)
 		}
 
-		logger.Info("Deleted profile '" + profileName + "'")
+		cmd.info("Deleted profile '" + profileName + "'")
 	}
 
 	// Write to lock.json
-	err = lockJSON.Write()
+	err = cmd.writeLockJSON(lockJSON)
 	if err != nil {
 		return
 	}
@@ -425,43 +507,52 @@ func (cmd *profileCmd) doRename(args []string) (err error) {
 	}
 
 	// Begin transaction
-	trx, err := transaction.Start()
-	if err != nil {
-		return
-	}
-	defer func() {
-		if e := trx.Done(); e != nil {
-			err = e
+	if !cmd.dryRun {
+		var trx transaction.Transaction
+		if trx, err = transaction.Start(); err != nil {
+			return
 		}
-	}()
+		defer func() {
+			if e := trx.Done(); e != nil {
+				err = e
+			}
+		}()
+	}
 
 	// Rename profile names
 	lockJSON.Profiles[index].Name = newName
 	if lockJSON.CurrentProfileName == oldName {
 		lockJSON.CurrentProfileName = newName
 	}
+	for i := range lockJSON.ExtraProfileNames {
+		if lockJSON.ExtraProfileNames[i] == oldName {
+			lockJSON.ExtraProfileNames[i] = newName
+		}
+	}
 
 	// Rename $VOLTPATH/rc/{profile} dir
 	oldRCDir := pathutil.RCDir(oldName)
 	if pathutil.Exists(oldRCDir) {
 		newRCDir := pathutil.RCDir(newName)
-		if err = os.Rename(oldRCDir, newRCDir); err != nil {
+		if cmd.dryRun {
+			logDryRun("Would rename %s to %s", oldRCDir, newRCDir)
+		} else if err = os.Rename(oldRCDir, newRCDir); err != nil {
 			return
 		}
 	}
 
 	// Write to lock.json
-	err = lockJSON.Write()
+	err = cmd.writeLockJSON(lockJSON)
 	if err != nil {
 		return
 	}
 
-	logger.Infof("Renamed profile '%s' to '%s'", oldName, newName)
+	cmd.info(fmt.Sprintf("Renamed profile '%s' to '%s'", oldName, newName))
 
 	return
 }
 
-func (cmd *profileCmd) doAdd(args []string) error {
+func (cmd *profileCmd) doAdd(ctx context.Context, args []string) error {
 	// Read lock.json
 	lockJSON, err := lockjson.Read()
 	if err != nil {
@@ -486,7 +577,7 @@ func (cmd *profileCmd) doAdd(args []string) error {
 				logger.Warn("repository '" + reposPath.String() + "' is already enabled")
 			} else {
 				profile.ReposPath = append(profile.ReposPath, reposPath)
-				logger.Info("Enabled '" + reposPath.String() + "' on profile '" + profileName + "'")
+				cmd.info("Enabled '" + reposPath.String() + "' on profile '" + profileName + "'")
 			}
 		}
 	})
@@ -495,7 +586,7 @@ func (cmd *profileCmd) doAdd(args []string) error {
 	}
 
 	// Build ~/.vim/pack/volt dir
-	err = builder.Build(false)
+	err = cmd.build(ctx, lockJSON, true)
 	if err != nil {
 		return errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
 	}
@@ -503,7 +594,7 @@ func (cmd *profileCmd) doAdd(args []string) error {
 	return nil
 }
 
-func (cmd *profileCmd) doRm(args []string) error {
+func (cmd *profileCmd) doRm(ctx context.Context, args []string) error {
 	// Read lock.json
 	lockJSON, err := lockjson.Read()
 	if err != nil {
@@ -528,7 +619,10 @@ func (cmd *profileCmd) doRm(args []string) error {
 			if index >= 0 {
 				// Remove profile.ReposPath[index]
 				profile.ReposPath = append(profile.ReposPath[:index], profile.ReposPath[index+1:]...)
-				logger.Info("Disabled '" + reposPath.String() + "' from profile '" + profileName + "'")
+				if index := profile.NoBuild.IndexOf(reposPath); index >= 0 {
+					profile.NoBuild = append(profile.NoBuild[:index], profile.NoBuild[index+1:]...)
+				}
+				cmd.info("Disabled '" + reposPath.String() + "' from profile '" + profileName + "'")
 			} else {
 				logger.Warn("repository '" + reposPath.String() + "' is already disabled")
 			}
@@ -539,7 +633,73 @@ func (cmd *profileCmd) doRm(args []string) error {
 	}
 
 	// Build ~/.vim/pack/volt dir
-	err = builder.Build(false)
+	err = cmd.build(ctx, lockJSON, true)
+	if err != nil {
+		return errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+	}
+
+	return nil
+}
+
+// doNoBuild marks (noBuild is true) or unmarks (noBuild is false) the
+// repositories of the profile as no-build.
+func (cmd *profileCmd) doNoBuild(ctx context.Context, args []string, noBuild bool) error {
+	subCmd := "build"
+	if noBuild {
+		subCmd = "no-build"
+	}
+
+	// Read lock.json
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return errors.Wrap(err, "failed to read lock.json")
+	}
+
+	// Parse args
+	profileName, reposPathList, err := cmd.parseAddArgs(lockJSON, subCmd, args)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse args")
+	}
+
+	if profileName == "-current" {
+		profileName = lockJSON.CurrentProfileName
+	}
+
+	// Validate if all repositories exist in the profile
+	profile, err := lockJSON.Profiles.FindByName(profileName)
+	if err != nil {
+		return err
+	}
+	for _, reposPath := range reposPathList {
+		if !profile.ReposPath.Contains(reposPath) {
+			return errors.New("repository '" + reposPath.String() + "' is not enabled on profile '" + profileName + "'")
+		}
+	}
+
+	// Read modified profile and write to lock.json
+	err = cmd.transactProfile(lockJSON, profileName, func(profile *lockjson.Profile) {
+		for _, reposPath := range reposPathList {
+			index := profile.NoBuild.IndexOf(reposPath)
+			switch {
+			case noBuild && index >= 0:
+				logger.Warn("repository '" + reposPath.String() + "' is already marked as no-build")
+			case noBuild:
+				profile.NoBuild = append(profile.NoBuild, reposPath)
+				cmd.info("Marked '" + reposPath.String() + "' as no-build on profile '" + profileName + "'")
+			case index >= 0:
+				profile.NoBuild = append(profile.NoBuild[:index], profile.NoBuild[index+1:]...)
+				cmd.info("Marked '" + reposPath.String() + "' as build on profile '" + profileName + "'")
+			default:
+				logger.Warn("repository '" + reposPath.String() + "' is not marked as no-build")
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Build ~/.vim/pack/volt dir
+	err = cmd.build(ctx, lockJSON, true)
 	if err != nil {
 		return errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
 	}
@@ -555,13 +715,9 @@ func (cmd *profileCmd) parseAddArgs(lockJSON *lockjson.LockJSON, subCmd string,
 	}
 
 	profileName := args[0]
-	reposPathList := make([]pathutil.ReposPath, 0, len(args)-1)
-	for _, arg := range args[1:] {
-		reposPath, err := pathutil.NormalizeRepos(arg)
-		if err != nil {
-			return "", nil, err
-		}
-		reposPathList = append(reposPathList, reposPath)
+	reposPathList, err := resolveReposPathList(args[1:], lockJSON)
+	if err != nil {
+		return "", nil, err
 	}
 
 	// Validate if all repositories exist in repos[]
@@ -575,7 +731,7 @@ func (cmd *profileCmd) parseAddArgs(lockJSON *lockjson.LockJSON, subCmd string,
 }
 
 // Run modifyProfile and write modified structure to lock.json
-func (*profileCmd) transactProfile(lockJSON *lockjson.LockJSON, profileName string, modifyProfile func(*lockjson.Profile)) (err error) {
+func (cmd *profileCmd) transactProfile(lockJSON *lockjson.LockJSON, profileName string, modifyProfile func(*lockjson.Profile)) (err error) {
 	// Return error if profiles[]/name does not match profileName
 	profile, err := lockJSON.Profiles.FindByName(profileName)
 	if err != nil {
@@ -583,22 +739,57 @@ func (*profileCmd) transactProfile(lockJSON *lockjson.LockJSON, profileName stri
 	}
 
 	// Begin transaction
-	trx, err := transaction.Start()
-	if err != nil {
-		return
-	}
-	defer func() {
-		if e := trx.Done(); e != nil {
-			err = e
+	if !cmd.dryRun {
+		var trx transaction.Transaction
+		if trx, err = transaction.Start(); err != nil {
+			return
 		}
-	}()
+		defer func() {
+			if e := trx.Done(); e != nil {
+				err = e
+			}
+		}()
+	}
 
 	modifyProfile(profile)
 
 	// Write to lock.json
-	err = lockJSON.Write()
+	err = cmd.writeLockJSON(lockJSON)
 	if err != nil {
 		return
 	}
 	return
 }
+
+// writeLockJSON writes lockJSON to lock.json, or shows that it would be
+// written if "volt -dry-run" was given.
+func (cmd *profileCmd) writeLockJSON(lockJSON *lockjson.LockJSON) error {
+	if cmd.dryRun {
+		dryRunWriteLockJSON()
+		return nil
+	}
+	return lockJSON.Write()
+}
+
+// build builds ~/.vim/pack/volt dir for lockJSON. If delta is true, only the
+// repositories added to or removed from the profiles are installed or
+// uninstalled if possible (see builder.BuildDelta()).
+func (cmd *profileCmd) build(ctx context.Context, lockJSON *lockjson.LockJSON, delta bool) error {
+	if cmd.dryRun {
+		return dryRunBuild(lockJSON, false, delta)
+	}
+	if delta {
+		return builder.BuildDelta(ctx)
+	}
+	return builder.Build(ctx, false)
+}
+
+// info shows msg of a change, which is marked by "(dry-run)" if the change is
+// not made because "volt -dry-run" was given.
+func (cmd *profileCmd) info(msg string) {
+	if cmd.dryRun {
+		logDryRun("%s", msg)
+		return
+	}
+	logger.Info(msg)
+}
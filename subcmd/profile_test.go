@@ -23,6 +23,7 @@ import (
 // * Run `volt profile set <profile>` (`<profile>` is not current profile) (A, B, a, b)
 // * Run `volt profile set <profile>` (`<profile>` is current profile) (!A, !B, !a)
 // * Run `volt profile set -n <profile>` (`<profile>` is not current profile and non-existing profile) (A, B, a)
+// * Run `volt profile set -n <profile1> <profile2>` (`<profile1>` is non-existing profile) (A, B, a, b)
 func TestVoltProfileSet(t *testing.T) {
 	t.Run("Run `volt profile set <profile>` (`<profile>` is not current profile)", func(t *testing.T) {
 		testProfileMatrix(t, func(t *testing.T, strategy string) {
@@ -120,6 +121,46 @@ func TestVoltProfileSet(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("Run `volt profile set -n <profile1> <profile2>` (`<profile1>` is non-existing profile)", func(t *testing.T) {
+		testProfileMatrix(t, func(t *testing.T, strategy string) {
+			// =============== setup =============== //
+
+			testutil.SetUpEnv(t)
+			defer testutil.CleanUpEnv(t)
+
+			reposPathList := []pathutil.ReposPath{pathutil.ReposPath("github.com/tyru/caw.vim")}
+			teardown := testutil.SetUpRepos(t, "caw.vim", lockjson.ReposGitType, reposPathList, strategy)
+			defer teardown()
+			testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+
+			// =============== run =============== //
+
+			out, err := testutil.RunVolt("profile", "set", "-n", "foo", "default")
+			// (A, B)
+			testutil.SuccessExit(t, out, err)
+
+			// (a)
+			lockJSON, err := lockjson.Read()
+			if err != nil {
+				t.Error("lockjson.Read() returned non-nil error: " + err.Error())
+			}
+			if lockJSON.CurrentProfileName != "foo" {
+				t.Errorf("expected: %s, got: %s", "foo", lockJSON.CurrentProfileName)
+			}
+			if len(lockJSON.ExtraProfileNames) != 1 || lockJSON.ExtraProfileNames[0] != "default" {
+				t.Errorf("expected: %v, got: %v", []string{"default"}, lockJSON.ExtraProfileNames)
+			}
+
+			// (b) Plugins of "default" profile are also installed
+			for _, reposPath := range reposPathList {
+				vimReposDir := reposPath.EncodeToPlugDirName()
+				if !pathutil.Exists(vimReposDir) {
+					t.Error("vim repos does not exist: " + vimReposDir)
+				}
+			}
+		})
+	})
 }
 
 // Checks:
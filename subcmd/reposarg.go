@@ -0,0 +1,95 @@
+package subcmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// resolveReposPathList converts repository arguments into repos paths.
+// Each argument is a repos path accepted by pathutil.NormalizeRepos, or a
+// plugin name (e.g. "caw.vim") which is resolved against lock.json.
+// See resolveReposPath() for details.
+func resolveReposPathList(args []string, lockJSON *lockjson.LockJSON) (pathutil.ReposPathList, error) {
+	reposPathList := make(pathutil.ReposPathList, 0, len(args))
+	for _, arg := range args {
+		reposPath, err := resolveReposPath(arg, lockJSON)
+		if err != nil {
+			return nil, err
+		}
+		reposPathList = append(reposPathList, reposPath)
+	}
+	return reposPathList, nil
+}
+
+// resolveReposPath returns the repos path of arg.
+// If arg is a repos path installed in lock.json, it is returned as-is.
+// Otherwise, arg is looked up as a plugin name or a trailing part of repos
+// path (e.g. "tyru/caw.vim" matches "gitlab.com/tyru/caw.vim").
+// If it matches multiple repositories, a user is asked to choose one when stdin
+// is a terminal. Aliases of repos.alias in config.toml are not looked up.
+func resolveReposPath(arg string, lockJSON *lockjson.LockJSON) (pathutil.ReposPath, error) {
+	reposPath, normErr := pathutil.NormalizeRepos(arg)
+	if lockJSON == nil || normErr == nil && lockJSON.Repos.Contains(reposPath) {
+		return reposPath, normErr
+	}
+	// arg is an alias of repos.alias in config.toml
+	if normErr == nil && !strings.Contains(filepath.ToSlash(arg), "/") {
+		return reposPath, nil
+	}
+
+	candidates := lockJSON.Repos.FindAllByName(arg)
+	switch len(candidates) {
+	case 0:
+		if normErr == nil {
+			// Let the caller report the repository is not installed
+			return reposPath, nil
+		}
+		return "", errors.New("no repository matched with '" + arg + "'")
+	case 1:
+		return candidates[0].Path, nil
+	}
+
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return "", ambiguousReposError(arg, candidates)
+	}
+	return chooseRepos(arg, candidates, os.Stdin, os.Stdout)
+}
+
+// chooseRepos shows candidates and reads the number of chosen one from in.
+func chooseRepos(arg string, candidates []lockjson.Repos, in io.Reader, out io.Writer) (pathutil.ReposPath, error) {
+	fmt.Fprintf(out, "'%s' matched multiple repositories:\n", arg)
+	for i := range candidates {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, candidates[i].Path)
+	}
+	fmt.Fprintf(out, "Choose a repository [1-%d]: ", len(candidates))
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", errors.Wrap(err, "could not read the answer")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(candidates) {
+		return "", ambiguousReposError(arg, candidates)
+	}
+	return candidates[n-1].Path, nil
+}
+
+func ambiguousReposError(arg string, candidates []lockjson.Repos) error {
+	paths := make([]string, 0, len(candidates))
+	for i := range candidates {
+		paths = append(paths, candidates[i].Path.String())
+	}
+	return errors.Errorf("'%s' is ambiguous, specify one of: %s",
+		arg, strings.Join(paths, ", "))
+}
@@ -0,0 +1,101 @@
+package subcmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestResolveReposPath(t *testing.T) {
+	lockJSON := &lockjson.LockJSON{
+		Repos: lockjson.ReposList{
+			{Path: pathutil.ReposPath("github.com/tyru/caw.vim")},
+			{Path: pathutil.ReposPath("github.com/tyru/open-browser.vim")},
+			{Path: pathutil.ReposPath("github.com/tyru/open-browser-github.vim")},
+			{Path: pathutil.ReposPath("gitlab.com/foo/bar.vim")},
+			{Path: pathutil.ReposPath("github.com/foo/bar.vim")},
+		},
+	}
+
+	for _, tt := range []struct {
+		arg     string
+		want    string
+		wantErr bool
+	}{
+		{arg: "tyru/caw.vim", want: "github.com/tyru/caw.vim"},
+		{arg: "caw.vim", want: "github.com/tyru/caw.vim"},
+		{arg: "CaW.vim", want: "github.com/tyru/caw.vim"},
+		{arg: "caw", want: "github.com/tyru/caw.vim"},
+		{arg: "open-browser.vim", want: "github.com/tyru/open-browser.vim"},
+		{arg: "open-browser", wantErr: true},
+		{arg: "bar.vim", wantErr: true},
+		{arg: "foo/bar.vim", want: "github.com/foo/bar.vim"},
+		{arg: "gitlab.com/foo/bar.vim", want: "gitlab.com/foo/bar.vim"},
+		{arg: "tyru/not-installed.vim", want: "github.com/tyru/not-installed.vim"},
+		{arg: "not-installed.vim", wantErr: true},
+	} {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := resolveReposPath(tt.arg, lockJSON)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("expected %q but got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChooseRepos(t *testing.T) {
+	candidates := []lockjson.Repos{
+		{Path: pathutil.ReposPath("github.com/foo/bar.vim")},
+		{Path: pathutil.ReposPath("gitlab.com/foo/bar.vim")},
+	}
+
+	var out bytes.Buffer
+	got, err := chooseRepos("bar.vim", candidates, strings.NewReader("2\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != candidates[1].Path {
+		t.Errorf("expected %q but got %q", candidates[1].Path, got)
+	}
+	if !strings.Contains(out.String(), "  1) github.com/foo/bar.vim\n") {
+		t.Errorf("candidates were not shown: %q", out.String())
+	}
+
+	for _, answer := range []string{"", "0", "3", "foo\n"} {
+		_, err := chooseRepos("bar.vim", candidates, strings.NewReader(answer), &out)
+		if err == nil {
+			t.Errorf("expected error for answer %q", answer)
+		}
+	}
+}
+
+func TestResolveReposPathAlias(t *testing.T) {
+	pathutil.SetReposAliases(map[string]string{"fzf": "junegunn/fzf.vim"})
+	defer pathutil.SetReposAliases(nil)
+
+	lockJSON := &lockjson.LockJSON{
+		Repos: lockjson.ReposList{
+			{Path: pathutil.ReposPath("github.com/foo/fzf-preview.vim")},
+		},
+	}
+	// The alias wins over plugin name matching even if it is not installed
+	got, err := resolveReposPath("fzf", lockJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "github.com/junegunn/fzf.vim"; got.String() != want {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+}
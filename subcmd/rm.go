@@ -1,12 +1,16 @@
 package subcmd
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/mattn/go-isatty"
 	"github.com/pkg/errors"
 
 	"github.com/vim-volt/volt/fileutil"
@@ -26,6 +30,21 @@ type rmCmd struct {
 	helped     bool
 	rmRepos    bool
 	rmPlugconf bool
+	noDeps     bool
+	keepRepos  bool
+	dryRun     bool
+	// trx is the transaction of the removal (nil if dry-run)
+	trx transaction.Transaction
+	// removed is the result of "volt -output json rm"
+	removed []rmResult
+}
+
+// rmResult is a repository removed by "volt rm".
+type rmResult struct {
+	Repository pathutil.ReposPath `json:"repository"`
+	// Files are the repository directory and the plugconf file removed by -r
+	// and -p options
+	Files []string `json:"files"`
 }
 
 func (cmd *rmCmd) ProhibitRootExecution(args []string) bool { return true }
@@ -36,13 +55,14 @@ func (cmd *rmCmd) FlagSet() *flag.FlagSet {
 	fs.Usage = func() {
 		fmt.Print(`
 Usage
-  volt rm [-help] [-r] [-p] {repository} [{repository2} ...]
+  volt rm [-help] [-r] [-p] [-no-deps] [-keep-repos] {repository} [{repository2} ...]
 
 Quick example
   $ volt rm tyru/caw.vim    # Remove tyru/caw.vim plugin from lock.json
   $ volt rm -r tyru/caw.vim # Remove tyru/caw.vim plugin from lock.json, and remove repository directory
   $ volt rm -p tyru/caw.vim # Remove tyru/caw.vim plugin from lock.json, and remove plugconf
   $ volt rm -r -p tyru/caw.vim # Remove tyru/caw.vim plugin from lock.json, and remove repository directory, plugconf
+  $ volt rm -keep-repos tyru/caw.vim # Remove tyru/caw.vim plugin from lock.json, and stop managing repository directory
 
 Description
   Uninstall one or more {repository} from every profile.
@@ -52,7 +72,28 @@ Description
   If -r option was given, remove also repository directories of specified repositories.
   If -p option was given, remove also plugconf files of specified repositories.
 
-  {repository} is treated as same format as "volt get" (see "volt get -help").` + "\n\n")
+  If -keep-repos option was given, the repository directories are kept in
+  $VOLTPATH/repos and volt stops managing them: they are not reported as
+  "not in lock.json" by "volt status" and "volt verify-lock", and not removed
+  by "volt sync -prune". This is useful to keep a local clone for development.
+  (Without -r option, the repository directories are kept as well, but they
+  are reported and pruned.) "volt get {repository}" manages it again.
+
+  The plugins in s:depends() of the plugconfs of {repository} list which are
+  no longer depended by any plugin are removed too after asking (with -r and
+  -p options as well). If stdin is not a terminal, or -no-deps option was
+  given, they are not removed.
+
+  {repository} is treated as same format as "volt get" (see "volt get -help").
+  {repository} can also be a plugin name (e.g. "caw.vim") of installed
+  repositories. If it matches multiple repositories, you are asked to choose one.
+
+  If volt is interrupted or fails (e.g. to build ~/.vim/pack/volt), the
+  removed directories and files, lock.json, and ~/.vim/pack/volt are restored.
+
+  If -dry-run global option was given ("volt -dry-run rm"), the directories
+  and files which would be removed, and the changes of ~/.vim/pack/volt are
+  shown, and no files are changed.` + "\n\n")
 		//fmt.Println("Options")
 		//fs.PrintDefaults()
 		fmt.Println()
@@ -60,25 +101,66 @@ Description
 	}
 	fs.BoolVar(&cmd.rmRepos, "r", false, "remove also repository directories")
 	fs.BoolVar(&cmd.rmPlugconf, "p", false, "remove also plugconf files")
+	fs.BoolVar(&cmd.noDeps, "no-deps", false, "do not remove the dependencies which are no longer used")
+	fs.BoolVar(&cmd.keepRepos, "keep-repos", false, "keep repository directories, and stop managing them")
 	return fs
 }
 
-func (cmd *rmCmd) Run(args []string) *Error {
-	reposPathList, err := cmd.parseArgs(args)
+func (cmd *rmCmd) Run(cmdctx *CmdContext) (result *Error) {
+	reposPathList, err := cmd.parseArgs(cmdctx.Args, cmdctx.LockJSON)
 	if err == ErrShowedHelp {
 		return nil
 	}
 	if err != nil {
 		return &Error{Code: 10, Msg: err.Error()}
 	}
+	cmd.dryRun = cmdctx.DryRun
+	defer func() { cmdctx.Result = cmd.removed }()
 
-	err = cmd.doRemove(reposPathList)
-	if err != nil {
-		return &Error{Code: 11, Msg: "Failed to remove repository: " + err.Error()}
+	// Begin transaction
+	// If failed or interrupted, restore removed files, lock.json, and
+	// ~/.vim/pack/volt
+	if !cmd.dryRun {
+		if cmd.trx, err = transaction.Start(); err != nil {
+			return &Error{Code: 11, Msg: "Failed to begin transaction: " + err.Error()}
+		}
+		defer func() {
+			if result != nil {
+				if err := cmd.trx.Rollback(); err != nil {
+					logger.Error("Failed to rollback: " + err.Error())
+				}
+				return
+			}
+			if err := cmd.trx.Done(); err != nil {
+				result = &Error{Code: 11, Msg: "Failed to end transaction: " + err.Error()}
+			}
+		}()
+	}
+
+	for len(reposPathList) > 0 {
+		var depends pathutil.ReposPathList
+		if !cmd.noDeps {
+			if depends, err = cmd.dependsOf(reposPathList, cmdctx.LockJSON); err != nil {
+				return &Error{Code: 11, Msg: "Failed to read plugconf: " + err.Error()}
+			}
+		}
+		err = cmd.doRemove(reposPathList, cmdctx.LockJSON)
+		if err != nil {
+			return &Error{Code: 11, Msg: "Failed to remove repository: " + err.Error()}
+		}
+		// Remove the dependencies which are no longer used
+		reposPathList, err = cmd.askUnusedDepends(depends, cmdctx.LockJSON)
+		if err != nil {
+			return &Error{Code: 11, Msg: "Failed to remove dependencies: " + err.Error()}
+		}
 	}
 
 	// Build opt dir
-	err = builder.Build(false)
+	if cmd.dryRun {
+		err = dryRunBuild(cmdctx.LockJSON, false, false)
+	} else {
+		err = builder.Build(cmdctx.Ctx, false)
+	}
 	if err != nil {
 		return &Error{Code: 12, Msg: "Could not build " + pathutil.VimVoltDir() + ": " + err.Error()}
 	}
@@ -86,7 +168,7 @@ func (cmd *rmCmd) Run(args []string) *Error {
 	return nil
 }
 
-func (cmd *rmCmd) parseArgs(args []string) ([]pathutil.ReposPath, error) {
+func (cmd *rmCmd) parseArgs(args []string, lockJSON *lockjson.LockJSON) ([]pathutil.ReposPath, error) {
 	fs := cmd.FlagSet()
 	fs.Parse(args)
 	if cmd.helped {
@@ -97,36 +179,14 @@ func (cmd *rmCmd) parseArgs(args []string) ([]pathutil.ReposPath, error) {
 		fs.Usage()
 		return nil, errors.New("repository was not given")
 	}
-
-	var reposPathList []pathutil.ReposPath
-	for _, arg := range fs.Args() {
-		reposPath, err := pathutil.NormalizeRepos(arg)
-		if err != nil {
-			return nil, err
-		}
-		reposPathList = append(reposPathList, reposPath)
-	}
-	return reposPathList, nil
-}
-
-func (cmd *rmCmd) doRemove(reposPathList []pathutil.ReposPath) (err error) {
-	// Read lock.json
-	lockJSON, err := lockjson.Read()
-	if err != nil {
-		return
+	if cmd.keepRepos && cmd.rmRepos {
+		return nil, errors.New("-keep-repos and -r cannot be given at the same time")
 	}
 
-	// Begin transaction
-	trx, err := transaction.Start()
-	if err != nil {
-		return
-	}
-	defer func() {
-		if e := trx.Done(); e != nil {
-			err = e
-		}
-	}()
+	return resolveReposPathList(fs.Args(), lockJSON)
+}
 
+func (cmd *rmCmd) doRemove(reposPathList []pathutil.ReposPath, lockJSON *lockjson.LockJSON) (err error) {
 	// Get the existing entries if already have it
 	// (e.g. github.com/tyru/CaW.vim -> github.com/tyru/caw.vim)
 	for i := range reposPathList {
@@ -154,6 +214,8 @@ func (cmd *rmCmd) doRemove(reposPathList []pathutil.ReposPath) (err error) {
 
 	removeCount := 0
 	for _, reposPath := range reposPathList {
+		result := rmResult{Repository: reposPath, Files: make([]string, 0, 2)}
+		prevCount := removeCount
 		// Remove repository directory
 		if cmd.rmRepos {
 			fullReposPath := reposPath.FullPath()
@@ -161,6 +223,7 @@ func (cmd *rmCmd) doRemove(reposPathList []pathutil.ReposPath) (err error) {
 				if err = cmd.removeRepos(fullReposPath); err != nil {
 					return
 				}
+				result.Files = append(result.Files, fullReposPath)
 				removeCount++
 			} else {
 				logger.Debugf("No repository was installed for '%s' ... skip.", reposPath)
@@ -174,6 +237,7 @@ func (cmd *rmCmd) doRemove(reposPathList []pathutil.ReposPath) (err error) {
 				if err = cmd.removePlugconf(plugconfPath); err != nil {
 					return
 				}
+				result.Files = append(result.Files, plugconfPath)
 				removeCount++
 			} else {
 				logger.Debugf("No plugconf was installed for '%s' ... skip.", reposPath)
@@ -186,6 +250,9 @@ func (cmd *rmCmd) doRemove(reposPathList []pathutil.ReposPath) (err error) {
 		if err == nil || err2 == nil {
 			removeCount++
 		}
+		if removeCount > prevCount {
+			cmd.removed = append(cmd.removed, result)
+		}
 	}
 	if removeCount == 0 {
 		err = errors.New("no plugins are removed")
@@ -193,14 +260,175 @@ func (cmd *rmCmd) doRemove(reposPathList []pathutil.ReposPath) (err error) {
 	}
 
 	// Write to lock.json
-	err = lockJSON.Write()
+	if cmd.dryRun {
+		dryRunWriteLockJSON()
+		if cmd.keepRepos {
+			logDryRun("Would write %s", pathutil.KeptReposFile())
+		}
+		return
+	}
+	if err = lockJSON.Write(); err != nil {
+		return
+	}
+	err = cmd.updateKeptRepos(reposPathList)
 	return
 }
 
+// updateKeptRepos adds the existing directories of reposPathList to
+// pathutil.KeptReposFile() if -keep-repos was given, otherwise removes
+// reposPathList from it (they were managed again by "volt get").
+func (cmd *rmCmd) updateKeptRepos(reposPathList []pathutil.ReposPath) error {
+	kept, err := readKeptRepos()
+	if err != nil {
+		return err
+	}
+	var newKept pathutil.ReposPathList
+	for _, reposPath := range kept {
+		if !pathutil.ReposPathList(reposPathList).Contains(reposPath) {
+			newKept = append(newKept, reposPath)
+		}
+	}
+	changed := len(newKept) != len(kept)
+	if cmd.keepRepos {
+		for _, reposPath := range reposPathList {
+			if pathutil.Exists(reposPath.FullPath()) {
+				logger.Infof("Keeping %s (volt no longer manages it)", reposPath.FullPath())
+				newKept = append(newKept, reposPath)
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writeKeptRepos(newKept)
+}
+
+// readKeptRepos returns the repositories removed by "volt rm -keep-repos".
+func readKeptRepos() (pathutil.ReposPathList, error) {
+	content, err := ioutil.ReadFile(pathutil.KeptReposFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var kept pathutil.ReposPathList
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, pathutil.ReposPath(line))
+		}
+	}
+	return kept, nil
+}
+
+// writeKeptRepos writes kept to pathutil.KeptReposFile(), or removes it if
+// kept is empty.
+func writeKeptRepos(kept pathutil.ReposPathList) error {
+	if len(kept) == 0 {
+		return transaction.RemoveAll(pathutil.KeptReposFile())
+	}
+	content := strings.Join(kept.Strings(), "\n") + "\n"
+	return transaction.WriteFile(pathutil.KeptReposFile(), []byte(content), 0644)
+}
+
+// dependsOf returns the plugins in s:depends() of the plugconfs of
+// reposPathList. It must be called before the plugconfs are removed.
+func (*rmCmd) dependsOf(reposPathList []pathutil.ReposPath, lockJSON *lockjson.LockJSON) (pathutil.ReposPathList, error) {
+	var depends pathutil.ReposPathList
+	for i := range reposPathList {
+		reposPath := reposPathList[i]
+		if r := lockJSON.Repos.FindByPath(reposPath); r != nil {
+			reposPath = r.Path
+		}
+		deps, err := plugconf.DependsOf(reposPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			if !depends.Contains(dep) {
+				depends = append(depends, dep)
+			}
+		}
+	}
+	return depends, nil
+}
+
+// askUnusedDepends asks whether to remove each plugin of depends which is in
+// lock.json and no longer depended by any plugin, and returns the plugins to
+// be removed.
+func (cmd *rmCmd) askUnusedDepends(depends pathutil.ReposPathList, lockJSON *lockjson.LockJSON) ([]pathutil.ReposPath, error) {
+	var unused []pathutil.ReposPath
+	for _, dep := range depends {
+		repos := lockJSON.Repos.FindByPath(dep)
+		if repos == nil {
+			continue
+		}
+		rdeps, err := plugconf.RdepsOf(repos.Path, lockJSON.Repos)
+		if err != nil {
+			return nil, err
+		}
+		if len(rdeps) == 0 {
+			unused = append(unused, repos.Path)
+		}
+	}
+	if len(unused) == 0 {
+		return nil, nil
+	}
+	if cmd.dryRun {
+		for _, reposPath := range unused {
+			logDryRun("Would ask whether to remove %s which is no longer depended by any plugin", reposPath)
+		}
+		return nil, nil
+	}
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		for _, reposPath := range unused {
+			logger.Infof("%s is no longer depended by any plugin (remove it by \"volt rm %s\")", reposPath, reposPath)
+		}
+		return nil, nil
+	}
+	in := bufio.NewReader(os.Stdin)
+	var result []pathutil.ReposPath
+	for _, reposPath := range unused {
+		yes, err := askRemoveDepends(reposPath, in, os.Stdout)
+		if err != nil {
+			return nil, err
+		}
+		if yes {
+			result = append(result, reposPath)
+		}
+	}
+	return result, nil
+}
+
+// askRemoveDepends asks whether to remove reposPath which is no longer
+// depended by any plugin.
+func askRemoveDepends(reposPath pathutil.ReposPath, in *bufio.Reader, out io.Writer) (bool, error) {
+	for {
+		fmt.Fprintf(out, "%s is no longer depended by any plugin. Remove it? [y/N]: ", reposPath)
+		line, err := in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return false, errors.Wrap(err, "could not read the answer")
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no", "":
+			return false, nil
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+	}
+}
+
 // Remove repository directory
 func (cmd *rmCmd) removeRepos(fullReposPath string) error {
+	if cmd.dryRun {
+		logDryRun("Would remove %s", fullReposPath)
+		return nil
+	}
 	logger.Info("Removing " + fullReposPath + " ...")
-	if err := os.RemoveAll(fullReposPath); err != nil {
+	if err := cmd.trx.Remove(fullReposPath); err != nil {
 		return err
 	}
 	fileutil.RemoveDirs(filepath.Dir(fullReposPath))
@@ -208,9 +436,13 @@ func (cmd *rmCmd) removeRepos(fullReposPath string) error {
 }
 
 // Remove plugconf file
-func (*rmCmd) removePlugconf(plugconfPath string) error {
+func (cmd *rmCmd) removePlugconf(plugconfPath string) error {
+	if cmd.dryRun {
+		logDryRun("Would remove %s", plugconfPath)
+		return nil
+	}
 	logger.Info("Removing plugconf files ...")
-	if err := os.Remove(plugconfPath); err != nil {
+	if err := cmd.trx.Remove(plugconfPath); err != nil {
 		return err
 	}
 	// Remove parent directories of plugconf
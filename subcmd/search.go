@@ -0,0 +1,397 @@
+package subcmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/httputil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/metadata"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func init() {
+	cmdMap["search"] = &searchCmd{}
+}
+
+// vimAwesomeURL is the base URL of Vim Awesome API (overwritten in tests).
+var vimAwesomeURL = "https://vimawesome.com"
+
+type searchCmd struct {
+	helped     bool
+	num        int
+	vimAwesome bool
+	category   string
+	install    bool
+}
+
+// searchResult is a plugin found by "volt search".
+type searchResult struct {
+	Repository  pathutil.ReposPath `json:"repository"`
+	Stars       int                `json:"stars"`
+	Description string             `json:"description"`
+	// Users is the number of users on Vim Awesome (counted from the dotfiles
+	// on GitHub)
+	Users int `json:"users,omitempty"`
+	// Category is the category on Vim Awesome (e.g. "language")
+	Category string `json:"category,omitempty"`
+	// Installed is true if the repository is in lock.json
+	Installed bool `json:"installed"`
+}
+
+func (cmd *searchCmd) ProhibitRootExecution(args []string) bool {
+	for _, arg := range args {
+		if arg == "-install" || arg == "--install" {
+			return true
+		}
+	}
+	return false
+}
+
+func (cmd *searchCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt search [-help] [-n {N}] [-vimawesome] [-category {category}] [-install] {keyword} [{keyword2} ...]
+
+Quick example
+  $ volt search comment
+    1) github.com/tomtom/tcomment_vim (1300 stars)
+       An extensible & universal comment vim-plugin that also handles embedded filetypes
+    2) github.com/tyru/caw.vim (200 stars) [installed]
+       Vim comment plugin: supported operator/non-operator mappings, repeatable by dot-command, 300+ filetypes
+  $ volt search -vimawesome comment
+    1) github.com/tpope/vim-commentary (5000 stars, 8000 users, other)
+       Comment stuff out.
+    ...
+  $ volt search -category language typescript
+  $ volt search -install comment  # choose plugins to install
+
+Description
+  Search GitHub for Vim script repositories matched with {keyword} list, and
+  show them in order of stars. The repositories in lock.json are marked as
+  "[installed]".
+
+  If -vimawesome option was given, Vim Awesome (https://vimawesome.com) is
+  also searched, and the results are merged. They are shown in order of the
+  users on Vim Awesome (counted from dotfiles on GitHub), and then stars, with
+  the category of Vim Awesome (e.g. "language", "completion"). The plugins on
+  Vim Awesome which are not on GitHub are not shown.
+  The results of Vim Awesome are cached for 24 hours in
+  $VOLTPATH/metadata/vimawesome.com/ .
+
+  If -category option was given, only the plugins of the category on Vim
+  Awesome are shown (-vimawesome is implied).
+
+  If -install option was given, you are asked the numbers of the plugins to
+  install (e.g. "1 3"), and "volt get" installs them. Nothing is installed if
+  the answer is empty.
+
+  If $GITHUB_TOKEN is set, it is used to authenticate the requests to GitHub
+  API, which raises the rate limit.` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.IntVar(&cmd.num, "n", 10, "the maximum number of results")
+	fs.BoolVar(&cmd.vimAwesome, "vimawesome", false, "search also Vim Awesome")
+	fs.StringVar(&cmd.category, "category", "", "show only the plugins of the category on Vim Awesome")
+	fs.BoolVar(&cmd.install, "install", false, "ask which plugins to install, and install them")
+	return fs
+}
+
+func (cmd *searchCmd) Run(cmdctx *CmdContext) *Error {
+	keywords, err := cmd.parseArgs(cmdctx.Args)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
+	}
+
+	results, err := cmd.search(cmdctx.Ctx, strings.Join(keywords, " "), cmdctx.LockJSON)
+	if err != nil {
+		return &Error{Code: 11, Msg: "Failed to search: " + err.Error()}
+	}
+	cmdctx.Result = results
+	if len(results) == 0 {
+		logger.Info("No plugins matched with " + strings.Join(keywords, " "))
+		return nil
+	}
+	showSearchResults(os.Stdout, results)
+
+	if !cmd.install {
+		return nil
+	}
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return &Error{Code: 12, Msg: "-install needs a terminal to choose plugins (run \"volt get {repository}\" instead)"}
+	}
+	chosen, err := chooseSearchResults(results, os.Stdin, os.Stdout)
+	if err != nil {
+		return &Error{Code: 12, Msg: err.Error()}
+	}
+	if len(chosen) == 0 {
+		return nil
+	}
+	return Exec(cmdctx.Ctx, "get", chosen)
+}
+
+func (cmd *searchCmd) parseArgs(args []string) ([]string, error) {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, ErrShowedHelp
+	}
+	if len(fs.Args()) == 0 {
+		fs.Usage()
+		return nil, errors.New("keyword was not given")
+	}
+	if cmd.num < 1 || cmd.num > 100 {
+		return nil, errors.New("-n must be between 1 and 100")
+	}
+	return fs.Args(), nil
+}
+
+// search searches query on GitHub (and Vim Awesome if -vimawesome was given),
+// and returns at most cmd.num results in order of users on Vim Awesome and
+// stars.
+func (cmd *searchCmd) search(ctx context.Context, query string, lockJSON *lockjson.LockJSON) ([]searchResult, error) {
+	results, err := searchGitHub(ctx, query, cmd.num)
+	if err != nil {
+		return nil, errors.Wrap(err, "GitHub")
+	}
+	if cmd.vimAwesome || cmd.category != "" {
+		more, err := searchVimAwesome(ctx, query)
+		if err != nil {
+			return nil, errors.Wrap(err, "Vim Awesome")
+		}
+		results = mergeSearchResults(results, more)
+	}
+	if cmd.category != "" {
+		filtered := make([]searchResult, 0, len(results))
+		for i := range results {
+			if strings.EqualFold(results[i].Category, cmd.category) {
+				filtered = append(filtered, results[i])
+			}
+		}
+		results = filtered
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Users != results[j].Users {
+			return results[i].Users > results[j].Users
+		}
+		return results[i].Stars > results[j].Stars
+	})
+	if len(results) > cmd.num {
+		results = results[:cmd.num]
+	}
+	for i := range results {
+		results[i].Installed = lockJSON.Repos.FindByPath(results[i].Repository) != nil
+	}
+	return results, nil
+}
+
+// searchGitHub searches Vim script repositories of GitHub.
+func searchGitHub(ctx context.Context, query string, num int) ([]searchResult, error) {
+	q := url.Values{}
+	q.Set("q", query+` language:"Vim Script"`)
+	q.Set("sort", "stars")
+	q.Set("per_page", strconv.Itoa(num))
+	header := make(http.Header)
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		header.Set("Authorization", "token "+token)
+	}
+	r, err := httputil.GetContentReaderWithHeader(ctx, githubAPIURL+"/search/repositories?"+q.Encode(), header)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var res struct {
+		Items []struct {
+			FullName    string `json:"full_name"`
+			Stars       int    `json:"stargazers_count"`
+			Description string `json:"description"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		return nil, err
+	}
+	results := make([]searchResult, 0, len(res.Items))
+	for _, item := range res.Items {
+		results = append(results, searchResult{
+			Repository:  pathutil.ReposPath("github.com/" + item.FullName),
+			Stars:       item.Stars,
+			Description: item.Description,
+		})
+	}
+	return results, nil
+}
+
+// vimAwesomeCache is the cache file of the search results of Vim Awesome.
+type vimAwesomeCache struct {
+	Results   []searchResult `json:"results"`
+	FetchedAt time.Time      `json:"fetched_at"`
+}
+
+// searchVimAwesome searches Vim Awesome (the first page of the results).
+// The cached results at pathutil.VimAwesomeCache(query) are returned if they
+// were fetched within metadata.TTL. If fetching failed, the expired cache is
+// returned if exists.
+func searchVimAwesome(ctx context.Context, query string) ([]searchResult, error) {
+	cacheFile := pathutil.VimAwesomeCache(query)
+	var cache vimAwesomeCache
+	cacheErr := readVimAwesomeCache(cacheFile, &cache)
+	if cacheErr == nil && time.Since(cache.FetchedAt) < metadata.TTL {
+		return cache.Results, nil
+	}
+	results, err := fetchVimAwesome(ctx, query)
+	if err != nil {
+		if cacheErr == nil {
+			logger.Warn("Could not search Vim Awesome, using the cached results: " + err.Error())
+			return cache.Results, nil
+		}
+		return nil, err
+	}
+	cache = vimAwesomeCache{Results: results, FetchedAt: time.Now()}
+	if err := writeVimAwesomeCache(cacheFile, &cache); err != nil {
+		logger.Warn("Could not write cache of Vim Awesome: " + err.Error())
+	}
+	return results, nil
+}
+
+func fetchVimAwesome(ctx context.Context, query string) ([]searchResult, error) {
+	q := url.Values{}
+	q.Set("query", query)
+	q.Set("page", "1")
+	r, err := httputil.GetContentReader(ctx, vimAwesomeURL+"/api/plugins?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var res struct {
+		Plugins []struct {
+			Owner       string `json:"github_owner"`
+			Name        string `json:"github_repo_name"`
+			Stars       int    `json:"github_stars"`
+			Description string `json:"short_desc"`
+			Users       int    `json:"plugin_manager_users"`
+			Category    string `json:"category"`
+		} `json:"plugins"`
+	}
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		return nil, err
+	}
+	results := make([]searchResult, 0, len(res.Plugins))
+	for _, p := range res.Plugins {
+		if p.Owner == "" || p.Name == "" {
+			continue
+		}
+		results = append(results, searchResult{
+			Repository:  pathutil.ReposPath("github.com/" + p.Owner + "/" + p.Name),
+			Stars:       p.Stars,
+			Description: p.Description,
+			Users:       p.Users,
+			Category:    p.Category,
+		})
+	}
+	return results, nil
+}
+
+func readVimAwesomeCache(path string, cache *vimAwesomeCache) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, cache)
+}
+
+func writeVimAwesomeCache(path string, cache *vimAwesomeCache) error {
+	content, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// mergeSearchResults appends the results of more which are not in results.
+// The users and the category of the results in more are set to the same
+// repositories in results.
+func mergeSearchResults(results, more []searchResult) []searchResult {
+	for _, r := range more {
+		found := false
+		for i := range results {
+			if results[i].Repository.Equals(r.Repository) {
+				results[i].Users = r.Users
+				results[i].Category = r.Category
+				found = true
+				break
+			}
+		}
+		if !found {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+func showSearchResults(w io.Writer, results []searchResult) {
+	for i, r := range results {
+		installed := ""
+		if r.Installed {
+			installed = " [installed]"
+		}
+		info := fmt.Sprintf("%d stars", r.Stars)
+		if r.Users > 0 {
+			info += fmt.Sprintf(", %d users", r.Users)
+		}
+		if r.Category != "" {
+			info += ", " + r.Category
+		}
+		fmt.Fprintf(w, "  %d) %s (%s)%s\n", i+1, r.Repository, info, installed)
+		if r.Description != "" {
+			fmt.Fprintf(w, "     %s\n", r.Description)
+		}
+	}
+}
+
+// chooseSearchResults reads the numbers of results to install from in, and
+// returns the repositories.
+func chooseSearchResults(results []searchResult, in io.Reader, out io.Writer) ([]string, error) {
+	fmt.Fprintf(out, "Install which plugins? (e.g. \"1 3\", empty to cancel) [1-%d]: ", len(results))
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "could not read the answer")
+	}
+	var chosen []string
+	for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' || r == '\n' || r == '\r' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(results) {
+			return nil, errors.Errorf("invalid number %q: must be 1-%d", field, len(results))
+		}
+		chosen = append(chosen, results[n-1].Repository.String())
+	}
+	return chosen, nil
+}
@@ -0,0 +1,119 @@
+package subcmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/lockjson"
+)
+
+func TestSearch(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	vimAwesomeRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search/repositories":
+			if q := r.URL.Query().Get("q"); q != `comment language:"Vim Script"` {
+				t.Errorf("unexpected query: %q", q)
+			}
+			w.Write([]byte(`{"items": [
+				{"full_name": "tomtom/tcomment_vim", "stargazers_count": 1300, "description": "tcomment"},
+				{"full_name": "tyru/caw.vim", "stargazers_count": 200, "description": "caw"}
+			]}`))
+		case "/api/plugins":
+			vimAwesomeRequests++
+			if vimAwesomeRequests > 1 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"plugins": [
+				{"github_owner": "tpope", "github_repo_name": "vim-commentary", "github_stars": 4000, "short_desc": "commentary", "plugin_manager_users": 5000, "category": "other"},
+				{"github_owner": "tyru", "github_repo_name": "caw.vim", "github_stars": 200, "short_desc": "caw", "plugin_manager_users": 100, "category": "language"},
+				{"github_owner": "", "github_repo_name": "", "github_stars": 0, "short_desc": "vim.org only"}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(orig string) { githubAPIURL = orig }(githubAPIURL)
+	githubAPIURL = server.URL
+	defer func(orig string) { vimAwesomeURL = orig }(vimAwesomeURL)
+	vimAwesomeURL = server.URL
+
+	lockJSON := &lockjson.LockJSON{
+		Repos: lockjson.ReposList{{Type: lockjson.ReposGitType, Path: "github.com/tyru/caw.vim"}},
+	}
+	results, err := (&searchCmd{num: 10, vimAwesome: true}).search(context.Background(), "comment", lockJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ranked by users on Vim Awesome, and then stars
+	expected := []searchResult{
+		{Repository: "github.com/tpope/vim-commentary", Stars: 4000, Description: "commentary", Users: 5000, Category: "other"},
+		{Repository: "github.com/tyru/caw.vim", Stars: 200, Description: "caw", Users: 100, Category: "language", Installed: true},
+		{Repository: "github.com/tomtom/tcomment_vim", Stars: 1300, Description: "tcomment"},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %+v but got %+v", expected, results)
+	}
+
+	// The results of Vim Awesome are cached
+	results, err = (&searchCmd{num: 10, category: "Language"}).search(context.Background(), "comment", lockJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, expected[1:2]) {
+		t.Errorf("expected %+v but got %+v", expected[1:2], results)
+	}
+	if vimAwesomeRequests != 1 {
+		t.Errorf("expected Vim Awesome was requested once but got %d", vimAwesomeRequests)
+	}
+
+	results, err = (&searchCmd{num: 1}).search(context.Background(), "comment", lockJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Repository != "github.com/tomtom/tcomment_vim" {
+		t.Errorf("expected only tcomment_vim but got %+v", results)
+	}
+}
+
+func TestChooseSearchResults(t *testing.T) {
+	results := []searchResult{
+		{Repository: "github.com/tomtom/tcomment_vim"},
+		{Repository: "github.com/tyru/caw.vim"},
+	}
+	for _, tt := range []struct {
+		answer   string
+		expected []string
+		err      bool
+	}{
+		{"1 2\n", []string{"github.com/tomtom/tcomment_vim", "github.com/tyru/caw.vim"}, false},
+		{"2,\n", []string{"github.com/tyru/caw.vim"}, false},
+		{"\n", nil, false},
+		{"", nil, false},
+		{"3\n", nil, true},
+		{"caw\n", nil, true},
+	} {
+		var out bytes.Buffer
+		chosen, err := chooseSearchResults(results, strings.NewReader(tt.answer), &out)
+		if (err != nil) != tt.err || !reflect.DeepEqual(chosen, tt.expected) {
+			t.Errorf("%q: expected %q (error=%v) but got %q (%v)", tt.answer, tt.expected, tt.err, chosen, err)
+		}
+	}
+}
@@ -1,6 +1,13 @@
 package subcmd
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -16,6 +23,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/vim-volt/volt/config"
 	"github.com/vim-volt/volt/httputil"
 	"github.com/vim-volt/volt/logger"
 )
@@ -24,9 +32,31 @@ func init() {
 	cmdMap["self-upgrade"] = &selfUpgradeCmd{}
 }
 
+// voltLatestReleaseURL is the API URL of the latest stable release of volt
+// (overwritten in tests).
+var voltLatestReleaseURL = "https://api.github.com/repos/vim-volt/volt/releases/latest"
+
+// voltReleasesURL is the API URL of the recent releases of volt including
+// pre-releases (overwritten in tests).
+var voltReleasesURL = "https://api.github.com/repos/vim-volt/volt/releases?per_page=30"
+
+// checksumsAssetName is the name of the release asset which lists SHA256
+// checksums of the other assets in the format of sha256sum(1).
+const checksumsAssetName = "SHA256SUMS"
+
+// signatureAssetName is the name of the release asset which is the detached
+// ed25519 signature (base64) of checksumsAssetName.
+const signatureAssetName = checksumsAssetName + ".sig"
+
+// releasePublicKey is the base64 ed25519 public key which signs
+// checksumsAssetName. If it is not empty, the signature is required.
+var releasePublicKey = ""
+
 type selfUpgradeCmd struct {
-	helped bool
-	check  bool
+	helped   bool
+	check    bool
+	insecure bool
+	channel  string
 }
 
 func (cmd *selfUpgradeCmd) ProhibitRootExecution(args []string) bool { return true }
@@ -37,21 +67,33 @@ func (cmd *selfUpgradeCmd) FlagSet() *flag.FlagSet {
 	fs.Usage = func() {
 		fmt.Print(`
 Usage
-  volt self-upgrade [-help] [-check]
+  volt self-upgrade [-help] [-check] [-insecure] [-channel {stable|pre}]
 
 Description
-    Upgrade to the latest volt command, or if -check was given, it only checks the newer version is available.` + "\n\n")
-		//fmt.Println("Options")
-		//fs.PrintDefaults()
+    Upgrade to the latest volt command, or if -check was given, it only checks the newer version is available.
+
+    If -channel pre was given, pre-releases (e.g. "v0.4.0-rc1") are also the
+    candidates of the upgrade. The default channel is self_upgrade.channel of
+    config.toml ("stable" if it is not set).
+
+    The downloaded binary is verified against the SHA256 checksum in "` + checksumsAssetName + `"
+    of the release (and its signature "` + signatureAssetName + `" if volt has a public key
+    of releases) before replacing the current binary. The upgrade is aborted
+    if they do not match. If -insecure was given, the binary is installed even
+    if the release does not have "` + checksumsAssetName + `" (a mismatch is still an error).` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
 		fmt.Println()
 		cmd.helped = true
 	}
 	fs.BoolVar(&cmd.check, "check", false, "only checks the newer version is available")
+	fs.BoolVar(&cmd.insecure, "insecure", false, "upgrade even if the release has no checksums")
+	fs.StringVar(&cmd.channel, "channel", "", "release channel (\"stable\" or \"pre\")")
 	return fs
 }
 
-func (cmd *selfUpgradeCmd) Run(args []string) *Error {
-	err := cmd.parseArgs(args)
+func (cmd *selfUpgradeCmd) Run(cmdctx *CmdContext) *Error {
+	err := cmd.parseArgs(cmdctx.Args)
 	if err == ErrShowedHelp {
 		return nil
 	}
@@ -64,8 +106,10 @@ func (cmd *selfUpgradeCmd) Run(args []string) *Error {
 			return &Error{Code: 11, Msg: "Failed to clean up old binary: " + err.Error()}
 		}
 	} else {
-		latestURL := "https://api.github.com/repos/vim-volt/volt/releases/latest"
-		if err = cmd.doSelfUpgrade(latestURL); err != nil {
+		if cmd.channel == "" {
+			cmd.channel = cmdctx.Config.SelfUpgrade.Channel
+		}
+		if err = cmd.doSelfUpgrade(cmdctx.Ctx); err != nil {
 			return &Error{Code: 12, Msg: "Failed to self-upgrade: " + err.Error()}
 		}
 	}
@@ -79,6 +123,9 @@ func (cmd *selfUpgradeCmd) parseArgs(args []string) error {
 	if cmd.helped {
 		return ErrShowedHelp
 	}
+	if cmd.channel != "" && cmd.channel != config.StableChannel && cmd.channel != config.PreChannel {
+		return errors.Errorf("-channel must be %q or %q", config.StableChannel, config.PreChannel)
+	}
 	return nil
 }
 
@@ -122,9 +169,11 @@ func (*selfUpgradeCmd) processIsAlive(pid int) bool {
 }
 
 type latestRelease struct {
-	TagName string `json:"tag_name"`
-	Body    string `json:"body"`
-	Assets  []releaseAsset
+	TagName    string `json:"tag_name"`
+	Body       string `json:"body"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []releaseAsset
 }
 
 type releaseAsset struct {
@@ -132,9 +181,9 @@ type releaseAsset struct {
 	Name               string `json:"name"`
 }
 
-func (cmd *selfUpgradeCmd) doSelfUpgrade(latestURL string) error {
+func (cmd *selfUpgradeCmd) doSelfUpgrade(ctx context.Context) error {
 	// Check the latest binary info
-	release, err := cmd.checkLatest(latestURL)
+	release, err := cmd.checkLatest(ctx, cmd.channel)
 	if err != nil {
 		return err
 	}
@@ -147,7 +196,11 @@ func (cmd *selfUpgradeCmd) doSelfUpgrade(latestURL string) error {
 		logger.Info("No updates were found.")
 		return nil
 	}
-	logger.Infof("Found update: %s -> %s", voltVersion, release.TagName)
+	if isPrerelease(tagNameVer) {
+		logger.Infof("Found update: %s -> %s (pre-release)", voltVersion, release.TagName)
+	} else {
+		logger.Infof("Found update: %s -> %s", voltVersion, release.TagName)
+	}
 
 	// Show release note
 	fmt.Println("---")
@@ -167,9 +220,13 @@ func (cmd *selfUpgradeCmd) doSelfUpgrade(latestURL string) error {
 	if err != nil {
 		return err
 	}
-	err = cmd.download(latestFile, release)
+	name, hash, err := cmd.download(ctx, latestFile, release)
 	latestFile.Close()
+	if err == nil {
+		err = cmd.verify(ctx, release, name, hash)
+	}
 	if err != nil {
+		os.Remove(voltExe + ".latest")
 		return err
 	}
 
@@ -200,33 +257,157 @@ func (*selfUpgradeCmd) getExecutablePath() (string, error) {
 	return filepath.EvalSymlinks(exe)
 }
 
-func (*selfUpgradeCmd) checkLatest(url string) (*latestRelease, error) {
-	content, err := httputil.GetContent(url)
+// checkLatest returns the latest release of channel. If channel is
+// config.PreChannel, the newest version of the recent releases (including
+// pre-releases) is returned.
+func (*selfUpgradeCmd) checkLatest(ctx context.Context, channel string) (*latestRelease, error) {
+	if channel != config.PreChannel {
+		content, err := httputil.GetContent(ctx, voltLatestReleaseURL)
+		if err != nil {
+			return nil, err
+		}
+		var release latestRelease
+		if err = json.Unmarshal(content, &release); err != nil {
+			return nil, err
+		}
+		return &release, nil
+	}
+
+	content, err := httputil.GetContent(ctx, voltReleasesURL)
 	if err != nil {
 		return nil, err
 	}
-	var release latestRelease
-	if err = json.Unmarshal(content, &release); err != nil {
+	var releases []latestRelease
+	if err = json.Unmarshal(content, &releases); err != nil {
 		return nil, err
 	}
-	return &release, nil
+	var latest *latestRelease
+	var latestVer versionInfo
+	for i := range releases {
+		if releases[i].Draft {
+			continue
+		}
+		v, err := parseVersion(releases[i].TagName)
+		if err != nil {
+			logger.Debugf("Skipping release %q: %s", releases[i].TagName, err)
+			continue
+		}
+		if latest == nil || compareVersion(v, latestVer) > 0 {
+			latest, latestVer = &releases[i], v
+		}
+	}
+	if latest == nil {
+		return nil, errors.New("no releases were found")
+	}
+	return latest, nil
 }
 
-func (*selfUpgradeCmd) download(w io.Writer, release *latestRelease) error {
+// download writes the asset of current platform to w, and returns the asset
+// name and its SHA256 checksum (hex).
+func (*selfUpgradeCmd) download(ctx context.Context, w io.Writer, release *latestRelease) (string, string, error) {
 	suffix := runtime.GOOS + "-" + runtime.GOARCH
 	for i := range release.Assets {
 		// e.g.: Name = "volt-v0.1.2-linux-amd64"
 		if strings.HasSuffix(release.Assets[i].Name, suffix) {
-			r, err := httputil.GetContentReader(release.Assets[i].BrowserDownloadURL)
+			r, err := httputil.GetContentReader(ctx, release.Assets[i].BrowserDownloadURL)
 			if err != nil {
-				return err
+				return "", "", err
 			}
 			defer r.Close()
-			if _, err = io.Copy(w, r); err != nil {
-				return err
+			h := sha256.New()
+			if _, err = io.Copy(io.MultiWriter(w, h), r); err != nil {
+				return "", "", err
 			}
-			break
+			return release.Assets[i].Name, hex.EncodeToString(h.Sum(nil)), nil
+		}
+	}
+	return "", "", errors.Errorf("no binary for %s was found in release %s", suffix, release.TagName)
+}
+
+// verify checks hash is the checksum of asset name in checksumsAssetName of
+// release, and the signature of checksumsAssetName if releasePublicKey is set.
+func (cmd *selfUpgradeCmd) verify(ctx context.Context, release *latestRelease, name, hash string) error {
+	sumsAsset := release.findAsset(checksumsAssetName)
+	if sumsAsset == nil {
+		if cmd.insecure {
+			logger.Warnf("%s was not found in release %s: installing unverified binary", checksumsAssetName, release.TagName)
+			return nil
 		}
+		return errors.Errorf("%s was not found in release %s (run with -insecure to upgrade without verification)", checksumsAssetName, release.TagName)
+	}
+	sums, err := httputil.GetContent(ctx, sumsAsset.BrowserDownloadURL)
+	if err != nil {
+		return errors.Wrap(err, "could not download "+checksumsAssetName)
+	}
+
+	if releasePublicKey != "" {
+		sigAsset := release.findAsset(signatureAssetName)
+		if sigAsset == nil {
+			return errors.Errorf("%s was not found in release %s", signatureAssetName, release.TagName)
+		}
+		sig, err := httputil.GetContent(ctx, sigAsset.BrowserDownloadURL)
+		if err != nil {
+			return errors.Wrap(err, "could not download "+signatureAssetName)
+		}
+		if err := verifySignature(releasePublicKey, sums, sig); err != nil {
+			return err
+		}
+		logger.Debugf("Verified the signature of %s", checksumsAssetName)
+	}
+
+	expected, err := parseChecksums(sums, name)
+	if err != nil {
+		return err
+	}
+	if hash != expected {
+		return errors.Errorf("checksum mismatch of %s: expected %s but got %s", name, expected, hash)
+	}
+	logger.Debugf("Verified the checksum of %s (%s)", name, hash)
+	return nil
+}
+
+func (release *latestRelease) findAsset(name string) *releaseAsset {
+	for i := range release.Assets {
+		if release.Assets[i].Name == name {
+			return &release.Assets[i]
+		}
+	}
+	return nil
+}
+
+// parseChecksums returns the checksum of name in the output of sha256sum(1)
+// ("{hex}  {name}" or "{hex} *{name}" lines).
+func parseChecksums(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		if _, err := hex.DecodeString(fields[0]); err != nil || len(fields[0]) != sha256.Size*2 {
+			return "", errors.Errorf("invalid checksum of %s in %s: %s", name, checksumsAssetName, fields[0])
+		}
+		return strings.ToLower(fields[0]), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.Errorf("checksum of %s was not found in %s", name, checksumsAssetName)
+}
+
+// verifySignature verifies sig (base64) is the ed25519 signature of msg by
+// pubKey (base64).
+func verifySignature(pubKey string, msg, sig []byte) error {
+	key, err := base64.StdEncoding.DecodeString(pubKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("invalid public key of releases")
+	}
+	rawSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return errors.Wrap(err, "invalid "+signatureAssetName)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), msg, rawSig) {
+		return errors.Errorf("signature mismatch of %s", checksumsAssetName)
 	}
 	return nil
 }
@@ -1,10 +1,22 @@
 package subcmd
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/vim-volt/volt/config"
 )
 
 func TestVoltSelfUpgrade(t *testing.T) {
@@ -83,3 +95,133 @@ func captureOutput(t *testing.T, f func()) string {
 	os.Stderr = oldStderr
 	return <-outCh
 }
+
+func TestSelfUpgradeVerify(t *testing.T) {
+	binary := []byte("new volt binary")
+	sum := sha256.Sum256(binary)
+	hash := hex.EncodeToString(sum[:])
+	name := "volt-v9.9.9-" + runtime.GOOS + "-" + runtime.GOARCH
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, content)
+	}))
+	defer srv.Close()
+	release := &latestRelease{
+		TagName: "v9.9.9",
+		Assets: []releaseAsset{
+			{Name: name, BrowserDownloadURL: srv.URL + "/" + name},
+			{Name: checksumsAssetName, BrowserDownloadURL: srv.URL + "/" + checksumsAssetName},
+			{Name: signatureAssetName, BrowserDownloadURL: srv.URL + "/" + signatureAssetName},
+		},
+	}
+	setSums := func(sums string, signer ed25519.PrivateKey) {
+		files["/"+checksumsAssetName] = sums
+		files["/"+signatureAssetName] = base64.StdEncoding.EncodeToString(ed25519.Sign(signer, []byte(sums)))
+	}
+	files["/"+name] = string(binary)
+
+	oldKey := releasePublicKey
+	defer func() { releasePublicKey = oldKey }()
+	_, otherPriv, _ := ed25519.GenerateKey(nil)
+
+	for _, tt := range []struct {
+		desc    string
+		sums    string
+		signer  ed25519.PrivateKey
+		pubKey  string
+		wantErr string
+	}{
+		{desc: "matched", sums: hash + "  " + name + "\n"},
+		{desc: "matched (binary mode)", sums: strings.Repeat("0", 64) + "  other\n" + hash + " *" + name + "\n"},
+		{desc: "mismatch", sums: strings.Repeat("0", 64) + "  " + name + "\n", wantErr: "checksum mismatch"},
+		{desc: "not listed", sums: hash + "  other\n", wantErr: "was not found in " + checksumsAssetName},
+		{desc: "signed", sums: hash + "  " + name + "\n", signer: priv, pubKey: base64.StdEncoding.EncodeToString(pub)},
+		{desc: "bad signature", sums: hash + "  " + name + "\n", signer: otherPriv, pubKey: base64.StdEncoding.EncodeToString(pub), wantErr: "signature mismatch"},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			signer := tt.signer
+			if signer == nil {
+				signer = priv
+			}
+			setSums(tt.sums, signer)
+			releasePublicKey = tt.pubKey
+			cmd := &selfUpgradeCmd{}
+			var buf bytes.Buffer
+			gotName, gotHash, err := cmd.download(context.Background(), &buf, release)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotName != name || gotHash != hash || buf.String() != string(binary) {
+				t.Fatalf("download returned (%q, %q, %q)", gotName, gotHash, buf.String())
+			}
+			err = cmd.verify(context.Background(), release, gotName, gotHash)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("expected nil error, but got: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing %q, but got: %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("no checksums", func(t *testing.T) {
+		releasePublicKey = ""
+		noSums := &latestRelease{TagName: "v9.9.9", Assets: release.Assets[:1]}
+		if err := (&selfUpgradeCmd{}).verify(context.Background(), noSums, name, hash); err == nil {
+			t.Error("expected error without " + checksumsAssetName)
+		}
+		if err := (&selfUpgradeCmd{insecure: true}).verify(context.Background(), noSums, name, hash); err != nil {
+			t.Errorf("expected nil error with -insecure, but got: %v", err)
+		}
+	})
+}
+
+func TestSelfUpgradeCheckLatestChannel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			io.WriteString(w, `{"tag_name": "v0.3.6"}`)
+		case "/releases":
+			io.WriteString(w, `[
+				{"tag_name": "v0.5.0-alpha", "draft": true, "prerelease": true},
+				{"tag_name": "v0.4.0-rc2", "prerelease": true},
+				{"tag_name": "nightly", "prerelease": true},
+				{"tag_name": "v0.4.0-rc10", "prerelease": true},
+				{"tag_name": "v0.3.6"}
+			]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	oldLatest, oldReleases := voltLatestReleaseURL, voltReleasesURL
+	voltLatestReleaseURL, voltReleasesURL = srv.URL+"/latest", srv.URL+"/releases"
+	defer func() { voltLatestReleaseURL, voltReleasesURL = oldLatest, oldReleases }()
+
+	for _, tt := range []struct {
+		channel  string
+		expected string
+	}{
+		{config.StableChannel, "v0.3.6"},
+		{config.PreChannel, "v0.4.0-rc10"},
+	} {
+		release, err := (&selfUpgradeCmd{}).checkLatest(context.Background(), tt.channel)
+		if err != nil {
+			t.Errorf("channel %q: expected nil error, but got: %v", tt.channel, err)
+			continue
+		}
+		if release.TagName != tt.expected {
+			t.Errorf("channel %q: expected %q, but got %q", tt.channel, tt.expected, release.TagName)
+		}
+	}
+}
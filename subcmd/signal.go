@@ -0,0 +1,49 @@
+package subcmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// exitCodeInterrupted is an exit code when volt was interrupted twice.
+const exitCodeInterrupted = 130
+
+// cancelOnInterrupt returns a context which is cancelled when volt receives
+// SIGINT or SIGTERM, so that subcommands can stop and clean up.
+// If volt receives the signal again while cleaning up, volt exits
+// immediately.
+// The returned function must be called to stop handling signals.
+func cancelOnInterrupt() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-sigCh:
+			logger.Warnf("Received %s, cleaning up ... (interrupt again to exit immediately)", sig)
+			cancel()
+		case <-stop:
+			return
+		}
+		select {
+		case <-sigCh:
+			logger.Error("Exited without cleaning up: please remove " + filepath.Join(pathutil.TrxDir(), "lock") + " if it exists")
+			os.Exit(exitCodeInterrupted)
+		case <-stop:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigCh)
+		close(stop)
+		cancel()
+	}
+}
@@ -0,0 +1,236 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func init() {
+	cmdMap["size"] = &sizeCmd{}
+}
+
+type sizeCmd struct {
+	helped bool
+}
+
+func (cmd *sizeCmd) ProhibitRootExecution(args []string) bool { return false }
+
+func (cmd *sizeCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt size [-help]
+
+Quick example
+  $ volt size
+
+Description
+  Show disk usage of each repository in lock.json (largest first):
+
+    FILES   files of $VOLTPATH/repos/{repository} except .git
+    GIT     git objects and history ($VOLTPATH/repos/{repository}/.git)
+    BUILT   files of ~/.vim/pack/volt/opt/{repository}
+            (symlinks are 0, and hard links to the object store of
+            build.strategy = "copy" are counted as well)
+
+  And show the repositories which can be cleaned up:
+
+  * not used by any profile:
+      "volt rm {repository}" removes the repository.
+  * git history is much larger than the files:
+      Re-clone the repository shallowly (e.g. "git clone --depth 1").
+  * build.strategy = "copy" does not use the worktree:
+      The repository can be a bare repository, because the files are copied
+      from git objects ("git clone --bare").` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *sizeCmd) Run(cmdctx *CmdContext) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(cmdctx.Args)
+	if cmd.helped {
+		return nil
+	}
+	if len(fs.Args()) > 0 {
+		return &Error{Code: 10, Msg: "Failed to parse args: size command does not accept arguments"}
+	}
+
+	sizes := make([]reposSize, 0, len(cmdctx.LockJSON.Repos))
+	used := cmdctx.LockJSON.GetAllProfilesReposList()
+	for i := range cmdctx.LockJSON.Repos {
+		repos := &cmdctx.LockJSON.Repos[i]
+		s, err := measureRepos(repos)
+		if err != nil {
+			return &Error{Code: 11, Msg: "Failed to measure " + repos.Path.String() + ": " + err.Error()}
+		}
+		s.used = used.Contains(repos.Path)
+		sizes = append(sizes, *s)
+	}
+	sort.SliceStable(sizes, func(i, j int) bool {
+		return sizes[i].total() > sizes[j].total()
+	})
+
+	if err := writeSizes(os.Stdout, sizes, cmdctx.Config.Build.Strategy); err != nil {
+		return &Error{Code: 11, Msg: "Failed to write: " + err.Error()}
+	}
+	return nil
+}
+
+// suggestMinBytes is the minimum size to suggest reducing, and
+// shallowSuggestRatio is the minimum ratio of git history size to the files
+// to suggest a shallow re-clone.
+const (
+	suggestMinBytes     = 1 << 20
+	shallowSuggestRatio = 4
+)
+
+// reposSize is disk usage of a repository.
+type reposSize struct {
+	path  pathutil.ReposPath
+	files int64
+	git   int64
+	built int64
+	// bare is true if the repository is a bare repository
+	bare bool
+	// shallow is true if the repository is a shallow clone
+	shallow bool
+	// used is true if any profile has the repository
+	used bool
+}
+
+func (s *reposSize) total() int64 {
+	return s.files + s.git + s.built
+}
+
+// suggestions returns the suggestions to reduce disk usage of the repository.
+// strategy is build.strategy of config.toml.
+func (s *reposSize) suggestions(strategy string) []string {
+	if !s.used {
+		return []string{fmt.Sprintf(
+			"not used by any profile: \"volt rm %s\" saves %s", s.path, formatSize(s.total()))}
+	}
+	var result []string
+	if !s.bare && !s.shallow && s.git >= suggestMinBytes && s.git >= s.files*shallowSuggestRatio {
+		result = append(result, fmt.Sprintf(
+			"git history is %s (files are %s): a shallow re-clone saves most of it", formatSize(s.git), formatSize(s.files)))
+	}
+	if strategy == config.CopyBuilder && s.git > 0 && !s.bare && s.files >= suggestMinBytes {
+		result = append(result, fmt.Sprintf(
+			"build.strategy = %q does not use the worktree: converting to a bare repository saves %s", config.CopyBuilder, formatSize(s.files)))
+	}
+	return result
+}
+
+// measureRepos returns disk usage of repos.
+func measureRepos(repos *lockjson.Repos) (*reposSize, error) {
+	s := &reposSize{path: repos.Path}
+	fullpath := repos.Path.FullPath()
+	gitDir := filepath.Join(fullpath, ".git")
+	if !pathutil.Exists(gitDir) && pathutil.Exists(filepath.Join(fullpath, "HEAD")) &&
+		pathutil.Exists(filepath.Join(fullpath, "objects")) {
+		gitDir = fullpath
+		s.bare = true
+	}
+
+	var err error
+	if pathutil.Exists(gitDir) {
+		if s.git, err = dirSize(gitDir); err != nil {
+			return nil, err
+		}
+		s.shallow = pathutil.Exists(filepath.Join(gitDir, "shallow"))
+	}
+	if !s.bare && pathutil.Exists(fullpath) {
+		if s.files, err = dirSize(fullpath); err != nil {
+			return nil, err
+		}
+		s.files -= s.git
+	}
+	if built := repos.Path.EncodeToPlugDirName(); pathutil.Exists(built) {
+		if s.built, err = dirSize(built); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// dirSize returns the total size of regular files under dir.
+// Symlinks are not followed.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// writeSizes writes the table of sizes and the suggestions to w.
+func writeSizes(w io.Writer, sizes []reposSize, strategy string) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tFILES\tGIT\tBUILT\tTOTAL")
+	var total reposSize
+	for i := range sizes {
+		s := &sizes[i]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			s.path, formatSize(s.files), formatSize(s.git), formatSize(s.built), formatSize(s.total()))
+		total.files += s.files
+		total.git += s.git
+		total.built += s.built
+	}
+	fmt.Fprintf(tw, "(total)\t%s\t%s\t%s\t%s\n",
+		formatSize(total.files), formatSize(total.git), formatSize(total.built), formatSize(total.total()))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	header := false
+	for i := range sizes {
+		for _, msg := range sizes[i].suggestions(strategy) {
+			if !header {
+				fmt.Fprintln(w, "\nSuggestions:")
+				header = true
+			}
+			if _, err := fmt.Fprintf(w, "  %s: %s\n", sizes[i].path, msg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// formatSize returns human-readable size (e.g. "1.5M").
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	f := float64(size) / unit
+	for _, suffix := range []string{"K", "M"} {
+		if f < unit {
+			return fmt.Sprintf("%.1f%s", f, suffix)
+		}
+		f /= unit
+	}
+	return fmt.Sprintf("%.1fG", f)
+}
@@ -0,0 +1,53 @@
+package subcmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/config"
+)
+
+func TestFormatSize(t *testing.T) {
+	for size, expected := range map[int64]string{
+		0:          "0B",
+		1023:       "1023B",
+		1536:       "1.5K",
+		5 << 20:    "5.0M",
+		3 << 30:    "3.0G",
+		2048 << 30: "2048.0G",
+	} {
+		if got := formatSize(size); got != expected {
+			t.Errorf("%d: expected %q but got %q", size, expected, got)
+		}
+	}
+}
+
+func TestSizeSuggestions(t *testing.T) {
+	for _, tt := range []struct {
+		size     reposSize
+		strategy string
+		expected []string
+	}{
+		// Removal candidate
+		{reposSize{files: 1, git: 100 << 20}, config.SymlinkBuilder, []string{"not used by any profile"}},
+		// Large history
+		{reposSize{files: 1 << 20, git: 4 << 20, used: true}, config.SymlinkBuilder, []string{"git history is 4.0M"}},
+		{reposSize{files: 1 << 20, git: 4 << 20, used: true, shallow: true}, config.SymlinkBuilder, nil},
+		{reposSize{files: 1 << 10, git: 1 << 19, used: true}, config.SymlinkBuilder, nil},
+		// Worktree of copy strategy
+		{reposSize{files: 1 << 20, git: 1 << 20, used: true}, config.CopyBuilder, []string{"converting to a bare repository saves 1.0M"}},
+		{reposSize{git: 1 << 20, used: true, bare: true}, config.CopyBuilder, nil},
+		{reposSize{files: 1 << 20, used: true}, config.CopyBuilder, nil},
+	} {
+		got := tt.size.suggestions(tt.strategy)
+		if len(got) != len(tt.expected) {
+			t.Errorf("%+v: expected %q but got %q", tt.size, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if !strings.Contains(got[i], tt.expected[i]) {
+				t.Errorf("%+v: expected %q but got %q", tt.size, tt.expected[i], got[i])
+			}
+		}
+	}
+}
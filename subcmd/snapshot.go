@@ -0,0 +1,476 @@
+package subcmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/builder"
+	"github.com/vim-volt/volt/transaction"
+)
+
+func init() {
+	cmdMap["snapshot"] = &snapshotCmd{}
+}
+
+type snapshotCmd struct {
+	helped bool
+}
+
+func (cmd *snapshotCmd) ProhibitRootExecution(args []string) bool {
+	return len(args) == 0 || args[0] != "list"
+}
+
+func (cmd *snapshotCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  snapshot [-help] {command}
+
+Command
+  snapshot create [-plugconf] {name}
+    Save a copy of lock.json as snapshot {name}. If -plugconf option was
+    given, plugconf files are saved as well.
+
+  snapshot restore {name}
+    Restore lock.json (and plugconf files if saved) of snapshot {name}, and
+    make the repositories the same as the snapshot. Saved plugconf files
+    replace plugconf directory (plugconf files added after the snapshot are
+    removed):
+    * Git repositories are cloned if they do not exist, and reset to the
+      commits of the snapshot. The current branch is moved to the commit, so
+      "volt get -u" upgrades it again. A repository which has local changes
+      is not restored.
+    * Release repositories are downloaded if the versions are different.
+    * Static repositories must exist.
+    Then ~/.vim/pack/volt is rebuilt. If some repositories could not be
+    restored (or interrupted), the restored repositories are rolled back, and
+    lock.json and plugconf files are not changed.
+    The directories of the repositories which are not in the snapshot are
+    left (see "volt verify-lock -help" to remove them).
+
+  snapshot list
+    List all snapshots.
+
+  Snapshots are saved in $VOLTPATH/snapshots/{name}. Remove the directory to
+  delete the snapshot.
+
+Quick example
+  $ volt snapshot create before-lsp   # cheap insurance before experimenting
+  $ volt get prabirshrestha/vim-lsp
+  $ volt snapshot restore before-lsp  # go back if you don't like it
+  $ volt snapshot list
+  before-lsp  2018-04-01 12:00:00` + "\n\n")
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *snapshotCmd) Run(cmdctx *CmdContext) *Error {
+	// Parse args
+	args, err := cmd.parseArgs(cmdctx.Args)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: err.Error()}
+	}
+
+	subCmd := args[0]
+	switch subCmd {
+	case "create":
+		err = cmd.doCreate(args[1:])
+	case "restore":
+		err = cmd.doRestore(cmdctx.Ctx, cmdctx.Config, args[1:])
+	case "list":
+		err = cmd.doList(args[1:])
+	default:
+		return &Error{Code: 11, Msg: "Unknown subcommand: " + subCmd}
+	}
+
+	if err != nil {
+		return &Error{Code: 20, Msg: err.Error()}
+	}
+
+	return nil
+}
+
+func (cmd *snapshotCmd) parseArgs(args []string) ([]string, error) {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, ErrShowedHelp
+	}
+	if len(fs.Args()) == 0 {
+		fs.Usage()
+		logger.Error("must specify subcommand")
+		return nil, ErrShowedHelp
+	}
+	return fs.Args(), nil
+}
+
+// snapshotDir returns "$VOLTPATH/snapshots/{name}".
+func snapshotDir(name string) string {
+	return filepath.Join(pathutil.SnapshotsDir(), name)
+}
+
+// validateSnapshotName returns an error if name cannot be a directory name
+// under $VOLTPATH/snapshots.
+func validateSnapshotName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return errors.Errorf("invalid snapshot name %q", name)
+	}
+	return nil
+}
+
+func (cmd *snapshotCmd) doCreate(args []string) error {
+	withPlugconf := false
+	if len(args) > 0 && args[0] == "-plugconf" {
+		withPlugconf = true
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		cmd.FlagSet().Usage()
+		return errors.New("'volt snapshot create' receives snapshot name")
+	}
+	name := args[0]
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+	dir := snapshotDir(name)
+	if pathutil.Exists(dir) {
+		return errors.Errorf("snapshot '%s' already exists", name)
+	}
+	if !pathutil.Exists(pathutil.LockJSON()) {
+		return errors.New("lock.json does not exist, nothing to save")
+	}
+
+	// Create the snapshot in a temporary directory not to leave a broken
+	// snapshot on failure
+	if err := os.MkdirAll(pathutil.SnapshotsDir(), 0755); err != nil {
+		return err
+	}
+	tempDir, err := ioutil.TempDir(pathutil.SnapshotsDir(), "."+name+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	snapLockJSON := filepath.Join(tempDir, "lock.json")
+	if err := fileutil.CopyFile(pathutil.LockJSON(), snapLockJSON, nil, 0644); err != nil {
+		return errors.Wrap(err, "could not copy lock.json")
+	}
+	// The modification time is the created time of the snapshot
+	if err := fileutil.SetModTime(snapLockJSON, time.Now()); err != nil {
+		return err
+	}
+	if plugconfDir := filepath.Join(pathutil.VoltConfigDir(), "plugconf"); withPlugconf && pathutil.Exists(plugconfDir) {
+		if err := fileutil.CopyDir(plugconfDir, filepath.Join(tempDir, "plugconf"), nil, 0755, 0); err != nil {
+			return errors.Wrap(err, "could not copy plugconf")
+		}
+	}
+	if err := os.Rename(tempDir, dir); err != nil {
+		return err
+	}
+
+	logger.Infof("Created snapshot '%s'", name)
+	return nil
+}
+
+// snapshotInfo is a snapshot in $VOLTPATH/snapshots.
+type snapshotInfo struct {
+	name     string
+	created  time.Time
+	plugconf bool
+}
+
+func (cmd *snapshotCmd) doList(args []string) error {
+	if len(args) > 0 {
+		return errors.New("'volt snapshot list' does not accept arguments")
+	}
+	snapshots, err := readSnapshots()
+	if err != nil {
+		return err
+	}
+	return writeSnapshots(os.Stdout, snapshots)
+}
+
+// readSnapshots returns the snapshots in $VOLTPATH/snapshots sorted by the
+// created time.
+func readSnapshots() ([]snapshotInfo, error) {
+	infos, err := ioutil.ReadDir(pathutil.SnapshotsDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	snapshots := make([]snapshotInfo, 0, len(infos))
+	for _, fi := range infos {
+		if !fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		lockfile, err := os.Stat(filepath.Join(snapshotDir(fi.Name()), "lock.json"))
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshotInfo{
+			name:     fi.Name(),
+			created:  lockfile.ModTime(),
+			plugconf: pathutil.Exists(filepath.Join(snapshotDir(fi.Name()), "plugconf")),
+		})
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].created.Before(snapshots[j].created)
+	})
+	return snapshots, nil
+}
+
+// writeSnapshots writes "{name} {created} [(with plugconf)]" lines to w.
+func writeSnapshots(w io.Writer, snapshots []snapshotInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for i := range snapshots {
+		s := &snapshots[i]
+		if s.plugconf {
+			fmt.Fprintf(tw, "%s\t%s\t(with plugconf)\n", s.name, s.created.Local().Format("2006-01-02 15:04:05"))
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\n", s.name, s.created.Local().Format("2006-01-02 15:04:05"))
+		}
+	}
+	return tw.Flush()
+}
+
+func (cmd *snapshotCmd) doRestore(ctx context.Context, cfg *config.Config, args []string) (err error) {
+	if len(args) != 1 {
+		cmd.FlagSet().Usage()
+		return errors.New("'volt snapshot restore' receives snapshot name")
+	}
+	name := args[0]
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+	dir := snapshotDir(name)
+	if !pathutil.Exists(dir) {
+		return errors.Errorf("snapshot '%s' does not exist", name)
+	}
+	snapLockJSON, err := lockjson.ReadFile(filepath.Join(dir, "lock.json"))
+	if err != nil {
+		return errors.Wrap(err, "failed to read the snapshot")
+	}
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return errors.Wrap(err, "failed to read lock.json")
+	}
+
+	// Begin transaction
+	// If failed or interrupted, reset the repositories to the previous
+	// commits, remove cloned repositories, and restore downloaded
+	// repositories, lock.json, and plugconf
+	trx, err := transaction.Start()
+	if err != nil {
+		return
+	}
+	heads := make(map[pathutil.ReposPath]string, len(snapLockJSON.Repos))
+	defer func() {
+		if err != nil || ctx.Err() != nil {
+			if e := resetSnapshotRepos(heads); e != nil {
+				err = multierror.Append(err, errors.Wrap(e, "failed to rollback"))
+			}
+			if e := trx.Rollback(); e != nil {
+				err = multierror.Append(err, errors.Wrap(e, "failed to rollback"))
+			}
+			return
+		}
+		if e := trx.Done(); e != nil {
+			err = e
+		}
+	}()
+
+	get := &getCmd{}
+	failed := false
+	statusList := make([]string, 0, len(snapLockJSON.Repos))
+	for i := range snapLockJSON.Repos {
+		repos := &snapLockJSON.Repos[i]
+		if repos.Type == lockjson.ReposGitType {
+			if head, e := gitutil.GetHEAD(repos.Path); e == nil {
+				heads[repos.Path] = head
+			}
+		}
+		log := logger.NewReposBuffer(repos.Path.String())
+		status, e := restoreSnapshotRepos(ctx, get, repos, lockJSON.Repos.FindByPath(repos.Path), cfg, trx, log)
+		log.Flush()
+		if e != nil {
+			status = fmt.Sprintf("! %s > could not restore\n  * %s", repos.Path, e)
+			failed = true
+		}
+		statusList = append(statusList, status)
+	}
+	for i := range lockJSON.Repos {
+		if snapLockJSON.Repos.FindByPath(lockJSON.Repos[i].Path) == nil {
+			statusList = append(statusList, fmt.Sprintf("- %s > removed from lock.json (the directory is left)", lockJSON.Repos[i].Path))
+		}
+	}
+	sort.Strings(statusList)
+	defer func() {
+		for i := range statusList {
+			fmt.Println(statusList[i])
+		}
+	}()
+	if failed {
+		return errors.New("could not restore some repositories, the restored repositories were rolled back")
+	}
+	if ctx.Err() != nil {
+		return errors.New("interrupted")
+	}
+
+	if err = snapLockJSON.Write(); err != nil {
+		return errors.Wrap(err, "could not write to lock.json")
+	}
+	if snapPlugconf := filepath.Join(dir, "plugconf"); pathutil.Exists(snapPlugconf) {
+		if err = restoreSnapshotPlugconf(trx, snapPlugconf); err != nil {
+			return errors.Wrap(err, "could not restore plugconf")
+		}
+	}
+	if err = builder.Build(ctx, false); err != nil {
+		return errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+	}
+
+	logger.Infof("Restored snapshot '%s'", name)
+	return nil
+}
+
+// restoreSnapshotPlugconf replaces plugconf directory with snapPlugconf
+// directory of a snapshot. The plugconf files which are not in the snapshot
+// are removed. The previous plugconf directory is restored by
+// trx.Rollback().
+func restoreSnapshotPlugconf(trx transaction.Transaction, snapPlugconf string) error {
+	plugconfDir := pathutil.PlugconfDir()
+	if err := trx.Remove(plugconfDir); err != nil {
+		return err
+	}
+	trx.Created(plugconfDir)
+	return fileutil.CopyDir(snapPlugconf, plugconfDir, nil, 0755, 0)
+}
+
+// resetSnapshotRepos resets the git repositories to the commits of heads
+// (the commits before restoring a snapshot).
+func resetSnapshotRepos(heads map[pathutil.ReposPath]string) error {
+	var merr *multierror.Error
+	for reposPath, head := range heads {
+		if current, err := gitutil.GetHEAD(reposPath); err == nil && current == head {
+			continue
+		}
+		r, err := git.PlainOpen(reposPath.FullPath())
+		if err == nil {
+			err = gitutil.ResetHEAD(r, head)
+		}
+		if err != nil {
+			merr = multierror.Append(merr, errors.Wrap(err, "could not reset "+reposPath.String()+" to "+head))
+		}
+	}
+	return merr.ErrorOrNil()
+}
+
+// restoreSnapshotRepos makes the repository the same as repos of the
+// snapshot. current is the entry of current lock.json (nil if it is not in
+// lock.json). Cloned or downloaded repositories are recorded to trx.
+func restoreSnapshotRepos(ctx context.Context, get *getCmd, repos, current *lockjson.Repos, cfg *config.Config, trx transaction.Transaction, log *logger.Buffer) (string, error) {
+	fullpath := repos.Path.FullPath()
+	switch repos.Type {
+	case lockjson.ReposGitType:
+		cloned := false
+		if !pathutil.Exists(fullpath) {
+			log.Debug("Cloning " + repos.Path + " ...")
+			if err := get.clonePlugin(ctx, repos.Path, repos.Clone, cfg, log); err != nil {
+				get.removeDir(fullpath)
+				return "", err
+			}
+			trx.Created(fullpath)
+			cloned = true
+		}
+		head, err := gitutil.GetHEAD(repos.Path)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get HEAD commit hash")
+		}
+		if head == repos.Version {
+			if cloned {
+				if err := get.updateSubmodules(ctx, repos.Path, log); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf(fmtInstalled, repos.Path), nil
+			}
+			return fmt.Sprintf(fmtNoChange, repos.Path), nil
+		}
+		if changed, err := hasLocalChanges(repos.Path); err != nil {
+			return "", err
+		} else if changed {
+			return "", errors.New("the repository has local changes")
+		}
+		r, err := git.PlainOpen(fullpath)
+		if err != nil {
+			return "", err
+		}
+		log.Debugf("Resetting %s to %s ...", repos.Path, repos.Version)
+		if err := gitutil.ResetHEAD(r, repos.Version); err != nil {
+			// The commit may not be fetched yet
+			log.Debugf("Could not reset %s, checking out: %s", repos.Path, err)
+			ref := pathutil.ReposRef{Type: pathutil.ReposRefCommit, Name: repos.Version}
+			if err := get.checkoutRef(ctx, repos.Path, ref, true, cfg, log); err != nil {
+				return "", err
+			}
+		}
+		if err := get.updateSubmodules(ctx, repos.Path, log); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("* %s > restored (%s..%s)", repos.Path, head, repos.Version), nil
+	case lockjson.ReposReleaseType:
+		if pathutil.Exists(fullpath) && current != nil && current.Type == repos.Type && current.Version == repos.Version {
+			return fmt.Sprintf(fmtNoChange, repos.Path), nil
+		}
+		if err := trx.Remove(fullpath); err != nil {
+			return "", err
+		}
+		trx.Created(fullpath)
+		log.Debugf("Downloading release %s of %s ...", repos.Version, repos.Path)
+		if err := get.downloadRelease(ctx, repos.Path, repos.Version); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("* %s > restored release %s", repos.Path, repos.Version), nil
+	case lockjson.ReposArchiveType:
+		if pathutil.Exists(fullpath) && current != nil && current.Type == repos.Type && current.Version == repos.Version {
+			return fmt.Sprintf(fmtNoChange, repos.Path), nil
+		}
+		if err := trx.Remove(fullpath); err != nil {
+			return "", err
+		}
+		trx.Created(fullpath)
+		log.Debugf("Downloading %s ...", repos.URL)
+		if _, err := get.downloadArchive(ctx, repos.Path, repos.URL, repos.Version, ""); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("* %s > restored archive %s", repos.Path, shortArchiveHash(repos.Version)), nil
+	default:
+		if !pathutil.Exists(fullpath) {
+			return "", errors.Errorf("%s repository does not exist, restore the directory", repos.Type)
+		}
+		return fmt.Sprintf(fmtNoChange, repos.Path), nil
+	}
+}
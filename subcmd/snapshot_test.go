@@ -0,0 +1,168 @@
+package subcmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/transaction"
+)
+
+func TestValidateSnapshotName(t *testing.T) {
+	for _, name := range []string{"before-lsp", "2018-04-01", "a.b"} {
+		if err := validateSnapshotName(name); err != nil {
+			t.Errorf("%q: expected valid but got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", ".hidden", "a/b", `a\b`} {
+		if err := validateSnapshotName(name); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+}
+
+func TestSnapshotCreate(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	cmd := &snapshotCmd{}
+	if err := cmd.doCreate([]string{"empty"}); err == nil {
+		t.Error("expected an error without lock.json")
+	}
+
+	lockJSON := &lockjson.LockJSON{
+		Version:            2,
+		CurrentProfileName: "default",
+		Repos: lockjson.ReposList{
+			{Type: lockjson.ReposStaticType, Path: "localhost/local/hello"},
+		},
+		Profiles: lockjson.ProfileList{
+			{Name: "default", ReposPath: []pathutil.ReposPath{"localhost/local/hello"}},
+		},
+	}
+	if err := lockJSON.Write(); err != nil {
+		t.Fatal(err)
+	}
+	plugconf := pathutil.ReposPath("localhost/local/hello").Plugconf()
+	if err := os.MkdirAll(filepath.Dir(plugconf), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(plugconf, []byte("\" plugconf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cmd.doCreate([]string{"first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.doCreate([]string{"-plugconf", "second"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.doCreate([]string{"first"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected 'already exists' error but got %v", err)
+	}
+
+	snapLockJSON, err := lockjson.ReadFile(filepath.Join(snapshotDir("first"), "lock.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapLockJSON.Repos.FindByPath("localhost/local/hello") == nil {
+		t.Errorf("expected the snapshot has localhost/local/hello: %+v", snapLockJSON.Repos)
+	}
+	if !pathutil.Exists(filepath.Join(snapshotDir("second"), "plugconf", "localhost", "local", "hello.vim")) {
+		t.Error("expected the snapshot has plugconf")
+	}
+
+	snapshots, err := readSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := writeSnapshots(&buf, snapshots); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "first ") || strings.Contains(lines[0], "plugconf") ||
+		!strings.HasPrefix(lines[1], "second ") || !strings.HasSuffix(lines[1], "(with plugconf)") {
+		t.Errorf("unexpected list: %q", buf.String())
+	}
+}
+
+func TestRestoreSnapshotStaticRepos(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	repos := &lockjson.Repos{Type: lockjson.ReposStaticType, Path: "localhost/local/hello"}
+	if _, err := restoreSnapshotRepos(context.Background(), &getCmd{}, repos, nil, nil, nil, logger.NewBuffer()); err == nil {
+		t.Error("expected an error for missing static repository")
+	}
+	if err := os.MkdirAll(repos.Path.FullPath(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	status, err := restoreSnapshotRepos(context.Background(), &getCmd{}, repos, nil, nil, nil, logger.NewBuffer())
+	if err != nil || status != "# localhost/local/hello > no change" {
+		t.Errorf("unexpected result: (%q, %v)", status, err)
+	}
+}
+
+func TestRestoreSnapshotPlugconf(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	snapPlugconf := filepath.Join(tempDir, "snapshot", "plugconf")
+	saved := filepath.Join(snapPlugconf, "github.com", "tyru", "caw.vim.vim")
+	kept := pathutil.ReposPath("github.com/tyru/caw.vim").Plugconf()
+	added := pathutil.ReposPath("github.com/tyru/open-browser.vim").Plugconf()
+	for path, content := range map[string]string{saved: "snapshot", kept: "current", added: "added"} {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trx, err := transaction.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restoreSnapshotPlugconf(trx, snapPlugconf); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := ioutil.ReadFile(kept); err != nil || string(content) != "snapshot" {
+		t.Errorf("expected the plugconf of the snapshot but got %q (%v)", content, err)
+	}
+	if pathutil.Exists(added) {
+		t.Error("expected the plugconf added after the snapshot is removed")
+	}
+
+	// The previous plugconf files are restored by rollback
+	if err := trx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := ioutil.ReadFile(kept); err != nil || string(content) != "current" {
+		t.Errorf("expected the current plugconf is restored but got %q (%v)", content, err)
+	}
+	if !pathutil.Exists(added) {
+		t.Error("expected the added plugconf is restored")
+	}
+}
@@ -0,0 +1,153 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/builder"
+)
+
+func init() {
+	cmdMap["status"] = &statusCmd{}
+}
+
+type statusCmd struct {
+	helped bool
+}
+
+func (cmd *statusCmd) ProhibitRootExecution(args []string) bool { return false }
+
+func (cmd *statusCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt status [-help]
+
+Quick example
+  $ volt status
+
+    github.com/tyru/caw.vim > ok
+  ! github.com/junegunn/fzf > HEAD is 2f0c9d5... but locked version is 8b1b8e8...
+  * github.com/junegunn/fzf > worktree has local changes
+  ? github.com/tyru/open-browser.vim > not in lock.json
+  ~ github.com/tyru/skk.vim > build is stale: not installed
+
+Description
+  Show the status of each repository in lock.json and $VOLTPATH/repos, and
+  whether ~/.vim/pack/volt is stale:
+
+    ! {repository} > directory is missing
+    ! {repository} > HEAD is {commit} but locked version is {version}
+        lock.json and $VOLTPATH/repos are different
+        (fix them by "volt verify-lock -repair")
+    * {repository} > worktree has local changes
+        The git repository has uncommitted changes
+    ? {repository} > not in lock.json
+        The directory in $VOLTPATH/repos is not in lock.json
+        (fix it by "volt verify-lock -repair")
+    ~ {repository} > build is stale: {reason}
+        The installed files in ~/.vim/pack/volt are different from lock.json
+        (fix them by "volt build")
+
+  "{repository} > ok" is shown if there are no problems. If all repositories
+  must be installed again (e.g. build.strategy was changed), it is shown as:
+
+    ~ ~/.vim/pack/volt > build is stale: {reason}
+
+  Nothing is changed by this command.` + "\n\n")
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *statusCmd) Run(cmdctx *CmdContext) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(cmdctx.Args)
+	if cmd.helped {
+		return nil
+	}
+	if len(fs.Args()) > 0 {
+		return &Error{Code: 10, Msg: "Failed to parse args: status command does not accept arguments"}
+	}
+
+	stale, err := builder.CheckStale(cmdctx.LockJSON, cmdctx.Config)
+	if err != nil {
+		return &Error{Code: 11, Msg: "Failed to read build-info.json: " + err.Error()}
+	}
+	if err := cmd.showStatus(os.Stdout, cmdctx.LockJSON, stale); err != nil {
+		return &Error{Code: 12, Msg: "Failed to check status: " + err.Error()}
+	}
+	return nil
+}
+
+// showStatus writes the status lines of each repository to w.
+func (cmd *statusCmd) showStatus(w io.Writer, lockJSON *lockjson.LockJSON, stale *builder.StaleBuild) error {
+	problems, err := findLockProblems(lockJSON)
+	if err != nil {
+		return err
+	}
+
+	// Repositories are shown in the order of lock.json, the untracked
+	// directories, and the installed repositories not in lock.json
+	var order []pathutil.ReposPath
+	lines := make(map[pathutil.ReposPath][]string)
+	add := func(reposPath pathutil.ReposPath, line string) {
+		if _, ok := lines[reposPath]; !ok {
+			order = append(order, reposPath)
+		}
+		if line != "" {
+			lines[reposPath] = append(lines[reposPath], line)
+		} else if lines[reposPath] == nil {
+			lines[reposPath] = []string{}
+		}
+	}
+
+	missing := make(map[pathutil.ReposPath]bool)
+	for i := range lockJSON.Repos {
+		add(lockJSON.Repos[i].Path, "")
+	}
+	for i := range problems {
+		add(problems[i].reposPath, problems[i].String())
+		if problems[i].kind == lockProblemMissing {
+			missing[problems[i].reposPath] = true
+		}
+	}
+	for i := range lockJSON.Repos {
+		repos := &lockJSON.Repos[i]
+		if repos.Type != lockjson.ReposGitType || missing[repos.Path] {
+			continue
+		}
+		changed, err := hasLocalChanges(repos.Path)
+		if err != nil {
+			return errors.Wrap(err, "failed to get worktree status of "+repos.Path.String())
+		}
+		if changed {
+			add(repos.Path, fmt.Sprintf("* %s > worktree has local changes", repos.Path))
+		}
+	}
+	for i := range stale.Repos {
+		add(stale.Repos[i].Path, fmt.Sprintf("~ %s > build is stale: %s", stale.Repos[i].Path, stale.Repos[i].Reason))
+	}
+
+	for _, reposPath := range order {
+		if len(lines[reposPath]) == 0 {
+			fmt.Fprintf(w, "  %s > ok\n", reposPath)
+			continue
+		}
+		for _, line := range lines[reposPath] {
+			fmt.Fprintln(w, line)
+		}
+	}
+	if stale.All != "" {
+		fmt.Fprintf(w, "~ %s > build is stale: %s\n", pathutil.VimVoltDir(), stale.All)
+	}
+	return nil
+}
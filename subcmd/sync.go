@@ -0,0 +1,99 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func init() {
+	cmdMap["sync"] = &syncCmd{}
+}
+
+type syncCmd struct {
+	helped bool
+	prune  bool
+}
+
+func (cmd *syncCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *syncCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt sync [-help] [-prune]
+
+Quick example
+  $ volt sync         # installs the repositories of lock.json which do not exist
+  $ volt sync -prune  # also removes the repositories which are not in lock.json
+
+Description
+  Make $VOLTPATH/repos the same as lock.json, and build ~/.vim/pack/volt.
+  This reproduces the plugins from lock.json checked into dotfiles:
+
+    $ ln -s ~/dotfiles/volt/lock.json ~/volt/lock.json
+    $ volt sync -prune
+
+  * The repositories in lock.json which do not exist are cloned and checked
+    out at the locked version (git repository), or the locked release is
+    downloaded (release repository). Static repositories cannot be installed.
+  * The locked version is checked out in the git repositories whose HEAD is
+    another commit. It is fetched if it does not exist. A repository which
+    has local changes is not changed.
+  * The directories in $VOLTPATH/repos which are not in lock.json are removed
+    if -prune was given.
+
+  lock.json is not changed. If some repositories could not be synchronized,
+  volt exits with non-zero status.` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.BoolVar(&cmd.prune, "prune", false, "remove repositories which are not in lock.json")
+	return fs
+}
+
+func (cmd *syncCmd) Run(cmdctx *CmdContext) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(cmdctx.Args)
+	if cmd.helped {
+		return nil
+	}
+	if len(fs.Args()) > 0 {
+		return &Error{Code: 10, Msg: "Failed to parse args: sync command does not accept arguments"}
+	}
+
+	problems, err := findLockProblems(cmdctx.LockJSON)
+	if err != nil {
+		return &Error{Code: 11, Msg: "Failed to verify lock.json: " + err.Error()}
+	}
+	if len(problems) == 0 {
+		logger.Info(pathutil.ReposDir() + " is already in sync with lock.json")
+		return nil
+	}
+
+	// Untracked directories are not the failures of sync unless -prune
+	// was given
+	skipped := 0
+	untracked := func(reposPath pathutil.ReposPath) (string, string, error) {
+		if cmd.prune {
+			return "r", "", nil
+		}
+		skipped++
+		return "s", `run "volt sync -prune" to remove it`, nil
+	}
+	remains, err := (&verifyLockCmd{}).repairProblems(cmdctx.Ctx, problems, cmdctx.LockJSON, cmdctx.Config, untracked)
+	if err != nil {
+		return &Error{Code: 12, Msg: "Failed to sync: " + err.Error()}
+	}
+	if remains -= skipped; remains > 0 {
+		return &Error{Code: 13, Msg: fmt.Sprintf("%d repositories could not be synchronized", remains)}
+	}
+	return nil
+}
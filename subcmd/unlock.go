@@ -0,0 +1,90 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/transaction"
+)
+
+func init() {
+	cmdMap["unlock"] = &unlockCmd{}
+}
+
+type unlockCmd struct {
+	helped bool
+	force  bool
+}
+
+func (cmd *unlockCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *unlockCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt unlock [-help] [-force]
+
+Quick example
+  $ volt unlock         # release the lock of a crashed volt process
+  $ volt unlock -force  # release the lock even if its process is running
+
+Description
+  Release $VOLTPATH/trx/lock, which is created while volt changes
+  $VOLTPATH or ~/.vim/pack/volt (get, rm, build, ...).
+
+  If the volt process which created the lock is no longer running, the lock
+  is taken over automatically by the next command. This command is for the
+  remaining cases: the lock was created by older volt (its PID is unknown),
+  or the process is still running (e.g. it hangs). The latter requires
+  -force.
+
+  If the interrupted operation removed or overwrote any files, the lock is
+  moved to $VOLTPATH/trx/interrupted-{time} instead of being removed.
+  "journal" in the directory shows what the operation changed, and "backup"
+  has the previous files.` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.BoolVar(&cmd.force, "force", false, "release the lock even if its process is running")
+	return fs
+}
+
+func (cmd *unlockCmd) Run(cmdctx *CmdContext) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(cmdctx.Args)
+	if cmd.helped {
+		return nil
+	}
+	if len(fs.Args()) > 0 {
+		return &Error{Code: 10, Msg: "Failed to parse args: unlock command does not accept arguments"}
+	}
+
+	info, err := transaction.ReadLock()
+	if err != nil {
+		return &Error{Code: 11, Msg: "Could not read the lock: " + err.Error()}
+	}
+	if info == nil {
+		logger.Info("No lock exists")
+		return nil
+	}
+	if info.Running && !cmd.force {
+		return &Error{Code: 12, Msg: fmt.Sprintf("The volt process (PID %d) which holds the lock is running: wait for it, or run \"volt unlock -force\"", info.PID)}
+	}
+
+	moved, err := transaction.ReleaseLock(info)
+	if err != nil {
+		return &Error{Code: 13, Msg: "Could not release the lock: " + err.Error()}
+	}
+	if moved != "" {
+		logger.Infof("Released the lock (moved to %s: see \"journal\" in it for what the interrupted operation changed)", moved)
+	} else {
+		logger.Info("Released the lock")
+	}
+	return nil
+}
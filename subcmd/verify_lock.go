@@ -0,0 +1,450 @@
+package subcmd
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/builder"
+	"github.com/vim-volt/volt/transaction"
+)
+
+func init() {
+	cmdMap["verify-lock"] = &verifyLockCmd{}
+}
+
+type verifyLockCmd struct {
+	helped bool
+	repair bool
+}
+
+func (cmd *verifyLockCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *verifyLockCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt verify-lock [-help] [-repair]
+
+Quick example
+  $ volt verify-lock          # will show differences between lock.json and $VOLTPATH/repos
+  $ volt verify-lock -repair  # will fix them
+
+Description
+  Check that lock.json and the repositories in $VOLTPATH/repos are
+  consistent, and show the following problems:
+
+    ! {repository} > directory is missing
+        The repository in lock.json does not exist in $VOLTPATH/repos.
+        -repair clones the repository and checks out the locked version
+        (git repository), or downloads the locked release (release
+        repository). Static repositories cannot be repaired.
+
+    ! {repository} > HEAD is {commit} but locked version is {version}
+        -repair checks out the locked version as detached HEAD (see
+        "Version" of "volt get -help"). A repository which has local changes
+        is not repaired.
+
+    ? {repository} > not in lock.json
+        The directory in $VOLTPATH/repos is not in lock.json.
+        -repair asks whether to adopt it (add it to lock.json and current
+        profile like "volt get"), or to remove the directory. It is skipped
+        if stdin is not a terminal.
+
+  If there are problems which are not repaired, volt exits with non-zero
+  status.` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.BoolVar(&cmd.repair, "repair", false, "fix the problems")
+	return fs
+}
+
+func (cmd *verifyLockCmd) Run(cmdctx *CmdContext) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(cmdctx.Args)
+	if cmd.helped {
+		return nil
+	}
+	if len(fs.Args()) > 0 {
+		return &Error{Code: 10, Msg: "Failed to parse args: verify-lock command does not accept arguments"}
+	}
+
+	problems, err := findLockProblems(cmdctx.LockJSON)
+	if err != nil {
+		return &Error{Code: 11, Msg: "Failed to verify lock.json: " + err.Error()}
+	}
+	if len(problems) == 0 {
+		logger.Info("lock.json is consistent with " + pathutil.ReposDir())
+		return nil
+	}
+
+	if !cmd.repair {
+		for i := range problems {
+			fmt.Println(problems[i].String())
+		}
+		return &Error{Code: 20, Msg: fmt.Sprintf("Found %d problems (run \"volt verify-lock -repair\" to fix them)", len(problems))}
+	}
+
+	interactive := isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd())
+	in := bufio.NewReader(os.Stdin)
+	untracked := func(reposPath pathutil.ReposPath) (string, string, error) {
+		if !interactive {
+			return "s", "stdin is not a terminal", nil
+		}
+		answer, err := askUntracked(reposPath, in, os.Stdout)
+		return answer, "", err
+	}
+	remains, err := cmd.repairProblems(cmdctx.Ctx, problems, cmdctx.LockJSON, cmdctx.Config, untracked)
+	if err != nil {
+		return &Error{Code: 21, Msg: "Failed to repair: " + err.Error()}
+	}
+	if remains > 0 {
+		return &Error{Code: 22, Msg: fmt.Sprintf("%d problems were not repaired", remains)}
+	}
+	return nil
+}
+
+type lockProblemKind int
+
+const (
+	// lockProblemMissing is a repository in lock.json which does not exist
+	lockProblemMissing lockProblemKind = iota
+	// lockProblemHEAD is a git repository whose HEAD is not the locked version
+	lockProblemHEAD
+	// lockProblemUntracked is a directory in $VOLTPATH/repos which is not in
+	// lock.json
+	lockProblemUntracked
+)
+
+// lockProblem is a difference between lock.json and $VOLTPATH/repos.
+type lockProblem struct {
+	kind      lockProblemKind
+	reposPath pathutil.ReposPath
+	// repos is nil if kind is lockProblemUntracked
+	repos *lockjson.Repos
+	// head is HEAD commit if kind is lockProblemHEAD
+	head string
+}
+
+func (p *lockProblem) String() string {
+	switch p.kind {
+	case lockProblemMissing:
+		return fmt.Sprintf("! %s > directory is missing", p.reposPath)
+	case lockProblemHEAD:
+		return fmt.Sprintf("! %s > HEAD is %s but locked version is %s", p.reposPath, p.head, p.repos.Version)
+	default:
+		return fmt.Sprintf("? %s > not in lock.json", p.reposPath)
+	}
+}
+
+// findLockProblems returns differences between lock.json and
+// $VOLTPATH/repos.
+func findLockProblems(lockJSON *lockjson.LockJSON) ([]lockProblem, error) {
+	var problems []lockProblem
+	for i := range lockJSON.Repos {
+		repos := &lockJSON.Repos[i]
+		if !pathutil.Exists(repos.Path.FullPath()) {
+			problems = append(problems, lockProblem{kind: lockProblemMissing, reposPath: repos.Path, repos: repos})
+			continue
+		}
+		if repos.Type != lockjson.ReposGitType {
+			continue
+		}
+		head, err := gitutil.GetHEAD(repos.Path)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get HEAD commit hash of "+repos.Path.String())
+		}
+		if head != repos.Version {
+			problems = append(problems, lockProblem{kind: lockProblemHEAD, reposPath: repos.Path, repos: repos, head: head})
+		}
+	}
+
+	dirs, err := reposDirsOnDisk()
+	if err != nil {
+		return nil, err
+	}
+	// The directories removed by "volt rm -keep-repos" are not managed
+	kept, err := readKeptRepos()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read "+pathutil.KeptReposFile())
+	}
+	for _, dir := range dirs {
+		reposPath, err := pathutil.ReposPathOfDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if lockJSON.Repos.FindByPath(reposPath) == nil && !kept.Contains(reposPath) {
+			problems = append(problems, lockProblem{kind: lockProblemUntracked, reposPath: reposPath})
+		}
+	}
+	return problems, nil
+}
+
+// reposDirsOnDisk returns "$VOLTPATH/repos/{host}/{user}/{name}" directories.
+// Hidden directories (e.g. temporary directories of "volt get") are ignored.
+func reposDirsOnDisk() ([]string, error) {
+	dirs := []string{pathutil.ReposDir()}
+	for depth := 0; depth < 3; depth++ {
+		var children []string
+		for _, dir := range dirs {
+			infos, err := ioutil.ReadDir(dir)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			for _, fi := range infos {
+				if fi.IsDir() && !strings.HasPrefix(fi.Name(), ".") {
+					children = append(children, filepath.Join(dir, fi.Name()))
+				}
+			}
+		}
+		dirs = children
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// untrackedFunc decides what to do with the directory which is not in
+// lock.json, and returns "a" (adopt), "r" (remove), or "s" (skip).
+// note is shown with the problem if it is skipped.
+type untrackedFunc func(reposPath pathutil.ReposPath) (answer, note string, err error)
+
+// repairProblems fixes problems, and returns the number of problems which
+// were not repaired.
+func (cmd *verifyLockCmd) repairProblems(ctx context.Context, problems []lockProblem, lockJSON *lockjson.LockJSON, cfg *config.Config, untracked untrackedFunc) (remains int, err error) {
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil || ctx.Err() != nil {
+			if e := trx.Rollback(); e != nil {
+				err = multierror.Append(err, errors.Wrap(e, "failed to rollback"))
+			}
+			return
+		}
+		if e := trx.Done(); e != nil {
+			err = e
+		}
+	}()
+
+	get := &getCmd{}
+	statusList := make([]string, 0, len(problems))
+	var updatedLockJSON, modified bool
+	for i := range problems {
+		p := &problems[i]
+		var status string
+		var repaired bool
+		log := logger.NewReposBuffer(p.reposPath.String())
+		switch p.kind {
+		case lockProblemMissing:
+			status, repaired = cmd.restoreRepos(ctx, get, p.repos, cfg, trx, log)
+		case lockProblemHEAD:
+			status, repaired = cmd.checkoutLocked(ctx, get, p, cfg, log)
+		case lockProblemUntracked:
+			var answer, note string
+			answer, note, err = untracked(p.reposPath)
+			if err != nil {
+				return 0, err
+			}
+			switch answer {
+			case "a":
+				status, repaired = cmd.adoptRepos(get, p.reposPath, lockJSON)
+				updatedLockJSON = updatedLockJSON || repaired
+			case "r":
+				status, repaired = cmd.removeRepos(trx, p.reposPath)
+			default:
+				if note != "" {
+					status = p.String() + " (skipped: " + note + ")"
+				} else {
+					status = p.String() + " (skipped)"
+				}
+			}
+		}
+		log.Flush()
+		if repaired {
+			modified = true
+		} else {
+			remains++
+		}
+		statusList = append(statusList, status)
+	}
+
+	if ctx.Err() != nil {
+		return 0, errors.New("interrupted")
+	}
+	if updatedLockJSON {
+		if err := lockJSON.Write(); err != nil {
+			return 0, errors.Wrap(err, "could not write to lock.json")
+		}
+	}
+	if modified {
+		if err := builder.Build(ctx, false); err != nil {
+			return 0, errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+		}
+	}
+
+	for i := range statusList {
+		fmt.Println(statusList[i])
+	}
+	return remains, nil
+}
+
+// restoreRepos clones or downloads the missing repository at the locked
+// version.
+func (*verifyLockCmd) restoreRepos(ctx context.Context, get *getCmd, repos *lockjson.Repos, cfg *config.Config, trx transaction.Transaction, log *logger.Buffer) (string, bool) {
+	failed := func(err error) (string, bool) {
+		return fmt.Sprintf("! %s > could not restore: %s", repos.Path, err), false
+	}
+	fullpath := repos.Path.FullPath()
+	switch repos.Type {
+	case lockjson.ReposGitType:
+		log.Debug("Cloning " + repos.Path + " ...")
+		if err := get.clonePlugin(ctx, repos.Path, repos.Clone, cfg, log); err != nil {
+			get.removeDir(fullpath)
+			return failed(err)
+		}
+		trx.Created(fullpath)
+		ref := pathutil.ReposRef{Type: pathutil.ReposRefCommit, Name: repos.Version}
+		if err := get.checkoutRef(ctx, repos.Path, ref, false, cfg, log); err != nil {
+			return failed(errors.Wrap(err, "cloned but could not check out "+repos.Version))
+		}
+		return fmt.Sprintf("* %s > cloned (%s)", repos.Path, repos.Version), true
+	case lockjson.ReposReleaseType:
+		log.Debugf("Downloading release %s of %s ...", repos.Version, repos.Path)
+		if err := get.downloadRelease(ctx, repos.Path, repos.Version); err != nil {
+			return failed(err)
+		}
+		trx.Created(fullpath)
+		return fmt.Sprintf("* %s > downloaded release %s", repos.Path, repos.Version), true
+	case lockjson.ReposArchiveType:
+		log.Debugf("Downloading %s ...", repos.URL)
+		if _, err := get.downloadArchive(ctx, repos.Path, repos.URL, repos.Version, ""); err != nil {
+			return failed(err)
+		}
+		trx.Created(fullpath)
+		return fmt.Sprintf("* %s > downloaded archive %s", repos.Path, shortArchiveHash(repos.Version)), true
+	default:
+		return failed(errors.Errorf("%s repository cannot be restored, restore the directory or run \"volt rm %s\"", repos.Type, repos.Path))
+	}
+}
+
+// checkoutLocked checks out the locked version of the repository.
+func (*verifyLockCmd) checkoutLocked(ctx context.Context, get *getCmd, p *lockProblem, cfg *config.Config, log *logger.Buffer) (string, bool) {
+	failed := func(err error) (string, bool) {
+		return fmt.Sprintf("! %s > could not check out %s: %s", p.reposPath, p.repos.Version, err), false
+	}
+	if changed, err := hasLocalChanges(p.reposPath); err != nil {
+		return failed(err)
+	} else if changed {
+		return failed(errors.New("the repository has local changes"))
+	}
+	ref := pathutil.ReposRef{Type: pathutil.ReposRefCommit, Name: p.repos.Version}
+	if err := get.checkoutRef(ctx, p.reposPath, ref, false, cfg, log); err != nil {
+		return failed(err)
+	}
+	return fmt.Sprintf(fmtCheckedOut, p.reposPath, ref, p.head, p.repos.Version), true
+}
+
+// hasLocalChanges returns true if the worktree of the git repository has
+// changes. A bare repository has no changes.
+func hasLocalChanges(reposPath pathutil.ReposPath) (bool, error) {
+	r, err := git.PlainOpen(reposPath.FullPath())
+	if err != nil {
+		return false, err
+	}
+	wt, err := r.Worktree()
+	if err == git.ErrIsBareRepository {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	st, err := wt.Status()
+	if err != nil {
+		return false, err
+	}
+	return !st.IsClean(), nil
+}
+
+// adoptRepos adds the repository to lock.json and current profile.
+func (*verifyLockCmd) adoptRepos(get *getCmd, reposPath pathutil.ReposPath, lockJSON *lockjson.LockJSON) (string, bool) {
+	failed := func(err error) (string, bool) {
+		return fmt.Sprintf("! %s > could not adopt: %s", reposPath, err), false
+	}
+	reposType, err := get.detectReposType(reposPath.FullPath())
+	if err != nil {
+		return failed(err)
+	}
+	var version string
+	if reposType == lockjson.ReposGitType {
+		if version, err = gitutil.GetHEAD(reposPath); err != nil {
+			return failed(err)
+		}
+	}
+	profile, err := lockJSON.Profiles.FindByName(lockJSON.CurrentProfileName)
+	if err != nil {
+		return failed(err)
+	}
+	get.updateReposVersion(lockJSON, &getParallelResult{
+		reposPath: reposPath,
+		reposType: reposType,
+		hash:      version,
+	}, profile)
+	return fmt.Sprintf(fmtAddedRepos, reposPath), true
+}
+
+// removeRepos removes the directory of the repository in trx.
+func (*verifyLockCmd) removeRepos(trx transaction.Transaction, reposPath pathutil.ReposPath) (string, bool) {
+	fullpath := reposPath.FullPath()
+	if err := trx.Remove(fullpath); err != nil {
+		return fmt.Sprintf("! %s > could not remove: %s", reposPath, err), false
+	}
+	fileutil.RemoveDirs(filepath.Dir(fullpath))
+	return fmt.Sprintf("- %s > removed the directory", reposPath), true
+}
+
+// askUntracked asks what to do with the repository which is not in
+// lock.json, and returns "a" (adopt), "r" (remove), or "s" (skip).
+func askUntracked(reposPath pathutil.ReposPath, in *bufio.Reader, out io.Writer) (string, error) {
+	for {
+		fmt.Fprintf(out, "%s is not in lock.json. [a]dopt, [r]emove, or [s]kip? [a/r/S]: ", reposPath)
+		line, err := in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", errors.Wrap(err, "could not read the answer")
+		}
+		switch answer := strings.ToLower(strings.TrimSpace(line)); answer {
+		case "a", "r", "s":
+			return answer, nil
+		case "":
+			return "s", nil
+		}
+		if err == io.EOF {
+			return "s", nil
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package subcmd
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestFindLockProblems(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", tempDir)
+
+	lockJSON := &lockjson.LockJSON{
+		Repos: lockjson.ReposList{
+			{Type: lockjson.ReposStaticType, Path: "localhost/local/hello"},
+			{Type: lockjson.ReposReleaseType, Path: "github.com/junegunn/fzf", Version: "0.17.0"},
+		},
+	}
+	for _, dir := range []string{
+		pathutil.ReposPath("localhost/local/hello").FullPath(),
+		pathutil.ReposPath("github.com/tyru/caw.vim").FullPath(),
+		filepath.Join(pathutil.ReposDir(), "github.com", "tyru", ".caw.vim-123"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	problems, err := findLockProblems(lockJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for i := range problems {
+		got = append(got, problems[i].String())
+	}
+	expected := []string{
+		"! github.com/junegunn/fzf > directory is missing",
+		"? github.com/tyru/caw.vim > not in lock.json",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q but got %q", expected, got)
+	}
+
+	// The directories removed by "volt rm -keep-repos" are not reported
+	if err := writeKeptRepos(pathutil.ReposPathList{"github.com/tyru/caw.vim"}); err != nil {
+		t.Fatal(err)
+	}
+	problems, err = findLockProblems(lockJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].kind != lockProblemMissing {
+		t.Errorf("expected only the missing directory is reported but got %v", problems)
+	}
+}
+
+func TestAskUntracked(t *testing.T) {
+	for _, tt := range []struct {
+		input    string
+		expected string
+	}{
+		{"a\n", "a"},
+		{"R\n", "r"},
+		{"\n", "s"},
+		{"", "s"},
+		{"yes\nr\n", "r"},
+	} {
+		var out bytes.Buffer
+		answer, err := askUntracked("github.com/tyru/caw.vim", bufio.NewReader(strings.NewReader(tt.input)), &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if answer != tt.expected {
+			t.Errorf("%q: expected %q but got %q", tt.input, tt.expected, answer)
+		}
+	}
+}
@@ -40,9 +40,9 @@ Description
 	return fs
 }
 
-func (cmd *versionCmd) Run(args []string) *Error {
+func (cmd *versionCmd) Run(cmdctx *CmdContext) *Error {
 	fs := cmd.FlagSet()
-	fs.Parse(args)
+	fs.Parse(cmdctx.Args)
 	if cmd.helped {
 		return nil
 	}
@@ -51,16 +51,18 @@ func (cmd *versionCmd) Run(args []string) *Error {
 	return nil
 }
 
-// [major, minor, patch, alphaBeta]
+// [major, minor, patch, alphaBetaRC, prereleaseNumber]
 type versionInfo []int
 
 const (
 	suffixAlpha  = 1
 	suffixBeta   = 2
+	suffixRC     = 3
 	suffixStable = 9
 )
 
-var rxVersion = regexp.MustCompile(`^v?([0-9]+)\.([0-9]+)(?:\.([0-9]+))?(-alpha|-beta)?`)
+// e.g. "v0.3.6", "v0.4.0-beta", "v0.4.0-rc1", "v0.4.0-rc.2"
+var rxVersion = regexp.MustCompile(`^v?([0-9]+)\.([0-9]+)(?:\.([0-9]+))?(?:-(alpha|beta|rc)\.?([0-9]*))?`)
 
 func voltVersionInfo() versionInfo {
 	// parseVersion(voltVersionInfo) must not return non-nil error!
@@ -71,8 +73,13 @@ func voltVersionInfo() versionInfo {
 	return voltVersionInfo
 }
 
+// isPrerelease returns true if v is an alpha, beta, or release candidate.
+func isPrerelease(v versionInfo) bool {
+	return v[3] != suffixStable
+}
+
 func compareVersion(v1, v2 versionInfo) int {
-	for i := 0; i < 4; i++ {
+	for i := 0; i < 5; i++ {
 		if v1[i] > v2[i] {
 			return 1
 		} else if v1[i] < v2[i] {
@@ -87,7 +94,7 @@ func parseVersion(ver string) (versionInfo, error) {
 	if len(m) == 0 {
 		return nil, errors.New("version number format is invalid: " + ver)
 	}
-	info := make(versionInfo, 0, 4)
+	info := make(versionInfo, 0, 5)
 	for i := 1; i <= 3 && m[i] != ""; i++ {
 		n, err := strconv.Atoi(m[i])
 		if err != nil {
@@ -101,10 +108,20 @@ func parseVersion(ver string) (versionInfo, error) {
 	switch m[4] {
 	case "":
 		info = append(info, suffixStable)
-	case "-alpha":
+	case "alpha":
 		info = append(info, suffixAlpha)
-	case "-beta":
+	case "beta":
 		info = append(info, suffixBeta)
+	case "rc":
+		info = append(info, suffixRC)
+	}
+	n := 0
+	if m[5] != "" {
+		var err error
+		if n, err = strconv.Atoi(m[5]); err != nil {
+			return nil, err
+		}
 	}
+	info = append(info, n)
 	return info, nil
 }
@@ -20,6 +20,13 @@ func TestCompareVersion(t *testing.T) {
 		{"1.0-alpha", "1.0-beta", -1},
 		{"1.0-beta", "1.0", -1},
 		{"1.0", "1.0-alpha", 1},
+		{"0.4.0-beta", "0.4.0-rc1", -1},
+		{"0.4.0-rc1", "0.4.0-rc2", -1},
+		{"0.4.0-rc2", "0.4.0-rc10", -1},
+		{"0.4.0-rc.2", "0.4.0-rc2", 0},
+		{"0.4.0-rc", "0.4.0-rc1", -1},
+		{"0.4.0-rc1", "0.4.0", -1},
+		{"0.3.6", "0.4.0-rc1", -1},
 	} {
 		for _, prefix := range []string{"", "v"} {
 			v1 := parse(t, prefix+tt.v1)
@@ -41,7 +48,7 @@ func parse(t *testing.T, ver string) versionInfo {
 	if err != nil {
 		t.Errorf("\"%s\" should be a version number but isn't: %s", ver, err.Error())
 	}
-	if len(vinfo) != 4 {
+	if len(vinfo) != 5 {
 		t.Errorf("parseVersion(%q) returned invalid versionInfo: %q", ver, vinfo)
 	}
 	return vinfo
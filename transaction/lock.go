@@ -0,0 +1,97 @@
+package transaction
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// pidFileName is a filename under "lock" directory, which has the PID of the
+// volt process which started the transaction.
+const pidFileName = "pid"
+
+// LockInfo is the information of $VOLTPATH/trx/lock directory.
+type LockInfo struct {
+	// Dir is the path of "lock" directory
+	Dir string
+	// PID is the PID of the volt process which holds the lock.
+	// It is 0 if it is unknown (e.g. the lock was created by older volt)
+	PID int
+	// Running is true if the process of PID is running
+	Running bool
+	// ModTime is the time the lock was created
+	ModTime time.Time
+	// HasJournal is true if the transaction changed any files (see
+	// Transaction.Remove and Transaction.Write)
+	HasJournal bool
+}
+
+// ReadLock returns the information of $VOLTPATH/trx/lock directory, or nil if
+// it does not exist.
+func ReadLock() (*LockInfo, error) {
+	lockDir := lockDirPath()
+	fi, err := os.Stat(lockDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	info := &LockInfo{
+		Dir:        lockDir,
+		ModTime:    fi.ModTime(),
+		HasJournal: pathutil.Exists(filepath.Join(lockDir, journalName)),
+	}
+	if pid := readPIDFile(lockDir); pid > 0 {
+		info.PID = pid
+		info.Running = processIsAlive(pid)
+	}
+	return info, nil
+}
+
+// Stale returns true if the process which holds the lock is known to be
+// no longer running.
+func (info *LockInfo) Stale() bool {
+	return info.PID != 0 && !info.Running
+}
+
+// ReleaseLock releases $VOLTPATH/trx/lock directory of info.
+// If the transaction has a journal, the directory is moved to
+// $VOLTPATH/trx/interrupted-{unixtime} to keep the backups of removed or
+// overwritten files, and the new path is returned. Otherwise, the directory
+// is removed and "" is returned.
+func ReleaseLock(info *LockInfo) (string, error) {
+	if !info.HasJournal {
+		return "", os.RemoveAll(info.Dir)
+	}
+	dst := filepath.Join(pathutil.TrxDir(), fmt.Sprintf("interrupted-%d", time.Now().Unix()))
+	if err := os.Rename(info.Dir, dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+func lockDirPath() string {
+	return filepath.Join(pathutil.TrxDir(), "lock")
+}
+
+// readPIDFile returns the PID written in lockDir, or 0 if it is unknown.
+func readPIDFile(lockDir string) int {
+	content, err := ioutil.ReadFile(filepath.Join(lockDir, pidFileName))
+	if err != nil {
+		return 0
+	}
+	if pid, err := strconv.Atoi(strings.TrimSpace(string(content))); err == nil && pid > 0 {
+		return pid
+	}
+	return 0
+}
+
+func writePIDFile(lockDir string) error {
+	return ioutil.WriteFile(filepath.Join(lockDir, pidFileName), []byte(strconv.Itoa(os.Getpid())), 0644)
+}
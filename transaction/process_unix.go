@@ -0,0 +1,20 @@
+// +build !windows
+
+package transaction
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// processIsAlive returns true if the process of pid is running.
+func processIsAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// EPERM means the process exists but is owned by another user
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
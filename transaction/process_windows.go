@@ -0,0 +1,14 @@
+package transaction
+
+import "os"
+
+// processIsAlive returns true if the process of pid is running.
+// On Windows, os.FindProcess fails if the process does not exist.
+func processIsAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -1,28 +1,144 @@
 package transaction
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
+	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
 )
 
 // Start creates $VOLTPATH/trx/lock directory.
+// It also saves current lock.json to the directory to restore it by
+// Rollback(). The transaction is Current() until Done() or Rollback().
 func Start() (Transaction, error) {
 	os.MkdirAll(pathutil.TrxDir(), 0755)
-	lockDir := filepath.Join(pathutil.TrxDir(), "lock")
-	if err := os.Mkdir(lockDir, 0755); err != nil {
-		return nil, errors.Wrap(err, "failed to begin transaction: "+lockDir+" exists: if no other volt process is currently running, this probably means a volt process crashed earlier. Make sure no other volt process is running and remove the file manually to continue")
+	lockDir := lockDirPath()
+	if err := lock(lockDir); err != nil {
+		return nil, err
 	}
 	trxID, err := genNewTrxID()
 	if err != nil {
+		os.RemoveAll(lockDir)
 		return nil, errors.Wrap(err, "could not allocate a new transaction ID")
 	}
-	return &transaction{id: trxID}, nil
+	if err := saveLockJSON(lockDir); err != nil {
+		os.RemoveAll(lockDir)
+		return nil, errors.Wrap(err, "could not save lock.json")
+	}
+	trx := &transaction{id: trxID, lockDir: lockDir}
+	currentM.Lock()
+	current = trx
+	currentM.Unlock()
+	return trx, nil
+}
+
+// lock creates lockDir, and writes the PID of this process to it.
+// If lockDir exists but the process which created it is no longer running,
+// the lock is taken over.
+func lock(lockDir string) error {
+	err := os.Mkdir(lockDir, 0755)
+	if os.IsExist(err) {
+		info, rerr := ReadLock()
+		if rerr == nil && info != nil && info.Stale() {
+			if err = takeOver(info); err == nil {
+				err = os.Mkdir(lockDir, 0755)
+			}
+		}
+	}
+	if os.IsExist(err) {
+		return errors.Wrap(err, "failed to begin transaction: "+lockDir+" exists: if no other volt process is currently running, this probably means a volt process crashed earlier. Make sure no other volt process is running and run \"volt unlock\" to continue")
+	} else if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	if err := writePIDFile(lockDir); err != nil {
+		os.RemoveAll(lockDir)
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	return nil
+}
+
+// takeOver releases the stale lock of info.
+// Other processes may take over the same lock at the same time, so the lock
+// directory is renamed to a unique name first, and it is released only if it
+// is still the lock of info. If another process has already taken over the
+// lock, its new lock is moved back.
+func takeOver(info *LockInfo) error {
+	stale := filepath.Join(pathutil.TrxDir(), fmt.Sprintf("stale-%d-%d", os.Getpid(), time.Now().UnixNano()))
+	if err := os.Rename(info.Dir, stale); err != nil {
+		if os.IsNotExist(err) {
+			return nil // released by another process
+		}
+		return errors.Wrap(err, "failed to take over the lock of "+info.Dir)
+	}
+	if pid := readPIDFile(stale); pid != info.PID {
+		if err := os.Rename(stale, info.Dir); err != nil {
+			logger.Warnf("could not move back the lock of other volt process: %s", stale)
+		}
+		return errors.Errorf("failed to take over the lock of %s: other volt process (PID %d) has taken it over", info.Dir, pid)
+	}
+	staleInfo := *info
+	staleInfo.Dir = stale
+	moved, err := ReleaseLock(&staleInfo)
+	if err != nil {
+		return errors.Wrap(err, "failed to take over the lock of "+info.Dir)
+	}
+	logger.Warnf("took over the lock of the volt process (PID %d) which is no longer running", info.PID)
+	if moved != "" {
+		logger.Warnf("the interrupted operation may have changed files: see %s", moved)
+	}
+	return nil
+}
+
+var (
+	current  *transaction
+	currentM sync.Mutex
+)
+
+// Current returns the transaction which this process has started, or nil.
+func Current() Transaction {
+	currentM.Lock()
+	defer currentM.Unlock()
+	if current == nil {
+		return nil
+	}
+	return current
+}
+
+// WriteFile writes content to path in the current transaction (see
+// Transaction.Write), or just writes it if no transaction is started.
+func WriteFile(path string, content []byte, perm os.FileMode) error {
+	if trx := Current(); trx != nil {
+		return trx.Write(path, content, perm)
+	}
+	return ioutil.WriteFile(path, content, perm)
+}
+
+// RemoveAll removes path in the current transaction (see
+// Transaction.Remove), or just removes it if no transaction is started.
+func RemoveAll(path string) error {
+	if trx := Current(); trx != nil {
+		return trx.Remove(path)
+	}
+	return os.RemoveAll(path)
+}
+
+func (trx *transaction) end() {
+	currentM.Lock()
+	defer currentM.Unlock()
+	if current == trx {
+		current = nil
+	}
 }
 
 // Transaction provides transaction methods.
@@ -30,22 +146,237 @@ type Transaction interface {
 	// Done renames "lock" directory to "{trxid}" directory
 	Done() error
 
+	// Rollback removes created paths, restores lock.json at the time of
+	// Start(), and removes "lock" directory
+	Rollback() error
+
+	// Created adds path to the list of paths which are removed by Rollback()
+	Created(path string)
+
+	// Remove removes path, which is restored by Rollback()
+	Remove(path string) error
+
+	// Write writes content to path, whose previous content is restored by
+	// Rollback() (or path is removed if it did not exist)
+	Write(path string, content []byte, perm os.FileMode) error
+
 	// ID returns transaction ID
 	ID() TrxID
 }
 
 type transaction struct {
-	id TrxID
+	id      TrxID
+	lockDir string
+	m       sync.Mutex
+	journal []journalEntry
 }
 
+// savedLockJSONName is a filename of lock.json saved under "lock" directory.
+const savedLockJSONName = "lock.json"
+
+// journalName is a filename of the journal under "lock" directory, which
+// has the destructive steps of the transaction (JSON per line). Each entry is
+// appended before the step, so the journal shows what was done even if volt
+// crashed.
+const journalName = "journal"
+
+// backupDirName is a directory name under "lock" directory, which has the
+// files and directories removed or overwritten in the transaction.
+const backupDirName = "backup"
+
+// journalEntry is a destructive step in a transaction.
+type journalEntry struct {
+	Op   journalOp `json:"op"`
+	Path string    `json:"path"`
+	// Backup is the path where the previous path was moved or copied to.
+	// It is empty if path did not exist, or could not be backed up
+	Backup string `json:"backup,omitempty"`
+}
+
+type journalOp string
+
+const (
+	opCreate journalOp = "create"
+	opRemove journalOp = "remove"
+	opWrite  journalOp = "write"
+)
+
 func (trx *transaction) ID() TrxID {
 	return trx.id
 }
 
-// Done removes $VOLTPATH/trx/lock directory.
+// Done removes $VOLTPATH/trx/lock directory (and the backups in it).
 func (trx *transaction) Done() error {
-	lockDir := filepath.Join(pathutil.TrxDir(), "lock")
-	return os.Remove(lockDir)
+	trx.end()
+	return os.RemoveAll(trx.lockDir)
+}
+
+func (trx *transaction) Created(path string) {
+	trx.m.Lock()
+	defer trx.m.Unlock()
+	trx.record(journalEntry{Op: opCreate, Path: path})
+}
+
+// Remove moves path to the backup directory. If path could not be moved
+// (e.g. it is on another filesystem), it is removed without backup, and
+// Rollback() cannot restore it.
+func (trx *transaction) Remove(path string) error {
+	trx.m.Lock()
+	defer trx.m.Unlock()
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+	backup, err := trx.newBackupPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(path, backup); err != nil {
+		if os.IsPermission(err) {
+			return err
+		}
+		trx.record(journalEntry{Op: opRemove, Path: path})
+		return os.RemoveAll(path)
+	}
+	trx.record(journalEntry{Op: opRemove, Path: path, Backup: backup})
+	return nil
+}
+
+// Write copies path to the backup directory if it exists, and writes content
+// to path.
+func (trx *transaction) Write(path string, content []byte, perm os.FileMode) error {
+	trx.m.Lock()
+	defer trx.m.Unlock()
+	entry := journalEntry{Op: opWrite, Path: path}
+	if prev, err := ioutil.ReadFile(path); err == nil {
+		backup, err := trx.newBackupPath()
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(backup, prev, perm); err != nil {
+			return errors.Wrap(err, "could not back up "+path)
+		}
+		entry.Backup = backup
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	trx.record(entry)
+	return ioutil.WriteFile(path, content, perm)
+}
+
+// newBackupPath returns an unused path in the backup directory.
+func (trx *transaction) newBackupPath() (string, error) {
+	dir := filepath.Join(trx.lockDir, backupDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strconv.Itoa(len(trx.journal))), nil
+}
+
+// record appends entry to the journal. The journal file is best-effort,
+// the steps are rolled back by the journal in memory.
+func (trx *transaction) record(entry journalEntry) {
+	trx.journal = append(trx.journal, entry)
+	f, err := os.OpenFile(filepath.Join(trx.lockDir, journalName), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	json.NewEncoder(f).Encode(&entry)
+}
+
+// Rollback undoes the steps in the journal in reverse order (removes created
+// paths, restores removed or overwritten paths), and restores lock.json, then
+// removes $VOLTPATH/trx/lock directory.
+// $VOLTPATH/trx/lock directory is left if it failed to restore lock.json or
+// any removed or overwritten paths.
+func (trx *transaction) Rollback() error {
+	trx.m.Lock()
+	defer trx.m.Unlock()
+	defer trx.end()
+
+	var merr *multierror.Error
+	restored := true
+	for i := len(trx.journal) - 1; i >= 0; i-- {
+		if err := trx.journal[i].undo(); err != nil {
+			merr = multierror.Append(merr, err)
+			if trx.journal[i].Op != opCreate {
+				restored = false
+			}
+		}
+	}
+	trx.journal = nil
+
+	if err := restoreLockJSON(trx.lockDir); err != nil {
+		merr = multierror.Append(merr, errors.Wrap(err, "could not restore lock.json"))
+		return merr
+	}
+	if !restored {
+		return merr
+	}
+	if err := os.RemoveAll(trx.lockDir); err != nil {
+		merr = multierror.Append(merr, err)
+	}
+	return merr.ErrorOrNil()
+}
+
+// undo undoes the step of entry.
+func (entry *journalEntry) undo() error {
+	switch entry.Op {
+	case opCreate:
+		return os.RemoveAll(entry.Path)
+	case opRemove:
+		if entry.Backup == "" {
+			return errors.Errorf("could not restore %s: it was removed without backup", entry.Path)
+		}
+		if err := os.RemoveAll(entry.Path); err != nil {
+			return err
+		}
+		os.MkdirAll(filepath.Dir(entry.Path), 0755)
+		return errors.Wrap(os.Rename(entry.Backup, entry.Path), "could not restore "+entry.Path)
+	case opWrite:
+		if entry.Backup == "" {
+			if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}
+		info, err := os.Stat(entry.Backup)
+		if err != nil {
+			return errors.Wrap(err, "could not restore "+entry.Path)
+		}
+		content, err := ioutil.ReadFile(entry.Backup)
+		if err != nil {
+			return errors.Wrap(err, "could not restore "+entry.Path)
+		}
+		return errors.Wrap(ioutil.WriteFile(entry.Path, content, info.Mode().Perm()), "could not restore "+entry.Path)
+	}
+	return errors.Errorf("unknown step in journal: %s", entry.Op)
+}
+
+// saveLockJSON copies lock.json to lockDir if it exists.
+func saveLockJSON(lockDir string) error {
+	content, err := ioutil.ReadFile(pathutil.LockJSON())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(lockDir, savedLockJSONName), content, 0644)
+}
+
+// restoreLockJSON restores lock.json saved by saveLockJSON().
+// If lock.json did not exist, it removes lock.json.
+func restoreLockJSON(lockDir string) error {
+	content, err := ioutil.ReadFile(filepath.Join(lockDir, savedLockJSONName))
+	if os.IsNotExist(err) {
+		if err := os.Remove(pathutil.LockJSON()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(pathutil.LockJSON(), content, 0644)
 }
 
 // genNewTrxID gets unallocated transaction ID looking $VOLTPATH/trx/ directory.
@@ -0,0 +1,209 @@
+package transaction
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestRollback(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+	os.Setenv("VOLTPATH", tempDir)
+	defer os.Unsetenv("VOLTPATH")
+
+	before := []byte(`{"version":2}`)
+	if err := ioutil.WriteFile(pathutil.LockJSON(), before, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	trx, err := Start()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %s", err)
+	}
+	repos := filepath.Join(tempDir, "repos", "github.com", "tyru", "caw.vim")
+	os.MkdirAll(repos, 0755)
+	trx.Created(repos)
+	if err := ioutil.WriteFile(pathutil.LockJSON(), []byte(`{"version":2,"repos":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := trx.Rollback(); err != nil {
+		t.Fatalf("failed to rollback: %s", err)
+	}
+	if content, err := ioutil.ReadFile(pathutil.LockJSON()); err != nil || string(content) != string(before) {
+		t.Errorf("expected lock.json is restored to %q but got %q", string(before), string(content))
+	}
+	if pathutil.Exists(repos) {
+		t.Errorf("expected %s is removed", repos)
+	}
+	if pathutil.Exists(filepath.Join(pathutil.TrxDir(), "lock")) {
+		t.Errorf("expected lock directory is removed")
+	}
+}
+
+func TestRollbackJournal(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+	os.Setenv("VOLTPATH", tempDir)
+	defer os.Unsetenv("VOLTPATH")
+
+	repos := filepath.Join(tempDir, "repos", "github.com", "tyru", "caw.vim")
+	if err := os.MkdirAll(repos, 0755); err != nil {
+		t.Fatal(err)
+	}
+	plugconf := filepath.Join(tempDir, "plugconf", "caw.vim")
+	newPlugconf := filepath.Join(tempDir, "plugconf", "new.vim")
+	os.MkdirAll(filepath.Dir(plugconf), 0755)
+	if err := ioutil.WriteFile(plugconf, []byte("before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	trx, err := Start()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %s", err)
+	}
+	if Current() != trx {
+		t.Error("expected the started transaction is current")
+	}
+	if err := trx.Remove(repos); err != nil {
+		t.Fatal(err)
+	}
+	if err := trx.Write(plugconf, []byte("after"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := trx.Write(newPlugconf, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if pathutil.Exists(repos) {
+		t.Errorf("expected %s is removed", repos)
+	}
+	journal, err := ioutil.ReadFile(filepath.Join(pathutil.TrxDir(), "lock", journalName))
+	if err != nil || strings.Count(string(journal), "\n") != 3 {
+		t.Errorf("expected 3 steps in the journal but got %q (%v)", journal, err)
+	}
+
+	if err := trx.Rollback(); err != nil {
+		t.Fatalf("failed to rollback: %s", err)
+	}
+	if Current() != nil {
+		t.Error("expected no transaction is current after rollback")
+	}
+	if !pathutil.Exists(repos) {
+		t.Errorf("expected %s is restored", repos)
+	}
+	if content, err := ioutil.ReadFile(plugconf); err != nil || string(content) != "before" {
+		t.Errorf("expected %s is restored but got %q (%v)", plugconf, content, err)
+	}
+	if pathutil.Exists(newPlugconf) {
+		t.Errorf("expected %s is removed", newPlugconf)
+	}
+	if pathutil.Exists(filepath.Join(pathutil.TrxDir(), "lock")) {
+		t.Errorf("expected lock directory is removed")
+	}
+}
+
+func TestStartTakesOverStaleLock(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+	os.Setenv("VOLTPATH", tempDir)
+	defer os.Unsetenv("VOLTPATH")
+
+	// The lock of a running process is not taken over
+	lockDir := filepath.Join(pathutil.TrxDir(), "lock")
+	os.MkdirAll(lockDir, 0755)
+	if err := writePIDFile(lockDir); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := ReadLock(); err != nil || info == nil || !info.Running || info.Stale() {
+		t.Fatalf("expected the lock of this process is running but got %+v (%v)", info, err)
+	}
+	if _, err := Start(); err == nil {
+		t.Fatal("expected the lock of a running process is not taken over")
+	}
+
+	// The lock of a process which is no longer running is taken over, and
+	// the lock with a journal is moved to keep the backups
+	const deadPID = "99999999"
+	if err := ioutil.WriteFile(filepath.Join(lockDir, pidFileName), []byte(deadPID), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(lockDir, journalName), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := ReadLock(); err != nil || info == nil || !info.Stale() || !info.HasJournal {
+		t.Fatalf("expected the lock is stale but got %+v (%v)", info, err)
+	}
+	trx, err := Start()
+	if err != nil {
+		t.Fatalf("expected the stale lock is taken over but got %s", err)
+	}
+	defer trx.Done()
+	matches, _ := filepath.Glob(filepath.Join(pathutil.TrxDir(), "interrupted-*", journalName))
+	if len(matches) != 1 {
+		t.Errorf("expected the journal of the stale lock is kept but got %v", matches)
+	}
+	if info, err := ReadLock(); err != nil || info == nil || info.PID != os.Getpid() {
+		t.Errorf("expected the lock is held by this process but got %+v (%v)", info, err)
+	}
+}
+
+func TestTakeOverRace(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-")
+	if err != nil {
+		t.Fatal("failed to create temp dir")
+	}
+	defer os.RemoveAll(tempDir)
+	os.Setenv("VOLTPATH", tempDir)
+	defer os.Unsetenv("VOLTPATH")
+
+	lockDir := filepath.Join(pathutil.TrxDir(), "lock")
+	os.MkdirAll(lockDir, 0755)
+	if err := ioutil.WriteFile(filepath.Join(lockDir, pidFileName), []byte("99999999"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := ReadLock()
+	if err != nil || info == nil || !info.Stale() {
+		t.Fatalf("expected the lock is stale but got %+v (%v)", info, err)
+	}
+
+	// Other process took over the stale lock after it was read
+	os.RemoveAll(lockDir)
+	os.MkdirAll(lockDir, 0755)
+	if err := writePIDFile(lockDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := takeOver(info); err == nil {
+		t.Error("expected the lock of other process is not taken over")
+	}
+	if got, err := ReadLock(); err != nil || got == nil || got.PID != os.Getpid() {
+		t.Errorf("expected the lock of other process is kept but got %+v (%v)", got, err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(pathutil.TrxDir(), "stale-*")); len(matches) > 0 {
+		t.Errorf("expected no stale directory is left but got %v", matches)
+	}
+
+	// The stale lock is released
+	if err := ioutil.WriteFile(filepath.Join(lockDir, pidFileName), []byte("99999999"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := takeOver(info); err != nil {
+		t.Fatal(err)
+	}
+	if pathutil.Exists(lockDir) {
+		t.Error("expected the stale lock is removed")
+	}
+}